@@ -13,6 +13,14 @@ import (
 	"kwdb-playground/internal/config"
 )
 
+// 支持的 --format 取值
+const (
+	formatText     = "text"
+	formatJSON     = "json"
+	formatJUnit    = "junit"
+	formatMarkdown = "md"
+)
+
 // NewCommand 创建 check 子命令：
 // - Docker 环境检查
 // - 端口占用检查
@@ -26,6 +34,7 @@ func NewCommand(staticFiles embed.FS) *cobra.Command {
 		port       int
 		coursesDir string
 		useEmbed   bool
+		format     string
 	)
 
 	cmd := &cobra.Command{
@@ -36,7 +45,11 @@ func NewCommand(staticFiles embed.FS) *cobra.Command {
 2) 镜像源可用性(Docker Hub/ghcr.io/Aliyun ACR)
 3) 指定端口是否被占用
 4) 课程资源加载与数据完整性
-5) Playground 服务运行与健康状态`,
+5) Playground 服务运行与健康状态
+
+每项检查携带稳定的 Code（如 docker.unavailable）与 severity（error/warn/info），
+--format 支持 text（默认，人类可读）、json（机器可读）、junit（JUnit XML，供CI将其作为测试报告解析）、md（Markdown表格，便于粘贴到PR/Issue）。
+退出码契约：0 全部通过，1 存在警告，2 存在错误，脚本/CI可据此判断结果而无需解析中文文案。`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// 静默模式：禁用标准库日志输出，避免内部模块在检查期间输出日志
 			// 注意：仅影响该命令的执行周期，结束后通过 defer 恢复，避免影响其他命令
@@ -71,12 +84,33 @@ func NewCommand(staticFiles embed.FS) *cobra.Command {
 			eff.Course.Dir = effectiveCoursesDir
 			eff.Course.UseEmbed = effectiveUseEmbed
 
-			summary := envcheck.RunFromConfig(staticFiles, eff)
-			fmt.Println(envcheck.RenderSummaryCLI(summary))
-
-			if !summary.OK {
-				return fmt.Errorf("环境检查存在失败项，请根据提示修复后重试")
+			var summary envcheck.Summary
+			switch format {
+			case formatJSON:
+				var err error
+				summary, err = envcheck.RunFromConfigJSON(staticFiles, eff, os.Stdout)
+				if err != nil {
+					return fmt.Errorf("渲染JSON检查结果失败: %w", err)
+				}
+			case formatJUnit:
+				summary = envcheck.RunFromConfig(staticFiles, eff)
+				out, err := envcheck.RenderSummaryJUnit(summary)
+				if err != nil {
+					return fmt.Errorf("渲染JUnit检查结果失败: %w", err)
+				}
+				fmt.Println(out)
+			case formatMarkdown:
+				summary = envcheck.RunFromConfig(staticFiles, eff)
+				fmt.Println(envcheck.RenderSummaryMarkdown(summary))
+			case formatText, "":
+				summary = envcheck.RunFromConfig(staticFiles, eff)
+				fmt.Println(envcheck.RenderSummaryCLI(summary))
+			default:
+				return fmt.Errorf("不支持的 --format 取值: %s（可选 text|json|junit|md）", format)
 			}
+
+			// 退出码契约：0 全部通过，1 存在警告，2 存在错误，脚本/CI无需解析中文文案
+			os.Exit(summary.ExitCode())
 			return nil
 		},
 	}
@@ -86,6 +120,7 @@ func NewCommand(staticFiles embed.FS) *cobra.Command {
 	cmd.Flags().IntVar(&port, "port", 0, "指定服务端口（默认从环境变量/配置读取）")
 	cmd.Flags().StringVar(&coursesDir, "courses-dir", "", "课程目录（未设置时从配置读取）")
 	cmd.Flags().BoolVar(&useEmbed, "courses-use-embed", false, "是否使用嵌入课程资源进行检查（未设置时从配置读取）")
+	cmd.Flags().StringVar(&format, "format", formatText, "检查结果输出格式: text|json|junit|md")
 
 	return cmd
 }
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"kwdb-playground/internal/config"
+)
+
+// healthDetails 与 api.getHealthDetails 返回的JSON结构对应，仅取 status 子命令关心的字段
+type healthDetails struct {
+	Status          string `json:"status"`
+	UptimeSeconds   int    `json:"uptimeSeconds"`
+	CourseCount     int    `json:"courseCount"`
+	DockerAvailable bool   `json:"dockerAvailable"`
+	Config          struct {
+		Host        string `json:"host"`
+		Port        int    `json:"port"`
+		CourseDir   string `json:"courseDir"`
+		UseEmbed    bool   `json:"useEmbed"`
+		RuntimeType string `json:"runtimeType"`
+	} `json:"config"`
+}
+
+// NewStatusCommand 查询守护进程运行状态：先校验PID文件与进程是否仍存活，
+// 再通过TCP访问 /api/health 获取运行时长、课程数、Docker可用性等详情
+func NewStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "查询 kwdb-playground 守护进程运行状态",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pid, ok := readPIDFromFile(pidFilePath)
+			if !ok || !isProcessRunning(pid) {
+				fmt.Println("kwdb-playground 未运行")
+				return nil
+			}
+			fmt.Printf("kwdb-playground 正在运行 (PID=%d)\n", pid)
+
+			cfg := config.Load()
+			if cfg == nil {
+				return fmt.Errorf("读取配置失败，无法查询健康状态")
+			}
+
+			url := fmt.Sprintf("http://%s:%d/api/health", cfg.Server.Host, cfg.Server.Port)
+			resp, err := http.Get(url)
+			if err != nil {
+				return fmt.Errorf("无法连接到 %s: %w", url, err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return fmt.Errorf("读取健康状态响应失败: %w", err)
+			}
+
+			var health healthDetails
+			if err := json.Unmarshal(body, &health); err != nil {
+				// 接口返回异常格式时至少把原始内容打印出来，而不是彻底报错
+				fmt.Println(string(body))
+				return nil
+			}
+			fmt.Printf("运行时长: %s\n", time.Duration(health.UptimeSeconds)*time.Second)
+			fmt.Printf("课程数: %d\n", health.CourseCount)
+			fmt.Printf("Docker可用: %v\n", health.DockerAvailable)
+			fmt.Printf("监听地址: %s:%d\n", health.Config.Host, health.Config.Port)
+			fmt.Printf("课程目录: %s (嵌入模式: %v)\n", health.Config.CourseDir, health.Config.UseEmbed)
+			fmt.Printf("运行时后端: %s\n", health.Config.RuntimeType)
+			return nil
+		},
+	}
+}
+
+// NewStopCommand 停止守护进程：先发 SIGTERM 等待 srv.Shutdown 优雅退出，
+// 超过 --timeout 仍未退出则升级为 SIGKILL
+func NewStopCommand() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "停止正在运行的 kwdb-playground 守护进程",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pid, ok := readPIDFromFile(pidFilePath)
+			if !ok || !isProcessRunning(pid) {
+				fmt.Println("kwdb-playground 未运行")
+				removePIDFile(pidFilePath)
+				return nil
+			}
+
+			fmt.Printf("正在停止 kwdb-playground (PID=%d)...\n", pid)
+			if err := signalTerminate(pid); err != nil {
+				return fmt.Errorf("发送终止信号失败: %w", err)
+			}
+
+			deadline := time.Now().Add(timeout)
+			for time.Now().Before(deadline) {
+				if !isProcessRunning(pid) {
+					fmt.Println("已优雅停止")
+					removePIDFile(pidFilePath)
+					return nil
+				}
+				time.Sleep(200 * time.Millisecond)
+			}
+
+			fmt.Println("优雅停止超时，强制终止...")
+			if err := signalKill(pid); err != nil {
+				return fmt.Errorf("强制终止失败: %w", err)
+			}
+			removePIDFile(pidFilePath)
+			fmt.Println("已强制停止")
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "等待优雅退出的最长时间，超时后强制终止")
+	return cmd
+}
+
+// NewReloadCommand 通知守护进程重新加载配置与课程，不中断已建立的WebSocket连接
+// （对应 Run() 中对 SIGHUP 的处理，而非重启整个进程）
+func NewReloadCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "重新加载配置与课程，不中断已建立的连接",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pid, ok := readPIDFromFile(pidFilePath)
+			if !ok || !isProcessRunning(pid) {
+				return fmt.Errorf("kwdb-playground 未运行")
+			}
+			if err := signalReload(pid); err != nil {
+				return fmt.Errorf("发送重新加载信号失败: %w", err)
+			}
+			fmt.Printf("已通知 PID=%d 重新加载配置与课程\n", pid)
+			return nil
+		},
+	}
+}
+
+// NewLogsCommand 查看守护进程日志，-f 时持续输出新增内容（类似 tail -f）
+func NewLogsCommand() *cobra.Command {
+	var follow bool
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "查看守护进程日志",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(daemonLogPath)
+			if err != nil {
+				return fmt.Errorf("打开日志文件失败: %w", err)
+			}
+			defer f.Close()
+
+			if _, err := io.Copy(os.Stdout, f); err != nil {
+				return fmt.Errorf("读取日志失败: %w", err)
+			}
+			if !follow {
+				return nil
+			}
+
+			for {
+				if _, err := io.Copy(os.Stdout, f); err != nil {
+					return fmt.Errorf("读取日志失败: %w", err)
+				}
+				time.Sleep(500 * time.Millisecond)
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "持续输出新增日志内容（类似 tail -f）")
+	return cmd
+}
@@ -60,3 +60,19 @@ func runAsDaemon(pidFile, logFile string, args []string) error {
 	fmt.Printf("守护进程启动成功，PID=%d，日志=%s，PID文件=%s\n", cmd.Process.Pid, logFile, pidFile)
 	return nil
 }
+
+// signalTerminate 请求守护进程优雅退出（Unix）：触发 Run() 中 SIGTERM/SIGINT 分支的 srv.Shutdown
+func signalTerminate(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// signalKill 强制终止守护进程（Unix），用于优雅退出超时后的兜底
+func signalKill(pid int) error {
+	return syscall.Kill(pid, syscall.SIGKILL)
+}
+
+// signalReload 请求守护进程重新加载配置与课程（Unix）：触发 Run() 中的 SIGHUP 分支，
+// 不关闭HTTP服务器也不断开已建立的WebSocket连接
+func signalReload(pid int) error {
+	return syscall.Kill(pid, syscall.SIGHUP)
+}
@@ -2,17 +2,93 @@
 
 package server
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
 
-// isProcessRunning（Windows）
-// 简化为始终返回 false，以避免依赖不可用的信号语义。
-// Windows 下建议通过任务管理器或服务管理查询进程状态。
-func isProcessRunning(_ int) bool {
-	return false
+	"golang.org/x/sys/windows"
+)
+
+// isProcessRunning（Windows）：通过 OpenProcess+GetExitCodeProcess 判断进程是否仍存活，
+// 对应Unix下 syscall.Kill(pid, 0) 的探测语义
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+
+	var exitCode uint32
+	if err := windows.GetExitCodeProcess(h, &exitCode); err != nil {
+		return false
+	}
+	return exitCode == uint32(windows.STILL_ACTIVE)
+}
+
+// runAsDaemon（Windows）：以分离的后台进程方式启动自身，不继承控制台，
+// 近似Unix下 Setsid 守护模式的效果；注册为系统服务请改用 `server service install`
+func runAsDaemon(pidFile, logFile string, args []string) error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("无法获取可执行文件路径: %w", err)
+	}
+
+	childArgs := append([]string{"server"}, filterDaemonFlags(args)...)
+
+	if err = ensureDirForFile(logFile); err != nil {
+		return fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	logFH, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %w", err)
+	}
+	defer logFH.Close()
+
+	cmd := exec.Command(exePath, childArgs...)
+	cmd.Stdout = logFH
+	cmd.Stderr = logFH
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: windows.CREATE_NEW_PROCESS_GROUP | windows.DETACHED_PROCESS,
+	}
+	cmd.Env = append(os.Environ(), "DAEMON_MODE=1")
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("守护子进程启动失败: %w", err)
+	}
+
+	if err := writePID(pidFile, cmd.Process.Pid); err != nil {
+		return fmt.Errorf("写入 PID 文件失败: %w", err)
+	}
+
+	fmt.Printf("守护进程启动成功，PID=%d，日志=%s，PID文件=%s\n", cmd.Process.Pid, logFile, pidFile)
+	return nil
+}
+
+// signalTerminate（Windows）：向进程组发送 CTRL_BREAK_EVENT，Go 运行时将其作为 os.Interrupt 投递，
+// 走 Run() 信号循环中 SIGINT/SIGTERM 共用的优雅关闭路径
+// （该信号循环同时监听 extShutdown，供 Windows 服务路径以同样方式转发，见 service_windows.go）
+func signalTerminate(pid int) error {
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(pid))
+}
+
+// signalKill（Windows）：OpenProcess+TerminateProcess 强制终止，用于优雅退出超时后的兜底
+func signalKill(pid int) error {
+	h, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("打开进程失败: %w", err)
+	}
+	defer windows.CloseHandle(h)
+	return windows.TerminateProcess(h, 1)
 }
 
-// runAsDaemon（Windows）
-// Windows 平台不支持 Unix 风格的 Setsid 守护模式，这里返回明确错误提示。
-func runAsDaemon(_ string, _ string, _ []string) error {
-	return fmt.Errorf("守护进程模式在 Windows 未实现；请在 Windows 下以服务或计划任务方式运行")
+// signalReload（Windows）：SIGHUP 没有等价语义，且 CTRL_BREAK_EVENT 已用于 signalTerminate，
+// reload 子命令暂不支持；请重启守护进程或服务以应用新配置
+func signalReload(_ int) error {
+	return fmt.Errorf("reload 子命令在 Windows 未实现；请重启守护进程或服务以应用新配置")
 }
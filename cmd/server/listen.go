@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// buildListener 根据形如 tcp://host:port、unix:///path/to.sock、fd://N 的地址创建对应的 net.Listener，
+// 约定与 dockerd 的 --host/-H 一致：
+//   - tcp://：普通TCP监听
+//   - unix://：Unix域套接字，监听后按 socketGroup 调整属组并将权限收紧为 0660（仅属主与属组可读写）
+//   - fd://：systemd socket activation，直接复用由父进程（如 systemd）在该编号上预先绑定好的socket，
+//     不在此处创建/权限调整
+func buildListener(addr string, socketGroup string) (net.Listener, error) {
+	scheme, value, err := splitListenAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scheme {
+	case "tcp":
+		return net.Listen("tcp", value)
+	case "unix":
+		return buildUnixListener(value, socketGroup)
+	case "fd":
+		return buildFDListener(value)
+	default:
+		return nil, fmt.Errorf("不支持的监听地址scheme: %q（仅支持 tcp/unix/fd）", scheme)
+	}
+}
+
+// splitListenAddr 把 "scheme://value" 形式的监听地址拆分为 scheme 与 value
+func splitListenAddr(addr string) (scheme string, value string, err error) {
+	parts := strings.SplitN(addr, "://", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("无效的监听地址 %q，期望形如 tcp://host:port、unix:///path/to.sock 或 fd://N", addr)
+	}
+	return parts[0], parts[1], nil
+}
+
+// buildUnixListener 监听Unix域套接字：先清理上次异常退出残留的套接字文件，再按 socketGroup 调整属组，
+// 最后收紧权限为 0660，使仅套接字属主与属组内的本地用户可以连接——配合 peercred 中间件，
+// 免去为 unix:// 调用方额外签发JWT的必要性
+func buildUnixListener(path string, socketGroup string) (net.Listener, error) {
+	if err := ensureDirForFile(path); err != nil {
+		return nil, fmt.Errorf("创建套接字目录失败: %w", err)
+	}
+	// 进程异常退出可能遗留旧的套接字文件，导致 bind: address already in use
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if socketGroup != "" {
+		gid, gerr := resolveGroupID(socketGroup)
+		if gerr != nil {
+			ln.Close()
+			return nil, fmt.Errorf("解析套接字属组 %q 失败: %w", socketGroup, gerr)
+		}
+		if cerr := os.Chown(path, -1, gid); cerr != nil {
+			ln.Close()
+			return nil, fmt.Errorf("设置套接字属组失败: %w", cerr)
+		}
+	}
+
+	if err := os.Chmod(path, 0o660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("设置套接字权限失败: %w", err)
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket 删除已存在的套接字文件（若存在且确实是套接字），为重新bind让路
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("检查已存在的套接字文件失败: %w", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("路径 %s 已存在且不是套接字文件，拒绝覆盖", path)
+	}
+	return os.Remove(path)
+}
+
+// resolveGroupID 将 group 解析为GID：优先当作组名查找，失败则尝试直接解析为数字GID
+func resolveGroupID(group string) (int, error) {
+	if g, err := user.LookupGroup(group); err == nil {
+		return strconv.Atoi(g.Gid)
+	}
+	gid, err := strconv.Atoi(group)
+	if err != nil {
+		return 0, fmt.Errorf("既不是已知的组名也不是合法的GID: %s", group)
+	}
+	return gid, nil
+}
+
+// buildFDListener 复用 systemd socket activation 等场景下由父进程预先绑定在指定编号上的socket
+func buildFDListener(fdStr string) (net.Listener, error) {
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的fd编号 %q: %w", fdStr, err)
+	}
+	f := os.NewFile(uintptr(fd), "kwdb-playground-listener-fd-"+fdStr)
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("由fd %d 创建监听器失败: %w", fd, err)
+	}
+	// net.FileListener内部会dup底层fd，原始*os.File不再需要
+	_ = f.Close()
+	return ln, nil
+}
+
+// resolveListenAddrs 汇总最终需要监听的地址列表：cfg.Server.Listen 非空时直接使用；
+// 否则退化为仅监听 host:port 一个TCP地址，保持历史行为
+func resolveListenAddrs(host string, port int, extra []string) []string {
+	if len(extra) > 0 {
+		return extra
+	}
+	return []string{fmt.Sprintf("tcp://%s:%d", host, port)}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"embed"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,15 +16,23 @@ import (
 	"time"
 
 	"kwdb-playground/internal/api"
+	"kwdb-playground/internal/backend"
 	"kwdb-playground/internal/config"
 	"kwdb-playground/internal/course"
 	"kwdb-playground/internal/docker"
 	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/middleware/peercred"
+	"kwdb-playground/internal/middleware/requestid"
+	"kwdb-playground/internal/runtime"
+	"kwdb-playground/internal/session"
+	"kwdb-playground/internal/staticfs"
 	"kwdb-playground/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 // 守护进程相关默认路径（相对当前工作目录）
@@ -31,6 +41,20 @@ const (
 	daemonLogPath = "logs/daemon.log"         // 守护进程日志文件，重定向标准输出/错误
 )
 
+// extShutdown 供不经由Unix信号触发优雅关闭的外部控制面使用（目前是Windows服务控制管理器，
+// 见 service_windows.go），效果与收到 SIGTERM 相同；Unix下永远不会有写入者，零开销
+var extShutdown = make(chan struct{}, 1)
+
+// TriggerShutdown 请求 Run 走与 SIGTERM 相同的优雅关闭路径。
+// Windows 没有 Unix 信号语义，服务控制管理器下发 Stop/Shutdown 时通过此函数转发，
+// 而不是另起一套关闭逻辑
+func TriggerShutdown() {
+	select {
+	case extShutdown <- struct{}{}:
+	default:
+	}
+}
+
 // Run 是 server 子命令的入口
 // 参数:
 //   - staticFiles: 嵌入的静态资源与课程文件（来自上层 main 的 go:embed）
@@ -69,7 +93,17 @@ func Run(staticFiles embed.FS, args []string) error {
 		os.Exit(1)
 	}
 
-	appLogger := logger.NewLogger(logger.ParseLogLevel(cfg.Log.Level))
+	// 全局日志级别覆盖：后续通过 POST /api/admin/log-level 调整时，appLogger与其他所有Logger
+	// （SetGlobalLevel未设置时各自维持自身级别）都会立即生效，无需重启进程
+	logger.SetGlobalLevel(logger.ParseLogLevel(cfg.Log.Level))
+
+	appLogger, err := newAppLogger(cfg)
+	if err != nil {
+		tempLogger := logger.NewLogger(logger.ERROR)
+		tempLogger.Error("初始化日志系统失败: %v", err)
+		os.Exit(1)
+	}
+	appLogger.SetFormat(cfg.Log.Format)
 
 	// 初始化课程服务（嵌入/磁盘双模式）
 	var courseService *course.Service
@@ -81,6 +115,28 @@ func Run(staticFiles embed.FS, args []string) error {
 		appLogger.Info("Course service initialized in disk mode: %s", cfg.Course.Dir)
 	}
 	courseService.SetLogger(appLogger)
+
+	// 按配置选择进度存储后端：默认沿用Service构造时的JSON文件存储，
+	// 非默认配置下（sqlite/badger/http）替换为对应实现
+	if cfg.Progress.Backend != "" && cfg.Progress.Backend != "json" {
+		progressStore, err := course.NewProgressStoreFromConfig(cfg.Progress, cfg.DataDir)
+		if err != nil {
+			appLogger.Warn("初始化进度存储后端 %s 失败，继续使用默认JSON文件存储: %v", cfg.Progress.Backend, err)
+		} else {
+			walPath := course.ProgressWALPathForBackend(cfg.Progress, cfg.DataDir)
+			courseService.SetProgressStore(progressStore, walPath)
+			appLogger.Info("进度存储后端已切换为: %s", cfg.Progress.Backend)
+		}
+	}
+
+	for _, srcCfg := range cfg.Course.ExtraSources {
+		src, err := course.NewSourceFromConfig(srcCfg, cfg.DataDir)
+		if err != nil {
+			appLogger.Warn("跳过无效的课程数据源配置 %+v: %v", srcCfg, err)
+			continue
+		}
+		courseService.AddSource(src)
+	}
 	if err := courseService.LoadCourses(); err != nil {
 		appLogger.Warn("Warning: failed to load courses: %v", err)
 	}
@@ -89,6 +145,64 @@ func Run(staticFiles embed.FS, args []string) error {
 	terminalManager := websocket.NewTerminalManager()
 	terminalManager.SetLogger(appLogger)
 
+	// 启动课程热重载监听：磁盘目录变化走fsnotify，HTTP/Git源走各自的后台轮询，
+	// 变化发生时把 courseService.Events() 转发为 course_updated 消息广播给对应课程的终端会话
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	if err := courseService.Watch(watchCtx); err != nil {
+		appLogger.Warn("启动课程热重载监听失败: %v", err)
+	} else {
+		go func() {
+			for ev := range courseService.Events() {
+				terminalManager.BroadcastCourseUpdated(ev.CourseID, string(ev.Type))
+			}
+		}()
+	}
+
+	// 分层配置热更新：Manager 始终创建，SIGHUP（见下方 waitLoop）和下面的轮询/文件监听都通过它
+	// 重新装配+校验配置；先用当前cfg做一次Load，使Manager.Get()从启动时起就有值可比较
+	configManager := config.NewManager(config.ProvidersFromConfig(cfg.ConfigSource), appLogger)
+	if _, err := configManager.Load(nil); err != nil {
+		appLogger.Warn("初始化配置 Manager 失败，SIGHUP 热重载将不可用: %v", err)
+	}
+	configManager.Subscribe(config.ObserverFunc(func(diff config.ConfigDiff) {
+		newCfg := diff.New
+		for _, field := range diff.Changed {
+			switch field {
+			case "Log.Level":
+				logger.SetGlobalLevel(logger.ParseLogLevel(newCfg.Log.Level))
+				appLogger.SetLevel(logger.ParseLogLevel(newCfg.Log.Level))
+				appLogger.Info("配置热更新: 日志级别变更为 %s", newCfg.Log.Level)
+			case "Log.Format":
+				appLogger.SetFormat(newCfg.Log.Format)
+			case "Course.Dir", "Course.Reload":
+				cfg.Course.Reload = newCfg.Course.Reload
+				cfg.Course.Dir = newCfg.Course.Dir
+				if err := courseService.LoadCourses(); err != nil {
+					appLogger.Warn("配置热更新触发的课程重新加载失败: %v", err)
+				} else {
+					appLogger.Info("配置热更新: 课程已重新加载")
+				}
+			case "Server.SessionLimit":
+				appLogger.Info("配置热更新: 并发会话限制变更为 %d", newCfg.Server.SessionLimit)
+			case "Supervisor.RestartPolicy":
+				appLogger.Info("配置热更新: 容器自动重启策略变更为 %s", newCfg.Supervisor.RestartPolicy)
+			}
+		}
+	}))
+	// 仅当配置了file/consul/etcd数据源时才启动轮询，避免无意义的空跑goroutine；
+	// env层单独变化本来就需要重启进程才能被读到，不依赖Watch也能生效
+	if cfg.ConfigSource.FilePath != "" || cfg.ConfigSource.ConsulAddr != "" || cfg.ConfigSource.EtcdAddr != "" {
+		watchInterval := time.Duration(cfg.ConfigSource.WatchIntervalSecs) * time.Second
+		go configManager.Watch(watchCtx, watchInterval)
+	}
+	// file数据源额外用fsnotify监听，比轮询更快地感知到配置文件的变化
+	if cfg.ConfigSource.FilePath != "" {
+		if err := configManager.WatchFile(watchCtx, cfg.ConfigSource.FilePath); err != nil {
+			appLogger.Warn("启动配置文件监听失败，仍可通过轮询/SIGHUP感知变化: %v", err)
+		}
+	}
+
 	// 初始化 Docker 控制器
 	dockerController, err := docker.NewControllerWithTerminalManager(terminalManager)
 	if err != nil {
@@ -97,6 +211,67 @@ func Run(staticFiles embed.FS, args []string) error {
 	}
 	appLogger.Info("WebSocket终端管理器初始化完成")
 
+	// 按配置启动容器监督：订阅Docker事件流，容器意外退出时按策略自动重启；
+	// RestartPolicy默认为"none"，保持历史行为（退出后需要用户手动重新启动）
+	if dockerController != nil {
+		policy := docker.ParseRestartPolicy(cfg.Supervisor.RestartPolicy)
+		dockerController.StartSupervisor(context.Background(), policy, cfg.Supervisor.StatePath)
+		appLogger.Info("容器监督已启动，重启策略: %s", cfg.Supervisor.RestartPolicy)
+	}
+
+	// 按 cfg.Runtime.Type 选择WebSocket终端实际驱动exec的运行时后端：
+	// "docker"（默认）直接复用上面的dockerController；"containerd"另外建立一条独立连接；
+	// "cri"连接标准CRI gRPC端点；"auto"按containerd socket是否存在自动选择，探测不到
+	// 则退回dockerController；"podman"兼容Docker Engine API，同样复用dockerController，
+	// 要求 DOCKER_HOST 指向podman的socket（如 unix:///run/user/$UID/podman/podman.sock）
+	var runtimeController runtime.Controller
+	switch cfg.Runtime.Type {
+	case "containerd":
+		containerdController, err := runtime.NewContainerd(runtime.KindContainerd, runtime.ContainerdConfig{
+			Address:   cfg.Runtime.ContainerdAddress,
+			Namespace: cfg.Runtime.ContainerdNamespace,
+		})
+		if err != nil {
+			appLogger.Warn("containerd 运行时初始化失败，终端会话将不可用: %v", err)
+		} else {
+			runtimeController = containerdController
+		}
+	case "cri":
+		criController, err := runtime.NewContainerd(runtime.KindCRI, runtime.ContainerdConfig{
+			Address:   cfg.Runtime.CRIEndpoint,
+			Namespace: cfg.Runtime.ContainerdNamespace,
+		})
+		if err != nil {
+			appLogger.Warn("CRI 运行时初始化失败，终端会话将不可用: %v", err)
+		} else {
+			runtimeController = criController
+		}
+	case "auto":
+		addr := cfg.Runtime.ContainerdAddress
+		if addr == "" {
+			addr = "/run/containerd/containerd.sock"
+		}
+		autoController, statErr := func() (runtime.Controller, error) {
+			if _, err := os.Stat(addr); err != nil {
+				return nil, err
+			}
+			return runtime.NewContainerd(runtime.KindContainerd, runtime.ContainerdConfig{
+				Address:   addr,
+				Namespace: cfg.Runtime.ContainerdNamespace,
+			})
+		}()
+		if statErr == nil {
+			runtimeController = autoController
+		} else if dockerController != nil {
+			runtimeController = dockerController
+		}
+	default:
+		if dockerController != nil {
+			runtimeController = dockerController
+		}
+	}
+	terminalManager.SetRuntimeController(runtimeController)
+
 	// GIN_MODE=release 设置 Gin 为发布模式
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
@@ -104,71 +279,101 @@ func Run(staticFiles embed.FS, args []string) error {
 		r = gin.New()
 	}
 	r.Use(gin.Recovery())
+	// 为每个请求签发/透传requestID，写入请求上下文供 Logger.WithContext 提取，
+	// 使同一次请求产生的多条结构化日志可以按requestID串联排查
+	r.Use(requestid.Middleware())
+	// 把unix://监听地址上由ConnContext注入的对端uid透传到Gin上下文，供 internal/api 的管理类接口
+	// 豁免本地调用方的JWT鉴权（TCP连接不会注入任何值，不影响现有鉴权行为）
+	r.Use(peercred.Middleware())
+
+	// 静态文件服务（优先磁盘，回退嵌入）：两者都在启动时一次性建立ETag/预压缩索引，
+	// 取代此前逐请求ReadFile+手写Content-Type+手写Cache-Control的重复逻辑
+	var diskAssets *staticfs.Set
+	if !cfg.Course.UseEmbed {
+		if s, err := staticfs.NewSet(os.DirFS("dist")); err == nil {
+			diskAssets = s
+		} else {
+			appLogger.Debug("索引本地dist目录失败，将回退到嵌入资源: %v", err)
+		}
+	}
+	embedRoot, err := fs.Sub(staticFiles, "dist")
+	if err != nil {
+		return fmt.Errorf("嵌入静态资源缺少dist子目录: %w", err)
+	}
+	embedAssets, err := staticfs.NewSet(embedRoot)
+	if err != nil {
+		return fmt.Errorf("索引嵌入静态资源失败: %w", err)
+	}
 
-	// 静态文件服务（优先磁盘，回退嵌入）
 	r.GET("/assets/*filepath", func(c *gin.Context) {
 		p := c.Param("filepath")
 		if strings.Contains(p, "..") {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path"})
 			return
 		}
-		if !cfg.Course.UseEmbed {
-			if data, err := os.ReadFile("dist/assets" + p); err == nil {
-				contentType := getContentType(p)
-				c.Header("Cache-Control", "no-cache")
-				c.Data(http.StatusOK, contentType, data)
-				return
-			}
+		assetPath := "assets" + p
+		if diskAssets != nil && diskAssets.Serve(c, assetPath, false) {
+			return
 		}
-		data, err := staticFiles.ReadFile("dist/assets" + p)
-		if err != nil {
-			appLogger.Debug("Static file not found: %s, error: %v", p, err)
-			c.Status(http.StatusNotFound)
+		if embedAssets.Serve(c, assetPath, false) {
 			return
 		}
-		contentType := getContentType(p)
-		c.Header("Cache-Control", "public, max-age=31536000")
-		c.Data(http.StatusOK, contentType, data)
+		appLogger.Debug("Static file not found: %s", p)
+		c.Status(http.StatusNotFound)
 	})
 
 	// 兼容根级静态文件（favicon、manifest等）
 	// 单独路由以支持 /favicon.ico 和 /favicon.svg 等常见路径
 	r.GET("/favicon.ico", func(c *gin.Context) {
-		// 优先读取磁盘
-		if !cfg.Course.UseEmbed {
-			if data, err := os.ReadFile("dist/favicon.ico"); err == nil {
-				c.Header("Cache-Control", "public, max-age=31536000")
-				c.Data(http.StatusOK, "image/x-icon", data)
-				return
-			}
+		if diskAssets != nil && diskAssets.Serve(c, "favicon.ico", false) {
+			return
 		}
-		// 回退到嵌入资源
-		if data, err := staticFiles.ReadFile("dist/favicon.ico"); err == nil {
-			c.Header("Cache-Control", "public, max-age=31536000")
-			c.Data(http.StatusOK, "image/x-icon", data)
+		if embedAssets.Serve(c, "favicon.ico", false) {
 			return
 		}
 		c.Status(http.StatusNotFound)
 	})
 
 	r.GET("/favicon.svg", func(c *gin.Context) {
-		if !cfg.Course.UseEmbed {
-			if data, err := os.ReadFile("dist/favicon.svg"); err == nil {
-				c.Header("Cache-Control", "public, max-age=31536000")
-				c.Data(http.StatusOK, "image/svg+xml", data)
-				return
-			}
+		if diskAssets != nil && diskAssets.Serve(c, "favicon.svg", false) {
+			return
 		}
-		if data, err := staticFiles.ReadFile("dist/favicon.svg"); err == nil {
-			c.Header("Cache-Control", "public, max-age=31536000")
-			c.Data(http.StatusOK, "image/svg+xml", data)
+		if embedAssets.Serve(c, "favicon.svg", false) {
 			return
 		}
 		c.Status(http.StatusNotFound)
 	})
 
+	// 初始化会话管理器，为多学生共享部署提供容器隔离、配额与空闲回收
+	sessionManager := session.NewManager(cfg.Session, dockerController, appLogger)
+	sessionManager.StartReaper()
+	defer sessionManager.StopReaper()
+
 	// API 路由
 	apiHandler := api.NewHandler(courseService, dockerController, terminalManager, appLogger, cfg)
+	apiHandler.SetSessionManager(sessionManager)
+
+	// 配置了 backend.runtime: kubernetes 的课程需要一个共享集群来调度，按需装配
+	if cfg.Kubernetes.Enabled {
+		if kubernetesRuntime, err := newKubernetesRuntime(cfg.Kubernetes); err != nil {
+			appLogger.Warn("Kubernetes 运行时初始化失败，kubernetes 课程将不可用: %v", err)
+		} else {
+			apiHandler.SetKubernetesRuntime(kubernetesRuntime)
+		}
+	}
+
+	// 启动阶段并发探测所有课程引用的镜像是否可达，避免第一个进入课程的学生等到容器创建时才触发拉取；
+	// 复用 apiHandler 已构造的 registryManager，保证与 /api/images/* 其余接口看到的是同一份镜像源状态
+	if dockerController != nil {
+		imageWarmer := docker.NewImageWarmer(dockerController, apiHandler.RegistryManager(), appLogger, cfg.ImageWarm.Concurrency, cfg.ImageWarm.Prepull)
+		apiHandler.SetImageWarmer(imageWarmer)
+		var warmImages []string
+		for _, crs := range courseService.GetCourses() {
+			warmImages = append(warmImages, crs.DockerImage)
+		}
+		go imageWarmer.Warm(watchCtx, warmImages)
+	}
+
 	apiHandler.SetupRoutes(r)
 
 	// 调试：列出所有已注册路由
@@ -176,60 +381,121 @@ func Run(staticFiles embed.FS, args []string) error {
 		appLogger.Debug("Route registered: %s %s", ri.Method, ri.Path)
 	}
 
-	// 前端路由（index.html）
+	// 前端路由（index.html）：noCache=true确保新版本发布后SPA不会继续使用旧的入口文件，
+	// 但仍然带ETag，未变化时浏览器重新加载只需一次304往返
 	r.NoRoute(func(c *gin.Context) {
 		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "API endpoint not found"})
 			return
 		}
-		if !cfg.Course.UseEmbed {
-			if data, err := os.ReadFile("dist/index.html"); err == nil {
-				c.Header("Cache-Control", "no-cache")
-				c.Data(http.StatusOK, "text/html; charset=utf-8", data)
-				return
-			}
+		if diskAssets != nil && diskAssets.Serve(c, "index.html", true) {
+			return
 		}
-		data, err := staticFiles.ReadFile("dist/index.html")
-		if err != nil {
-			c.String(http.StatusInternalServerError, "Error loading page")
+		if embedAssets.Serve(c, "index.html", true) {
 			return
 		}
-		c.Data(http.StatusOK, "text/html; charset=utf-8", data)
+		c.String(http.StatusInternalServerError, "Error loading page")
 	})
 
-	addr := cfg.Server.Host + ":" + strconv.Itoa(cfg.Server.Port)
-	srv := &http.Server{Addr: addr, Handler: r, ReadTimeout: 15 * time.Second, WriteTimeout: 15 * time.Second, IdleTimeout: 60 * time.Second}
+	// 支持同时监听多个地址（tcp/unix/fd），不配置 cfg.Server.Listen 时退化为仅监听 Host:Port 一个TCP地址
+	listenAddrs := resolveListenAddrs(cfg.Server.Host, cfg.Server.Port, cfg.Server.Listen)
+	srv := &http.Server{
+		Handler:      r,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		// 在Unix域套接字连接建立时读取SO_PEERCRED对端uid，TCP连接原样透传ctx不受影响
+		ConnContext: peercred.ConnContext,
+	}
 
-	appLogger.Info("KWDB Playground starting on %s", addr)
+	listeners := make([]net.Listener, 0, len(listenAddrs))
+	for _, addr := range listenAddrs {
+		ln, err := buildListener(addr, cfg.Server.ListenSocketGroup)
+		if err != nil {
+			appLogger.Error("监听地址 %s 失败: %v", addr, err)
+			os.Exit(1)
+		}
+		appLogger.Info("KWDB Playground listening on %s", addr)
+		listeners = append(listeners, ln)
+	}
 	if cfg.Course.UseEmbed {
 		appLogger.Info("Courses served from embedded FS")
 	} else {
 		appLogger.Info("Courses directory: %s", cfg.Course.Dir)
 	}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			appLogger.Error("Failed to start server: %v", err)
-			os.Exit(1)
-		}
-	}()
+	for _, ln := range listeners {
+		ln := ln
+		go func() {
+			if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+				appLogger.Error("Failed to start server: %v", err)
+				os.Exit(1)
+			}
+		}()
+	}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+waitLoop:
+	for {
+		select {
+		case sig := <-sigCh:
+			if sig != syscall.SIGHUP {
+				break waitLoop
+			}
+			// reload子命令发来的SIGHUP：走 configManager.Reload 而不是直接 *cfg = *newCfg，
+			// 这样 Server.Host/Port 等immutable字段被误改时会被拒绝，候选配置校验失败时保留旧配置，
+			// 配置本身发生变化的字段由上面注册的 Observer 去应用。课程目录额外无条件重新扫描一次：
+			// 管理员编辑课程YAML后kill -HUP是一直以来的操作习惯，即使配置本身没有变化也要生效
+			// 不触碰srv/terminalManager，已建立的WebSocket连接不受影响
+			appLogger.Info("收到 SIGHUP，重新加载配置与课程...")
+			if _, err := configManager.Reload(nil); err != nil {
+				appLogger.Warn("重新加载配置失败，继续使用现有配置: %v", err)
+			}
+			if err := courseService.LoadCourses(); err != nil {
+				appLogger.Warn("重新加载课程失败: %v", err)
+			} else {
+				appLogger.Info("课程已重新加载")
+			}
+		case <-extShutdown:
+			// 来自 Windows 服务控制管理器等外部控制面的关闭请求，走与SIGTERM相同的路径
+			break waitLoop
+		}
+	}
 	appLogger.Info("Shutting down server...")
 
+	// 先停止容器监督再关停HTTP服务器，避免关停过程中仍有后台goroutine尝试重启容器
+	if dockerController != nil {
+		dockerController.StopSupervisor()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	_ = srv.Shutdown(ctx)
 
+	if err := courseService.Close(); err != nil {
+		appLogger.Warn("Failed to close course service cleanly: %v", err)
+	}
+
 	if os.Getenv("DAEMON_MODE") == "1" {
 		removePIDFile(pidFilePath)
 	}
 	appLogger.Info("Server exited")
+	appLogger.Flush() // 异步日志模式下排空channel，确保退出前的日志不丢失；同步模式下为空操作
+	_ = appLogger.Close()
 	return nil
 }
 
+// newAppLogger 按 cfg.Log 构建主日志记录器：FilePath非空时使用滚动文件sink（异步落盘），
+// 否则退化为同步模式输出到stderr（历史行为）；两种情况下日志级别都遵循全局覆盖（见SetGlobalLevel）
+func newAppLogger(cfg *config.Config) (*logger.Logger, error) {
+	level := logger.ParseLogLevel(cfg.Log.Level)
+	if cfg.Log.FilePath == "" {
+		return logger.NewLogger(level), nil
+	}
+	return logger.NewRotatingLogger(cfg.Log.FilePath, level, cfg.Log.RotateOptions())
+}
+
 // ----------------------------
 // 子命令参数与帮助
 // ----------------------------
@@ -251,8 +517,14 @@ func printHelp() {
 	fmt.Println("\n环境变量(常用):")
 	fmt.Println("  SERVER_HOST         服务器监听地址 (默认: localhost)")
 	fmt.Println("  SERVER_PORT         服务器端口 (默认: 3006/配置)")
+	fmt.Println("  SERVER_LISTEN       逗号分隔的监听地址，设置后取代 SERVER_HOST:SERVER_PORT，如 unix:///var/run/kwdb-playground.sock、fd://3")
 	fmt.Println("  COURSES_USE_EMBED   是否使用嵌入式FS (默认: 由编译期/环境决定)")
 	fmt.Println("  COURSES_RELOAD      是否启用课程热重载 (开发模式友好)")
+	fmt.Println("\n子命令:")
+	fmt.Println("  status              查询守护进程运行状态")
+	fmt.Println("  stop                停止守护进程")
+	fmt.Println("  reload              重新加载配置与课程，不中断已建立的连接")
+	fmt.Println("  logs [-f]           查看守护进程日志")
 }
 
 // ----------------------------
@@ -315,36 +587,6 @@ func removePIDFile(filePath string) { _ = os.Remove(filePath) }
 //  - daemon_unix.go（非 Windows）
 //  - daemon_windows.go（Windows）
 
-// ----------------------------
-// 静态工具函数
-// ----------------------------
-
-// getContentType 根据文件路径返回对应的Content-Type
-func getContentType(p string) string {
-	switch {
-	case strings.HasSuffix(p, ".js"):
-		return "application/javascript"
-	case strings.HasSuffix(p, ".css"):
-		return "text/css"
-	case strings.HasSuffix(p, ".svg"):
-		return "image/svg+xml"
-	case strings.HasSuffix(p, ".png"):
-		return "image/png"
-	case strings.HasSuffix(p, ".jpg") || strings.HasSuffix(p, ".jpeg"):
-		return "image/jpeg"
-	case strings.HasSuffix(p, ".gif"):
-		return "image/gif"
-	case strings.HasSuffix(p, ".ico"):
-		return "image/x-icon"
-	case strings.HasSuffix(p, ".woff") || strings.HasSuffix(p, ".woff2"):
-		return "font/woff"
-	case strings.HasSuffix(p, ".ttf"):
-		return "font/ttf"
-	default:
-		return "text/plain"
-	}
-}
-
 // NewCommand 定义 server 子命令（Cobra 风格）
 // - 仅暴露 --daemon/-d 开关，其他运行参数通过 Flags（优先级最高）或环境变量读取
 // - 为了降低重构风险，内部仍然复用现有 Run() 实现，并通过设置环境变量来实现“Flags > Env > 默认值”的优先级
@@ -371,6 +613,19 @@ func NewCommand(staticFiles embed.FS) *cobra.Command {
 				lf, _ := cmd.Flags().GetString("log-format")
 				_ = os.Setenv("LOG_FORMAT", lf)
 			}
+			// --listen 可重复指定，合并为逗号分隔的列表写入SERVER_LISTEN（覆盖环境变量中原有的值），与 dockerd 的 -H 语义一致
+			if cmd.Flags().Changed("listen") {
+				listenAddrs, _ := cmd.Flags().GetStringArray("listen")
+				_ = os.Setenv("SERVER_LISTEN", strings.Join(listenAddrs, ","))
+			}
+			if cmd.Flags().Changed("listen-socket-group") {
+				group, _ := cmd.Flags().GetString("listen-socket-group")
+				_ = os.Setenv("SERVER_LISTEN_SOCKET_GROUP", group)
+			}
+			if cmd.Flags().Changed("runtime") {
+				rt, _ := cmd.Flags().GetString("runtime")
+				_ = os.Setenv("RUNTIME_TYPE", rt)
+			}
 
 			// 守护进程开关（为了兼容原有实现，仍然通过传参透传）
 			daemon, _ := cmd.Flags().GetBool("daemon")
@@ -388,6 +643,30 @@ func NewCommand(staticFiles embed.FS) *cobra.Command {
 	cmd.Flags().Int("port", 0, "服务器端口（默认从环境变量 SERVER_PORT 或默认值读取）")
 	cmd.Flags().String("log-level", "warn", "日志级别: debug|info|warn|error（默认从环境变量 LOG_LEVEL 或默认值读取）")
 	cmd.Flags().String("log-format", "text", "日志格式: json|text（默认从环境变量 LOG_FORMAT 或默认值读取）")
+	// 可重复指定以同时监听多个地址，如 --listen tcp://0.0.0.0:3006 --listen unix:///var/run/kwdb-playground.sock
+	cmd.Flags().StringArray("listen", nil, "监听地址，格式 tcp://host:port|unix:///path/to.sock|fd://N，可重复指定（默认从环境变量 SERVER_LISTEN 或 host/port 读取）")
+	cmd.Flags().String("listen-socket-group", "", "unix://监听地址对应套接字文件的属组（组名或GID）")
+	cmd.Flags().String("runtime", "", "容器运行时后端: docker|containerd|cri|podman|auto（auto按containerd socket→Docker socket顺序自动探测；默认从环境变量 RUNTIME_TYPE 或默认值 docker 读取）")
+
+	// 控制面子命令：status/stop/reload/logs，与 -d 启动的守护进程配套，
+	// 取代此前只能手动 kill 的做法（类似 dockerd 客户端与守护进程的关系）
+	cmd.AddCommand(NewStatusCommand(), NewStopCommand(), NewReloadCommand(), NewLogsCommand())
+	// service 子命令：注册/管理 Windows 服务（Unix 下提示改用 -d/--daemon，见 service_other.go）
+	cmd.AddCommand(NewServiceCommand(staticFiles))
 
 	return cmd
 }
+
+// newKubernetesRuntime 根据配置构建 backend.KubernetesRuntime
+// Kubeconfig 为空时使用集群内配置（适用于以 ServiceAccount 身份运行在集群中的场景）
+func newKubernetesRuntime(cfg config.KubernetesConfig) (*backend.KubernetesRuntime, error) {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	return backend.NewKubernetesRuntime(clientset, restConfig), nil
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+
+package server
+
+import (
+	"embed"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewServiceCommand（非Windows）：Windows服务注册在其他平台上没有对应概念，
+// 这里保留子命令占位以便 `kwdb-playground server service --help` 能给出明确提示，
+// 而不是直接报“未知命令”；Unix下请改用 -d/--daemon 搭配 status/stop/reload
+func NewServiceCommand(_ embed.FS) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "管理 Windows 服务（仅 Windows 平台支持）",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return fmt.Errorf("service 子命令仅在 Windows 平台可用；请使用 -d/--daemon 搭配 status/stop/reload")
+		},
+	}
+	cmd.AddCommand(
+		&cobra.Command{Use: "install", Short: "安装为 Windows 服务（仅 Windows）", RunE: cmd.RunE},
+		&cobra.Command{Use: "uninstall", Short: "卸载 Windows 服务（仅 Windows）", RunE: cmd.RunE},
+		&cobra.Command{Use: "start", Short: "启动 Windows 服务（仅 Windows）", RunE: cmd.RunE},
+		&cobra.Command{Use: "stop", Short: "停止 Windows 服务（仅 Windows）", RunE: cmd.RunE},
+		&cobra.Command{Use: "status", Short: "查询 Windows 服务状态（仅 Windows）", RunE: cmd.RunE},
+	)
+	return cmd
+}
@@ -0,0 +1,276 @@
+//go:build windows
+
+package server
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceName/serviceDisplayName 注册到服务控制管理器(SCM)的标识，
+// 与Unix下 pidFilePath/daemonLogPath 扮演相似的角色：唯一地定位这一个后台实例
+const (
+	serviceName        = "KWDBPlayground"
+	serviceDisplayName = "KWDB Playground"
+)
+
+// NewServiceCommand（Windows）：以 Windows 服务方式安装/启停 kwdb-playground，
+// 对应Unix下 `-d/--daemon` 搭配 status/stop/reload 子命令的守护进程模式
+func NewServiceCommand(staticFiles embed.FS) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "service",
+		Short: "管理 Windows 服务",
+	}
+	cmd.AddCommand(newServiceInstallCommand())
+	cmd.AddCommand(newServiceUninstallCommand())
+	cmd.AddCommand(newServiceStartCommand())
+	cmd.AddCommand(newServiceStopCommand())
+	cmd.AddCommand(newServiceStatusCommand())
+	cmd.AddCommand(newServiceRunCommand(staticFiles))
+	return cmd
+}
+
+// newServiceInstallCommand 把当前可执行文件注册为Windows服务，
+// 服务启动时实际执行的是隐藏的 `server service run` 子命令
+func newServiceInstallCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "install",
+		Short: "将当前可执行文件注册为 Windows 服务",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exePath, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("获取可执行文件路径失败: %w", err)
+			}
+
+			m, err := mgr.Connect()
+			if err != nil {
+				return fmt.Errorf("连接服务控制管理器失败: %w", err)
+			}
+			defer m.Disconnect()
+
+			if s, err := m.OpenService(serviceName); err == nil {
+				s.Close()
+				return fmt.Errorf("服务 %s 已存在，请先执行 uninstall", serviceName)
+			}
+
+			s, err := m.CreateService(serviceName, exePath, mgr.Config{
+				DisplayName: serviceDisplayName,
+				Description: "KWDB Playground 后端服务",
+				StartType:   mgr.StartAutomatic,
+			}, "server", "service", "run")
+			if err != nil {
+				return fmt.Errorf("创建服务失败: %w", err)
+			}
+			defer s.Close()
+
+			fmt.Printf("服务 %s 安装成功，可通过 `server service start` 启动\n", serviceName)
+			return nil
+		},
+	}
+}
+
+// newServiceUninstallCommand 从SCM中移除服务；若服务仍在运行，先发送停止请求
+func newServiceUninstallCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "uninstall",
+		Short: "从服务控制管理器中移除服务",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := mgr.Connect()
+			if err != nil {
+				return fmt.Errorf("连接服务控制管理器失败: %w", err)
+			}
+			defer m.Disconnect()
+
+			s, err := m.OpenService(serviceName)
+			if err != nil {
+				return fmt.Errorf("打开服务失败: %w", err)
+			}
+			defer s.Close()
+
+			if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+				_, _ = s.Control(svc.Stop)
+			}
+
+			if err := s.Delete(); err != nil {
+				return fmt.Errorf("删除服务失败: %w", err)
+			}
+			fmt.Printf("服务 %s 已移除\n", serviceName)
+			return nil
+		},
+	}
+}
+
+// newServiceStartCommand 通过SCM启动已安装的服务
+func newServiceStartCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "start",
+		Short: "启动已安装的 Windows 服务",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := mgr.Connect()
+			if err != nil {
+				return fmt.Errorf("连接服务控制管理器失败: %w", err)
+			}
+			defer m.Disconnect()
+
+			s, err := m.OpenService(serviceName)
+			if err != nil {
+				return fmt.Errorf("打开服务失败: %w", err)
+			}
+			defer s.Close()
+
+			if err := s.Start(); err != nil {
+				return fmt.Errorf("启动服务失败: %w", err)
+			}
+			fmt.Printf("服务 %s 已启动\n", serviceName)
+			return nil
+		},
+	}
+}
+
+// newServiceStopCommand 发送Stop控制请求，并等待服务进入Stopped状态（与 NewStopCommand 的超时轮询风格一致）
+func newServiceStopCommand() *cobra.Command {
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "停止正在运行的 Windows 服务",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := mgr.Connect()
+			if err != nil {
+				return fmt.Errorf("连接服务控制管理器失败: %w", err)
+			}
+			defer m.Disconnect()
+
+			s, err := m.OpenService(serviceName)
+			if err != nil {
+				return fmt.Errorf("打开服务失败: %w", err)
+			}
+			defer s.Close()
+
+			status, err := s.Control(svc.Stop)
+			if err != nil {
+				return fmt.Errorf("发送停止请求失败: %w", err)
+			}
+
+			deadline := time.Now().Add(timeout)
+			for status.State != svc.Stopped && time.Now().Before(deadline) {
+				time.Sleep(200 * time.Millisecond)
+				if status, err = s.Query(); err != nil {
+					return fmt.Errorf("查询服务状态失败: %w", err)
+				}
+			}
+			if status.State != svc.Stopped {
+				return fmt.Errorf("等待服务停止超时")
+			}
+			fmt.Printf("服务 %s 已停止\n", serviceName)
+			return nil
+		},
+	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "等待服务停止的最长时间")
+	return cmd
+}
+
+// newServiceStatusCommand 查询服务当前状态
+func newServiceStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "查询 Windows 服务运行状态",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := mgr.Connect()
+			if err != nil {
+				return fmt.Errorf("连接服务控制管理器失败: %w", err)
+			}
+			defer m.Disconnect()
+
+			s, err := m.OpenService(serviceName)
+			if err != nil {
+				fmt.Printf("服务 %s 未安装\n", serviceName)
+				return nil
+			}
+			defer s.Close()
+
+			status, err := s.Query()
+			if err != nil {
+				return fmt.Errorf("查询服务状态失败: %w", err)
+			}
+			fmt.Printf("服务 %s: %s\n", serviceName, serviceStateName(status.State))
+			return nil
+		},
+	}
+}
+
+// newServiceRunCommand 是服务控制管理器实际启动的隐藏入口，不应由用户手动执行
+func newServiceRunCommand(staticFiles embed.FS) *cobra.Command {
+	return &cobra.Command{
+		Use:    "run",
+		Short:  "以 Windows 服务方式运行（由服务控制管理器调用）",
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return svc.Run(serviceName, &windowsService{staticFiles: staticFiles})
+		},
+	}
+}
+
+// windowsService 实现 svc.Handler，把SCM下发的 Stop/Shutdown 控制请求
+// 转发给 Run 中与Unix共用的优雅关闭路径（见 server.go 的 TriggerShutdown），
+// 而不是另起一套独立的关闭逻辑；Interrogate 只回报当前状态
+type windowsService struct {
+	staticFiles embed.FS
+}
+
+func (w *windowsService) Execute(_ []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (svcSpecificEC bool, exitCode uint32) {
+	const accepted = svc.AcceptStop | svc.AcceptShutdown
+
+	s <- svc.Status{State: svc.StartPending}
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- Run(w.staticFiles, nil)
+	}()
+
+	s <- svc.Status{State: svc.Running, Accepts: accepted}
+
+loop:
+	for {
+		select {
+		case err := <-runErrCh:
+			if err != nil {
+				s <- svc.Status{State: svc.Stopped}
+				return false, 1
+			}
+			break loop
+		case req := <-r:
+			switch req.Cmd {
+			case svc.Interrogate:
+				s <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				s <- svc.Status{State: svc.StopPending}
+				TriggerShutdown()
+			}
+		}
+	}
+
+	s <- svc.Status{State: svc.Stopped}
+	return false, 0
+}
+
+// serviceStateName 把 svc.State 渲染为人可读的中文描述
+func serviceStateName(state svc.State) string {
+	switch state {
+	case svc.Stopped:
+		return "已停止"
+	case svc.StartPending:
+		return "正在启动"
+	case svc.StopPending:
+		return "正在停止"
+	case svc.Running:
+		return "运行中"
+	default:
+		return fmt.Sprintf("未知(%d)", state)
+	}
+}
@@ -0,0 +1,307 @@
+package stress
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+)
+
+// NewCommand 创建 stress 子命令：模拟多个学员并发通过 /ws/sql 执行SQL语句，
+// 报告延迟分布（p50/p90/p99）、吞吐量与错误率。
+// 这填补了一个真实的盲区：此前的WebSocket测试只覆盖单连接的ping/pong与一次非法init消息
+// （见 TestSQLWebSocketPingPong），对“单实例能承载多少并发学员”给不出任何信号；
+// 同时也借此对 sql.DriverManager 的并发路径（已由 TestDriverManagerConcurrentAccess
+// 验证过正确性）施加真实负载，观测其承载能力
+func NewCommand() *cobra.Command {
+	var (
+		target      string
+		concurrency int
+		total       int
+		duration    time.Duration
+		courseID    string
+		scriptPath  string
+		format      string
+		stmtTimeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stress",
+		Short: "对SQL WebSocket施加并发负载并报告延迟/吞吐",
+		Long: `模拟 --concurrency 个虚拟学员并发打开 /ws/sql 连接，依次发送 --script 中的SQL语句，
+逐条测量往返延迟，汇总为 p50/p90/p99 延迟、吞吐量（请求数/秒）与错误率。
+--total 控制每个虚拟用户执行的脚本轮数，--duration 控制按时长停止（二者至少指定一个，
+同时指定时两者任一先达到即停止）。--format=json 可供CI记录基线并比较回归。`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if concurrency <= 0 {
+				return fmt.Errorf("--concurrency 必须为正整数")
+			}
+			if total <= 0 && duration <= 0 {
+				return fmt.Errorf("必须指定 --total 或 --duration 之一")
+			}
+			if courseID == "" {
+				return fmt.Errorf("必须通过 --course-id 指定目标课程")
+			}
+			if scriptPath == "" {
+				return fmt.Errorf("必须通过 --script 指定SQL脚本文件")
+			}
+
+			statements, err := loadScript(scriptPath)
+			if err != nil {
+				return fmt.Errorf("读取 --script 失败: %w", err)
+			}
+
+			report := run(runOptions{
+				target:      target,
+				concurrency: concurrency,
+				total:       total,
+				duration:    duration,
+				courseID:    courseID,
+				statements:  statements,
+				stmtTimeout: stmtTimeout,
+			})
+
+			if format == "json" {
+				data, err := json.MarshalIndent(report, "", "  ")
+				if err != nil {
+					return fmt.Errorf("序列化结果失败: %w", err)
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			fmt.Println(renderTable(report))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&target, "url", "ws://127.0.0.1:3006", "目标服务地址（ws://或wss://），不含路径")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 10, "并发虚拟用户数")
+	cmd.Flags().IntVar(&total, "total", 0, "每个虚拟用户执行脚本的轮数，与 --duration 至少指定一个")
+	cmd.Flags().DurationVar(&duration, "duration", 0, "压测持续时长（如 30s、2m），与 --total 至少指定一个")
+	cmd.Flags().StringVar(&courseID, "course-id", "", "init消息携带的courseId（必填，决定沙箱/鉴权范围）")
+	cmd.Flags().StringVar(&scriptPath, "script", "", "每行一条SQL语句的脚本文件（必填，以 -- 开头的行视为注释）")
+	cmd.Flags().StringVar(&format, "format", "text", "结果输出格式: text|json")
+	cmd.Flags().DurationVar(&stmtTimeout, "stmt-timeout", 10*time.Second, "单条语句的最长等待时间")
+
+	return cmd
+}
+
+// runOptions 描述一次压测的全部参数
+type runOptions struct {
+	target      string
+	concurrency int
+	total       int
+	duration    time.Duration
+	courseID    string
+	statements  []string
+	stmtTimeout time.Duration
+}
+
+// Report 一次压测的汇总结果，--format=json 下直接序列化为该结构，供CI做回归比较
+type Report struct {
+	Concurrency   int     `json:"concurrency"`
+	TotalRequests int64   `json:"totalRequests"`
+	ErrorCount    int64   `json:"errorCount"`
+	ElapsedMs     int64   `json:"elapsedMs"`
+	ThroughputRPS float64 `json:"throughputRps"`
+	P50Ms         float64 `json:"p50Ms"`
+	P90Ms         float64 `json:"p90Ms"`
+	P99Ms         float64 `json:"p99Ms"`
+}
+
+// queryCounter 为每条语句生成全局唯一的queryId，虚拟用户间共享
+var queryCounter int64
+
+// run 按 opts 启动 opts.concurrency 个虚拟用户并发压测，阻塞直至全部完成
+func run(opts runOptions) Report {
+	var (
+		mu         sync.Mutex
+		latencies  []time.Duration
+		errorCount int64
+		totalCount int64
+	)
+
+	var deadline time.Time
+	if opts.duration > 0 {
+		deadline = time.Now().Add(opts.duration)
+	}
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			conn, err := dialSQL(opts.target, opts.stmtTimeout)
+			if err != nil {
+				atomic.AddInt64(&errorCount, 1)
+				return
+			}
+			defer conn.Close()
+
+			if err := initSession(conn, opts.courseID, opts.stmtTimeout); err != nil {
+				atomic.AddInt64(&errorCount, 1)
+				return
+			}
+
+			for round := 0; opts.total <= 0 || round < opts.total; round++ {
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					return
+				}
+				for _, stmt := range opts.statements {
+					if !deadline.IsZero() && time.Now().After(deadline) {
+						return
+					}
+					elapsed, err := execOne(conn, stmt, opts.stmtTimeout)
+					atomic.AddInt64(&totalCount, 1)
+					if err != nil {
+						atomic.AddInt64(&errorCount, 1)
+						continue
+					}
+					mu.Lock()
+					latencies = append(latencies, elapsed)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report := Report{
+		Concurrency:   opts.concurrency,
+		TotalRequests: totalCount,
+		ErrorCount:    errorCount,
+		ElapsedMs:     elapsed.Milliseconds(),
+		P50Ms:         percentileMs(latencies, 0.50),
+		P90Ms:         percentileMs(latencies, 0.90),
+		P99Ms:         percentileMs(latencies, 0.99),
+	}
+	if elapsed.Seconds() > 0 {
+		report.ThroughputRPS = float64(totalCount) / elapsed.Seconds()
+	}
+	return report
+}
+
+// percentileMs 计算已排序延迟切片的第p分位数（0<p<=1），单位毫秒
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return float64(sorted[idx]) / float64(time.Millisecond)
+}
+
+// dialSQL 以普通WebSocket客户端身份连接 /ws/sql，不走鉴权（压测面向未启用JWT的本地/CI场景）
+func dialSQL(target string, handshakeTimeout time.Duration) (*websocket.Conn, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = "/ws/sql"
+
+	dialer := websocket.Dialer{HandshakeTimeout: handshakeTimeout}
+	conn, _, err := dialer.Dial(u.String(), nil)
+	return conn, err
+}
+
+// initSession 发送init消息并等待ready/error响应，与 handleSqlWebSocket 的协议一致
+func initSession(conn *websocket.Conn, courseID string, timeout time.Duration) error {
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type":     "init",
+		"courseId": courseID,
+	}); err != nil {
+		return err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	var resp map[string]interface{}
+	if err := conn.ReadJSON(&resp); err != nil {
+		return fmt.Errorf("等待init响应失败: %w", err)
+	}
+	if resp["type"] == "error" {
+		return fmt.Errorf("init失败: %v", resp["message"])
+	}
+	return nil
+}
+
+// execOne 发送一条query消息并等待complete/error，返回从发送到收到结束帧的耗时；
+// 期间的columns/rows等中间帧被忽略，只用于驱动读循环前进
+func execOne(conn *websocket.Conn, stmt string, timeout time.Duration) (time.Duration, error) {
+	queryID := fmt.Sprintf("stress-%d", atomic.AddInt64(&queryCounter, 1))
+
+	start := time.Now()
+	if err := conn.WriteJSON(map[string]interface{}{
+		"type":    "query",
+		"queryId": queryID,
+		"sql":     stmt,
+	}); err != nil {
+		return 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		_ = conn.SetReadDeadline(deadline)
+		var resp map[string]interface{}
+		if err := conn.ReadJSON(&resp); err != nil {
+			return time.Since(start), err
+		}
+		switch resp["type"] {
+		case "complete":
+			return time.Since(start), nil
+		case "error":
+			return time.Since(start), fmt.Errorf("%v", resp["message"])
+		}
+	}
+}
+
+// loadScript 读取脚本文件，按行拆分为语句；跳过空行与以 -- 开头的注释行
+func loadScript(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		statements = append(statements, strings.TrimSuffix(line, ";"))
+	}
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("脚本文件中未找到任何SQL语句: %s", path)
+	}
+	return statements, nil
+}
+
+// renderTable 把Report渲染为对齐的终端表格
+func renderTable(r Report) string {
+	errRate := 0.0
+	if r.TotalRequests > 0 {
+		errRate = float64(r.ErrorCount) / float64(r.TotalRequests) * 100
+	}
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "并发数\t总请求数\t错误数\t错误率\t耗时\t吞吐(req/s)\tp50(ms)\tp90(ms)\tp99(ms)")
+	fmt.Fprintf(w, "%d\t%d\t%d\t%.2f%%\t%dms\t%.2f\t%.2f\t%.2f\t%.2f\n",
+		r.Concurrency, r.TotalRequests, r.ErrorCount, errRate, r.ElapsedMs, r.ThroughputRPS, r.P50Ms, r.P90Ms, r.P99Ms)
+	w.Flush()
+	return b.String()
+}
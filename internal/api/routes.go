@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -11,16 +12,30 @@ import (
 	"sync"
 	"time"
 
+	"kwdb-playground/internal/audit"
+	"kwdb-playground/internal/backend"
 	"kwdb-playground/internal/check"
 	"kwdb-playground/internal/config"
 	"kwdb-playground/internal/course"
 	"kwdb-playground/internal/docker"
 	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/metrics"
+	"kwdb-playground/internal/middleware/auth"
+	"kwdb-playground/internal/middleware/peercred"
+	"kwdb-playground/internal/probe"
+	"kwdb-playground/internal/recording"
+	"kwdb-playground/internal/registry"
+	"kwdb-playground/internal/session"
+	"kwdb-playground/internal/snapshot"
 	sql "kwdb-playground/internal/sql"
+	"kwdb-playground/internal/sqlparse"
 	ws "kwdb-playground/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Handler API处理器
@@ -41,8 +56,71 @@ type Handler struct {
 	// sqlDriver KWDB 连接驱动（SQL 终端使用）
 	sqlDriver *sql.Driver
 
+	// auditLogger 变更类操作的结构化审计日志（容器/SQL），见 auditAction
+	auditLogger *audit.Logger
+
+	// sessionManager 多租户会话管理器，nil 表示未启用会话隔离（单用户/历史行为）
+	sessionManager *session.Manager
+
+	// kubernetesRuntime 课程 backend.runtime 为 "kubernetes" 时使用的运行时后端，nil 表示未配置（退化为报错）
+	kubernetesRuntime backend.Runtime
+
+	// registryManager 课程镜像的多源解析与可用性探测，依据 cfg.Registries 构建
+	registryManager *registry.Manager
+
+	// imageWarmer 启动阶段预热课程镜像可用性的结果缓存，nil 表示未启用（如 Docker 不可用时）
+	imageWarmer *docker.ImageWarmer
+
+	// authVerifier JWT鉴权验签器，nil 表示未启用鉴权（单用户/历史行为），由 requireAuth 等中间件按需跳过校验
+	authVerifier *auth.Verifier
+
 	// containerMutex 容器操作互斥锁，防止并发创建/删除容器
 	containerMutex sync.Mutex
+
+	// startedAt 进程启动时间，用于 /api/health 上报运行时长，供 `kwdb-playground status` 展示
+	startedAt time.Time
+}
+
+// SetStartedAt 记录进程启动时间，供 /api/health 计算uptime；未调用时默认取NewHandler构造的时刻
+func (h *Handler) SetStartedAt(t time.Time) {
+	h.startedAt = t
+}
+
+// SetKubernetesRuntime 注入 Kubernetes 运行时后端，使 backend.runtime: kubernetes 的课程可以被调度到共享集群
+// 未调用本方法时，此类课程在启动阶段会返回错误
+func (h *Handler) SetKubernetesRuntime(rt backend.Runtime) {
+	h.kubernetesRuntime = rt
+}
+
+// SetSessionManager 注入会话管理器，启用多租户会话隔离
+// 未调用本方法时，容器按课程维度隔离（兼容单用户部署）
+func (h *Handler) SetSessionManager(sm *session.Manager) {
+	h.sessionManager = sm
+}
+
+// SetImageWarmer 注入镜像预热器，使 GET /api/images/status 可以返回启动阶段的探测结果
+// 未调用本方法时，该接口返回空列表
+func (h *Handler) SetImageWarmer(w *docker.ImageWarmer) {
+	h.imageWarmer = w
+}
+
+// RegistryManager 返回本Handler持有的镜像源管理器，供调用方（如 cmd/server 构造 docker.ImageWarmer）
+// 复用同一份已加载的静态/自定义镜像源，而不是各自重复从磁盘加载一份互不感知增删的副本
+func (h *Handler) RegistryManager() *registry.Manager {
+	return h.registryManager
+}
+
+// sessionIDFor 返回当前请求应使用的会话ID
+// 未启用会话管理器时返回空字符串，调用方据此退化为课程级隔离
+func (h *Handler) sessionIDFor(c *gin.Context) string {
+	if h.sessionManager == nil {
+		return ""
+	}
+	sess := session.Get(c)
+	if sess == nil {
+		return ""
+	}
+	return sess.ID
 }
 
 // NewHandler 创建新的API处理器
@@ -61,13 +139,74 @@ func NewHandler(
 	logger *logger.Logger,
 	cfg *config.Config,
 ) *Handler {
-	return &Handler{
+	sqlDriver := &sql.Driver{}
+	sqlDriver.SetMetricsRecorder(metrics.DefaultRecorder)
+	dockerController.SetMetricsRecorder(metrics.DefaultRecorder)
+	// 把playground配置文件里per-registry的静态用户名/密码注册为拉取鉴权链的一环，
+	// 优先级高于默认的 ~/.docker/config.json，供没有部署docker CLI凭据体系的环境使用
+	dockerController.SetRegistryAuthProvider(docker.NewStaticRegistryAuthProvider(cfg.Registries))
+
+	h := &Handler{
 		courseService:    courseService,
 		dockerController: dockerController,
 		terminalManager:  terminalManager,
 		logger:           logger,
 		cfg:              cfg,
-		sqlDriver:        &sql.Driver{},
+		sqlDriver:        sqlDriver,
+		registryManager:  registry.NewManager(cfg.Registries, cfg.DataDir),
+		auditLogger:      audit.NewLogger(cfg.Audit, logger),
+		startedAt:        time.Now(),
+	}
+
+	// cfg.Auth.Enabled 为 false（默认）时 authVerifier 保持 nil，requireAuth 据此放行所有请求（单用户/历史行为）
+	if cfg.Auth.Enabled {
+		verifier, err := auth.NewVerifier(cfg.Auth.Algorithm, cfg.Auth.HMACSecret, cfg.Auth.RSAPublicKeyPEM)
+		if err != nil {
+			logger.Error("初始化JWT鉴权失败，鉴权中间件将不会生效: %v", err)
+		} else {
+			h.authVerifier = verifier
+		}
+	}
+
+	return h
+}
+
+// requireAuth 校验请求携带的JWT并把解析出的Claims写入Gin上下文，供 auth.RequireRole/auth.RequireCourseAccess 使用
+// h.authVerifier 为 nil（鉴权未启用）时直接放行，保持单用户/历史行为；
+// WebSocket升级路由上该中间件先于 Upgrade（及其 CheckOrigin）执行，未通过鉴权的请求不会建立连接
+func (h *Handler) requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.authVerifier == nil {
+			c.Next()
+			return
+		}
+		token := auth.TokenFromRequest(c.Request)
+		claims, err := h.authVerifier.Parse(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("鉴权失败: %v", err)})
+			return
+		}
+		auth.SetClaims(c, claims)
+		c.Next()
+	}
+}
+
+// requireAdmin 用于销毁类管理接口（如清理全部容器）：经由unix://监听地址到达的本地调用方直接放行，
+// 免去签发JWT的必要（对应 server.Run 新增的 peercred 中间件，类似dockerd对unix socket调用方的本地信任模型）；
+// 其余情况（TCP或鉴权未启用场景下allocated的其他入口）退化为 requireAuth + teacher 角色校验，维持历史行为
+func (h *Handler) requireAdmin() gin.HandlerFunc {
+	requireAuth := h.requireAuth()
+	requireTeacher := auth.RequireRole("teacher")
+	return func(c *gin.Context) {
+		if peercred.IsLocalPeer(c) {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+		if c.IsAborted() {
+			return
+		}
+		requireTeacher(c)
 	}
 }
 
@@ -79,51 +218,140 @@ func NewHandler(
 func (h *Handler) SetupRoutes(r *gin.Engine) {
 	// 健康检查路由（根级别）
 	r.GET("/health", h.healthCheck)
+	// Prometheus 指标端点
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// 会话中间件：为每个请求签发/校验会话Cookie，使容器操作按会话隔离
+	// 未注入 sessionManager 时（SetSessionManager 未被调用）不启用，保持单用户历史行为
+	if h.sessionManager != nil {
+		r.Use(h.sessionManager.Middleware())
+	}
 
 	api := r.Group("/api")
 	{
 		// 环境检测
 		api.GET("/check", h.envCheck)
+		// 详细健康状态：供 `kwdb-playground status` 子命令查询，/health 仅用于存活探测
+		api.GET("/health", h.getHealthDetails)
 
 		// 课程相关路由
 		courses := api.Group("/courses")
 		{
 			courses.GET("", h.getCourses)
 			courses.GET("/:id", h.getCourse)
-			courses.POST("/:id/start", h.startCourse)
-			courses.POST("/:id/stop", h.stopCourse)
-			// 端口冲突检查和容器清理接口
-			courses.GET("/:id/check-port-conflict", h.checkPortConflict)
-			courses.POST("/:id/cleanup-containers", h.cleanupCourseContainers)
+			courses.POST("/:id/start", h.auditAction("start_course", false, h.startCourse))
+			courses.POST("/:id/stop", h.auditAction("stop_course", false, h.stopCourse))
+			// 端口冲突检查和容器清理接口：仅课程本身有权限的用户（或teacher）可访问
+			courses.GET("/:id/check-port-conflict", h.requireAuth(), auth.RequireCourseAccess("id"), h.checkPortConflict)
+			courses.POST("/:id/cleanup-containers", h.requireAuth(), auth.RequireCourseAccess("id"), h.auditAction("cleanup_course_containers", false, h.cleanupCourseContainers))
+			// 就绪事件流：与 startCourse 的同步阻塞等价，供希望增量展示"启动中"进度的客户端使用
+			courses.GET("/:id/events", h.getCourseEvents)
+			// 快照：commit 容器文件系统 + 打包 backend.volumes 卷数据，用于课程进度的保存与恢复
+			courses.POST("/:id/snapshot", h.auditAction("snapshot_course", false, h.snapshotCourse))
+			courses.POST("/:id/restore", h.auditAction("restore_course", false, h.restoreCourse))
+			courses.GET("/:id/snapshots", h.listCourseSnapshots)
+			// 终端会话录制：列出该课程下已落盘的 .cast 文件，供讲师审计页面列出可回放/下载的会话
+			courses.GET("/:id/recordings", h.listCourseRecordings)
+			// 课程素材文件（如 data/rdb.tar.gz 等种子数据包），支持 If-None-Match 条件请求
+			courses.GET("/:id/files/*filepath", h.getCourseFile)
+			// 课程终端：按课程ID自动定位运行中的容器并启动交互式shell，供步骤页"在终端中尝试"按钮使用
+			courses.GET("/:id/terminal", h.requireAuth(), auth.RequireCourseAccess("id"), h.handleCourseTerminalWebSocket)
 		}
 
 		// 容器相关路由
 		containers := api.Group("/containers")
 		{
 			containers.GET("", h.getAllContainers)
-			containers.DELETE("", h.cleanupAllContainers)
+			// 销毁全部容器：本地unix socket调用方免鉴权直接放行，其余按teacher角色校验JWT（见 requireAdmin）
+			containers.DELETE("", h.requireAdmin(), h.auditAction("cleanup_all_containers", false, h.cleanupAllContainers))
 			containers.GET("/:id/status", h.getContainerStatus)
 			containers.GET("/:id/logs", h.getContainerLogs)
-			containers.POST("/:id/restart", h.restartContainer)
-			containers.POST("/:id/stop", h.stopContainerByID)
+			containers.GET("/:id/events", h.getContainerEvents)
+			containers.POST("/:id/restart", h.auditAction("container_restart", true, h.restartContainer))
+			// 按容器ID停止任意容器不受课程范围限制，仅限teacher角色操作
+			containers.POST("/:id/stop", h.requireAdmin(), h.auditAction("container_stop", true, h.stopContainerByID))
 		}
 
 		// 镜像相关路由
 		images := api.Group("/images")
 		{
 			images.POST("/check-availability", h.checkImageAvailability)
+			images.GET("/status", h.getImageWarmStatus)
 			images.GET("/sources", h.getImageSources)
+			images.POST("/sources", h.addImageSource)
+			images.DELETE("/sources/:id", h.deleteImageSource)
+		}
+
+		// 管理类接口：日志级别热调整，鉴权与容器清理等销毁类接口一致（本地unix socket调用方豁免，其余需teacher角色）
+		admin := api.Group("/admin")
+		{
+			admin.POST("/log-level", h.requireAdmin(), h.setLogLevel)
+			admin.POST("/prewarm", h.requireAdmin(), h.prewarmImage)
+			admin.GET("/courses/:courseId/usage", h.requireAdmin(), h.getCourseUsage)
+			admin.POST("/registry/login", h.requireAdmin(), h.registryLogin)
+			admin.POST("/registry/logout", h.requireAdmin(), h.registryLogout)
+		}
+
+		// 进度事件：面向课堂监控看板的只读展示接口，不做teacher限制（与 /ws/sql 等一致，
+		// 依赖部署方自行控制网络可达性），SSE是无法升级WebSocket的浏览器/代理环境下的兜底
+		progress := api.Group("/progress")
+		{
+			progress.GET("/stream", h.getProgressStream)
 		}
 
 		// SQL 信息与健康（REST 信息类）
 		api.GET("/sql/info", h.sqlInfo)
 		api.GET("/sql/health", h.sqlHealth)
+		// 结构化SQL执行（分页结果集 + EXPLAIN 预览）
+		api.POST("/sql/query", h.auditAction("sql_query", false, h.sqlQuery))
+
+		// 终端会话录制：下载 .cast 文件、以 WebSocket 方式按原始帧间隔回放
+		terminalSessions := api.Group("/terminal/sessions")
+		{
+			terminalSessions.GET("/:id/recording", h.downloadTerminalRecording)
+			terminalSessions.GET("/:id/replay", h.replayTerminalRecording)
+		}
 	}
 
 	// WebSocket路由
-	r.GET("/ws/terminal", h.handleTerminalWebSocket)
-	// SQL WebSocket 路由（与Shell终端操作方式一致）
-	r.GET("/ws/sql", h.handleSqlWebSocket)
+	// 鉴权中间件先于Upgrade执行：未携带有效令牌（或无权访问该课程）的升级请求在CheckOrigin之前即被拒绝
+	r.GET("/ws/terminal", h.requireAuth(), auth.RequireCourseAccess("course_id"), h.handleTerminalWebSocket)
+	// SQL WebSocket 路由（与Shell终端操作方式一致）；courseId 随 {type:"init"} 消息在升级后才送达，
+	// 课程级权限校验在 handleSqlWebSocket 内收到 init 消息时进行
+	r.GET("/ws/sql", h.requireAuth(), h.handleSqlWebSocket)
+	// SQL 结果流式推送（用于大结果集分块返回，避免一次性缓冲）；courseId 随查询参数在升级前即已知，
+	// 不像 /ws/sql 需要等 init 消息，故直接用 RequireCourseAccess 中间件而非在handler内延后校验
+	r.GET("/ws/sql/stream", h.requireAuth(), auth.RequireCourseAccess("courseId"), h.handleSqlStreamWebSocket)
+	// 进度事件推送：课堂监控看板订阅 started/advanced/completed/reset 事件，
+	// 可选 user_id/course_id 查询参数按维度过滤
+	r.GET("/ws/progress", h.handleProgressWebSocket)
+}
+
+// auditAction 包装一个变更类接口的 handler，记录其耗时与结果为一条结构化审计日志
+// idIsContainer 为 true 时路径参数 :id 记为 ContainerID（容器相关路由），否则记为 CourseID（课程相关路由）；
+// 没有 :id 的路由（如批量清理）二者皆为空，仍会记录 Action/Outcome/DurationMs
+func (h *Handler) auditAction(action string, idIsContainer bool, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		handler(c)
+
+		entry := audit.Entry{
+			SessionID:  h.sessionIDFor(c),
+			Action:     action,
+			DurationMs: time.Since(start).Milliseconds(),
+			Outcome:    "success",
+		}
+		if idIsContainer {
+			entry.ContainerID = c.Param("id")
+		} else {
+			entry.CourseID = c.Param("id")
+		}
+		if c.Writer.Status() >= http.StatusBadRequest {
+			entry.Outcome = "error"
+			entry.ErrorClass = fmt.Sprintf("http_%d", c.Writer.Status())
+		}
+		h.auditLogger.Record(entry)
+	}
 }
 
 // sqlInfo 返回KWDB连接信息（版本、端口、架构、编译时间、连接状态）
@@ -212,6 +440,302 @@ func (h *Handler) sqlHealth(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "latency": time.Since(start).String()})
 }
 
+// sqlQueryDefaultMaxRows 结构化SQL查询默认返回行数上限
+const sqlQueryDefaultMaxRows = 500
+
+// sqlQueryHardMaxRows 结构化SQL查询允许的最大行数上限，防止前端误传过大的值拖垮内存
+const sqlQueryHardMaxRows = 5000
+
+// sqlQueryDefaultTimeoutMs 结构化SQL查询默认语句超时（毫秒）
+const sqlQueryDefaultTimeoutMs = 10000
+
+// sqlQueryRequest POST /api/sql/query 请求体
+type sqlQueryRequest struct {
+	CourseID  string        `json:"courseId" binding:"required"`
+	SQL       string        `json:"sql" binding:"required"`
+	Params    []interface{} `json:"params,omitempty"`
+	MaxRows   int           `json:"maxRows,omitempty"`
+	TimeoutMs int           `json:"timeoutMs,omitempty"`
+	Format    string        `json:"format,omitempty"` // json（默认） | explain
+}
+
+// sqlColumn 结构化结果集的列描述
+type sqlColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	OID      uint32 `json:"oid"`
+	Nullable bool   `json:"nullable"`
+}
+
+// pgOIDTypeName 返回常见 PostgreSQL/KWDB OID 对应的类型名，未知OID以数字形式返回
+// 仅覆盖课程场景中常见的类型，足以让前端渲染合适的列
+func pgOIDTypeName(oid uint32) string {
+	switch oid {
+	case 16:
+		return "bool"
+	case 20:
+		return "int8"
+	case 21:
+		return "int2"
+	case 23:
+		return "int4"
+	case 25:
+		return "text"
+	case 700:
+		return "float4"
+	case 701:
+		return "float8"
+	case 1042:
+		return "bpchar"
+	case 1043:
+		return "varchar"
+	case 1082:
+		return "date"
+	case 1114:
+		return "timestamp"
+	case 1184:
+		return "timestamptz"
+	case 1700:
+		return "numeric"
+	case 2950:
+		return "uuid"
+	default:
+		return fmt.Sprintf("oid:%d", oid)
+	}
+}
+
+// prepareSQLQuery 校验请求参数，解析课程并确保底层连接池就绪
+// 返回课程对象以及规范化后的 maxRows/timeoutMs
+func (h *Handler) prepareSQLQuery(ctx context.Context, req *sqlQueryRequest) (*course.Course, int, int, error) {
+	if strings.TrimSpace(req.CourseID) == "" {
+		return nil, 0, 0, fmt.Errorf("缺少 courseId")
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		return nil, 0, 0, fmt.Errorf("缺少 sql")
+	}
+	courseObj, exists := h.courseService.GetCourse(req.CourseID)
+	if !exists {
+		return nil, 0, 0, fmt.Errorf("课程不存在")
+	}
+
+	maxRows := req.MaxRows
+	if maxRows <= 0 {
+		maxRows = sqlQueryDefaultMaxRows
+	}
+	if maxRows > sqlQueryHardMaxRows {
+		maxRows = sqlQueryHardMaxRows
+	}
+
+	timeoutMs := req.TimeoutMs
+	if timeoutMs <= 0 {
+		timeoutMs = sqlQueryDefaultTimeoutMs
+	}
+
+	if err := h.sqlDriver.EnsureReady(ctx, courseObj); err != nil {
+		return nil, 0, 0, fmt.Errorf("KWDB未就绪: %w", err)
+	}
+
+	return courseObj, maxRows, timeoutMs, nil
+}
+
+// sqlQuery 执行结构化SQL查询，返回列信息与分页行数据
+// POST /api/sql/query
+// 请求体: {"courseId","sql","params","maxRows","timeoutMs","format"}
+// 响应:
+//
+//	200: {"columns":[...],"rows":[...],"rowCount":n,"hasMore":bool} 或 format=explain 时 {"format":"explain","plan":...}
+//	400: 参数错误
+//	404: 课程不存在
+//	500: 查询失败
+func (h *Handler) sqlQuery(c *gin.Context) {
+	var req sqlQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求参数解析失败: %v", err)})
+		return
+	}
+
+	reqCtx := c.Request.Context()
+	courseObj, maxRows, timeoutMs, err := h.prepareSQLQuery(reqCtx, &req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == "课程不存在" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	defer h.sqlDriver.RecordQuery(courseObj.ID, time.Now())
+
+	ctx, cancel := context.WithTimeout(reqCtx, time.Duration(timeoutMs)*time.Millisecond)
+	defer cancel()
+
+	pool := h.sqlDriver.Pool()
+	// 按课程会话设置语句超时，避免失控查询拖垮容器
+	if _, err := pool.Exec(ctx, fmt.Sprintf("SET statement_timeout = %d", timeoutMs)); err != nil {
+		h.logger.Warn("[sqlQuery] 设置 statement_timeout 失败: %v", err)
+	}
+
+	sqlText := req.SQL
+	if strings.EqualFold(strings.TrimSpace(req.Format), "explain") {
+		sqlText = fmt.Sprintf("EXPLAIN (ANALYZE, FORMAT JSON) %s", req.SQL)
+	}
+
+	rows, err := pool.Query(ctx, sqlText, req.Params...)
+	if err != nil {
+		h.logger.Error("[sqlQuery] 课程 %s 查询失败: %v", courseObj.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	fieldDescs := rows.FieldDescriptions()
+	columns := make([]sqlColumn, 0, len(fieldDescs))
+	for _, f := range fieldDescs {
+		columns = append(columns, sqlColumn{
+			Name:     string(f.Name),
+			Type:     pgOIDTypeName(f.DataTypeOID),
+			OID:      f.DataTypeOID,
+			Nullable: true, // pgx 未在字段描述中提供 NOT NULL 信息，保守地标记为可空
+		})
+	}
+
+	outRows := make([][]interface{}, 0, maxRows)
+	hasMore := false
+	for rows.Next() {
+		if len(outRows) >= maxRows {
+			hasMore = true
+			break
+		}
+		vals, err := rows.Values()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i, v := range vals {
+			if t, ok := v.(time.Time); ok {
+				vals[i] = t.Format(time.RFC3339)
+			}
+		}
+		outRows = append(outRows, vals)
+	}
+	if rows.Err() != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": rows.Err().Error()})
+		return
+	}
+
+	if strings.EqualFold(strings.TrimSpace(req.Format), "explain") {
+		c.JSON(http.StatusOK, gin.H{"format": "explain", "plan": outRows})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"columns":  columns,
+		"rows":     outRows,
+		"rowCount": len(outRows),
+		"hasMore":  hasMore,
+	})
+}
+
+// sqlStreamChunkSize 流式查询每帧推送的行数
+const sqlStreamChunkSize = 200
+
+// handleSqlStreamWebSocket 以WebSocket分块推送大结果集，避免一次性在内存中缓冲全部行
+// 查询参数: courseId, sql, maxRows, timeoutMs（用法与 POST /api/sql/query 一致）
+// 帧协议: {"type":"columns","columns":[...]} -> 多个 {"type":"chunk","rows":[...]} -> {"type":"done","rowCount":n,"hasMore":bool} / {"type":"error","message":...}
+// 鉴权：路由上挂了 h.requireAuth() 与 auth.RequireCourseAccess("courseId")，未携带有效令牌或无权访问
+// courseId 指定课程的请求在Upgrade之前即被拒绝，避免任意来源凭猜测courseId执行任意SQL
+func (h *Handler) handleSqlStreamWebSocket(c *gin.Context) {
+	courseID := c.Query("courseId")
+	sqlText := c.Query("sql")
+	maxRows, _ := strconv.Atoi(c.Query("maxRows"))
+	timeoutMs, _ := strconv.Atoi(c.Query("timeoutMs"))
+
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("[handleSqlStreamWebSocket] WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	req := sqlQueryRequest{CourseID: courseID, SQL: sqlText, MaxRows: maxRows, TimeoutMs: timeoutMs}
+	reqCtx := c.Request.Context()
+	courseObj, maxRows, timeoutMs, err := h.prepareSQLQuery(reqCtx, &req)
+	if err != nil {
+		_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(reqCtx, time.Duration(timeoutMs)*time.Millisecond)
+	defer cancel()
+
+	pool := h.sqlDriver.Pool()
+	if _, err := pool.Exec(ctx, fmt.Sprintf("SET statement_timeout = %d", timeoutMs)); err != nil {
+		h.logger.Warn("[handleSqlStreamWebSocket] 设置 statement_timeout 失败: %v", err)
+	}
+
+	rows, err := pool.Query(ctx, sqlText)
+	if err != nil {
+		_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": err.Error()})
+		return
+	}
+	defer rows.Close()
+
+	fieldDescs := rows.FieldDescriptions()
+	columns := make([]sqlColumn, 0, len(fieldDescs))
+	for _, f := range fieldDescs {
+		columns = append(columns, sqlColumn{Name: string(f.Name), Type: pgOIDTypeName(f.DataTypeOID), OID: f.DataTypeOID, Nullable: true})
+	}
+	_ = conn.WriteJSON(map[string]interface{}{"type": "columns", "columns": columns, "courseId": courseObj.ID})
+
+	chunk := make([][]interface{}, 0, sqlStreamChunkSize)
+	total := 0
+	hasMore := false
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := conn.WriteJSON(map[string]interface{}{"type": "chunk", "rows": chunk})
+		chunk = chunk[:0]
+		return err
+	}
+
+	for rows.Next() {
+		if total >= maxRows {
+			hasMore = true
+			break
+		}
+		vals, err := rows.Values()
+		if err != nil {
+			_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": err.Error()})
+			return
+		}
+		for i, v := range vals {
+			if t, ok := v.(time.Time); ok {
+				vals[i] = t.Format(time.RFC3339)
+			}
+		}
+		chunk = append(chunk, vals)
+		total++
+		if len(chunk) >= sqlStreamChunkSize {
+			if err := flush(); err != nil {
+				h.logger.Debug("[handleSqlStreamWebSocket] 客户端已断开: %v", err)
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return
+	}
+	if rows.Err() != nil {
+		_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": rows.Err().Error()})
+		return
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"type": "done", "rowCount": total, "hasMore": hasMore})
+}
+
 // getAllContainers 获取所有 Playground 容器
 func (h *Handler) getAllContainers(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -248,101 +772,408 @@ func (h *Handler) cleanupAllContainers(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
-// healthCheck 健康检查
-// 提供服务健康状态检查接口，用于监控和负载均衡
-// 响应: {"status": "ok", "message": "KWDB Playground is running"}
-func (h *Handler) healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "ok",
-		"message": "KWDB Playground is running",
-	})
-}
-
-// envCheck 环境检测，与 cmd/check 保持一致的检查逻辑但以 JSON 返回
-func (h *Handler) envCheck(c *gin.Context) {
-	if h.logger != nil {
-		h.logger.Info("Handling /api/check request")
+// setLogLevel 运行时调整全局日志级别，无需重启进程
+// POST /api/admin/log-level
+// 请求体: {"level": "debug|info|warn|error"}
+// 通过 logger.SetGlobalLevel 覆盖所有 Logger 的级别判定（包括已创建的实例，见 Logger.shouldLog），
+// 并广播给 logger.SubscribeLevelChanges 的订阅者
+func (h *Handler) setLogLevel(c *gin.Context) {
+	var req struct {
+		Level string `json:"level" binding:"required"`
 	}
-	if h.cfg == nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "配置未初始化"})
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: level 不能为空"})
 		return
 	}
-	// 使用共享的检查包进行检查，复用课程服务
-	items := make([]check.Item, 0, 4)
-
-	// Docker
-	dockerOK, dockerMsg := check.DockerEnv()
-	items = append(items, check.Item{Name: "Docker 环境", OK: dockerOK, Message: dockerMsg})
 
-	// 课程完整性（使用已加载的服务）
-	coursesOK, coursesMsg := check.CoursesIntegrity(h.courseService)
-	items = append(items, check.Item{Name: "课程加载与完整性", OK: coursesOK, Message: coursesMsg})
+	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+	if !validLevels[strings.ToLower(req.Level)] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "level 必须为 debug/info/warn/error 之一"})
+		return
+	}
 
-	// 服务健康
-	serviceOK, serviceMsg := check.ServiceHealth(h.cfg.Server.Host, h.cfg.Server.Port)
-	items = append(items, check.Item{Name: fmt.Sprintf("服务健康检查 (%s:%d)", h.cfg.Server.Host, h.cfg.Server.Port), OK: serviceOK, Message: serviceMsg})
+	level := logger.ParseLogLevel(req.Level)
+	logger.SetGlobalLevel(level)
+	h.logger.WithContext(c.Request.Context()).Info("[setLogLevel] 全局日志级别已更新为: %s", logger.LogLevelNames[level])
 
-	ok := true
-	for _, it := range items {
-		if !it.OK {
-			ok = false
-		}
-	}
-	c.JSON(http.StatusOK, check.Summary{OK: ok, Items: items})
+	c.JSON(http.StatusOK, gin.H{"level": logger.LogLevelNames[level]})
 }
 
-// getCourses 获取所有课程
-// 返回系统中所有可用课程的列表
-// 响应: {"courses": [courseObject, ...]}
-func (h *Handler) getCourses(c *gin.Context) {
-	coursesMap := h.courseService.GetCourses()
+// prewarmImage 为指定镜像预热n个空闲容器，写入 docker.Controller 的 PrewarmPool，
+// 提前把镜像拉取到本地；后续同镜像的课程容器创建命中池子时仍会按真实请求重新创建容器，
+// 省下的是镜像拉取等待而非容器创建本身
+func (h *Handler) prewarmImage(c *gin.Context) {
+	var req struct {
+		CourseID string `json:"courseId" binding:"required"`
+		Image    string `json:"image" binding:"required"`
+		N        int    `json:"n" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: courseId/image/n 均为必填"})
+		return
+	}
 
-	// 将map转换为数组格式，以便前端使用
-	coursesList := make([]*course.Course, 0, len(coursesMap))
-	for _, course := range coursesMap {
-		coursesList = append(coursesList, course)
+	config := &docker.ContainerConfig{Image: req.Image}
+	if err := h.dockerController.Prewarm(c.Request.Context(), req.CourseID, config, req.N); err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("[prewarmImage] 预热镜像 %s 失败: %v", req.Image, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("预热失败: %v", err)})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"courses": coursesList,
-	})
+	c.JSON(http.StatusOK, gin.H{"image": req.Image, "n": req.N})
 }
 
-// getCourse 获取指定课程
-// 根据课程ID获取课程详细信息，包括课程内容和步骤
-// 路径参数:
-//
-//	id: 课程ID
-//
-// 响应:
-//
-//	200: {"course": courseObject} - 课程详细信息
-//	400: {"error": "课程ID不能为空"} - 课程ID为空
-//	404: {"error": "课程不存在"} - 课程不存在
-func (h *Handler) getCourse(c *gin.Context) {
-	id := c.Param("id")
+// getCourseUsage 返回 docker.Controller 按课程聚合统计的实时资源用量（ResourceGovernor），
+// 该课程尚无正在被统计的容器时返回404
+func (h *Handler) getCourseUsage(c *gin.Context) {
+	courseID := c.Param("courseId")
 
-	// 验证课程ID不能为空
-	if strings.TrimSpace(id) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "课程ID不能为空",
-		})
+	usage, ok := h.dockerController.GetCourseUsage(courseID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("课程 %s 当前没有正在被统计的容器", courseID)})
 		return
 	}
 
-	course, exists := h.courseService.GetCourse(id)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "课程不存在",
-		})
+	c.JSON(http.StatusOK, usage)
+}
+
+// registryLogin 向指定镜像仓库校验一组凭据，成功后缓存进程内供后续拉取复用，镜像docker CLI的`docker login`
+func (h *Handler) registryLogin(c *gin.Context) {
+	var req struct {
+		Host     string `json:"host" binding:"required"`
+		Username string `json:"username" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: host/username/password 均为必填"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	status, err := h.dockerController.Login(c.Request.Context(), req.Host, req.Username, req.Password)
+	if err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("[registryLogin] 登录镜像仓库 %s 失败: %v", req.Host, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("登录失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"host": req.Host, "status": status})
+}
+
+// registryLogout 清除 registryLogin 为指定镜像仓库缓存的凭据
+func (h *Handler) registryLogout(c *gin.Context) {
+	var req struct {
+		Host string `json:"host" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: host 为必填"})
+		return
+	}
+
+	h.dockerController.Logout(req.Host)
+	c.JSON(http.StatusOK, gin.H{"host": req.Host})
+}
+
+// progressFilterFromQuery 从 user_id/course_id 查询参数构建过滤器，二者皆为空表示不过滤，
+// 供 /ws/progress 与 /api/progress/stream 共用
+func progressFilterFromQuery(c *gin.Context) course.ProgressEventFilter {
+	return course.ProgressEventFilter{
+		UserID:   c.Query("user_id"),
+		CourseID: c.Query("course_id"),
+	}
+}
+
+// handleProgressWebSocket 把 course.ProgressManager 的事件总线（started/advanced/completed/reset）
+// 推送给课堂监控看板，用法与其他WebSocket路由一致：用{type:"ping"}保活，服务端回{type:"pong"}；
+// 可选 user_id/course_id 查询参数按维度过滤，不落盘任何状态，纯粹是展示性的只读信号
+func (h *Handler) handleProgressWebSocket(c *gin.Context) {
+	filter := progressFilterFromQuery(c)
+
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("进度WebSocket升级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebSocket连接升级失败"})
+		return
+	}
+	defer conn.Close()
+
+	events, cancel := h.courseService.SubscribeProgress(filter)
+	defer cancel()
+
+	var writeMu sync.Mutex
+	writeJSON := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(v)
+	}
+
+	// 读循环只负责响应客户端的ping保活，连接关闭（ReadMessage报错）时通知写循环退出
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg["type"] == "ping" {
+				_ = writeJSON(map[string]interface{}{"type": "pong"})
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeJSON(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// getProgressStream 以 SSE 形式推送进度事件，供无法升级WebSocket的浏览器/代理环境使用，
+// 与 /ws/progress 共享同一个事件总线和 user_id/course_id 过滤参数
+func (h *Handler) getProgressStream(c *gin.Context) {
+	filter := progressFilterFromQuery(c)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	events, cancel := h.courseService.SubscribeProgress(filter)
+	defer cancel()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			c.SSEvent("message", ev)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// healthCheck 健康检查
+// 提供服务健康状态检查接口，用于监控和负载均衡
+// 响应: {"status": "ok", "message": "KWDB Playground is running"}
+func (h *Handler) healthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"message": "KWDB Playground is running",
+	})
+}
+
+// getHealthDetails 返回供运维查询的详细运行状态，是 `kwdb-playground status` 子命令的数据来源，
+// 与 /health（单纯的存活探测，供负载均衡使用）区分开
+// 响应: {"status","uptimeSeconds","courseCount","dockerAvailable","config":{"host","port","courseDir","useEmbed","runtimeType"}}
+func (h *Handler) getHealthDetails(c *gin.Context) {
+	dockerAvailable := h.dockerController != nil
+	if dockerAvailable {
+		if _, err := h.dockerController.ListContainers(c.Request.Context()); err != nil {
+			dockerAvailable = false
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":          "ok",
+		"uptimeSeconds":   int(time.Since(h.startedAt).Seconds()),
+		"courseCount":     len(h.courseService.GetCourses()),
+		"dockerAvailable": dockerAvailable,
+		"config": gin.H{
+			"host":        h.cfg.Server.Host,
+			"port":        h.cfg.Server.Port,
+			"courseDir":   h.cfg.Course.Dir,
+			"useEmbed":    h.cfg.Course.UseEmbed,
+			"runtimeType": h.cfg.Runtime.Type,
+		},
+	})
+}
+
+// envCheck 环境检测，与 cmd/check 保持一致的检查逻辑但以 JSON 返回
+func (h *Handler) envCheck(c *gin.Context) {
+	if h.logger != nil {
+		h.logger.Info("Handling /api/check request")
+	}
+	if h.cfg == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "配置未初始化"})
+		return
+	}
+	// 使用共享的检查包进行检查，复用课程服务
+	items := make([]check.Item, 0, 4)
+
+	// Docker
+	dockerOK, dockerMsg := check.DockerEnv()
+	dockerItem := check.Item{Name: "Docker 环境", Code: check.CodeOK, OK: dockerOK, Severity: check.SeverityInfo, Message: dockerMsg}
+	if !dockerOK {
+		dockerItem.Code, dockerItem.Severity = check.CodeDockerUnavailable, check.SeverityError
+	}
+	items = append(items, dockerItem)
+
+	// 课程完整性（使用已加载的服务）
+	coursesOK, coursesMsg := check.CoursesIntegrity(h.courseService)
+	coursesItem := check.Item{Name: "课程加载与完整性", Code: check.CodeOK, OK: coursesOK, Severity: check.SeverityInfo, Message: coursesMsg}
+	if !coursesOK {
+		coursesItem.Code, coursesItem.Severity = check.CodeCourseIntegrity, check.SeverityError
+	}
+	items = append(items, coursesItem)
+
+	// 服务健康
+	serviceOK, serviceMsg := check.ServiceHealth(h.cfg.Server.Host, h.cfg.Server.Port)
+	serviceItem := check.Item{Name: fmt.Sprintf("服务健康检查 (%s:%d)", h.cfg.Server.Host, h.cfg.Server.Port), Code: check.CodeOK, OK: serviceOK, Severity: check.SeverityInfo, Message: serviceMsg}
+	if !serviceOK {
+		serviceItem.Code, serviceItem.Severity = check.CodeServiceUnhealthy, check.SeverityError
+	}
+	items = append(items, serviceItem)
+
+	ok := true
+	for _, it := range items {
+		if !it.OK {
+			ok = false
+		}
+	}
+	summary := check.Summary{OK: ok, Items: items}
+
+	switch checkResponseFormat(c) {
+	case "junit":
+		out, err := check.RenderSummaryJUnit(summary)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "渲染JUnit结果失败: " + err.Error()})
+			return
+		}
+		c.String(http.StatusOK, out)
+	case "md":
+		c.String(http.StatusOK, check.RenderSummaryMarkdown(summary))
+	case "cli":
+		c.String(http.StatusOK, check.RenderSummaryCLI(summary))
+	default:
+		c.JSON(http.StatusOK, summary)
+	}
+}
+
+// checkResponseFormat 决定 GET /api/check 的响应格式：显式的 ?format= 查询参数优先，
+// 其次按 Accept 头粗略匹配，两者都未命中已知格式时回退到历史行为（JSON）
+func checkResponseFormat(c *gin.Context) string {
+	switch strings.ToLower(c.Query("format")) {
+	case "junit":
+		return "junit"
+	case "md", "markdown":
+		return "md"
+	case "cli", "text":
+		return "cli"
+	case "json":
+		return "json"
+	}
+
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "xml"):
+		return "junit"
+	case strings.Contains(accept, "markdown"):
+		return "md"
+	case strings.Contains(accept, "text/plain"):
+		return "cli"
+	default:
+		return "json"
+	}
+}
+
+// getCourses 获取所有课程
+// 返回系统中所有可用课程的列表
+// 响应: {"courses": [courseObject, ...]}
+func (h *Handler) getCourses(c *gin.Context) {
+	coursesMap := h.courseService.GetCourses()
+
+	// 将map转换为数组格式，以便前端使用
+	coursesList := make([]*course.Course, 0, len(coursesMap))
+	for _, course := range coursesMap {
+		coursesList = append(coursesList, course)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"courses": coursesList,
+	})
+}
+
+// getCourse 获取指定课程
+// 根据课程ID获取课程详细信息，包括课程内容和步骤
+// 路径参数:
+//
+//	id: 课程ID
+//
+// 响应:
+//
+//	200: {"course": courseObject} - 课程详细信息
+//	400: {"error": "课程ID不能为空"} - 课程ID为空
+//	404: {"error": "课程不存在"} - 课程不存在
+func (h *Handler) getCourse(c *gin.Context) {
+	id := c.Param("id")
+
+	// 验证课程ID不能为空
+	if strings.TrimSpace(id) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "课程ID不能为空",
+		})
+		return
+	}
+
+	course, exists := h.courseService.GetCourse(id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "课程不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
 		"course": course,
 	})
 }
 
+// containerResourcesFromCourse 把课程声明的DockerHostConfig转换为docker.ContainerResources；
+// 课程未声明MemoryMB/CPUShares/PidsLimit时，分别套用 serverCfg.SessionMemoryMB/SessionCPUShares/
+// SessionPidsLimit 作为默认值（而不是直接交给 applyResourcePolicy 的包级默认兜底），使运营方可以
+// 通过环境变量统一调整全部课程的默认配额，而不必逐个课程声明
+func containerResourcesFromCourse(cfg course.DockerHostConfig, serverCfg config.ServerConfig) *docker.ContainerResources {
+	ulimits := make([]docker.ContainerUlimit, 0, len(cfg.Ulimits))
+	for _, u := range cfg.Ulimits {
+		ulimits = append(ulimits, docker.ContainerUlimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	memoryMB := cfg.MemoryMB
+	if memoryMB == 0 {
+		memoryMB = int64(serverCfg.SessionMemoryMB)
+	}
+	cpuShares := cfg.CPUShares
+	if cpuShares == 0 {
+		cpuShares = int64(serverCfg.SessionCPUShares)
+	}
+	pidsLimit := cfg.PidsLimit
+	if pidsLimit == 0 {
+		pidsLimit = int64(serverCfg.SessionPidsLimit)
+	}
+	return &docker.ContainerResources{
+		CPUShares:      cpuShares,
+		CPUQuota:       cfg.CPUQuota,
+		MemoryMB:       memoryMB,
+		PidsLimit:      pidsLimit,
+		ReadOnlyRootfs: cfg.ReadOnlyRootfs,
+		CapDrop:        cfg.CapDrop,
+		SecurityOpt:    cfg.SecurityOpt,
+		Tmpfs:          cfg.Tmpfs,
+		Ulimits:        ulimits,
+	}
+}
+
 // startCourse 启动课程容器
 // 为指定课程启动Docker容器环境，提供隔离的实验环境
 // 路径参数:
@@ -531,8 +1362,9 @@ func (h *Handler) startCourse(c *gin.Context) {
 		Cmd:        cmd,                       // 根据课程配置的Cmd启动容器
 		Privileged: course.Backend.Privileged, // 根据课程配置的Privileged启动容器
 		Ports:      map[string]string{"26257": fmt.Sprintf("%d", course.Backend.Port)},
-		Volumes:    volumes, // 课程定义的卷绑定
-		Env:        env,     // 课程定义的环境变量
+		Volumes:    volumes,                                                             // 课程定义的卷绑定
+		Env:        env,                                                                 // 课程定义的环境变量
+		Resources:  containerResourcesFromCourse(course.DockerHostConfig, h.cfg.Server), // 课程声明的资源限制/加固选项，未声明字段套用SESSION_*默认值
 	}
 
 	h.logger.Debug("[startCourse] 创建容器配置完成，镜像: %s，工作目录: %s，Cmd: %v, Privileged: %v",
@@ -545,10 +1377,43 @@ func (h *Handler) startCourse(c *gin.Context) {
 		h.terminalManager.BroadcastImagePullProgress(progress)
 	}
 
-	// 创建容器 - 使用带进度回调的版本以支持镜像拉取进度显示
-	h.logger.Debug("[startCourse] 开始创建容器...")
+	// 课程配置了 kubernetes 运行时后端时，交由 backend.Runtime 处理，不走下面的 Docker 路径
+	if backend.KindForCourse(course.Backend.Runtime) == backend.RuntimeKubernetes {
+		h.startCourseOnKubernetes(c, ctx, course, config)
+		return
+	}
+
+	// 按镜像源优先级依次尝试拉取镜像（docker.io 不可达时回退到配置的私有仓库/离线 tar 包），
+	// 解析成功后用实际生效的镜像引用创建容器
+	resolvedImage, err := h.dockerController.EnsureImageAvailable(ctx, h.registryManager.List(), config.Image, progressCallback)
+	if err != nil {
+		h.logger.Error("[startCourse] 镜像 %s 在所有已配置的镜像源均不可用: %v", config.Image, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("镜像获取失败: %v", err),
+		})
+		return
+	}
+	config.Image = resolvedImage
 
-	containerInfo, err := h.dockerController.CreateContainerWithProgress(ctx, id, config, progressCallback)
+	// 创建容器 - 使用带进度回调的版本以支持镜像拉取进度显示
+	h.logger.Debug("[startCourse] 开始创建容器，最终镜像: %s...", config.Image)
+
+	sessionID := h.sessionIDFor(c)
+	var containerInfo *docker.ContainerInfo
+	if sessionID != "" {
+		// 会话隔离模式：先检查配额，再按会话命名创建容器
+		if err := h.sessionManager.ReserveContainer(sessionID, id); err != nil {
+			h.logger.Warn("[startCourse] 会话 %s 超出容器配额: %v", sessionID, err)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		containerInfo, err = h.dockerController.CreateContainerForSession(ctx, id, sessionID, config, progressCallback)
+		if err != nil {
+			h.sessionManager.ReleaseContainer(sessionID, id)
+		}
+	} else {
+		containerInfo, err = h.dockerController.CreateContainerWithProgress(ctx, id, config, progressCallback)
+	}
 	if err != nil {
 		h.logger.Error("[startCourse] 容器创建失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -575,11 +1440,300 @@ func (h *Handler) startCourse(c *gin.Context) {
 	}
 
 	h.logger.Info("[startCourse] 容器启动成功，课程ID: %s，容器ID: %s，镜像: %s", id, containerInfo.ID, imageName)
-	c.JSON(http.StatusOK, gin.H{
+
+	// 就绪门禁：容器处于运行态不代表 KWDB 已能接受连接，这里阻塞直至就绪探针通过（或达到失败阈值）
+	// 未配置 course.Backend.ReadinessProbe 时退化为对 backend.port 的 TCP 探测，取代此前 sqlInfo 里临时的轮询连接
+	readinessSpec := course.Backend.ReadinessProbe
+	if readinessSpec.IsZero() {
+		readinessSpec = defaultReadinessProbe(course.Backend.Port)
+	}
+	if err := probe.Wait(ctx, readinessSpec, containerInfo.ID, h.dockerController, nil); err != nil {
+		h.logger.Warn("[startCourse] 课程 %s 就绪探针未通过: %v", id, err)
+		c.JSON(http.StatusOK, gin.H{
+			"message":     "课程容器已启动，但就绪探针未通过，请稍后重试或查看 /events",
+			"courseId":    id,
+			"containerId": containerInfo.ID,
+			"ready":       false,
+		})
+		return
+	}
+
+	// 依赖服务（backend.services）按 DependsOn 拓扑顺序启动，失败不影响主容器，仅记录在响应中
+	var servicesError string
+	if len(course.Backend.Services) > 0 {
+		if err := h.startServices(ctx, course, sessionID); err != nil {
+			h.logger.Warn("[startCourse] 课程 %s 的依赖服务启动失败: %v", id, err)
+			servicesError = err.Error()
+		}
+	}
+
+	response := gin.H{
 		"message":     "课程容器启动成功",
 		"courseId":    id,
 		"containerId": containerInfo.ID,
+		"ready":       true,
 		"image":       imageName,
+	}
+	if servicesError != "" {
+		response["servicesError"] = servicesError
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// startServices 按 backend.services 声明的 DependsOn 顺序依次创建并启动依赖服务容器（例如指标采集 sidecar）
+// 每个服务被当作一个独立的"课程"对待（courseID 加上服务名后缀），从而复用现有的容器命名与清理隔离逻辑
+func (h *Handler) startServices(ctx context.Context, courseObj *course.Course, sessionID string) error {
+	order, err := courseObj.Backend.OrderedServiceNames()
+	if err != nil {
+		return fmt.Errorf("解析服务依赖顺序失败: %w", err)
+	}
+
+	byName := make(map[string]course.ServiceSpec, len(courseObj.Backend.Services))
+	for _, svc := range courseObj.Backend.Services {
+		byName[svc.Name] = svc
+	}
+
+	for _, name := range order {
+		if name == course.MainServiceName {
+			continue
+		}
+		svc := byName[name]
+
+		env := make(map[string]string)
+		for _, e := range svc.Env {
+			parts := strings.SplitN(e, "=", 2)
+			if len(parts) == 2 {
+				env[parts[0]] = parts[1]
+			}
+		}
+
+		serviceCourseID := serviceCourseID(courseObj.ID, svc.Name)
+		cfg := &docker.ContainerConfig{Image: svc.ImageID, Cmd: svc.Cmd, Env: env}
+
+		var containerInfo *docker.ContainerInfo
+		var cerr error
+		if sessionID != "" {
+			containerInfo, cerr = h.dockerController.CreateContainerForSession(ctx, serviceCourseID, sessionID, cfg, nil)
+		} else {
+			containerInfo, cerr = h.dockerController.CreateContainerWithProgress(ctx, serviceCourseID, cfg, nil)
+		}
+		if cerr != nil {
+			return fmt.Errorf("服务 %s 创建失败: %w", name, cerr)
+		}
+		if err := h.dockerController.StartContainer(ctx, containerInfo.ID); err != nil {
+			return fmt.Errorf("服务 %s 启动失败: %w", name, err)
+		}
+		if !svc.ReadinessProbe.IsZero() {
+			if err := probe.Wait(ctx, svc.ReadinessProbe, containerInfo.ID, h.dockerController, nil); err != nil {
+				return fmt.Errorf("服务 %s 就绪探针未通过: %w", name, err)
+			}
+		}
+		h.logger.Info("[startCourse] 依赖服务 %s 启动成功，容器ID: %s", name, containerInfo.ID)
+	}
+	return nil
+}
+
+// stopServices 按 DependsOn 的逆序清理 backend.services 声明的依赖服务容器
+func (h *Handler) stopServices(ctx context.Context, courseObj *course.Course) {
+	order, err := courseObj.Backend.OrderedServiceNames()
+	if err != nil {
+		h.logger.Warn("[stopCourse] 解析服务依赖顺序失败: %v", err)
+		return
+	}
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		if name == course.MainServiceName {
+			continue
+		}
+		if _, err := h.dockerController.CleanupCourseContainers(ctx, serviceCourseID(courseObj.ID, name)); err != nil {
+			h.logger.Warn("[stopCourse] 清理服务 %s 容器失败: %v", name, err)
+		}
+	}
+}
+
+// serviceCourseID 将依赖服务当作一个独立的课程ID对待，从而复用容器命名前缀隔离与 cleanupCourseContainers 清理逻辑
+func serviceCourseID(courseID, serviceName string) string {
+	return fmt.Sprintf("%s--%s", courseID, serviceName)
+}
+
+// defaultReadinessProbe 课程未声明 backend.readinessProbe 时使用的默认探针
+// 对 backend.port 做 TCP 探测，最长等待约 30 秒，取代此前散落在 sqlInfo 中的临时快速轮询
+func defaultReadinessProbe(port int) *probe.Spec {
+	return &probe.Spec{
+		TCPSocket:           &probe.TCPSocketAction{Port: port},
+		InitialDelaySeconds: 1,
+		PeriodSeconds:       1,
+		FailureThreshold:    30,
+	}
+}
+
+// getCourseFile 读取课程下任意相对路径的原始素材文件（如 data/rdb.tar.gz 等种子数据包），
+// 支持 If-None-Match 条件请求：命中缓存的ETag时返回 304 而不重复传输文件体。
+// 路径参数:
+//
+//	id: 课程ID
+//	filepath: 课程内的相对路径（以 "/" 开头，由gin的 *filepath 通配捕获）
+//
+// 请求头:
+//
+//	If-None-Match: 上一次响应的ETag，可选
+//
+// 响应:
+//
+//	200: 文件内容，附带 ETag 响应头
+//	304: 未变化，无响应体
+//	404: 文件不存在
+func (h *Handler) getCourseFile(c *gin.Context) {
+	id := c.Param("id")
+	relPath := strings.TrimPrefix(c.Param("filepath"), "/")
+	if relPath == "" || strings.Contains(relPath, "..") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path"})
+		return
+	}
+
+	data, etag, notModified, err := h.courseService.ReadCourseFileWithETag(id, relPath, c.GetHeader("If-None-Match"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", etag)
+	if notModified {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// getCourseEvents 以 SSE 形式推送课程启动的就绪事件，语义与 startCourse 的阻塞式就绪门禁一致
+// 响应:
+//
+//	text/event-stream，依次推送 {"state":"pending","attempt":N} 若干次，最终推送一次
+//	{"state":"ready"} 或 {"state":"failed","error":"..."}，随后连接关闭
+func (h *Handler) getCourseEvents(c *gin.Context) {
+	id := c.Param("id")
+	courseObj, exists := h.courseService.GetCourse(id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return
+	}
+
+	containerID := c.Query("containerId")
+	if containerID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少 containerId"})
+		return
+	}
+
+	readinessSpec := courseObj.Backend.ReadinessProbe
+	if readinessSpec.IsZero() {
+		readinessSpec = defaultReadinessProbe(courseObj.Backend.Port)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	ctx := c.Request.Context()
+	err := probe.Wait(ctx, readinessSpec, containerID, h.dockerController, func(attempt int, attemptErr error) {
+		if attemptErr == nil {
+			return
+		}
+		c.SSEvent("message", gin.H{"state": "pending", "attempt": attempt})
+		c.Writer.Flush()
+	})
+	if err != nil {
+		c.SSEvent("message", gin.H{"state": "failed", "error": err.Error()})
+	} else {
+		c.SSEvent("message", gin.H{"state": "ready"})
+	}
+	c.Writer.Flush()
+}
+
+// startCourseOnKubernetes 在共享 Kubernetes 集群上为课程创建并启动工作负载
+// 与 Docker 路径共用课程解析、镜像/Cmd/卷/环境变量的构建逻辑，仅创建阶段走 backend.Runtime
+func (h *Handler) startCourseOnKubernetes(c *gin.Context, ctx context.Context, courseObj *course.Course, dockerCfg *docker.ContainerConfig) {
+	id := courseObj.ID
+	if h.kubernetesRuntime == nil {
+		h.logger.Error("[startCourse] 课程 %s 配置了 kubernetes 运行时，但未注入 KubernetesRuntime", id)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Kubernetes 运行时未配置",
+		})
+		return
+	}
+
+	workloadCfg := backend.WorkloadConfig{
+		CourseID:     id,
+		Image:        dockerCfg.Image,
+		Env:          dockerCfg.Env,
+		Port:         courseObj.Backend.Port,
+		WorkingDir:   dockerCfg.WorkingDir,
+		Cmd:          dockerCfg.Cmd,
+		Volumes:      dockerCfg.Volumes,
+		Privileged:   dockerCfg.Privileged,
+		Namespace:    courseObj.Backend.Namespace,
+		NodeSelector: courseObj.Backend.NodeSelector,
+		Resources: backend.ResourceLimits{
+			Requests: courseObj.Backend.Resources.Requests,
+			Limits:   courseObj.Backend.Resources.Limits,
+		},
+	}
+
+	workload, err := h.kubernetesRuntime.CreateWorkload(ctx, workloadCfg)
+	if err != nil {
+		h.logger.Error("[startCourse] Kubernetes 工作负载创建失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("工作负载创建失败: %v", err),
+		})
+		return
+	}
+
+	if err := h.kubernetesRuntime.StartWorkload(ctx, workload.ID); err != nil {
+		h.logger.Error("[startCourse] Kubernetes 工作负载启动失败: %v，开始清理", err)
+		if cleanupErr := h.kubernetesRuntime.StopWorkload(ctx, workload.ID); cleanupErr != nil {
+			h.logger.Warn("[startCourse] 清理 Kubernetes 工作负载失败: %v", cleanupErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("工作负载启动失败: %v", err),
+		})
+		return
+	}
+
+	h.logger.Info("[startCourse] Kubernetes 工作负载启动成功，课程ID: %s，工作负载ID: %s", id, workload.ID)
+
+	// 就绪门禁：通过临时的 port-forward 对课程端口做探测，与 Docker 路径的语义保持一致
+	// exec 类型探针在 Kubernetes 路径下不受支持（backend.Runtime 尚未统一 exec 接口），此时直接跳过门禁
+	readinessSpec := courseObj.Backend.ReadinessProbe
+	ready := true
+	if !readinessSpec.IsZero() && readinessSpec.Exec == nil {
+		localPort, closeForward, fwErr := h.kubernetesRuntime.PortForward(ctx, workload.ID, courseObj.Backend.Port)
+		if fwErr != nil {
+			h.logger.Warn("[startCourse] 课程 %s 建立 port-forward 失败，跳过就绪探测: %v", id, fwErr)
+		} else {
+			defer closeForward()
+			forwardedSpec := *readinessSpec
+			if forwardedSpec.TCPSocket != nil {
+				withPort := *forwardedSpec.TCPSocket
+				withPort.Port = localPort
+				forwardedSpec.TCPSocket = &withPort
+			}
+			if forwardedSpec.HTTPGet != nil {
+				withPort := *forwardedSpec.HTTPGet
+				withPort.Port = localPort
+				forwardedSpec.HTTPGet = &withPort
+			}
+			if err := probe.Wait(ctx, &forwardedSpec, "", nil, nil); err != nil {
+				h.logger.Warn("[startCourse] 课程 %s 就绪探针未通过: %v", id, err)
+				ready = false
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "课程容器启动成功",
+		"courseId":    id,
+		"containerId": workload.ID,
+		"image":       workloadCfg.Image,
+		"ready":       ready,
 	})
 }
 
@@ -612,6 +1766,12 @@ func (h *Handler) stopCourse(c *gin.Context) {
 	defer h.containerMutex.Unlock()
 	h.logger.Debug("[stopCourse] 获取容器操作锁，课程ID: %s", id)
 
+	// 课程配置了 kubernetes 运行时后端时，交由 backend.Runtime 处理，不走下面的 Docker 容器查找路径
+	if courseObj, exists := h.courseService.GetCourse(id); exists && backend.KindForCourse(courseObj.Backend.Runtime) == backend.RuntimeKubernetes {
+		h.stopCourseOnKubernetes(c, id, courseObj)
+		return
+	}
+
 	// 检查Docker控制器是否可用
 	if h.dockerController == nil {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
@@ -621,7 +1781,11 @@ func (h *Handler) stopCourse(c *gin.Context) {
 	}
 
 	// 查找课程对应的容器 - 使用精确的容器名称前缀（带连字符）
+	// 启用会话隔离时，前缀额外携带会话ID，避免误删其他学生的容器
 	coursePrefix := fmt.Sprintf("kwdb-playground-%s-", id)
+	if sessionID := h.sessionIDFor(c); sessionID != "" {
+		coursePrefix = session.ContainerNamePrefix(id, sessionID)
+	}
 	h.logger.Debug("[stopCourse] 查找容器前缀: %s", coursePrefix)
 	ctx := context.Background()
 	containers, err := h.dockerController.ListContainers(ctx)
@@ -668,23 +1832,412 @@ func (h *Handler) stopCourse(c *gin.Context) {
 		h.logger.Info("[stopCourse] 容器停止成功: %s", target.ID)
 	}
 
-	// 删除容器以彻底清理资源（无论停止是否成功都尝试删除）
-	h.logger.Debug("[stopCourse] 正在删除容器: %s", target.ID)
-	err = h.dockerController.RemoveContainer(ctx, target.ID)
+	// 删除容器以彻底清理资源（无论停止是否成功都尝试删除）
+	h.logger.Debug("[stopCourse] 正在删除容器: %s", target.ID)
+	err = h.dockerController.RemoveContainer(ctx, target.ID)
+	if err != nil {
+		// 删除失败时记录日志并返回 500
+		h.logger.Error("[stopCourse] 删除容器失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("容器删除失败: %v", err),
+		})
+		return
+	}
+	h.logger.Debug("[stopCourse] 容器删除成功: %s", target.ID)
+
+	// 同时清理该课程声明的依赖服务容器（backend.services），逆序销毁
+	if courseObj, exists := h.courseService.GetCourse(id); exists && len(courseObj.Backend.Services) > 0 {
+		h.stopServices(ctx, courseObj)
+	}
+
+	if h.sessionManager != nil {
+		if sessionID := h.sessionIDFor(c); sessionID != "" {
+			h.sessionManager.ReleaseContainer(sessionID, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "课程容器停止成功",
+		"courseId":    id,
+		"containerId": target.ID,
+	})
+}
+
+// stopCourseOnKubernetes 停止并清理课程在共享 Kubernetes 集群上的工作负载
+func (h *Handler) stopCourseOnKubernetes(c *gin.Context, id string, courseObj *course.Course) {
+	if h.kubernetesRuntime == nil {
+		h.logger.Error("[stopCourse] 课程 %s 配置了 kubernetes 运行时，但未注入 KubernetesRuntime", id)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Kubernetes 运行时未配置",
+		})
+		return
+	}
+
+	namespace := courseObj.Backend.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	workloadID := fmt.Sprintf("%s/kwdb-playground-%s", namespace, strings.ToLower(id))
+
+	ctx := context.Background()
+	if err := h.kubernetesRuntime.StopWorkload(ctx, workloadID); err != nil {
+		h.logger.Error("[stopCourse] Kubernetes 工作负载清理失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("工作负载清理失败: %v", err),
+		})
+		return
+	}
+
+	if h.sessionManager != nil {
+		if sessionID := h.sessionIDFor(c); sessionID != "" {
+			h.sessionManager.ReleaseContainer(sessionID, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "课程容器停止成功",
+		"courseId":    id,
+		"containerId": workloadID,
+	})
+}
+
+// findRunningCourseContainer 查找课程（可选按会话隔离）当前正在运行/启动中的容器，找不到时返回 nil
+func (h *Handler) findRunningCourseContainer(ctx context.Context, courseID, sessionID string) (*docker.ContainerInfo, error) {
+	coursePrefix := fmt.Sprintf("kwdb-playground-%s-", courseID)
+	if sessionID != "" {
+		coursePrefix = session.ContainerNamePrefix(courseID, sessionID)
+	}
+	containers, err := h.dockerController.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取容器列表失败: %w", err)
+	}
+	for _, ctn := range containers {
+		if strings.HasPrefix(ctn.ID, coursePrefix) && (ctn.State == docker.StateRunning || ctn.State == docker.StateStarting) {
+			return ctn, nil
+		}
+	}
+	return nil, nil
+}
+
+// findContainerByCourseID 按 ContainerInfo.CourseID 精确匹配查找容器，不限定运行状态、不考虑会话隔离；
+// 与 findRunningCourseContainer（按名称前缀+运行状态过滤，面向多会话部署）相比，
+// 这里面向"一个课程只有一个容器"的更简单场景
+func (h *Handler) findContainerByCourseID(ctx context.Context, courseID string) (*docker.ContainerInfo, error) {
+	if strings.TrimSpace(courseID) == "" {
+		return nil, fmt.Errorf("课程ID不能为空")
+	}
+	if h.dockerController == nil {
+		return nil, fmt.Errorf("Docker服务不可用")
+	}
+	containers, err := h.dockerController.ListContainers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取容器列表失败: %w", err)
+	}
+	for _, ctn := range containers {
+		if ctn.CourseID == courseID {
+			return ctn, nil
+		}
+	}
+	return nil, fmt.Errorf("未找到课程 %s 的容器", courseID)
+}
+
+// resolveVolumeHostPaths 解析 backend.volumes 声明的宿主机路径（与 startCourse 的卷绑定解析规则一致），用于快照打包
+func (h *Handler) resolveVolumeHostPaths(courseObj *course.Course) []string {
+	if len(courseObj.Backend.Volumes) == 0 {
+		return nil
+	}
+
+	baseDir := h.cfg.Course.Dir
+	if !filepath.IsAbs(baseDir) {
+		if absBase, err := filepath.Abs(baseDir); err == nil {
+			baseDir = absBase
+		}
+	}
+	courseBase := filepath.Join(baseDir, courseObj.ID)
+
+	var hostPaths []string
+	for _, bind := range courseObj.Backend.Volumes {
+		b := strings.TrimSpace(bind)
+		if b == "" {
+			continue
+		}
+		parts := strings.SplitN(b, ":", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		hostPath := strings.TrimSpace(parts[0])
+		if hostPath == "~" || strings.HasPrefix(hostPath, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				hostPath = filepath.Join(home, strings.TrimPrefix(hostPath, "~"))
+			}
+		}
+		if !filepath.IsAbs(hostPath) {
+			hostPath = filepath.Join(courseBase, hostPath)
+		}
+		hostPath = filepath.Clean(hostPath)
+		if absHost, err := filepath.Abs(hostPath); err == nil {
+			hostPath = absHost
+		}
+		if _, err := os.Stat(hostPath); err == nil {
+			hostPaths = append(hostPaths, hostPath)
+		}
+	}
+	return hostPaths
+}
+
+// dumpSchemaAsText 将查询结果集转储为以制表符分隔的文本，每行一条记录，用于恢复时在新容器上重放 Schema
+func dumpSchemaAsText(rows pgx.Rows) string {
+	var sb strings.Builder
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			continue
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = fmt.Sprintf("%v", v)
+		}
+		sb.WriteString(strings.Join(parts, "\t"))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// snapshotCourse 对课程当前运行的容器做一次快照：commit 容器文件系统为新镜像，
+// 并打包 backend.volumes 声明的宿主机卷数据；KWDB 已就绪时额外转储一份 Schema 作为镜像丢失时的兜底
+// POST /api/courses/:id/snapshot
+func (h *Handler) snapshotCourse(c *gin.Context) {
+	id := c.Param("id")
+	if strings.TrimSpace(id) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课程ID不能为空"})
+		return
+	}
+
+	courseObj, exists := h.courseService.GetCourse(id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return
+	}
+
+	if h.dockerController == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Docker服务暂不可用"})
+		return
+	}
+
+	h.containerMutex.Lock()
+	defer h.containerMutex.Unlock()
+
+	ctx := context.Background()
+	sessionID := h.sessionIDFor(c)
+
+	target, err := h.findRunningCourseContainer(ctx, id, sessionID)
+	if err != nil {
+		h.logger.Error("[snapshotCourse] %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到课程对应的运行中容器"})
+		return
+	}
+
+	snapshotID := fmt.Sprintf("%s-%d", id, time.Now().UnixNano())
+	manifest := &snapshot.Manifest{
+		ID:        snapshotID,
+		CourseID:  id,
+		CreatedAt: time.Now(),
+	}
+
+	repoTag := fmt.Sprintf("kwdb-playground-snapshot/%s:%s", id, snapshotID)
+	image, err := h.dockerController.CommitContainer(ctx, target.ID, repoTag)
+	if err != nil {
+		h.logger.Error("[snapshotCourse] 提交容器快照失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("提交容器快照失败: %v", err)})
+		return
+	}
+	manifest.Image = image
+
+	store := snapshot.NewStore(h.cfg.DataDir)
+	if hostPaths := h.resolveVolumeHostPaths(courseObj); len(hostPaths) > 0 {
+		archiveName := snapshotID + "-volumes.tar.gz"
+		archivePath := filepath.Join(store.Dir(), id, archiveName)
+		sum, err := snapshot.ExportVolumes(hostPaths, archivePath)
+		if err != nil {
+			h.logger.Warn("[snapshotCourse] 打包卷数据失败，快照仅包含镜像: %v", err)
+		} else {
+			manifest.VolumeArchive = archiveName
+			manifest.VolumeSHA256 = sum
+		}
+	}
+
+	// KWDB 未就绪（例如课程尚未连接过数据库）时跳过 Schema 转储，不影响镜像+卷数据的快照
+	if err := h.sqlDriver.EnsureReady(ctx, courseObj); err == nil {
+		if rows, err := h.sqlDriver.Pool().Query(ctx, "SHOW CREATE ALL TABLES"); err == nil {
+			manifest.SchemaDump = dumpSchemaAsText(rows)
+			rows.Close()
+		} else {
+			h.logger.Warn("[snapshotCourse] 转储 Schema 失败: %v", err)
+		}
+	} else {
+		h.logger.Debug("[snapshotCourse] KWDB 未就绪，跳过 Schema 转储: %v", err)
+	}
+
+	if err := store.Save(manifest); err != nil {
+		h.logger.Error("[snapshotCourse] 保存快照清单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存快照清单失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "课程快照创建成功",
+		"courseId":   id,
+		"snapshotId": manifest.ID,
+		"image":      manifest.Image,
+		"hasVolumes": manifest.VolumeArchive != "",
+		"hasSchema":  manifest.SchemaDump != "",
+	})
+}
+
+// restoreCourse 依据快照重建课程容器：快照镜像仍存在时直接从该镜像启动；
+// 镜像已被清理时退化为默认镜像启动，并在 KWDB 就绪后重放快照中的 Schema 转储
+// POST /api/courses/:id/restore，请求体 {"snapshotId": "..."}
+func (h *Handler) restoreCourse(c *gin.Context) {
+	id := c.Param("id")
+	if strings.TrimSpace(id) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课程ID不能为空"})
+		return
+	}
+
+	var req struct {
+		SnapshotID string `json:"snapshotId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.SnapshotID) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "snapshotId 不能为空"})
+		return
+	}
+
+	courseObj, exists := h.courseService.GetCourse(id)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return
+	}
+
+	if h.dockerController == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Docker服务暂不可用"})
+		return
+	}
+
+	store := snapshot.NewStore(h.cfg.DataDir)
+	manifest, err := store.Get(req.SnapshotID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("快照不存在: %v", err)})
+		return
+	}
+	if manifest.CourseID != id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "快照与课程不匹配"})
+		return
+	}
+
+	h.containerMutex.Lock()
+	defer h.containerMutex.Unlock()
+
+	ctx := context.Background()
+	sessionID := h.sessionIDFor(c)
+
+	// 镜像仍然存在就直接复用（保留容器文件系统的全部改动）；
+	// 镜像已被清理（例如磁盘回收）则退化为课程默认镜像，并在 KWDB 就绪后重放 Schema
+	imageName := manifest.Image
+	imageMissing := false
+	if _, err := h.dockerController.EnsureImageAvailable(ctx, nil, imageName, nil); err != nil {
+		h.logger.Warn("[restoreCourse] 快照镜像 %s 不可用，退化为默认镜像并重放 Schema: %v", imageName, err)
+		imageMissing = true
+		imageName = courseObj.Backend.ImageID
+		if imageName == "" {
+			imageName = "kwdb/kwdb:latest"
+		}
+	}
+
+	containerCfg := &docker.ContainerConfig{
+		Image:      imageName,
+		WorkingDir: courseObj.Backend.Workspace,
+		Cmd:        []string{"/bin/bash", "-c", "while true; do sleep 3600; done"},
+		Privileged: courseObj.Backend.Privileged,
+		Ports:      map[string]string{"26257": fmt.Sprintf("%d", courseObj.Backend.Port)},
+	}
+
+	var containerInfo *docker.ContainerInfo
+	if sessionID != "" {
+		containerInfo, err = h.dockerController.CreateContainerForSession(ctx, id, sessionID, containerCfg, nil)
+	} else {
+		containerInfo, err = h.dockerController.CreateContainerWithProgress(ctx, id, containerCfg, nil)
+	}
+	if err != nil {
+		h.logger.Error("[restoreCourse] 创建容器失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("创建容器失败: %v", err)})
+		return
+	}
+
+	if err := h.dockerController.StartContainer(ctx, containerInfo.ID); err != nil {
+		h.logger.Error("[restoreCourse] 启动容器失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("启动容器失败: %v", err)})
+		return
+	}
+
+	if manifest.VolumeArchive != "" {
+		archivePath := filepath.Join(store.Dir(), id, manifest.VolumeArchive)
+		if err := snapshot.ImportVolumes(archivePath); err != nil {
+			h.logger.Warn("[restoreCourse] 还原卷数据失败: %v", err)
+		}
+	}
+
+	schemaReplayed := false
+	if imageMissing && manifest.SchemaDump != "" {
+		if err := h.sqlDriver.EnsureReady(ctx, courseObj); err != nil {
+			h.logger.Warn("[restoreCourse] KWDB 未就绪，跳过 Schema 重放: %v", err)
+		} else {
+			for _, stmt := range strings.Split(manifest.SchemaDump, "\n") {
+				stmt = strings.TrimSpace(stmt)
+				if stmt == "" {
+					continue
+				}
+				if _, err := h.sqlDriver.Pool().Exec(ctx, stmt); err != nil {
+					h.logger.Warn("[restoreCourse] 重放 Schema 语句失败: %s, %v", stmt, err)
+				}
+			}
+			schemaReplayed = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "课程容器已从快照恢复",
+		"courseId":       id,
+		"containerId":    containerInfo.ID,
+		"snapshotId":     manifest.ID,
+		"imageMissing":   imageMissing,
+		"schemaReplayed": schemaReplayed,
+	})
+}
+
+// listCourseSnapshots 列出指定课程的全部快照
+// GET /api/courses/:id/snapshots
+func (h *Handler) listCourseSnapshots(c *gin.Context) {
+	id := c.Param("id")
+	if strings.TrimSpace(id) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课程ID不能为空"})
+		return
+	}
+
+	store := snapshot.NewStore(h.cfg.DataDir)
+	manifests, err := store.List(id)
 	if err != nil {
-		// 删除失败时记录日志并返回 500
-		h.logger.Error("[stopCourse] 删除容器失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("容器删除失败: %v", err),
-		})
+		h.logger.Error("[listCourseSnapshots] 读取快照列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("读取快照列表失败: %v", err)})
 		return
 	}
-	h.logger.Debug("[stopCourse] 容器删除成功: %s", target.ID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "课程容器停止成功",
-		"courseId":    id,
-		"containerId": target.ID,
+		"courseId":  id,
+		"snapshots": manifests,
 	})
 }
 
@@ -847,6 +2400,37 @@ func (h *Handler) getContainerLogs(c *gin.Context) {
 	})
 }
 
+// getContainerEvents 以 SSE 形式持续推送容器监督事件（退出/自动重启），直到客户端断开连接；
+// 未启用容器监督（docker.RestartPolicy为"none"）时该流永远不会收到事件，但连接本身仍会保持打开
+func (h *Handler) getContainerEvents(c *gin.Context) {
+	id := c.Param("id")
+	if h.dockerController == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Docker服务暂不可用"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	events, cancel := h.dockerController.SupervisorEvents(id)
+	defer cancel()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			c.SSEvent("message", ev)
+			c.Writer.Flush()
+		}
+	}
+}
+
 // restartContainer 重启容器
 // 重启指定的Docker容器
 // 路径参数:
@@ -909,7 +2493,12 @@ func (h *Handler) restartContainer(c *gin.Context) {
 //
 //	container_id: 容器ID（终端模式必需，进度模式可选）
 //	session_id: 会话ID（可选）
+//	course_id: 课程ID（可选，仅用于给录制文件分目录）
 //	progress_only: 是否仅用于接收镜像拉取进度（可选，true/false）
+//	record: 是否将本次会话录制为 asciinema v2 格式（可选，true/false），用于教学场景下的批改与审计；
+//	        仅当 course_id 对应课程的 Backend.Recording.Enabled 为 true 时才会真正生效
+//	role: 连接在共享会话中的角色（可选，driver/follower，默认driver）。同一session_id被多条连接
+//	      共用时即为"共享终端"：教师driver演示、学生follower围观，或TA以driver身份加入帮忙排障
 //
 // 响应:
 //
@@ -919,7 +2508,13 @@ func (h *Handler) restartContainer(c *gin.Context) {
 func (h *Handler) handleTerminalWebSocket(c *gin.Context) {
 	sessionID := c.Query("session_id")
 	containerID := c.Query("container_id")
+	courseID := c.Query("course_id")
 	progressOnly := c.Query("progress_only") == "true"
+	record := c.Query("record") == "true"
+	role := ws.RoleDriver
+	if c.Query("role") == "follower" {
+		role = ws.RoleFollower
+	}
 
 	// 生成会话ID（如果未提供）
 	if sessionID == "" {
@@ -932,6 +2527,11 @@ func (h *Handler) handleTerminalWebSocket(c *gin.Context) {
 		return
 	}
 
+	// 终端连接建立视为一次用户活动，喂给会话回收器以重置空闲计时
+	if userSessionID := h.sessionIDFor(c); userSessionID != "" {
+		h.sessionManager.Touch(userSessionID)
+	}
+
 	// 检查终端管理器
 	if h.terminalManager == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "终端管理器不可用"})
@@ -953,43 +2553,370 @@ func (h *Handler) handleTerminalWebSocket(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	// 创建会话
-	session := h.terminalManager.CreateSession(sessionID, containerID, conn)
-	defer h.terminalManager.RemoveSession(sessionID)
+	// 创建或加入共享会话：session_id已存在时，这里拿到的是同一个会话，conn只是以role身份挂载上去
+	session, isNew := h.terminalManager.CreateSession(sessionID, containerID, courseID, conn, role)
+	defer h.terminalManager.DetachConnection(sessionID, conn)
 
-	if progressOnly {
-		// 进度模式：仅用于接收镜像拉取进度，不启动终端会话
-		h.logger.Info("WebSocket连接已建立（进度模式），会话: %s", sessionID)
+	// 录制是会话级别而非连接级别的，只在新建会话时开启一次，避免加入者重新开一个Recorder把之前的文件句柄悬空
+	if record && isNew {
+		if !h.courseAllowsRecording(courseID) {
+			h.logger.Warn("课程 %s 未开启终端录制，忽略 record=true 请求", courseID)
+		} else {
+			store := recording.NewStore(h.cfg.DataDir)
+			maxSizeBytes := int64(0)
+			if c, ok := h.courseService.GetCourse(courseID); ok {
+				maxSizeBytes = c.Backend.Recording.MaxSizeBytes
+			}
+			if err := session.EnableRecording(store, progressOnly, maxSizeBytes); err != nil {
+				h.logger.Warn("开启终端会话录制失败: %v", err)
+			}
+		}
+	}
 
-		session.StartProgressSession()
+	if progressOnly {
+		if isNew {
+			// 进度模式：仅用于接收镜像拉取进度，不启动终端会话
+			h.logger.Info("WebSocket连接已建立（进度模式），会话: %s", sessionID)
+			session.StartProgressSession()
+		} else {
+			h.logger.Info("加入已存在的进度会话 %s，角色: %s", sessionID, role)
+		}
 
-		// 保持连接直到会话结束
+		// 保持连接直到这条连接自己掉线，或者整个共享会话结束
 		select {
 		case <-c.Request.Context().Done():
 			h.logger.Info("客户端断开连接（进度模式），会话: %s", sessionID)
+		case <-session.ConnDone(conn):
+			h.logger.Info("客户端断开连接（进度模式），会话: %s", sessionID)
 		case <-session.Done():
 			h.logger.Info("进度会话结束: %s", sessionID)
 		}
 	} else {
-		// 终端模式：启动交互式bash会话
-		err = session.StartInteractiveSession()
-		if err != nil {
-			h.logger.Error("启动终端会话失败: %v", err)
-			return
+		if isNew {
+			// 终端模式：启动交互式bash会话
+			if err := session.StartInteractiveSession(); err != nil {
+				h.logger.Error("启动终端会话失败: %v", err)
+				return
+			}
+			h.logger.Info("终端会话 %s 已启动，容器: %s", sessionID, containerID)
+		} else {
+			h.logger.Info("加入已存在的终端会话 %s，容器: %s，角色: %s", sessionID, containerID, role)
 		}
 
-		h.logger.Info("终端会话 %s 已启动，容器: %s", sessionID, containerID)
-
-		// 保持连接直到会话结束
+		// 保持连接直到这条连接自己掉线，或者整个共享会话结束
 		select {
 		case <-c.Request.Context().Done():
 			h.logger.Info("客户端断开连接，会话: %s", sessionID)
+		case <-session.ConnDone(conn):
+			h.logger.Info("客户端断开连接，会话: %s", sessionID)
 		case <-session.Done():
 			h.logger.Info("终端会话结束: %s", sessionID)
 		}
 	}
 }
 
+// listCourseRecordings 列出指定课程下所有已落盘的终端会话录制（含镜像拉取进度录制）摘要
+// 路径参数:
+//
+//	id: 课程ID
+//
+// 响应:
+//
+//	200: {"courseId": "...", "recordings": [recording.RecordingInfo, ...]}
+//	400: {"error": "课程ID不能为空"}
+//	500: {"error": "..."} 读取录制目录失败
+func (h *Handler) listCourseRecordings(c *gin.Context) {
+	id := c.Param("id")
+	if strings.TrimSpace(id) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课程ID不能为空"})
+		return
+	}
+
+	store := recording.NewStore(h.cfg.DataDir)
+	infos, err := store.List(id)
+	if err != nil {
+		h.logger.Error("[listCourseRecordings] 读取录制列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("读取录制列表失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"courseId":   id,
+		"recordings": infos,
+	})
+}
+
+// courseAllowsRecording 判断指定课程是否开启了终端会话录制（Backend.Recording.Enabled）。
+// courseID为空（调用方未声明所属课程）时没有配置可查，统一视为未开启，避免默认把任意会话落盘
+func (h *Handler) courseAllowsRecording(courseID string) bool {
+	if courseID == "" {
+		return false
+	}
+	c, ok := h.courseService.GetCourse(courseID)
+	if !ok {
+		return false
+	}
+	return c.Backend.Recording.Enabled
+}
+
+// downloadTerminalRecording 下载指定终端会话的录制文件（asciinema v2 格式，.cast）
+// 路径参数:
+//
+//	id: 终端会话ID（即创建会话时的 session_id）
+//
+// 查询参数:
+//
+//	progress_only: 是否下载镜像拉取进度录制（可选，true/false），默认下载终端输出录制
+//
+// 响应:
+//
+//	200: .cast 文件内容
+//	404: {"error": "..."} 未找到对应录制
+func (h *Handler) downloadTerminalRecording(c *gin.Context) {
+	sessionID := c.Param("id")
+	progressOnly := c.Query("progress_only") == "true"
+
+	store := recording.NewStore(h.cfg.DataDir)
+	path, err := store.Find(sessionID, progressOnly)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.FileAttachment(path, filepath.Base(path))
+}
+
+// replayTerminalRecording 以WebSocket方式回放已录制的终端会话，按原始帧间隔重新发送，
+// 使前端可以复用实时终端的渲染逻辑回放历史会话（教学场景下的批改、审计）
+// 路径参数:
+//
+//	id: 终端会话ID（即创建会话时的 session_id）
+//
+// 查询参数:
+//
+//	progress_only: 是否回放镜像拉取进度录制（可选，true/false），默认回放终端输出录制
+//
+// 响应:
+//
+//	101: WebSocket连接建立成功，随后以 {"type":"output"|"resize",...} 消息重放各帧，
+//	     结束后发送 {"type":"replay_done"}
+//	404: {"error": "..."} 未找到对应录制
+func (h *Handler) replayTerminalRecording(c *gin.Context) {
+	sessionID := c.Param("id")
+	progressOnly := c.Query("progress_only") == "true"
+
+	store := recording.NewStore(h.cfg.DataDir)
+	path, err := store.Find(sessionID, progressOnly)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, events, err := recording.ReadCast(path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("解析录制文件失败: %v", err)})
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return true // 允许所有来源
+		},
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("回放WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	elapsed := 0.0
+	for _, ev := range events {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-time.After(time.Duration((ev.Time - elapsed) * float64(time.Second))):
+		}
+		elapsed = ev.Time
+
+		msgType := "output"
+		if ev.Type == "r" {
+			msgType = "resize"
+		}
+		if err := conn.WriteJSON(ws.Message{Type: msgType, Data: ev.Data}); err != nil {
+			return
+		}
+	}
+	conn.WriteJSON(ws.Message{Type: "replay_done"})
+}
+
+// 课程终端WebSocket的心跳与读取限制，取值与 websocket.TerminalSession 一致
+const (
+	courseTerminalWriteWait      = 10 * time.Second
+	courseTerminalPingPeriod     = 54 * time.Second
+	courseTerminalMaxMessageSize = 8192
+)
+
+// handleCourseTerminalWebSocket 按课程ID解析当前运行中的容器并在其内启动一次交互式exec会话，
+// 经WebSocket双向转发，使课程步骤页面的"在终端中尝试"按钮无需关心具体容器ID
+// 与 /ws/terminal（container_id 驱动、基于 docker CLI + pty）不同，这里直接使用
+// docker.Controller.Exec（Docker Engine API），resize 通过返回的 ExecSession 直接下发
+// 路径参数:
+//
+//	id: 课程ID
+//
+// 查询参数:
+//
+//	session_id: 会话ID（可选，多用户隔离部署下用于定位该用户专属的课程容器）
+//
+// 响应:
+//
+//	101: WebSocket连接建立成功，随后以 {"type":"connected"|"output"|"error",...} 消息通信；
+//	     前端发送 {"type":"input","data":"..."} 写入标准输入、
+//	     {"type":"resize","data":{"cols":N,"rows":N}} 调整终端尺寸、{"type":"ping"} 心跳
+//	404: {"error": "..."} 课程不存在，或未找到运行中的课程容器
+//	500: {"error": "..."} 启动终端会话失败
+func (h *Handler) handleCourseTerminalWebSocket(c *gin.Context) {
+	courseID := c.Param("id")
+	sessionID := c.Query("session_id")
+	if sessionID == "" {
+		sessionID = h.sessionIDFor(c)
+	}
+
+	if _, ok := h.courseService.GetCourse(courseID); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	containerInfo, err := h.findRunningCourseContainer(ctx, courseID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("查找课程容器失败: %v", err)})
+		return
+	}
+	if containerInfo == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "未找到运行中的课程容器，请先启动课程"})
+		return
+	}
+
+	// 终端连接建立视为一次用户活动，喂给会话回收器以重置空闲计时
+	if userSessionID := h.sessionIDFor(c); userSessionID != "" {
+		h.sessionManager.Touch(userSessionID)
+	}
+
+	// 优先尝试 /bin/bash，不存在时回退到 /bin/sh，与 websocket.TerminalSession 的策略保持一致
+	execSession, shell, err := h.startCourseTerminalShell(ctx, containerInfo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("启动交互式终端失败: %v", err)})
+		return
+	}
+	defer execSession.Close()
+	h.logger.Info("课程终端会话已启动，课程: %s，容器: %s，shell: %s", courseID, containerInfo.ID, shell)
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			return true // 允许所有来源
+		},
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("课程终端WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	writeJSON := func(msg ws.Message) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(msg)
+	}
+	_ = writeJSON(ws.Message{Type: "connected", Data: "Terminal session started"})
+
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	closeDone := func() { closeOnce.Do(func() { close(done) }) }
+
+	// 读取容器输出并转发给前端
+	go func() {
+		defer closeDone()
+		buf := make([]byte, 4096)
+		for {
+			n, readErr := execSession.Read(buf)
+			if n > 0 {
+				if werr := writeJSON(ws.Message{Type: "output", Data: string(buf[:n])}); werr != nil {
+					return
+				}
+			}
+			if readErr != nil {
+				return
+			}
+		}
+	}()
+
+	// 心跳：定期ping，前端长时间无响应时Close会话避免exec泄漏
+	go func() {
+		ticker := time.NewTicker(courseTerminalPingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(courseTerminalWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					closeDone()
+					return
+				}
+			}
+		}
+	}()
+
+	conn.SetReadLimit(courseTerminalMaxMessageSize)
+readLoop:
+	for {
+		var msg ws.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			break readLoop
+		}
+		switch msg.Type {
+		case "ping":
+			_ = writeJSON(ws.Message{Type: "pong"})
+		case "input":
+			if data, ok := msg.Data.(string); ok {
+				if _, err := execSession.Write([]byte(data)); err != nil {
+					break readLoop
+				}
+			}
+		case "resize":
+			dataMap, ok := msg.Data.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			cols, ok1 := dataMap["cols"].(float64)
+			rows, ok2 := dataMap["rows"].(float64)
+			if ok1 && ok2 {
+				if err := execSession.Resize(ctx, uint(rows), uint(cols)); err != nil {
+					h.logger.Warn("调整课程终端尺寸失败: %v", err)
+				}
+			}
+		}
+	}
+	closeDone()
+	<-done
+}
+
+// startCourseTerminalShell 依次尝试 /bin/bash、/bin/sh 在容器内启动交互式shell，返回成功使用的shell名
+func (h *Handler) startCourseTerminalShell(ctx context.Context, containerID string) (*docker.ExecSession, string, error) {
+	for _, shell := range []string{"/bin/bash", "/bin/sh"} {
+		session, err := h.dockerController.Exec(ctx, containerID, []string{shell}, docker.ExecOptions{Tty: true})
+		if err == nil {
+			return session, shell, nil
+		}
+		h.logger.Warn("%s 不可用，容器: %s，错误: %v", shell, containerID, err)
+	}
+	return nil, "", fmt.Errorf("无可用Shell(/bin/bash, /bin/sh均不可用)")
+}
+
 // stopContainerByID 按容器ID停止并删除容器
 // 路径参数:
 //
@@ -1026,148 +2953,515 @@ func (h *Handler) stopContainerByID(c *gin.Context) {
 		h.logger.Warn("[stopContainerByID] 停止容器失败，继续删除: %v", err)
 	}
 
-	// 删除容器
-	if err := h.dockerController.RemoveContainer(ctx, id); err != nil {
-		h.logger.Error("[stopContainerByID] 删除容器失败: %v", err)
-		// 针对不存在的容器返回404
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "No such container") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "容器不存在"})
-			return
+	// 删除容器
+	if err := h.dockerController.RemoveContainer(ctx, id); err != nil {
+		h.logger.Error("[stopContainerByID] 删除容器失败: %v", err)
+		// 针对不存在的容器返回404
+		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "No such container") {
+			c.JSON(http.StatusNotFound, gin.H{"error": "容器不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("容器操作失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "容器停止成功", "containerId": id})
+}
+
+// sqlWSDefaultChunkSize 终端 WebSocket 流式结果默认每帧行数，可由 init 消息的 chunkSize 覆盖
+const sqlWSDefaultChunkSize = 500
+
+// sqlExecutor 抽象 pgxpool.Pool 和 *pgx.Conn 共有的查询接口，
+// 使流式结果输出逻辑既能跑在连接池上（普通 query），也能跑在专用连接上（prepare/execute 依赖同一条物理连接）
+type sqlExecutor interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// sandboxCursorState 沙箱模式下一个尚未读完的服务器端游标，跨 "query"/"fetchMore" 消息维持
+type sandboxCursorState struct {
+	cursorName  string // DECLARE 出的游标名
+	columnsSent bool
+	total       int
+	start       time.Time
+}
+
+// sandboxCursorName 把 queryId 转换为合法的SQL游标标识符：仅保留字母数字，其余替换为下划线
+func sandboxCursorName(qid string) string {
+	var b strings.Builder
+	b.WriteString("sbcur_")
+	for _, r := range qid {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// sandboxFetchSize 沙箱模式下每次 FETCH FORWARD 的行数，即游标分页粒度
+const sandboxFetchSize = 200
+
+// estimateRowBytes 粗略估算一行结果的字节数（各列值的字符串长度之和），用于沙箱模式的结果集大小上限判断
+func estimateRowBytes(vals []interface{}) int {
+	total := 0
+	for _, v := range vals {
+		total += len(fmt.Sprint(v))
+	}
+	return total
+}
+
+// isCreateTempTableStatement 判断语句是否为创建临时表：沙箱模式下default_transaction_read_only
+// 会一并挡住临时表，这类语句需要单独临时放行（并计入 sandbox.ReserveTempTable 配额）
+func isCreateTempTableStatement(stmt string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(stmt))
+	prefixes := []string{"CREATE TEMP TABLE", "CREATE TEMPORARY TABLE", "CREATE LOCAL TEMP TABLE", "CREATE LOCAL TEMPORARY TABLE"}
+	for _, p := range prefixes {
+		if strings.HasPrefix(upper, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// sandboxRateLimitKey 返回沙箱限流/临时表配额的归属键：优先取JWT中的userID，其次取会话ID，
+// 单用户部署（既无鉴权也无会话）时退化为课程ID
+func (h *Handler) sandboxRateLimitKey(c *gin.Context, courseID string) string {
+	if claims := auth.GetClaims(c); claims != nil && claims.UserID != "" {
+		return claims.UserID
+	}
+	if sid := h.sessionIDFor(c); sid != "" {
+		return sid
+	}
+	return courseID
+}
+
+// sqlWSErrorMessage 将 context 取消/超时错误转换为更友好的提示，其余错误原样返回
+func sqlWSErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "查询已取消"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "查询超时"
+	default:
+		return err.Error()
+	}
+}
+
+// handleSqlWebSocket 处理 SQL 终端的 WebSocket 通道
+// 协议：
+//   - 客户端发送 {type:"init", courseId:"...", chunkSize?:500, timeoutMs?:10000} 进行初始化
+//   - 客户端发送 {type:"query", queryId:"uuid", sql:"SELECT 1"} 执行查询，语句类型由 sqlparse.Classify 判定
+//     服务端依次返回 {type:"columns",...} -> 若干 {type:"rows", rows:[...]} -> {type:"complete", rowCount, elapsedMs}
+//     （非查询类语句跳过 columns/rows，直接返回 complete）；sql 中若以 ; 分隔了多条语句，
+//     会被 sqlparse.SplitStatements 拆开后按 batch 语义逐条执行并各自返回 result 帧
+//   - 客户端发送 {type:"cancel", queryId:"uuid"} 取消对应的在途查询
+//   - 客户端发送 {type:"prepare", name:"...", sql:"SELECT * FROM t WHERE id = $1"} 在本连接上注册预处理语句
+//   - 客户端发送 {type:"execute", queryId:"uuid", name:"...", params:[...]} 执行已注册的预处理语句，结果帧与 query 一致
+//   - 客户端发送 {type:"deallocate", name:"..."} 释放预处理语句
+//   - 客户端发送 {type:"batch", queryId:"uuid", statements:["...","..."]} 在一个 pgx Batch 内按顺序执行多条语句，
+//     每条语句返回一个 {type:"result", statementIndex, columns, rows, rowCount} 帧，全部完成后返回 {type:"batchComplete"}；
+//     任一语句失败会中止后续语句（pgx 流水线在首个错误处截断），并返回对应 statementIndex 的 error 帧
+//   - 客户端发送 {type:"ping"} 保活
+//   - 服务端返回 ready/info/columns/rows/complete/prepared/result/batchComplete/error/pong
+//
+// 大结果集按 chunkSize 分帧推送，避免像早期版本那样把 outRows 整体缓冲进内存；
+// 每个查询绑定独立的 context，取消/超时都会中断底层 pgx 的 Query/Exec 调用。
+// prepare/execute 依赖同一条物理连接（预处理语句只在创建它的连接上有效），因此会从连接池中
+// Acquire 一个专用连接并持有到 WebSocket 关闭；batch 不依赖预处理语句，直接在连接池上发送。
+//
+// courseObj.Backend.Sandbox.Enabled 时进入沙箱模式：查询改在一条切换到只读角色、
+// 设置了 statement_timeout/idle_in_transaction_session_timeout 的专用连接上执行，
+// SELECT 类语句以 "BEGIN READ ONLY; DECLARE ... CURSOR FOR ...; FETCH FORWARD N" 分页读取，
+// 单页累计字节数达到 MaxResultBytes 时提前返回 {hasMore:true, nextCursor}，客户端以
+// {type:"fetchMore", queryId, cursor} 续读；沙箱模式下 prepare/execute/deallocate/batch
+// 以及单消息多语句一律拒绝，只允许沙箱连接执行的单条语句；创建临时表需要
+// default_transaction_read_only 临时关闭，按 sandbox.ReserveTempTable 配额放行。
+// 并发查询数（跨该课程所有学生会话）与每用户/会话每分钟查询数分别由 sql.Sandbox 的
+// 信号量与令牌桶限制。
+func (h *Handler) handleSqlWebSocket(c *gin.Context) {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.logger.Error("SQL WebSocket升级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebSocket连接升级失败"})
+		return
+	}
+	defer conn.Close()
+
+	var courseObj *course.Course
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	chunkSize := sqlWSDefaultChunkSize
+	queryTimeoutMs := sqlQueryDefaultTimeoutMs
+
+	// writeMu 序列化对 conn 的写入：读循环发送 ready/info/pong，查询 goroutine 并发发送 columns/rows/complete
+	var writeMu sync.Mutex
+	writeJSON := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(v)
+	}
+
+	// cancels 记录在途查询的取消函数，供 {type:"cancel"} 消息按 queryId 中断对应的 pgx 调用
+	var cancelsMu sync.Mutex
+	cancels := make(map[string]context.CancelFunc)
+
+	// 沙箱模式状态：sandbox 非nil 表示 courseObj.Backend.Sandbox.Enabled，由 init 消息里的课程决定
+	// sandboxConn 是本次WebSocket会话专用的只读连接（临时表需要在同一条连接上跨查询存活），
+	// sandboxConnMu 序列化对它的访问（同一条连接不能并发执行多个语句）
+	var sandbox *sql.Sandbox
+	var sandboxKey string
+	var sandboxConn *pgxpool.Conn
+	var sandboxConnMu sync.Mutex
+
+	// sandboxCursors 记录沙箱模式下尚未读完的游标，key 为发起查询的 queryId（同时作为 nextCursor 的值）
+	var sandboxCursorsMu sync.Mutex
+	sandboxCursors := make(map[string]*sandboxCursorState)
+
+	// closeSandboxCursor 回滚并丢弃一个沙箱游标；调用方需已持有 sandboxConnMu
+	closeSandboxCursor := func(qid string) {
+		sandboxCursorsMu.Lock()
+		delete(sandboxCursors, qid)
+		sandboxCursorsMu.Unlock()
+		if sandboxConn != nil {
+			_, _ = sandboxConn.Exec(context.Background(), "ROLLBACK")
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("容器操作失败: %v", err)})
-		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "容器停止成功", "containerId": id})
-}
-
-// isSelectQuery 判断 SQL 语句是否为查询操作
-// 支持跳过各种类型的注释（单行注释 --、多行注释 /* */）
-// 能够识别：SELECT、SHOW、DESCRIBE、DESC、EXPLAIN、WITH（CTE）
-func isSelectQuery(sqlText string) bool {
-	// 移除所有注释并获取第一个有效的 SQL 关键词
-	cleanSQL := removeComments(sqlText)
+	defer func() {
+		sandboxConnMu.Lock()
+		defer sandboxConnMu.Unlock()
+		if sandboxConn != nil {
+			_, _ = sandboxConn.Exec(context.Background(), "ROLLBACK")
+			_, _ = sandboxConn.Exec(context.Background(), "DISCARD ALL")
+			sandboxConn.Release()
+		}
+		if sandbox != nil && sandboxKey != "" {
+			sandbox.ReleaseTempTables(sandboxKey)
+		}
+	}()
+
+	// preparedMu 保护下面两个字段：prepare/execute/deallocate 都可能来自读循环的连续消息，
+	// 但 execute 在 goroutine 里异步跑，需要与 deallocate 互斥
+	var preparedMu sync.Mutex
+	var preparedConn *pgxpool.Conn // prepare 时惰性 Acquire 的专用连接；预处理语句只在创建它的连接上可见
+	preparedStmts := make(map[string]string)
+
+	// acquirePreparedConn 返回本次会话专用的连接，首次调用时从连接池 Acquire 并保持到 WebSocket 关闭
+	acquirePreparedConn := func(ctx context.Context) (*pgxpool.Conn, error) {
+		preparedMu.Lock()
+		defer preparedMu.Unlock()
+		if preparedConn != nil {
+			return preparedConn, nil
+		}
+		pc, err := h.sqlDriver.Pool().Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		preparedConn = pc
+		return preparedConn, nil
+	}
+	defer func() {
+		preparedMu.Lock()
+		defer preparedMu.Unlock()
+		if preparedConn != nil {
+			preparedConn.Release()
+		}
+	}()
+
+	runQuery := func(ctx context.Context, executor sqlExecutor, qid, execSQL string, isSelect bool, args ...interface{}) {
+		start := time.Now()
+		defer func() {
+			cancelsMu.Lock()
+			delete(cancels, qid)
+			cancelsMu.Unlock()
+			h.sqlDriver.RecordQuery(courseObj.ID, start)
+		}()
+
+		if isSelect {
+			rows, err := executor.Query(ctx, execSQL, args...)
+			if err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+				return
+			}
+			defer rows.Close()
 
-	// 去除空白字符并转为大写
-	cleanSQL = strings.TrimSpace(strings.ToUpper(cleanSQL))
+			fieldDescs := rows.FieldDescriptions()
+			cols := make([]string, 0, len(fieldDescs))
+			for _, f := range fieldDescs {
+				cols = append(cols, string(f.Name))
+			}
+			_ = writeJSON(map[string]interface{}{"type": "columns", "queryId": qid, "columns": cols})
 
-	if cleanSQL == "" {
-		return false
+			chunk := make([][]interface{}, 0, chunkSize)
+			total := 0
+			flush := func() bool {
+				if len(chunk) == 0 {
+					return true
+				}
+				err := writeJSON(map[string]interface{}{"type": "rows", "queryId": qid, "rows": chunk})
+				chunk = chunk[:0]
+				return err == nil
+			}
+			for rows.Next() {
+				vals, err := rows.Values()
+				if err != nil {
+					_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+					return
+				}
+				// 格式化时间戳数据，确保时区信息一致
+				for i, v := range vals {
+					if t, ok := v.(time.Time); ok {
+						vals[i] = t.Format(time.RFC3339)
+					}
+				}
+				chunk = append(chunk, vals)
+				total++
+				if len(chunk) >= chunkSize {
+					if !flush() {
+						h.logger.Debug("[handleSqlWebSocket] 客户端已断开，终止推送: queryId=%s", qid)
+						return
+					}
+				}
+			}
+			if !flush() {
+				return
+			}
+			if rows.Err() != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(rows.Err())})
+				return
+			}
+			_ = writeJSON(map[string]interface{}{"type": "complete", "queryId": qid, "rowCount": total, "elapsedMs": time.Since(start).Milliseconds()})
+		} else {
+			// 数据修改操作：使用 Exec() 方法
+			commandTag, err := executor.Exec(ctx, execSQL, args...)
+			if err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+				return
+			}
+			_ = writeJSON(map[string]interface{}{"type": "complete", "queryId": qid, "rowCount": int(commandTag.RowsAffected()), "elapsedMs": time.Since(start).Milliseconds()})
+		}
 	}
 
-	// 检查是否为查询操作
-	queryKeywords := []string{"SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "WITH"}
+	// runBatch 在一个 pgx.Batch 内按顺序执行多条语句，每条语句单独返回一个 result 帧，
+	// 首个出错的语句会中止后续语句（与 pgx 流水线的实际执行语义一致），并返回 error 帧
+	runBatch := func(ctx context.Context, qid string, statements []string) {
+		start := time.Now()
+		defer func() {
+			cancelsMu.Lock()
+			delete(cancels, qid)
+			cancelsMu.Unlock()
+			h.sqlDriver.RecordQuery(courseObj.ID, start)
+		}()
+
+		batch := &pgx.Batch{}
+		for _, stmt := range statements {
+			batch.Queue(stmt)
+		}
+		br := h.sqlDriver.Pool().SendBatch(ctx, batch)
+		defer br.Close()
 
-	for _, keyword := range queryKeywords {
-		if strings.HasPrefix(cleanSQL, keyword) {
-			// 确保关键词后面是空白字符或结束，避免误匹配（如 SELECTALL）
-			if len(cleanSQL) == len(keyword) ||
-				(len(cleanSQL) > len(keyword) && isWhitespace(rune(cleanSQL[len(keyword)]))) {
-				return true
+		executed := 0
+		for i, stmt := range statements {
+			if sqlparse.Classify(stmt).IsQuery() {
+				rows, err := br.Query()
+				if err != nil {
+					_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "statementIndex": i, "message": sqlWSErrorMessage(err)})
+					return
+				}
+				fieldDescs := rows.FieldDescriptions()
+				cols := make([]string, 0, len(fieldDescs))
+				for _, f := range fieldDescs {
+					cols = append(cols, string(f.Name))
+				}
+				outRows := make([][]interface{}, 0)
+				for rows.Next() {
+					vals, err := rows.Values()
+					if err != nil {
+						rows.Close()
+						_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "statementIndex": i, "message": sqlWSErrorMessage(err)})
+						return
+					}
+					for j, v := range vals {
+						if t, ok := v.(time.Time); ok {
+							vals[j] = t.Format(time.RFC3339)
+						}
+					}
+					outRows = append(outRows, vals)
+				}
+				rowsErr := rows.Err()
+				rows.Close()
+				if rowsErr != nil {
+					_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "statementIndex": i, "message": sqlWSErrorMessage(rowsErr)})
+					return
+				}
+				_ = writeJSON(map[string]interface{}{"type": "result", "queryId": qid, "statementIndex": i, "columns": cols, "rows": outRows, "rowCount": len(outRows)})
+			} else {
+				commandTag, err := br.Exec()
+				if err != nil {
+					_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "statementIndex": i, "message": sqlWSErrorMessage(err)})
+					return
+				}
+				_ = writeJSON(map[string]interface{}{"type": "result", "queryId": qid, "statementIndex": i, "columns": []string{}, "rows": [][]interface{}{}, "rowCount": int(commandTag.RowsAffected())})
 			}
+			executed++
 		}
+		_ = writeJSON(map[string]interface{}{"type": "batchComplete", "queryId": qid, "count": executed, "elapsedMs": time.Since(start).Milliseconds()})
 	}
 
-	return false
-}
-
-// removeComments 移除 SQL 语句中的注释
-// 支持单行注释（--）和多行注释（/* */）
-func removeComments(sql string) string {
-	var result strings.Builder
-	runes := []rune(sql)
-	i := 0
+	// drainSandboxCursor 从已 DECLARE 的游标里反复 FETCH FORWARD sandboxFetchSize，直到游标耗尽或
+	// 累计字节数达到 sandbox.Config().MaxResultBytes；调用方需已持有 sandboxConnMu
+	drainSandboxCursor := func(ctx context.Context, qid string) {
+		sandboxCursorsMu.Lock()
+		st, ok := sandboxCursors[qid]
+		sandboxCursorsMu.Unlock()
+		if !ok || sandboxConn == nil {
+			_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "游标不存在或已关闭"})
+			return
+		}
 
-	for i < len(runes) {
-		// 检查单行注释 --
-		if i < len(runes)-1 && runes[i] == '-' && runes[i+1] == '-' {
-			// 跳过到行尾
-			for i < len(runes) && runes[i] != '\n' && runes[i] != '\r' {
-				i++
+		pageBytes := 0
+		for {
+			rows, err := sandboxConn.Query(ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", sandboxFetchSize, st.cursorName))
+			if err != nil {
+				closeSandboxCursor(qid)
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+				return
 			}
-			// 保留换行符
-			if i < len(runes) && (runes[i] == '\n' || runes[i] == '\r') {
-				result.WriteRune(' ') // 用空格替换换行，保持语句结构
-				i++
+			if !st.columnsSent {
+				fieldDescs := rows.FieldDescriptions()
+				cols := make([]string, 0, len(fieldDescs))
+				for _, f := range fieldDescs {
+					cols = append(cols, string(f.Name))
+				}
+				_ = writeJSON(map[string]interface{}{"type": "columns", "queryId": qid, "columns": cols})
+				st.columnsSent = true
 			}
-			continue
-		}
 
-		// 检查多行注释 /* */
-		if i < len(runes)-1 && runes[i] == '/' && runes[i+1] == '*' {
-			i += 2 // 跳过 /*
-			// 寻找注释结束 */
-			for i < len(runes)-1 {
-				if runes[i] == '*' && runes[i+1] == '/' {
-					i += 2 // 跳过 */
-					break
+			chunk := make([][]interface{}, 0, chunkSize)
+			fetched := 0
+			for rows.Next() {
+				vals, err := rows.Values()
+				if err != nil {
+					rows.Close()
+					closeSandboxCursor(qid)
+					_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+					return
+				}
+				for i, v := range vals {
+					if t, ok := v.(time.Time); ok {
+						vals[i] = t.Format(time.RFC3339)
+					}
+				}
+				pageBytes += estimateRowBytes(vals)
+				chunk = append(chunk, vals)
+				fetched++
+				st.total++
+				if len(chunk) >= chunkSize {
+					_ = writeJSON(map[string]interface{}{"type": "rows", "queryId": qid, "rows": chunk})
+					chunk = chunk[:0]
 				}
-				i++
 			}
-			result.WriteRune(' ') // 用空格替换注释
-			continue
+			rowsErr := rows.Err()
+			rows.Close()
+			if len(chunk) > 0 {
+				_ = writeJSON(map[string]interface{}{"type": "rows", "queryId": qid, "rows": chunk})
+			}
+			if rowsErr != nil {
+				closeSandboxCursor(qid)
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(rowsErr)})
+				return
+			}
+
+			if fetched == 0 {
+				closeSandboxCursor(qid)
+				_ = writeJSON(map[string]interface{}{"type": "complete", "queryId": qid, "rowCount": st.total, "elapsedMs": time.Since(st.start).Milliseconds(), "hasMore": false})
+				return
+			}
+			if pageBytes >= sandbox.Config().MaxResultBytes {
+				_ = writeJSON(map[string]interface{}{"type": "complete", "queryId": qid, "rowCount": st.total, "elapsedMs": time.Since(st.start).Milliseconds(), "hasMore": true, "nextCursor": qid})
+				return
+			}
+			if fetched < sandboxFetchSize {
+				closeSandboxCursor(qid)
+				_ = writeJSON(map[string]interface{}{"type": "complete", "queryId": qid, "rowCount": st.total, "elapsedMs": time.Since(st.start).Milliseconds(), "hasMore": false})
+				return
+			}
+			// 本批恰好取满且尚未达到字节上限：继续下一批 FETCH，同一次 query/fetchMore 调用里尽量多返回数据
+		}
+	}
+
+	// runSandboxQuery 沙箱模式下执行单条语句：非查询语句直接在只读连接上 Exec（default_transaction_read_only
+	// 会拒绝真正的写操作；CREATE TEMP TABLE 按 sandbox.ReserveTempTable 配额临时放行只读限制），
+	// 查询语句则 BEGIN READ ONLY 后 DECLARE 游标交给 drainSandboxCursor 分页读取。
+	// SET/RESET（sqlparse.SessionControl，含 SET ROLE、SET SESSION AUTHORIZATION）一律拒绝而非直接
+	// Exec：这类语句不受 READ ONLY 事务约束，放行会让学生先 RESET ROLE / 关掉 default_transaction_read_only
+	// 再执行任意写操作，彻底绕开沙箱
+	runSandboxQuery := func(ctx context.Context, qid, execSQL string, isQuery bool) {
+		start := time.Now()
+		defer func() {
+			cancelsMu.Lock()
+			delete(cancels, qid)
+			cancelsMu.Unlock()
+			h.sqlDriver.RecordQuery(courseObj.ID, start)
+		}()
+
+		if !sandbox.Allow(sandboxKey) {
+			_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "查询过于频繁，请稍后再试"})
+			return
 		}
+		release, err := sandbox.Acquire(ctx)
+		if err != nil {
+			_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "并发查询数已达上限，请稍后再试"})
+			return
+		}
+		defer release()
 
-		// 检查字符串字面量，避免在字符串内误判注释
-		if runes[i] == '\'' || runes[i] == '"' {
-			quote := runes[i]
-			result.WriteRune(runes[i])
-			i++
+		sandboxConnMu.Lock()
+		defer sandboxConnMu.Unlock()
 
-			// 处理字符串内容，直到找到匹配的引号
-			for i < len(runes) {
-				if runes[i] == quote {
-					result.WriteRune(runes[i])
-					i++
-					break
-				}
-				// 处理转义字符
-				if runes[i] == '\\' && i < len(runes)-1 {
-					result.WriteRune(runes[i])
-					i++
-					if i < len(runes) {
-						result.WriteRune(runes[i])
-						i++
-					}
-				} else {
-					result.WriteRune(runes[i])
-					i++
+		if !isQuery {
+			if sqlparse.Classify(execSQL) == sqlparse.SessionControl {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "沙箱模式下不允许执行 SET/RESET 等会话级语句"})
+				return
+			}
+			if isCreateTempTableStatement(execSQL) {
+				if !sandbox.ReserveTempTable(sandboxKey) {
+					_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "临时表数量已达沙箱配额上限"})
+					return
 				}
+				_, _ = sandboxConn.Exec(ctx, "SET default_transaction_read_only = off")
+				defer func() { _, _ = sandboxConn.Exec(context.Background(), "SET default_transaction_read_only = on") }()
 			}
-			continue
+			if _, err := sandboxConn.Exec(ctx, execSQL); err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+				return
+			}
+			_ = writeJSON(map[string]interface{}{"type": "complete", "queryId": qid, "rowCount": 0, "elapsedMs": time.Since(start).Milliseconds()})
+			return
 		}
 
-		// 普通字符
-		result.WriteRune(runes[i])
-		i++
-	}
-
-	return result.String()
-}
+		cursorName := sandboxCursorName(qid)
+		if _, err := sandboxConn.Exec(ctx, "BEGIN READ ONLY"); err != nil {
+			_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+			return
+		}
+		if _, err := sandboxConn.Exec(ctx, fmt.Sprintf("DECLARE %s CURSOR FOR %s", cursorName, execSQL)); err != nil {
+			_, _ = sandboxConn.Exec(ctx, "ROLLBACK")
+			_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": sqlWSErrorMessage(err)})
+			return
+		}
 
-// isWhitespace 检查字符是否为空白字符
-func isWhitespace(r rune) bool {
-	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' || r == '\v'
-}
+		sandboxCursorsMu.Lock()
+		sandboxCursors[qid] = &sandboxCursorState{cursorName: cursorName, start: start}
+		sandboxCursorsMu.Unlock()
 
-// handleSqlWebSocket 处理 SQL 终端的 WebSocket 通道
-// 协议：
-//   - 客户端发送 {type:"init", courseId:"..."} 进行初始化
-//   - 客户端发送 {type:"query", queryId:"uuid", sql:"SELECT 1"} 执行查询（简版，返回完整结果）
-//   - 客户端发送 {type:"ping"} 保活
-//   - 服务端返回 ready/info/result/complete/error/pong
-func (h *Handler) handleSqlWebSocket(c *gin.Context) {
-	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		h.logger.Error("SQL WebSocket升级失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "WebSocket连接升级失败"})
-		return
+		drainSandboxCursor(ctx, qid)
 	}
-	defer conn.Close()
-
-	var courseObj *course.Course
-	ctx := context.Background()
 
 	for {
 		var msg map[string]interface{}
@@ -1180,114 +3474,254 @@ func (h *Handler) handleSqlWebSocket(c *gin.Context) {
 		case "init":
 			courseID, _ := msg["courseId"].(string)
 			if strings.TrimSpace(courseID) == "" {
-				_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": "缺少 courseId"})
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "缺少 courseId"})
 				continue
 			}
 			if co, ok := h.courseService.GetCourse(courseID); ok {
 				courseObj = co
 			} else {
-				_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": "课程不存在"})
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "课程不存在"})
+				continue
+			}
+			// courseId 在升级完成后才随 init 消息送达，课程级权限校验延后到此处进行
+			if claims := auth.GetClaims(c); claims != nil && claims.Role != "teacher" && !claims.HasCourse(courseID) {
+				courseObj = nil
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "无权访问该课程"})
 				continue
 			}
+			if cs, ok := msg["chunkSize"].(float64); ok && cs > 0 {
+				chunkSize = int(cs)
+			}
+			if tm, ok := msg["timeoutMs"].(float64); ok && tm > 0 {
+				queryTimeoutMs = int(tm)
+			}
 			// 确保连接池就绪
-			if err := h.sqlDriver.EnsureReady(ctx, courseObj); err != nil {
-				_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": fmt.Sprintf("KWDB未就绪: %v", err)})
+			if err := h.sqlDriver.EnsureReady(baseCtx, courseObj); err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": fmt.Sprintf("KWDB未就绪: %v", err)})
 				continue
 			}
+			// 课程开启了沙箱模式：惰性创建只读角色并准备本次会话专用的沙箱连接
+			if courseObj.Backend.Sandbox.Enabled {
+				sb := courseObj.Backend.Sandbox
+				cfg := sql.NewSandboxConfig(sb.StatementTimeoutMs, sb.IdleInTransactionTimeoutMs, sb.MaxResultBytes, sb.MaxConcurrentQueries, sb.QueriesPerMinute, sb.MaxTempTables)
+				sandbox = h.sqlDriver.Sandbox(courseObj.ID, cfg)
+				sandboxKey = h.sandboxRateLimitKey(c, courseObj.ID)
+				roleName, err := sandbox.EnsureRole(baseCtx, h.sqlDriver.Pool(), sql.SandboxRoleName(courseObj.ID))
+				if err != nil {
+					sandbox = nil
+					_ = writeJSON(map[string]interface{}{"type": "error", "message": err.Error()})
+					continue
+				}
+				pc, err := h.sqlDriver.Pool().Acquire(baseCtx)
+				if err != nil {
+					sandbox = nil
+					_ = writeJSON(map[string]interface{}{"type": "error", "message": fmt.Sprintf("获取沙箱连接失败: %v", err)})
+					continue
+				}
+				if err := sandbox.ConfigureConn(baseCtx, pc, roleName); err != nil {
+					pc.Release()
+					sandbox = nil
+					_ = writeJSON(map[string]interface{}{"type": "error", "message": err.Error()})
+					continue
+				}
+				sandboxConn = pc
+			}
 			// ready + info
-			_ = conn.WriteJSON(map[string]interface{}{"type": "ready"})
-			_ = conn.WriteJSON(map[string]interface{}{"type": "info", "port": courseObj.Backend.Port, "connected": true})
+			_ = writeJSON(map[string]interface{}{"type": "ready"})
+			_ = writeJSON(map[string]interface{}{"type": "info", "port": courseObj.Backend.Port, "connected": true, "sandbox": sandbox != nil})
 		case "query":
 			if courseObj == nil || h.sqlDriver.Pool() == nil {
-				_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": "连接未初始化"})
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "连接未初始化"})
 				continue
 			}
 			sqlText, _ := msg["sql"].(string)
 			qid, _ := msg["queryId"].(string)
 			if strings.TrimSpace(sqlText) == "" {
-				_ = conn.WriteJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "SQL不能为空"})
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "SQL不能为空"})
+				continue
+			}
+			statements := sqlparse.SplitStatements(sqlText)
+			if len(statements) == 0 {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "SQL不能为空"})
+				continue
+			}
+			if sandbox != nil && len(statements) > 1 {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "沙箱模式下一次只能执行一条语句"})
 				continue
 			}
 
-			// 判断 SQL 语句类型：使用优化的函数检查是否为查询操作
-			// 支持跳过注释，能处理以注释开头的 SQL 语句
-			if isSelectQuery(sqlText) {
-				// 查询操作：使用 Query() 方法
-				rows, err := h.sqlDriver.Pool().Query(ctx, sqlText)
-				if err != nil {
-					_ = conn.WriteJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": err.Error()})
-					continue
-				}
-				defer rows.Close()
-
-				// 获取列信息
-				fieldDescs := rows.FieldDescriptions()
-				cols := make([]string, 0, len(fieldDescs))
-				for _, f := range fieldDescs {
-					cols = append(cols, string(f.Name))
-				}
-
-				// 获取行数据
-				outRows := make([][]interface{}, 0, 128)
-				for rows.Next() {
-					vals, err := rows.Values()
-					if err != nil {
-						_ = conn.WriteJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": err.Error()})
-						break
-					}
-
-					// 格式化时间戳数据，确保时区信息一致
-					formattedVals := make([]interface{}, len(vals))
-					for i, val := range vals {
-						if t, ok := val.(time.Time); ok {
-							// 将时间戳格式化为RFC3339，保留原始时区信息
-							formattedVals[i] = t.Format(time.RFC3339)
-						} else {
-							formattedVals[i] = val
-						}
+			queryCtx, cancel := context.WithTimeout(baseCtx, time.Duration(queryTimeoutMs)*time.Millisecond)
+			if qid != "" {
+				cancelsMu.Lock()
+				cancels[qid] = cancel
+				cancelsMu.Unlock()
+			}
+			// 异步执行：读循环需要继续处理后续的 cancel/ping 消息，不能被本次查询阻塞
+			if len(statements) == 1 {
+				// 单条语句走流式通道，支持大结果集分帧与 chunkSize
+				stmt := statements[0]
+				isQuery := sqlparse.Classify(stmt).IsQuery()
+				go func() {
+					defer cancel()
+					if sandbox != nil {
+						runSandboxQuery(queryCtx, qid, stmt, isQuery)
+					} else {
+						runQuery(queryCtx, h.sqlDriver.Pool(), qid, stmt, isQuery)
 					}
-
-					outRows = append(outRows, formattedVals)
-				}
-
-				h.logger.Debug("[handleSqlWebSocket] 查询结果，列: %v, 行: %v", cols, outRows)
-
-				// 返回查询结果（包含列和行数据）
-				_ = conn.WriteJSON(map[string]interface{}{
-					"type":     "result",
-					"queryId":  qid,
-					"columns":  cols,
-					"rows":     outRows,
-					"rowCount": len(outRows),
-					"hasMore":  false,
-				})
+				}()
 			} else {
-				// 数据修改操作：使用 Exec() 方法
-				commandTag, err := h.sqlDriver.Pool().Exec(ctx, sqlText)
-				if err != nil {
-					_ = conn.WriteJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": err.Error()})
-					continue
-				}
-
-				// 获取受影响的行数
-				rowsAffected := commandTag.RowsAffected()
+				// 一次 query 消息里夹带了多条由 ; 分隔的语句，按语句分类逐条执行并分别返回 result 帧
+				go func() {
+					defer cancel()
+					runBatch(queryCtx, qid, statements)
+				}()
+			}
+		case "fetchMore":
+			qid, _ := msg["queryId"].(string)
+			if cursor, ok := msg["cursor"].(string); ok && cursor != "" {
+				qid = cursor
+			}
+			if sandbox == nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "当前连接未启用沙箱模式"})
+				continue
+			}
+			fetchCtx, cancel := context.WithTimeout(baseCtx, time.Duration(queryTimeoutMs)*time.Millisecond)
+			if qid != "" {
+				cancelsMu.Lock()
+				cancels[qid] = cancel
+				cancelsMu.Unlock()
+			}
+			go func() {
+				defer cancel()
+				sandboxConnMu.Lock()
+				defer sandboxConnMu.Unlock()
+				drainSandboxCursor(fetchCtx, qid)
+			}()
+		case "prepare":
+			if courseObj == nil || h.sqlDriver.Pool() == nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "连接未初始化"})
+				continue
+			}
+			if sandbox != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "沙箱模式下不支持预处理语句"})
+				continue
+			}
+			name, _ := msg["name"].(string)
+			sqlText, _ := msg["sql"].(string)
+			if strings.TrimSpace(name) == "" || strings.TrimSpace(sqlText) == "" {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "prepare 缺少 name 或 sql"})
+				continue
+			}
+			pc, err := acquirePreparedConn(baseCtx)
+			if err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": fmt.Sprintf("获取专用连接失败: %v", err)})
+				continue
+			}
+			if _, err := pc.Conn().Prepare(baseCtx, name, sqlText); err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": fmt.Sprintf("prepare失败: %v", err)})
+				continue
+			}
+			preparedMu.Lock()
+			preparedStmts[name] = sqlText
+			preparedMu.Unlock()
+			_ = writeJSON(map[string]interface{}{"type": "prepared", "name": name})
+		case "execute":
+			if courseObj == nil || h.sqlDriver.Pool() == nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "连接未初始化"})
+				continue
+			}
+			if sandbox != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "沙箱模式下不支持预处理语句"})
+				continue
+			}
+			name, _ := msg["name"].(string)
+			qid, _ := msg["queryId"].(string)
+			preparedMu.Lock()
+			sqlText, ok := preparedStmts[name]
+			pc := preparedConn
+			preparedMu.Unlock()
+			if !ok || pc == nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": fmt.Sprintf("预处理语句不存在: %s", name)})
+				continue
+			}
+			var params []interface{}
+			if rawParams, ok := msg["params"].([]interface{}); ok {
+				params = rawParams
+			}
 
-				// 返回执行结果（无列数据，但包含受影响的行数）
-				_ = conn.WriteJSON(map[string]interface{}{
-					"type":     "result",
-					"queryId":  qid,
-					"columns":  []string{},
-					"rows":     [][]interface{}{},
-					"rowCount": int(rowsAffected),
-					"hasMore":  false,
-				})
+			queryCtx, cancel := context.WithTimeout(baseCtx, time.Duration(queryTimeoutMs)*time.Millisecond)
+			if qid != "" {
+				cancelsMu.Lock()
+				cancels[qid] = cancel
+				cancelsMu.Unlock()
+			}
+			isQuery := sqlparse.Classify(sqlText).IsQuery()
+			go func() {
+				defer cancel()
+				runQuery(queryCtx, pc.Conn(), qid, name, isQuery, params...)
+			}()
+		case "deallocate":
+			if sandbox != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "沙箱模式下不支持预处理语句"})
+				continue
+			}
+			name, _ := msg["name"].(string)
+			preparedMu.Lock()
+			pc := preparedConn
+			delete(preparedStmts, name)
+			preparedMu.Unlock()
+			if pc == nil {
+				continue
+			}
+			if err := pc.Conn().Deallocate(baseCtx, name); err != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": fmt.Sprintf("deallocate失败: %v", err)})
+				continue
+			}
+			_ = writeJSON(map[string]interface{}{"type": "deallocated", "name": name})
+		case "batch":
+			if courseObj == nil || h.sqlDriver.Pool() == nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "连接未初始化"})
+				continue
+			}
+			if sandbox != nil {
+				_ = writeJSON(map[string]interface{}{"type": "error", "message": "沙箱模式下不支持批量执行"})
+				continue
+			}
+			qid, _ := msg["queryId"].(string)
+			rawStatements, _ := msg["statements"].([]interface{})
+			if len(rawStatements) == 0 {
+				_ = writeJSON(map[string]interface{}{"type": "error", "queryId": qid, "message": "batch 缺少 statements"})
+				continue
+			}
+			statements := make([]string, 0, len(rawStatements))
+			for _, s := range rawStatements {
+				if stmt, ok := s.(string); ok && strings.TrimSpace(stmt) != "" {
+					statements = append(statements, stmt)
+				}
 			}
 
-			_ = conn.WriteJSON(map[string]interface{}{"type": "complete", "queryId": qid})
+			batchCtx, cancel := context.WithTimeout(baseCtx, time.Duration(queryTimeoutMs)*time.Millisecond)
+			if qid != "" {
+				cancelsMu.Lock()
+				cancels[qid] = cancel
+				cancelsMu.Unlock()
+			}
+			go func() {
+				defer cancel()
+				runBatch(batchCtx, qid, statements)
+			}()
+		case "cancel":
+			qid, _ := msg["queryId"].(string)
+			cancelsMu.Lock()
+			cancel, ok := cancels[qid]
+			cancelsMu.Unlock()
+			if ok {
+				cancel()
+			}
 		case "ping":
-			_ = conn.WriteJSON(map[string]interface{}{"type": "pong"})
+			_ = writeJSON(map[string]interface{}{"type": "pong"})
 		default:
-			_ = conn.WriteJSON(map[string]interface{}{"type": "error", "message": "未知消息类型"})
+			_ = writeJSON(map[string]interface{}{"type": "error", "message": "未知消息类型"})
 		}
 	}
 }
@@ -1506,65 +3940,109 @@ func (h *Handler) checkImageAvailability(c *gin.Context) {
 
 	h.logger.Info("[checkImageAvailability] 检查镜像可用性: %s", req.ImageName)
 
-	// 检查Docker控制器是否可用
-	if h.dockerController == nil {
-		h.logger.Error("[checkImageAvailability] Docker控制器未初始化")
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Docker服务暂不可用",
-		})
-		return
+	// 并行探测所有已配置镜像源（docker.io / 私有仓库 / 离线 tar 包目录）
+	ctx := context.Background()
+	mirrors := h.registryManager.CheckAvailability(ctx, req.ImageName)
+
+	available := false
+	for _, m := range mirrors {
+		if m.Available {
+			available = true
+			break
+		}
 	}
 
-	// 调用Docker控制器检查镜像可用性
-	ctx := context.Background()
-	availability, err := h.dockerController.CheckImageAvailability(ctx, req.ImageName)
-	if err != nil {
-		h.logger.Error("[checkImageAvailability] 检查失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("检查镜像可用性失败: %v", err),
-		})
-		return
+	// 结合历史EWMA延迟挑出当前最快可达的源，供前端提示"将优先从XX拉取"
+	bestMirror := ""
+	if best, err := h.dockerController.SelectBestMirror(ctx, h.registryManager.List(), req.ImageName); err == nil {
+		bestMirror = best.Name()
 	}
 
-	h.logger.Info("[checkImageAvailability] 检查完成: %s, 可用: %v", req.ImageName, availability.Available)
+	h.logger.Info("[checkImageAvailability] 检查完成: %s, 可用: %v", req.ImageName, available)
+
+	c.JSON(http.StatusOK, gin.H{
+		"imageName":  req.ImageName,
+		"available":  available,
+		"mirrors":    mirrors,
+		"bestMirror": bestMirror,
+	})
+}
 
-	c.JSON(http.StatusOK, availability)
+// getImageWarmStatus 返回启动阶段 docker.ImageWarmer 对所有课程镜像的探测结果
+// GET /api/images/status
+// 响应:
+//
+//	200: {"images": [...]} - 每个课程镜像最近一次探测/预拉取的可用性结果，未启用预热时为空列表
+func (h *Handler) getImageWarmStatus(c *gin.Context) {
+	if h.imageWarmer == nil {
+		c.JSON(http.StatusOK, gin.H{"images": []docker.ImageAvailability{}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"images": h.imageWarmer.Status()})
 }
 
-// getImageSources 获取可用的镜像源列表
+// getImageSources 获取已配置的镜像源列表（按优先级排序）
 // GET /api/images/sources
 // 响应:
 //
-//	200: 镜像源列表
+//	200: {"sources": [...]} - 镜像源列表
 func (h *Handler) getImageSources(c *gin.Context) {
 	h.logger.Info("[getImageSources] 获取镜像源列表")
 
-	// 定义常用的镜像源
-	sources := []gin.H{
-		{
-			"id":          "docker-hub",
-			"name":        "Docker Hub (官方)",
-			"prefix":      "",
-			"description": "Docker官方镜像仓库",
-			"example":     "kwdb/kwdb:latest",
-		},
-		{
-			"id":          "ghcr",
-			"name":        "GitHub Container Registry",
-			"prefix":      "ghcr.io/",
-			"description": "GitHub容器镜像仓库",
-			"example":     "ghcr.io/kwdb/kwdb:latest",
-		},
-		{
-			"id":          "custom",
-			"name":        "自定义源",
-			"prefix":      "",
-			"description": "使用自定义的镜像仓库地址",
-			"example":     "your-registry.com/kwdb/kwdb:latest",
-		},
+	sources := h.registryManager.List()
+	out := make([]gin.H, 0, len(sources))
+	for _, s := range sources {
+		out = append(out, gin.H{
+			"id":     s.ID(),
+			"name":   s.Name(),
+			"prefix": s.Prefix(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sources": out,
+	})
+}
+
+// addImageSource 注册一个用户自定义的通用 OCI Distribution v2 镜像源
+// POST /api/images/sources
+// 请求体: {"id": "harbor", "url": "harbor.example.com", "username": "ci", "password": "***"}
+// 凭据加密后持久化到 cfg.DataDir/registry/sources.json，重启后仍然生效
+func (h *Handler) addImageSource(c *gin.Context) {
+	var req struct {
+		ID       string `json:"id" binding:"required"`
+		URL      string `json:"url" binding:"required"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "参数错误: id 与 url 不能为空"})
+		return
+	}
+
+	src, err := h.registryManager.AddSource(req.ID, req.URL, req.Username, req.Password)
+	if err != nil {
+		h.logger.Error("[addImageSource] 添加镜像源失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
+	h.logger.Info("[addImageSource] 已添加镜像源: %s (%s)", src.ID(), src.Prefix())
 	c.JSON(http.StatusOK, gin.H{
-		"sources": sources,
+		"id":     src.ID(),
+		"name":   src.Name(),
+		"prefix": src.Prefix(),
 	})
 }
+
+// deleteImageSource 删除一个用户自定义镜像源
+// DELETE /api/images/sources/:id
+func (h *Handler) deleteImageSource(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.registryManager.RemoveSource(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Info("[deleteImageSource] 已删除镜像源: %s", id)
+	c.JSON(http.StatusOK, gin.H{"message": "镜像源已删除", "id": id})
+}
@@ -0,0 +1,153 @@
+// Package audit 记录课程/容器/SQL 的变更类操作为结构化 JSON 日志，供课堂运维与事后排查使用。
+// 每条记录落盘为一行 JSON（便于 grep/jq 或常见日志采集器直接解析），文件按大小滚动；
+// 另外可选地把同一条记录转发到 syslog（UDP）或任意 HTTP 日志接收端点，
+// 满足把审计日志汇聚到已有可观测性后端的需求。
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/config"
+	"kwdb-playground/internal/logger"
+)
+
+// Entry 单条审计记录
+type Entry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	SessionID   string    `json:"sessionId,omitempty"`
+	CourseID    string    `json:"courseId,omitempty"`
+	Action      string    `json:"action"`
+	ContainerID string    `json:"containerId,omitempty"`
+	DurationMs  int64     `json:"durationMs"`
+	Outcome     string    `json:"outcome"`
+	ErrorClass  string    `json:"errorClass,omitempty"`
+}
+
+// maxLogBytes 单个审计日志文件的滚动阈值，超过后重命名为 .1（覆盖上一份）并重新创建
+const maxLogBytes = 50 * 1024 * 1024
+
+// Logger 审计日志记录器：落盘 + 可选外发，外发失败只记录警告，不影响主流程
+type Logger struct {
+	mu         sync.Mutex
+	path       string
+	file       *os.File
+	size       int64
+	syslogConn net.Conn // 可选：syslog（UDP）转发
+	sinkURL    string   // 可选：以 JSON POST 转发到的外部日志接收端点
+	httpClient *http.Client
+	log        *logger.Logger
+}
+
+// NewLogger 依据 config.AuditConfig 构建审计日志记录器
+// cfg.LogPath 为空时退化为不落盘（仍可能转发到 syslog/HTTP），cfg 整体为零值时等价于完全关闭审计
+func NewLogger(cfg config.AuditConfig, log *logger.Logger) *Logger {
+	l := &Logger{log: log, sinkURL: cfg.OTLPEndpoint, httpClient: &http.Client{Timeout: 3 * time.Second}}
+
+	if cfg.LogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(cfg.LogPath), 0755); err != nil {
+			log.Warn("[audit] 创建审计日志目录失败: %v", err)
+		} else if f, err := os.OpenFile(cfg.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+			log.Warn("[audit] 打开审计日志文件失败: %v", err)
+		} else {
+			l.path = cfg.LogPath
+			l.file = f
+			if info, err := f.Stat(); err == nil {
+				l.size = info.Size()
+			}
+		}
+	}
+
+	if cfg.SyslogAddr != "" {
+		conn, err := net.Dial("udp", cfg.SyslogAddr)
+		if err != nil {
+			log.Warn("[audit] 连接 syslog 地址 %s 失败: %v", cfg.SyslogAddr, err)
+		} else {
+			l.syslogConn = conn
+		}
+	}
+
+	return l
+}
+
+// Record 写入一条审计记录（补全时间戳后落盘、转发）
+func (l *Logger) Record(e Entry) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		l.log.Warn("[audit] 序列化审计记录失败: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	if l.file != nil {
+		l.rotateIfNeededLocked()
+		if n, werr := l.file.Write(append(data, '\n')); werr != nil {
+			l.log.Warn("[audit] 写入审计日志失败: %v", werr)
+		} else {
+			l.size += int64(n)
+		}
+	}
+	l.mu.Unlock()
+
+	if l.syslogConn != nil {
+		if _, err := l.syslogConn.Write(data); err != nil {
+			l.log.Warn("[audit] 转发审计记录到 syslog 失败: %v", err)
+		}
+	}
+
+	if l.sinkURL != "" {
+		go l.forwardHTTP(data)
+	}
+}
+
+// rotateIfNeededLocked 文件大小超过阈值时滚动：重命名为 .1 后重新创建，调用方必须持有 l.mu
+func (l *Logger) rotateIfNeededLocked() {
+	if l.size < maxLogBytes {
+		return
+	}
+	l.file.Close()
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		l.log.Warn("[audit] 滚动审计日志失败: %v", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		l.log.Warn("[audit] 重新创建审计日志文件失败: %v", err)
+		l.file = nil
+		return
+	}
+	l.file = f
+	l.size = 0
+}
+
+// forwardHTTP 将单条记录以 JSON POST 转发到配置的日志接收端点
+func (l *Logger) forwardHTTP(data []byte) {
+	resp, err := l.httpClient.Post(l.sinkURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		l.log.Warn("[audit] 转发审计记录到 %s 失败: %v", l.sinkURL, err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// Close 关闭底层文件与连接
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.syslogConn != nil {
+		l.syslogConn.Close()
+	}
+	if l.file != nil {
+		return l.file.Close()
+	}
+	return nil
+}
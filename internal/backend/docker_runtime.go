@@ -0,0 +1,109 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"kwdb-playground/internal/docker"
+)
+
+// DockerRuntime 基于本地/远程 Docker 守护进程实现 Runtime 接口
+// 内部直接委托给现有的 docker.Controller，保持与单机场景完全一致的行为
+type DockerRuntime struct {
+	controller docker.Controller
+}
+
+// NewDockerRuntime 基于现有的 docker.Controller 创建 DockerRuntime
+func NewDockerRuntime(controller docker.Controller) *DockerRuntime {
+	return &DockerRuntime{controller: controller}
+}
+
+// CreateWorkload 创建容器，等价于 docker.Controller.CreateContainer
+func (r *DockerRuntime) CreateWorkload(ctx context.Context, cfg WorkloadConfig) (*Workload, error) {
+	containerInfo, err := r.controller.CreateContainer(ctx, cfg.CourseID, &docker.ContainerConfig{
+		Image:      cfg.Image,
+		Env:        cfg.Env,
+		Ports:      map[string]string{"26257": fmt.Sprintf("%d", cfg.Port)},
+		Volumes:    cfg.Volumes,
+		WorkingDir: cfg.WorkingDir,
+		Cmd:        cfg.Cmd,
+		Privileged: cfg.Privileged,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workloadFromContainer(containerInfo), nil
+}
+
+// StartWorkload 启动容器
+func (r *DockerRuntime) StartWorkload(ctx context.Context, id string) error {
+	return r.controller.StartContainer(ctx, id)
+}
+
+// StopWorkload 停止并删除容器，使语义与其他后端的"清理工作负载"保持一致
+func (r *DockerRuntime) StopWorkload(ctx context.Context, id string) error {
+	if err := r.controller.StopContainer(ctx, id); err != nil {
+		return err
+	}
+	return r.controller.RemoveContainer(ctx, id)
+}
+
+// Logs 获取容器日志
+func (r *DockerRuntime) Logs(ctx context.Context, id string, tailLines int) (string, error) {
+	reader, err := r.controller.GetContainerLogs(ctx, id, tailLines, false)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Exec 在容器内执行交互式命令
+func (r *DockerRuntime) Exec(ctx context.Context, id string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	_, err := r.controller.ExecCommandInteractive(ctx, id, cmd, stdin, stdout, stderr, docker.ExecOptions{Tty: true})
+	return err
+}
+
+// ListWorkloads 列出所有 Playground 容器
+func (r *DockerRuntime) ListWorkloads(ctx context.Context) ([]*Workload, error) {
+	containers, err := r.controller.ListContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	workloads := make([]*Workload, 0, len(containers))
+	for _, c := range containers {
+		workloads = append(workloads, workloadFromContainer(c))
+	}
+	return workloads, nil
+}
+
+// PortForward 对 Docker 而言端口在容器创建时已通过 HostConfig.PortBindings 发布到宿主机，
+// 因此这里直接把课程配置的端口原样返回，不需要额外建立转发会话
+func (r *DockerRuntime) PortForward(ctx context.Context, id string, remotePort int) (int, func() error, error) {
+	return remotePort, func() error { return nil }, nil
+}
+
+// workloadFromContainer 将 docker.ContainerInfo 映射为后端无关的 Workload
+func workloadFromContainer(c *docker.ContainerInfo) *Workload {
+	state := WorkloadPending
+	switch c.State {
+	case docker.StateRunning, docker.StateStarting:
+		state = WorkloadRunning
+	case docker.StateStopped, docker.StateExited:
+		state = WorkloadStopped
+	case docker.StateError, docker.StateOOMKilled:
+		state = WorkloadError
+	}
+	return &Workload{
+		ID:       c.ID,
+		CourseID: c.CourseID,
+		State:    state,
+		Image:    c.Image,
+		Message:  c.Message,
+	}
+}
@@ -0,0 +1,329 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+const (
+	// podLabelCourse 标记 Pod/Service 归属的课程ID，ListWorkloads 据此筛选出本运行时管理的对象
+	podLabelCourse = "kwdb-playground/course-id"
+	// workloadContainerName Pod 内唯一容器的名字，固定值以简化 exec/logs 定位
+	workloadContainerName = "course"
+)
+
+// KubernetesRuntime 基于共享 Kubernetes 集群实现 Runtime 接口
+// 每个工作负载物化为一个 Pod，并配一个同名 Service 暴露课程端口，workload ID 形如 "<namespace>/<podName>"
+type KubernetesRuntime struct {
+	clientset  kubernetes.Interface
+	restConfig *rest.Config
+}
+
+// NewKubernetesRuntime 基于 client-go 的 Clientset 和集群配置创建 KubernetesRuntime
+// restConfig 用于建立 exec/port-forward 所需的 SPDY 连接
+func NewKubernetesRuntime(clientset kubernetes.Interface, restConfig *rest.Config) *KubernetesRuntime {
+	return &KubernetesRuntime{clientset: clientset, restConfig: restConfig}
+}
+
+// CreateWorkload 创建 Pod + Service，课程容器固定监听 26257
+func (r *KubernetesRuntime) CreateWorkload(ctx context.Context, cfg WorkloadConfig) (*Workload, error) {
+	namespace := cfg.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	name := podName(cfg.CourseID)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{podLabelCourse: cfg.CourseID},
+		},
+		Spec: corev1.PodSpec{
+			NodeSelector:  cfg.NodeSelector,
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:       workloadContainerName,
+					Image:      cfg.Image,
+					Command:    cfg.Cmd,
+					WorkingDir: cfg.WorkingDir,
+					Env:        toEnvVars(cfg.Env),
+					Resources:  toResourceRequirements(cfg.Resources),
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: &cfg.Privileged,
+					},
+					Ports: []corev1.ContainerPort{
+						{ContainerPort: 26257},
+					},
+				},
+			},
+		},
+	}
+
+	createdPod, err := r.clientset.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pod: %w", err)
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{podLabelCourse: cfg.CourseID},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{podLabelCourse: cfg.CourseID},
+			Type:     corev1.ServiceTypeNodePort,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "sql",
+					Port:       26257,
+					TargetPort: intstr.FromInt(26257),
+				},
+			},
+		},
+	}
+	if _, err := r.clientset.CoreV1().Services(namespace).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create service: %w", err)
+	}
+
+	return &Workload{
+		ID:       workloadID(namespace, name),
+		CourseID: cfg.CourseID,
+		State:    podStateToWorkloadState(createdPod.Status.Phase),
+		Image:    cfg.Image,
+	}, nil
+}
+
+// StartWorkload Kubernetes 中 Pod 在创建后由调度器自动拉起，无需额外启动动作
+func (r *KubernetesRuntime) StartWorkload(ctx context.Context, id string) error {
+	return nil
+}
+
+// StopWorkload 删除工作负载对应的 Pod 与 Service
+func (r *KubernetesRuntime) StopWorkload(ctx context.Context, id string) error {
+	namespace, name, err := splitWorkloadID(id)
+	if err != nil {
+		return err
+	}
+	if err := r.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete pod: %w", err)
+	}
+	if err := r.clientset.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete service: %w", err)
+	}
+	return nil
+}
+
+// Logs 获取 Pod 日志
+func (r *KubernetesRuntime) Logs(ctx context.Context, id string, tailLines int) (string, error) {
+	namespace, name, err := splitWorkloadID(id)
+	if err != nil {
+		return "", err
+	}
+	tail := int64(tailLines)
+	req := r.clientset.CoreV1().Pods(namespace).GetLogs(name, &corev1.PodLogOptions{
+		Container: workloadContainerName,
+		TailLines: &tail,
+	})
+	stream, err := req.Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to open log stream: %w", err)
+	}
+	defer stream.Close()
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return "", fmt.Errorf("failed to read log stream: %w", err)
+	}
+	return string(data), nil
+}
+
+// Exec 通过 SPDY 在 Pod 内执行交互式命令，转发 stdin/stdout/stderr
+func (r *KubernetesRuntime) Exec(ctx context.Context, id string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	namespace, name, err := splitWorkloadID(id)
+	if err != nil {
+		return err
+	}
+
+	req := r.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(name).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: workloadContainerName,
+			Command:   cmd,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+			TTY:       true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(r.restConfig, "POST", req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create SPDY executor: %w", err)
+	}
+
+	return executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+		Tty:    true,
+	})
+}
+
+// ListWorkloads 列出所有带课程标签的 Pod
+func (r *KubernetesRuntime) ListWorkloads(ctx context.Context) ([]*Workload, error) {
+	pods, err := r.clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		LabelSelector: podLabelCourse,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	workloads := make([]*Workload, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		image := ""
+		if len(pod.Spec.Containers) > 0 {
+			image = pod.Spec.Containers[0].Image
+		}
+		workloads = append(workloads, &Workload{
+			ID:       workloadID(pod.Namespace, pod.Name),
+			CourseID: pod.Labels[podLabelCourse],
+			State:    podStateToWorkloadState(pod.Status.Phase),
+			Image:    image,
+			Message:  pod.Status.Message,
+		})
+	}
+	return workloads, nil
+}
+
+// PortForward 通过 client-go 的 portforward 子资源建立一条到 Pod 的转发隧道，返回本机可连接的端口
+func (r *KubernetesRuntime) PortForward(ctx context.Context, id string, remotePort int) (int, func() error, error) {
+	namespace, name, err := splitWorkloadID(id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req := r.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(name).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(r.restConfig)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to build SPDY round tripper: %w", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	readyCh := make(chan struct{})
+	stopCh := make(chan struct{})
+	errOut := &bytes.Buffer{}
+	pf, err := portforward.New(dialer, []string{fmt.Sprintf("0:%d", remotePort)}, stopCh, readyCh, io.Discard, errOut)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to set up port-forward: %w", err)
+	}
+
+	forwardErrCh := make(chan error, 1)
+	go func() {
+		forwardErrCh <- pf.ForwardPorts()
+	}()
+
+	select {
+	case <-readyCh:
+	case err := <-forwardErrCh:
+		return 0, nil, fmt.Errorf("port-forward exited before becoming ready: %w", err)
+	}
+
+	ports, err := pf.GetPorts()
+	if err != nil || len(ports) == 0 {
+		close(stopCh)
+		return 0, nil, fmt.Errorf("failed to determine forwarded local port: %w", err)
+	}
+
+	closeFn := func() error {
+		close(stopCh)
+		if errOut.Len() > 0 {
+			return fmt.Errorf("port-forward error: %s", errOut.String())
+		}
+		return nil
+	}
+	return int(ports[0].Local), closeFn, nil
+}
+
+func podName(courseID string) string {
+	return fmt.Sprintf("kwdb-playground-%s", strings.ToLower(courseID))
+}
+
+func workloadID(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func splitWorkloadID(id string) (namespace, name string, err error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid kubernetes workload id %q, expected \"<namespace>/<name>\"", id)
+	}
+	return parts[0], parts[1], nil
+}
+
+func podStateToWorkloadState(phase corev1.PodPhase) WorkloadState {
+	switch phase {
+	case corev1.PodRunning:
+		return WorkloadRunning
+	case corev1.PodSucceeded, corev1.PodFailed:
+		return WorkloadStopped
+	case corev1.PodPending:
+		return WorkloadPending
+	default:
+		return WorkloadError
+	}
+}
+
+func toEnvVars(env map[string]string) []corev1.EnvVar {
+	if len(env) == 0 {
+		return nil
+	}
+	vars := make([]corev1.EnvVar, 0, len(env))
+	for k, v := range env {
+		vars = append(vars, corev1.EnvVar{Name: k, Value: v})
+	}
+	return vars
+}
+
+func toResourceRequirements(limits ResourceLimits) corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: toResourceList(limits.Requests),
+		Limits:   toResourceList(limits.Limits),
+	}
+}
+
+func toResourceList(values map[string]string) corev1.ResourceList {
+	if len(values) == 0 {
+		return nil
+	}
+	list := make(corev1.ResourceList, len(values))
+	for name, value := range values {
+		if qty, err := resource.ParseQuantity(value); err == nil {
+			list[corev1.ResourceName(name)] = qty
+		}
+	}
+	return list
+}
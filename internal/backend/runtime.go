@@ -0,0 +1,89 @@
+// Package backend 定义课程工作负载的可插拔运行时后端
+// 同一套课程配置既可以跑在学生本地的 Docker 上，也可以跑在共享的 Kubernetes 集群中，
+// api.Handler 只依赖 Runtime 接口，具体由哪种后端承载由课程的 backend.runtime 字段决定。
+package backend
+
+import (
+	"context"
+	"io"
+)
+
+// RuntimeKind 运行时类型，对应课程 YAML 中的 backend.runtime
+type RuntimeKind string
+
+const (
+	// RuntimeDocker 本地/远程 Docker 守护进程（默认）
+	RuntimeDocker RuntimeKind = "docker"
+	// RuntimeKubernetes 共享 Kubernetes 集群，课程被物化为 Pod + Service
+	RuntimeKubernetes RuntimeKind = "kubernetes"
+)
+
+// WorkloadConfig 创建课程工作负载所需的配置，与具体后端无关
+type WorkloadConfig struct {
+	CourseID     string
+	Image        string
+	Env          map[string]string
+	Port         int // 课程对外暴露的端口（容器内固定监听 26257）
+	WorkingDir   string
+	Cmd          []string
+	Volumes      map[string]string
+	Privileged   bool
+	Namespace    string            // 仅 kubernetes 使用，默认 "default"
+	NodeSelector map[string]string // 仅 kubernetes 使用
+	Resources    ResourceLimits    // 仅 kubernetes 使用
+}
+
+// ResourceLimits 资源请求/限制，取值格式与 Kubernetes 一致，如 "500m"、"256Mi"
+type ResourceLimits struct {
+	Requests map[string]string
+	Limits   map[string]string
+}
+
+// WorkloadState 工作负载状态，跨后端统一表达
+type WorkloadState string
+
+const (
+	WorkloadPending WorkloadState = "pending"
+	WorkloadRunning WorkloadState = "running"
+	WorkloadStopped WorkloadState = "stopped"
+	WorkloadError   WorkloadState = "error"
+)
+
+// Workload 工作负载信息，跨后端统一表达（Docker 容器 / Kubernetes Pod）
+type Workload struct {
+	ID       string // 后端内部标识：Docker 为容器名，Kubernetes 为 "<namespace>/<podName>"
+	CourseID string
+	State    WorkloadState
+	Image    string
+	Message  string
+}
+
+// Runtime 课程工作负载的运行时后端接口
+// 实现方需要是并发安全的
+type Runtime interface {
+	// CreateWorkload 为课程创建工作负载（Docker容器 / K8s Pod+Service），但不保证已经在运行
+	CreateWorkload(ctx context.Context, cfg WorkloadConfig) (*Workload, error)
+	// StartWorkload 启动已创建的工作负载
+	StartWorkload(ctx context.Context, id string) error
+	// StopWorkload 停止并清理工作负载
+	StopWorkload(ctx context.Context, id string) error
+	// Logs 获取工作负载日志
+	Logs(ctx context.Context, id string, tailLines int) (string, error)
+	// Exec 在工作负载内执行交互式命令，双向转发 stdin/stdout/stderr
+	Exec(ctx context.Context, id string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) error
+	// ListWorkloads 列出该后端管理的所有工作负载
+	ListWorkloads(ctx context.Context) ([]*Workload, error)
+	// PortForward 建立到工作负载端口的转发，返回本机可连接的端口
+	// Docker 后端可直接返回容器启动时已发布的宿主机端口；Kubernetes 后端需建立实际的 port-forward 会话
+	PortForward(ctx context.Context, id string, remotePort int) (localPort int, closeFn func() error, err error)
+}
+
+// KindForCourse 根据课程配置的 backend.runtime 字段返回运行时类型，空值默认为 Docker
+func KindForCourse(runtime string) RuntimeKind {
+	switch RuntimeKind(runtime) {
+	case RuntimeKubernetes:
+		return RuntimeKubernetes
+	default:
+		return RuntimeDocker
+	}
+}
@@ -1,29 +1,53 @@
 package check
 
 import (
-	"context"
 	"embed"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
+	"kwdb-playground/internal/check/portowner"
 	"kwdb-playground/internal/config"
 	"kwdb-playground/internal/course"
 	"kwdb-playground/internal/docker"
+	"kwdb-playground/internal/metrics"
+)
+
+// Severity 表示单项检查结果的严重程度，供脚本/CI按优先级分流处理
+type Severity string
+
+const (
+	SeverityError Severity = "error" // 阻断性问题，必须修复
+	SeverityWarn  Severity = "warn"  // 可降级运行，但建议关注
+	SeverityInfo  Severity = "info"  // 正常/信息性结果
+)
+
+// 稳定的检查项代码，供脚本按 Code 而非中文 Message 做判断
+const (
+	CodeDockerUnavailable     = "docker.unavailable"
+	CodeRegistryNoneReachable = "registry.none_reachable"
+	CodeRegistryDegraded      = "registry.degraded"
+	CodePortOccupied          = "port.occupied"
+	CodeCourseIntegrity       = "course.integrity"
+	CodeServiceUnhealthy      = "service.unhealthy"
+	CodeOK                    = "ok"
 )
 
 // Item 单项检查结果
 type Item struct {
-	Name    string `json:"name"`
-	OK      bool   `json:"ok"`
-	Message string `json:"message"`
-	Details string `json:"details,omitempty"`
+	Name       string                `json:"name"`
+	Code       string                `json:"code"`
+	OK         bool                  `json:"ok"`
+	Severity   Severity              `json:"severity"`
+	Message    string                `json:"message"`
+	Details    string                `json:"details,omitempty"`
+	Hint       string                `json:"hint,omitempty"`
+	PortOwners []portowner.PortOwner `json:"port_owners,omitempty"`
 }
 
 // Summary 检查汇总
@@ -32,25 +56,74 @@ type Summary struct {
 	Items []Item `json:"items"`
 }
 
+// HasErrors 是否存在 error 级别的检查项
+func (s Summary) HasErrors() bool {
+	for _, it := range s.Items {
+		if it.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// HasWarnings 是否存在 warn 级别的检查项
+func (s Summary) HasWarnings() bool {
+	for _, it := range s.Items {
+		if it.Severity == SeverityWarn {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode 按退出码契约折算本次检查结果：0 全部通过，1 存在警告，2 存在错误
+// 供 CLI（及 CI 流水线）在不解析中文文案的情况下判断结果
+func (s Summary) ExitCode() int {
+	switch {
+	case s.HasErrors():
+		return 2
+	case s.HasWarnings():
+		return 1
+	default:
+		return 0
+	}
+}
+
 // RunFromConfig 使用配置与课程来源（嵌入或磁盘）执行检查
 func RunFromConfig(staticFiles embed.FS, cfg *config.Config) Summary {
 	items := make([]Item, 0, 5)
 
 	// 1) Docker
 	dockerOK, dockerMsg := DockerEnv()
-	items = append(items, Item{Name: "Docker 环境", OK: dockerOK, Message: dockerMsg})
+	items = append(items, Item{
+		Name: "Docker 环境", Code: codeOr(dockerOK, CodeDockerUnavailable), OK: dockerOK,
+		Severity: severityOr(dockerOK, SeverityError), Message: dockerMsg,
+		Hint: hintIf(!dockerOK, "请确认 Docker 已安装并启动，且当前用户有权访问 Docker socket"),
+	})
 
 	// 2) 镜像源可用性
 	imageOK, imageMsg, imageDetails := ImageSourcesAvailability()
-	items = append(items, Item{Name: "镜像源可用性", OK: imageOK, Message: imageMsg, Details: imageDetails})
+	imageCode := CodeOK
+	imageSeverity := SeverityInfo
+	switch {
+	case !imageOK:
+		imageCode, imageSeverity = CodeRegistryNoneReachable, SeverityError
+	case strings.Contains(imageMsg, "不可用"):
+		imageCode, imageSeverity = CodeRegistryDegraded, SeverityWarn
+	}
+	items = append(items, Item{
+		Name: "镜像源可用性", Code: imageCode, OK: imageOK, Severity: imageSeverity,
+		Message: imageMsg, Details: imageDetails,
+		Hint: hintIf(imageSeverity != SeverityInfo, "请检查网络连通性，或在配置中调整镜像源优先级/新增可用镜像"),
+	})
 
 	// 3) 端口占用
-	portOK, portMsg, procInfo := PortOccupation(cfg.Server.Host, cfg.Server.Port)
-	details := ""
-	if !portOK && procInfo != "" {
-		details = procInfo
-	}
-	items = append(items, Item{Name: fmt.Sprintf("端口占用 (%s:%d)", cfg.Server.Host, cfg.Server.Port), OK: portOK, Message: portMsg, Details: details})
+	portOK, portMsg, owners := PortOccupation(cfg.Server.Host, cfg.Server.Port)
+	items = append(items, Item{
+		Name: fmt.Sprintf("端口占用 (%s:%d)", cfg.Server.Host, cfg.Server.Port), Code: codeOr(portOK, CodePortOccupied),
+		OK: portOK, Severity: severityOr(portOK, SeverityError), Message: portMsg, Details: renderPortOwners(owners),
+		Hint: hintIf(!portOK, "请停止占用该端口的进程，或通过 --host/--port 更换监听地址"), PortOwners: owners,
+	})
 
 	// 4) 课程加载与完整性
 	var svc *course.Service
@@ -61,21 +134,66 @@ func RunFromConfig(staticFiles embed.FS, cfg *config.Config) Summary {
 	}
 	_ = svc.LoadCourses()
 	coursesOK, coursesMsg := CoursesIntegrity(svc)
-	items = append(items, Item{Name: "课程加载与完整性", OK: coursesOK, Message: coursesMsg})
+	items = append(items, Item{
+		Name: "课程加载与完整性", Code: codeOr(coursesOK, CodeCourseIntegrity), OK: coursesOK,
+		Severity: severityOr(coursesOK, SeverityError), Message: coursesMsg,
+		Hint: hintIf(!coursesOK, "请检查课程目录/嵌入资源中缺失标题、步骤说明或 Intro/Finish 文本的课程"),
+	})
 
 	// 5) 服务健康
 	serviceOK, serviceMsg := ServiceHealth(cfg.Server.Host, cfg.Server.Port)
-	items = append(items, Item{Name: fmt.Sprintf("服务健康检查 (%s:%d)", cfg.Server.Host, cfg.Server.Port), OK: serviceOK, Message: serviceMsg})
+	items = append(items, Item{
+		Name: fmt.Sprintf("服务健康检查 (%s:%d)", cfg.Server.Host, cfg.Server.Port), Code: codeOr(serviceOK, CodeServiceUnhealthy),
+		OK: serviceOK, Severity: severityOr(serviceOK, SeverityError), Message: serviceMsg,
+		Hint: hintIf(!serviceOK, "请确认服务已启动且 /health 端点可访问；服务尚未启动时本项视为通过，不会触发该提示"),
+	})
 
 	ok := true
+	checkItems := make([]metrics.CheckItemStatus, 0, len(items))
 	for _, it := range items {
 		if !it.OK {
 			ok = false
 		}
+		checkItems = append(checkItems, metrics.CheckItemStatus{Name: it.Name, OK: it.OK})
 	}
+	metrics.DefaultRecorder.SetCheckItems(checkItems)
+
 	return Summary{OK: ok, Items: items}
 }
 
+// RunFromConfigJSON 与 RunFromConfig 等价，执行检查后直接将 Summary 以 JSON 写入 w
+// 供 --format=json 的 CLI 输出以及其他希望以机器可读格式消费检查结果的调用方使用
+func RunFromConfigJSON(staticFiles embed.FS, cfg *config.Config, w io.Writer) (Summary, error) {
+	summary := RunFromConfig(staticFiles, cfg)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return summary, enc.Encode(summary)
+}
+
+// codeOr 在检查通过时返回 CodeOK，否则返回对应的失败代码
+func codeOr(ok bool, failCode string) string {
+	if ok {
+		return CodeOK
+	}
+	return failCode
+}
+
+// severityOr 在检查通过时返回 info，否则返回调用方指定的失败严重级别
+func severityOr(ok bool, failSeverity Severity) Severity {
+	if ok {
+		return SeverityInfo
+	}
+	return failSeverity
+}
+
+// hintIf 仅在条件成立时返回修复建议，便于 JSON/JUnit 输出中省略空字段
+func hintIf(cond bool, hint string) string {
+	if !cond {
+		return ""
+	}
+	return hint
+}
+
 // DockerEnv 检查 Docker 是否可用
 func DockerEnv() (bool, string) {
 	controller, err := docker.NewController()
@@ -86,31 +204,58 @@ func DockerEnv() (bool, string) {
 	return true, "Docker 客户端与守护进程连接正常"
 }
 
-// PortOccupation 检查端口占用
-func PortOccupation(host string, port int) (bool, string, string) {
+// PortOccupation 检查端口占用，第三个返回值为占用该端口的进程列表（跨平台，不依赖 lsof）
+func PortOccupation(host string, port int) (bool, string, []portowner.PortOwner) {
 	addr := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", addr, 800*time.Millisecond)
 	if err != nil {
-		return true, "端口未被占用，可用", ""
+		return true, "端口未被占用，可用", nil
 	}
 	_ = conn.Close()
 
 	if IsPortUsedByCurrentService(host, port) {
-		return true, "端口被本服务使用（正常）", ""
+		return true, "端口被本服务使用（正常）", nil
 	}
 
-	procInfo, lerr := ListPortProcesses(port)
+	owners, lerr := portowner.ListPortOwners(port)
 	if lerr != nil {
-		return false, "端口已被占用（进程信息获取失败，可能未安装 lsof）", ""
+		return false, fmt.Sprintf("端口已被占用（进程信息获取失败：%v）", lerr), nil
+	}
+	if len(owners) == 0 {
+		return false, "端口已被占用（但未能获取到进程信息）", nil
 	}
-	if procInfo == "" {
-		return false, "端口已被占用（但未能获取到进程信息）", ""
+	return false, "端口已被占用", owners
+}
+
+// renderPortOwners 将结构化的端口占用进程列表渲染为一行一条的文本，供 Item.Details 与CLI文本输出复用
+func renderPortOwners(owners []portowner.PortOwner) string {
+	if len(owners) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(owners))
+	for _, o := range owners {
+		line := fmt.Sprintf("pid=%d", o.PID)
+		if o.Cmdline != "" {
+			line += fmt.Sprintf(" cmd=%s", o.Cmdline)
+		}
+		if o.Exe != "" {
+			line += fmt.Sprintf(" exe=%s", o.Exe)
+		}
+		if o.User != "" {
+			line += fmt.Sprintf(" user=%s", o.User)
+		}
+		lines = append(lines, line)
 	}
-	return false, "端口已被占用", procInfo
+	return strings.Join(lines, "\n")
 }
 
-// IsPortUsedByCurrentService 通过 /health 识别是否为本服务
+// IsPortUsedByCurrentService 识别占用该端口的是否为本服务：优先看 /health，
+// 若 /health 不可用或响应不符合预期，再看 /metrics 是否暴露了本服务的指标作为补充证据
 func IsPortUsedByCurrentService(host string, port int) bool {
+	return isSelfHealth(host, port) || isSelfMetrics(host, port)
+}
+
+func isSelfHealth(host string, port int) bool {
 	url := fmt.Sprintf("http://%s:%d/health", host, port)
 	client := &http.Client{Timeout: 800 * time.Millisecond}
 	resp, err := client.Get(url)
@@ -128,20 +273,23 @@ func IsPortUsedByCurrentService(host string, port int) bool {
 	return strings.ToLower(payload.Status) == "ok" && strings.Contains(payload.Message, "KWDB Playground")
 }
 
-// ListPortProcesses 使用 lsof 列出监听进程（最佳努力）
-func ListPortProcesses(port int) (string, error) {
-	cmd := exec.Command("lsof", "-i", fmt.Sprintf(":%d", port), "-sTCP:LISTEN", "-n", "-P")
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-	cmd = exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...)
-	out, _ := cmd.CombinedOutput()
-	if ctx.Err() == context.DeadlineExceeded {
-		return "", fmt.Errorf("查询端口占用超时")
+// isSelfMetrics 探测 /metrics，以响应体中出现本服务的指标名前缀作为属于本服务的证据
+func isSelfMetrics(host string, port int) bool {
+	url := fmt.Sprintf("http://%s:%d/metrics", host, port)
+	client := &http.Client{Timeout: 800 * time.Millisecond}
+	resp, err := client.Get(url)
+	if err != nil {
+		return false
 	}
-	if len(out) == 0 {
-		return "", nil
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return false
 	}
-	return string(out), nil
+	return strings.Contains(string(body), "kwdb_playground_")
 }
 
 // CoursesIntegrity 基础完整性检查
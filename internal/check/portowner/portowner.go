@@ -0,0 +1,13 @@
+// Package portowner 在不依赖 lsof 的前提下，跨平台定位监听某TCP端口的进程。
+// Linux 直接解析 /proc，macOS 优先 lsof 并以 netstat -anv 兜底，Windows 通过 iphlpapi.dll
+// 的 GetExtendedTcpTable 取得拥有该连接的 PID。各平台实现位于 portowner_<os>.go。
+package portowner
+
+// PortOwner 描述一个监听（或拥有连接）指定端口的进程，字段在信息不可得时留空而非报错，
+// 以便调用方（如 check 包）以统一结构渲染，不再依赖解析 lsof 的文本输出。
+type PortOwner struct {
+	PID     int    `json:"pid"`
+	Exe     string `json:"exe,omitempty"`
+	Cmdline string `json:"cmdline,omitempty"`
+	User    string `json:"user,omitempty"`
+}
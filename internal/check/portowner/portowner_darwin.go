@@ -0,0 +1,94 @@
+//go:build darwin
+
+package portowner
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListPortOwners 优先使用 lsof（信息最完整，含用户名与命令行），
+// 在 lsof 不可用（沙箱/精简安装常见）时回退到 netstat -anv（仅能给出 PID）
+func ListPortOwners(port int) ([]PortOwner, error) {
+	owners, err := lsofPortOwners(port)
+	if err == nil && len(owners) > 0 {
+		return owners, nil
+	}
+
+	netstatOwners, nerr := netstatPortOwners(port)
+	if nerr != nil {
+		if err != nil {
+			return nil, fmt.Errorf("lsof 不可用（%v），netstat -anv 回退也失败: %w", err, nerr)
+		}
+		return nil, nerr
+	}
+	return netstatOwners, nil
+}
+
+// lsofPortOwners 解析 `lsof -i :<port> -sTCP:LISTEN -n -P` 的输出
+func lsofPortOwners(port int) ([]PortOwner, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "lsof", "-i", fmt.Sprintf(":%d", port), "-sTCP:LISTEN", "-n", "-P").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("执行 lsof 失败: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	owners := make([]PortOwner, 0, len(lines))
+	for i, line := range lines {
+		if i == 0 {
+			continue // 表头: COMMAND PID USER FD TYPE DEVICE SIZE/OFF NODE NAME
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		owners = append(owners, PortOwner{PID: pid, Cmdline: fields[0], User: fields[2]})
+	}
+	return owners, nil
+}
+
+// netstatPortOwners 解析 `netstat -anv` 按本地端口过滤监听行，PID为唯一能稳定拿到的字段
+func netstatPortOwners(port int) ([]PortOwner, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "netstat", "-anv", "-p", "tcp").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("执行 netstat -anv 失败: %w", err)
+	}
+
+	suffix := fmt.Sprintf(".%d", port)
+	seen := make(map[int]struct{})
+	owners := make([]PortOwner, 0)
+	for _, line := range strings.Split(string(out), "\n") {
+		if !strings.Contains(line, "LISTEN") {
+			continue
+		}
+		fields := strings.Fields(line)
+		// Proto Recv-Q Send-Q Local-Address Foreign-Address (State) ... pid
+		if len(fields) < 4 || !strings.HasSuffix(fields[3], suffix) {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[pid]; ok {
+			continue
+		}
+		seen[pid] = struct{}{}
+		owners = append(owners, PortOwner{PID: pid})
+	}
+	return owners, nil
+}
@@ -0,0 +1,167 @@
+//go:build linux
+
+package portowner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListenState 是 /proc/net/tcp{,6} 中 st 字段的十六进制值，0A 表示 TCP_LISTEN
+const tcpListenState = "0A"
+
+// ListPortOwners 解析 /proc/net/tcp 与 /proc/net/tcp6 找到监听 port 的 socket inode，
+// 再遍历 /proc/*/fd 寻找指向该 inode 的 socket:[N] 符号链接以反查 PID
+func ListPortOwners(port int) ([]PortOwner, error) {
+	inodes, err := listeningInodes(port)
+	if err != nil {
+		return nil, err
+	}
+	if len(inodes) == 0 {
+		return nil, nil
+	}
+
+	pids, err := pidsForInodes(inodes)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make([]PortOwner, 0, len(pids))
+	for _, pid := range pids {
+		owners = append(owners, describeProcess(pid))
+	}
+	return owners, nil
+}
+
+// listeningInodes 从 /proc/net/tcp 与 /proc/net/tcp6 中收集处于 LISTEN 状态且本地端口匹配的 socket inode
+func listeningInodes(port int) (map[string]struct{}, error) {
+	inodes := make(map[string]struct{})
+	wantHexPort := fmt.Sprintf("%04X", port)
+
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		first := true
+		for scanner.Scan() {
+			if first {
+				first = false // 跳过表头
+				continue
+			}
+			fields := strings.Fields(scanner.Text())
+			// 字段: sl local_address rem_address st ... inode
+			if len(fields) < 10 {
+				continue
+			}
+			localAddr := fields[1]
+			state := fields[3]
+			inode := fields[9]
+			if state != tcpListenState {
+				continue
+			}
+			parts := strings.Split(localAddr, ":")
+			if len(parts) != 2 || !strings.EqualFold(parts[1], wantHexPort) {
+				continue
+			}
+			inodes[inode] = struct{}{}
+		}
+		f.Close()
+	}
+	return inodes, nil
+}
+
+// pidsForInodes 遍历 /proc/*/fd，找到 fd 符号链接目标为 socket:[inode] 的进程
+func pidsForInodes(inodes map[string]struct{}) ([]int, error) {
+	procEntries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("读取 /proc 失败: %w", err)
+	}
+
+	seen := make(map[int]struct{})
+	for _, entry := range procEntries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue // 非PID目录
+		}
+		fdDir := filepath.Join("/proc", entry.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue // 进程已退出或无权限访问，最佳努力跳过
+		}
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			if !strings.HasPrefix(target, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")
+			if _, ok := inodes[inode]; ok {
+				seen[pid] = struct{}{}
+			}
+		}
+	}
+
+	pids := make([]int, 0, len(seen))
+	for pid := range seen {
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// describeProcess 读取 /proc/<pid> 下的 exe/cmdline/status 组装 PortOwner，单项读取失败不影响其余字段
+func describeProcess(pid int) PortOwner {
+	owner := PortOwner{PID: pid}
+
+	if exe, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", pid)); err == nil {
+		owner.Exe = exe
+	}
+
+	if raw, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil {
+		owner.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(raw), "\x00", " "))
+	}
+
+	if uid, ok := readUID(pid); ok {
+		if u, err := user.LookupId(uid); err == nil {
+			owner.User = u.Username
+		} else {
+			owner.User = uid
+		}
+	}
+
+	return owner
+}
+
+// readUID 从 /proc/<pid>/status 的 Uid 行取真实UID
+func readUID(pid int) (string, bool) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Uid:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			return fields[1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,41 @@
+//go:build !linux && !darwin && !windows
+
+package portowner
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListPortOwners 在未专门适配的平台（如各类BSD）上退化为沿用原先的 lsof 方案，最佳努力
+func ListPortOwners(port int) ([]PortOwner, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "lsof", "-i", fmt.Sprintf(":%d", port), "-sTCP:LISTEN", "-n", "-P").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("执行 lsof 失败（本平台未实现原生端口归属发现）: %w", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	owners := make([]PortOwner, 0, len(lines))
+	for i, line := range lines {
+		if i == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		owners = append(owners, PortOwner{PID: pid, Cmdline: fields[0], User: fields[2]})
+	}
+	return owners, nil
+}
@@ -0,0 +1,112 @@
+//go:build windows
+
+package portowner
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	afInet                  = 2 // AF_INET
+	tcpTableOwnerPidAll     = 5 // TCP_TABLE_OWNER_PID_ALL
+	mibTCPStateListen       = 2 // MIB_TCP_STATE_LISTEN
+	processQueryLimitedInfo = 0x1000
+)
+
+var (
+	modIphlpapi           = syscall.NewLazyDLL("iphlpapi.dll")
+	modKernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procGetExtendedTCPTab = modIphlpapi.NewProc("GetExtendedTcpTable")
+	procOpenProcess       = modKernel32.NewProc("OpenProcess")
+	procCloseHandle       = modKernel32.NewProc("CloseHandle")
+	procQueryFullImageW   = modKernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+// mibTCPRowOwnerPID 对应 Windows 的 MIB_TCPROW_OWNER_PID，字段顺序与内存布局必须与其一致
+type mibTCPRowOwnerPID struct {
+	State      uint32
+	LocalAddr  uint32
+	LocalPort  uint32
+	RemoteAddr uint32
+	RemotePort uint32
+	OwningPID  uint32
+}
+
+// ListPortOwners 通过 iphlpapi.dll 的 GetExtendedTcpTable 取得 IPv4 TCP 连接表，
+// 过滤出本地监听 port 的条目后，再用 OpenProcess + QueryFullProcessImageName 反查可执行文件路径
+func ListPortOwners(port int) ([]PortOwner, error) {
+	rows, err := tcpOwnerPidTable()
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make(map[uint32]struct{})
+	for _, row := range rows {
+		if row.State != mibTCPStateListen {
+			continue
+		}
+		// dwLocalPort 按网络字节序存放在低16位，需要交换字节序才是主机序端口号
+		localPort := ((row.LocalPort & 0xFF) << 8) | ((row.LocalPort >> 8) & 0xFF)
+		if int(localPort) != port {
+			continue
+		}
+		pids[row.OwningPID] = struct{}{}
+	}
+
+	owners := make([]PortOwner, 0, len(pids))
+	for pid := range pids {
+		owners = append(owners, PortOwner{PID: int(pid), Exe: processImagePath(pid)})
+	}
+	return owners, nil
+}
+
+// tcpOwnerPidTable 调用 GetExtendedTcpTable 两次：第一次探测所需缓冲区大小，第二次实际取数据
+func tcpOwnerPidTable() ([]mibTCPRowOwnerPID, error) {
+	var size uint32
+	procGetExtendedTCPTab.Call(0, uintptr(unsafe.Pointer(&size)), 0, afInet, tcpTableOwnerPidAll, 0)
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	ret, _, _ := procGetExtendedTCPTab.Call(
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(unsafe.Pointer(&size)),
+		0,
+		afInet,
+		tcpTableOwnerPidAll,
+		0,
+	)
+	if ret != 0 {
+		return nil, fmt.Errorf("GetExtendedTcpTable 返回错误码: %d", ret)
+	}
+
+	numEntries := *(*uint32)(unsafe.Pointer(&buf[0]))
+	rows := make([]mibTCPRowOwnerPID, 0, numEntries)
+	rowSize := unsafe.Sizeof(mibTCPRowOwnerPID{})
+	base := uintptr(unsafe.Pointer(&buf[0])) + unsafe.Sizeof(numEntries)
+	for i := uint32(0); i < numEntries; i++ {
+		row := (*mibTCPRowOwnerPID)(unsafe.Pointer(base + uintptr(i)*rowSize))
+		rows = append(rows, *row)
+	}
+	return rows, nil
+}
+
+// processImagePath 打开目标PID（仅查询权限）并取其完整可执行文件路径，失败时返回空字符串
+func processImagePath(pid uint32) string {
+	h, _, _ := procOpenProcess.Call(uintptr(processQueryLimitedInfo), 0, uintptr(pid))
+	if h == 0 {
+		return ""
+	}
+	defer procCloseHandle.Call(h)
+
+	buf := make([]uint16, 1024)
+	size := uint32(len(buf))
+	ok, _, _ := procQueryFullImageW.Call(h, 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size)))
+	if ok == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:size])
+}
@@ -1,6 +1,8 @@
 package check
 
 import (
+    "encoding/json"
+    "encoding/xml"
     "fmt"
     "strings"
 )
@@ -23,12 +25,102 @@ func RenderSummaryCLI(summary Summary) string {
                 b.WriteString("\n")
             }
         }
+        if strings.TrimSpace(it.Hint) != "" {
+            b.WriteString(indent("建议："+it.Hint, "    "))
+            b.WriteString("\n")
+        }
     }
 
     b.WriteString("================ 环境检查结束 ================")
     return b.String()
 }
 
+// RenderSummaryJSON 将环境检查结果序列化为缩进JSON，供 GET /api/check?format=json
+// 及 `playground check --format=json` 以外、不依赖 io.Writer 的调用方（如需要先拿到完整字节再落盘/发送）使用
+func RenderSummaryJSON(summary Summary) ([]byte, error) {
+    return json.MarshalIndent(summary, "", "  ")
+}
+
+// RenderSummaryMarkdown 将环境检查结果渲染为Markdown表格，便于 Web UI 或 PR/Issue 评论直接粘贴展示，
+// 比RenderSummaryCLI的纯文本更适合在已经做Markdown渲染的界面里呈现
+func RenderSummaryMarkdown(summary Summary) string {
+    var b strings.Builder
+    b.WriteString("| 状态 | 检查项 | 说明 |\n")
+    b.WriteString("| --- | --- | --- |\n")
+    for _, it := range summary.Items {
+        mark := "✅"
+        if !it.OK {
+            mark = "❌"
+        }
+        message := escapeMarkdownTableCell(it.Message)
+        if strings.TrimSpace(it.Hint) != "" {
+            message += "<br/>建议：" + escapeMarkdownTableCell(it.Hint)
+        }
+        b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", mark, escapeMarkdownTableCell(it.Name), message))
+    }
+    return b.String()
+}
+
+// escapeMarkdownTableCell 转义会破坏Markdown表格结构的字符（竖线、换行）
+func escapeMarkdownTableCell(s string) string {
+    s = strings.ReplaceAll(s, "|", "\\|")
+    s = strings.ReplaceAll(s, "\n", "<br/>")
+    return s
+}
+
+// junitTestSuites/junitTestCase/junitFailure 对应 JUnit XML 的最小必要子集，
+// 使 `kwdb-playground check --format=junit` 的输出可直接被 CI（如 GitLab/Jenkins）解析为测试报告
+type junitTestSuites struct {
+    XMLName xml.Name         `xml:"testsuites"`
+    Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+    Name     string          `xml:"name,attr"`
+    Tests    int             `xml:"tests,attr"`
+    Failures int             `xml:"failures,attr"`
+    Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+    Name      string        `xml:"name,attr"`
+    Classname string        `xml:"classname,attr"`
+    Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+    Message string `xml:"message,attr"`
+    Type    string `xml:"type,attr"`
+    Content string `xml:",chardata"`
+}
+
+// RenderSummaryJUnit 将环境检查结果渲染为 JUnit XML，OK 为 false 的项记为 failure（type 为该项的 Severity），
+// classname 固定为该项的 Code，便于CI按稳定代码而非中文文案做断言或分流
+func RenderSummaryJUnit(summary Summary) (string, error) {
+    suite := junitTestSuite{Name: "kwdb-playground-check", Tests: len(summary.Items)}
+    for _, it := range summary.Items {
+        tc := junitTestCase{Name: it.Name, Classname: it.Code}
+        if !it.OK {
+            suite.Failures++
+            content := it.Message
+            if strings.TrimSpace(it.Details) != "" {
+                content += "\n" + it.Details
+            }
+            if strings.TrimSpace(it.Hint) != "" {
+                content += "\n建议：" + it.Hint
+            }
+            tc.Failure = &junitFailure{Message: it.Message, Type: string(it.Severity), Content: content}
+        }
+        suite.Cases = append(suite.Cases, tc)
+    }
+
+    out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+    if err != nil {
+        return "", err
+    }
+    return xml.Header + string(out), nil
+}
+
 // indent 将多行文本缩进，便于在 CLI 中更清晰展示（内部使用）
 func indent(s, prefix string) string {
     lines := []byte(s)
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"kwdb-playground/internal/logger"
 )
@@ -19,8 +22,105 @@ type Config struct {
 	Server ServerConfig `json:"server" yaml:"server"`
 	Docker DockerConfig `json:"docker" yaml:"docker"`
 	// Course 课程相关配置
-	Course CourseConfig `json:"course" yaml:"course"`
-	Log    LogConfig    `json:"log" yaml:"log"`
+	Course     CourseConfig     `json:"course" yaml:"course"`
+	Log        LogConfig        `json:"log" yaml:"log"`
+	Session    SessionConfig    `json:"session" yaml:"session"`
+	Kubernetes KubernetesConfig `json:"kubernetes" yaml:"kubernetes"`
+	Runtime    RuntimeConfig    `json:"runtime" yaml:"runtime"`
+	// Registries 镜像源优先级列表，startCourse 拉取镜像时按顺序尝试，用于 docker.io 不稳定的教室网络环境
+	Registries []RegistryEntry `json:"registries" yaml:"registries"`
+	// DataDir 快照等持久化数据的存储根目录
+	DataDir string `json:"dataDir" yaml:"dataDir"`
+	// Audit 审计日志相关配置
+	Audit AuditConfig `json:"audit" yaml:"audit"`
+	// Auth JWT鉴权相关配置
+	Auth AuthConfig `json:"auth" yaml:"auth"`
+	// Supervisor 容器监督/自动重启相关配置
+	Supervisor SupervisorConfig `json:"supervisor" yaml:"supervisor"`
+	// Progress 学习进度存储后端相关配置
+	Progress ProgressConfig `json:"progress" yaml:"progress"`
+	// ConfigSource 分层配置来源相关配置（配置文件路径、远程KV地址等），供 Manager 装配 Provider 列表
+	ConfigSource ConfigSourceConfig `json:"configSource" yaml:"configSource"`
+	// ImageWarm 启动阶段并发预热课程镜像可用性的相关配置
+	ImageWarm ImageWarmConfig `json:"imageWarm" yaml:"imageWarm"`
+}
+
+// ImageWarmConfig 控制 docker.ImageWarmer 在启动阶段对课程引用镜像的并发探测行为
+type ImageWarmConfig struct {
+	// Concurrency 并发探测/预拉取的worker数量
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+	// Prepull 为true时，探测不到任何可用镜像源的镜像会额外尝试一次完整拉取兜底
+	Prepull bool `json:"prepull" yaml:"prepull"`
+}
+
+// ConfigSourceConfig 描述分层配置体系中file/consul/etcd三层数据源的接入参数，本身只能通过
+// 环境变量或命令行设置（它描述的是"去哪里找配置"，不能循环依赖那些被描述的数据源）
+type ConfigSourceConfig struct {
+	// FilePath YAML/JSON配置文件路径，留空表示不启用file层
+	FilePath string `json:"filePath,omitempty" yaml:"filePath,omitempty"`
+	// ConsulAddr Consul HTTP API地址（例如 http://127.0.0.1:8500），留空表示不启用consul层
+	ConsulAddr string `json:"consulAddr,omitempty" yaml:"consulAddr,omitempty"`
+	// ConsulPrefix Consul KV前缀
+	ConsulPrefix string `json:"consulPrefix,omitempty" yaml:"consulPrefix,omitempty"`
+	// EtcdAddr etcd v3 grpc-gateway JSON API地址（例如 http://127.0.0.1:2379），留空表示不启用etcd层
+	EtcdAddr string `json:"etcdAddr,omitempty" yaml:"etcdAddr,omitempty"`
+	// EtcdPrefix etcd KV前缀
+	EtcdPrefix string `json:"etcdPrefix,omitempty" yaml:"etcdPrefix,omitempty"`
+	// WatchIntervalSecs Manager.Watch 轮询远程数据源的间隔（秒）
+	WatchIntervalSecs int `json:"watchIntervalSeconds" yaml:"watchIntervalSeconds"`
+}
+
+// ProgressConfig 学习进度存储后端配置，对应 course.ProgressStore 的可插拔实现
+type ProgressConfig struct {
+	// Backend 存储后端类型："json"（默认，单文件）、"sqlite"、"badger"、"http"（多实例同步）
+	Backend string `json:"backend" yaml:"backend"`
+	// Path 本地存储的文件/目录路径，json为文件路径、sqlite为数据库文件路径、badger为目录路径；
+	// http 后端忽略此字段
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// HTTPSyncURL backend为"http"时，远端进度同步服务的基础地址，例如 http://progress-sync:8080
+	HTTPSyncURL string `json:"httpSyncUrl,omitempty" yaml:"httpSyncUrl,omitempty"`
+	// HTTPSyncToken backend为"http"时，携带在请求头 Authorization: Bearer <token> 中，留空表示不鉴权
+	HTTPSyncToken string `json:"httpSyncToken,omitempty" yaml:"httpSyncToken,omitempty"`
+}
+
+// SupervisorConfig 容器监督器相关配置：监听Docker事件流，按策略自动重启意外退出的课程容器
+type SupervisorConfig struct {
+	// RestartPolicy 重启策略，取值 "none"（默认，不自动重启）、"always"、"on-failure:N"，语法与docker run --restart一致
+	RestartPolicy string `json:"restartPolicy" yaml:"restartPolicy"`
+	// StatePath 监督状态机的持久化文件路径，默认 ./tmp/supervisor-state.json
+	StatePath string `json:"statePath" yaml:"statePath"`
+}
+
+// AuditConfig 审计日志相关配置
+// 记录每一次容器/SQL 的变更类操作，便于课堂运维与事后排查
+type AuditConfig struct {
+	// LogPath 审计日志文件路径，留空表示不落盘（仍可能转发到 Syslog/HTTP）
+	LogPath string `json:"logPath,omitempty" yaml:"logPath,omitempty"`
+	// SyslogAddr 可选，按 UDP 转发审计记录的 syslog 地址（host:port）
+	SyslogAddr string `json:"syslogAddr,omitempty" yaml:"syslogAddr,omitempty"`
+	// OTLPEndpoint 可选，以 JSON POST 转发审计记录的日志接收端点
+	OTLPEndpoint string `json:"otlpEndpoint,omitempty" yaml:"otlpEndpoint,omitempty"`
+}
+
+// AuthConfig JWT鉴权相关配置，用于多租户场景下按角色/课程隔离容器、终端等敏感接口
+// Enabled 为 false（默认）时保持历史行为：路由不做任何鉴权校验
+type AuthConfig struct {
+	// Enabled 是否启用JWT鉴权中间件
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Algorithm JWT签名算法，"HS256" 或 "RS256"
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// HMACSecret HS256 验签密钥
+	HMACSecret string `json:"hmacSecret,omitempty" yaml:"hmacSecret,omitempty"`
+	// RSAPublicKeyPEM RS256 验签所需的RSA公钥（PEM编码），从 AUTH_RSA_PUBLIC_KEY_PATH 指定的文件读取
+	RSAPublicKeyPEM string `json:"-" yaml:"-"`
+}
+
+// RegistryEntry 单个镜像仓库配置项
+// URL 留空表示官方 docker.io；"file://<dir>" 表示本地离线 tar 包目录，作为所有在线源都不可达时的兜底
+type RegistryEntry struct {
+	URL          string `json:"url" yaml:"url"`
+	Username     string `json:"username,omitempty" yaml:"username,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty" yaml:"passwordFile,omitempty"` // 密码从文件读取，避免明文写入环境变量/配置
 }
 
 // ServerConfig 服务器配置
@@ -31,6 +131,25 @@ type ServerConfig struct {
 	// Port 服务器监听端口，默认为3006
 	Port         int `json:"port" yaml:"port"`                 // 服务器监听端口
 	SessionLimit int `json:"sessionLimit" yaml:"sessionLimit"` // 并发会话限制
+
+	// Listen 监听地址列表（非空时取代 Host:Port），支持 tcp://host:port、unix:///path/to.sock、fd://N（systemd socket activation）三种scheme，
+	// 可重复指定以同时监听多个地址（参考 dockerd 同时监听 TCP 与 unix:///var/run/docker.sock 的做法）。
+	// 为空时退化为仅监听 Host:Port 一个TCP地址，保持历史行为
+	Listen []string `json:"listen,omitempty" yaml:"listen,omitempty"`
+	// ListenSocketGroup unix:// 监听地址对应套接字文件的属组（组名或GID），留空则保持进程默认属组不变
+	ListenSocketGroup string `json:"listenSocketGroup,omitempty" yaml:"listenSocketGroup,omitempty"`
+	// ShutdownTimeoutSeconds 收到第一次SIGINT/SIGTERM后，shutdown.Trap等待所有关闭钩子
+	// 完成的最长时间；超时或收到第二次信号都会取消仍在进行的清理
+	ShutdownTimeoutSeconds int `json:"shutdownTimeoutSeconds" yaml:"shutdownTimeoutSeconds"`
+
+	// SessionMemoryMB 每个课程容器默认的内存上限，课程自身未通过DockerHostConfig声明时套用此值
+	SessionMemoryMB int `json:"sessionMemoryMb" yaml:"sessionMemoryMb"`
+	// SessionCPUShares 每个课程容器默认的CPU份额（Docker --cpu-shares），课程未声明时套用此值
+	SessionCPUShares int `json:"sessionCpuShares" yaml:"sessionCpuShares"`
+	// SessionPidsLimit 每个课程容器默认的进程数上限（防fork炸弹），课程未声明时套用此值
+	SessionPidsLimit int `json:"sessionPidsLimit" yaml:"sessionPidsLimit"`
+	// SessionDiskMB 每个课程容器默认的磁盘配额（MB），0表示不限制
+	SessionDiskMB int `json:"sessionDiskMb" yaml:"sessionDiskMb"`
 }
 
 // DockerConfig Docker容器相关配置
@@ -46,12 +165,155 @@ type CourseConfig struct {
 	Dir      string `json:"dir" yaml:"dir"`           // 课程文件目录路径
 	Reload   bool   `json:"reload" yaml:"reload"`     // 是否启用热重载
 	UseEmbed bool   `json:"useEmbed" yaml:"useEmbed"` // 是否使用嵌入式FS作为课程数据来源
+
+	// ExtraSources 额外的课程数据源，按声明顺序以低于 Dir/UseEmbed 主数据源的优先级叠加，
+	// 用于从运营方自建的 HTTP/Git 发布渠道叠加课程包，无需重新编译或重新分发二进制
+	ExtraSources []CourseSourceConfig `json:"extraSources,omitempty" yaml:"extraSources,omitempty"`
+}
+
+// CourseSourceConfig 描述一个额外的课程数据源
+type CourseSourceConfig struct {
+	// Type 数据源类型："http"（HTTP(S) 上的 tar.gz/tgz/zip 课程包）或 "git"（Git 仓库）
+	Type string `json:"type" yaml:"type"`
+	// URL 数据源地址：http(s) 类型为课程包下载地址，git 类型为仓库地址
+	URL string `json:"url" yaml:"url"`
+	// Ref 仅 git 类型生效，克隆/拉取的分支名，留空使用仓库默认分支
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// CacheDir 拉取内容的本地缓存目录，留空则使用 DataDir 下以该数据源URL派生的默认子目录
+	CacheDir string `json:"cacheDir,omitempty" yaml:"cacheDir,omitempty"`
 }
 
 // LogConfig 日志系统相关配置
 type LogConfig struct {
 	Level  string `json:"level" yaml:"level"`   // 日志级别 (debug, info, warn, error)
 	Format string `json:"format" yaml:"format"` // 日志格式 (json, text)
+
+	// FilePath 滚动日志文件路径，留空表示不落盘（仅输出到stderr，历史行为）
+	FilePath string `json:"filePath,omitempty" yaml:"filePath,omitempty"`
+	// RotateInterval 按时间切分粒度："none"（默认，仅按大小滚动）、"daily"、"hourly"
+	RotateInterval string `json:"rotateInterval,omitempty" yaml:"rotateInterval,omitempty"`
+	// RotateMaxSizeMB 单个日志文件达到该大小（MB）后在同一时间段内再滚动一次，<=0 表示不按大小滚动
+	RotateMaxSizeMB int `json:"rotateMaxSizeMb,omitempty" yaml:"rotateMaxSizeMb,omitempty"`
+	// RotateMaxAgeDays 超过该天数的历史日志文件会被清理，<=0 表示不按时间清理
+	RotateMaxAgeDays int `json:"rotateMaxAgeDays,omitempty" yaml:"rotateMaxAgeDays,omitempty"`
+	// RotateMaxBackups 保留的历史日志文件数量上限，<=0 表示不限制
+	RotateMaxBackups int `json:"rotateMaxBackups,omitempty" yaml:"rotateMaxBackups,omitempty"`
+}
+
+// RotateOptions 把 LogConfig 中的滚动配置转换为 logger.RotateOptions，供 FilePath 非空时
+// 构建 logger.NewRotatingLogger 使用
+func (c LogConfig) RotateOptions() logger.RotateOptions {
+	interval := logger.RotateNone
+	switch strings.ToLower(c.RotateInterval) {
+	case "daily":
+		interval = logger.RotateDaily
+	case "hourly":
+		interval = logger.RotateHourly
+	}
+	return logger.RotateOptions{
+		MaxSizeBytes: int64(c.RotateMaxSizeMB) * 1024 * 1024,
+		MaxAge:       time.Duration(c.RotateMaxAgeDays) * 24 * time.Hour,
+		MaxBackups:   c.RotateMaxBackups,
+		Interval:     interval,
+	}
+}
+
+// SessionConfig 多租户会话相关配置
+// 用于隔离同一部署下的多个学生/用户，避免互相抢占容器
+type SessionConfig struct {
+	// CookieName 会话Cookie名称
+	CookieName string `json:"cookieName" yaml:"cookieName"`
+	// Secret 签名密钥，用于签发/校验会话Cookie
+	Secret string `json:"secret" yaml:"secret"`
+	// MaxContainersPerUser 单个用户允许同时存在的容器数上限
+	MaxContainersPerUser int `json:"maxContainersPerUser" yaml:"maxContainersPerUser"`
+	// IdleTTLSeconds 会话最近一次活动超过该时长后，后台回收器将清理其容器
+	IdleTTLSeconds int `json:"idleTTLSeconds" yaml:"idleTTLSeconds"`
+	// ReapIntervalSeconds 后台回收器的扫描间隔
+	ReapIntervalSeconds int `json:"reapIntervalSeconds" yaml:"reapIntervalSeconds"`
+}
+
+// KubernetesConfig Kubernetes 运行时后端相关配置
+// 仅当存在课程将 backend.runtime 配置为 "kubernetes" 时才会用到
+type KubernetesConfig struct {
+	// Enabled 是否启用 Kubernetes 运行时后端
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Kubeconfig kubeconfig 文件路径，留空时使用集群内配置（ServiceAccount）
+	Kubeconfig string `json:"kubeconfig" yaml:"kubeconfig"`
+}
+
+// RuntimeConfig 容器运行时后端选择，与 Kubernetes/Docker 的"部署后端"正交：
+// 这里选的是单机场景下谁来实际创建/执行容器（Docker守护进程、containerd 还是标准CRI端点），
+// 不涉及Kubernetes集群编排
+type RuntimeConfig struct {
+	// Type 运行时类型："docker"（默认）、"containerd"、"cri" 或 "auto"
+	// （auto按containerd socket→Docker socket的顺序自动探测，见 docker.NewAutoDetectController）
+	Type string `json:"type" yaml:"type"`
+	// ContainerdAddress containerd 守护进程的 unix socket 地址，Type为"containerd"时生效
+	ContainerdAddress string `json:"containerdAddress" yaml:"containerdAddress"`
+	// ContainerdNamespace containerd 命名空间，隔离本程序管理的容器与宿主机上的其他容器
+	ContainerdNamespace string `json:"containerdNamespace" yaml:"containerdNamespace"`
+	// CRIEndpoint 标准CRI（RuntimeService/ImageService）gRPC端点的unix socket地址，
+	// Type为"cri"时生效，留空时使用 /run/containerd/containerd.sock（containerd默认同时暴露CRI插件）
+	CRIEndpoint string `json:"criEndpoint" yaml:"criEndpoint"`
+}
+
+// envLookup 是 buildConfig 读取单个键的抽象：返回(值, 是否存在)。
+// Load() 传入基于 os.LookupEnv 的 lookup，保持历史行为；Manager.Load 则传入按
+// defaults→file→env→remote→flags 顺序合并后的 lookup，复用同一套 buildConfig 与校验逻辑
+type envLookup func(key string) (string, bool)
+
+// osLookup 是 envLookup 的默认实现，直接读取进程环境变量
+func osLookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// getEnvWith/getEnvIntWith/getEnvBoolWith/getEnvListWith 是 getEnv 系列函数的 lookup 参数化版本，
+// 解析规则与对应的 getEnv* 完全一致（只是值的来源从 os.Getenv 换成传入的 lookup）
+func getEnvWith(lookup envLookup, key, defaultValue string) string {
+	if value, ok := lookup(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvIntWith(lookup envLookup, key string, defaultValue int) int {
+	if value, ok := lookup(key); ok && value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+		logger.NewLogger(logger.WARN).Warn("failed to parse %s as integer: %s, using default: %d", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvBoolWith(lookup envLookup, key string, defaultValue bool) bool {
+	if value, ok := lookup(key); ok && value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+		logger.NewLogger(logger.WARN).Warn("failed to parse %s as boolean: %s, using default: %t", key, value, defaultValue)
+	}
+	return defaultValue
+}
+
+func getEnvListWith(lookup envLookup, key string, defaultValue []string) []string {
+	value, ok := lookup(key)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
 }
 
 // Load 从环境变量加载配置
@@ -59,12 +321,31 @@ type LogConfig struct {
 // 支持的环境变量:
 //   - SERVER_HOST: 服务器监听地址 (默认: 0.0.0.0)
 //   - SERVER_PORT: 服务器监听端口 (默认: 3006)
+//   - SERVER_LISTEN: 逗号分隔的监听地址列表，设置后取代 SERVER_HOST:SERVER_PORT，如 "unix:///var/run/kwdb-playground.sock,fd://3"
 //   - COURSE_DIR: 课程文件目录 (默认: ./courses)
 //   - COURSES_RELOAD: 是否启用课程热重载 (默认: true)
 //   - COURSES_USE_EMBED: 是否使用嵌入式FS作为课程数据来源 (默认: false 或由 BuildDefaultUseEmbed 指定)
+//   - SUPERVISOR_RESTART_POLICY: 容器自动重启策略，"none"/"always"/"on-failure:N" (默认: none)
+//   - PROGRESS_BACKEND: 学习进度存储后端，"json"/"sqlite"/"badger"/"http" (默认: json)
+//   - PROGRESS_PATH: 存储后端对应的文件/目录路径 (默认由后端类型派生，见 course.NewProgressStoreFromConfig)
+//   - PROGRESS_HTTP_SYNC_URL: backend为"http"时，远端进度同步服务地址
+//   - LOG_FORMAT: 日志输出格式，"json"（默认）或"text"
+//   - LOG_FILE_PATH: 滚动日志文件路径，留空表示不落盘（默认，仅输出到stderr）
+//   - LOG_ROTATE_INTERVAL: 按时间切分粒度，"none"（默认）/"daily"/"hourly"
+//   - LOG_ROTATE_MAX_SIZE_MB / LOG_ROTATE_MAX_AGE_DAYS / LOG_ROTATE_MAX_BACKUPS: 按大小滚动与历史文件清理策略
+//   - IMAGE_WARM_CONCURRENCY: docker.ImageWarmer 启动阶段并发探测课程镜像的worker数量 (默认: 4)
+//   - IMAGE_PREPULL: 探测不到任何可用镜像源时是否额外尝试一次完整拉取兜底 (默认: false)
+//   - REGISTRY_MIRRORS: 逗号分隔的镜像源host列表，追加在 REGISTRY_MIRRORS_FILE 声明的源之后，仅提供host、不支持鉴权
 //
 // 返回完整的配置对象，如果配置验证失败会记录警告但不会中断程序
 func Load() *Config {
+	return buildConfig(osLookup)
+}
+
+// buildConfig 是 Load() 的实际实现，参数化了键值查找来源：Load() 传入 osLookup 保持历史行为，
+// Manager.Load 传入按 defaults→file→env→remote→flags 顺序合并后的 lookup，两者共用同一套
+// 解析与校验逻辑，避免出现两份容易失配的配置构建代码
+func buildConfig(lookup envLookup) *Config {
 	// 创建临时logger实例用于配置加载过程
 	// 取消临时 DEBUG 输出，避免启动期噪声
 	_ = logger.NewLogger(logger.ERROR) // 保留占位，如需未来扩展可使用
@@ -74,22 +355,88 @@ func Load() *Config {
 
 	config := &Config{
 		Server: ServerConfig{
-			Host:         getEnv("SERVER_HOST", "0.0.0.0"),
-			Port:         getEnvInt("SERVER_PORT", 3006),
-			SessionLimit: getEnvInt("SESSION_LIMIT", 1),
+			Host:                   getEnvWith(lookup, "SERVER_HOST", "0.0.0.0"),
+			Port:                   getEnvIntWith(lookup, "SERVER_PORT", 3006),
+			SessionLimit:           getEnvIntWith(lookup, "SESSION_LIMIT", 1),
+			Listen:                 getEnvListWith(lookup, "SERVER_LISTEN", nil),
+			ListenSocketGroup:      getEnvWith(lookup, "SERVER_LISTEN_SOCKET_GROUP", ""),
+			ShutdownTimeoutSeconds: getEnvIntWith(lookup, "SHUTDOWN_TIMEOUT", 15),
+			SessionMemoryMB:        getEnvIntWith(lookup, "SESSION_MEMORY_MB", 512),
+			SessionCPUShares:       getEnvIntWith(lookup, "SESSION_CPU_SHARES", 512),
+			SessionPidsLimit:       getEnvIntWith(lookup, "SESSION_PIDS_LIMIT", 512),
+			SessionDiskMB:          getEnvIntWith(lookup, "SESSION_DISK_MB", 0),
 		},
 		Docker: DockerConfig{
-			Host:    getEnv("DOCKER_HOST", ""),
-			Timeout: getEnvInt("DOCKER_TIMEOUT", 30),
+			Host:    getEnvWith(lookup, "DOCKER_HOST", ""),
+			Timeout: getEnvIntWith(lookup, "DOCKER_TIMEOUT", 30),
 		},
 		Course: CourseConfig{
-			Dir:      getEnv("COURSE_DIR", "./courses"),
-			Reload:   getEnvBool("COURSES_RELOAD", true),
-			UseEmbed: getEnvBool("COURSES_USE_EMBED", defaultUseEmbed),
+			Dir:      getEnvWith(lookup, "COURSE_DIR", "./courses"),
+			Reload:   getEnvBoolWith(lookup, "COURSES_RELOAD", true),
+			UseEmbed: getEnvBoolWith(lookup, "COURSES_USE_EMBED", defaultUseEmbed),
 		},
 		Log: LogConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:            getEnvWith(lookup, "LOG_LEVEL", "info"),
+			Format:           getEnvWith(lookup, "LOG_FORMAT", "json"),
+			FilePath:         getEnvWith(lookup, "LOG_FILE_PATH", ""),
+			RotateInterval:   getEnvWith(lookup, "LOG_ROTATE_INTERVAL", "none"),
+			RotateMaxSizeMB:  getEnvIntWith(lookup, "LOG_ROTATE_MAX_SIZE_MB", 0),
+			RotateMaxAgeDays: getEnvIntWith(lookup, "LOG_ROTATE_MAX_AGE_DAYS", 0),
+			RotateMaxBackups: getEnvIntWith(lookup, "LOG_ROTATE_MAX_BACKUPS", 0),
+		},
+		Session: SessionConfig{
+			CookieName:           getEnvWith(lookup, "SESSION_COOKIE_NAME", "kwdb_session"),
+			Secret:               getEnvWith(lookup, "SESSION_SECRET", "kwdb-playground-dev-secret"),
+			MaxContainersPerUser: getEnvIntWith(lookup, "SESSION_MAX_CONTAINERS", 3),
+			IdleTTLSeconds:       getEnvIntWith(lookup, "SESSION_IDLE_TTL_SECONDS", 1800),
+			ReapIntervalSeconds:  getEnvIntWith(lookup, "SESSION_REAP_INTERVAL_SECONDS", 60),
+		},
+		Kubernetes: KubernetesConfig{
+			Enabled:    getEnvBoolWith(lookup, "KUBERNETES_ENABLED", false),
+			Kubeconfig: getEnvWith(lookup, "KUBECONFIG", ""),
+		},
+		Runtime: RuntimeConfig{
+			Type:                getEnvWith(lookup, "RUNTIME_TYPE", "docker"),
+			ContainerdAddress:   getEnvWith(lookup, "CONTAINERD_ADDRESS", "/run/containerd/containerd.sock"),
+			ContainerdNamespace: getEnvWith(lookup, "CONTAINERD_NAMESPACE", "kwdb-playground"),
+			CRIEndpoint:         getEnvWith(lookup, "CRI_ENDPOINT", "/run/containerd/containerd.sock"),
+		},
+		// Registries 合并两种镜像源声明方式：REGISTRY_MIRRORS_FILE（支持鉴权，见loadRegistries）在前，
+		// REGISTRY_MIRRORS（逗号分隔的host列表，仅用于ImageWarmer这类不需要鉴权的轻量预热场景）追加在后
+		Registries: append(loadRegistries(), registryMirrorEntries(getEnvListWith(lookup, "REGISTRY_MIRRORS", nil))...),
+		DataDir:    getEnvWith(lookup, "DATA_DIR", "./data"),
+		Audit: AuditConfig{
+			LogPath:      getEnvWith(lookup, "AUDIT_LOG_PATH", ""),
+			SyslogAddr:   getEnvWith(lookup, "AUDIT_SYSLOG_ADDR", ""),
+			OTLPEndpoint: getEnvWith(lookup, "AUDIT_OTLP_ENDPOINT", ""),
+		},
+		Auth: AuthConfig{
+			Enabled:         getEnvBoolWith(lookup, "AUTH_ENABLED", false),
+			Algorithm:       getEnvWith(lookup, "AUTH_ALGORITHM", "HS256"),
+			HMACSecret:      getEnvWith(lookup, "AUTH_HMAC_SECRET", ""),
+			RSAPublicKeyPEM: loadRSAPublicKeyPEM(),
+		},
+		Supervisor: SupervisorConfig{
+			RestartPolicy: getEnvWith(lookup, "SUPERVISOR_RESTART_POLICY", "none"),
+			StatePath:     getEnvWith(lookup, "SUPERVISOR_STATE_PATH", "./tmp/supervisor-state.json"),
+		},
+		Progress: ProgressConfig{
+			Backend:       getEnvWith(lookup, "PROGRESS_BACKEND", "json"),
+			Path:          getEnvWith(lookup, "PROGRESS_PATH", ""),
+			HTTPSyncURL:   getEnvWith(lookup, "PROGRESS_HTTP_SYNC_URL", ""),
+			HTTPSyncToken: getEnvWith(lookup, "PROGRESS_HTTP_SYNC_TOKEN", ""),
+		},
+		ConfigSource: ConfigSourceConfig{
+			FilePath:          getEnvWith(lookup, "CONFIG_FILE_PATH", ""),
+			ConsulAddr:        getEnvWith(lookup, "CONFIG_CONSUL_ADDR", ""),
+			ConsulPrefix:      getEnvWith(lookup, "CONFIG_CONSUL_PREFIX", "kwdb-playground"),
+			EtcdAddr:          getEnvWith(lookup, "CONFIG_ETCD_ADDR", ""),
+			EtcdPrefix:        getEnvWith(lookup, "CONFIG_ETCD_PREFIX", "kwdb-playground"),
+			WatchIntervalSecs: getEnvIntWith(lookup, "CONFIG_WATCH_INTERVAL_SECONDS", 15),
+		},
+		ImageWarm: ImageWarmConfig{
+			Concurrency: getEnvIntWith(lookup, "IMAGE_WARM_CONCURRENCY", 4),
+			Prepull:     getEnvBoolWith(lookup, "IMAGE_PREPULL", false),
 		},
 	}
 
@@ -120,6 +467,23 @@ func validateConfig(cfg *Config, logger *logger.Logger) error {
 		return fmt.Errorf("invalid docker timeout: %d, must be positive", cfg.Docker.Timeout)
 	}
 
+	if cfg.Server.ShutdownTimeoutSeconds < 1 {
+		return fmt.Errorf("invalid shutdown timeout: %d, must be positive", cfg.Server.ShutdownTimeoutSeconds)
+	}
+
+	if cfg.Server.SessionMemoryMB < 1 {
+		return fmt.Errorf("invalid session memory limit: %d, must be positive", cfg.Server.SessionMemoryMB)
+	}
+	if cfg.Server.SessionCPUShares < 1 {
+		return fmt.Errorf("invalid session cpu shares: %d, must be positive", cfg.Server.SessionCPUShares)
+	}
+	if cfg.Server.SessionPidsLimit < 1 {
+		return fmt.Errorf("invalid session pids limit: %d, must be positive", cfg.Server.SessionPidsLimit)
+	}
+	if cfg.Server.SessionDiskMB < 0 {
+		return fmt.Errorf("invalid session disk limit: %d, must not be negative", cfg.Server.SessionDiskMB)
+	}
+
 	// 检查课程目录是否存在（仅在非嵌入模式下）
 	if !cfg.Course.UseEmbed {
 		if _, err := os.Stat(cfg.Course.Dir); os.IsNotExist(err) {
@@ -132,9 +496,79 @@ func validateConfig(cfg *Config, logger *logger.Logger) error {
 		return fmt.Errorf("invalid log level: %s, must be one of: debug, info, warn, error", cfg.Log.Level)
 	}
 
+	validLogFormats := map[string]bool{"json": true, "text": true}
+	if !validLogFormats[strings.ToLower(cfg.Log.Format)] {
+		return fmt.Errorf("invalid log format: %s, must be one of: json, text", cfg.Log.Format)
+	}
+
+	// podman 兼容Docker Engine API，复用dockerController即可，不需要单独的客户端/适配器；
+	// cri 连接标准CRI gRPC端点；auto 按containerd socket→Docker socket顺序自动探测
+	validRuntimeTypes := map[string]bool{"docker": true, "containerd": true, "podman": true, "cri": true, "auto": true}
+	if !validRuntimeTypes[cfg.Runtime.Type] {
+		return fmt.Errorf("invalid runtime type: %s, must be one of: docker, containerd, podman, cri, auto", cfg.Runtime.Type)
+	}
+
+	validProgressBackends := map[string]bool{"json": true, "sqlite": true, "badger": true, "http": true}
+	if !validProgressBackends[cfg.Progress.Backend] {
+		return fmt.Errorf("invalid progress backend: %s, must be one of: json, sqlite, badger, http", cfg.Progress.Backend)
+	}
+	if cfg.Progress.Backend == "http" && cfg.Progress.HTTPSyncURL == "" {
+		return fmt.Errorf("progress backend \"http\" requires PROGRESS_HTTP_SYNC_URL to be set")
+	}
+
+	if cfg.ImageWarm.Concurrency < 1 {
+		return fmt.Errorf("invalid image warm concurrency: %d, must be positive", cfg.ImageWarm.Concurrency)
+	}
+
 	return nil
 }
 
+// loadRegistries 从 REGISTRY_MIRRORS_FILE 指定的 JSON 文件加载镜像源优先级列表
+// 文件内容形如 [{"url":"harbor.example.com","username":"ci","passwordFile":"/etc/kwdb/harbor.pass"}]
+// 未设置该环境变量或加载失败时返回空列表，由 registry.Manager 退化为仅使用 docker.io 一个源
+func loadRegistries() []RegistryEntry {
+	path := getEnv("REGISTRY_MIRRORS_FILE", "")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.NewLogger(logger.WARN).Warn("failed to read REGISTRY_MIRRORS_FILE %s: %v", path, err)
+		return nil
+	}
+	var entries []RegistryEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		logger.NewLogger(logger.WARN).Warn("failed to parse REGISTRY_MIRRORS_FILE %s: %v", path, err)
+		return nil
+	}
+	return entries
+}
+
+// registryMirrorEntries 把 REGISTRY_MIRRORS 逗号分隔的host列表转换为不带鉴权信息的 RegistryEntry，
+// 需要鉴权的镜像源请继续用 REGISTRY_MIRRORS_FILE
+func registryMirrorEntries(hosts []string) []RegistryEntry {
+	entries := make([]RegistryEntry, 0, len(hosts))
+	for _, h := range hosts {
+		entries = append(entries, RegistryEntry{URL: h})
+	}
+	return entries
+}
+
+// loadRSAPublicKeyPEM 从 AUTH_RSA_PUBLIC_KEY_PATH 指定的文件读取 RS256 验签所需的 RSA 公钥（PEM编码）
+// 未设置该环境变量或读取失败时返回空字符串，Algorithm 为 RS256 时 auth.NewVerifier 会据此报错
+func loadRSAPublicKeyPEM() string {
+	path := getEnv("AUTH_RSA_PUBLIC_KEY_PATH", "")
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		logger.NewLogger(logger.WARN).Warn("failed to read AUTH_RSA_PUBLIC_KEY_PATH %s: %v", path, err)
+		return ""
+	}
+	return string(data)
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 // 参数:
 //
@@ -169,6 +603,33 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvList 获取以逗号分隔的字符串列表类环境变量，自动去除每一项前后空白并跳过空项；
+// 未设置该环境变量或解析后为空列表时返回 defaultValue
+// 参数:
+//
+// key: 环境变量名称
+// defaultValue: 默认值
+//
+// 返回: 解析后的字符串列表或默认值
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
+
 // getEnvBool 获取布尔类型的环境变量，如果不存在或转换失败则返回默认值
 // 参数:
 //
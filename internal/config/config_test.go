@@ -133,12 +133,56 @@ func TestLoadConfig_InvalidSessionLimit(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_InvalidSessionMemoryMB(t *testing.T) {
+	os.Setenv("SESSION_MEMORY_MB", "0")
+	defer os.Unsetenv("SESSION_MEMORY_MB")
+
+	_, err := Load()
+	if err == nil {
+		t.Error("Load() should fail with non-positive session memory limit")
+	}
+}
+
+func TestLoadConfig_InvalidSessionCPUShares(t *testing.T) {
+	os.Setenv("SESSION_CPU_SHARES", "-1")
+	defer os.Unsetenv("SESSION_CPU_SHARES")
+
+	_, err := Load()
+	if err == nil {
+		t.Error("Load() should fail with non-positive session cpu shares")
+	}
+}
+
+func TestLoadConfig_InvalidSessionPidsLimit(t *testing.T) {
+	os.Setenv("SESSION_PIDS_LIMIT", "0")
+	defer os.Unsetenv("SESSION_PIDS_LIMIT")
+
+	_, err := Load()
+	if err == nil {
+		t.Error("Load() should fail with non-positive session pids limit")
+	}
+}
+
+func TestLoadConfig_InvalidSessionDiskMB(t *testing.T) {
+	os.Setenv("SESSION_DISK_MB", "-1")
+	defer os.Unsetenv("SESSION_DISK_MB")
+
+	_, err := Load()
+	if err == nil {
+		t.Error("Load() should fail with negative session disk limit")
+	}
+}
+
 func TestValidateConfig(t *testing.T) {
 	cfg := &Config{
 		Server: ServerConfig{
-			Host:         "0.0.0.0",
-			Port:         3006,
-			SessionLimit: 1,
+			Host:                   "0.0.0.0",
+			Port:                   3006,
+			SessionLimit:           1,
+			ShutdownTimeoutSeconds: 15,
+			SessionMemoryMB:        512,
+			SessionCPUShares:       512,
+			SessionPidsLimit:       512,
 		},
 		Docker: DockerConfig{
 			Host:    "",
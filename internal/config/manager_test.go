@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"kwdb-playground/internal/logger"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	os.Setenv("COURSES_USE_EMBED", "true")
+	t.Cleanup(func() { os.Unsetenv("COURSES_USE_EMBED") })
+	return NewManager(ProvidersFromConfig(ConfigSourceConfig{}), logger.NewLogger(logger.ERROR))
+}
+
+func TestManager_ReloadAppliesLogLevelWithoutRestart(t *testing.T) {
+	os.Setenv("LOG_LEVEL", "info")
+	t.Cleanup(func() { os.Unsetenv("LOG_LEVEL") })
+
+	m := newTestManager(t)
+	if _, err := m.Load(nil); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := m.Get().Log.Level; got != "info" {
+		t.Fatalf("Log.Level = %s, want info", got)
+	}
+
+	os.Setenv("LOG_LEVEL", "debug")
+	diff, err := m.Reload(nil)
+	if err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if got := m.Get().Log.Level; got != "debug" {
+		t.Fatalf("Log.Level after reload = %s, want debug", got)
+	}
+	if !containsField(diff.Changed, "Log.Level") {
+		t.Errorf("diff.Changed = %v, want it to contain Log.Level", diff.Changed)
+	}
+}
+
+func TestManager_ReloadAppliesSessionLimitWithoutRestart(t *testing.T) {
+	os.Setenv("SESSION_LIMIT", "1")
+	t.Cleanup(func() { os.Unsetenv("SESSION_LIMIT") })
+
+	m := newTestManager(t)
+	if _, err := m.Load(nil); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	os.Setenv("SESSION_LIMIT", "5")
+	diff, err := m.Reload(nil)
+	if err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if got := m.Get().Server.SessionLimit; got != 5 {
+		t.Fatalf("Server.SessionLimit after reload = %d, want 5", got)
+	}
+	if !containsField(diff.Changed, "Server.SessionLimit") {
+		t.Errorf("diff.Changed = %v, want it to contain Server.SessionLimit", diff.Changed)
+	}
+}
+
+func TestManager_ReloadRejectsImmutablePortChange(t *testing.T) {
+	os.Setenv("SERVER_PORT", "3006")
+	t.Cleanup(func() { os.Unsetenv("SERVER_PORT") })
+
+	m := newTestManager(t)
+	if _, err := m.Load(nil); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	os.Setenv("SERVER_PORT", "4000")
+	_, err := m.Reload(nil)
+	if err == nil {
+		t.Fatal("Reload() should reject a changed Server.Port")
+	}
+	if got := m.Get().Server.Port; got != 3006 {
+		t.Errorf("Server.Port after rejected reload = %d, want unchanged 3006", got)
+	}
+}
+
+func TestManager_ReloadRejectsInvalidCandidate(t *testing.T) {
+	m := newTestManager(t)
+	if _, err := m.Load(nil); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	os.Setenv("DOCKER_TIMEOUT", "-1")
+	t.Cleanup(func() { os.Unsetenv("DOCKER_TIMEOUT") })
+	_, err := m.Reload(nil)
+	if err == nil {
+		t.Fatal("Reload() should reject a candidate that fails validateConfig")
+	}
+}
+
+func TestManager_SubscribeNotifiesObserverOnChange(t *testing.T) {
+	os.Setenv("LOG_LEVEL", "info")
+	t.Cleanup(func() { os.Unsetenv("LOG_LEVEL") })
+
+	m := newTestManager(t)
+	if _, err := m.Load(nil); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	var received ConfigDiff
+	calls := 0
+	m.Subscribe(ObserverFunc(func(diff ConfigDiff) {
+		calls++
+		received = diff
+	}))
+
+	os.Setenv("LOG_LEVEL", "warn")
+	if _, err := m.Reload(nil); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("observer called %d times, want 1", calls)
+	}
+	if received.New.Log.Level != "warn" {
+		t.Errorf("diff.New.Log.Level = %s, want warn", received.New.Log.Level)
+	}
+
+	// 再次Reload但配置没有变化：不应重复通知
+	if _, err := m.Reload(nil); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("observer called %d times after no-op reload, want still 1", calls)
+	}
+}
+
+func containsField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
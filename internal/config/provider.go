@@ -0,0 +1,229 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"kwdb-playground/internal/logger"
+)
+
+// Provider 是分层配置体系中的一层数据源，返回的 map 以现有环境变量名（如 "SERVER_HOST"）为键，
+// 这样 buildConfig 中原有的 getEnvWith/getEnvIntWith 等解析逻辑可以原样复用，不必为每个Provider
+// 单独实现一遍到 Config 字段的映射
+type Provider interface {
+	// Name 用于日志与 merge 顺序上下文中标识这一层来源
+	Name() string
+	// Load 返回这一层当前持有的全部键值对；出错时不应阻塞其他层，调用方会记录警告并跳过
+	Load() (map[string]string, error)
+}
+
+// envProvider 把进程环境变量原样作为一层Provider，使 Manager 的合并顺序里"env"与
+// 包级 Load()（直接读 os.Getenv）保持完全一致的语义
+type envProvider struct{}
+
+// NewEnvProvider 创建读取进程环境变量的 Provider
+func NewEnvProvider() Provider { return envProvider{} }
+
+func (envProvider) Name() string { return "env" }
+
+func (envProvider) Load() (map[string]string, error) {
+	result := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if idx := strings.IndexByte(kv, '='); idx >= 0 {
+			result[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	return result, nil
+}
+
+// fileProvider 从一个YAML/JSON文件读取配置覆盖，文件内容是扁平的 "ENV_VAR_NAME: value" 映射
+// （与 getEnv 系列读取的环境变量同名），而不是 Config 结构体的嵌套形状——这样无需为每个字段
+// 维护一份独立的"文件路径 -> 环境变量名"映射表，新增配置项时只需要在 config.go 里加一行 getEnv 调用
+type fileProvider struct {
+	path string
+}
+
+// NewFileProvider 创建基于文件的 Provider，根据扩展名选择 YAML 或 JSON 解析（.json 走JSON，其余按YAML处理，
+// 纯YAML解析器本身兼容JSON语法）
+func NewFileProvider(path string) Provider {
+	return fileProvider{path: path}
+}
+
+func (p fileProvider) Name() string { return fmt.Sprintf("file(%s)", p.path) }
+
+func (p fileProvider) Load() (map[string]string, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	raw := make(map[string]string)
+	if strings.EqualFold(filepathExt(p.path), ".json") {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("解析JSON配置文件失败: %w", err)
+		}
+		return raw, nil
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析YAML配置文件失败: %w", err)
+	}
+	return raw, nil
+}
+
+// filepathExt 避免仅为取扩展名引入 path/filepath 的包级别名冲突（config.go 未导入该包）
+func filepathExt(path string) string {
+	if idx := strings.LastIndexByte(path, '.'); idx >= 0 {
+		return path[idx:]
+	}
+	return ""
+}
+
+// remoteKVTimeout 远程KV Provider共用的HTTP客户端超时设置
+const remoteKVTimeout = 5 * time.Second
+
+// consulProvider 通过 Consul HTTP API（/v1/kv/<prefix>?recurse=true）读取一层覆盖，
+// key 为 prefix 之后的路径按 "/" -> "_" 转换并转大写，和其他Provider统一成环境变量名风格
+type consulProvider struct {
+	addr   string // 例如 http://127.0.0.1:8500
+	prefix string
+}
+
+// NewConsulProvider 创建 Consul KV Provider
+func NewConsulProvider(addr, prefix string) Provider {
+	return consulProvider{addr: strings.TrimSuffix(addr, "/"), prefix: strings.Trim(prefix, "/")}
+}
+
+func (p consulProvider) Name() string { return fmt.Sprintf("consul(%s)", p.prefix) }
+
+type consulKVEntry struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"` // base64编码
+}
+
+func (p consulProvider) Load() (map[string]string, error) {
+	url := fmt.Sprintf("%s/v1/kv/%s?recurse=true", p.addr, p.prefix)
+	client := &http.Client{Timeout: remoteKVTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Consul KV 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]string{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Consul KV 返回异常状态码: %d", resp.StatusCode)
+	}
+
+	var entries []consulKVEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("解析 Consul KV 响应失败: %w", err)
+	}
+
+	result := make(map[string]string, len(entries))
+	for _, e := range entries {
+		decoded, err := base64.StdEncoding.DecodeString(e.Value)
+		if err != nil {
+			continue
+		}
+		result[kvKeyToEnvName(e.Key, p.prefix)] = string(decoded)
+	}
+	return result, nil
+}
+
+// etcdProvider 通过 etcd v3 的 grpc-gateway JSON API（POST /v3/kv/range）读取一层覆盖，
+// 键名转换规则与 consulProvider 一致
+type etcdProvider struct {
+	addr   string // 例如 http://127.0.0.1:2379
+	prefix string
+}
+
+// NewEtcdProvider 创建 etcd v3 KV Provider
+func NewEtcdProvider(addr, prefix string) Provider {
+	return etcdProvider{addr: strings.TrimSuffix(addr, "/"), prefix: strings.Trim(prefix, "/")}
+}
+
+func (p etcdProvider) Name() string { return fmt.Sprintf("etcd(%s)", p.prefix) }
+
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Key   string `json:"key"`   // base64编码
+		Value string `json:"value"` // base64编码
+	} `json:"kvs"`
+}
+
+func (p etcdProvider) Load() (map[string]string, error) {
+	key := base64.StdEncoding.EncodeToString([]byte(p.prefix + "/"))
+	rangeEnd := base64.StdEncoding.EncodeToString(prefixRangeEnd([]byte(p.prefix + "/")))
+
+	body, err := json.Marshal(map[string]string{"key": key, "range_end": rangeEnd})
+	if err != nil {
+		return nil, fmt.Errorf("构造 etcd range 请求失败: %w", err)
+	}
+
+	client := &http.Client{Timeout: remoteKVTimeout}
+	resp, err := client.Post(p.addr+"/v3/kv/range", "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("请求 etcd KV 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("etcd KV 返回异常状态码: %d", resp.StatusCode)
+	}
+
+	var parsed etcdRangeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("解析 etcd KV 响应失败: %w", err)
+	}
+
+	result := make(map[string]string, len(parsed.Kvs))
+	for _, kv := range parsed.Kvs {
+		k, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			continue
+		}
+		v, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			continue
+		}
+		result[kvKeyToEnvName(string(k), p.prefix)] = string(v)
+	}
+	return result, nil
+}
+
+// prefixRangeEnd 计算etcd前缀查询所需的range_end（前缀最后一个字节+1），是etcd官方推荐的前缀扫描写法
+func prefixRangeEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// 全为0xff（极端情况），没有range_end可言，退化为单key查询
+	return nil
+}
+
+// kvKeyToEnvName 把远程KV的完整key（形如 "kwdb-playground/server/host"）转换为
+// 其余Provider统一使用的环境变量名风格（"SERVER_HOST"）
+func kvKeyToEnvName(fullKey, prefix string) string {
+	rel := strings.TrimPrefix(fullKey, prefix+"/")
+	rel = strings.Trim(rel, "/")
+	rel = strings.ReplaceAll(rel, "/", "_")
+	return strings.ToUpper(rel)
+}
+
+// warnProviderError 各Provider加载失败时的统一日志处理：某一层不可用不应阻塞整体配置加载，
+// 继续使用其余层已解析出的值（与 validateConfig 失败时只警告不中断的风格一致）
+func warnProviderError(loggerInstance *logger.Logger, p Provider, err error) {
+	loggerInstance.Warn("配置数据源 %s 加载失败，已跳过该层: %v", p.Name(), err)
+}
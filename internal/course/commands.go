@@ -0,0 +1,344 @@
+package course
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// ExecutableCommand 是从课程Markdown内容的围栏代码块中解析出的一条可执行命令/语句。
+// 一个代码块可以包含多条以 "$ " 开头的命令（典型地是多条SQL语句），每条都会展开为独立的
+// ExecutableCommand，共享同一代码块声明的元数据（Language/Session/Timeout等）
+type ExecutableCommand struct {
+	// ID 命令标识符。显式指定了 id 属性的代码块里，多条命令以 "<id>_<序号>" 区分；
+	// 未指定 id 时退化为 "<Language>_<序号>"（序号在产生该命令的整段Markdown文本内全局递增）
+	ID string `json:"id"`
+	// Language 代码块info string中的语言标签（第一个词），如 bash、sql
+	Language string `json:"language"`
+	// Session 属性 session=，同一session的SQL语句块之间共享连接/事务上下文，未声明为空
+	Session string `json:"session,omitempty"`
+	// Timeout 属性 timeout=（如 "30s"），未声明或无法解析为0，表示不限时
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// ExpectedOutput 运行结果预期包含的内容，供runner断言。优先取代码块的 expect= 属性；
+	// 未声明expect时，取该命令后、下一条命令前的非"$"行（即命令的示例输出文本）
+	ExpectedOutput string `json:"expectedOutput,omitempty"`
+	// HideOutput 属性 hide=true 时，runner不应把该命令的输出回显给用户
+	HideOutput bool `json:"hideOutput,omitempty"`
+	// Run 属性 run=（如 "auto"），"auto"表示课程页面加载后自动执行，未声明为空即需手动触发
+	Run string `json:"run,omitempty"`
+	// Raw 命令/语句的实际文本，已去掉前导的 "$ "；续行（含行尾反斜杠）与heredoc正文原样保留
+	Raw string `json:"raw"`
+	// Line 代码块在原Markdown文本中的起始行号（从1开始），仅用于日志定位
+	Line int `json:"line"`
+}
+
+// parseExecutableCommands 用goldmark解析Markdown文本，从其中每个围栏代码块提取可执行命令。
+// 围栏代码块的info string形如 "bash {id=init, run=auto, timeout=30s, expect=\"CREATE TABLE\"}"：
+// 第一个词是语言标签，花括号内是以逗号分隔的 key=value 属性（值可以用双引号包含空格/逗号）。
+// 代码块内容按行处理：
+//   - 以 "$ " 开头的行开启一条新命令；
+//   - 命令行以 "\" 结尾时与下一行续接（shell续行），直到某一行不以 "\" 结尾；
+//   - 命令行中出现 "<<DELIM" 形式的heredoc标记时，后续行原样并入该命令，直到出现与DELIM
+//     相同的整行为止；
+//   - 既不是命令行、也不在续行/heredoc状态中的行，视为上一条命令的预期输出而非命令的一部分
+//     （修复了旧实现把这类行悄悄拼进命令文本、破坏多语句SQL块的问题）
+func parseExecutableCommands(mdText string) []ExecutableCommand {
+	source := []byte(mdText)
+	md := goldmark.New()
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	var commands []ExecutableCommand
+	seq := 0
+
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		fcb, ok := n.(*ast.FencedCodeBlock)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		lang, attrs := parseFenceInfo(fcb, source)
+		if lang == "" {
+			return ast.WalkSkipChildren, nil
+		}
+
+		content := fenceContent(fcb, source)
+		line := lineNumber(source, fenceStartOffset(fcb))
+		blockCmds := splitBlockCommands(content, lang, attrs, line, &seq)
+		commands = append(commands, blockCmds...)
+		return ast.WalkSkipChildren, nil
+	})
+
+	return commands
+}
+
+// fenceAttrs 是围栏代码块info string花括号部分解析出的属性
+type fenceAttrs struct {
+	id         string
+	session    string
+	timeout    time.Duration
+	expect     string
+	hideOutput bool
+	run        string
+}
+
+// parseFenceInfo 解析围栏代码块的info string，返回语言标签与花括号属性；
+// 没有语言标签（裸的 ``` 代码块，常用于展示非命令的示例）时lang为空，调用方应跳过该块
+func parseFenceInfo(fcb *ast.FencedCodeBlock, source []byte) (string, fenceAttrs) {
+	if fcb.Info == nil {
+		return "", fenceAttrs{}
+	}
+	info := strings.TrimSpace(string(fcb.Info.Value(source)))
+	if info == "" {
+		return "", fenceAttrs{}
+	}
+
+	lang := info
+	attrPart := ""
+	if idx := strings.IndexByte(info, '{'); idx >= 0 {
+		lang = strings.TrimSpace(info[:idx])
+		end := strings.LastIndexByte(info, '}')
+		if end > idx {
+			attrPart = info[idx+1 : end]
+		}
+	} else if idx := strings.IndexByte(info, ' '); idx >= 0 {
+		lang = info[:idx]
+	}
+
+	return lang, parseFenceAttrs(attrPart)
+}
+
+// parseFenceAttrs 解析花括号内以逗号分隔的 key=value 列表，值允许用双引号包含空格/逗号
+func parseFenceAttrs(s string) fenceAttrs {
+	var attrs fenceAttrs
+	for _, pair := range splitAttrPairs(s) {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(strings.Trim(strings.TrimSpace(value), `"`))
+
+		switch key {
+		case "id":
+			attrs.id = value
+		case "session":
+			attrs.session = value
+		case "timeout":
+			if d, err := time.ParseDuration(value); err == nil {
+				attrs.timeout = d
+			}
+		case "expect":
+			attrs.expect = value
+		case "hide":
+			attrs.hideOutput, _ = strconv.ParseBool(value)
+		case "run":
+			attrs.run = value
+		}
+	}
+	return attrs
+}
+
+// splitAttrPairs 按逗号切分属性列表，但忽略双引号内的逗号（expect="a, b"这样的值）
+func splitAttrPairs(s string) []string {
+	var pairs []string
+	inQuotes := false
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '"':
+			inQuotes = !inQuotes
+		case ',':
+			if !inQuotes {
+				pairs = append(pairs, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(s) {
+		pairs = append(pairs, s[start:])
+	}
+	return pairs
+}
+
+// fenceContent 拼出围栏代码块的原始正文（不含围栏标记本身）
+func fenceContent(fcb *ast.FencedCodeBlock, source []byte) string {
+	var buf bytes.Buffer
+	lines := fcb.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		seg := lines.At(i)
+		buf.Write(seg.Value(source))
+	}
+	return buf.String()
+}
+
+func fenceStartOffset(fcb *ast.FencedCodeBlock) int {
+	lines := fcb.Lines()
+	if lines.Len() == 0 {
+		return 0
+	}
+	return lines.At(0).Start
+}
+
+// lineNumber 把字节offset换算成从1开始的行号
+func lineNumber(source []byte, offset int) int {
+	if offset > len(source) {
+		offset = len(source)
+	}
+	return bytes.Count(source[:offset], []byte("\n")) + 1
+}
+
+// heredocDelimiter 粗略匹配shell的 "<<[-]DELIM"/"<<'DELIM'"/"<<\"DELIM\"" 写法
+func heredocDelimiter(cmdLine string) (string, bool) {
+	idx := strings.Index(cmdLine, "<<")
+	if idx < 0 {
+		return "", false
+	}
+	rest := strings.TrimLeft(cmdLine[idx+2:], "-~")
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return "", false
+	}
+	// 取第一个token作为分隔符，去掉可能的引号
+	end := strings.IndexAny(rest, " \t")
+	if end >= 0 {
+		rest = rest[:end]
+	}
+	rest = strings.Trim(rest, `"'`)
+	if rest == "" {
+		return "", false
+	}
+	return rest, true
+}
+
+// splitBlockCommands 把一个代码块的正文按"$ "前缀切分为若干条命令，处理续行/heredoc，
+// 并把非命令行归入上一条命令的ExpectedOutput。block级别的属性（session/timeout/...）
+// 应用到块内每一条命令；seq是跨整段Markdown文本累加的序号，用于生成默认ID
+func splitBlockCommands(content, lang string, attrs fenceAttrs, startLine int, seq *int) []ExecutableCommand {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	var pending []ExecutableCommand
+	var seqNums []int // 与pending一一对应，记录该命令在*seq递增时拿到的全局序号
+	var cur *ExecutableCommand
+	var curSeq int
+	var rawBuilder strings.Builder
+	var outputBuilder strings.Builder
+	inHeredoc := false
+	heredocDelim := ""
+	continued := false
+
+	flushCurrent := func() {
+		if cur == nil {
+			return
+		}
+		cur.Raw = strings.TrimSpace(rawBuilder.String())
+		if attrs.expect != "" {
+			cur.ExpectedOutput = attrs.expect
+		} else {
+			cur.ExpectedOutput = strings.TrimSpace(outputBuilder.String())
+		}
+		pending = append(pending, *cur)
+		seqNums = append(seqNums, curSeq)
+		cur = nil
+		rawBuilder.Reset()
+		outputBuilder.Reset()
+	}
+
+	startCommand := func(lineNo int) {
+		flushCurrent()
+		*seq++
+		curSeq = *seq
+		cur = &ExecutableCommand{
+			Language:   lang,
+			Session:    attrs.session,
+			Timeout:    attrs.timeout,
+			HideOutput: attrs.hideOutput,
+			Run:        attrs.run,
+			Line:       lineNo,
+		}
+	}
+
+	for i, line := range lines {
+		lineNo := startLine + i
+		trimmed := strings.TrimSpace(line)
+
+		if inHeredoc {
+			if rawBuilder.Len() > 0 {
+				rawBuilder.WriteByte('\n')
+			}
+			rawBuilder.WriteString(line)
+			if trimmed == heredocDelim {
+				inHeredoc = false
+			}
+			continue
+		}
+
+		if continued {
+			// 续行：原样并入上一条命令（保留末尾的"\"，使Raw仍是可直接交给shell执行的
+			// 合法多行语法），不当作"$"命令也不当作输出
+			if rawBuilder.Len() > 0 {
+				rawBuilder.WriteByte('\n')
+			}
+			rawBuilder.WriteString(line)
+			continued = strings.HasSuffix(strings.TrimRight(line, " \t"), "\\")
+			if delim, ok := heredocDelimiter(line); ok && !continued {
+				inHeredoc = true
+				heredocDelim = delim
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "$") {
+			startCommand(lineNo)
+			cmdLine := strings.TrimSpace(strings.TrimPrefix(trimmed, "$"))
+			rawBuilder.WriteString(cmdLine)
+			if strings.HasSuffix(cmdLine, "\\") {
+				continued = true
+			} else if delim, ok := heredocDelimiter(cmdLine); ok {
+				inHeredoc = true
+				heredocDelim = delim
+			}
+			continue
+		}
+
+		if trimmed == "" {
+			continue
+		}
+
+		// 非命令行：视为上一条命令的预期输出
+		if cur != nil {
+			if outputBuilder.Len() > 0 {
+				outputBuilder.WriteByte('\n')
+			}
+			outputBuilder.WriteString(line)
+		}
+	}
+	flushCurrent()
+
+	assignIDs(pending, seqNums, attrs.id)
+	return pending
+}
+
+// assignIDs 为一个代码块内解析出的命令分配ID：未显式声明id时使用 "<lang>_<全局序号>"
+// （全局序号即该命令在整段Markdown文本中开始时拿到的seq值，跨代码块递增）；显式声明了id
+// 且块内有多条命令时，以 "<id>_<块内序号>" 区分，避免多条语句共用同一ID
+func assignIDs(cmds []ExecutableCommand, seqNums []int, explicitID string) {
+	for i := range cmds {
+		switch {
+		case explicitID == "":
+			cmds[i].ID = fmt.Sprintf("%s_%d", cmds[i].Language, seqNums[i])
+		case len(cmds) > 1:
+			cmds[i].ID = fmt.Sprintf("%s_%d", explicitID, i+1)
+		default:
+			cmds[i].ID = explicitID
+		}
+	}
+}
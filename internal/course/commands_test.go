@@ -0,0 +1,169 @@
+package course
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseExecutableCommands_Metadata 验证围栏代码块info string中各属性被正确解析
+func TestParseExecutableCommands_Metadata(t *testing.T) {
+	md := "```bash {id=init, run=auto, timeout=30s, hide=true}\n" +
+		"$ mkdir -p /data\n" +
+		"```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 1 {
+		t.Fatalf("got %d commands, want 1", len(cmds))
+	}
+	c := cmds[0]
+	if c.ID != "init" {
+		t.Errorf("ID = %q, want %q", c.ID, "init")
+	}
+	if c.Language != "bash" {
+		t.Errorf("Language = %q, want %q", c.Language, "bash")
+	}
+	if c.Run != "auto" {
+		t.Errorf("Run = %q, want %q", c.Run, "auto")
+	}
+	if c.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", c.Timeout)
+	}
+	if !c.HideOutput {
+		t.Error("HideOutput = false, want true")
+	}
+	if c.Raw != "mkdir -p /data" {
+		t.Errorf("Raw = %q, want %q", c.Raw, "mkdir -p /data")
+	}
+}
+
+// TestParseExecutableCommands_MultipleStatementsShareMetadata 验证一个代码块内多条命令
+// 共享block级元数据，显式id则以"<id>_<序号>"区分
+func TestParseExecutableCommands_MultipleStatementsShareMetadata(t *testing.T) {
+	md := "```sql {id=q, session=main}\n" +
+		"$ CREATE TABLE t (a INT);\n" +
+		"$ INSERT INTO t VALUES (1);\n" +
+		"```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 2 {
+		t.Fatalf("got %d commands, want 2", len(cmds))
+	}
+	if cmds[0].ID != "q_1" || cmds[1].ID != "q_2" {
+		t.Errorf("IDs = %q, %q, want q_1, q_2", cmds[0].ID, cmds[1].ID)
+	}
+	for _, c := range cmds {
+		if c.Session != "main" {
+			t.Errorf("Session = %q, want %q", c.Session, "main")
+		}
+	}
+}
+
+// TestParseExecutableCommands_ExpectedOutputFromTrailingLines 验证未显式声明expect时，
+// 命令后、下一条命令前的非"$"行被当作预期输出，而不是被拼接进命令文本本身
+// （这是对旧实现"悄悄把续行拼进命令、破坏多语句SQL块"问题的修复）
+func TestParseExecutableCommands_ExpectedOutputFromTrailingLines(t *testing.T) {
+	md := "```sql\n" +
+		"$ SELECT * FROM t;\n" +
+		"a | b\n" +
+		"1 | 2\n" +
+		"$ SELECT 1;\n" +
+		"```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 2 {
+		t.Fatalf("got %d commands, want 2", len(cmds))
+	}
+	if cmds[0].Raw != "SELECT * FROM t;" {
+		t.Errorf("Raw = %q, want %q (output lines must not be merged in)", cmds[0].Raw, "SELECT * FROM t;")
+	}
+	if cmds[0].ExpectedOutput != "a | b\n1 | 2" {
+		t.Errorf("ExpectedOutput = %q, want %q", cmds[0].ExpectedOutput, "a | b\n1 | 2")
+	}
+	if cmds[1].ExpectedOutput != "" {
+		t.Errorf("ExpectedOutput = %q, want empty", cmds[1].ExpectedOutput)
+	}
+}
+
+// TestParseExecutableCommands_ExplicitExpectOverridesOutput 验证显式expect属性优先于
+// 从代码块尾随行推断出的预期输出
+func TestParseExecutableCommands_ExplicitExpectOverridesOutput(t *testing.T) {
+	md := "```sql {expect=\"CREATE TABLE\"}\n" +
+		"$ CREATE TABLE t (a INT);\n" +
+		"ERROR: should be ignored\n" +
+		"```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 1 {
+		t.Fatalf("got %d commands, want 1", len(cmds))
+	}
+	if cmds[0].ExpectedOutput != "CREATE TABLE" {
+		t.Errorf("ExpectedOutput = %q, want %q", cmds[0].ExpectedOutput, "CREATE TABLE")
+	}
+}
+
+// TestParseExecutableCommands_BackslashContinuation 验证以"\"结尾的续行被原样并入同一条命令
+func TestParseExecutableCommands_BackslashContinuation(t *testing.T) {
+	md := "```bash\n" +
+		"$ echo a \\\n" +
+		"  b\n" +
+		"```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 1 {
+		t.Fatalf("got %d commands, want 1", len(cmds))
+	}
+	want := "echo a \\\n  b"
+	if cmds[0].Raw != want {
+		t.Errorf("Raw = %q, want %q", cmds[0].Raw, want)
+	}
+}
+
+// TestParseExecutableCommands_Heredoc 验证heredoc正文（含空行）被原样保留为同一条命令的一部分，
+// 不会被按行拆分或误判为预期输出
+func TestParseExecutableCommands_Heredoc(t *testing.T) {
+	md := "```bash\n" +
+		"$ cat <<EOF > f.txt\n" +
+		"line1\n" +
+		"\n" +
+		"line2\n" +
+		"EOF\n" +
+		"$ cat f.txt\n" +
+		"```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 2 {
+		t.Fatalf("got %d commands, want 2", len(cmds))
+	}
+	want := "cat <<EOF > f.txt\nline1\n\nline2\nEOF"
+	if cmds[0].Raw != want {
+		t.Errorf("Raw = %q, want %q", cmds[0].Raw, want)
+	}
+	if cmds[1].Raw != "cat f.txt" {
+		t.Errorf("Raw = %q, want %q", cmds[1].Raw, "cat f.txt")
+	}
+}
+
+// TestParseExecutableCommands_DefaultIDsAreSequential 验证未声明id的命令按全文整体顺序
+// 编号，跨多个代码块也不重复
+func TestParseExecutableCommands_DefaultIDsAreSequential(t *testing.T) {
+	md := "```bash\n$ echo 1\n```\n\n```bash\n$ echo 2\n```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 2 {
+		t.Fatalf("got %d commands, want 2", len(cmds))
+	}
+	if cmds[0].ID != "bash_1" || cmds[1].ID != "bash_2" {
+		t.Errorf("IDs = %q, %q, want bash_1, bash_2", cmds[0].ID, cmds[1].ID)
+	}
+}
+
+// TestParseExecutableCommands_IgnoresBareCodeBlocks 验证没有语言标签的裸代码块
+// （常用于展示非命令的示例输出）被跳过，不产生命令
+func TestParseExecutableCommands_IgnoresBareCodeBlocks(t *testing.T) {
+	md := "```\nsome example output, not a command\n```\n"
+
+	cmds := parseExecutableCommands(md)
+	if len(cmds) != 0 {
+		t.Fatalf("got %d commands, want 0", len(cmds))
+	}
+}
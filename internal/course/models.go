@@ -1,6 +1,10 @@
 package course
 
-import "time"
+import (
+	"time"
+
+	"kwdb-playground/internal/probe"
+)
 
 // Course 课程模型
 type Course struct {
@@ -14,6 +18,39 @@ type Course struct {
 	Tags             []string          `json:"tags" yaml:"tags"`
 	DockerImage      string            `json:"dockerImage" yaml:"dockerImage"`
 	DockerEnv        map[string]string `json:"dockerEnv,omitempty" yaml:"dockerEnv,omitempty"`
+	// DockerHostConfig 课程容器的资源限制与加固选项，留空字段由 policy 包的默认值与强制上限兜底，
+	// 防止不受信任的课程YAML请求无限资源或放开不该放开的能力
+	DockerHostConfig DockerHostConfig `json:"dockerHostConfig,omitempty" yaml:"dockerHostConfig,omitempty"`
+}
+
+// DockerHostConfig 课程容器的资源限制与运行时加固选项，字段对应 Docker HostConfig 的子集，
+// 为0/空的字段表示"不指定"，由调用方套用策略默认值而非零值语义
+type DockerHostConfig struct {
+	// CPUShares CPU相对权重（Docker --cpu-shares），0表示不设置
+	CPUShares int64 `json:"cpuShares,omitempty" yaml:"cpuShares,omitempty"`
+	// CPUQuota 每CPU周期可用的微秒数（Docker --cpu-quota），配合100000微秒的默认周期换算CPU核数上限
+	CPUQuota int64 `json:"cpuQuota,omitempty" yaml:"cpuQuota,omitempty"`
+	// MemoryMB 内存上限（MiB），0表示使用策略默认值
+	MemoryMB int64 `json:"memoryMB,omitempty" yaml:"memoryMB,omitempty"`
+	// PidsLimit 容器内进程数上限，防止fork炸弹耗尽宿主机PID空间
+	PidsLimit int64 `json:"pidsLimit,omitempty" yaml:"pidsLimit,omitempty"`
+	// ReadOnlyRootfs 是否将容器根文件系统设为只读，需要写入的路径应通过Tmpfs或显式挂载声明
+	ReadOnlyRootfs bool `json:"readOnlyRootfs,omitempty" yaml:"readOnlyRootfs,omitempty"`
+	// CapDrop 额外要丢弃的Linux能力，最终生效集合始终包含策略强制丢弃的能力（如NET_RAW）
+	CapDrop []string `json:"capDrop,omitempty" yaml:"capDrop,omitempty"`
+	// SecurityOpt 透传给HostConfig.SecurityOpt的条目，如 "seccomp=<profile路径>"
+	SecurityOpt []string `json:"securityOpt,omitempty" yaml:"securityOpt,omitempty"`
+	// Tmpfs 容器内路径到tmpfs挂载选项的映射，例如 {"/tmp": "size=64m"}
+	Tmpfs map[string]string `json:"tmpfs,omitempty" yaml:"tmpfs,omitempty"`
+	// Ulimits 容器的ulimit设置
+	Ulimits []Ulimit `json:"ulimits,omitempty" yaml:"ulimits,omitempty"`
+}
+
+// Ulimit 单项ulimit设置，字段含义与 `docker run --ulimit name=soft:hard` 一致
+type Ulimit struct {
+	Name string `json:"name" yaml:"name"`
+	Soft int64  `json:"soft" yaml:"soft"`
+	Hard int64  `json:"hard" yaml:"hard"`
 }
 
 // CourseDetail 课程详细信息
@@ -40,6 +77,79 @@ type CourseFile struct {
 type Backend struct {
 	ImageID   string `json:"imageid" yaml:"imageid"`
 	Workspace string `json:"workspace" yaml:"workspace"` // 容器工作目录
+
+	// Runtime 选择承载课程的运行时后端："docker"（默认）或 "kubernetes"
+	Runtime string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	// Namespace 仅 kubernetes 运行时生效，课程 Pod/Service 所在命名空间，默认 "default"
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// NodeSelector 仅 kubernetes 运行时生效，约束课程 Pod 调度到的节点
+	NodeSelector map[string]string `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty"`
+	// Resources 仅 kubernetes 运行时生效，课程容器的资源请求/限制
+	Resources ResourceRequirements `json:"resources,omitempty" yaml:"resources,omitempty"`
+
+	// ReadinessProbe 就绪探针，startCourse 在返回成功之前会等待该探针通过
+	ReadinessProbe *probe.Spec `json:"readinessProbe,omitempty" yaml:"readinessProbe,omitempty"`
+	// LivenessProbe 存活探针，探测失败会被记录，是否自动重启由调用方决定
+	LivenessProbe *probe.Spec `json:"livenessProbe,omitempty" yaml:"livenessProbe,omitempty"`
+
+	// Services 课程可声明多个容器（例如 KWDB 主容器 + 指标采集 sidecar），按 DependsOn 拓扑排序启动，逆序销毁
+	// 留空时退化为单容器课程（历史行为），由本结构体自身描述的镜像/Cmd等字段定义
+	Services []ServiceSpec `json:"services,omitempty" yaml:"services,omitempty"`
+
+	// Sandbox SQL终端沙箱模式配置，Enabled为false（默认）时保持历史行为：直接在连接池上以root执行任意语句
+	Sandbox SQLSandbox `json:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+
+	// Recording 终端会话录制配置，Enabled为false（默认）时即使客户端请求 record=true 也不会落盘
+	Recording TerminalRecordingConfig `json:"recording,omitempty" yaml:"recording,omitempty"`
+}
+
+// TerminalRecordingConfig 课程级别的终端会话录制开关与配额
+type TerminalRecordingConfig struct {
+	// Enabled 是否允许为本课程的终端会话开启 asciinema v2 录制
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// MaxSizeBytes 单个录制文件的大小上限，超出后轮转（截断回文件头重新累积），0表示使用内置默认值
+	MaxSizeBytes int64 `json:"maxSizeBytes,omitempty" yaml:"maxSizeBytes,omitempty"`
+}
+
+// SQLSandbox 课程SQL终端沙箱模式配置：以只读角色+资源配额的方式把SQL执行暴露给学生，
+// 避免单条失控或恶意查询拖垮共享的KWDB后端；各数值字段为0时由 sql.NewSandboxConfig 套用包内默认值
+type SQLSandbox struct {
+	// Enabled 是否启用沙箱模式
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	// StatementTimeoutMs 单条语句超时（毫秒）
+	StatementTimeoutMs int `json:"statementTimeoutMs,omitempty" yaml:"statementTimeoutMs,omitempty"`
+	// IdleInTransactionTimeoutMs 事务内空闲超时（毫秒），防止忘记提交/回滚的事务占用连接
+	IdleInTransactionTimeoutMs int `json:"idleInTransactionTimeoutMs,omitempty" yaml:"idleInTransactionTimeoutMs,omitempty"`
+	// MaxResultBytes 单次查询结果集字节上限，超出后截断并返回 hasMore+nextCursor
+	MaxResultBytes int `json:"maxResultBytes,omitempty" yaml:"maxResultBytes,omitempty"`
+	// MaxConcurrentQueries 该课程允许同时在途的沙箱查询数（跨所有学生会话）
+	MaxConcurrentQueries int `json:"maxConcurrentQueries,omitempty" yaml:"maxConcurrentQueries,omitempty"`
+	// QueriesPerMinute 每用户/会话每分钟允许发起的查询数
+	QueriesPerMinute int `json:"queriesPerMinute,omitempty" yaml:"queriesPerMinute,omitempty"`
+	// MaxTempTables 每用户/会话允许创建的临时表数量上限
+	MaxTempTables int `json:"maxTempTables,omitempty" yaml:"maxTempTables,omitempty"`
+}
+
+// ServiceSpec 课程下的一个附加容器服务
+type ServiceSpec struct {
+	// Name 服务名，课程内唯一，容器名称会以此为后缀
+	Name string `json:"name" yaml:"name"`
+	// ImageID 服务镜像
+	ImageID string `json:"imageid" yaml:"imageid"`
+	// Cmd 启动命令
+	Cmd []string `json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	// Env 环境变量，"KEY=VALUE" 形式
+	Env []string `json:"env,omitempty" yaml:"env,omitempty"`
+	// DependsOn 依赖的其他服务名（或主容器名 "main"），决定启动顺序
+	DependsOn []string `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty"`
+	// ReadinessProbe 该服务自身的就绪探针
+	ReadinessProbe *probe.Spec `json:"readinessProbe,omitempty" yaml:"readinessProbe,omitempty"`
+}
+
+// ResourceRequirements 资源请求与限制（CPU/内存），取值格式与 Kubernetes 一致，如 "500m"、"256Mi"
+type ResourceRequirements struct {
+	Requests map[string]string `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty" yaml:"limits,omitempty"`
 }
 
 // UserProgress 用户课程进度
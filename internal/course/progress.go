@@ -1,31 +1,160 @@
 package course
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"os"
+	"hash/fnv"
 	"sync"
 	"time"
 
 	"kwdb-playground/internal/logger"
 )
 
-// ProgressManager 用户进度管理器，负责进度的持久化存储和访问
-// 使用文件存储和 sync.Mutex 确保并发安全
-type ProgressManager struct {
-	filePath string
-	mu       sync.Mutex
-	logger   *logger.Logger
+// progressShardCount 内存缓存的分片数量，userID按哈希分散到各分片，使不同用户的读写互不阻塞
+const progressShardCount = 16
+
+// progressFlushInterval 写回(write-behind)后台goroutine的默认批量落盘间隔
+const progressFlushInterval = time.Second
+
+// ProgressSnapshot 某一时刻所有用户进度的完整导出，用于备份、跨存储迁移或离线分析
+type ProgressSnapshot struct {
+	Version   string
+	UpdatedAt time.Time
+	Progress  map[string]UserProgress // key: "userID:courseID"
+}
+
+// ProgressStore 进度数据的存储后端接口，ProgressManager 只通过该接口读写进度，
+// 不关心数据最终落在JSON文件、Badger KV还是SQLite中。实现方需要是并发安全的
+type ProgressStore interface {
+	// Get 读取指定用户某门课程的进度，不存在时 exists 为 false
+	Get(userID, courseID string) (progress *UserProgress, exists bool, err error)
+	// Put 写入（新建或覆盖）一条进度记录
+	Put(progress UserProgress) error
+	// Delete 删除指定用户某门课程的进度，记录不存在时视为成功
+	Delete(userID, courseID string) error
+	// List 返回所有进度记录，key 为 "userID:courseID"
+	List() (map[string]UserProgress, error)
+	// Snapshot 导出某一时刻的完整进度快照
+	Snapshot() (*ProgressSnapshot, error)
+	// Close 释放存储持有的底层资源（文件句柄/数据库连接）
+	Close() error
+}
+
+// ProgressWatcher 是 ProgressStore 的可选扩展接口，由需要向本实例推送"其他实例写入"的后端实现
+// （目前只有 HTTPProgressStore）。ProgressManager 在构造时通过类型断言探测该接口是否实现，
+// 实现了就额外启动一个后台goroutine把远端变化直接并入本地分片缓存
+type ProgressWatcher interface {
+	// Watch 返回一个远端变化会持续推送到的channel，ctx取消后channel应被关闭
+	Watch(ctx context.Context) (<-chan UserProgress, error)
+}
+
+// progressShard 是内存缓存按userID哈希分桶后的其中一个桶，持有该桶内所有记录的锁与数据，
+// 用N把独立的RWMutex替换原来的单个全局sync.Mutex，不同桶的读写可以完全并发
+type progressShard struct {
+	mu   sync.RWMutex
+	data map[string]UserProgress
+}
+
+func (s *progressShard) get(key string) (UserProgress, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	progress, exists := s.data[key]
+	return progress, exists
+}
+
+func (s *progressShard) put(key string, progress UserProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = progress
+}
+
+func (s *progressShard) delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+}
+
+// progressDirtyKey 标识一条待写回Store的记录，写回时重新从所属分片读取当前值，
+// 因此这里只需要足够定位分片与记录的userID/courseID
+type progressDirtyKey struct {
+	userID   string
+	courseID string
+}
+
+// ProgressEventType 区分 ProgressManager 事件总线上推送的几种进度变化
+type ProgressEventType string
+
+const (
+	ProgressStarted   ProgressEventType = "started"   // 该用户首次保存这门课程的进度
+	ProgressAdvanced  ProgressEventType = "advanced"  // 已有进度记录，步骤前进（未触发完成）
+	ProgressCompleted ProgressEventType = "completed" // 本次保存使 Completed 由 false 变为 true
+	ProgressReset     ProgressEventType = "reset"     // ResetProgress 清除了该用户的进度记录
+)
+
+// ProgressEvent 是一次 SaveProgress/ResetProgress 调用对外广播的通知，
+// 供 /ws/progress 与 /api/progress/stream 等展示性消费者使用
+type ProgressEvent struct {
+	Type        ProgressEventType `json:"type"`
+	UserID      string            `json:"userId"`
+	CourseID    string            `json:"courseId"`
+	CurrentStep int               `json:"currentStep"`
+	Completed   bool              `json:"completed"`
+	At          time.Time         `json:"at"`
+}
+
+// progressEventBufferSize 每个订阅者的事件缓冲区大小；写满后新事件会顶替最旧的一条（drop-oldest），
+// 而不是阻塞 SaveProgress/ResetProgress 的调用方——事件总线只是展示性信号，允许偶发丢失
+const progressEventBufferSize = 32
+
+// ProgressEventFilter 限定一次 Subscribe 只接收匹配的事件，字段为空表示不限制该维度
+type ProgressEventFilter struct {
+	UserID   string
+	CourseID string
+}
+
+func (f ProgressEventFilter) matches(ev ProgressEvent) bool {
+	if f.UserID != "" && f.UserID != ev.UserID {
+		return false
+	}
+	if f.CourseID != "" && f.CourseID != ev.CourseID {
+		return false
+	}
+	return true
 }
 
-// ProgressStore 进度存储结构，包含所有用户的进度数据
-type ProgressStore struct {
-	Version   string                  `json:"version"`
-	UpdatedAt time.Time               `json:"updated_at"`
-	Progress  map[string]UserProgress `json:"progress"` // key: "userID:courseID"
+// progressEventSub 是一个活跃订阅：ch为订阅者的接收端，filter决定哪些事件会被投递给它
+type progressEventSub struct {
+	ch     chan ProgressEvent
+	filter ProgressEventFilter
 }
 
-// NewProgressManager 创建新的进度管理器
+// ProgressManager 用户进度管理器，负责进度的内存缓存与持久化
+// GetProgress 完全由分片内存缓存提供服务；SaveProgress/ResetProgress 先同步写WAL再更新内存缓存，
+// 真正写入 ProgressStore 的动作交给后台write-behind goroutine按 progressFlushInterval 批量执行，
+// 消除了旧实现里"每次保存都整份重写JSON文件 + 单个全局锁"的瓶颈。WAL保证进程崩溃时
+// 最后一批尚未来得及落盘的变更不会丢失：启动时先加载Store中的快照，再重放WAL补齐
+type ProgressManager struct {
+	store  ProgressStore
+	logger *logger.Logger
+
+	shards [progressShardCount]*progressShard
+	wal    *progressWAL
+
+	dirtyMu sync.Mutex
+	dirty   map[string]progressDirtyKey
+
+	eventsMu sync.Mutex
+	events   []*progressEventSub
+
+	watchCancel context.CancelFunc
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewProgressManager 创建新的进度管理器，使用默认的JSON文件存储，
+// WAL文件路径固定为 filePath 加上 ".wal" 后缀
 // 参数:
 //
 //	filePath: 进度数据存储文件路径 (例如 "data/progress.json")
@@ -33,13 +162,88 @@ type ProgressStore struct {
 //
 // 返回: 初始化的进度管理器
 func NewProgressManager(filePath string, loggerInstance *logger.Logger) *ProgressManager {
-	return &ProgressManager{
-		filePath: filePath,
-		logger:   loggerInstance,
+	walPath := ""
+	if filePath != "" {
+		walPath = filePath + ".wal"
+	}
+	return NewProgressManagerWithStore(NewJSONProgressStore(filePath), walPath, loggerInstance)
+}
+
+// NewProgressManagerWithStore 基于指定的 ProgressStore 创建进度管理器，
+// 供需要 Badger/SQLite 等其他存储后端的部署使用
+// 参数:
+//
+//	store: 进度存储后端
+//	walPath: WAL文件路径，空字符串表示不启用WAL（重启后仅能依赖Store中已flush的数据）
+//	loggerInstance: 日志记录器实例
+//
+// 返回: 初始化的进度管理器
+func NewProgressManagerWithStore(store ProgressStore, walPath string, loggerInstance *logger.Logger) *ProgressManager {
+	pm := &ProgressManager{
+		store:  store,
+		logger: loggerInstance,
+		dirty:  make(map[string]progressDirtyKey),
+		stopCh: make(chan struct{}),
+	}
+	for i := range pm.shards {
+		pm.shards[i] = &progressShard{data: make(map[string]UserProgress)}
+	}
+
+	wal, err := newProgressWAL(walPath)
+	if err != nil {
+		loggerInstance.Warn("初始化进度WAL失败，将不具备崩溃恢复能力: %v", err)
+		wal = &progressWAL{}
+	}
+	pm.wal = wal
+	pm.loadFromStoreAndWAL()
+
+	pm.wg.Add(1)
+	go pm.writeBehindLoop()
+
+	if watcher, ok := store.(ProgressWatcher); ok {
+		watchCtx, cancel := context.WithCancel(context.Background())
+		pm.watchCancel = cancel
+		pm.wg.Add(1)
+		go pm.watchRemoteLoop(watchCtx, watcher)
+	}
+
+	return pm
+}
+
+// shardFor 返回userID对应哈希分片
+func (pm *ProgressManager) shardFor(userID string) *progressShard {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return pm.shards[h.Sum32()%progressShardCount]
+}
+
+// loadFromStoreAndWAL 启动时把Store中的快照整体加载进内存缓存，再重放WAL补齐快照之后、
+// 上次进程可能来不及flush的最后一批变更，保证重启后内存视图与崩溃前一致
+func (pm *ProgressManager) loadFromStoreAndWAL() {
+	snapshot, err := pm.store.Snapshot()
+	if err != nil {
+		pm.logger.Warn("加载进度快照失败，将以空缓存启动: %v", err)
+	} else if snapshot != nil {
+		for key, progress := range snapshot.Progress {
+			pm.shardFor(progress.UserID).put(key, progress)
+		}
+	}
+
+	if err := pm.wal.replay(func(rec progressWALRecord) {
+		switch rec.Op {
+		case walOpPut:
+			pm.shardFor(rec.Progress.UserID).put(progressKey(rec.Progress.UserID, rec.Progress.CourseID), rec.Progress)
+			pm.markDirty(rec.Progress.UserID, rec.Progress.CourseID)
+		case walOpDelete:
+			pm.shardFor(rec.UserID).delete(progressKey(rec.UserID, rec.CourseID))
+			pm.markDirty(rec.UserID, rec.CourseID)
+		}
+	}); err != nil {
+		pm.logger.Warn("重放进度WAL失败: %v", err)
 	}
 }
 
-// GetProgress 获取用户的课程进度
+// GetProgress 获取用户的课程进度，完全由内存缓存提供服务，不触碰底层Store
 // 参数:
 //
 //	userID: 用户ID
@@ -49,29 +253,17 @@ func NewProgressManager(filePath string, loggerInstance *logger.Logger) *Progres
 //
 //	progress: 用户进度对象（如果不存在则为零值）
 //	exists: 是否存在该进度记录
-//	err: 错误信息
+//	err: 错误信息（当前实现恒为nil，保留返回值以兼容调用方及未来可能的缓存未命中回源）
 func (pm *ProgressManager) GetProgress(userID, courseID string) (*UserProgress, bool, error) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
-	// 读取进度文件
-	store, err := pm.readProgressFile()
-	if err != nil {
-		return nil, false, err
-	}
-
-	// 查找指定用户的课程进度
-	key := pm.getProgressKey(userID, courseID)
-	progress, exists := store.Progress[key]
-
+	progress, exists := pm.shardFor(userID).get(progressKey(userID, courseID))
 	if exists {
 		pm.logger.Debug("获取用户进度成功: userID=%s, courseID=%s, currentStep=%d", userID, courseID, progress.CurrentStep)
 	}
-
 	return &progress, exists, nil
 }
 
-// SaveProgress 保存或更新用户的课程进度
+// SaveProgress 保存或更新用户的课程进度：先同步追加WAL记录，再更新内存缓存并标记为dirty，
+// 真正写入Store的动作由后台write-behind goroutine批量完成
 // 参数:
 //
 //	userID: 用户ID
@@ -79,19 +271,13 @@ func (pm *ProgressManager) GetProgress(userID, courseID string) (*UserProgress,
 //	step: 当前步骤索引
 //	completed: 是否已完成课程
 //
-// 返回: 错误信息
+// 返回: 错误信息（仅WAL写入失败时返回，内存缓存更新与Store落盘不会导致调用失败）
 func (pm *ProgressManager) SaveProgress(userID, courseID string, step int, completed bool) error {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
-	// 读取现有进度数据
-	store, err := pm.readProgressFile()
-	if err != nil {
-		return err
-	}
+	shard := pm.shardFor(userID)
+	key := progressKey(userID, courseID)
+	existing, exists := shard.get(key)
 
 	// 构建或更新进度对象
-	key := pm.getProgressKey(userID, courseID)
 	progress := UserProgress{
 		UserID:      userID,
 		CourseID:    courseID,
@@ -102,7 +288,7 @@ func (pm *ProgressManager) SaveProgress(userID, courseID string, step int, compl
 
 	// 如果是新建记录，设置起始时间
 	var existingCompleted bool
-	if existing, exists := store.Progress[key]; exists {
+	if exists {
 		progress.StartedAt = existing.StartedAt
 		progress.CompletedAt = existing.CompletedAt
 		existingCompleted = existing.Completed
@@ -121,117 +307,242 @@ func (pm *ProgressManager) SaveProgress(userID, courseID string, step int, compl
 		progress.CompletedAt = nil
 	}
 
-	store.Progress[key] = progress
-	store.UpdatedAt = time.Now()
-
-	// 写入进度文件
-	if err := pm.writeProgressFile(store); err != nil {
+	if err := pm.wal.appendPut(progress); err != nil {
 		return err
 	}
 
+	shard.put(key, progress)
+	pm.markDirty(userID, courseID)
+
 	pm.logger.Debug("保存用户进度成功: userID=%s, courseID=%s, currentStep=%d, completed=%v",
 		userID, courseID, step, completed)
 
+	eventType := ProgressAdvanced
+	switch {
+	case !exists:
+		eventType = ProgressStarted
+	case completed && !existingCompleted:
+		eventType = ProgressCompleted
+	}
+	pm.emitEvent(ProgressEvent{
+		Type:        eventType,
+		UserID:      userID,
+		CourseID:    courseID,
+		CurrentStep: step,
+		Completed:   completed,
+		At:          progress.UpdatedAt,
+	})
+
 	return nil
 }
 
-// ResetProgress 重置用户的课程进度
+// ResetProgress 重置用户的课程进度：先同步追加WAL删除记录，再从内存缓存移除并标记为dirty
 // 参数:
 //
 //	userID: 用户ID
 //	courseID: 课程ID
 //
-// 返回: 错误信息
+// 返回: 错误信息（仅WAL写入失败时返回）
 func (pm *ProgressManager) ResetProgress(userID, courseID string) error {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
-	// 读取现有进度数据
-	store, err := pm.readProgressFile()
-	if err != nil {
+	if err := pm.wal.appendDelete(userID, courseID); err != nil {
 		return err
 	}
 
-	// 删除指定用户的课程进度
-	key := pm.getProgressKey(userID, courseID)
-	if _, exists := store.Progress[key]; exists {
-		delete(store.Progress, key)
-		store.UpdatedAt = time.Now()
+	pm.shardFor(userID).delete(progressKey(userID, courseID))
+	pm.markDirty(userID, courseID)
 
-		// 写入进度文件
-		if err := pm.writeProgressFile(store); err != nil {
-			return err
-		}
+	pm.logger.Debug("重置用户进度成功: userID=%s, courseID=%s", userID, courseID)
 
-		pm.logger.Debug("重置用户进度成功: userID=%s, courseID=%s", userID, courseID)
-	} else {
-		pm.logger.Debug("重置用户进度: 进度记录不存在，userID=%s, courseID=%s", userID, courseID)
-	}
+	pm.emitEvent(ProgressEvent{
+		Type:     ProgressReset,
+		UserID:   userID,
+		CourseID: courseID,
+		At:       time.Now(),
+	})
 
 	return nil
 }
 
-// readProgressFile 读取进度文件内容
-// 如果文件不存在，返回空的进度存储结构
-// 返回: 进度存储对象或错误信息
-func (pm *ProgressManager) readProgressFile() (*ProgressStore, error) {
-	// 如果文件不存在，返回初始化的存储结构
-	if _, err := os.Stat(pm.filePath); os.IsNotExist(err) {
-		return &ProgressStore{
-			Version:   "1.0",
-			UpdatedAt: time.Now(),
-			Progress:  make(map[string]UserProgress),
-		}, nil
+// Subscribe 注册一个进度事件订阅，供 /ws/progress 与 /api/progress/stream 使用；
+// filter为零值表示不按userID/courseID过滤，接收全部事件。
+// 返回的cancel函数必须在调用方结束监听（如客户端断开）时调用，否则channel会一直占用在订阅列表中
+func (pm *ProgressManager) Subscribe(filter ProgressEventFilter) (<-chan ProgressEvent, func()) {
+	sub := &progressEventSub{
+		ch:     make(chan ProgressEvent, progressEventBufferSize),
+		filter: filter,
 	}
 
-	// 读取文件内容
-	data, err := os.ReadFile(pm.filePath)
-	if err != nil {
-		return nil, fmt.Errorf("读取进度文件失败: %w", err)
+	pm.eventsMu.Lock()
+	pm.events = append(pm.events, sub)
+	pm.eventsMu.Unlock()
+
+	cancel := func() {
+		pm.eventsMu.Lock()
+		defer pm.eventsMu.Unlock()
+		for i, s := range pm.events {
+			if s == sub {
+				pm.events = append(pm.events[:i], pm.events[i+1:]...)
+				break
+			}
+		}
+		close(sub.ch)
 	}
+	return sub.ch, cancel
+}
+
+// emitEvent 把一次进度变化广播给所有filter匹配的订阅者；订阅者的缓冲区已满时
+// 丢弃其最旧的一条事件为新事件腾出空间（drop-oldest），不阻塞 SaveProgress/ResetProgress 的调用方
+func (pm *ProgressManager) emitEvent(ev ProgressEvent) {
+	pm.eventsMu.Lock()
+	subs := append([]*progressEventSub(nil), pm.events...)
+	pm.eventsMu.Unlock()
 
-	// 解析JSON
-	var store ProgressStore
-	if err := json.Unmarshal(data, &store); err != nil {
-		pm.logger.Warn("进度文件格式错误，将重新初始化: %v", err)
-		return &ProgressStore{
-			Version:   "1.0",
-			UpdatedAt: time.Now(),
-			Progress:  make(map[string]UserProgress),
-		}, nil
+	for _, sub := range subs {
+		if !sub.filter.matches(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			select {
+			case <-sub.ch:
+			default:
+			}
+			select {
+			case sub.ch <- ev:
+			default:
+			}
+		}
 	}
+}
 
-	return &store, nil
+// markDirty 把一条记录加入待写回集合，由write-behind goroutine按批flush到Store
+func (pm *ProgressManager) markDirty(userID, courseID string) {
+	pm.dirtyMu.Lock()
+	pm.dirty[progressKey(userID, courseID)] = progressDirtyKey{userID: userID, courseID: courseID}
+	pm.dirtyMu.Unlock()
 }
 
-// writeProgressFile 写入进度文件内容
-// 参数:
-//
-//	store: 要写入的进度存储对象
-//
-// 返回: 错误信息
-func (pm *ProgressManager) writeProgressFile(store *ProgressStore) error {
-	// 序列化为JSON
-	data, err := json.MarshalIndent(store, "", "  ")
+// watchRemoteLoop 把 watcher 推送的远端变化直接并入本地分片缓存，使多个 playground 实例
+// 共用同一个远端进度存储（如 HTTPProgressStore）时能最终看到彼此的写入。远端记录已经持久化
+// 在Store中，这里只更新内存缓存，既不写WAL也不标记dirty——否则会在下一次flush时把同一条
+// 记录原样写回Store，造成无意义的重复写入
+func (pm *ProgressManager) watchRemoteLoop(ctx context.Context, watcher ProgressWatcher) {
+	defer pm.wg.Done()
+
+	ch, err := watcher.Watch(ctx)
 	if err != nil {
-		return fmt.Errorf("序列化进度数据失败: %w", err)
+		pm.logger.Warn("启动远端进度监听失败: %v", err)
+		return
+	}
+	for progress := range ch {
+		pm.shardFor(progress.UserID).put(progressKey(progress.UserID, progress.CourseID), progress)
+		pm.logger.Debug("应用远端进度更新: userID=%s, courseID=%s, currentStep=%d",
+			progress.UserID, progress.CourseID, progress.CurrentStep)
+	}
+}
+
+// writeBehindLoop 每隔progressFlushInterval把dirty集合批量写入Store，
+// stopCh关闭（Close）后做最后一次flush再退出，确保不丢失关闭前的最后一批变更
+func (pm *ProgressManager) writeBehindLoop() {
+	defer pm.wg.Done()
+
+	ticker := time.NewTicker(progressFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := pm.flush(); err != nil {
+				pm.logger.Warn("批量写入进度存储失败: %v", err)
+			}
+		case <-pm.stopCh:
+			if err := pm.flush(); err != nil {
+				pm.logger.Warn("关闭前最后一次写入进度存储失败: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// flush 把当前dirty集合中的记录批量写入Store：记录仍在内存缓存中则Put，已被删除则Delete，
+// 全部成功后清空WAL——WAL只需要保留"自上次成功flush以来"的变更即可在重启时重放
+func (pm *ProgressManager) flush() error {
+	pm.dirtyMu.Lock()
+	if len(pm.dirty) == 0 {
+		pm.dirtyMu.Unlock()
+		return nil
+	}
+	batch := make([]progressDirtyKey, 0, len(pm.dirty))
+	for _, dk := range pm.dirty {
+		batch = append(batch, dk)
+	}
+	pm.dirty = make(map[string]progressDirtyKey)
+	pm.dirtyMu.Unlock()
+
+	var firstErr error
+	for _, dk := range batch {
+		key := progressKey(dk.userID, dk.courseID)
+		if progress, exists := pm.shardFor(dk.userID).get(key); exists {
+			if err := pm.store.Put(progress); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("写入进度记录 %s 失败: %w", key, err)
+			}
+		} else {
+			if err := pm.store.Delete(dk.userID, dk.courseID); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("删除进度记录 %s 失败: %w", key, err)
+			}
+		}
 	}
 
-	// 写入文件
-	if err := os.WriteFile(pm.filePath, data, 0644); err != nil {
-		return fmt.Errorf("写入进度文件失败: %w", err)
+	if firstErr != nil {
+		// 这批记录没能全部落盘，重新标记为dirty以便下次重试；对应的WAL记录也原样保留
+		pm.dirtyMu.Lock()
+		for _, dk := range batch {
+			pm.dirty[progressKey(dk.userID, dk.courseID)] = dk
+		}
+		pm.dirtyMu.Unlock()
+		return firstErr
 	}
 
+	if err := pm.wal.truncate(); err != nil {
+		pm.logger.Warn("清空进度WAL失败: %v", err)
+	}
 	return nil
 }
 
-// getProgressKey 生成进度记录的唯一键
-// 参数:
-//
-//	userID: 用户ID
-//	courseID: 课程ID
-//
-// 返回: 组合的键字符串
-func (pm *ProgressManager) getProgressKey(userID, courseID string) string {
+// Flush 立即把当前dirty集合写入Store，不等待下一次周期性flush
+// 主要用于测试以及需要强一致读取底层Store（备份、迁移）前的场景
+func (pm *ProgressManager) Flush() error {
+	return pm.flush()
+}
+
+// Close 停止write-behind后台goroutine（flush完最后一批dirty记录）并释放WAL/Store持有的资源，
+// 应在 ProgressManager 不再使用时调用（例如进程退出前）
+func (pm *ProgressManager) Close() error {
+	var err error
+	pm.closeOnce.Do(func() {
+		if pm.watchCancel != nil {
+			pm.watchCancel()
+		}
+		close(pm.stopCh)
+		pm.wg.Wait()
+
+		if walErr := pm.wal.Close(); walErr != nil {
+			err = walErr
+		}
+		if storeErr := pm.store.Close(); storeErr != nil && err == nil {
+			err = storeErr
+		}
+	})
+	return err
+}
+
+// SetLogger 替换日志记录器实例，供 Service.SetLogger 统一下发
+func (pm *ProgressManager) SetLogger(loggerInstance *logger.Logger) {
+	pm.logger = loggerInstance
+}
+
+// progressKey 生成进度记录的唯一键，所有 ProgressStore 实现共用
+func progressKey(userID, courseID string) string {
 	return fmt.Sprintf("%s:%s", userID, courseID)
 }
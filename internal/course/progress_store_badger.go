@@ -0,0 +1,127 @@
+package course
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// BadgerProgressStore 基于嵌入式 Badger KV 的 ProgressStore 实现，面向高并发写入场景
+// （大规模在线课堂同时上报进度）：每条记录独立存储为一个key-value，单次写入只涉及该记录
+// 对应的LSM层追加，不像 JSONProgressStore 那样需要重写整份文件
+type BadgerProgressStore struct {
+	db *badger.DB
+}
+
+// NewBadgerProgressStore 打开（或创建）dir 目录下的 Badger 数据库作为进度存储
+func NewBadgerProgressStore(dir string) (*BadgerProgressStore, error) {
+	opts := badger.DefaultOptions(dir).WithLogger(nil)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, fmt.Errorf("打开Badger进度存储失败: %w", err)
+	}
+	return &BadgerProgressStore{db: db}, nil
+}
+
+// Get 读取指定用户某门课程的进度
+func (s *BadgerProgressStore) Get(userID, courseID string) (*UserProgress, bool, error) {
+	var progress UserProgress
+	exists := false
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(progressKey(userID, courseID)))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		exists = true
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &progress)
+		})
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("读取进度记录失败: %w", err)
+	}
+
+	return &progress, exists, nil
+}
+
+// Put 写入（新建或覆盖）一条进度记录
+func (s *BadgerProgressStore) Put(progress UserProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("序列化进度数据失败: %w", err)
+	}
+
+	key := []byte(progressKey(progress.UserID, progress.CourseID))
+	if err := s.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, data)
+	}); err != nil {
+		return fmt.Errorf("写入进度记录失败: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 删除指定用户某门课程的进度，记录不存在时视为成功
+func (s *BadgerProgressStore) Delete(userID, courseID string) error {
+	key := []byte(progressKey(userID, courseID))
+	err := s.db.Update(func(txn *badger.Txn) error {
+		err := txn.Delete(key)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("删除进度记录失败: %w", err)
+	}
+
+	return nil
+}
+
+// List 返回所有进度记录
+func (s *BadgerProgressStore) List() (map[string]UserProgress, error) {
+	out := make(map[string]UserProgress)
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			key := string(item.Key())
+			var progress UserProgress
+			if err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &progress)
+			}); err != nil {
+				return err
+			}
+			out[key] = progress
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出进度记录失败: %w", err)
+	}
+
+	return out, nil
+}
+
+// Snapshot 导出某一时刻的完整进度快照
+func (s *BadgerProgressStore) Snapshot() (*ProgressSnapshot, error) {
+	progress, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return &ProgressSnapshot{Version: "1.0", UpdatedAt: time.Now(), Progress: progress}, nil
+}
+
+// Close 关闭底层 Badger 数据库，释放文件锁与内存表
+func (s *BadgerProgressStore) Close() error {
+	return s.db.Close()
+}
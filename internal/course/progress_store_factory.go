@@ -0,0 +1,62 @@
+package course
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"kwdb-playground/internal/config"
+)
+
+// NewProgressStoreFromConfig 根据 config.ProgressConfig 构造对应的 ProgressStore，
+// 供 cmd/server 在启动时替换 Service 默认的JSON文件存储。dataDir 在 cfg.Path 未显式指定时
+// 用于派生 sqlite/badger 后端的默认存储路径（通常是 cfg.DataDir）
+func NewProgressStoreFromConfig(cfg config.ProgressConfig, dataDir string) (ProgressStore, error) {
+	switch cfg.Backend {
+	case "", "json":
+		path := cfg.Path
+		if path == "" {
+			path = filepath.Join(dataDir, "progress.json")
+		}
+		return NewJSONProgressStore(path), nil
+	case "sqlite":
+		path := cfg.Path
+		if path == "" {
+			path = filepath.Join(dataDir, "progress.db")
+		}
+		return NewSQLiteProgressStore(path)
+	case "badger":
+		dir := cfg.Path
+		if dir == "" {
+			dir = filepath.Join(dataDir, "progress-badger")
+		}
+		return NewBadgerProgressStore(dir)
+	case "http":
+		if cfg.HTTPSyncURL == "" {
+			return nil, fmt.Errorf("http progress backend requires HTTPSyncURL to be set")
+		}
+		return NewHTTPProgressStore(cfg.HTTPSyncURL, cfg.HTTPSyncToken), nil
+	default:
+		return nil, fmt.Errorf("unknown progress backend %q (expected \"json\", \"sqlite\", \"badger\" or \"http\")", cfg.Backend)
+	}
+}
+
+// ProgressWALPathForBackend 返回给定后端对应的WAL文件路径，与 NewProgressStoreFromConfig 的
+// 存储路径放在同一目录下；远端http后端本身没有本地存储路径，WAL改为固定落在 dataDir 下，
+// 使崩溃恢复不依赖远端服务的可用性
+func ProgressWALPathForBackend(cfg config.ProgressConfig, dataDir string) string {
+	if cfg.Backend == "http" {
+		return filepath.Join(dataDir, "progress-http.wal")
+	}
+	path := cfg.Path
+	if path == "" {
+		switch cfg.Backend {
+		case "sqlite":
+			path = filepath.Join(dataDir, "progress.db")
+		case "badger":
+			path = filepath.Join(dataDir, "progress-badger")
+		default:
+			path = filepath.Join(dataDir, "progress.json")
+		}
+	}
+	return path + ".wal"
+}
@@ -0,0 +1,249 @@
+package course
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpProgressWatchInterval 是长轮询请求之间的最小间隔：服务端本应阻塞至有变化或超时才返回，
+// 这里仍设一个下限防止行为异常的远端服务（立即返回空结果）把本地goroutine拖入忙轮询
+const httpProgressWatchInterval = time.Second
+
+// httpProgressWatchTimeout 单次长轮询请求的服务端等待上限，通过 ?waitSeconds= 查询参数传递
+const httpProgressWatchTimeout = 30 * time.Second
+
+// HTTPProgressStore 把进度数据同步到一个远端HTTP服务的 ProgressStore 实现，
+// 供多个 playground 实例部署在同一负载均衡器之后共享进度数据。
+// 远端服务需要实现一组简单的REST接口（见各方法注释），具体存储介质由远端自行决定
+type HTTPProgressStore struct {
+	baseURL string
+	token   string
+	client  *http.Client
+
+	watchCancel context.CancelFunc
+}
+
+// NewHTTPProgressStore 创建一个同步到 baseURL 的进度存储，token非空时以
+// "Authorization: Bearer <token>" 携带在每个请求中
+func NewHTTPProgressStore(baseURL, token string) *HTTPProgressStore {
+	return &HTTPProgressStore{
+		baseURL: baseURL,
+		token:   token,
+		client:  &http.Client{Timeout: httpProgressWatchTimeout + 10*time.Second},
+	}
+}
+
+// Get 对应远端 GET /progress?user_id=&course_id=，404视为不存在
+func (s *HTTPProgressStore) Get(userID, courseID string) (*UserProgress, bool, error) {
+	resp, err := s.do(http.MethodGet, fmt.Sprintf("/progress?user_id=%s&course_id=%s", userID, courseID), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("远端进度服务返回状态码 %d", resp.StatusCode)
+	}
+
+	var progress UserProgress
+	if err := json.NewDecoder(resp.Body).Decode(&progress); err != nil {
+		return nil, false, fmt.Errorf("解析远端进度响应失败: %w", err)
+	}
+	return &progress, true, nil
+}
+
+// Put 对应远端 PUT /progress，请求体为JSON编码的 UserProgress
+func (s *HTTPProgressStore) Put(progress UserProgress) error {
+	body, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("序列化进度数据失败: %w", err)
+	}
+
+	resp, err := s.do(http.MethodPut, "/progress", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("远端进度服务写入失败，状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Delete 对应远端 DELETE /progress?user_id=&course_id=
+func (s *HTTPProgressStore) Delete(userID, courseID string) error {
+	resp, err := s.do(http.MethodDelete, fmt.Sprintf("/progress?user_id=%s&course_id=%s", userID, courseID), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("远端进度服务删除失败，状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// List 对应远端 GET /progress/list，返回以 "userID:courseID" 为key的全量记录
+func (s *HTTPProgressStore) List() (map[string]UserProgress, error) {
+	resp, err := s.do(http.MethodGet, "/progress/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("远端进度服务返回状态码 %d", resp.StatusCode)
+	}
+
+	var entries []UserProgress
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("解析远端进度列表失败: %w", err)
+	}
+
+	out := make(map[string]UserProgress, len(entries))
+	for _, p := range entries {
+		out[progressKey(p.UserID, p.CourseID)] = p
+	}
+	return out, nil
+}
+
+// Snapshot 对应远端 GET /progress/snapshot，远端未提供该接口时退化为基于 List 在本地拼装
+func (s *HTTPProgressStore) Snapshot() (*ProgressSnapshot, error) {
+	resp, err := s.do(http.MethodGet, "/progress/snapshot", nil)
+	if err == nil {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			var snapshot ProgressSnapshot
+			if err := json.NewDecoder(resp.Body).Decode(&snapshot); err == nil {
+				return &snapshot, nil
+			}
+		}
+	}
+
+	progress, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return &ProgressSnapshot{Version: "1.0", UpdatedAt: time.Now(), Progress: progress}, nil
+}
+
+// Close 停止Watch启动的长轮询goroutine（若已启动）
+func (s *HTTPProgressStore) Close() error {
+	if s.watchCancel != nil {
+		s.watchCancel()
+	}
+	return nil
+}
+
+// Watch 实现 ProgressWatcher：对远端 GET /progress/watch?since=<cursor>&waitSeconds=N 做长轮询，
+// 远端应在有新变化或超过 waitSeconds 秒后返回（本身即是一种简易长轮询协议，WebSocket接入方式
+// 留给远端服务自行决定，本地只需要这一个HTTP入口）。收到的每条记录被推送到返回的channel，
+// 由 ProgressManager 直接并入本地内存缓存，使多个 playground 实例最终看到彼此的写入
+func (s *HTTPProgressStore) Watch(ctx context.Context) (<-chan UserProgress, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.watchCancel = cancel
+
+	ch := make(chan UserProgress, 32)
+	go func() {
+		defer close(ch)
+		cursor := ""
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			start := time.Now()
+			entries, nextCursor, err := s.pollOnce(ctx, cursor)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(httpProgressWatchInterval):
+				}
+				continue
+			}
+			cursor = nextCursor
+			for _, p := range entries {
+				select {
+				case ch <- p:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if wait := httpProgressWatchInterval - time.Since(start); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// httpProgressWatchResponse 是 GET /progress/watch 的响应结构
+type httpProgressWatchResponse struct {
+	Entries []UserProgress `json:"entries"`
+	Cursor  string         `json:"cursor"`
+}
+
+func (s *HTTPProgressStore) pollOnce(ctx context.Context, cursor string) ([]UserProgress, string, error) {
+	path := fmt.Sprintf("/progress/watch?since=%s&waitSeconds=%d", cursor, int(httpProgressWatchTimeout.Seconds()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+path, nil)
+	if err != nil {
+		return nil, cursor, err
+	}
+	s.setAuth(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, cursor, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, cursor, fmt.Errorf("远端进度服务长轮询返回状态码 %d", resp.StatusCode)
+	}
+
+	var parsed httpProgressWatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, cursor, fmt.Errorf("解析长轮询响应失败: %w", err)
+	}
+	return parsed.Entries, parsed.Cursor, nil
+}
+
+func (s *HTTPProgressStore) do(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("构建远端进度请求失败: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	s.setAuth(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求远端进度服务失败: %w", err)
+	}
+	return resp, nil
+}
+
+func (s *HTTPProgressStore) setAuth(req *http.Request) {
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+}
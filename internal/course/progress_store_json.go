@@ -0,0 +1,195 @@
+package course
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// jsonProgressFile 是 JSONProgressStore 落盘的文件格式，整份文件随每次 Put/Delete 原子重写
+type jsonProgressFile struct {
+	Version   string                  `json:"version"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Progress  map[string]UserProgress `json:"progress"` // key: "userID:courseID"
+}
+
+// JSONProgressStore 基于单个JSON文件的 ProgressStore 实现，NewProgressManager的默认后端
+// 每次写入都是"读取整份文件 -> 修改 -> 写临时文件 -> fsync -> os.Rename"，
+// 原子替换目标文件，避免进程崩溃或断电导致文件被截断成半份JSON；
+// 用一把互斥锁串行化读写，写放大问题（重写整份文件）由 BadgerProgressStore/SQLiteProgressStore 解决
+type JSONProgressStore struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewJSONProgressStore 创建基于JSON文件的进度存储
+// 参数:
+//
+//	filePath: 进度数据存储文件路径 (例如 "data/progress.json")
+func NewJSONProgressStore(filePath string) *JSONProgressStore {
+	return &JSONProgressStore{filePath: filePath}
+}
+
+// Get 读取指定用户某门课程的进度
+func (s *JSONProgressStore) Get(userID, courseID string) (*UserProgress, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, false, err
+	}
+
+	progress, exists := file.Progress[progressKey(userID, courseID)]
+	return &progress, exists, nil
+}
+
+// Put 写入（新建或覆盖）一条进度记录
+func (s *JSONProgressStore) Put(progress UserProgress) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	file.Progress[progressKey(progress.UserID, progress.CourseID)] = progress
+	file.UpdatedAt = time.Now()
+
+	return s.writeAtomic(file)
+}
+
+// Delete 删除指定用户某门课程的进度，记录不存在时视为成功
+func (s *JSONProgressStore) Delete(userID, courseID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	key := progressKey(userID, courseID)
+	if _, exists := file.Progress[key]; !exists {
+		return nil
+	}
+
+	delete(file.Progress, key)
+	file.UpdatedAt = time.Now()
+
+	return s.writeAtomic(file)
+}
+
+// List 返回所有进度记录
+func (s *JSONProgressStore) List() (map[string]UserProgress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]UserProgress, len(file.Progress))
+	for k, v := range file.Progress {
+		out[k] = v
+	}
+	return out, nil
+}
+
+// Snapshot 导出某一时刻的完整进度快照
+func (s *JSONProgressStore) Snapshot() (*ProgressSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make(map[string]UserProgress, len(file.Progress))
+	for k, v := range file.Progress {
+		progress[k] = v
+	}
+	return &ProgressSnapshot{Version: file.Version, UpdatedAt: file.UpdatedAt, Progress: progress}, nil
+}
+
+// Close JSON文件存储没有需要释放的底层资源
+func (s *JSONProgressStore) Close() error {
+	return nil
+}
+
+// read 读取进度文件内容，文件不存在或内容损坏时返回空的进度存储结构
+func (s *JSONProgressStore) read() (*jsonProgressFile, error) {
+	if _, err := os.Stat(s.filePath); os.IsNotExist(err) {
+		return &jsonProgressFile{
+			Version:   "1.0",
+			UpdatedAt: time.Now(),
+			Progress:  make(map[string]UserProgress),
+		}, nil
+	}
+
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取进度文件失败: %w", err)
+	}
+
+	var file jsonProgressFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return &jsonProgressFile{
+			Version:   "1.0",
+			UpdatedAt: time.Now(),
+			Progress:  make(map[string]UserProgress),
+		}, nil
+	}
+	if file.Progress == nil {
+		file.Progress = make(map[string]UserProgress)
+	}
+
+	return &file, nil
+}
+
+// writeAtomic 先把内容写到同目录下的临时文件并fsync，再通过os.Rename原子替换目标文件，
+// 确保其他读者/进程崩溃重启后永远只能看到完整的旧文件或完整的新文件，不会是半份JSON
+func (s *JSONProgressStore) writeAtomic(file *jsonProgressFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化进度数据失败: %w", err)
+	}
+
+	dir := filepath.Dir(s.filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建进度数据目录失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".progress-*.tmp")
+	if err != nil {
+		return fmt.Errorf("创建临时进度文件失败: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // Rename成功后该路径已不存在，这里是失败路径的兜底清理
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时进度文件失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("同步临时进度文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("关闭临时进度文件失败: %w", err)
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return fmt.Errorf("设置进度文件权限失败: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		return fmt.Errorf("替换进度文件失败: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package course
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯Go SQLite驱动，避免引入CGO构建依赖
+)
+
+// SQLiteProgressStore 基于 SQLite 的 ProgressStore 实现，面向需要对进度做即席查询/
+// 统计分析的部署（例如按课程聚合完成率），数据以关系表形式落盘，天然支持SQL查询
+type SQLiteProgressStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteProgressStore 打开（或创建）path 指向的 SQLite 数据库文件并建表
+func NewSQLiteProgressStore(path string) (*SQLiteProgressStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("打开SQLite进度存储失败: %w", err)
+	}
+	db.SetMaxOpenConns(1) // SQLite同一时间只允许一个写连接，固定为1避免SQLITE_BUSY
+
+	const schema = `
+CREATE TABLE IF NOT EXISTS progress (
+	user_id      TEXT NOT NULL,
+	course_id    TEXT NOT NULL,
+	current_step INTEGER NOT NULL,
+	completed    INTEGER NOT NULL,
+	started_at   TEXT NOT NULL,
+	completed_at TEXT,
+	updated_at   TEXT NOT NULL,
+	PRIMARY KEY (user_id, course_id)
+);`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化SQLite进度表失败: %w", err)
+	}
+
+	return &SQLiteProgressStore{db: db}, nil
+}
+
+// Get 读取指定用户某门课程的进度
+func (s *SQLiteProgressStore) Get(userID, courseID string) (*UserProgress, bool, error) {
+	row := s.db.QueryRow(`SELECT user_id, course_id, current_step, completed, started_at, completed_at, updated_at
+		FROM progress WHERE user_id = ? AND course_id = ?`, userID, courseID)
+
+	progress, err := scanProgress(row)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("读取进度记录失败: %w", err)
+	}
+
+	return progress, true, nil
+}
+
+// Put 写入（新建或覆盖）一条进度记录
+func (s *SQLiteProgressStore) Put(progress UserProgress) error {
+	_, err := s.db.Exec(`INSERT INTO progress (user_id, course_id, current_step, completed, started_at, completed_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, course_id) DO UPDATE SET
+			current_step = excluded.current_step,
+			completed = excluded.completed,
+			started_at = excluded.started_at,
+			completed_at = excluded.completed_at,
+			updated_at = excluded.updated_at`,
+		progress.UserID, progress.CourseID, progress.CurrentStep, progress.Completed,
+		formatTime(progress.StartedAt), formatTimePtr(progress.CompletedAt), formatTime(progress.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("写入进度记录失败: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 删除指定用户某门课程的进度，记录不存在时视为成功
+func (s *SQLiteProgressStore) Delete(userID, courseID string) error {
+	if _, err := s.db.Exec(`DELETE FROM progress WHERE user_id = ? AND course_id = ?`, userID, courseID); err != nil {
+		return fmt.Errorf("删除进度记录失败: %w", err)
+	}
+	return nil
+}
+
+// List 返回所有进度记录
+func (s *SQLiteProgressStore) List() (map[string]UserProgress, error) {
+	rows, err := s.db.Query(`SELECT user_id, course_id, current_step, completed, started_at, completed_at, updated_at FROM progress`)
+	if err != nil {
+		return nil, fmt.Errorf("列出进度记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]UserProgress)
+	for rows.Next() {
+		progress, err := scanProgress(rows)
+		if err != nil {
+			return nil, fmt.Errorf("解析进度记录失败: %w", err)
+		}
+		out[progressKey(progress.UserID, progress.CourseID)] = *progress
+	}
+	return out, rows.Err()
+}
+
+// Snapshot 导出某一时刻的完整进度快照
+func (s *SQLiteProgressStore) Snapshot() (*ProgressSnapshot, error) {
+	progress, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return &ProgressSnapshot{Version: "1.0", UpdatedAt: time.Now(), Progress: progress}, nil
+}
+
+// Close 关闭底层数据库连接
+func (s *SQLiteProgressStore) Close() error {
+	return s.db.Close()
+}
+
+// progressScanner 抽象 *sql.Row 与 *sql.Rows 共用的Scan方法，供scanProgress复用
+type progressScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProgress 把一行 progress 表记录解析为 UserProgress
+func scanProgress(scanner progressScanner) (*UserProgress, error) {
+	var (
+		p           UserProgress
+		completed   int
+		startedAt   string
+		completedAt sql.NullString
+		updatedAt   string
+	)
+	if err := scanner.Scan(&p.UserID, &p.CourseID, &p.CurrentStep, &completed, &startedAt, &completedAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	p.Completed = completed != 0
+	p.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+	p.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+	if completedAt.Valid {
+		if t, err := time.Parse(time.RFC3339Nano, completedAt.String); err == nil {
+			p.CompletedAt = &t
+		}
+	}
+
+	return &p, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func formatTimePtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
@@ -16,6 +16,7 @@ func TestGetProgress_FileNotExist_ReturnsEmpty(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	progress, exists, err := manager.GetProgress("user1", "course1")
 	if err != nil {
@@ -37,6 +38,7 @@ func TestSaveProgress_CreatesFileAndPersists(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	userID := "user1"
 	courseID := "course1"
@@ -48,6 +50,10 @@ func TestSaveProgress_CreatesFileAndPersists(t *testing.T) {
 		t.Fatalf("SaveProgress() error: %v", err)
 	}
 
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
 	if _, err := os.Stat(progressFile); os.IsNotExist(err) {
 		t.Error("SaveProgress() should create progress file")
 	}
@@ -92,6 +98,7 @@ func TestSaveProgress_UpdatesCompletedTimestamp(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	userID := "user1"
 	courseID := "course1"
@@ -149,6 +156,7 @@ func TestResetProgress_RemovesEntry(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	userID := "user1"
 	courseID := "course1"
@@ -192,6 +200,7 @@ func TestReadProgressFile_CorruptedJSON_ResetsToEmptyStore(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	progress, exists, err := manager.GetProgress("user1", "course1")
 	if err != nil {
@@ -213,6 +222,7 @@ func TestSaveProgress_MultipleUsers(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	users := []struct {
 		userID   string
@@ -254,6 +264,7 @@ func TestProgressManager_ConcurrentAccess(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	done := make(chan bool, 10)
 	for i := 0; i < 10; i++ {
@@ -270,12 +281,16 @@ func TestProgressManager_ConcurrentAccess(t *testing.T) {
 		<-done
 	}
 
+	if err := manager.Flush(); err != nil {
+		t.Fatalf("Flush() error: %v", err)
+	}
+
 	data, err := os.ReadFile(progressFile)
 	if err != nil {
 		t.Fatalf("ReadFile() error: %v", err)
 	}
 
-	var store ProgressStore
+	var store jsonProgressFile
 	if err := json.Unmarshal(data, &store); err != nil {
 		t.Fatalf("json.Unmarshal() error: %v", err)
 	}
@@ -291,6 +306,7 @@ func TestResetProgress_NonexistentProgress(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	err := manager.ResetProgress("nonexistent", "course1")
 	if err != nil {
@@ -304,6 +320,7 @@ func TestSaveProgress_PreservesOtherUserData(t *testing.T) {
 
 	loggerInstance := logger.NewLogger(logger.INFO)
 	manager := NewProgressManager(progressFile, loggerInstance)
+	defer manager.Close()
 
 	err := manager.SaveProgress("user1", "course1", 3, false)
 	if err != nil {
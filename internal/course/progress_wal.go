@@ -0,0 +1,139 @@
+package course
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// progressWALOp 一条WAL记录的操作类型
+type progressWALOp string
+
+const (
+	walOpPut    progressWALOp = "put"
+	walOpDelete progressWALOp = "delete"
+)
+
+// progressWALRecord 是WAL文件中的一行JSON，记录一次对某条进度记录的变更意图。
+// ProgressStore 的真正落盘由后台write-behind goroutine批量异步完成，
+// WAL先于内存缓存更新同步写入，崩溃重启时通过重放WAL补齐尚未来得及flush的最后一批变更
+type progressWALRecord struct {
+	Op       progressWALOp `json:"op"`
+	Progress UserProgress  `json:"progress,omitempty"` // op=put时有效
+	UserID   string        `json:"userId,omitempty"`   // op=delete时有效
+	CourseID string        `json:"courseId,omitempty"` // op=delete时有效
+}
+
+// progressWAL 维护一个追加写入的WAL文件，每条记录一行JSON（JSONL），
+// 成功flush到Store之后通过 truncate 清空，避免文件无限增长、重放耗时越来越长
+type progressWAL struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// newProgressWAL 打开（或创建）path对应的WAL文件；path为空表示不启用WAL，
+// 此时返回的 progressWAL 是无操作的零值（用于不需要崩溃恢复能力的场景，如内存Store）
+func newProgressWAL(path string) (*progressWAL, error) {
+	if path == "" {
+		return &progressWAL{}, nil
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建WAL目录失败: %w", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开WAL文件失败: %w", err)
+	}
+
+	return &progressWAL{path: path, file: f}, nil
+}
+
+// appendPut 追加一条Put意图记录
+func (w *progressWAL) appendPut(progress UserProgress) error {
+	return w.append(progressWALRecord{Op: walOpPut, Progress: progress})
+}
+
+// appendDelete 追加一条Delete意图记录
+func (w *progressWAL) appendDelete(userID, courseID string) error {
+	return w.append(progressWALRecord{Op: walOpDelete, UserID: userID, CourseID: courseID})
+}
+
+// append 把一条记录序列化为一行JSON追加写入并fsync，保证返回前该条变更已落盘
+func (w *progressWAL) append(rec progressWALRecord) error {
+	if w.file == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("序列化WAL记录失败: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入WAL失败: %w", err)
+	}
+	return w.file.Sync()
+}
+
+// replay 按顺序读取WAL中的每条记录并回调apply，用于启动时补齐快照之后尚未flush的变更。
+// 文件末尾若存在因崩溃写了一半的残缺行，直接跳过该行，不影响之前已完整写入的记录
+func (w *progressWAL) replay(apply func(progressWALRecord)) error {
+	if w.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取WAL文件失败: %w", err)
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec progressWALRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			continue
+		}
+		apply(rec)
+	}
+	return nil
+}
+
+// truncate 清空WAL文件内容，在一批记录成功flush到Store后调用
+func (w *progressWAL) truncate() error {
+	if w.file == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("清空WAL文件失败: %w", err)
+	}
+	_, err := w.file.Seek(0, 0)
+	return err
+}
+
+// Close 关闭底层WAL文件
+func (w *progressWAL) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
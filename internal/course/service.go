@@ -2,7 +2,7 @@
 // 包括课程加载、内容解析、命令提取等核心功能
 //
 // 主要功能:
-//   - 从文件系统加载课程配置和内容
+//   - 从一个或多个 CourseSource（本地目录、embed.FS、HTTP(S)课程包、Git仓库）加载课程配置和内容
 //   - 解析Markdown格式的课程文档
 //   - 提取课程中的可执行命令
 //   - 线程安全的课程数据访问
@@ -15,11 +15,11 @@
 package course
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
-	"os"
-	"path"
-	"path/filepath"
 	"strings"
 	"sync"
 
@@ -31,51 +31,95 @@ import (
 // Service 课程服务，负责管理所有课程的加载和访问
 // 线程安全，支持并发访问
 type Service struct {
-	coursesDir       string             // 课程文件根目录
-	coursesFS        fs.FS              // 课程文件系统
-	coursesBasePath  string             // 课程在FS中的根路径
-	courses          map[string]*Course // 课程缓存，key为课程ID
-	mu               sync.RWMutex       // 读写锁，保护courses map的并发访问
-	logger           *logger.Logger     // 日志记录器实例
+	sources []CourseSource     // 课程数据源，按优先级排列，靠前的源在课程ID冲突时生效
+	courses map[string]*Course // 课程缓存，key为课程ID
+	mu      sync.RWMutex       // 读写锁，保护sources/courses的并发访问
+	logger  *logger.Logger     // 日志记录器实例
+
+	cmdMu    sync.Mutex                     // 保护cmdCache的并发访问，与mu分开以避免命令提取阻塞课程读写
+	cmdCache map[string][]ExecutableCommand // 按课程ID缓存的ExtractExecutableCommands结果，Watch热重载时失效
+	events   chan CourseEvent               // Watch检测到变化后推送的课程事件，供HTTP层转发为SSE等
+
+	fileMu    sync.Mutex                 // 保护fileCache的并发访问，与mu分开的理由同cmdMu
+	fileCache map[string]courseFileEntry // 按"courseID/relPath"缓存ReadCourseFileWithETag结果，Watch热重载时随所属课程一起失效
+
+	progressManager *ProgressManager // 学习进度的持久化管理，默认使用JSON文件存储
+}
+
+// defaultProgressFilePath 进度数据默认存储路径，与 config.DataDir 的默认值 "./data" 保持一致
+const defaultProgressFilePath = "data/progress.json"
+
+// CourseEventType 描述一次 CourseEvent 的种类
+type CourseEventType string
+
+const (
+	// CourseEventUpdated 表示某个课程被新增或内容发生了变化
+	CourseEventUpdated CourseEventType = "updated"
+	// CourseEventRemoved 表示某个课程从所有数据源中消失，已从缓存移除
+	CourseEventRemoved CourseEventType = "removed"
+)
+
+// CourseEvent 描述一次由 Watch 触发的课程热重载事件
+type CourseEvent struct {
+	Type     CourseEventType
+	CourseID string
+}
+
+// courseFileEntry 是 ReadCourseFileWithETag 的一条缓存结果
+type courseFileEntry struct {
+	data []byte
+	etag string
 }
 
-// NewService 创建新的课程服务实例
+// NewService 创建新的课程服务实例，课程来自本地磁盘目录
 // 参数:
 //
 //	coursesDir: 课程文件存储目录路径
 //
 // 返回: 初始化的课程服务实例
 func NewService(coursesDir string) *Service {
-	// 创建默认INFO级别的logger实例
 	loggerInstance := logger.NewLogger(logger.INFO)
 	loggerInstance.Debug("Creating new course service with directory: %s", coursesDir)
-	return &Service{
-		coursesDir:      coursesDir,
-		courses:         make(map[string]*Course),
-		mu:              sync.RWMutex{},
-		logger:          loggerInstance,
-	}
+	return NewServiceWithSources([]CourseSource{NewLocalSource(coursesDir)})
 }
 
 // NewServiceFromFS 基于嵌入式文件系统创建课程服务（发布模式）
 // 参数:
 //
-//  coursesFS: 提供课程内容的文件系统，通常为 embed.FS
-//  basePath: 课程在FS中的根路径，例如 "courses"
+//	coursesFS: 提供课程内容的文件系统，通常为 embed.FS
+//	basePath: 课程在FS中的根路径，例如 "courses"
 //
 // 返回: 初始化的课程服务实例
 func NewServiceFromFS(coursesFS fs.FS, basePath string) *Service {
 	loggerInstance := logger.NewLogger(logger.INFO)
 	loggerInstance.Debug("Creating new course service from FS with base path: %s", basePath)
+	return NewServiceWithSources([]CourseSource{NewFSSource(coursesFS, basePath)})
+}
+
+// NewServiceWithSources 基于一组显式的 CourseSource 创建课程服务，用于需要叠加多个来源
+// （例如本地目录 + 运营方独立发布的 HTTP/Git 课程包）的场景。sources 按优先级排列，
+// LoadCourses 合并时靠前的源在课程ID冲突时生效
+func NewServiceWithSources(sources []CourseSource) *Service {
+	loggerInstance := logger.NewLogger(logger.INFO)
 	return &Service{
-		coursesFS:       coursesFS,
-		coursesBasePath: basePath,
+		sources:         sources,
 		courses:         make(map[string]*Course),
-		mu:              sync.RWMutex{},
 		logger:          loggerInstance,
+		cmdCache:        make(map[string][]ExecutableCommand),
+		fileCache:       make(map[string]courseFileEntry),
+		events:          make(chan CourseEvent, 32),
+		progressManager: NewProgressManager(defaultProgressFilePath, loggerInstance),
 	}
 }
 
+// AddSource 追加一个优先级更低的课程数据源，课程ID与已有源冲突时被忽略
+// 需要在下一次 LoadCourses 调用后才会生效
+func (s *Service) AddSource(src CourseSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources = append(s.sources, src)
+}
+
 // SetLogger 设置日志记录器实例
 // 允许外部配置logger，使其与配置系统兼容
 // 参数:
@@ -83,216 +127,340 @@ func NewServiceFromFS(coursesFS fs.FS, basePath string) *Service {
 //	loggerInstance: 要设置的logger实例
 func (s *Service) SetLogger(loggerInstance *logger.Logger) {
 	s.logger = loggerInstance
+	s.progressManager.SetLogger(loggerInstance)
+}
+
+// SetProgressStore 替换进度管理器使用的存储后端（例如Badger/SQLite），
+// 取代构造时默认的JSON文件存储；walPath为新进度管理器的WAL文件路径，空字符串表示不启用WAL。
+// 旧的进度管理器会先被关闭（flush尚未落盘的记录），需要在使用进度相关方法前调用
+func (s *Service) SetProgressStore(store ProgressStore, walPath string) {
+	if s.progressManager != nil {
+		if err := s.progressManager.Close(); err != nil {
+			s.logger.Warn("关闭旧的进度管理器失败: %v", err)
+		}
+	}
+	s.progressManager = NewProgressManagerWithStore(store, walPath, s.logger)
+}
+
+// GetProgress 获取用户的课程进度，委托给内部的 ProgressManager
+func (s *Service) GetProgress(userID, courseID string) (*UserProgress, bool, error) {
+	return s.progressManager.GetProgress(userID, courseID)
+}
+
+// SaveProgress 保存或更新用户的课程进度，委托给内部的 ProgressManager
+func (s *Service) SaveProgress(userID, courseID string, step int, completed bool) error {
+	return s.progressManager.SaveProgress(userID, courseID, step, completed)
+}
+
+// Close 释放课程服务持有的资源，目前只涉及进度管理器（flush待写回的进度、关闭WAL/Store）
+func (s *Service) Close() error {
+	return s.progressManager.Close()
+}
+
+// ResetProgress 重置用户的课程进度，委托给内部的 ProgressManager
+func (s *Service) ResetProgress(userID, courseID string) error {
+	return s.progressManager.ResetProgress(userID, courseID)
+}
+
+// SubscribeProgress 订阅进度事件（started/advanced/completed/reset），委托给内部的 ProgressManager，
+// 供 /ws/progress 与 /api/progress/stream 等展示性消费者使用
+func (s *Service) SubscribeProgress(filter ProgressEventFilter) (<-chan ProgressEvent, func()) {
+	return s.progressManager.Subscribe(filter)
 }
 
 // LoadCourses 加载所有课程
-// 扫描课程目录，加载所有有效的课程配置和内容
-// 该方法是线程安全的，会清空现有课程缓存并重新加载
-// 返回: 如果目录不存在或读取失败则返回错误
+// 依次扫描每个数据源并按优先级合并课程目录，清空现有课程缓存并重新加载
+// 该方法是线程安全的
+// 返回: 所有数据源均未能列出任何课程时返回错误
 func (s *Service) LoadCourses() error {
-	// 如果设置了嵌入式FS，则走嵌入模式
-	if s.coursesFS != nil {
-		s.logger.Debug("Loading courses from embedded FS: %s", s.coursesBasePath)
+	s.mu.RLock()
+	sources := append([]CourseSource(nil), s.sources...)
+	s.mu.RUnlock()
+
+	if len(sources) == 0 {
+		return fmt.Errorf("no course sources configured")
+	}
 
-		entries, err := fs.ReadDir(s.coursesFS, s.coursesBasePath)
+	merged := make(map[string]*Course)
+	var sourceErrs []string
+	for _, src := range sources {
+		ids, err := src.ListCourses()
 		if err != nil {
-			return fmt.Errorf("failed to read courses base path in FS: %w", err)
+			s.logger.Error("列出数据源 %s 的课程失败: %v", src.Name(), err)
+			sourceErrs = append(sourceErrs, fmt.Sprintf("%s: %v", src.Name(), err))
+			continue
 		}
 
-		// 使用写锁保护courses map
-		s.mu.Lock()
-		defer s.mu.Unlock()
-
-		// 重新初始化缓存，避免旧数据残留
-		s.courses = make(map[string]*Course)
-
-		loadedCount := 0
-		for _, entry := range entries {
-			if entry.IsDir() {
-				courseID := entry.Name()
-				s.logger.Debug("Loading course (FS): %s", courseID)
-
-				coursePath := path.Join(s.coursesBasePath, courseID)
-				course, err := s.loadCourseFromFS(courseID, coursePath)
-				if err != nil {
-					s.logger.Error("Failed to load course %s from FS: %v", courseID, err)
-					continue
-				}
+		for _, courseID := range ids {
+			if _, exists := merged[courseID]; exists {
+				s.logger.Debug("课程 %s 已存在于优先级更高的数据源，跳过来自 %s 的版本", courseID, src.Name())
+				continue
+			}
 
-				s.courses[courseID] = course
-				loadedCount++
+			c, err := s.loadCourseFromSource(src, courseID)
+			if err != nil {
+				s.logger.Error("从数据源 %s 加载课程 %s 失败: %v", src.Name(), courseID, err)
+				continue
 			}
+			merged[courseID] = c
 		}
-
-		s.logger.Info("Successfully loaded %d courses from embedded FS", loadedCount)
-		return nil
 	}
 
-	s.logger.Debug("Loading courses from directory: %s", s.coursesDir)
+	s.mu.Lock()
+	s.courses = merged
+	s.mu.Unlock()
 
-	// 检查课程目录是否存在
-	if _, err := os.Stat(s.coursesDir); os.IsNotExist(err) {
-		return fmt.Errorf("courses directory does not exist: %s", s.coursesDir)
+	s.logger.Info("成功加载 %d 个课程（共 %d 个数据源）", len(merged), len(sources))
+	if len(merged) == 0 && len(sourceErrs) > 0 {
+		return fmt.Errorf("failed to list courses from any source: %s", strings.Join(sourceErrs, "; "))
 	}
+	return nil
+}
 
-	entries, err := os.ReadDir(s.coursesDir)
-	if err != nil {
-		return fmt.Errorf("failed to read courses directory: %w", err)
-	}
+// Watch 启动所有数据源的变化监听（本地磁盘目录走fsnotify，HTTP/Git等走各自的后台轮询），
+// 变化发生时增量重载受影响的课程（仅替换该课程在courses中的缓存项，而非重建整个map），
+// 并通过 Events() 推送一个 CourseEvent。ctx 取消后所有监听goroutine退出。
+// 没有任何数据源支持监听时直接返回nil，不启动后台goroutine
+func (s *Service) Watch(ctx context.Context) error {
+	s.mu.RLock()
+	sources := append([]CourseSource(nil), s.sources...)
+	s.mu.RUnlock()
 
-	// 使用写锁保护courses map
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	var chans []<-chan string
+	for _, src := range sources {
+		ch, err := src.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to watch course source %s: %w", src.Name(), err)
+		}
+		if ch != nil {
+			chans = append(chans, ch)
+		}
+	}
+	if len(chans) == 0 {
+		s.logger.Debug("没有数据源支持变化监听，跳过课程热重载")
+		return nil
+	}
 
-	// 重新初始化缓存，避免旧数据残留
-	s.courses = make(map[string]*Course)
+	go s.watchLoop(ctx, chans)
+	return nil
+}
 
-	loadedCount := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			courseID := entry.Name()
-			s.logger.Debug("Loading course: %s", courseID)
+// Events 返回课程热重载事件的只读channel；只有调用过 Watch 之后才会产生事件
+func (s *Service) Events() <-chan CourseEvent {
+	return s.events
+}
 
-			coursePath := filepath.Join(s.coursesDir, courseID)
-			course, err := s.loadCourse(courseID, coursePath)
-			if err != nil {
-				s.logger.Error("Failed to load course %s: %v", courseID, err)
-				continue
+// watchLoop 把所有数据源的变化channel汇聚到一起，按课程ID增量重载，courseID为空字符串的
+// 通知（整包/整仓库更新，无法定位到具体课程）触发一次完整的 LoadCourses
+func (s *Service) watchLoop(ctx context.Context, chans []<-chan string) {
+	merged := make(chan string, 16)
+	var wg sync.WaitGroup
+	for _, ch := range chans {
+		wg.Add(1)
+		go func(ch <-chan string) {
+			defer wg.Done()
+			for courseID := range ch {
+				select {
+				case merged <- courseID:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case courseID, ok := <-merged:
+			if !ok {
+				return
+			}
+			if courseID == "" {
+				s.reloadAll()
+			} else {
+				s.reloadCourse(courseID)
 			}
-
-			s.courses[courseID] = course
-			loadedCount++
 		}
 	}
-
-	s.logger.Info("Successfully loaded %d courses", loadedCount)
-	return nil
 }
 
-// loadCourse 加载单个课程的配置和内容（磁盘模式）
-// courseID: 课程的唯一标识符
-// coursePath: 课程目录的完整路径
-// 返回完整的课程对象或错误信息
-func (s *Service) loadCourse(courseID, coursePath string) (*Course, error) {
-	configPath := filepath.Join(coursePath, "index.yaml")
+// reloadCourse 增量重载单个课程：按数据源优先级找到第一个仍拥有该课程ID的源并重新加载，
+// 只替换courses中的这一项；所有数据源都不再拥有该课程ID时视为删除
+func (s *Service) reloadCourse(courseID string) {
+	s.mu.RLock()
+	sources := append([]CourseSource(nil), s.sources...)
+	s.mu.RUnlock()
 
-	// 检查配置文件是否存在
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("course config file not found: %s", configPath)
-	}
+	for _, src := range sources {
+		ids, err := src.ListCourses()
+		if err != nil {
+			s.logger.Warn("热重载课程 %s 时列出数据源 %s 失败: %v", courseID, src.Name(), err)
+			continue
+		}
+		owned := false
+		for _, id := range ids {
+			if id == courseID {
+				owned = true
+				break
+			}
+		}
+		if !owned {
+			continue
+		}
 
-	// 读取课程配置文件
-	configData, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read course config: %w", err)
+		c, err := s.loadCourseFromSource(src, courseID)
+		if err != nil {
+			s.logger.Error("热重载课程 %s 失败: %v", courseID, err)
+			return
+		}
+
+		s.mu.Lock()
+		s.courses[courseID] = c
+		s.mu.Unlock()
+
+		s.invalidateCommandsCache(courseID)
+		s.invalidateFileCache(courseID)
+		s.emitEvent(CourseEvent{Type: CourseEventUpdated, CourseID: courseID})
+		s.logger.Info("课程 %s 已热重载（来自数据源 %s）", courseID, src.Name())
+		return
 	}
 
-	// 检查配置文件是否为空
-	if len(configData) == 0 {
-		return nil, fmt.Errorf("course config file is empty: %s", configPath)
+	s.mu.Lock()
+	_, existed := s.courses[courseID]
+	delete(s.courses, courseID)
+	s.mu.Unlock()
+
+	if existed {
+		s.invalidateCommandsCache(courseID)
+		s.invalidateFileCache(courseID)
+		s.emitEvent(CourseEvent{Type: CourseEventRemoved, CourseID: courseID})
+		s.logger.Info("课程 %s 已从所有数据源移除", courseID)
 	}
+}
 
-	// 解析YAML配置
-	var course Course
-	if err := yaml.Unmarshal(configData, &course); err != nil {
-		return nil, fmt.Errorf("failed to parse course config: %w", err)
+// reloadAll 用于无法定位到具体课程的变化通知：重新执行一次完整的 LoadCourses，
+// 清空整个命令缓存，并为重载后仍存在的每个课程各推送一个 updated 事件
+func (s *Service) reloadAll() {
+	if err := s.LoadCourses(); err != nil {
+		s.logger.Error("热重载全部课程失败: %v", err)
+		return
 	}
 
-	// 设置课程ID和基础信息
-	course.ID = courseID
-	if course.Title == "" {
-		course.Title = courseID // 如果没有设置标题，使用ID作为默认标题
+	s.mu.RLock()
+	ids := make([]string, 0, len(s.courses))
+	for id := range s.courses {
+		ids = append(ids, id)
 	}
+	s.mu.RUnlock()
 
-	// 加载课程详细内容
-	if err := s.loadCourseContent(&course, coursePath); err != nil {
-		return nil, fmt.Errorf("failed to load course content: %w", err)
+	s.cmdMu.Lock()
+	s.cmdCache = make(map[string][]ExecutableCommand)
+	s.cmdMu.Unlock()
+
+	s.fileMu.Lock()
+	s.fileCache = make(map[string]courseFileEntry)
+	s.fileMu.Unlock()
+
+	for _, id := range ids {
+		s.emitEvent(CourseEvent{Type: CourseEventUpdated, CourseID: id})
 	}
+}
 
-	return &course, nil
+// invalidateCommandsCache 清除指定课程缓存的ExtractExecutableCommands结果，
+// 使下一次调用重新从最新内容解析
+func (s *Service) invalidateCommandsCache(courseID string) {
+	s.cmdMu.Lock()
+	delete(s.cmdCache, courseID)
+	s.cmdMu.Unlock()
 }
 
-// loadCourseFromFS 加载单个课程的配置和内容（嵌入模式）
-// courseID: 课程的唯一标识符
-// coursePath: 课程在FS中的目录路径（使用/分隔）
-// 返回完整的课程对象或错误信息
-func (s *Service) loadCourseFromFS(courseID, coursePath string) (*Course, error) {
-	configPath := path.Join(coursePath, "index.yaml")
+// invalidateFileCache 清除指定课程缓存的所有 ReadCourseFileWithETag 结果，
+// 使下一次读取重新计算ETag
+func (s *Service) invalidateFileCache(courseID string) {
+	prefix := courseID + "/"
+	s.fileMu.Lock()
+	for key := range s.fileCache {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.fileCache, key)
+		}
+	}
+	s.fileMu.Unlock()
+}
 
-	// 读取课程配置文件（FS内）
-	configData, err := fs.ReadFile(s.coursesFS, configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read course config from FS: %w", err)
+// emitEvent 非阻塞地推送一个课程事件；events channel已满时丢弃并记录警告，
+// 与各CourseSource.Watch()的通知channel保持相同的"尽力而为、不阻塞生产者"语义
+func (s *Service) emitEvent(ev CourseEvent) {
+	select {
+	case s.events <- ev:
+	default:
+		s.logger.Warn("课程事件channel已满，丢弃事件: %+v", ev)
 	}
+}
 
-	// 检查配置文件是否为空
-	if len(configData) == 0 {
-		return nil, fmt.Errorf("course config file is empty: %s", configPath)
+// loadCourseFromSource 从指定数据源加载单个课程的配置与内容
+func (s *Service) loadCourseFromSource(src CourseSource, courseID string) (*Course, error) {
+	configData, err := src.OpenConfig(courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read course config: %w", err)
 	}
 
-	// 解析YAML配置
-	var course Course
-	if err := yaml.Unmarshal(configData, &course); err != nil {
+	var c Course
+	if err := yaml.Unmarshal(configData, &c); err != nil {
 		return nil, fmt.Errorf("failed to parse course config: %w", err)
 	}
 
-	// 设置课程ID和基础信息
-	course.ID = courseID
-	if course.Title == "" {
-		course.Title = courseID // 如果没有设置标题，使用ID作为默认标题
+	c.ID = courseID
+	if c.Title == "" {
+		c.Title = courseID // 如果没有设置标题，使用ID作为默认标题
 	}
 
-	// 加载课程详细内容（FS内）
-	if err := s.loadCourseContentFromFS(&course, coursePath); err != nil {
-		return nil, fmt.Errorf("failed to load course content from FS: %w", err)
+	if err := s.loadCourseContent(src, &c); err != nil {
+		return nil, fmt.Errorf("failed to load course content: %w", err)
 	}
-
-	return &course, nil
+	return &c, nil
 }
 
-// loadCourseContent 加载课程的详细内容，根据index.yaml中的details结构加载对应文件（磁盘模式）
-// course: 要加载内容的课程对象指针
-// coursePath: 课程目录的完整路径
-// 返回加载过程中遇到的错误
-func (s *Service) loadCourseContent(course *Course, coursePath string) error {
-	s.logger.Debug("Loading content for course: %s", course.ID)
+// loadCourseContent 加载课程的详细内容，根据index.yaml中的details结构从数据源加载对应文件。
+// 单个文件缺失/读取失败只记录警告并把对应Content置空，不影响课程其余部分的加载
+func (s *Service) loadCourseContent(src CourseSource, course *Course) error {
+	s.logger.Debug("Loading content for course: %s (source: %s)", course.ID, src.Name())
 
-	// 加载课程介绍内容
 	if course.Details.Intro.Text != "" {
-		introPath := filepath.Join(coursePath, course.Details.Intro.Text)
-		if content, err := s.loadMarkdownFile(introPath); err == nil {
-			course.Details.Intro.Content = content
+		if content, err := src.OpenMarkdown(course.ID, course.Details.Intro.Text); err == nil {
+			course.Details.Intro.Content = string(content)
 			s.logger.Debug("Loaded intro file %s for course: %s", course.Details.Intro.Text, course.ID)
 		} else {
 			s.logger.Warn("Failed to load intro file %s for course %s: %v", course.Details.Intro.Text, course.ID, err)
-			course.Details.Intro.Content = "" // 设置为空字符串
+			course.Details.Intro.Content = ""
 		}
 	}
 
-	// 加载课程步骤内容
 	for i := range course.Details.Steps {
 		step := &course.Details.Steps[i]
 		if step.Text != "" {
-			stepPath := filepath.Join(coursePath, step.Text)
-			if content, err := s.loadMarkdownFile(stepPath); err == nil {
-				step.Content = content
+			if content, err := src.OpenMarkdown(course.ID, step.Text); err == nil {
+				step.Content = string(content)
 				s.logger.Debug("Loaded step file %s for course: %s", step.Text, course.ID)
 			} else {
 				s.logger.Warn("Failed to load step file %s for course %s: %v", step.Text, course.ID, err)
-				step.Content = "" // 设置为空字符串
+				step.Content = ""
 			}
 		}
 	}
 
-	// 加载课程结束内容
 	if course.Details.Finish.Text != "" {
-		finishPath := filepath.Join(coursePath, course.Details.Finish.Text)
-		if content, err := s.loadMarkdownFile(finishPath); err == nil {
-			course.Details.Finish.Content = content
+		if content, err := src.OpenMarkdown(course.ID, course.Details.Finish.Text); err == nil {
+			course.Details.Finish.Content = string(content)
 			s.logger.Debug("Loaded finish file %s for course: %s", course.Details.Finish.Text, course.ID)
 		} else {
 			s.logger.Warn("Failed to load finish file %s for course %s: %v", course.Details.Finish.Text, course.ID, err)
-			course.Details.Finish.Content = "" // 设置为空字符串
+			course.Details.Finish.Content = ""
 		}
 	}
 
@@ -300,93 +468,55 @@ func (s *Service) loadCourseContent(course *Course, coursePath string) error {
 	return nil
 }
 
-// loadCourseContentFromFS 加载课程的详细内容（嵌入模式）
-// course: 要加载内容的课程对象指针
-// coursePath: 课程在FS中的目录路径（使用/分隔）
-// 返回加载过程中遇到的错误
-func (s *Service) loadCourseContentFromFS(course *Course, coursePath string) error {
-	s.logger.Debug("Loading content for course from FS: %s", course.ID)
-
-	// 加载课程介绍内容
-	if course.Details.Intro.Text != "" {
-		introPath := path.Join(coursePath, course.Details.Intro.Text)
-		if content, err := s.loadMarkdownFileFromFS(introPath); err == nil {
-			course.Details.Intro.Content = content
-			s.logger.Debug("Loaded intro file %s for course(FS): %s", course.Details.Intro.Text, course.ID)
-		} else {
-			s.logger.Warn("Failed to load intro file %s for course(FS) %s: %v", course.Details.Intro.Text, course.ID, err)
-			course.Details.Intro.Content = "" // 设置为空字符串
-		}
-	}
-
-	// 加载课程步骤内容
-	for i := range course.Details.Steps {
-		step := &course.Details.Steps[i]
-		if step.Text != "" {
-			stepPath := path.Join(coursePath, step.Text)
-			if content, err := s.loadMarkdownFileFromFS(stepPath); err == nil {
-				step.Content = content
-				s.logger.Debug("Loaded step file %s for course(FS): %s", step.Text, course.ID)
-			} else {
-				s.logger.Warn("Failed to load step file %s for course(FS) %s: %v", step.Text, course.ID, err)
-				step.Content = "" // 设置为空字符串
-			}
+// ReadCourseFile 读取指定课程下任意相对路径的原始文件内容（配置/Markdown之外的素材，
+// 如数据集打包文件），按数据源优先级依次尝试，返回第一个成功读取的结果
+func (s *Service) ReadCourseFile(courseID, relPath string) ([]byte, error) {
+	s.mu.RLock()
+	sources := append([]CourseSource(nil), s.sources...)
+	s.mu.RUnlock()
+
+	var lastErr error
+	for _, src := range sources {
+		data, err := src.OpenFile(courseID, relPath)
+		if err == nil {
+			return data, nil
 		}
+		lastErr = err
 	}
-
-	// 加载课程结束内容
-	if course.Details.Finish.Text != "" {
-		finishPath := path.Join(coursePath, course.Details.Finish.Text)
-		if content, err := s.loadMarkdownFileFromFS(finishPath); err == nil {
-			course.Details.Finish.Content = content
-			s.logger.Debug("Loaded finish file %s for course(FS): %s", course.Details.Finish.Text, course.ID)
-		} else {
-			s.logger.Warn("Failed to load finish file %s for course(FS) %s: %v", course.Details.Finish.Text, course.ID, err)
-			course.Details.Finish.Content = "" // 设置为空字符串
-		}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no course sources configured")
 	}
-
-	s.logger.Debug("Loaded content for course(FS): %s with %d steps", course.ID, len(course.Details.Steps))
-	return nil
+	return nil, lastErr
 }
 
-// loadMarkdownFile 读取并返回Markdown文件的内容（磁盘模式）
-// filePath: Markdown文件的完整路径
-// 返回文件内容字符串或错误信息
-func (s *Service) loadMarkdownFile(filePath string) (string, error) {
-	// 检查文件是否存在
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return "", fmt.Errorf("markdown file not found: %s", filePath)
-	}
-
-	// 读取文件内容
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read markdown file %s: %w", filePath, err)
-	}
+// ReadCourseFileWithETag 是 ReadCourseFile 的条件读取版本：按内容计算一个稳定的SHA-256 ETag
+// 并缓存结果，供HTTP层配合 If-None-Match 实现 304 校验——像 data/rdb.tar.gz 这类大体积
+// 素材不再需要在未变化时被重复传输。ifNoneMatch 命中缓存的ETag时，返回 notModified=true
+// 且 data 为 nil。缓存按 (courseID, relPath) 维度，随 Watch 热重载该课程时一并失效
+func (s *Service) ReadCourseFileWithETag(courseID, relPath, ifNoneMatch string) (data []byte, etag string, notModified bool, err error) {
+	key := courseID + "/" + relPath
 
-	// 检查文件是否为空
-	if len(content) == 0 {
-		return "", fmt.Errorf("markdown file is empty: %s", filePath)
-	}
+	s.fileMu.Lock()
+	entry, cached := s.fileCache[key]
+	s.fileMu.Unlock()
 
-	return string(content), nil
-}
+	if !cached {
+		data, err = s.ReadCourseFile(courseID, relPath)
+		if err != nil {
+			return nil, "", false, err
+		}
+		sum := sha256.Sum256(data)
+		entry = courseFileEntry{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`}
 
-// loadMarkdownFileFromFS 读取并返回Markdown文件的内容（嵌入模式）
-// filePath: Markdown文件在FS中的路径（使用/分隔）
-// 返回文件内容字符串或错误信息
-func (s *Service) loadMarkdownFileFromFS(filePath string) (string, error) {
-	content, err := fs.ReadFile(s.coursesFS, filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read markdown file from FS %s: %w", filePath, err)
+		s.fileMu.Lock()
+		s.fileCache[key] = entry
+		s.fileMu.Unlock()
 	}
 
-	if len(content) == 0 {
-		return "", fmt.Errorf("markdown file is empty: %s", filePath)
+	if ifNoneMatch != "" && ifNoneMatch == entry.etag {
+		return nil, entry.etag, true, nil
 	}
-
-	return string(content), nil
+	return entry.data, entry.etag, false, nil
 }
 
 // GetCourses 获取所有课程
@@ -425,85 +555,44 @@ func (s *Service) GetCourse(id string) (*Course, bool) {
 }
 
 // ExtractExecutableCommands 从课程内容中提取可执行命令
-// 扫描课程内容和所有步骤，提取Markdown代码块中的可执行命令
+// 用goldmark解析课程主要内容与所有步骤的Markdown，从围栏代码块中提取结构化的命令
+// （见 ExecutableCommand：语言、session、超时、预期输出等元数据均来自代码块的info string）
 // 参数:
 //
 //	course: 要提取命令的课程对象
 //
-// 返回: 命令标识符到命令内容的映射
-//   - content_N: 来自课程主要内容的第N个命令
-//   - step_<stepID>_N: 来自特定步骤的第N个命令
-func (s *Service) ExtractExecutableCommands(course *Course) map[string]string {
-	commands := make(map[string]string)
+// 返回: 按Markdown中出现顺序排列的命令列表，ID按来源分区前缀：
+//   - content_<lang>_N 或 content_<id>：来自课程主要内容（Intro）
+//   - step_<stepTitle>_<lang>_N 或 step_<stepTitle>_<id>：来自特定步骤
+//
+// 结果按课程ID缓存，Watch热重载该课程时会令缓存失效，避免每次调用都重新解析Markdown
+func (s *Service) ExtractExecutableCommands(course *Course) []ExecutableCommand {
+	s.cmdMu.Lock()
+	if cached, ok := s.cmdCache[course.ID]; ok {
+		s.cmdMu.Unlock()
+		return cached
+	}
+	s.cmdMu.Unlock()
+
+	var commands []ExecutableCommand
 
 	// 从课程内容中提取命令
-	contentCommands := extractCommandsFromText(course.Details.Intro.Content)
-	for i, cmd := range contentCommands {
-		key := fmt.Sprintf("content_%d", i+1)
-		commands[key] = cmd
+	for _, cmd := range parseExecutableCommands(course.Details.Intro.Content) {
+		cmd.ID = "content_" + cmd.ID
+		commands = append(commands, cmd)
 	}
 
 	// 从步骤中提取命令
 	for _, step := range course.Details.Steps {
-		stepCommands := extractCommandsFromText(step.Content)
-		for i, cmd := range stepCommands {
-			key := fmt.Sprintf("step_%s_%d", step.Title, i+1)
-			commands[key] = cmd
+		for _, cmd := range parseExecutableCommands(step.Content) {
+			cmd.ID = fmt.Sprintf("step_%s_%s", step.Title, cmd.ID)
+			commands = append(commands, cmd)
 		}
 	}
 
-	return commands
-}
-
-// extractCommandsFromText 从文本中提取命令
-// 解析Markdown格式的文本，提取代码块中以$开头的命令行
-// 支持多行命令的解析和合并
-// 参数:
-//
-//	text: 要解析的Markdown文本
-//
-// 返回: 提取到的命令列表
-func extractCommandsFromText(text string) []string {
-	var commands []string
-	lines := strings.Split(text, "\n")
-	inCodeBlock := false
-	currentCommand := ""
-
-	for _, line := range lines {
-		// 检查是否是代码块开始或结束
-		if strings.HasPrefix(line, "```") {
-			if inCodeBlock {
-				// 代码块结束
-				if currentCommand != "" {
-					commands = append(commands, strings.TrimSpace(currentCommand))
-					currentCommand = ""
-				}
-			}
-			inCodeBlock = !inCodeBlock
-			continue
-		}
-
-		// 如果在代码块中，检查是否是命令行
-		if inCodeBlock {
-			trimmedLine := strings.TrimSpace(line)
-			if strings.HasPrefix(trimmedLine, "$") {
-				// 如果已有命令，先保存
-				if currentCommand != "" {
-					commands = append(commands, strings.TrimSpace(currentCommand))
-				}
-				// 开始新命令（去掉$符号）
-				currentCommand = strings.TrimSpace(trimmedLine[1:])
-			} else if currentCommand != "" && trimmedLine != "" {
-				// 多行命令的续行
-				currentCommand += " " + trimmedLine
-			}
-		}
-	}
-
-	// 处理最后一个命令
-	if currentCommand != "" {
-		commands = append(commands, strings.TrimSpace(currentCommand))
-	}
+	s.cmdMu.Lock()
+	s.cmdCache[course.ID] = commands
+	s.cmdMu.Unlock()
 
 	return commands
 }
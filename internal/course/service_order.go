@@ -0,0 +1,79 @@
+package course
+
+import "fmt"
+
+// MainServiceName 课程主容器（由 Backend.ImageID/Cmd/... 描述）在依赖图中的名字
+const MainServiceName = "main"
+
+// OrderedServiceNames 对 Backend.Services 按 DependsOn 做拓扑排序，返回启动顺序（"main" 永远排第一）
+// 用于支持多容器课程（例如 KWDB 主容器 + 指标采集 sidecar）按依赖顺序启动、逆序销毁
+func (b *Backend) OrderedServiceNames() ([]string, error) {
+	if len(b.Services) == 0 {
+		return []string{MainServiceName}, nil
+	}
+
+	byName := make(map[string]ServiceSpec, len(b.Services))
+	for _, svc := range b.Services {
+		if svc.Name == "" {
+			return nil, fmt.Errorf("service has empty name")
+		}
+		if svc.Name == MainServiceName {
+			return nil, fmt.Errorf("service name %q is reserved for the primary container", MainServiceName)
+		}
+		if _, dup := byName[svc.Name]; dup {
+			return nil, fmt.Errorf("duplicate service name %q", svc.Name)
+		}
+		byName[svc.Name] = svc
+	}
+
+	const (
+		stateUnvisited = 0
+		stateVisiting  = 1
+		stateDone      = 2
+	)
+	state := make(map[string]int, len(byName)+1)
+	order := make([]string, 0, len(byName)+1)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		if name == MainServiceName {
+			if state[name] == stateDone {
+				return nil
+			}
+			state[name] = stateDone
+			order = append(order, name)
+			return nil
+		}
+		svc, ok := byName[name]
+		if !ok {
+			return fmt.Errorf("unknown service %q referenced in dependsOn", name)
+		}
+		switch state[name] {
+		case stateDone:
+			return nil
+		case stateVisiting:
+			return fmt.Errorf("circular dependsOn detected at service %q", name)
+		}
+		state[name] = stateVisiting
+		for _, dep := range svc.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = stateDone
+		order = append(order, name)
+		return nil
+	}
+
+	// 主容器没有依赖，最先访问，因此总是排在第一个启动
+	if err := visit(MainServiceName); err != nil {
+		return nil, err
+	}
+	for _, svc := range b.Services {
+		if err := visit(svc.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
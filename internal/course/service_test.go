@@ -108,3 +108,56 @@ func TestReadCourseFile_FSPriority(t *testing.T) {
 		t.Errorf("ReadCourseFile() = %q, want %q (should prefer FS over disk)", string(data), "from-embed")
 	}
 }
+
+func TestReadCourseFileWithETag(t *testing.T) {
+	memFS := fstest.MapFS{
+		"courses/smart-meter/data/rdb.tar.gz": {Data: []byte("fake-rdb-data")},
+	}
+	svc := NewServiceFromFS(memFS, "courses")
+
+	data, etag, notModified, err := svc.ReadCourseFileWithETag("smart-meter", "data/rdb.tar.gz", "")
+	if err != nil {
+		t.Fatalf("ReadCourseFileWithETag() error: %v", err)
+	}
+	if notModified {
+		t.Fatal("ReadCourseFileWithETag() notModified = true on first read, want false")
+	}
+	if string(data) != "fake-rdb-data" {
+		t.Errorf("ReadCourseFileWithETag() data = %q, want %q", string(data), "fake-rdb-data")
+	}
+	if etag == "" {
+		t.Fatal("ReadCourseFileWithETag() etag is empty")
+	}
+
+	t.Run("matching If-None-Match returns 304", func(t *testing.T) {
+		data, gotETag, notModified, err := svc.ReadCourseFileWithETag("smart-meter", "data/rdb.tar.gz", etag)
+		if err != nil {
+			t.Fatalf("ReadCourseFileWithETag() error: %v", err)
+		}
+		if !notModified {
+			t.Error("ReadCourseFileWithETag() notModified = false, want true")
+		}
+		if data != nil {
+			t.Errorf("ReadCourseFileWithETag() data = %v, want nil when notModified", data)
+		}
+		if gotETag != etag {
+			t.Errorf("ReadCourseFileWithETag() etag = %q, want %q", gotETag, etag)
+		}
+	})
+
+	t.Run("stale If-None-Match returns full content", func(t *testing.T) {
+		data, gotETag, notModified, err := svc.ReadCourseFileWithETag("smart-meter", "data/rdb.tar.gz", `"stale"`)
+		if err != nil {
+			t.Fatalf("ReadCourseFileWithETag() error: %v", err)
+		}
+		if notModified {
+			t.Error("ReadCourseFileWithETag() notModified = true, want false for stale ETag")
+		}
+		if string(data) != "fake-rdb-data" {
+			t.Errorf("ReadCourseFileWithETag() data = %q, want %q", string(data), "fake-rdb-data")
+		}
+		if gotETag != etag {
+			t.Errorf("ReadCourseFileWithETag() etag = %q, want %q", gotETag, etag)
+		}
+	})
+}
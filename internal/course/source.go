@@ -0,0 +1,226 @@
+package course
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"kwdb-playground/internal/logger"
+)
+
+// CourseSource 是课程内容的一个数据源：可以是本地磁盘目录、编译进二进制的 embed.FS，
+// 也可以是运营方独立发布的 HTTP(S) 压缩包或 Git 仓库（见 NewHTTPSource/NewGitSource）。
+// Service 按优先级持有多个 CourseSource 并在 LoadCourses 时合并它们的课程目录，
+// 使课程内容可以脱离二进制独立发布、甚至在运行时热替换，而不必重新编译/分发整个程序
+type CourseSource interface {
+	// Name 数据源名称，用于日志与多数据源合并时标识课程来自哪个源
+	Name() string
+	// ListCourses 列出该数据源当前拥有的课程ID（课程根目录下的直接子目录名）
+	ListCourses() ([]string, error)
+	// OpenConfig 读取指定课程的 index.yaml 原始内容，文件不存在或为空都视为错误
+	OpenConfig(courseID string) ([]byte, error)
+	// OpenMarkdown 读取指定课程下某个相对路径的Markdown文件内容，文件不存在或为空都视为错误
+	OpenMarkdown(courseID, relPath string) ([]byte, error)
+	// OpenFile 读取指定课程下某个相对路径的任意文件内容（配置与Markdown之外的素材，如数据集打包文件）
+	OpenFile(courseID, relPath string) ([]byte, error)
+	// Watch 返回一个在该数据源内容发生变化时收到通知的只读channel，元素是发生变化的课程ID；
+	// 变化无法定位到具体某个课程时（如远程整包更新）元素为空字符串，调用方应将其视为"重新加载全部课程"。
+	// ctx 取消后底层goroutine退出并关闭channel。不支持变化检测的数据源返回 nil channel
+	// （调用方应将其视为"永不主动通知"而非错误）
+	Watch(ctx context.Context) (<-chan string, error)
+}
+
+// fsSource 是基于 fs.FS 的 CourseSource 实现，本地磁盘目录（经 os.DirFS）与嵌入式 embed.FS
+// 在课程加载语义上完全一致，统一走这一个实现可以去掉原先磁盘/嵌入两套平行代码
+type fsSource struct {
+	name     string
+	fsys     fs.FS
+	base     string // 课程根目录在 fsys 中的路径，磁盘模式固定为 "."
+	watchDir string // 本地磁盘课程根目录的真实路径，供 Watch 使用fsnotify；embed.FS模式下为空，不支持监听
+	logger   *logger.Logger
+}
+
+// NewLocalSource 创建从本地磁盘目录读取课程的数据源
+func NewLocalSource(dir string) CourseSource {
+	return &fsSource{
+		name:     fmt.Sprintf("disk:%s", dir),
+		fsys:     os.DirFS(dir),
+		base:     ".",
+		watchDir: dir,
+		logger:   logger.NewLogger(logger.INFO),
+	}
+}
+
+// NewFSSource 创建从给定 fs.FS（通常为 embed.FS）读取课程的数据源
+// basePath 为课程根目录在 fsys 中的路径，例如 "courses"
+func NewFSSource(fsys fs.FS, basePath string) CourseSource {
+	if basePath == "" {
+		basePath = "."
+	}
+	return &fsSource{
+		name:   fmt.Sprintf("fs:%s", basePath),
+		fsys:   fsys,
+		base:   basePath,
+		logger: logger.NewLogger(logger.INFO),
+	}
+}
+
+func (s *fsSource) Name() string {
+	return s.name
+}
+
+func (s *fsSource) ListCourses() ([]string, error) {
+	entries, err := fs.ReadDir(s.fsys, s.base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read courses base path %s: %w", s.base, err)
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (s *fsSource) coursePath(courseID, relPath string) string {
+	return path.Join(s.base, courseID, relPath)
+}
+
+func (s *fsSource) OpenConfig(courseID string) ([]byte, error) {
+	p := s.coursePath(courseID, "index.yaml")
+	data, err := fs.ReadFile(s.fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read course config: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("course config file is empty: %s", p)
+	}
+	return data, nil
+}
+
+func (s *fsSource) OpenMarkdown(courseID, relPath string) ([]byte, error) {
+	p := s.coursePath(courseID, relPath)
+	data, err := fs.ReadFile(s.fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read markdown file %s: %w", p, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("markdown file is empty: %s", p)
+	}
+	return data, nil
+}
+
+func (s *fsSource) OpenFile(courseID, relPath string) ([]byte, error) {
+	p := s.coursePath(courseID, relPath)
+	data, err := fs.ReadFile(s.fsys, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read course file %s: %w", p, err)
+	}
+	return data, nil
+}
+
+// fsSourceDebounceWindow 合并fsnotify在短时间内对同一课程触发的多次事件（编辑器保存常常是
+// 先truncate再write，甚至rename+create），避免每次保存都触发一次完整的课程重载
+const fsSourceDebounceWindow = 200 * time.Millisecond
+
+// Watch 仅本地磁盘模式（watchDir非空）支持：用fsnotify递归监听课程根目录，
+// 按 fsSourceDebounceWindow 去抖后，将发生变化的课程ID发送到返回的channel。
+// embed.FS模式下没有真实路径可供监听，返回 nil channel
+func (s *fsSource) Watch(ctx context.Context) (<-chan string, error) {
+	if s.watchDir == "" {
+		return nil, nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fsnotify watcher for %s: %w", s.watchDir, err)
+	}
+	if err := addWatchDirsRecursive(watcher, s.watchDir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch course directory %s: %w", s.watchDir, err)
+	}
+
+	ch := make(chan string, 1)
+	go func() {
+		defer watcher.Close()
+		defer close(ch)
+
+		pending := make(map[string]*time.Timer)
+		fire := make(chan string, 16)
+		defer func() {
+			for _, t := range pending {
+				t.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				courseID := courseIDFromPath(s.watchDir, ev.Name)
+				if courseID == "" {
+					continue
+				}
+				if t, exists := pending[courseID]; exists {
+					t.Stop()
+				}
+				pending[courseID] = time.AfterFunc(fsSourceDebounceWindow, func() {
+					select {
+					case fire <- courseID:
+					default:
+					}
+				})
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.logger.Warn("监听课程目录 %s 出错: %v", s.watchDir, err)
+			case courseID := <-fire:
+				delete(pending, courseID)
+				select {
+				case ch <- courseID:
+				default:
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// addWatchDirsRecursive 递归地把root下的每一级目录加入watcher，fsnotify本身不支持递归监听
+func addWatchDirsRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := watcher.Add(p); err != nil {
+				return fmt.Errorf("failed to watch directory %s: %w", p, err)
+			}
+		}
+		return nil
+	})
+}
+
+// courseIDFromPath 把fsnotify事件路径映射到课程ID（课程根目录下的第一级子目录名），
+// 对不在root下或直接就是root本身的路径返回空字符串
+func courseIDFromPath(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	courseID, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	return courseID
+}
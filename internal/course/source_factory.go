@@ -0,0 +1,41 @@
+package course
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"kwdb-playground/internal/config"
+)
+
+// NewSourceFromConfig 根据 config.CourseSourceConfig 构造对应的 CourseSource，
+// 供 main.go/cmd/server 在启动时把 cfg.Course.ExtraSources 转换为 AddSource 调用。
+// defaultCacheRoot 在 cfg 未显式指定 CacheDir 时用于派生缓存目录（通常是 cfg.DataDir 下的子目录）
+func NewSourceFromConfig(cfg config.CourseSourceConfig, defaultCacheRoot string) (CourseSource, error) {
+	cacheDir := cfg.CacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(defaultCacheRoot, "course-sources", sourceCacheKey(cfg.Type, cfg.URL))
+	}
+
+	switch cfg.Type {
+	case "http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("http course source requires a url")
+		}
+		return NewHTTPSource(cfg.URL, cacheDir), nil
+	case "git":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("git course source requires a url")
+		}
+		return NewGitSource(cfg.URL, cfg.Ref, cacheDir), nil
+	default:
+		return nil, fmt.Errorf("unknown course source type %q (expected \"http\" or \"git\")", cfg.Type)
+	}
+}
+
+// sourceCacheKey 把数据源类型与地址折算为一个适合做目录名的短哈希，避免URL中的特殊字符污染路径
+func sourceCacheKey(sourceType, url string) string {
+	sum := sha1.Sum([]byte(sourceType + "|" + url))
+	return hex.EncodeToString(sum[:])
+}
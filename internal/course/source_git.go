@@ -0,0 +1,198 @@
+package course
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"kwdb-playground/internal/logger"
+)
+
+// gitSourceRefreshInterval 是 Watch() 为 Git 数据源启动的后台轮询周期
+const gitSourceRefreshInterval = 5 * time.Minute
+
+// gitSource 是从 Git 仓库（clone + pull）拉取内容的 CourseSource，仓库本身即课程根目录，
+// 组织方式与本地磁盘目录模式（NewLocalSource）相同：repoDir/<courseID>/index.yaml
+type gitSource struct {
+	name     string
+	repoURL  string
+	ref      string // 分支/标签名，留空则使用仓库默认分支
+	cacheDir string // 本地克隆目录
+	logger   *logger.Logger
+
+	mu    sync.Mutex
+	inner CourseSource // 指向 cacheDir 的 fsSource，首次 Refresh 成功后才非nil
+}
+
+// NewGitSource 创建一个从 repoURL 克隆/拉取课程内容的数据源，克隆内容保存在 cacheDir。
+// ref 为空时跟随仓库默认分支
+func NewGitSource(repoURL, ref, cacheDir string) CourseSource {
+	return &gitSource{
+		name:     fmt.Sprintf("git:%s@%s", repoURL, orDefault(ref, "HEAD")),
+		repoURL:  repoURL,
+		ref:      ref,
+		cacheDir: cacheDir,
+		logger:   logger.NewLogger(logger.INFO),
+	}
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func (s *gitSource) Name() string {
+	return s.name
+}
+
+func (s *gitSource) ensureReady() (CourseSource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inner != nil {
+		return s.inner, nil
+	}
+	if _, err := s.refreshLocked(); err != nil {
+		return nil, err
+	}
+	return s.inner, nil
+}
+
+func (s *gitSource) ListCourses() ([]string, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.ListCourses()
+}
+
+func (s *gitSource) OpenConfig(courseID string) ([]byte, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.OpenConfig(courseID)
+}
+
+func (s *gitSource) OpenMarkdown(courseID, relPath string) ([]byte, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.OpenMarkdown(courseID, relPath)
+}
+
+func (s *gitSource) OpenFile(courseID, relPath string) ([]byte, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.OpenFile(courseID, relPath)
+}
+
+// Watch 启动一个后台goroutine，按 gitSourceRefreshInterval 周期性 pull，commit hash 变化时
+// 向返回的channel发送一个空字符串（整个仓库一次性更新，无法定位到具体课程，由调用方重新加载全部课程）。
+// ctx 取消后goroutine退出并关闭channel
+func (s *gitSource) Watch(ctx context.Context) (<-chan string, error) {
+	ch := make(chan string, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(gitSourceRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				changed, err := s.refreshLocked()
+				s.mu.Unlock()
+				if err != nil {
+					s.logger.Warn("刷新 Git 课程源 %s 失败: %v", s.repoURL, err)
+					continue
+				}
+				if changed {
+					select {
+					case ch <- "":
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// refreshLocked 首次调用时 clone 仓库，此后调用 pull 增量更新，必须持有 s.mu。
+// 返回 HEAD 的 commit hash 相对上次调用是否发生了变化
+func (s *gitSource) refreshLocked() (bool, error) {
+	repo, err := git.PlainOpen(s.cacheDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return s.cloneLocked()
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open git course cache %s: %w", s.cacheDir, err)
+	}
+
+	beforeHash, err := headHash(repo)
+	if err != nil {
+		return false, err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree for %s: %w", s.repoURL, err)
+	}
+	pullOpts := &git.PullOptions{RemoteName: "origin"}
+	if s.ref != "" {
+		pullOpts.ReferenceName = plumbing.NewBranchReferenceName(s.ref)
+	}
+	if err := worktree.Pull(pullOpts); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return false, fmt.Errorf("failed to pull git course source %s: %w", s.repoURL, err)
+	}
+
+	afterHash, err := headHash(repo)
+	if err != nil {
+		return false, err
+	}
+
+	s.inner = NewLocalSource(s.cacheDir)
+	changed := beforeHash != afterHash
+	if changed {
+		s.logger.Info("课程源 %s 已更新: %s -> %s", s.repoURL, beforeHash, afterHash)
+	}
+	return changed, nil
+}
+
+func (s *gitSource) cloneLocked() (bool, error) {
+	cloneOpts := &git.CloneOptions{URL: s.repoURL}
+	if s.ref != "" {
+		cloneOpts.ReferenceName = plumbing.NewBranchReferenceName(s.ref)
+	}
+	repo, err := git.PlainClone(s.cacheDir, false, cloneOpts)
+	if err != nil {
+		return false, fmt.Errorf("failed to clone git course source %s: %w", s.repoURL, err)
+	}
+	hash, err := headHash(repo)
+	if err != nil {
+		return false, err
+	}
+	s.inner = NewLocalSource(s.cacheDir)
+	s.logger.Info("课程源 %s 已克隆至 %s，HEAD=%s", s.repoURL, s.cacheDir, hash)
+	return true, nil
+}
+
+func headHash(repo *git.Repository) (string, error) {
+	head, err := repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return head.Hash().String(), nil
+}
@@ -0,0 +1,324 @@
+package course
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+)
+
+// httpSourceRefreshInterval 是 Watch() 为 HTTP 数据源启动的后台轮询周期
+const httpSourceRefreshInterval = 5 * time.Minute
+
+// httpSourceMetaFile 记录上次成功拉取的 ETag，置于 cacheDir 下；启动时不会读回该文件，
+// 因此重启后的第一次拉取不带 If-None-Match，总是完整下载
+const httpSourceMetaFile = ".source-meta.json"
+
+type httpSourceMeta struct {
+	ETag string `json:"etag"`
+}
+
+// httpSource 是从 HTTP(S) 上的 tar.gz/zip 课程包拉取内容的 CourseSource。
+// 首次访问时惰性拉取一次，此后仅由 Watch() 启动的后台轮询按 ETag 做条件请求revalidate，
+// 未变化时直接复用本地缓存，避免每次课程加载都重新下载整包
+type httpSource struct {
+	name     string
+	url      string
+	cacheDir string
+	client   *http.Client
+	logger   *logger.Logger
+
+	mu    sync.Mutex
+	etag  string
+	inner CourseSource // 指向 cacheDir 中已解压内容的 fsSource，首次 Refresh 成功后才非nil
+}
+
+// NewHTTPSource 创建一个从 url 拉取 tar.gz/zip 课程包的数据源，解压后的内容缓存在 cacheDir
+// （按 url 的内容变化以 ETag 增量更新，不是每次都重新下载）
+func NewHTTPSource(url, cacheDir string) CourseSource {
+	return &httpSource{
+		name:     fmt.Sprintf("http:%s", url),
+		url:      url,
+		cacheDir: cacheDir,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		logger:   logger.NewLogger(logger.INFO),
+	}
+}
+
+func (s *httpSource) Name() string {
+	return s.name
+}
+
+// ensureReady 保证 inner 已经指向一份可用的本地缓存内容，仅在从未拉取成功过时才触发网络请求；
+// 此后的revalidate 由 Watch() 的后台轮询负责，读路径本身不隐式触发网络IO
+func (s *httpSource) ensureReady() (CourseSource, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inner != nil {
+		return s.inner, nil
+	}
+	if _, err := s.refreshLocked(); err != nil {
+		return nil, err
+	}
+	return s.inner, nil
+}
+
+func (s *httpSource) ListCourses() ([]string, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.ListCourses()
+}
+
+func (s *httpSource) OpenConfig(courseID string) ([]byte, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.OpenConfig(courseID)
+}
+
+func (s *httpSource) OpenMarkdown(courseID, relPath string) ([]byte, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.OpenMarkdown(courseID, relPath)
+}
+
+func (s *httpSource) OpenFile(courseID, relPath string) ([]byte, error) {
+	inner, err := s.ensureReady()
+	if err != nil {
+		return nil, err
+	}
+	return inner.OpenFile(courseID, relPath)
+}
+
+// Watch 启动一个后台goroutine，按 httpSourceRefreshInterval 周期性拉取并 revalidate 远端课程包，
+// 内容实际发生变化时向返回的channel发送一个空字符串（整包更新，无法定位到具体课程，由调用方
+// 重新加载全部课程）。ctx 取消后goroutine退出并关闭channel
+func (s *httpSource) Watch(ctx context.Context) (<-chan string, error) {
+	ch := make(chan string, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(httpSourceRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				changed, err := s.refreshLocked()
+				s.mu.Unlock()
+				if err != nil {
+					s.logger.Warn("刷新 HTTP 课程源 %s 失败: %v", s.url, err)
+					continue
+				}
+				if changed {
+					select {
+					case ch <- "":
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// refreshLocked 带条件请求地拉取 s.url，必须持有 s.mu。返回内容是否相对上次发生了变化
+func (s *httpSource) refreshLocked() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to build request for course source %s: %w", s.url, err)
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch course source %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		s.logger.Debug("课程源 %s 未变化（ETag命中），复用本地缓存", s.url)
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("course source %s returned status %d", s.url, resp.StatusCode)
+	}
+
+	extractDir := filepath.Join(s.cacheDir, "content")
+	stagingDir, err := os.MkdirTemp(s.cacheDir, "staging-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create staging dir under %s: %w", s.cacheDir, err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	if err := extractArchive(resp.Body, s.url, stagingDir); err != nil {
+		return false, fmt.Errorf("failed to extract course source %s: %w", s.url, err)
+	}
+
+	if err := os.RemoveAll(extractDir); err != nil {
+		return false, fmt.Errorf("failed to clear previous content dir %s: %w", extractDir, err)
+	}
+	if err := os.Rename(stagingDir, extractDir); err != nil {
+		return false, fmt.Errorf("failed to publish extracted course content: %w", err)
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	if err := s.writeMeta(); err != nil {
+		s.logger.Warn("写入课程源 %s 的缓存元数据失败: %v", s.url, err)
+	}
+	s.inner = NewLocalSource(extractDir)
+	s.logger.Info("课程源 %s 已刷新，内容缓存于 %s", s.url, extractDir)
+	return true, nil
+}
+
+func (s *httpSource) writeMeta() error {
+	data, err := json.Marshal(httpSourceMeta{ETag: s.etag})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.cacheDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.cacheDir, httpSourceMetaFile), data, 0644)
+}
+
+// extractArchive 将 body 按 url 后缀识别的归档格式（.tar.gz/.tgz 或 .zip）解压到 destDir
+func extractArchive(body io.Reader, url, destDir string) error {
+	switch {
+	case strings.HasSuffix(url, ".tar.gz"), strings.HasSuffix(url, ".tgz"):
+		return extractTarGz(body, destDir)
+	case strings.HasSuffix(url, ".zip"):
+		return extractZip(body, destDir)
+	default:
+		return fmt.Errorf("unsupported course archive format for %s (expected .tar.gz/.tgz/.zip)", url)
+	}
+}
+
+func extractTarGz(body io.Reader, destDir string) error {
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+
+		target, err := safeJoin(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return fmt.Errorf("failed to write %s: %w", target, err)
+			}
+			out.Close()
+		}
+	}
+}
+
+func extractZip(body io.Reader, destDir string) error {
+	// zip.Reader 需要 io.ReaderAt，先落盘为临时文件再打开
+	tmpFile, err := os.CreateTemp(destDir, "download-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if _, err := io.Copy(tmpFile, body); err != nil {
+		return fmt.Errorf("failed to buffer zip download: %w", err)
+	}
+
+	info, err := tmpFile.Stat()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(tmpFile, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+
+	for _, f := range zr.File {
+		target, err := safeJoin(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			rc.Close()
+			return err
+		}
+		if _, err := io.Copy(out, rc); err != nil {
+			out.Close()
+			rc.Close()
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		out.Close()
+		rc.Close()
+	}
+	return nil
+}
+
+// safeJoin 将归档条目名拼接到 destDir 下，并拒绝任何试图逃逸出 destDir 的路径（zip slip 防护）
+func safeJoin(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+	if target != destDir && !strings.HasPrefix(target, destDir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q escapes destination directory", name)
+	}
+	return target, nil
+}
@@ -5,8 +5,10 @@ import (
 	"io"
 
 	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
 	"github.com/moby/moby/api/types/image"
 	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/api/types/registry"
 	"github.com/moby/moby/client"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
@@ -97,6 +99,31 @@ func (d *dockerClientAdapter) ImagePull(ctx context.Context, refStr string, opti
 	return d.client.ImagePull(ctx, refStr, options)
 }
 
+// ImageLoad 从 tar 归档导入镜像，用于离线兜底场景
+func (d *dockerClientAdapter) ImageLoad(ctx context.Context, input io.Reader, options client.ImageLoadOptions) (image.LoadResponse, error) {
+	return d.client.ImageLoad(ctx, input, options)
+}
+
+// ContainerCommit 将容器当前文件系统提交为一个新镜像，用于课程快照
+func (d *dockerClientAdapter) ContainerCommit(ctx context.Context, containerID string, options client.ContainerCommitOptions) (container.CommitResponse, error) {
+	return d.client.ContainerCommit(ctx, containerID, options)
+}
+
+// ImageRemove 删除本地镜像，供 ImageManager 在引用计数归零且TTL到期后清理空间
+func (d *dockerClientAdapter) ImageRemove(ctx context.Context, imageID string, options client.ImageRemoveOptions) ([]image.DeleteResponse, error) {
+	return d.client.ImageRemove(ctx, imageID, options)
+}
+
+// ContainerStats 订阅容器的cgroup资源用量流，供 ResourceGovernor 按课程聚合统计
+func (d *dockerClientAdapter) ContainerStats(ctx context.Context, containerID string, options client.ContainerStatsOptions) (io.ReadCloser, error) {
+	return d.client.ContainerStats(ctx, containerID, options)
+}
+
+// RegistryLogin 向daemon请求校验一组registry凭据
+func (d *dockerClientAdapter) RegistryLogin(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error) {
+	return d.client.RegistryLogin(ctx, auth)
+}
+
 // ImageInspectWithRaw 检查镜像详细信息
 func (d *dockerClientAdapter) ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
 	// 使用ImageInspect方法，在新版本API中返回被包装在结构体中
@@ -144,6 +171,16 @@ func (d *dockerClientAdapter) Ping(ctx context.Context) (client.PingResult, erro
 	return d.client.Ping(ctx, client.PingOptions{})
 }
 
+// CopyToContainer 把tar归档内容写入容器文件系统
+func (d *dockerClientAdapter) CopyToContainer(ctx context.Context, containerID string, options client.CopyToContainerOptions) (client.CopyToContainerResult, error) {
+	return d.client.CopyToContainer(ctx, containerID, options)
+}
+
+// Events 订阅Docker事件流
+func (d *dockerClientAdapter) Events(ctx context.Context, options client.EventsListOptions) (<-chan events.Message, <-chan error) {
+	return d.client.Events(ctx, options)
+}
+
 // Close 关闭客户端连接
 func (d *dockerClientAdapter) Close() error {
 	return d.client.Close()
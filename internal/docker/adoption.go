@@ -0,0 +1,128 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/client"
+
+	"kwdb-playground/internal/logger"
+)
+
+// defaultAdoptionRelPath 是 adoption sidecar 文件相对用户主目录的默认路径
+// Docker 不支持给已创建的容器追加/修改 Labels（只能在创建时指定），因此对没有 LabelAppName
+// 标签的历史容器，AdoptLegacy 把解析出的课程ID写入这个sidecar文件，而不是尝试重建容器
+const defaultAdoptionRelPath = ".kwdb-playground/adopted-containers.json"
+
+// adoptionRecord 记录一个历史容器的迁移结果，键为 Docker容器ID
+type adoptionRecord struct {
+	CourseID  string `json:"courseId"`
+	AdoptedAt string `json:"adoptedAt"`
+}
+
+var adoptionMu sync.Mutex
+
+func defaultAdoptionPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaultAdoptionRelPath
+	}
+	return filepath.Join(home, defaultAdoptionRelPath)
+}
+
+// loadAdoptionSidecar 读取 adoption sidecar 文件，返回 Docker容器ID -> 课程ID 的映射
+// 文件不存在或内容损坏时静默退化为空映射，不影响容器加载主流程
+func loadAdoptionSidecar(log *logger.Logger) map[string]string {
+	adoptionMu.Lock()
+	defer adoptionMu.Unlock()
+
+	records, err := readAdoptionRecords()
+	if err != nil && log != nil {
+		log.Warn("读取 adoption sidecar 文件失败，按空记录处理: %v", err)
+	}
+	courseByID := make(map[string]string, len(records))
+	for dockerID, rec := range records {
+		courseByID[dockerID] = rec.CourseID
+	}
+	return courseByID
+}
+
+func readAdoptionRecords() (map[string]adoptionRecord, error) {
+	data, err := os.ReadFile(defaultAdoptionPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]adoptionRecord{}, nil
+		}
+		return map[string]adoptionRecord{}, err
+	}
+	records := make(map[string]adoptionRecord)
+	if err := json.Unmarshal(data, &records); err != nil {
+		return map[string]adoptionRecord{}, fmt.Errorf("解析 adoption sidecar 文件失败: %w", err)
+	}
+	return records, nil
+}
+
+func writeAdoptionRecords(records map[string]adoptionRecord) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := defaultAdoptionPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建 adoption sidecar 目录失败: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// AdoptLegacy 扫描没有 LabelAppName 标签、但名称符合 kwdb-playground-{courseID}-{timestamp} 约定的
+// 历史容器，把通过 legacyParseCourseIDFromContainerName 解析出的课程ID写入 adoption sidecar 文件，
+// 使后续 loadExistingContainers 无需再对这些容器重复做名称解析。返回本次新迁移的容器数量
+func (d *dockerController) AdoptLegacy(ctx context.Context) (int, error) {
+	all, err := d.client.ContainerList(ctx, client.ContainerListOptions{All: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	adoptionMu.Lock()
+	defer adoptionMu.Unlock()
+
+	records, err := readAdoptionRecords()
+	if err != nil {
+		d.logger.Warn("读取现有 adoption sidecar 记录失败，将重新生成: %v", err)
+		records = make(map[string]adoptionRecord)
+	}
+
+	adoptedCount := 0
+	for _, c := range all {
+		if c.Labels[LabelAppName] == appNameValue {
+			continue // 已有标签，无需迁移
+		}
+		if _, already := records[c.ID]; already {
+			continue
+		}
+
+		containerName := strings.TrimPrefix(firstContainerName(c.Names), "/")
+		courseID, valid := legacyParseCourseIDFromContainerName(containerName)
+		if !valid {
+			continue
+		}
+
+		records[c.ID] = adoptionRecord{CourseID: courseID, AdoptedAt: time.Now().UTC().Format(time.RFC3339)}
+		adoptedCount++
+		d.logger.Info("已迁移历史容器: %s (课程: %s)", containerName, courseID)
+	}
+
+	if adoptedCount == 0 {
+		return 0, nil
+	}
+	if err := writeAdoptionRecords(records); err != nil {
+		return 0, fmt.Errorf("写入 adoption sidecar 文件失败: %w", err)
+	}
+	return adoptedCount, nil
+}
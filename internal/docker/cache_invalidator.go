@@ -0,0 +1,269 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/api/types/filters"
+	"github.com/moby/moby/client"
+)
+
+// cacheInvalidatorFallbackAfterFailures 连续这么多次订阅/重连失败后，在等待下一次重连期间
+// 额外跑一轮轮询兜底，避免 containerCache 在事件流长时间中断期间停留在过期值上
+const cacheInvalidatorFallbackAfterFailures = 3
+
+// StateChange 是一次容器状态迁移的通知，由 cacheInvalidator 在消费事件流时产生，
+// 供 SubscribeStateChanges 的订阅者（WebSocket终端、前端）据此推送更新而不必轮询
+type StateChange struct {
+	ContainerID string         `json:"containerId"` // ContainerInfo.ID（容器名称），非Docker长ID
+	CourseID    string         `json:"courseId"`
+	State       ContainerState `json:"state"`
+	Message     string         `json:"message,omitempty"`
+	At          time.Time      `json:"at"`
+}
+
+// cacheInvalidator 订阅Docker事件流，让 containerCache 与 d.containers[*].State 对
+// start/die/kill/stop/destroy/oom/health_status 等容器生命周期事件立即失效/刷新，不再依赖固定
+// TTL被动过期——使缓存从"尽力而为"变为"权威"。事件流断开时按 restartBackoff 同量级的指数退避
+// 重连；连续重连失败时退化为定期轮询已知容器的真实状态作为兜底
+type cacheInvalidator struct {
+	client     DockerClientInterface
+	controller *dockerController
+	cache      *containerCache
+	logger     *logger.Logger
+
+	mu          sync.Mutex
+	subscribers map[string][]chan StateChange // key: courseID
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newCacheInvalidator 创建缓存失效器，不会自动启动后台goroutine，调用方需显式调用 Start
+func newCacheInvalidator(controller *dockerController, cli DockerClientInterface, cache *containerCache, log *logger.Logger) *cacheInvalidator {
+	return &cacheInvalidator{
+		client:      cli,
+		controller:  controller,
+		cache:       cache,
+		subscribers: make(map[string][]chan StateChange),
+		logger:      log,
+	}
+}
+
+// Start 订阅Docker容器事件流并开始让containerCache随事件即时失效/刷新；重复调用是安全的，
+// 第二次调用会先停止上一轮订阅
+func (ci *cacheInvalidator) Start(ctx context.Context) {
+	ci.Stop()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	ci.cancel = cancel
+
+	ci.wg.Add(1)
+	go ci.run(runCtx)
+}
+
+// Stop 停止订阅Docker事件流并等待后台goroutine退出
+func (ci *cacheInvalidator) Stop() {
+	if ci.cancel == nil {
+		return
+	}
+	ci.cancel()
+	ci.cancel = nil
+	ci.wg.Wait()
+}
+
+// run 持续订阅事件流；一轮订阅中断后按失败次数退避重连，连续失败达到阈值时在等待期间
+// 额外轮询一次已知容器的真实状态，防止缓存在事件流恢复前一直停留在旧值上
+func (ci *cacheInvalidator) run(ctx context.Context) {
+	defer ci.wg.Done()
+
+	failures := 0
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		msgCh, errCh := ci.client.Events(ctx, client.EventsListOptions{
+			Filters: filters.NewArgs(
+				filters.Arg("type", "container"),
+				filters.Arg("label", LabelAppName+"="+appNameValue),
+			),
+		})
+
+		if ci.watch(ctx, msgCh, errCh) {
+			failures = 0
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		failures++
+		if failures >= cacheInvalidatorFallbackAfterFailures {
+			ci.logger.Warn("容器事件流连续%d次连接失败，轮询兜底刷新容器状态缓存", failures)
+			ci.pollOnce(ctx)
+		}
+
+		delay := restartBackoff(failures - 1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// watch 消费一轮事件流直到errCh关闭或ctx取消；收到过至少一条消息即视为本轮连接成功（返回true），
+// 调用方据此决定是否重置重连失败计数
+func (ci *cacheInvalidator) watch(ctx context.Context, msgCh <-chan events.Message, errCh <-chan error) bool {
+	connected := false
+	for {
+		select {
+		case <-ctx.Done():
+			return connected
+		case err, ok := <-errCh:
+			if !ok {
+				return connected
+			}
+			if err != nil && err != io.EOF {
+				ci.logger.Warn("容器事件流中断，准备重连: %v", err)
+			}
+			return connected
+		case msg, ok := <-msgCh:
+			if !ok {
+				return connected
+			}
+			connected = true
+			ci.handleEvent(msg)
+		}
+	}
+}
+
+// handleEvent 把容器生命周期事件翻译为一次缓存更新，并同步推进 d.containers[*].State；
+// msg.Actor.ID就是 containerCache 的key（与 d.cache.set/get/delete在controller.go里统一
+// 使用的DockerID一致）
+func (ci *cacheInvalidator) handleEvent(msg events.Message) {
+	containerID := msg.Actor.ID
+	if containerID == "" {
+		return
+	}
+
+	switch msg.Action {
+	case "start":
+		ci.cache.set(containerID, true)
+		ci.updateState(containerID, StateRunning, "")
+	case "die", "kill", "stop", "destroy", "oom":
+		ci.cache.delete(containerID)
+		state := StateExited
+		if msg.Action == "oom" {
+			state = StateError
+		}
+		ci.updateState(containerID, state, string(msg.Action))
+	default:
+		// health_status事件的Action形如"health_status: healthy"，能收到就说明容器仍在运行，
+		// 顺带刷新一下缓存的新鲜度
+		if strings.HasPrefix(string(msg.Action), "health_status") {
+			ci.cache.set(containerID, true)
+		}
+	}
+}
+
+// updateState 把一次事件翻译为容器状态更新：复用 controller.updateContainerState 保证与
+// containerCache同步，再向该容器所属课程的订阅者广播一次StateChange；找不到对应的内部容器名称
+// （多半是非本应用创建的容器，或已被其他路径移除）时直接跳过
+func (ci *cacheInvalidator) updateState(dockerID string, state ContainerState, message string) {
+	if ci.controller == nil {
+		return
+	}
+	containerName, courseID, ok := ci.lookupByDockerID(dockerID)
+	if !ok {
+		return
+	}
+	ci.controller.updateContainerState(containerName, state, message)
+	ci.emit(courseID, containerName, state, message)
+}
+
+// lookupByDockerID 按DockerID反查容器在 d.containers 中的内部名称与所属课程ID
+func (ci *cacheInvalidator) lookupByDockerID(dockerID string) (containerName, courseID string, ok bool) {
+	ci.controller.mu.RLock()
+	defer ci.controller.mu.RUnlock()
+	for id, info := range ci.controller.containers {
+		if info.DockerID == dockerID {
+			return id, info.CourseID, true
+		}
+	}
+	return "", "", false
+}
+
+// Subscribe 注册一个课程的容器状态变化订阅，供WebSocket终端/前端用来推送更新而不必轮询；
+// 返回的cancel函数必须在调用方结束监听（如客户端断开）时调用，否则channel会一直占用在subscribers中
+func (ci *cacheInvalidator) Subscribe(courseID string) (<-chan StateChange, func()) {
+	ch := make(chan StateChange, 8)
+	ci.mu.Lock()
+	ci.subscribers[courseID] = append(ci.subscribers[courseID], ch)
+	ci.mu.Unlock()
+
+	cancel := func() {
+		ci.mu.Lock()
+		defer ci.mu.Unlock()
+		subs := ci.subscribers[courseID]
+		for i, c := range subs {
+			if c == ch {
+				ci.subscribers[courseID] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// emit 把一次状态变化广播给该courseID的订阅者；订阅者channel已满时直接丢弃该条通知（只是展示性
+// 提示，允许偶发丢失）而不是阻塞事件流消费
+func (ci *cacheInvalidator) emit(courseID, containerName string, state ContainerState, message string) {
+	ev := StateChange{
+		ContainerID: containerName,
+		CourseID:    courseID,
+		State:       state,
+		Message:     message,
+		At:          time.Now(),
+	}
+
+	ci.mu.Lock()
+	subs := append([]chan StateChange(nil), ci.subscribers[courseID]...)
+	ci.mu.Unlock()
+	for _, ch := range subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// pollOnce 轮询所有已知容器的真实运行状态并刷新缓存，是事件流连续失败时的兜底手段
+func (ci *cacheInvalidator) pollOnce(ctx context.Context) {
+	ci.controller.mu.RLock()
+	dockerIDs := make([]string, 0, len(ci.controller.containers))
+	for _, info := range ci.controller.containers {
+		dockerIDs = append(dockerIDs, info.DockerID)
+	}
+	ci.controller.mu.RUnlock()
+
+	pollCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	for _, dockerID := range dockerIDs {
+		inspect, err := ci.client.ContainerInspect(pollCtx, dockerID)
+		if err != nil {
+			continue
+		}
+		ci.cache.set(dockerID, inspect.State.Running)
+	}
+}
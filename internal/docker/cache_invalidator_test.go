@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"kwdb-playground/internal/logger"
+
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/client"
+)
+
+// TestCacheInvalidator_HandleEvent 验证各生命周期事件对containerCache的即时影响
+func TestCacheInvalidator_HandleEvent(t *testing.T) {
+	cache := newContainerCache(time.Hour)
+	ci := newCacheInvalidator(nil, nil, cache, logger.NewLogger(logger.ERROR))
+
+	ci.handleEvent(events.Message{Action: "start", Actor: events.Actor{ID: "abc"}})
+	if running, exists := cache.get("abc"); !exists || !running {
+		t.Fatalf("start事件后应缓存为运行中，got exists=%v running=%v", exists, running)
+	}
+
+	for _, action := range []events.Action{"die", "kill", "stop", "destroy", "oom"} {
+		cache.set("abc", true)
+		ci.handleEvent(events.Message{Action: action, Actor: events.Actor{ID: "abc"}})
+		if _, exists := cache.get("abc"); exists {
+			t.Fatalf("%s事件后缓存应被清除", action)
+		}
+	}
+
+	ci.handleEvent(events.Message{Action: "health_status: healthy", Actor: events.Actor{ID: "abc"}})
+	if running, exists := cache.get("abc"); !exists || !running {
+		t.Fatalf("health_status事件后应刷新缓存为运行中，got exists=%v running=%v", exists, running)
+	}
+
+	// 没有容器ID的事件应被忽略，不panic
+	ci.handleEvent(events.Message{Action: "start", Actor: events.Actor{}})
+}
+
+// eventsStepClient 是一个只实现Events的假客户端，每次调用从预先排好的步骤队列里取一步返回，
+// 用于驱动cacheInvalidator在"流中断→重连"之间的状态机
+type eventsStepClient struct {
+	fakeDockerClient
+	steps []func() (<-chan events.Message, <-chan error)
+	calls int
+}
+
+func (c *eventsStepClient) Events(ctx context.Context, options client.EventsListOptions) (<-chan events.Message, <-chan error) {
+	step := c.steps[c.calls]
+	if c.calls < len(c.steps)-1 {
+		c.calls++
+	}
+	return step()
+}
+
+// closedChannels 返回一对立即关闭的channel，模拟连接后马上掉线（重连失败）
+func closedChannels() (<-chan events.Message, <-chan error) {
+	msgCh := make(chan events.Message)
+	errCh := make(chan error)
+	close(msgCh)
+	close(errCh)
+	return msgCh, errCh
+}
+
+// TestCacheInvalidator_ReconnectsAfterStreamCloses 验证事件流关闭后失效器会重新订阅，
+// 并在重新收到的事件里继续刷新缓存
+func TestCacheInvalidator_ReconnectsAfterStreamCloses(t *testing.T) {
+	delivered := make(chan struct{})
+	fc := &eventsStepClient{
+		steps: []func() (<-chan events.Message, <-chan error){
+			closedChannels, // 第一次订阅：流已断开，触发重连
+			func() (<-chan events.Message, <-chan error) { // 第二次订阅：送达一条事件后保持挂起
+				msgCh := make(chan events.Message, 1)
+				errCh := make(chan error)
+				msgCh <- events.Message{Action: "start", Actor: events.Actor{ID: "xyz"}}
+				go func() { close(delivered) }()
+				return msgCh, errCh
+			},
+		},
+	}
+
+	cache := newContainerCache(time.Hour)
+	ci := newCacheInvalidator(nil, fc, cache, logger.NewLogger(logger.ERROR))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ci.Start(ctx)
+	defer func() {
+		cancel()
+		ci.Stop()
+	}()
+
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("事件流断开后应很快重连并再次收到事件")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if running, exists := cache.get("xyz"); exists && running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("重连后的事件应当刷新缓存")
+}
@@ -0,0 +1,674 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/containerd/containerd"
+	eventtypes "github.com/containerd/containerd/api/events"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+	"github.com/containerd/typeurl/v2"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/api/types/image"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/api/types/registry"
+	"github.com/moby/moby/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ContainerdConfig containerd后端的连接参数，字段含义与 internal/runtime.ContainerdConfig 一致，
+// 二者分别服务于本文件的 DockerClientInterface（dockerController 的完整容器生命周期管理）与
+// internal/runtime.Controller（只给WebSocket终端exec用的更窄接口），两处各自维护互不依赖
+type ContainerdConfig struct {
+	// Address containerd 守护进程的 unix socket 地址，留空时使用 /run/containerd/containerd.sock
+	Address string
+	// Namespace containerd 命名空间，隔离本程序管理的容器与宿主机上的其他workload
+	Namespace string
+}
+
+// pendingExec 记录 ContainerExecCreate 收到的参数，直到 ContainerExecAttach 真正创建containerd进程
+type pendingExec struct {
+	containerID string
+	cmd         []string
+	tty         bool
+	env         []string
+	workingDir  string
+	user        string
+}
+
+// containerdAdapter 用containerd替代moby client实现DockerClientInterface，使 dockerController
+// 在已运行containerd但未部署Docker守护进程的宿主机（K8s节点、精简主机）上无需改动即可工作。
+// 受限于containerd不提供Docker风格的镜像提交与日志驱动语义，ContainerCommit/ContainerLogs
+// 在该后端下明确返回不支持错误，而不是伪造一个看似可用实则语义不对的实现
+type containerdAdapter struct {
+	client    *containerd.Client
+	namespace string
+
+	mu         sync.RWMutex
+	containers map[string]containerd.Container
+	tasks      map[string]containerd.Task
+	pending    map[string]pendingExec
+	execs      map[string]containerd.Process
+}
+
+// NewContainerdAdapter 连接containerd的unix socket，返回一个DockerClientInterface实现
+func NewContainerdAdapter(cfg ContainerdConfig) (DockerClientInterface, error) {
+	addr := cfg.Address
+	if addr == "" {
+		addr = "/run/containerd/containerd.sock"
+	}
+	ns := cfg.Namespace
+	if ns == "" {
+		ns = "kwdb-playground"
+	}
+
+	cli, err := containerd.New(addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接containerd失败(%s): %w", addr, err)
+	}
+
+	return &containerdAdapter{
+		client:     cli,
+		namespace:  ns,
+		containers: make(map[string]containerd.Container),
+		tasks:      make(map[string]containerd.Task),
+		pending:    make(map[string]pendingExec),
+		execs:      make(map[string]containerd.Process),
+	}, nil
+}
+
+func (c *containerdAdapter) withNamespace(ctx context.Context) context.Context {
+	return namespaces.WithNamespace(ctx, c.namespace)
+}
+
+// ContainerCreate 拉取镜像（如尚未拉取过）并创建（但不启动）一个containerd容器，
+// containerName直接作为containerd容器ID，与dockerClientAdapter返回daemon生成ID不同，
+// 但dockerController自身也是以调用方传入的containerName为准保存ContainerInfo.ID，互不影响
+func (c *containerdAdapter) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error) {
+	nctx := c.withNamespace(ctx)
+
+	img, err := c.client.GetImage(nctx, config.Image)
+	if err != nil {
+		img, err = c.client.Pull(nctx, config.Image, containerd.WithPullUnpack)
+		if err != nil {
+			return container.CreateResponse{}, fmt.Errorf("拉取镜像失败: %w", err)
+		}
+	}
+
+	specOpts := []oci.SpecOpts{oci.WithImageConfig(img)}
+	if len(config.Cmd) > 0 {
+		specOpts = append(specOpts, oci.WithProcessArgs([]string(config.Cmd)...))
+	}
+	if config.WorkingDir != "" {
+		specOpts = append(specOpts, oci.WithProcessCwd(config.WorkingDir))
+	}
+	if len(config.Env) > 0 {
+		specOpts = append(specOpts, oci.WithEnv(config.Env))
+	}
+	if hostConfig != nil && hostConfig.Privileged {
+		specOpts = append(specOpts, oci.WithPrivileged)
+	}
+
+	cntr, err := c.client.NewContainer(
+		nctx, containerName,
+		containerd.WithImage(img),
+		containerd.WithContainerLabels(config.Labels),
+		containerd.WithNewSnapshot(containerName+"-snapshot", img),
+		containerd.WithNewSpec(specOpts...),
+	)
+	if err != nil {
+		return container.CreateResponse{}, fmt.Errorf("创建containerd容器失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.containers[containerName] = cntr
+	c.mu.Unlock()
+
+	return container.CreateResponse{ID: containerName}, nil
+}
+
+// ContainerStart 为已创建的容器启动一个task，stdio接到本进程自身的标准输入输出（cio.WithStdio），不持久化日志，
+// 交互式会话通过ContainerExecCreate/Attach单独创建exec，不复用这里的stdio
+func (c *containerdAdapter) ContainerStart(ctx context.Context, containerID string, options client.ContainerStartOptions) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	cntr, ok := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	task, err := cntr.NewTask(nctx, cio.NewCreator(cio.WithStdio))
+	if err != nil {
+		return fmt.Errorf("创建containerd task失败: %w", err)
+	}
+	if err := task.Start(nctx); err != nil {
+		return fmt.Errorf("启动containerd task失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.tasks[containerID] = task
+	c.mu.Unlock()
+	return nil
+}
+
+// ContainerStop 向容器主task发送SIGTERM；containerd没有daemon侧的超时后SIGKILL托管，
+// 这里只负责发信号，是否真正停止由调用方通过ContainerInspect轮询确认
+func (c *containerdAdapter) ContainerStop(ctx context.Context, containerID string, options client.ContainerStopOptions) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	task, ok := c.tasks[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	if err := task.Kill(nctx, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("停止containerd task失败: %w", err)
+	}
+	return nil
+}
+
+// ContainerRestart 停止主task后立即重新创建并启动一个新task
+func (c *containerdAdapter) ContainerRestart(ctx context.Context, containerID string, options client.ContainerStopOptions) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.Lock()
+	if task, ok := c.tasks[containerID]; ok {
+		_, _ = task.Delete(nctx, containerd.WithProcessKill)
+		delete(c.tasks, containerID)
+	}
+	c.mu.Unlock()
+
+	return c.ContainerStart(ctx, containerID, client.ContainerStartOptions{})
+}
+
+// ContainerRemove 删除容器的task与快照
+func (c *containerdAdapter) ContainerRemove(ctx context.Context, containerID string, options client.ContainerRemoveOptions) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if task, ok := c.tasks[containerID]; ok {
+		_, _ = task.Delete(nctx, containerd.WithProcessKill)
+		delete(c.tasks, containerID)
+	}
+
+	cntr, ok := c.containers[containerID]
+	if !ok {
+		return fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	if err := cntr.Delete(nctx, containerd.WithSnapshotCleanup(nctx, c.client.SnapshotService(""), containerID+"-snapshot")); err != nil {
+		return fmt.Errorf("删除containerd容器失败: %w", err)
+	}
+	delete(c.containers, containerID)
+	return nil
+}
+
+// ContainerInspect 把containerd容器+task状态映射为moby风格的InspectResponse，
+// NetworkSettings留空——调用方（GetContainerIP等）已按nil做了兼容处理
+func (c *containerdAdapter) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	cntr, ok := c.containers[containerID]
+	task, hasTask := c.tasks[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return container.InspectResponse{}, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	spec, err := cntr.Spec(nctx)
+	if err != nil {
+		return container.InspectResponse{}, fmt.Errorf("读取容器spec失败: %w", err)
+	}
+
+	state := &container.State{Status: "created"}
+	if hasTask {
+		status, err := task.Status(nctx)
+		if err != nil {
+			return container.InspectResponse{}, fmt.Errorf("查询containerd task状态失败: %w", err)
+		}
+		state.ExitCode = int(status.ExitStatus)
+		if status.Status == containerd.Running || status.Status == containerd.Created {
+			state.Running = status.Status == containerd.Running
+			state.Status = "running"
+		} else {
+			state.Status = "exited"
+			state.Dead = status.ExitStatus != 0
+		}
+	}
+
+	var env []string
+	var workingDir, user string
+	if spec.Process != nil {
+		env = spec.Process.Env
+		workingDir = spec.Process.Cwd
+		user = spec.Process.User.Username
+	}
+
+	cinfo, err := cntr.Info(nctx)
+	imageRef := ""
+	if err == nil {
+		imageRef = cinfo.Image
+	}
+
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    containerID,
+			Name:  "/" + containerID,
+			State: state,
+		},
+		Config: &container.Config{
+			Image:      imageRef,
+			Env:        env,
+			WorkingDir: workingDir,
+			User:       user,
+		},
+	}, nil
+}
+
+// ContainerList 列出本适配器在当前命名空间内创建的所有容器
+func (c *containerdAdapter) ContainerList(ctx context.Context, options client.ContainerListOptions) ([]container.Summary, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	summaries := make([]container.Summary, 0, len(c.containers))
+	for id, cntr := range c.containers {
+		cinfo, err := cntr.Info(nctx)
+		if err != nil {
+			continue
+		}
+		status := "created"
+		if task, ok := c.tasks[id]; ok {
+			if st, err := task.Status(nctx); err == nil && st.Status == containerd.Running {
+				status = "running"
+			}
+		}
+		summaries = append(summaries, container.Summary{
+			ID:     id,
+			Names:  []string{"/" + id},
+			Image:  cinfo.Image,
+			State:  status,
+			Labels: cinfo.Labels,
+		})
+	}
+	return summaries, nil
+}
+
+// ContainerLogs 在containerd后端下不受支持：ContainerStart把stdio接到cio.WithStdio，
+// 没有类似Docker json-file日志驱动的落盘与重放能力，容器输出只能通过交互式exec实时查看
+func (c *containerdAdapter) ContainerLogs(ctx context.Context, containerID string, options client.ContainerLogsOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("containerd后端暂不支持ContainerLogs：未接入日志驱动，无法回放容器历史输出")
+}
+
+// ImagePull 拉取镜像；返回的空内容流对调用方（按JSON行解析拉取进度）是安全的no-op，
+// 因为Pull已经同步完成，调用方读到io.EOF即视为"拉取完成"
+func (c *containerdAdapter) ImagePull(ctx context.Context, refStr string, options client.ImagePullOptions) (io.ReadCloser, error) {
+	nctx := c.withNamespace(ctx)
+	if _, err := c.client.Pull(nctx, refStr, containerd.WithPullUnpack); err != nil {
+		return nil, fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// ImageLoad 用containerd的Import从docker/OCI归档导入镜像，返回的Body按
+// parseLoadedImageRef期望的"Loaded image: <ref>"格式拼出一行，与Docker daemon的输出对齐
+func (c *containerdAdapter) ImageLoad(ctx context.Context, input io.Reader, options client.ImageLoadOptions) (image.LoadResponse, error) {
+	nctx := c.withNamespace(ctx)
+
+	imgs, err := c.client.Import(nctx, input)
+	if err != nil {
+		return image.LoadResponse{}, fmt.Errorf("导入镜像失败: %w", err)
+	}
+	if len(imgs) == 0 {
+		return image.LoadResponse{}, fmt.Errorf("tar包未包含任何镜像")
+	}
+	line := fmt.Sprintf(`{"stream":"Loaded image: %s\n"}`, imgs[0].Name)
+	return image.LoadResponse{Body: io.NopCloser(strings.NewReader(line))}, nil
+}
+
+// ImageInspectWithRaw 按镜像引用查询containerd本地是否已有该镜像；
+// 镜像不存在时client.GetImage返回的 github.com/containerd/errdefs 未找到错误，
+// 与 checkImageExists 中 errdefs.IsNotFound 的判断直接兼容
+func (c *containerdAdapter) ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+	nctx := c.withNamespace(ctx)
+	img, err := c.client.GetImage(nctx, imageID)
+	if err != nil {
+		return image.InspectResponse{}, nil, err
+	}
+	return image.InspectResponse{ID: img.Target().Digest.String()}, nil, nil
+}
+
+// ImageRemove 从containerd的镜像存储中删除指定镜像元数据，供 ImageManager 按引用计数清理；
+// 不做内容存储的GC，与dockerd层面"删除tag即可、底层layer由GC异步回收"的语义一致
+func (c *containerdAdapter) ImageRemove(ctx context.Context, imageID string, options client.ImageRemoveOptions) ([]image.DeleteResponse, error) {
+	nctx := c.withNamespace(ctx)
+	if err := c.client.ImageService().Delete(nctx, imageID); err != nil {
+		return nil, fmt.Errorf("删除镜像失败: %w", err)
+	}
+	return []image.DeleteResponse{{Untagged: imageID}}, nil
+}
+
+// ContainerStats 在containerd后端下不受支持：ResourceGovernor依赖的JSON流格式是dockerd特有的
+// cgroup快照序列化方式，containerd的task.Metrics()返回的是不同的原始cgroup结构体，没有直接对应的
+// 翻译目标，与ContainerLogs/ContainerCommit的既有限制保持一致，不做半成品实现
+func (c *containerdAdapter) ContainerStats(ctx context.Context, containerID string, options client.ContainerStatsOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("containerd后端暂不支持ContainerStats：ResourceGovernor的资源配额统计仅在docker后端下可用")
+}
+
+// RegistryLogin 在containerd后端下不受支持：鉴权校验与凭据持久化是dockerd自己的逻辑，
+// containerd客户端只在真正拉取时按镜像引用透传凭据，没有独立的"校验一下"接口
+func (c *containerdAdapter) RegistryLogin(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error) {
+	return registry.AuthenticateOKBody{}, fmt.Errorf("containerd后端暂不支持RegistryLogin：镜像仓库登录校验仅在docker后端下可用")
+}
+
+// ContainerCommit 在containerd后端下不受支持：把容器当前快照提交为新镜像需要自行拼装
+// content/manifest（containerd没有dockerd那样的一步到位的commit API），与internal/runtime包
+// 文档中对containerd后端"不覆盖快照提交"的既有限制保持一致，这里不做半成品实现
+func (c *containerdAdapter) ContainerCommit(ctx context.Context, containerID string, options client.ContainerCommitOptions) (container.CommitResponse, error) {
+	return container.CommitResponse{}, fmt.Errorf("containerd后端暂不支持ContainerCommit：课程快照提交仅在docker后端下可用")
+}
+
+// ContainerExecCreate 记录一次exec请求的参数；containerd要求创建task.Exec时就绑定好IO，
+// 因此真正的task.Exec调用推迟到ContainerExecAttach（拿到pipe后）再发生
+func (c *containerdAdapter) ContainerExecCreate(ctx context.Context, containerID string, config client.ExecCreateOptions) (client.ExecCreateResult, error) {
+	c.mu.RLock()
+	_, ok := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return client.ExecCreateResult{}, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	c.mu.Lock()
+	c.pending[execID] = pendingExec{
+		containerID: containerID,
+		cmd:         config.Cmd,
+		tty:         config.TTY,
+		env:         config.Env,
+		workingDir:  config.WorkingDir,
+		user:        config.User,
+	}
+	c.mu.Unlock()
+
+	return client.ExecCreateResult{ID: execID}, nil
+}
+
+// ContainerExecAttach 创建containerd task.Exec并绑定一对io.Pipe，返回的Conn/Reader
+// 与dockerClientAdapter语义一致；进程本身的Start被推迟到ContainerExecStart，
+// 和docker Create→Attach→Start的调用顺序保持一致
+func (c *containerdAdapter) ContainerExecAttach(ctx context.Context, execID string, config client.ExecAttachOptions) (client.HijackedResponse, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	pe, ok := c.pending[execID]
+	c.mu.RUnlock()
+	if !ok {
+		return client.HijackedResponse{}, fmt.Errorf("exec %s 不存在", execID)
+	}
+
+	c.mu.RLock()
+	cntr, ok := c.containers[pe.containerID]
+	task, hasTask := c.tasks[pe.containerID]
+	c.mu.RUnlock()
+	if !ok || !hasTask {
+		return client.HijackedResponse{}, fmt.Errorf("容器 %s 没有正在运行的task", pe.containerID)
+	}
+
+	spec, err := cntr.Spec(nctx)
+	if err != nil {
+		return client.HijackedResponse{}, fmt.Errorf("读取容器spec失败: %w", err)
+	}
+	pspec := spec.Process
+	pspec.Args = pe.cmd
+	pspec.Terminal = pe.tty
+	if pe.workingDir != "" {
+		pspec.Cwd = pe.workingDir
+	}
+	if len(pe.env) > 0 {
+		pspec.Env = append(pspec.Env, pe.env...)
+	}
+	if pe.user != "" {
+		pspec.User.Username = pe.user
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	process, err := task.Exec(nctx, execID, pspec, cio.NewCreator(cio.WithStreams(inR, outW, outW)))
+	if err != nil {
+		return client.HijackedResponse{}, fmt.Errorf("创建containerd exec失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.execs[execID] = process
+	c.mu.Unlock()
+
+	conn := &pipeConn{r: outR, w: inW}
+	return client.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+}
+
+// ContainerExecStart 启动已经由ContainerExecAttach创建好的containerd exec进程
+func (c *containerdAdapter) ContainerExecStart(ctx context.Context, execID string, config client.ExecStartOptions) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	process, ok := c.execs[execID]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("exec %s 不存在", execID)
+	}
+	if err := process.Start(nctx); err != nil {
+		return fmt.Errorf("启动containerd exec失败: %w", err)
+	}
+	return nil
+}
+
+// ContainerExecInspect 查询一次exec是否仍在运行及退出码
+func (c *containerdAdapter) ContainerExecInspect(ctx context.Context, execID string) (client.ExecInspectResult, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	process, ok := c.execs[execID]
+	c.mu.RUnlock()
+	if !ok {
+		return client.ExecInspectResult{}, fmt.Errorf("exec %s 不存在", execID)
+	}
+
+	status, err := process.Status(nctx)
+	if err != nil {
+		return client.ExecInspectResult{}, fmt.Errorf("查询containerd exec状态失败: %w", err)
+	}
+	return client.ExecInspectResult{
+		ExecID:   execID,
+		Running:  status.Status == containerd.Running,
+		ExitCode: int(status.ExitStatus),
+	}, nil
+}
+
+// ContainerExecResize 调整一次exec对应的TTY行列数
+func (c *containerdAdapter) ContainerExecResize(ctx context.Context, execID string, options client.ExecResizeOptions) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	process, ok := c.execs[execID]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("exec %s 不存在", execID)
+	}
+	return process.Resize(nctx, options.Width, options.Height)
+}
+
+// Ping 用Version RPC探测containerd连接是否存活，没有等价于Docker daemon /_ping的专用RPC
+func (c *containerdAdapter) Ping(ctx context.Context) (client.PingResult, error) {
+	nctx := c.withNamespace(ctx)
+	if _, err := c.client.Version(nctx); err != nil {
+		return client.PingResult{}, fmt.Errorf("连接containerd失败: %w", err)
+	}
+	return client.PingResult{}, nil
+}
+
+// CopyToContainer 把tar归档通过"容器内exec tar -x"的经典技巧写入文件系统，
+// 而不是像Docker那样由daemon直接操作容器的可写层——containerd没有暴露等价的底层接口
+func (c *containerdAdapter) CopyToContainer(ctx context.Context, containerID string, options client.CopyToContainerOptions) (client.CopyToContainerResult, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	cntr, ok := c.containers[containerID]
+	task, hasTask := c.tasks[containerID]
+	c.mu.RUnlock()
+	if !ok || !hasTask {
+		return client.CopyToContainerResult{}, fmt.Errorf("容器 %s 没有正在运行的task", containerID)
+	}
+
+	dest := options.DestinationPath
+	if dest == "" {
+		dest = "/"
+	}
+
+	spec, err := cntr.Spec(nctx)
+	if err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("读取容器spec失败: %w", err)
+	}
+	pspec := spec.Process
+	pspec.Args = []string{"tar", "-x", "-C", dest, "-f", "-"}
+	pspec.Terminal = false
+
+	execID := fmt.Sprintf("copy-%d", time.Now().UnixNano())
+	outR, outW := io.Pipe()
+	defer outR.Close()
+
+	process, err := task.Exec(nctx, execID, pspec, cio.NewCreator(cio.WithStreams(options.Content, outW, outW)))
+	if err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("创建containerd exec失败: %w", err)
+	}
+	defer process.Delete(nctx)
+
+	statusCh, err := process.Wait(nctx)
+	if err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("等待containerd exec失败: %w", err)
+	}
+	if err := process.Start(nctx); err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("启动containerd exec失败: %w", err)
+	}
+
+	go io.Copy(io.Discard, outR)
+
+	status := <-statusCh
+	if code := status.ExitCode(); code != 0 {
+		return client.CopyToContainerResult{}, fmt.Errorf("容器内tar解包失败，退出码: %d", code)
+	}
+	return client.CopyToContainerResult{}, nil
+}
+
+// Events 订阅containerd事件流并翻译成moby风格的events.Message，使cacheInvalidator等
+// 既有的Docker事件消费者无需区分后端。只翻译容器状态缓存关心的Task生命周期事件
+func (c *containerdAdapter) Events(ctx context.Context, options client.EventsListOptions) (<-chan events.Message, <-chan error) {
+	nctx := c.withNamespace(ctx)
+	msgCh := make(chan events.Message)
+	errCh := make(chan error, 1)
+
+	envelopeCh, subErrCh := c.client.EventService().Subscribe(nctx)
+
+	go func() {
+		defer close(msgCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case err, ok := <-subErrCh:
+				if !ok {
+					return
+				}
+				errCh <- err
+				return
+			case env, ok := <-envelopeCh:
+				if !ok {
+					return
+				}
+				msg, handled := translateContainerdEvent(env)
+				if !handled {
+					continue
+				}
+				select {
+				case msgCh <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return msgCh, errCh
+}
+
+// translateContainerdEvent 把containerd事件信封里关心的Task事件映射为moby风格的events.Message，
+// handled为false表示这条事件与容器生命周期无关，调用方应直接忽略
+func translateContainerdEvent(env *containerd.Envelope) (events.Message, bool) {
+	if env == nil || env.Event == nil {
+		return events.Message{}, false
+	}
+	payload, err := typeurl.UnmarshalAny(env.Event)
+	if err != nil {
+		return events.Message{}, false
+	}
+
+	switch e := payload.(type) {
+	case *eventtypes.TaskStart:
+		return events.Message{Action: "start", Actor: events.Actor{ID: e.ContainerID}}, true
+	case *eventtypes.TaskExit:
+		return events.Message{Action: "die", Actor: events.Actor{ID: e.ContainerID}}, true
+	case *eventtypes.TaskDelete:
+		return events.Message{Action: "destroy", Actor: events.Actor{ID: e.ContainerID}}, true
+	case *eventtypes.TaskOOM:
+		return events.Message{Action: "oom", Actor: events.Actor{ID: e.ContainerID}}, true
+	default:
+		return events.Message{}, false
+	}
+}
+
+// Close 关闭与containerd守护进程的连接
+func (c *containerdAdapter) Close() error {
+	return c.client.Close()
+}
+
+// pipeConn 把一对 io.Pipe 适配成 net.Conn，用于在containerd后端上满足
+// HijackedResponse.Conn 的类型约定；containerd 的 cio 基于 FIFO+io.Reader/Writer，
+// 没有原生的 net.Conn 语义，这里只需要 Read/Write 可用，Deadline 等方法按需实现为空操作
+type pipeConn struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (p *pipeConn) Read(b []byte) (int, error)  { return p.r.Read(b) }
+func (p *pipeConn) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *pipeConn) Close() error {
+	_ = p.r.Close()
+	return p.w.Close()
+}
+func (p *pipeConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (p *pipeConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (p *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (p *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (p *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// pipeAddr 是 pipeConn 的占位地址，containerd exec 不涉及真实网络端点
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "containerd-exec-pipe" }
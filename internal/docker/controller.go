@@ -2,35 +2,60 @@ package docker
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/metrics"
+	"kwdb-playground/internal/registry"
 
 	"github.com/containerd/errdefs"
 	"github.com/docker/go-connections/nat"
 	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/filters"
 	"github.com/moby/moby/api/types/image"
+	registrytypes "github.com/moby/moby/api/types/registry"
 	"github.com/moby/moby/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
+// ErrImagePull 标记一次镜像拉取失败（区别于本地 Docker 守护进程异常等不可重试的错误）
+// EnsureImageAvailable 依据 errors.Is(err, ErrImagePull) 判断是否应该继续尝试下一个镜像源
+var ErrImagePull = errors.New("镜像拉取失败")
+
 // dockerController Docker控制器实现
 type dockerController struct {
-	client          DockerClientInterface
-	containers      map[string]*ContainerInfo // 内存中的容器信息
-	mu              sync.RWMutex              // 保护containers映射的读写锁
-	cache           *containerCache           // 容器状态缓存
-	courseMu        map[string]*sync.Mutex    // 每个课程的互斥锁
-	courseMuMu      sync.RWMutex              // 保护courseMu映射的读写锁
-	logger          *logger.Logger            // 日志记录器
-	terminalManager TerminalManagerInterface  // WebSocket终端管理器接口
+	client           DockerClientInterface
+	containers       map[string]*ContainerInfo // 内存中的容器信息
+	mu               sync.RWMutex              // 保护containers映射的读写锁
+	cache            *containerCache           // 容器状态缓存
+	courseMu         map[string]*sync.Mutex    // 每个课程的互斥锁
+	courseMuMu       sync.RWMutex              // 保护courseMu映射的读写锁
+	logger           *logger.Logger            // 日志记录器
+	terminalManager  TerminalManagerInterface  // WebSocket终端管理器接口
+	metricsRecorder  metrics.Recorder          // Prometheus 指标上报，默认 metrics.DefaultRecorder
+	mirrorStats      *registry.LatencyStore    // 镜像源EWMA延迟统计，跨进程持久化在 ~/.kwdb-playground/mirrors.json
+	networkName      string                    // 课程容器所加入的自定义网络名，GetContainerIP据此优先选择对应端点
+	supervisor       *Supervisor               // 容器监督器，由 StartSupervisor 按需创建，nil表示未启用
+	invalidator      *cacheInvalidator         // 容器状态缓存失效器，随控制器一起创建并启动
+	imageManager     *ImageManager             // 镜像引用计数与TTL清理
+	prewarmPool      *PrewarmPool              // 按镜像维护的空闲预热容器池
+	resourceGovernor *ResourceGovernor         // 按课程聚合的资源用量统计与配额拒绝
+	authProvider     RegistryAuthProvider      // 镜像拉取鉴权链：SetRegistryAuthProvider注入的Provider（若有） -> Login缓存 -> ~/.docker/config.json
+	loginAuth        *loginAuthProvider        // Login/Logout操作的凭据缓存，同时是默认authProvider链的第一环
 }
 
+// imageManagerTTL 镜像引用计数归零后，ImageManager 等待复用再真正清理前保留的时间窗口
+const imageManagerTTL = 10 * time.Minute
+
 // createDockerClient 创建Docker客户端，支持多种socket路径
 func createDockerClient(log *logger.Logger) (*client.Client, error) {
 	// 定义要尝试的Docker socket路径
@@ -122,11 +147,89 @@ func NewControllerWithTerminalManager(terminalManager TerminalManagerInterface)
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	_, err = adapter.Ping(ctx)
-	if err != nil {
+	if _, err := adapter.Ping(ctx); err != nil {
 		return nil, fmt.Errorf("failed to ping docker daemon: %w", err)
 	}
 
+	return newControllerWithClient(adapter, terminalManager, log)
+}
+
+// NewContainerdController 创建一个完全由containerd驱动的Docker控制器（不依赖Docker守护进程），
+// 由RuntimeConfig.Type=="containerd"的部署选用，对dockerController及其上层调用方完全透明
+func NewContainerdController(cfg ContainerdConfig, terminalManager TerminalManagerInterface) (Controller, error) {
+	log := logger.NewLogger(logger.INFO)
+
+	adapter, err := NewContainerdAdapter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create containerd adapter: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := adapter.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping containerd: %w", err)
+	}
+
+	return newControllerWithClient(adapter, terminalManager, log)
+}
+
+// NewCRIController 创建一个由标准CRI（Container Runtime Interface）gRPC端点驱动的Docker控制器，
+// 由RuntimeConfig.Type=="cri"的部署选用，对dockerController及其上层调用方完全透明；
+// 与NewContainerdController的区别在于走kubelet同款的RuntimeService/ImageService协议，
+// 而不是containerd的私有API，因此也能接入CRI-O等其他实现
+func NewCRIController(cfg CRIConfig, terminalManager TerminalManagerInterface) (Controller, error) {
+	log := logger.NewLogger(logger.INFO)
+
+	adapter, err := NewCRIAdapter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CRI adapter: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := adapter.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping CRI runtime: %w", err)
+	}
+
+	return newControllerWithClient(adapter, terminalManager, log)
+}
+
+// NewAutoDetectController 按 RuntimeConfig.Type=="auto" 的部署选用：不要求运维显式声明
+// 宿主机到底跑的是哪种运行时，而是按"containerd socket → Docker socket"的顺序探测——
+// 优先containerd是因为没有安装Docker Desktop/dockerd的精简主机（K8s节点、CI runner）
+// 通常仍然跑着containerd，反过来几乎不成立，先试Docker更容易在这类主机上白白等待连接超时
+func NewAutoDetectController(containerdCfg ContainerdConfig, terminalManager TerminalManagerInterface) (Controller, error) {
+	log := logger.NewLogger(logger.INFO)
+
+	addr := containerdCfg.Address
+	if addr == "" {
+		addr = "/run/containerd/containerd.sock"
+	}
+	if _, err := os.Stat(addr); err == nil {
+		if controller, err := NewContainerdController(containerdCfg, terminalManager); err == nil {
+			log.Info("自动探测到containerd socket (%s)，使用containerd运行时后端", addr)
+			return controller, nil
+		} else {
+			log.Warn("探测到containerd socket (%s)但连接失败，回退到Docker: %v", addr, err)
+		}
+	}
+
+	controller, err := NewControllerWithTerminalManager(terminalManager)
+	if err != nil {
+		return nil, fmt.Errorf("自动探测失败：containerd不可用，Docker也连接失败: %w", err)
+	}
+	log.Info("未探测到可用的containerd socket，使用Docker运行时后端")
+	return controller, nil
+}
+
+// newControllerWithClient 用已经建立好连接的DockerClientInterface实现（moby或containerd后端）
+// 完成dockerController剩余的通用初始化：加载历史容器、启动缓存失效器
+func newControllerWithClient(adapter DockerClientInterface, terminalManager TerminalManagerInterface, log *logger.Logger) (Controller, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
 	// 创建缓存实例
 	cache := newContainerCache(5 * time.Minute)
 
@@ -138,110 +241,201 @@ func NewControllerWithTerminalManager(terminalManager TerminalManagerInterface)
 		courseMu:        make(map[string]*sync.Mutex),
 		logger:          log,
 		terminalManager: terminalManager,
+		metricsRecorder: metrics.DefaultRecorder,
+		mirrorStats:     registry.NewLatencyStore(""),
 	}
+	controller.imageManager = newImageManager(adapter, log, imageManagerTTL)
+	controller.prewarmPool = newPrewarmPool(adapter, log)
+	controller.resourceGovernor = newResourceGovernor(adapter, log, 0, 0)
+	controller.loginAuth = newLoginAuthProvider()
+	controller.authProvider = newChainAuthProvider(controller.loginAuth, newDockerConfigAuthProvider())
 
 	// 加载现有容器到内存
-	err = controller.loadExistingContainers(ctx)
-	if err != nil {
+	if err := controller.loadExistingContainers(ctx); err != nil {
 		controller.logger.Warn("Warning: failed to load existing containers: %v", err)
 	}
 
+	// 启动缓存失效器：订阅事件流，让容器状态缓存对start/die/stop等事件立即刷新，
+	// 不再单纯依赖TTL被动过期
+	controller.invalidator = newCacheInvalidator(controller, adapter, cache, log)
+	controller.invalidator.Start(context.Background())
+
+	// 启动镜像引用计数的后台清理循环
+	controller.imageManager.Start(context.Background())
+
 	return controller, nil
 }
 
 // loadExistingContainers 加载现有的容器到内存中
+// 优先通过 LabelAppName 标签（由 Docker 端 filters.Arg 过滤）定位由本应用创建的容器，
+// 并从 LabelCourseID/LabelSessionID 读取元数据；仅对没有该标签的历史容器（旧版本创建，
+// 或尚未经 AdoptLegacy 迁移）才退化到 adoption sidecar 记录 + 名称解析
 func (d *dockerController) loadExistingContainers(ctx context.Context) error {
 	d.logger.Info("开始加载现有容器到内存中...")
 
-	// 获取所有容器（包括停止的）
-	containers, err := d.client.ContainerList(ctx, client.ContainerListOptions{
-		All: true,
+	labeled, err := d.client.ContainerList(ctx, client.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", LabelAppName+"="+appNameValue)),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
 
 	loadedCount := 0
-	for _, container := range containers {
-		// 检查容器名称是否符合我们的命名规则
-		if len(container.Names) == 0 {
-			continue
+	labeledIDs := make(map[string]struct{}, len(labeled))
+	for _, c := range labeled {
+		labeledIDs[c.ID] = struct{}{}
+		courseID := c.Labels[LabelCourseID]
+		if courseID == "" {
+			continue // 标签不完整（异常写入），交给下方兜底逻辑尝试名称解析
 		}
-
-		containerName := strings.TrimPrefix(container.Names[0], "/")
-		if !strings.HasPrefix(containerName, "kwdb-playground-") {
-			continue
+		containerName := strings.TrimPrefix(firstContainerName(c.Names), "/")
+		if d.loadContainerInfo(ctx, containerName, c.ID, courseID, c.Labels) {
+			loadedCount++
 		}
+	}
 
-		// 解析容器名称获取课程ID
-		parts := strings.Split(containerName, "-")
-		if len(parts) < 3 {
+	// 兜底：遍历全部容器，跳过已通过标签识别的，对历史容器尝试 adoption sidecar 记录，
+	// 最后才退化到名称解析（AdoptLegacy 迁移完成后，理论上这一分支不会再匹配到任何容器）
+	all, err := d.client.ContainerList(ctx, client.ContainerListOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	adopted := loadAdoptionSidecar(d.logger)
+	for _, c := range all {
+		if _, ok := labeledIDs[c.ID]; ok {
 			continue
 		}
-
-		// 提取课程ID（去掉前缀kwdb-playground-）
-		courseID := strings.Join(parts[2:len(parts)-2], "-")
-		if courseID == "" {
+		containerName := strings.TrimPrefix(firstContainerName(c.Names), "/")
+		if containerName == "" {
 			continue
 		}
 
-		// 获取容器详细信息
-		inspect, err := d.client.ContainerInspect(ctx, container.ID)
-		if err != nil {
-			d.logger.Warn("警告：无法检查容器 %s: %v", containerName, err)
-			continue
+		courseID, ok := adopted[c.ID]
+		if !ok {
+			var valid bool
+			courseID, valid = legacyParseCourseIDFromContainerName(containerName)
+			if !valid {
+				continue
+			}
 		}
-
-		// 确定容器状态
-		var state ContainerState
-		if inspect.State.Running {
-			state = StateRunning
-		} else if inspect.State.Dead {
-			state = StateError
-		} else {
-			state = StateStopped
+		if d.loadContainerInfo(ctx, containerName, c.ID, courseID, c.Labels) {
+			loadedCount++
 		}
+	}
 
-		// 解析端口映射
-		ports := make(map[string]string)
-		for port, bindings := range inspect.NetworkSettings.Ports {
-			if len(bindings) > 0 {
-				ports[port.Port()] = bindings[0].HostPort
-			}
-		}
+	d.logger.Info("容器加载完成，共加载 %d 个容器", loadedCount)
+	return nil
+}
 
-		// 解析环境变量
-		env := make(map[string]string)
-		for _, envVar := range inspect.Config.Env {
-			parts := strings.SplitN(envVar, "=", 2)
-			if len(parts) == 2 {
-				env[parts[0]] = parts[1]
-			}
+// firstContainerName 返回容器的首个名称，容器没有名称时返回空字符串
+func firstContainerName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// loadContainerInfo inspect 指定容器并写入内存，成功返回true；labels为空map时（历史容器）
+// StartedAt/IsOneTimeExecution 退化为当前时间/false，与标签引入前的既有行为一致
+func (d *dockerController) loadContainerInfo(ctx context.Context, containerName, dockerID, courseID string, labels map[string]string) bool {
+	inspect, err := d.client.ContainerInspect(ctx, dockerID)
+	if err != nil {
+		d.logger.Warn("警告：无法检查容器 %s: %v", containerName, err)
+		return false
+	}
+
+	// 确定容器状态
+	var state ContainerState
+	if inspect.State.Running {
+		state = StateRunning
+	} else if inspect.State.Dead {
+		state = StateError
+	} else {
+		state = StateStopped
+	}
+
+	// 解析端口映射
+	ports := make(map[string]string)
+	for port, bindings := range inspect.NetworkSettings.Ports {
+		if len(bindings) > 0 {
+			ports[port.Port()] = bindings[0].HostPort
 		}
+	}
 
-		// 创建容器信息
-		containerInfo := &ContainerInfo{
-			ID:        containerName,
-			CourseID:  courseID,
-			DockerID:  container.ID,
-			State:     state,
-			Image:     inspect.Config.Image,
-			StartedAt: time.Now(), // 使用当前时间，因为无法准确获取原始启动时间
-			Env:       env,
-			Ports:     ports,
+	// 解析环境变量
+	env := make(map[string]string)
+	for _, envVar := range inspect.Config.Env {
+		parts := strings.SplitN(envVar, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
 		}
+	}
 
-		// 添加到内存中
-		d.mu.Lock()
-		d.containers[containerName] = containerInfo
-		d.mu.Unlock()
+	// LabelCreatedAt/LabelOneTime 记录了原始创建时间与一次性执行标记；历史容器没有这些标签时
+	// 分别退化为当前时间与false，保持标签引入前的既有行为
+	startedAt := time.Now()
+	if createdAt, err := time.Parse(time.RFC3339, labels[LabelCreatedAt]); err == nil {
+		startedAt = createdAt
+	}
+	isOneTime, _ := strconv.ParseBool(labels[LabelOneTime])
 
-		loadedCount++
-		d.logger.Info("已加载容器: %s (课程: %s, 状态: %s)", containerName, courseID, state)
+	containerInfo := &ContainerInfo{
+		ID:                 containerName,
+		CourseID:           courseID,
+		DockerID:           dockerID,
+		State:              state,
+		Image:              inspect.Config.Image,
+		StartedAt:          startedAt,
+		Env:                env,
+		Ports:              ports,
+		IsOneTimeExecution: isOneTime,
 	}
 
-	d.logger.Info("容器加载完成，共加载 %d 个容器", loadedCount)
-	return nil
+	d.mu.Lock()
+	d.containers[containerName] = containerInfo
+	d.mu.Unlock()
+
+	d.logger.Info("已加载容器: %s (课程: %s, 状态: %s)", containerName, courseID, state)
+	return true
+}
+
+// legacyParseCourseIDFromContainerName 从容器名称反解析课程ID，仅作为没有 LabelCourseID 标签时的兜底
+// 命名格式为 kwdb-playground-{courseID}-{timestamp} 或 kwdb-playground-{courseID}-{sessionID}-{timestamp}，
+// 这里只能假设末段为时间戳（纯数字）而去掉它，courseID 中间可能误含 sessionID —— 这正是该方案比标签脆弱的原因，
+// 也是为什么新容器一律通过 AdoptLegacy/创建时写入标签来避免继续依赖这里的启发式解析
+func legacyParseCourseIDFromContainerName(containerName string) (string, bool) {
+	const prefix = "kwdb-playground-"
+	if !strings.HasPrefix(containerName, prefix) {
+		return "", false
+	}
+	withoutPrefix := strings.TrimPrefix(containerName, prefix)
+	parts := strings.Split(withoutPrefix, "-")
+	if len(parts) < 2 {
+		return "", false
+	}
+
+	if isAllDigits(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+	}
+	courseID := strings.Join(parts, "-")
+	if courseID == "" {
+		return "", false
+	}
+	return courseID, true
+}
+
+// isAllDigits 报告字符串是否为非空的纯数字（用于识别时间戳段）
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // getCourseMutex 获取指定课程的互斥锁，如果不存在则创建
@@ -331,7 +525,7 @@ func (d *dockerController) isContainerRunningCached(ctx context.Context, dockerI
 	}
 
 	isRunning := inspect.State.Running
-	d.logger.Debug("Docker API查询结果: %s, 运行状态: %v, 退出码: %d", 
+	d.logger.Debug("Docker API查询结果: %s, 运行状态: %v, 退出码: %d",
 		dockerID[:12], isRunning, inspect.State.ExitCode)
 
 	// 更新缓存
@@ -431,69 +625,175 @@ func (d *dockerController) StopContainer(ctx context.Context, containerID string
 
 	d.logger.Info("容器 %s 停止成功", containerID)
 	d.updateContainerState(containerID, StateExited, "")
+
+	// 停止后立即poll一次State，记录是否被OOM killer终止以及具体的退出原因，
+	// 供前端区分"学生程序自己崩溃"、"命中内存上限"与"被管理员主动停止"
+	if inspect, err := d.client.ContainerInspect(ctx, containerInfo.DockerID); err == nil {
+		d.mu.Lock()
+		if info, exists := d.containers[containerID]; exists {
+			populateExitInfo(info, inspect.State)
+		}
+		d.mu.Unlock()
+	} else {
+		d.logger.Warn("停止容器 %s 后查询退出信息失败: %v", containerID, err)
+	}
+
 	return nil
 }
 
 // cleanupCourseContainers 清理指定课程的所有容器
+// 优先按 LabelCourseID 过滤（Docker端filters.Arg完成，不再需要本进程里逐个字符串匹配），
+// 仅对没有该标签的历史容器（早于标签引入或尚未经 AdoptLegacy 迁移）才退化到 adoption sidecar
+// 记录 + 名称前缀解析。这要求容器上的LabelCourseID/LabelSessionID始终是它实际服务的课程/会话——
+// createContainer命中PrewarmPool时会丢弃prewarm时刻的旧容器并按本次请求重新创建（见该函数），
+// 否则这里会漏过一个标签仍停留在prewarm时刻（courseID不同、sessionID为空）的容器
 func (d *dockerController) cleanupCourseContainers(ctx context.Context, courseID string) error {
 	d.logger.Info("开始清理课程 %s 的所有容器", courseID)
 
-	// 获取所有容器列表
-	containers, err := d.client.ContainerList(ctx, client.ContainerListOptions{All: true})
+	labeled, err := d.client.ContainerList(ctx, client.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", LabelCourseID+"="+courseID)),
+	})
 	if err != nil {
 		d.logger.Error("获取容器列表失败: %v", err)
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
 
-	// 查找匹配课程前缀的容器
-	coursePrefix := fmt.Sprintf("kwdb-playground-%s-", courseID)
-	cleanedCount := 0
+	targets := append([]container.Summary(nil), labeled...)
+	labeledIDs := make(map[string]struct{}, len(labeled))
+	for _, c := range labeled {
+		labeledIDs[c.ID] = struct{}{}
+	}
 
-	for _, container := range containers {
-		for _, name := range container.Names {
-			// 容器名称以 / 开头，需要去掉
-			cleanName := strings.TrimPrefix(name, "/")
-			if strings.HasPrefix(cleanName, coursePrefix) {
-				d.logger.Info("发现课程 %s 的容器: %s (状态: %s)", courseID, cleanName, container.State)
-
-				// 如果容器正在运行，先停止它
-				if container.State == "running" {
-					d.logger.Info("停止运行中的容器: %s", container.ID[:12])
-					timeout := 10
-					if err := d.client.ContainerStop(ctx, container.ID, client.ContainerStopOptions{Timeout: &timeout}); err != nil {
-						d.logger.Error("停止容器 %s 失败: %v", container.ID[:12], err)
-						return fmt.Errorf("failed to stop container %s: %w", container.ID, err)
-					}
-				}
+	all, err := d.client.ContainerList(ctx, client.ContainerListOptions{All: true})
+	if err != nil {
+		d.logger.Error("获取容器列表失败: %v", err)
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	adopted := loadAdoptionSidecar(d.logger)
+	for _, c := range all {
+		if _, ok := labeledIDs[c.ID]; ok {
+			continue
+		}
+		cid, ok := adopted[c.ID]
+		if !ok {
+			containerName := strings.TrimPrefix(firstContainerName(c.Names), "/")
+			cid, ok = legacyParseCourseIDFromContainerName(containerName)
+		}
+		if ok && cid == courseID {
+			targets = append(targets, c)
+		}
+	}
 
-				// 删除容器
-				d.logger.Info("删除容器: %s", container.ID[:12])
-				if err := d.client.ContainerRemove(ctx, container.ID, client.ContainerRemoveOptions{Force: true}); err != nil {
-					d.logger.Error("删除容器 %s 失败: %v", container.ID[:12], err)
-					return fmt.Errorf("failed to remove container %s: %w", container.ID, err)
-				}
+	cleanedCount := 0
+	for _, c := range targets {
+		d.logger.Info("发现课程 %s 的容器: %s (状态: %s)", courseID, c.ID[:12], c.State)
+
+		// 如果容器正在运行，先停止它
+		if c.State == "running" {
+			d.logger.Info("停止运行中的容器: %s", c.ID[:12])
+			timeout := 10
+			if err := d.client.ContainerStop(ctx, c.ID, client.ContainerStopOptions{Timeout: &timeout}); err != nil {
+				d.logger.Error("停止容器 %s 失败: %v", c.ID[:12], err)
+				return fmt.Errorf("failed to stop container %s: %w", c.ID, err)
+			}
+		}
 
-				// 从内存中移除容器信息
-				d.mu.Lock()
-				for id, info := range d.containers {
-					if info.DockerID == container.ID {
-						d.logger.Info("从内存中移除容器信息: %s", id)
-						delete(d.containers, id)
-						break
-					}
-				}
-				d.mu.Unlock()
+		// 删除容器
+		d.logger.Info("删除容器: %s", c.ID[:12])
+		if err := d.client.ContainerRemove(ctx, c.ID, client.ContainerRemoveOptions{Force: true}); err != nil {
+			d.logger.Error("删除容器 %s 失败: %v", c.ID[:12], err)
+			return fmt.Errorf("failed to remove container %s: %w", c.ID, err)
+		}
+		d.imageManager.Release(c.Image)
+		d.resourceGovernor.Untrack(courseID, c.ID)
 
-				cleanedCount++
+		// 从内存中移除容器信息
+		d.mu.Lock()
+		for id, info := range d.containers {
+			if info.DockerID == c.ID {
+				d.logger.Info("从内存中移除容器信息: %s", id)
+				delete(d.containers, id)
 				break
 			}
 		}
+		d.mu.Unlock()
+
+		cleanedCount++
 	}
 
 	d.logger.Info("课程 %s 容器清理完成，共清理 %d 个容器", courseID, cleanedCount)
 	return nil
 }
 
+// cleanupCourseSessionContainers 清理指定课程下、属于指定会话的容器
+// 优先按 LabelCourseID+LabelSessionID 过滤，与 cleanupCourseContainers 同样仅对没有标签的
+// 历史容器才退化到按名称前缀匹配（courseID/sessionID均编码在容器名称中）
+func (d *dockerController) cleanupCourseSessionContainers(ctx context.Context, courseID, sessionID string) error {
+	d.logger.Info("开始清理课程 %s 会话 %s 的容器", courseID, sessionID)
+
+	labeled, err := d.client.ContainerList(ctx, client.ContainerListOptions{
+		All: true,
+		Filters: filters.NewArgs(
+			filters.Arg("label", LabelCourseID+"="+courseID),
+			filters.Arg("label", LabelSessionID+"="+sessionID),
+		),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	targets := append([]container.Summary(nil), labeled...)
+	labeledIDs := make(map[string]struct{}, len(labeled))
+	for _, c := range labeled {
+		labeledIDs[c.ID] = struct{}{}
+	}
+
+	all, err := d.client.ContainerList(ctx, client.ContainerListOptions{All: true})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+	prefix := fmt.Sprintf("kwdb-playground-%s-%s-", courseID, sessionID)
+	for _, c := range all {
+		if _, ok := labeledIDs[c.ID]; ok {
+			continue
+		}
+		containerName := strings.TrimPrefix(firstContainerName(c.Names), "/")
+		if strings.HasPrefix(containerName, prefix) {
+			targets = append(targets, c)
+		}
+	}
+
+	cleanedCount := 0
+	for _, c := range targets {
+		if c.State == "running" {
+			timeout := 10
+			if err := d.client.ContainerStop(ctx, c.ID, client.ContainerStopOptions{Timeout: &timeout}); err != nil {
+				return fmt.Errorf("failed to stop container %s: %w", c.ID, err)
+			}
+		}
+		if err := d.client.ContainerRemove(ctx, c.ID, client.ContainerRemoveOptions{Force: true}); err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", c.ID, err)
+		}
+		d.imageManager.Release(c.Image)
+		d.resourceGovernor.Untrack(courseID, c.ID)
+
+		d.mu.Lock()
+		for id, info := range d.containers {
+			if info.DockerID == c.ID {
+				delete(d.containers, id)
+				break
+			}
+		}
+		d.mu.Unlock()
+
+		cleanedCount++
+	}
+
+	d.logger.Info("课程 %s 会话 %s 容器清理完成，共清理 %d 个容器", courseID, sessionID, cleanedCount)
+	return nil
+}
+
 // updateContainerState 更新容器状态
 func (d *dockerController) updateContainerState(containerID string, state ContainerState, message string) {
 	d.mu.Lock()
@@ -504,7 +804,7 @@ func (d *dockerController) updateContainerState(containerID string, state Contai
 		containerInfo.State = state
 		containerInfo.Message = message
 		d.logger.Info("容器状态已更新: %s, %s -> %s, 消息: %s", containerID, oldState, state, message)
-		
+
 		// 同步更新缓存状态，确保一致性
 		if containerInfo.DockerID != "" {
 			isRunning := (state == StateRunning)
@@ -516,6 +816,18 @@ func (d *dockerController) updateContainerState(containerID string, state Contai
 	}
 }
 
+// containerCountsByCourse 按课程ID统计当前内存中记录的容器数，供 containers_running 指标上报使用
+func (d *dockerController) containerCountsByCourse() map[string]int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	counts := make(map[string]int, len(d.containers))
+	for _, info := range d.containers {
+		counts[info.CourseID]++
+	}
+	return counts
+}
+
 // RestartContainer 重启容器
 func (d *dockerController) RestartContainer(ctx context.Context, containerID string) error {
 	d.mu.RLock()
@@ -543,34 +855,90 @@ func (d *dockerController) CreateContainer(ctx context.Context, courseID string,
 }
 
 // CreateContainerWithProgress 创建容器并支持镜像拉取进度回调
+// 未携带会话信息，容器清理与命名仅按课程维度隔离（兼容单用户场景）
 func (d *dockerController) CreateContainerWithProgress(ctx context.Context, courseID string, config *ContainerConfig, progressCallback ImagePullProgressCallback) (*ContainerInfo, error) {
-	d.logger.Info("开始创建容器，课程ID: %s, 镜像: %s", courseID, config.Image)
+	return d.createContainer(ctx, courseID, "", config, progressCallback)
+}
+
+// CreateContainerForSession 创建容器并将其绑定到指定会话
+// 容器名称形如 kwdb-playground-<courseID>-<sessionID>-<timestamp>，清理阶段也只会影响同一会话下的旧容器，
+// 使得同一课程下不同会话的学生互不干扰
+func (d *dockerController) CreateContainerForSession(ctx context.Context, courseID, sessionID string, config *ContainerConfig, progressCallback ImagePullProgressCallback) (*ContainerInfo, error) {
+	return d.createContainer(ctx, courseID, sessionID, config, progressCallback)
+}
+
+// createContainer 是 CreateContainerWithProgress / CreateContainerForSession 的共用实现
+// sessionID 为空时退化为课程级隔离（历史行为）
+func (d *dockerController) createContainer(ctx context.Context, courseID, sessionID string, config *ContainerConfig, progressCallback ImagePullProgressCallback) (containerInfo *ContainerInfo, err error) {
+	d.logger.Info("开始创建容器，课程ID: %s, 会话ID: %s, 镜像: %s", courseID, sessionID, config.Image)
+
+	start := time.Now()
+	defer func() {
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+		}
+		d.metricsRecorder.ContainerStart(courseID, outcome, time.Since(start))
+	}()
 
 	// 使用课程级别的互斥锁，确保同一课程的容器创建操作是原子性的
 	courseMutex := d.getCourseMutex(courseID)
 	courseMutex.Lock()
 	defer courseMutex.Unlock()
 
-	// 检查镜像是否存在，如果不存在则自动拉取
-	if err := d.ensureImageExistsWithProgress(ctx, config.Image, progressCallback); err != nil {
-		d.logger.Error("确保镜像 %s 存在失败: %v", config.Image, err)
-		return nil, d.enhanceImageError(err, config.Image)
+	// 解析目标平台，格式错误直接拒绝，避免带着无效的平台请求继续往下走
+	platform, err := parsePlatform(config.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	// 课程聚合配额校验：先于镜像拉取等更重的工作拒绝会让课程超出聚合内存配额的请求
+	requestedMemoryMB := defaultMemoryMB
+	if config.Resources != nil && config.Resources.MemoryMB > 0 {
+		requestedMemoryMB = config.Resources.MemoryMB
+	} else if config.MemoryLimit > 0 {
+		requestedMemoryMB = config.MemoryLimit / (1024 * 1024)
+	}
+	if err := d.resourceGovernor.CheckQuota(courseID, requestedMemoryMB); err != nil {
+		d.logger.Warn("课程 %s 创建容器被拒绝: %v", courseID, err)
+		d.metricsRecorder.QuotaRejected(courseID, "memory")
+		return nil, err
+	}
+
+	// 登记一次该镜像的引用：命中说明已有其他课程持有该镜像，可以跳过镜像存在性检查直接复用
+	cacheHit := d.imageManager.Acquire(config.Image)
+	if !cacheHit {
+		if err := d.ensureImageExistsWithProgress(ctx, config.Image, platform, progressCallback); err != nil {
+			d.logger.Error("确保镜像 %s 存在失败: %v", config.Image, err)
+			d.imageManager.Release(config.Image)
+			return nil, d.enhanceImageError(err, config.Image)
+		}
 	}
 
 	// 检查镜像兼容性并优化容器配置
-	if err := d.checkImageCompatibilityAndOptimizeConfig(ctx, config); err != nil {
+	if err := d.checkImageCompatibilityAndOptimizeConfig(ctx, config, platform); err != nil {
 		d.logger.Error("镜像兼容性检查失败: %v", err)
 		return nil, err
 	}
 
-	// 清理该课程的所有现有容器
-	if err := d.cleanupCourseContainers(ctx, courseID); err != nil {
+	// 清理该课程（或该课程+会话）的现有容器
+	if sessionID != "" {
+		if err := d.cleanupCourseSessionContainers(ctx, courseID, sessionID); err != nil {
+			d.logger.Warn("清理课程 %s 会话 %s 的现有容器失败: %v", courseID, sessionID, err)
+			return nil, fmt.Errorf("failed to cleanup existing containers: %w", err)
+		}
+	} else if err := d.cleanupCourseContainers(ctx, courseID); err != nil {
 		d.logger.Warn("清理课程 %s 的现有容器失败: %v", courseID, err)
 		return nil, fmt.Errorf("failed to cleanup existing containers: %w", err)
 	}
 
-	// 生成唯一的容器名称
-	containerName := fmt.Sprintf("kwdb-playground-%s-%d", courseID, time.Now().Unix())
+	// 生成唯一的容器名称，携带会话ID时按会话隔离
+	var containerName string
+	if sessionID != "" {
+		containerName = fmt.Sprintf("kwdb-playground-%s-%s-%d", courseID, sessionID, time.Now().Unix())
+	} else {
+		containerName = fmt.Sprintf("kwdb-playground-%s-%d", courseID, time.Now().Unix())
+	}
 	d.logger.Info("生成容器名称: %s", containerName)
 
 	// 构建环境变量
@@ -597,9 +965,18 @@ func (d *dockerController) CreateContainerWithProgress(ctx context.Context, cour
 	}
 
 	// 构建卷映射
+	relabelSuffix := ""
+	if config.Security != nil {
+		switch {
+		case config.Security.RelabelShared:
+			relabelSuffix = ":z"
+		case config.Security.RelabelPrivate:
+			relabelSuffix = ":Z"
+		}
+	}
 	binds := make([]string, 0)
 	for hostPath, containerPath := range config.Volumes {
-		binds = append(binds, fmt.Sprintf("%s:%s", hostPath, containerPath))
+		binds = append(binds, fmt.Sprintf("%s:%s%s", hostPath, containerPath, relabelSuffix))
 	}
 
 	// 创建容器配置
@@ -610,6 +987,7 @@ func (d *dockerController) CreateContainerWithProgress(ctx context.Context, cour
 		ExposedPorts: exposedPorts,
 		WorkingDir:   config.WorkingDir,
 		Cmd:          config.Cmd,
+		Labels:       containerLabels(courseID, sessionID, config.IsOneTimeExecution),
 	}
 
 	// 创建主机配置
@@ -618,6 +996,16 @@ func (d *dockerController) CreateContainerWithProgress(ctx context.Context, cour
 		Binds:        binds,
 	}
 
+	// 配置SELinux/AppArmor安全选项
+	if config.Security != nil {
+		if config.Security.SELinuxLabel != "" {
+			hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "label:"+config.Security.SELinuxLabel)
+		}
+		if config.Security.AppArmorProfile != "" {
+			hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "apparmor="+config.Security.AppArmorProfile)
+		}
+	}
+
 	// 设置资源限制
 	if config.MemoryLimit > 0 {
 		hostConfig.Memory = config.MemoryLimit
@@ -627,20 +1015,35 @@ func (d *dockerController) CreateContainerWithProgress(ctx context.Context, cour
 		hostConfig.CPUPeriod = 100000
 	}
 
-	// 创建容器
-	resp, err := d.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, nil, containerName)
+	// 应用课程声明的资源限制与加固选项，不受信任的课程YAML不能绕过 applyResourcePolicy 里的强制上限
+	applyResourcePolicy(hostConfig, config.Resources)
+	// 再按课程聚合配额收紧一次：单容器上限不能让该课程已分配的用量叠加后突破聚合配额
+	d.resourceGovernor.ApplyQuota(hostConfig, courseID)
+
+	// 创建容器：PrewarmPool 中同镜像的空闲容器只是在fill()时按prewarm时刻已知的信息创建的，
+	// 端口映射、卷绑定、SELinux/AppArmor选项、真实的per-session Env以及courseID/sessionID标签
+	// 都是本次请求才知道的，而Docker不允许在容器创建后修改这些字段，因此即便命中预热池也不能
+	// 直接拿来用：先把它移除，再按本次请求的完整配置正常创建，确保返回的容器确实服务于这次请求
+	if prewarmDockerID, ok := d.prewarmPool.acquire(config.Image); ok {
+		d.logger.Info("从预热池取出镜像 %s 的空闲容器 %s，其端口/卷/标签与本次请求不匹配，移除后按请求配置重新创建", config.Image, prewarmDockerID[:12])
+		if err := d.client.ContainerRemove(ctx, prewarmDockerID, client.ContainerRemoveOptions{Force: true}); err != nil {
+			d.logger.Warn("移除预热容器 %s 失败: %v", prewarmDockerID[:12], err)
+		}
+	}
+
+	resp, err := d.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, platform, containerName)
 	if err != nil {
 		d.logger.Error("创建容器失败: %v", err)
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
-
-	d.logger.Info("容器创建成功，Docker ID: %s", resp.ID[:12])
+	dockerID := resp.ID
+	d.logger.Info("容器创建成功，Docker ID: %s", dockerID[:12])
 
 	// 创建容器信息
-	containerInfo := &ContainerInfo{
+	containerInfo = &ContainerInfo{
 		ID:                 containerName,
 		CourseID:           courseID,
-		DockerID:           resp.ID,
+		DockerID:           dockerID,
 		State:              StateCreating,
 		Image:              config.Image,
 		StartedAt:          time.Now(),
@@ -653,6 +1056,11 @@ func (d *dockerController) CreateContainerWithProgress(ctx context.Context, cour
 	d.mu.Lock()
 	d.containers[containerName] = containerInfo
 	d.mu.Unlock()
+	d.metricsRecorder.SetContainersRunning(d.containerCountsByCourse())
+
+	// 开始统计该容器的资源用量，纳入课程聚合配额；用独立的长生命周期context而非ctx，
+	// 否则请求结束时ctx被取消会连带中断本该持续到容器退出的统计流
+	d.resourceGovernor.Track(context.Background(), courseID, dockerID)
 
 	d.logger.Info("容器 %s 创建完成", containerName)
 	return containerInfo, nil
@@ -694,6 +1102,9 @@ func (d *dockerController) RemoveContainer(ctx context.Context, containerID stri
 	d.mu.Lock()
 	delete(d.containers, containerID)
 	d.mu.Unlock()
+	d.metricsRecorder.SetContainersRunning(d.containerCountsByCourse())
+	d.imageManager.Release(containerInfo.Image)
+	d.resourceGovernor.Untrack(containerInfo.CourseID, containerInfo.DockerID)
 
 	// 清理缓存
 	d.cache.delete(containerInfo.DockerID)
@@ -748,10 +1159,84 @@ func (d *dockerController) GetContainer(ctx context.Context, containerID string)
 	if inspect.State.Error != "" {
 		result.Message = inspect.State.Error
 	}
+	populateExitInfo(&result, inspect.State)
 
 	return &result, nil
 }
 
+// SetNetworkName 配置GetContainerIP优先选择的网络名，课程容器会作为sidecar加入该自定义网络
+func (d *dockerController) SetNetworkName(name string) {
+	d.networkName = name
+}
+
+// SetRegistryAuthProvider 见 Controller 接口注释
+func (d *dockerController) SetRegistryAuthProvider(provider RegistryAuthProvider) {
+	d.authProvider = newChainAuthProvider(provider, d.authProvider)
+}
+
+// Login 向host校验一组凭据：通过 RegistryLogin 请求daemon验证，成功后把用户名/密码
+// （以及daemon可能返回的IdentityToken）缓存进loginAuth，之后该host的拉取无需再次指定凭据
+func (d *dockerController) Login(ctx context.Context, host, username, password string) (string, error) {
+	auth := registrytypes.AuthConfig{ServerAddress: host, Username: username, Password: password}
+	resp, err := d.client.RegistryLogin(ctx, auth)
+	if err != nil {
+		return "", fmt.Errorf("登录镜像仓库 %s 失败: %w", host, err)
+	}
+	if resp.IdentityToken != "" {
+		auth.IdentityToken = resp.IdentityToken
+	}
+	d.loginAuth.set(host, auth)
+	d.logger.Info("已登录镜像仓库 %s（用户: %s）", host, username)
+	return resp.Status, nil
+}
+
+// Logout 见 Controller 接口注释
+func (d *dockerController) Logout(host string) {
+	d.loginAuth.remove(host)
+	d.logger.Info("已退出镜像仓库 %s 的登录", host)
+}
+
+// GetContainerIP 获取容器的IP地址。配置了networkName时优先返回该网络下的端点地址，
+// 未配置或容器未加入该网络时退化为bridge网络，两者都没有则任取一个已连接的网络
+func (d *dockerController) GetContainerIP(ctx context.Context, containerID string) (string, error) {
+	d.mu.RLock()
+	containerInfo, exists := d.containers[containerID]
+	d.mu.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("container %s not found", containerID)
+	}
+
+	inspect, err := d.client.ContainerInspect(ctx, containerInfo.DockerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	if inspect.NetworkSettings == nil {
+		return "", fmt.Errorf("container %s has no network settings", containerID)
+	}
+
+	networks := inspect.NetworkSettings.Networks
+	if len(networks) == 0 {
+		return "", fmt.Errorf("container %s is not attached to any network", containerID)
+	}
+
+	if d.networkName != "" {
+		if endpoint, ok := networks[d.networkName]; ok {
+			return endpoint.IPAddress.String(), nil
+		}
+	}
+
+	if endpoint, ok := networks["bridge"]; ok {
+		return endpoint.IPAddress.String(), nil
+	}
+
+	for _, endpoint := range networks {
+		return endpoint.IPAddress.String(), nil
+	}
+	return "", fmt.Errorf("container %s has no usable network endpoint", containerID)
+}
+
 // ListContainers 列出所有容器
 func (d *dockerController) ListContainers(ctx context.Context) ([]*ContainerInfo, error) {
 	d.mu.RLock()
@@ -899,28 +1384,268 @@ func (d *dockerController) getContainerInfo(ctx context.Context, containerID str
 func (d *dockerController) PullImage(ctx context.Context, imageName string) error {
 	d.logger.Info("开始拉取镜像: %s", imageName)
 
+	options, err := d.buildImagePullOptions(imageName, nil)
+	if err != nil {
+		return err
+	}
+
 	// 拉取镜像
-	reader, err := d.client.ImagePull(ctx, imageName, client.ImagePullOptions{})
+	reader, err := d.client.ImagePull(ctx, imageName, options)
 	if err != nil {
 		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
 	}
 	defer reader.Close()
 
 	// 读取拉取进度（可选，这里简单处理）
-	_, err = io.Copy(io.Discard, reader)
+	n, err := io.Copy(io.Discard, reader)
 	if err != nil {
 		return fmt.Errorf("failed to read pull response: %w", err)
 	}
+	d.metricsRecorder.ImagePullBytes(imageRegistryLabel(imageName), n)
 
 	d.logger.Info("镜像拉取完成: %s", imageName)
 	return nil
 }
 
-// ExecCommand 在容器中执行命令
-func (d *dockerController) ExecCommand(ctx context.Context, containerID string, cmd []string) (string, error) {
-	d.logger.Info("在容器 %s 中执行命令: %v", containerID, cmd)
-
-	d.mu.RLock()
+// imageRegistryLabel 从镜像引用中提取用于指标打点的镜像源标识：取首个 "/" 之前的部分，
+// 仅当它形如一个 host（含 "." 或 ":"，或为 "localhost"）时才视为显式镜像源，否则视为官方 docker.io
+func imageRegistryLabel(imageName string) string {
+	ref := imageName
+	if at := strings.Index(ref, "@"); at != -1 {
+		ref = ref[:at]
+	}
+	slash := strings.Index(ref, "/")
+	if slash == -1 {
+		return "docker.io"
+	}
+	host := ref[:slash]
+	if host == "localhost" || strings.ContainsAny(host, ".:") {
+		return host
+	}
+	return "docker.io"
+}
+
+// buildImagePullOptions 组装ImagePull的公共选项：按platform限定多架构镜像的manifest，
+// 并通过authProvider链（SetRegistryAuthProvider注入的Provider（若有） -> Login缓存 -> ~/.docker/config.json）
+// 解析该镜像所属registry host的拉取凭据，解析失败时按匿名拉取降级而不是直接报错中止
+func (d *dockerController) buildImagePullOptions(imageName string, platform *v1.Platform) (client.ImagePullOptions, error) {
+	options := client.ImagePullOptions{}
+	if platform != nil {
+		options.Platforms = []v1.Platform{*platform}
+	}
+	if d.authProvider == nil {
+		return options, nil
+	}
+
+	host := imageRegistryLabel(imageName)
+	auth, ok, err := d.authProvider.Resolve(host)
+	if err != nil {
+		d.logger.Warn("解析镜像 %s 的拉取凭据失败（host: %s）: %v，按匿名拉取继续", imageName, host, err)
+		return options, nil
+	}
+	if !ok {
+		return options, nil
+	}
+
+	encoded, err := encodeRegistryAuth(auth)
+	if err != nil {
+		return options, fmt.Errorf("编码镜像 %s 的拉取凭据失败: %w", imageName, err)
+	}
+	options.RegistryAuth = encoded
+	return options, nil
+}
+
+// EnsureImageAvailable 按镜像源优先级依次尝试拉取镜像，返回实际生效的镜像引用
+// imageName 为 "image://file/<path>" 形式时跳过所有在线源，直接从本地 tar 包导入；
+// mirrors 为空时退化为仅尝试 imageName 本身（历史行为，兼容未配置镜像源的部署）
+// 尝试顺序并非固定的配置优先级，而是先经 d.mirrorStats 按历史EWMA延迟重排（见 SelectBestMirror），
+// 每次实际拉取的耗时与成败都会反过来更新该统计，使顺序随使用逐渐收敛到"最快可达的源优先"
+func (d *dockerController) EnsureImageAvailable(ctx context.Context, mirrors []registry.RegistrySource, imageName string, progressCallback ImagePullProgressCallback) (string, error) {
+	if path, ok := registry.ParseOfflineFileRef(imageName); ok {
+		return d.LoadImageFromFile(ctx, path)
+	}
+
+	if len(mirrors) == 0 {
+		if err := d.ensureImageExistsWithProgress(ctx, imageName, nil, progressCallback); err != nil {
+			return "", err
+		}
+		return imageName, nil
+	}
+
+	var lastErr error
+	for _, source := range d.rankedMirrors(mirrors) {
+		if offline, ok := source.(registry.OfflineResolver); ok {
+			resolved, err := d.LoadImageFromFile(ctx, offline.TarPath(imageName))
+			if err == nil {
+				return resolved, nil
+			}
+			d.logger.Warn("离线镜像源 %s 导入失败，尝试下一个镜像源: %v", source.Name(), err)
+			lastErr = err
+			continue
+		}
+
+		candidate, err := source.Resolve(imageName)
+		if err != nil {
+			d.logger.Warn("镜像源 %s 解析 %s 失败，尝试下一个镜像源: %v", source.Name(), imageName, err)
+			lastErr = err
+			continue
+		}
+		start := time.Now()
+		if err := d.ensureImageExistsWithProgress(ctx, candidate, nil, progressCallback); err != nil {
+			d.mirrorStats.Record(source.ID(), time.Since(start).Milliseconds(), false)
+			if !errors.Is(err, ErrImagePull) {
+				// 非拉取类错误（如本地 Docker 守护进程异常）直接中止，继续尝试其余源也无济于事
+				return "", err
+			}
+			d.logger.Warn("镜像源 %s 拉取 %s 失败，尝试下一个镜像源: %v", source.Name(), candidate, err)
+			lastErr = err
+			continue
+		}
+		d.mirrorStats.Record(source.ID(), time.Since(start).Milliseconds(), true)
+		return candidate, nil
+	}
+
+	return "", fmt.Errorf("所有镜像源均拉取失败，镜像: %s: %w", imageName, lastErr)
+}
+
+// rankedMirrors 按 d.mirrorStats 记录的历史EWMA延迟重排 mirrors，不改变调用方持有的切片
+func (d *dockerController) rankedMirrors(mirrors []registry.RegistrySource) []registry.RegistrySource {
+	return d.mirrorStats.Rank(mirrors)
+}
+
+// SelectBestMirror 并发探测 mirrors 中各源对 imageName 的可达性，按本次探测延迟选出最快的可达源（历史EWMA只记录，不参与这次选择）
+// 仅做轻量的 manifest HEAD 探测（RegistrySource.Probe），不等同于实际拉取；探测结果同样计入 d.mirrorStats，
+// 因此即便调用方从不调用本方法，EnsureImageAvailable 自身的拉取历史也足以让排序逐渐收敛
+func (d *dockerController) SelectBestMirror(ctx context.Context, mirrors []registry.RegistrySource, imageName string) (registry.RegistrySource, error) {
+	if len(mirrors) == 0 {
+		return nil, fmt.Errorf("未配置任何镜像源")
+	}
+
+	type probed struct {
+		source    registry.RegistrySource
+		latencyMs int64
+		ok        bool
+	}
+	results := make([]probed, len(mirrors))
+	var wg sync.WaitGroup
+	for i, source := range mirrors {
+		wg.Add(1)
+		go func(i int, source registry.RegistrySource) {
+			defer wg.Done()
+			start := time.Now()
+			ref, err := source.Resolve(imageName)
+			if err != nil {
+				results[i] = probed{source: source}
+				return
+			}
+			avail, err := source.Probe(ctx, ref)
+			latency := time.Since(start).Milliseconds()
+			ok := err == nil && avail.Available
+			d.mirrorStats.Record(source.ID(), latency, ok)
+			results[i] = probed{source: source, latencyMs: latency, ok: ok}
+		}(i, source)
+	}
+	wg.Wait()
+
+	best := -1
+	for i, r := range results {
+		if !r.ok {
+			continue
+		}
+		if best == -1 || r.latencyMs < results[best].latencyMs {
+			best = i
+		}
+	}
+	if best == -1 {
+		return nil, fmt.Errorf("所有镜像源均不可达，镜像: %s", imageName)
+	}
+	return results[best].source, nil
+}
+
+// LoadImageFromFile 从本地 tar 包导入镜像，用于无法访问任何在线镜像源的离线教室环境
+func (d *dockerController) LoadImageFromFile(ctx context.Context, tarPath string) (string, error) {
+	d.logger.Info("从离线 tar 包导入镜像: %s", tarPath)
+
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: 打开离线镜像包失败: %v", ErrImagePull, err)
+	}
+	defer f.Close()
+
+	resp, err := d.client.ImageLoad(ctx, f, client.ImageLoadOptions{})
+	if err != nil {
+		return "", fmt.Errorf("%w: 导入离线镜像包失败: %v", ErrImagePull, err)
+	}
+	defer resp.Body.Close()
+
+	loaded, parseErr := parseLoadedImageRef(resp.Body)
+	if parseErr != nil {
+		d.logger.Warn("解析离线镜像包导入结果失败: %v", parseErr)
+	}
+	if loaded == "" {
+		return "", fmt.Errorf("%w: 离线镜像包未返回可用的镜像标签: %s", ErrImagePull, tarPath)
+	}
+
+	d.logger.Info("离线镜像包导入成功: %s -> %s", tarPath, loaded)
+	return loaded, nil
+}
+
+// CommitContainer 将容器当前文件系统提交为一个新镜像，用于课程快照
+// repoTag 形如 "kwdb-playground-snapshot/<courseID>:<snapshotID>"
+func (d *dockerController) CommitContainer(ctx context.Context, containerID, repoTag string) (string, error) {
+	d.mu.RLock()
+	containerInfo, exists := d.containers[containerID]
+	d.mu.RUnlock()
+	if !exists {
+		return "", fmt.Errorf("container %s not found", containerID)
+	}
+
+	d.logger.Info("提交容器快照: %s -> %s", containerID, repoTag)
+
+	repo, tag := repoTag, "latest"
+	if idx := strings.LastIndex(repoTag, ":"); idx > 0 {
+		repo, tag = repoTag[:idx], repoTag[idx+1:]
+	}
+
+	resp, err := d.client.ContainerCommit(ctx, containerInfo.DockerID, client.ContainerCommitOptions{
+		Reference: repo + ":" + tag,
+		Comment:   "kwdb-playground course snapshot",
+	})
+	if err != nil {
+		return "", fmt.Errorf("提交容器快照失败: %w", err)
+	}
+
+	d.logger.Info("容器快照提交成功: %s -> %s (镜像ID: %s)", containerID, repoTag, resp.ID)
+	return repoTag, nil
+}
+
+// parseLoadedImageRef 从 ImageLoad 的流式 JSON 输出中解析 "Loaded image: <ref>" 行，提取实际生效的镜像标签
+func parseLoadedImageRef(r io.Reader) (string, error) {
+	const marker = "Loaded image: "
+	decoder := json.NewDecoder(r)
+	var ref string
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return ref, err
+		}
+		if idx := strings.Index(msg.Stream, marker); idx >= 0 {
+			ref = strings.TrimSpace(msg.Stream[idx+len(marker):])
+		}
+	}
+	return ref, nil
+}
+
+// ExecCommand 在容器中执行命令
+func (d *dockerController) ExecCommand(ctx context.Context, containerID string, cmd []string) (string, error) {
+	d.logger.Info("在容器 %s 中执行命令: %v", containerID, cmd)
+
+	d.mu.RLock()
 	containerInfo, exists := d.containers[containerID]
 	d.mu.RUnlock()
 
@@ -929,7 +1654,7 @@ func (d *dockerController) ExecCommand(ctx context.Context, containerID string,
 	}
 
 	// 创建执行配置
-	execConfig := container.ExecOptions{
+	execConfig := client.ExecCreateOptions{
 		Cmd:          cmd,
 		AttachStdout: true,
 		AttachStderr: true,
@@ -942,14 +1667,14 @@ func (d *dockerController) ExecCommand(ctx context.Context, containerID string,
 	}
 
 	// 启动执行
-	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, client.ExecAttachOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to attach exec: %w", err)
 	}
 	defer attachResp.Close()
 
 	// 启动命令执行
-	err = d.client.ContainerExecStart(ctx, execResp.ID, container.ExecStartOptions{})
+	err = d.client.ContainerExecStart(ctx, execResp.ID, client.ExecStartOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to start exec: %w", err)
 	}
@@ -973,30 +1698,39 @@ func (d *dockerController) ExecCommand(ctx context.Context, containerID string,
 	return string(output), nil
 }
 
-// ExecCommandInteractive 在容器中执行交互式命令
-// 支持实时双向通信，与docker exec -it功能完全一致
-func (d *dockerController) ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer) error {
+// ExecCommandInteractive 在容器中执行交互式命令，支持实时双向通信，与docker exec -it功能完全一致。
+// 返回值execID标识这次exec，供调用方把后续的ResizeTerminal精确指向它
+func (d *dockerController) ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer, opts ExecOptions) (execID string, err error) {
 	// 参数验证
 	if err := d.validateExecParams(containerID, cmd, stdoutWriter); err != nil {
-		return err
+		return "", err
 	}
 
 	// 获取容器信息
 	containerInfo, inspect, err := d.getContainerInfo(ctx, containerID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 准备执行环境
-	env, user, workingDir := d.prepareExecEnvironment(inspect, true)
+	env, user, workingDir := d.prepareExecEnvironment(inspect, opts.Tty)
+	if len(opts.Env) > 0 {
+		env = append(env, opts.Env...)
+	}
+	if opts.User != "" {
+		user = opts.User
+	}
+	if opts.WorkingDir != "" {
+		workingDir = opts.WorkingDir
+	}
 
 	// 创建交互式执行配置
-	execConfig := container.ExecOptions{
+	execConfig := client.ExecCreateOptions{
 		Cmd:          cmd,
 		AttachStdout: true,
 		AttachStderr: true,
 		AttachStdin:  true, // 支持标准输入
-		Tty:          true, // 使用TTY支持交互式命令
+		TTY:          opts.Tty,
 		WorkingDir:   workingDir,
 		Env:          env,
 		User:         user,
@@ -1006,195 +1740,210 @@ func (d *dockerController) ExecCommandInteractive(ctx context.Context, container
 	// 创建执行实例
 	execResp, err := d.client.ContainerExecCreate(ctx, containerInfo.DockerID, execConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create exec: %w", err)
+		return "", fmt.Errorf("failed to create exec: %w", err)
 	}
 
 	// 启动执行并附加输入输出流
-	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{
-		Tty: true,
+	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, client.ExecAttachOptions{
+		TTY: opts.Tty,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to attach exec: %w", err)
+		return "", fmt.Errorf("failed to attach exec: %w", err)
 	}
 	defer attachResp.Close()
 
 	// 启动命令执行
-	err = d.client.ContainerExecStart(ctx, execResp.ID, container.ExecStartOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to start exec: %w", err)
+	if err := d.client.ContainerExecStart(ctx, execResp.ID, client.ExecStartOptions{}); err != nil {
+		return "", fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	startedAt := time.Now()
+	if d.terminalManager != nil {
+		d.terminalManager.BroadcastExecLifecycleEvent(containerID, ExecLifecycleEvent{ExecID: execResp.ID, Phase: ExecPhaseStarted})
 	}
 
 	// 使用context来协调goroutines
-	var wg sync.WaitGroup
-	errorChan := make(chan error, 3)
+	errorChan := make(chan error, 2)
+	resultChan := make(chan execResult, 1)
 	ctxWithCancel, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// 处理标准输入流 - 从WebSocket到容器（高性能流式输入）
+	// 处理标准输入流 - 从WebSocket到容器。阻塞读取而不是轮询SetReadDeadline，
+	// 避免每个会话每秒唤醒上百次；goroutine退出（EOF/出错/ctx取消后stdinReader被关闭）时
+	// 半关闭写入端，通知容器输入已结束
 	if stdinReader != nil {
-		wg.Add(1)
 		go func() {
-			defer wg.Done()
 			defer func() {
-				// 关闭写入端，通知容器输入结束
 				if closer, ok := attachResp.Conn.(interface{ CloseWrite() error }); ok {
 					closer.CloseWrite()
 				}
 			}()
 
-			// 使用较小的缓冲区实现零延迟传输
 			buf := make([]byte, 256)
 			for {
-				select {
-				case <-ctxWithCancel.Done():
-					return
-				default:
-					// 设置短超时读取，避免阻塞
-					if conn, ok := stdinReader.(interface{ SetReadDeadline(time.Time) error }); ok {
-						conn.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
-					}
-
-					n, err := stdinReader.Read(buf)
-					if err != nil {
-						if err != io.EOF && !os.IsTimeout(err) {
-							select {
-							case errorChan <- fmt.Errorf("stdin read error: %w", err):
-							case <-ctxWithCancel.Done():
-							}
-							return
-						}
-						// 超时错误继续循环
-						continue
-					}
-					if n > 0 {
-						// 立即发送数据，不缓冲
-						_, writeErr := attachResp.Conn.Write(buf[:n])
-						if writeErr != nil {
-							select {
-							case errorChan <- fmt.Errorf("stdin write error: %w", writeErr):
-							case <-ctxWithCancel.Done():
-							}
-							return
+				n, err := stdinReader.Read(buf)
+				if n > 0 {
+					if _, writeErr := attachResp.Conn.Write(buf[:n]); writeErr != nil {
+						select {
+						case errorChan <- fmt.Errorf("stdin write error: %w", writeErr):
+						case <-ctxWithCancel.Done():
 						}
+						return
 					}
 				}
-			}
-		}()
-	}
-
-	// 处理标准输出流 - 从容器到WebSocket（高性能流式输出）
-	if stdoutWriter != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			// 使用较小的缓冲区实现零延迟传输
-			buf := make([]byte, 256)
-			for {
-				select {
-				case <-ctxWithCancel.Done():
-					return
-				default:
-					// 直接读取，不设置超时
-
-					n, err := attachResp.Reader.Read(buf)
-					if err != nil {
-						if err != io.EOF && !os.IsTimeout(err) {
-							select {
-							case errorChan <- fmt.Errorf("stdout read error: %w", err):
-							case <-ctxWithCancel.Done():
-							}
-							return
-						}
-						// 超时错误继续循环
-						continue
-					}
-					if n > 0 {
-						// 立即发送数据，不缓冲
-						_, writeErr := stdoutWriter.Write(buf[:n])
-						if writeErr != nil {
-							select {
-							case errorChan <- fmt.Errorf("stdout write error: %w", writeErr):
-							case <-ctxWithCancel.Done():
-							}
-							return
+				if err != nil {
+					if err != io.EOF {
+						select {
+						case errorChan <- fmt.Errorf("stdin read error: %w", err):
+						case <-ctxWithCancel.Done():
 						}
 					}
-				}
-			}
-		}()
-	}
-
-	// 处理标准错误流 - 从容器到WebSocket（如果提供了stderrWriter）
-	if stderrWriter != nil {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-
-			// 由于TTY模式下stderr和stdout是合并的，这里主要用于非TTY模式
-			// 在TTY模式下，这个goroutine可能不会收到数据
-			for {
-				select {
-				case <-ctxWithCancel.Done():
 					return
-				default:
-					// 在TTY模式下，stderr通常为空
-					time.Sleep(100 * time.Millisecond)
 				}
 			}
 		}()
-	}
 
-	// 等待命令执行完成或出现错误
-	done := make(chan struct{})
-	go func() {
-		wg.Wait()
-		close(done)
-	}()
+		// stdinReader.Read在ctx取消时可能仍阻塞（比如WebSocket尚未收到下一帧），
+		// 若它支持关闭就主动关闭来唤醒读取，让上面的goroutine能够退出
+		if closer, ok := stdinReader.(io.Closer); ok {
+			go func() {
+				<-ctxWithCancel.Done()
+				closer.Close()
+			}()
+		}
+	}
 
-	// 监控执行状态
+	// 处理标准输出/标准错误流 - 从容器到WebSocket；该流结束（EOF）意味着exec进程已经退出——
+	// Docker在进程退出时关闭attach连接——借此做唯一一次ContainerExecInspect取得准确退出码，
+	// 取代原来每秒轮询一次、且只在非0退出码时才报告的ticker
 	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctxWithCancel.Done():
-				return
-			case <-ticker.C:
-				// 检查执行状态
-				inspectResp, err := d.client.ContainerExecInspect(ctx, execResp.ID)
-				if err != nil {
-					select {
-					case errorChan <- fmt.Errorf("failed to inspect exec: %w", err):
-					case <-ctxWithCancel.Done():
-					}
-					return
-				}
+		var copyErr error
+		if opts.Tty {
+			// TTY模式下stdout/stderr合流且没有帧头，直接透传给stdoutWriter
+			copyErr = copyExecOutput(ctxWithCancel, attachResp.Reader, stdoutWriter)
+		} else {
+			// 非TTY模式下附加流按Docker的多路复用帧格式分别承载stdout/stderr，需要手动解复用
+			copyErr = demuxExecOutput(ctxWithCancel, attachResp.Reader, stdoutWriter, stderrWriter)
+		}
 
-				// 如果命令已完成且退出码不为0，报告错误
-				if !inspectResp.Running && inspectResp.ExitCode != 0 {
-					select {
-					case errorChan <- fmt.Errorf("command failed with exit code %d", inspectResp.ExitCode):
-					case <-ctxWithCancel.Done():
-					}
-					return
-				}
-			}
+		exitCode := 0
+		if inspectResp, inspectErr := d.client.ContainerExecInspect(ctx, execResp.ID); inspectErr == nil {
+			exitCode = inspectResp.ExitCode
+		}
+		select {
+		case resultChan <- execResult{exitCode: exitCode, err: copyErr}:
+		case <-ctxWithCancel.Done():
 		}
 	}()
 
 	// 等待完成或错误
+	var res execResult
 	select {
+	case res = <-resultChan:
 	case err := <-errorChan:
-		cancel()
-		return err
-	case <-done:
-		// 所有流处理完成
-		return nil
+		res = execResult{err: err}
 	case <-ctx.Done():
 		cancel()
-		return ctx.Err()
+		return execResp.ID, ctx.Err()
+	}
+	cancel()
+
+	if d.terminalManager != nil {
+		d.terminalManager.BroadcastExecLifecycleEvent(containerID, ExecLifecycleEvent{
+			ExecID:     execResp.ID,
+			Phase:      ExecPhaseExited,
+			ExitCode:   res.exitCode,
+			DurationMs: time.Since(startedAt).Milliseconds(),
+		})
+	}
+
+	if res.err != nil {
+		return execResp.ID, res.err
+	}
+	if res.exitCode != 0 {
+		return execResp.ID, fmt.Errorf("command failed with exit code %d", res.exitCode)
+	}
+	return execResp.ID, nil
+}
+
+// execResult 是ExecCommandInteractive的终态：exitCode仅在err为nil时才有意义
+type execResult struct {
+	exitCode int
+	err      error
+}
+
+// execStreamHeaderSize 非TTY exec attach流的帧头长度：第0字节为流类型，第1-3字节保留，
+// 第4-7字节为大端序payload长度，与docker API文档里的stream format一致
+const execStreamHeaderSize = 8
+
+// copyExecOutput 在TTY模式下把attach连接读到的数据原样转发给stdoutWriter；
+// TTY下stdout/stderr合流且没有帧头，直接透传即可
+func copyExecOutput(ctx context.Context, r io.Reader, stdoutWriter io.Writer) error {
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, writeErr := stdoutWriter.Write(buf[:n]); writeErr != nil {
+				return fmt.Errorf("stdout write error: %w", writeErr)
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("stdout read error: %w", err)
+		}
+	}
+}
+
+// demuxExecOutput 按非TTY exec/attach的多路复用帧格式解析r，把每帧payload按流类型
+// （1=stdout，2=stderr）分别转发给stdoutWriter/stderrWriter；等价于上游
+// pkg/stdcopy.StdCopy做的事情，这里手写是因为本仓库没有vendor那个包
+func demuxExecOutput(ctx context.Context, r io.Reader, stdoutWriter, stderrWriter io.Writer) error {
+	header := make([]byte, execStreamHeaderSize)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil
+			}
+			return fmt.Errorf("stdout read error: %w", err)
+		}
+
+		frameSize := binary.BigEndian.Uint32(header[4:8])
+		payload := make([]byte, frameSize)
+		if frameSize > 0 {
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return fmt.Errorf("stdout read error: %w", err)
+			}
+		}
+
+		var w io.Writer
+		switch header[0] {
+		case 1:
+			w = stdoutWriter
+		case 2:
+			w = stderrWriter
+		default:
+			continue
+		}
+		if w == nil {
+			continue
+		}
+		if _, err := w.Write(payload); err != nil {
+			return fmt.Errorf("stdout write error: %w", err)
+		}
 	}
 }
 
@@ -1210,7 +1959,7 @@ func (d *dockerController) ResizeTerminal(ctx context.Context, execID string, he
 	}
 
 	// 调整终端大小
-	err := d.client.ContainerExecResize(ctx, execID, client.ContainerResizeOptions{
+	err := d.client.ContainerExecResize(ctx, execID, client.ExecResizeOptions{
 		Height: height,
 		Width:  width,
 	})
@@ -1222,7 +1971,7 @@ func (d *dockerController) ResizeTerminal(ctx context.Context, execID string, he
 }
 
 // ContainerExecResize 调整执行实例的终端大小
-func (d *dockerController) ContainerExecResize(ctx context.Context, execID string, options client.ContainerResizeOptions) error {
+func (d *dockerController) ContainerExecResize(ctx context.Context, execID string, options client.ExecResizeOptions) error {
 	return d.client.ContainerExecResize(ctx, execID, options)
 }
 
@@ -1231,7 +1980,12 @@ func (d *dockerController) mapDockerState(state *container.State) ContainerState
 	if state.Running {
 		return StateRunning
 	}
-	if state.Dead || state.OOMKilled {
+	// OOMKilled单独判断，不再折进StateError：调用方（如Supervisor重启策略、前端展示）
+	// 需要把"命中内存上限"和"容器本身/镜像的其他错误"区分对待
+	if state.OOMKilled {
+		return StateOOMKilled
+	}
+	if state.Dead {
 		return StateError
 	}
 	if state.ExitCode != 0 {
@@ -1241,14 +1995,119 @@ func (d *dockerController) mapDockerState(state *container.State) ContainerState
 	return StateExited
 }
 
+// exitReasonFromState 把Docker State归类为一个简要的退出原因，供UI区分
+// "学生程序自己崩溃"（exit_code_N）、"命中内存上限被OOM killer杀掉"（oom_killed）与
+// "容器本身报错，如镜像损坏、rootfs问题"（error: ...）
+func exitReasonFromState(state *container.State) string {
+	switch {
+	case state.OOMKilled:
+		return "oom_killed"
+	case state.Error != "":
+		return fmt.Sprintf("error: %s", state.Error)
+	case state.Running:
+		return ""
+	case state.ExitCode == 0:
+		return "completed"
+	default:
+		return fmt.Sprintf("exit_code_%d", state.ExitCode)
+	}
+}
+
+// populateExitInfo 把Docker State里的OOMKilled/FinishedAt写入info，FinishedAt解析失败
+// （容器仍在运行时Docker返回零值时间戳）时保持info.FinishedAt不变
+func populateExitInfo(info *ContainerInfo, state *container.State) {
+	info.OOMKilled = state.OOMKilled
+	info.ExitReason = exitReasonFromState(state)
+	if finishedAt, err := time.Parse(time.RFC3339Nano, state.FinishedAt); err == nil && !finishedAt.IsZero() {
+		info.FinishedAt = finishedAt
+	}
+}
+
+// SetMetricsRecorder 替换默认的 Prometheus Recorder，主要用于单元测试注入桩实现
+func (d *dockerController) SetMetricsRecorder(r metrics.Recorder) {
+	if r != nil {
+		d.metricsRecorder = r
+	}
+}
+
 // Close 关闭控制器
 func (d *dockerController) Close() error {
+	if d.invalidator != nil {
+		d.invalidator.Stop()
+	}
+	if d.imageManager != nil {
+		d.imageManager.Stop()
+	}
+	if d.resourceGovernor != nil {
+		d.resourceGovernor.Stop()
+	}
 	if d.client != nil {
 		return d.client.Close()
 	}
 	return nil
 }
 
+// StartSupervisor 启动容器监督：首次调用时按需创建 Supervisor，随后（重复调用时亦然）
+// 订阅Docker事件流并开始按policy自动重启异常退出的容器
+func (d *dockerController) StartSupervisor(ctx context.Context, policy RestartPolicy, statePath string) {
+	if d.supervisor == nil {
+		d.supervisor = newSupervisor(d, d.client, d.terminalManager, d.logger, policy, statePath)
+	}
+	d.supervisor.Start(ctx)
+}
+
+// StopSupervisor 停止容器监督，尚未启用监督时为空操作
+func (d *dockerController) StopSupervisor() {
+	if d.supervisor != nil {
+		d.supervisor.Stop()
+	}
+}
+
+// SupervisorEvents 订阅指定容器的监督事件；尚未启用监督时返回一个立即关闭的channel与空操作cancel
+func (d *dockerController) SupervisorEvents(containerID string) (<-chan SupervisorEvent, func()) {
+	if d.supervisor == nil {
+		ch := make(chan SupervisorEvent)
+		close(ch)
+		return ch, func() {}
+	}
+	return d.supervisor.Subscribe(containerID)
+}
+
+// Prewarm 为 config.Image 预热 n 个空闲的已停止容器，提前把镜像拉取到本地；后续同镜像的
+// CreateContainer 命中池子时仍会移除重建（端口/卷/标签是请求级别的，无法复用），真正省下的
+// 是课程会话创建容器时本可能触发的镜像拉取等待，而非ContainerCreate本身
+func (d *dockerController) Prewarm(ctx context.Context, courseID string, config *ContainerConfig, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("预热容器数量必须为正数，实际: %d", n)
+	}
+
+	// 预热前先确保镜像存在，避免池中容器因镜像缺失而创建失败
+	platform, err := parsePlatform(config.Platform)
+	if err != nil {
+		return err
+	}
+	if err := d.ensureImageExistsWithProgress(ctx, config.Image, platform, nil); err != nil {
+		return d.enhanceImageError(err, config.Image)
+	}
+
+	return d.prewarmPool.fill(ctx, courseID, config, n)
+}
+
+// SubscribeStateChanges 订阅指定课程的容器状态变化，由 invalidator 消费Docker事件流驱动
+func (d *dockerController) SubscribeStateChanges(courseID string) (<-chan StateChange, func()) {
+	if d.invalidator == nil {
+		ch := make(chan StateChange)
+		close(ch)
+		return ch, func() {}
+	}
+	return d.invalidator.Subscribe(courseID)
+}
+
+// GetCourseUsage 返回指定课程当前的聚合资源用量快照，由 ResourceGovernor 统计
+func (d *dockerController) GetCourseUsage(courseID string) (CourseUsage, bool) {
+	return d.resourceGovernor.GetCourseUsage(courseID)
+}
+
 // classifyPullError 分类拉取错误并提供详细的错误信息和建议
 func (d *dockerController) classifyPullError(err error, imageName string) string {
 	errorStr := err.Error()
@@ -1327,7 +2186,7 @@ func (d *dockerController) classifyImageCheckError(err error, imageName string)
 }
 
 // ensureImageExistsWithProgress 确保镜像存在，如果不存在则自动拉取，支持进度回调
-func (d *dockerController) ensureImageExistsWithProgress(ctx context.Context, imageName string, progressCallback ImagePullProgressCallback) error {
+func (d *dockerController) ensureImageExistsWithProgress(ctx context.Context, imageName string, platform *v1.Platform, progressCallback ImagePullProgressCallback) error {
 	d.logger.Info("检查镜像是否存在: %s", imageName)
 
 	// 检查本地是否存在镜像
@@ -1367,7 +2226,7 @@ func (d *dockerController) ensureImageExistsWithProgress(ctx context.Context, im
 		Status:    "开始拉取镜像",
 	})
 
-	if err := d.pullImageWithProgress(ctx, imageName, webSocketCallback); err != nil {
+	if err := d.pullImageWithProgress(ctx, imageName, platform, webSocketCallback); err != nil {
 		// 使用详细的错误分类
 		errorMsg := d.classifyPullError(err, imageName)
 		// 发送拉取失败的进度信息
@@ -1376,7 +2235,7 @@ func (d *dockerController) ensureImageExistsWithProgress(ctx context.Context, im
 			Status:    "拉取失败",
 			Error:     errorMsg,
 		})
-		return fmt.Errorf(errorMsg)
+		return fmt.Errorf("%w: %s", ErrImagePull, errorMsg)
 	}
 
 	// 发送拉取成功的进度信息
@@ -1406,7 +2265,7 @@ func (d *dockerController) checkImageExists(ctx context.Context, imageName strin
 }
 
 // pullImageWithProgress 拉取镜像并支持进度回调
-func (d *dockerController) pullImageWithProgress(ctx context.Context, imageName string, progressCallback ImagePullProgressCallback) error {
+func (d *dockerController) pullImageWithProgress(ctx context.Context, imageName string, platform *v1.Platform, progressCallback ImagePullProgressCallback) error {
 	// 详细日志：开始拉取镜像
 	d.logger.Info("[镜像拉取] 开始拉取镜像: %s", imageName)
 
@@ -1421,8 +2280,15 @@ func (d *dockerController) pullImageWithProgress(ctx context.Context, imageName
 		d.logger.Warn("[镜像拉取] 进度回调函数为空: %s", imageName)
 	}
 
-	// 创建拉取选项
-	options := client.ImagePullOptions{}
+	// 创建拉取选项：指定平台时让多架构镜像返回匹配的manifest而非daemon本机架构，
+	// 并按需带上解析到的registry鉴权信息
+	options, err := d.buildImagePullOptions(imageName, platform)
+	if err != nil {
+		if progressCallback != nil {
+			progressCallback(ImagePullProgress{ImageName: imageName, Status: "拉取失败", Error: err.Error()})
+		}
+		return err
+	}
 	d.logger.Debug("[镜像拉取] 创建拉取选项: %s", imageName)
 
 	// 开始拉取镜像
@@ -1448,56 +2314,54 @@ func (d *dockerController) pullImageWithProgress(ctx context.Context, imageName
 	// 详细日志：成功开始拉取流
 	d.logger.Info("[镜像拉取] 成功开始镜像拉取流: %s", imageName)
 
-	// 读取拉取进度
-	decoder := json.NewDecoder(resp)
+	// 用streamPullEvents按层聚合解析JSON进度流（同PullImageEvents共用的实现），
+	// 这样既能驱动下面的legacy ImagePullProgress回调，也能把结构化的PullEvent
+	// （含按层状态、累计字节数、平滑ETA）广播给WebSocket终端，供前端渲染多行进度条
+	rawEvents := make(chan PullEvent, 16)
+	lastLayerBytes := make(map[string]int64) // 按层 id 记录已上报的累计字节数，用于计算ImagePullBytes增量
+	go func() {
+		defer close(rawEvents)
+		streamPullEvents(imageName, resp, rawEvents)
+	}()
+
 	progressCount := 0
-	for {
-		var progressInfo map[string]interface{}
-		err := decoder.Decode(&progressInfo)
-		if err != nil {
-			if err == io.EOF {
-				d.logger.Info("[镜像拉取] 拉取进度流结束: %s, 总进度事件数: %d", imageName, progressCount)
-				break
+	for ev := range rawEvents {
+		progressCount++
+
+		if ev.LayerID != "" {
+			delta := ev.Current - lastLayerBytes[ev.LayerID]
+			if delta > 0 {
+				d.metricsRecorder.ImagePullBytes(imageRegistryLabel(imageName), delta)
+				lastLayerBytes[ev.LayerID] = ev.Current
 			}
-			d.logger.Warn("[镜像拉取] 解析拉取进度JSON失败 - 镜像: %s, 错误: %v", imageName, err)
-			continue
 		}
 
-		progressCount++
-		// 解析进度信息
-		status, _ := progressInfo["status"].(string)
-		progress, _ := progressInfo["progress"].(string)
-		errorStr, _ := progressInfo["error"].(string)
-
-		// 为空的status提供默认值，避免前端显示undefined
-		if status == "" {
-			status = "正在拉取镜像..."
+		if d.terminalManager != nil {
+			d.terminalManager.BroadcastPullEvent(ev)
 		}
 
-		// 详细日志：进度事件
-		if errorStr != "" {
+		// 把结构化事件折算成legacy ImagePullProgress文案，兼容仍在使用简单回调的调用方
+		status := pullEventStatusText(ev)
+		if errorStr := ev.Error; errorStr != "" {
 			d.logger.Error("[镜像拉取] 拉取过程中出现错误 - 镜像: %s, 状态: %s, 错误: %s", imageName, status, errorStr)
 		} else {
-			d.logger.Debug("[镜像拉取] 进度事件 #%d - 镜像: %s, 状态: %s, 进度: %s", progressCount, imageName, status, progress)
+			d.logger.Debug("[镜像拉取] 进度事件 #%d - 镜像: %s, 状态: %s", progressCount, imageName, status)
 		}
 
-		// 发送进度回调
 		if progressCallback != nil {
 			d.logger.Debug("[镜像拉取] 发送进度回调 #%d: %s", progressCount, imageName)
 			progressCallback(ImagePullProgress{
 				ImageName: imageName,
 				Status:    status,
-				Progress:  progress,
-				Error:     errorStr,
+				Error:     ev.Error,
 			})
 		} else {
 			d.logger.Warn("[镜像拉取] 进度回调函数为空，无法发送进度 #%d: %s", progressCount, imageName)
 		}
 
-		// 检查是否有错误
-		if errorStr != "" {
-			d.logger.Error("[镜像拉取] 拉取失败，返回错误 - 镜像: %s, 错误: %s", imageName, errorStr)
-			return fmt.Errorf("拉取镜像过程中出现错误: %s", errorStr)
+		if ev.Error != "" {
+			d.logger.Error("[镜像拉取] 拉取失败，返回错误 - 镜像: %s, 错误: %s", imageName, ev.Error)
+			return fmt.Errorf("拉取镜像过程中出现错误: %s", ev.Error)
 		}
 	}
 
@@ -1515,6 +2379,30 @@ func (d *dockerController) pullImageWithProgress(ctx context.Context, imageName
 	return nil
 }
 
+// pullEventStatusText 把结构化PullEvent折算成legacy ImagePullProgress.Status使用的一句话文案，
+// 按优先级依次取：Message（"Pulling from…"/"Digest:"/"Status:"等无层ID的终端文案）、
+// 按层的阶段+百分比、或兜底文案，避免前端显示undefined
+func pullEventStatusText(ev PullEvent) string {
+	if ev.Message != "" {
+		return ev.Message
+	}
+	if ev.LayerID == "" {
+		return "正在拉取镜像..."
+	}
+	switch ev.Phase {
+	case PullPhaseWaiting:
+		return fmt.Sprintf("等待层 %s", ev.LayerID)
+	case PullPhaseDownloading:
+		return fmt.Sprintf("正在下载层 %s（%.0f%%）", ev.LayerID, ev.OverallPercent)
+	case PullPhaseExtracting:
+		return fmt.Sprintf("正在解压层 %s（%.0f%%）", ev.LayerID, ev.OverallPercent)
+	case PullPhaseComplete:
+		return fmt.Sprintf("层 %s 拉取完成", ev.LayerID)
+	default:
+		return "正在拉取镜像..."
+	}
+}
+
 // enhanceImageError 增强镜像相关错误信息，提供更详细的诊断和解决方案
 func (d *dockerController) enhanceImageError(err error, imageName string) error {
 	errorStr := err.Error()
@@ -1545,7 +2433,7 @@ func (d *dockerController) enhanceImageError(err error, imageName string) error
 }
 
 // checkImageCompatibilityAndOptimizeConfig 检查镜像兼容性并优化容器配置
-func (d *dockerController) checkImageCompatibilityAndOptimizeConfig(ctx context.Context, config *ContainerConfig) error {
+func (d *dockerController) checkImageCompatibilityAndOptimizeConfig(ctx context.Context, config *ContainerConfig, platform *v1.Platform) error {
 	d.logger.Info("检查镜像兼容性: %s", config.Image)
 
 	// 检查镜像信息
@@ -1554,6 +2442,11 @@ func (d *dockerController) checkImageCompatibilityAndOptimizeConfig(ctx context.
 		return fmt.Errorf("无法获取镜像信息: %w", err)
 	}
 
+	// 请求的平台与拉取到的镜像实际平台不一致时拒绝创建容器，避免"能启动但架构不对"的静默错误
+	if err := checkPlatformMatch(platform, &imageInfo); err != nil {
+		return err
+	}
+
 	// 分析镜像类型和特征
 	imageType := d.analyzeImageType(config.Image, &imageInfo)
 	d.logger.Info("检测到镜像类型: %s", imageType)
@@ -1606,6 +2499,37 @@ func (d *dockerController) analyzeImageType(imageName string, imageInfo *image.I
 	return "generic"
 }
 
+// imageResourceDefaults 按镜像类型给出的资源默认值，只用于填充config.Resources中
+// 课程未显式声明（零值）的数值字段，见 applyImageResourceDefaults
+type imageResourceDefaults struct {
+	cpuShares int64
+	cpuQuota  int64
+	memoryMB  int64
+	pidsLimit int64
+}
+
+// applyImageResourceDefaults 把defaults填进config.Resources里尚未被课程YAML显式设置（零值）的
+// 数值字段，config.Resources为nil时新建；CapDrop/Tmpfs/Ulimits等加固相关字段只应由课程/运维
+// 显式声明，这里不碰。最终仍会经过createContainer里的applyResourcePolicy套用强制上限
+func applyImageResourceDefaults(config *ContainerConfig, defaults imageResourceDefaults) {
+	if config.Resources == nil {
+		config.Resources = &ContainerResources{}
+	}
+	res := config.Resources
+	if res.CPUShares == 0 {
+		res.CPUShares = defaults.cpuShares
+	}
+	if res.CPUQuota == 0 {
+		res.CPUQuota = defaults.cpuQuota
+	}
+	if res.MemoryMB == 0 {
+		res.MemoryMB = defaults.memoryMB
+	}
+	if res.PidsLimit == 0 {
+		res.PidsLimit = defaults.pidsLimit
+	}
+}
+
 // optimizeForHelloWorldImage 为hello-world镜像优化配置
 func (d *dockerController) optimizeForHelloWorldImage(config *ContainerConfig) error {
 	d.logger.Info("检测到hello-world镜像，使用默认行为")
@@ -1615,6 +2539,8 @@ func (d *dockerController) optimizeForHelloWorldImage(config *ContainerConfig) e
 	config.Cmd = nil
 	// 标记这是一个一次性执行的镜像
 	config.IsOneTimeExecution = true
+	// 运行一次就退出，给一个很小的资源默认值即可
+	applyImageResourceDefaults(config, imageResourceDefaults{cpuShares: 128, cpuQuota: 10000, memoryMB: 64, pidsLimit: 64})
 	d.logger.Info("已为hello-world镜像清空启动命令，使用默认行为，标记为一次性执行")
 
 	return nil
@@ -1650,6 +2576,9 @@ func (d *dockerController) optimizeForMinimalImage(config *ContainerConfig, imag
 		}
 	}
 
+	// 最小化镜像体积小、用途单一，给一个小的资源默认值
+	applyImageResourceDefaults(config, imageResourceDefaults{cpuShares: 256, cpuQuota: 25000, memoryMB: 128, pidsLimit: 128})
+
 	return nil
 }
 
@@ -1663,6 +2592,9 @@ func (d *dockerController) optimizeForAlpineImage(config *ContainerConfig) error
 		d.logger.Info("Alpine镜像使用/bin/sh替代/bin/bash")
 	}
 
+	// Alpine体积小但常用来跑真实的课程任务，给比minimal宽松一些的默认值
+	applyImageResourceDefaults(config, imageResourceDefaults{cpuShares: 512, cpuQuota: 50000, memoryMB: 256, pidsLimit: 256})
+
 	return nil
 }
 
@@ -1675,6 +2607,9 @@ func (d *dockerController) optimizeForUbuntuImage(config *ContainerConfig) error
 		config.Cmd = []string{"/bin/bash"}
 	}
 
+	// Ubuntu常用来跑完整的课程环境（编译工具链、数据库等），给比较宽松的默认值
+	applyImageResourceDefaults(config, imageResourceDefaults{cpuShares: 1024, cpuQuota: 100000, memoryMB: 1024, pidsLimit: 1024})
+
 	return nil
 }
 
@@ -1687,6 +2622,9 @@ func (d *dockerController) optimizeForCentOSImage(config *ContainerConfig) error
 		config.Cmd = []string{"/bin/bash"}
 	}
 
+	// 与Ubuntu同类用途，套用相同的宽松默认值
+	applyImageResourceDefaults(config, imageResourceDefaults{cpuShares: 1024, cpuQuota: 100000, memoryMB: 1024, pidsLimit: 1024})
+
 	return nil
 }
 
@@ -1701,5 +2639,9 @@ func (d *dockerController) optimizeForGenericImage(config *ContainerConfig, imag
 		d.logger.Info("通用镜像默认使用/bin/bash，如果启动失败会自动尝试其他shell")
 	}
 
+	// 镜像类型未知，内存/pids套用applyResourcePolicy本身的默认值（defaultMemoryMB/defaultPidsLimit），
+	// CPU份额设为512
+	applyImageResourceDefaults(config, imageResourceDefaults{cpuShares: 512, cpuQuota: 0, memoryMB: defaultMemoryMB, pidsLimit: defaultPidsLimit})
+
 	return nil
 }
@@ -0,0 +1,286 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// CopyFilesOptions 控制写入容器的文件在tar归档中的元数据，零值即为合理默认值
+type CopyFilesOptions struct {
+	UID   int       // 文件归属的用户ID，默认0（root）
+	GID   int       // 文件归属的组ID，默认0（root）
+	Mode  int64     // 普通文件权限，默认0644；目录固定使用0755
+	Mtime time.Time // 文件修改时间，默认time.Now()
+
+	// PreservePermissions 为true时，自动生成的中间目录条目也使用Mode而非固定的0755
+	PreservePermissions bool
+
+	// SELinuxLabel 非空时，写入完成后对每个涉及的顶层目录执行一次 "chcon -R <label> <dir>"，
+	// 让SELinux强制模式下容器内进程能够访问这些刚写入的文件
+	SELinuxLabel string
+}
+
+// CopyFilesToContainer 把内存中的文件集合写入容器。当所有目标路径共享同一个顶层目录时，
+// 打包成单个tar流通过一次CopyToContainer调用写入（含中间目录条目，支持任意深度嵌套路径）；
+// 当文件分散在互不相关的顶层目录时，退化为逐文件调用，避免把无关目录语义混进同一次请求
+func (d *dockerController) CopyFilesToContainer(ctx context.Context, containerID string, files map[string][]byte) error {
+	return d.CopyFilesToContainerWithOptions(ctx, containerID, files, CopyFilesOptions{})
+}
+
+// CopyFilesToContainerWithOptions 同 CopyFilesToContainer，允许调用方覆盖tar条目的UID/GID/Mode/Mtime，
+// 以及在设置了SELinuxLabel时对写入的目录重新打标
+func (d *dockerController) CopyFilesToContainerWithOptions(ctx context.Context, containerID string, files map[string][]byte, opts CopyFilesOptions) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	d.mu.RLock()
+	containerInfo, exists := d.containers[containerID]
+	d.mu.RUnlock()
+	if !exists {
+		return fmt.Errorf("container %s not found", containerID)
+	}
+
+	if _, groupable := commonTopLevelDir(files); groupable {
+		if err := d.copyFilesGrouped(ctx, containerInfo.DockerID, files, opts); err != nil {
+			return err
+		}
+	} else {
+		d.logger.Debug("文件分布在多个互不相关的顶层目录，退化为逐文件写入: %s", containerID)
+		if err := d.copyFilesIndividually(ctx, containerInfo.DockerID, files, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.SELinuxLabel == "" {
+		return nil
+	}
+	return d.relabelDirectories(ctx, containerInfo.DockerID, opts.SELinuxLabel, destinationDirs(files))
+}
+
+// copyFilesGrouped 把所有文件打包进一个tar流，通过单次CopyToContainer调用写入
+func (d *dockerController) copyFilesGrouped(ctx context.Context, dockerID string, files map[string][]byte, opts CopyFilesOptions) error {
+	buf, err := buildFilesTar(files, opts)
+	if err != nil {
+		return fmt.Errorf("构建tar归档失败: %w", err)
+	}
+
+	if _, err := d.client.CopyToContainer(ctx, dockerID, client.CopyToContainerOptions{
+		DestinationPath: "/",
+		Content:         buf,
+	}); err != nil {
+		return fmt.Errorf("写入容器文件失败: %w", err)
+	}
+	return nil
+}
+
+// copyFilesIndividually 逐个文件调用CopyToContainer，用于文件分散在互不相关顶层目录的场景
+func (d *dockerController) copyFilesIndividually(ctx context.Context, dockerID string, files map[string][]byte, opts CopyFilesOptions) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf, err := buildFilesTar(map[string][]byte{name: files[name]}, opts)
+		if err != nil {
+			return fmt.Errorf("构建tar归档失败: %w", err)
+		}
+		if _, err := d.client.CopyToContainer(ctx, dockerID, client.CopyToContainerOptions{
+			DestinationPath: "/",
+			Content:         buf,
+		}); err != nil {
+			return fmt.Errorf("写入容器文件 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// commonTopLevelDir 判断files中所有目标路径是否共享同一个顶层目录（便于决定能否合并为单次tar调用），
+// 空集合或单文件都视为可合并
+func commonTopLevelDir(files map[string][]byte) (string, bool) {
+	var top string
+	first := true
+	for name := range files {
+		seg := topLevelSegment(name)
+		if first {
+			top = seg
+			first = false
+			continue
+		}
+		if seg != top {
+			return "", false
+		}
+	}
+	return top, true
+}
+
+// topLevelSegment 返回去掉前导'/'后路径的第一段，例如"/app/data/f.txt" -> "app"
+func topLevelSegment(name string) string {
+	trimmed := strings.TrimPrefix(name, "/")
+	if idx := strings.Index(trimmed, "/"); idx >= 0 {
+		return trimmed[:idx]
+	}
+	return trimmed
+}
+
+// destinationDirs 返回files中出现过的所有顶层目录（去重、排序），作为SELinux relabel的递归根
+func destinationDirs(files map[string][]byte) []string {
+	seen := make(map[string]bool)
+	dirs := make([]string, 0, len(files))
+	for name := range files {
+		top := topLevelSegment(name)
+		if top == "" || seen[top] {
+			continue
+		}
+		seen[top] = true
+		dirs = append(dirs, "/"+top)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// relabelDirectories 对每个目录依次执行一次 "chcon -R <label> <dir>"
+func (d *dockerController) relabelDirectories(ctx context.Context, dockerID, label string, dirs []string) error {
+	for _, dir := range dirs {
+		if err := d.execInContainer(ctx, dockerID, []string{"chcon", "-R", label, dir}); err != nil {
+			return fmt.Errorf("relabel %s 失败: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// execInContainer 在dockerID指定的容器内同步执行一条命令直至结束，仅关心命令是否以0退出；
+// 输出被丢弃，调用方如需结果应使用 ExecCommand
+func (d *dockerController) execInContainer(ctx context.Context, dockerID string, cmd []string) error {
+	execResp, err := d.client.ContainerExecCreate(ctx, dockerID, client.ExecCreateOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, client.ExecAttachOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer attachResp.Close()
+
+	if err := d.client.ContainerExecStart(ctx, execResp.ID, client.ExecStartOptions{}); err != nil {
+		return fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	if _, err := io.Copy(io.Discard, attachResp.Reader); err != nil {
+		return fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := d.client.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	if inspect.ExitCode != 0 {
+		return fmt.Errorf("command %v exited with code %d", cmd, inspect.ExitCode)
+	}
+	return nil
+}
+
+// buildFilesTar 把files打包为一个tar归档，tar entry name去掉destPath的前导'/'，
+// 并为每个文件补齐尚未写过的中间目录条目，使CopyToContainer(DestinationPath="/")能正确还原嵌套路径
+func buildFilesTar(files map[string][]byte, opts CopyFilesOptions) (*bytes.Buffer, error) {
+	fileMode := opts.Mode
+	if fileMode == 0 {
+		fileMode = 0644
+	}
+	dirMode := int64(0755)
+	if opts.PreservePermissions && opts.Mode != 0 {
+		dirMode = opts.Mode
+	}
+	mtime := opts.Mtime
+	if mtime.IsZero() {
+		mtime = time.Now()
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	writtenDirs := make(map[string]bool)
+
+	for _, name := range names {
+		entryName := strings.TrimPrefix(name, "/")
+		if entryName == "" {
+			continue
+		}
+
+		if err := writeParentDirs(tw, entryName, writtenDirs, dirMode, opts, mtime); err != nil {
+			return nil, err
+		}
+
+		content := files[name]
+		hdr := &tar.Header{
+			Name:     entryName,
+			Typeflag: tar.TypeReg,
+			Mode:     fileMode,
+			Size:     int64(len(content)),
+			ModTime:  mtime,
+			Uid:      opts.UID,
+			Gid:      opts.GID,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("写入tar头失败: %w", err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, fmt.Errorf("写入tar内容失败: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("关闭tar writer失败: %w", err)
+	}
+	return buf, nil
+}
+
+// writeParentDirs 为entryName补齐尚未写过的中间目录条目（由外到内依次写入），调用方通过written去重
+func writeParentDirs(tw *tar.Writer, entryName string, written map[string]bool, dirMode int64, opts CopyFilesOptions, mtime time.Time) error {
+	var dirs []string
+	for dir := path.Dir(entryName); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		if written[dir] {
+			continue
+		}
+		written[dir] = true
+
+		hdr := &tar.Header{
+			Name:     dir + "/",
+			Typeflag: tar.TypeDir,
+			Mode:     dirMode,
+			ModTime:  mtime,
+			Uid:      opts.UID,
+			Gid:      opts.GID,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("写入目录tar头失败: %w", err)
+		}
+	}
+	return nil
+}
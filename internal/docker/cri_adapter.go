@@ -0,0 +1,555 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/api/types/image"
+	"github.com/moby/moby/api/types/network"
+	"github.com/moby/moby/api/types/registry"
+	"github.com/moby/moby/client"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// CRIConfig CRI后端的连接参数，字段含义与 internal/docker.ContainerdConfig 一致，
+// 但走的是 kubelet 自 dockershim 退场后使用的标准 CRI gRPC（RuntimeService+ImageService），
+// 因此连的是 containerd/CRI-O 暴露的 CRI socket，而不是 containerd 的原生 API
+type CRIConfig struct {
+	// Endpoint CRI运行时的unix socket地址，留空时使用 /run/containerd/containerd.sock
+	// （containerd默认在同一个socket上同时暴露原生API与CRI插件）
+	Endpoint string
+	// Namespace 仅用于本进程内给Pod sandbox打标签做隔离，CRI协议本身没有命名空间概念
+	Namespace string
+}
+
+// criSandbox 记录一个课程容器对应的 Pod sandbox 与其内的唯一容器
+type criSandbox struct {
+	podSandboxID string
+	containerID  string
+}
+
+// criAdapter 用CRI（Container Runtime Interface）替代moby client实现DockerClientInterface，
+// 使 dockerController 可以直接对接 containerd/CRI-O 暴露的标准CRI gRPC端点，而不必像
+// containerdAdapter那样依赖containerd的私有API——这对只想通过kubelet同款协议接入的
+// K8s节点环境更友好。每个"容器"在CRI里实际物化为一个单容器Pod sandbox，
+// 与 dockerController 一个容器一个资源实体的既有假设保持一致
+type criAdapter struct {
+	conn      *grpc.ClientConn
+	runtime   criapi.RuntimeServiceClient
+	image     criapi.ImageServiceClient
+	namespace string
+
+	mu        sync.RWMutex
+	sandboxes map[string]*criSandbox // 容器名(containerName) -> sandbox+容器句柄
+}
+
+// NewCRIAdapter 连接CRI运行时的unix socket，返回一个DockerClientInterface实现
+func NewCRIAdapter(cfg CRIConfig) (DockerClientInterface, error) {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "/run/containerd/containerd.sock"
+	}
+	ns := cfg.Namespace
+	if ns == "" {
+		ns = "kwdb-playground"
+	}
+
+	conn, err := grpc.NewClient("unix://"+endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("连接CRI运行时失败(%s): %w", endpoint, err)
+	}
+
+	return &criAdapter{
+		conn:      conn,
+		runtime:   criapi.NewRuntimeServiceClient(conn),
+		image:     criapi.NewImageServiceClient(conn),
+		namespace: ns,
+		sandboxes: make(map[string]*criSandbox),
+	}, nil
+}
+
+// ContainerCreate 为容器单独创建一个Pod sandbox（RunPodSandbox），再在其中CreateContainer，
+// 但不启动；containerName同时作为sandbox/容器的Metadata.Name，并写入Labels供ListPodSandbox按
+// 标签过滤，承接与dockerClientAdapter/containerdAdapter一致的"按标签恢复"约定
+func (c *criAdapter) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error) {
+	labels := map[string]string{"io.kwdb-playground.namespace": c.namespace}
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+
+	sandboxCfg := &criapi.PodSandboxConfig{
+		Metadata: &criapi.PodSandboxMetadata{Name: containerName, Namespace: c.namespace, Uid: containerName},
+		Hostname: containerName,
+		Labels:   labels,
+	}
+
+	sandboxResp, err := c.runtime.RunPodSandbox(ctx, &criapi.RunPodSandboxRequest{Config: sandboxCfg})
+	if err != nil {
+		return container.CreateResponse{}, fmt.Errorf("创建Pod sandbox失败: %w", err)
+	}
+
+	envs := make([]*criapi.KeyValue, 0, len(config.Env))
+	for _, kv := range config.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			envs = append(envs, &criapi.KeyValue{Key: k, Value: v})
+		}
+	}
+
+	containerCfg := &criapi.ContainerConfig{
+		Metadata: &criapi.ContainerMetadata{Name: containerName},
+		Image:    &criapi.ImageSpec{Image: config.Image},
+		Command:  []string(config.Cmd),
+		Envs:     envs,
+		Labels:   labels,
+		Linux: &criapi.LinuxContainerConfig{
+			SecurityContext: &criapi.LinuxContainerSecurityContext{
+				Privileged: hostConfig != nil && hostConfig.Privileged,
+			},
+			Resources: criResourcesFromHostConfig(hostConfig),
+		},
+	}
+	if config.WorkingDir != "" {
+		containerCfg.WorkingDir = config.WorkingDir
+	}
+
+	createResp, err := c.runtime.CreateContainer(ctx, &criapi.CreateContainerRequest{
+		PodSandboxId:  sandboxResp.PodSandboxId,
+		Config:        containerCfg,
+		SandboxConfig: sandboxCfg,
+	})
+	if err != nil {
+		return container.CreateResponse{}, fmt.Errorf("创建CRI容器失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.sandboxes[containerName] = &criSandbox{podSandboxID: sandboxResp.PodSandboxId, containerID: createResp.ContainerId}
+	c.mu.Unlock()
+
+	return container.CreateResponse{ID: containerName}, nil
+}
+
+// criResourcesFromHostConfig 把moby风格HostConfig里的资源限制翻译成CRI的LinuxContainerResources，
+// hostConfig为nil或未设置的字段保持CRI零值，由运行时自身套用默认值
+func criResourcesFromHostConfig(hostConfig *container.HostConfig) *criapi.LinuxContainerResources {
+	res := &criapi.LinuxContainerResources{}
+	if hostConfig == nil {
+		return res
+	}
+	if hostConfig.Memory > 0 {
+		res.MemoryLimitInBytes = hostConfig.Memory
+	}
+	if hostConfig.CPUShares > 0 {
+		res.CpuShares = hostConfig.CPUShares
+	}
+	if hostConfig.CPUQuota > 0 {
+		res.CpuQuota = hostConfig.CPUQuota
+		res.CpuPeriod = 100000
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		res.PidsLimit = *hostConfig.PidsLimit
+	}
+	return res
+}
+
+// lookupSandbox 按容器名查找其CRI sandbox/容器句柄
+func (c *criAdapter) lookupSandbox(containerName string) (*criSandbox, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	sb, ok := c.sandboxes[containerName]
+	return sb, ok
+}
+
+// ContainerStart 启动ContainerCreate已创建好的CRI容器
+func (c *criAdapter) ContainerStart(ctx context.Context, containerID string, options client.ContainerStartOptions) error {
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	if _, err := c.runtime.StartContainer(ctx, &criapi.StartContainerRequest{ContainerId: sb.containerID}); err != nil {
+		return fmt.Errorf("启动CRI容器失败: %w", err)
+	}
+	return nil
+}
+
+// ContainerStop 优雅停止容器；CRI的StopContainer自带超时后SIGKILL的daemon侧托管，
+// 不需要像containerd后端那样自己只发信号再轮询
+func (c *criAdapter) ContainerStop(ctx context.Context, containerID string, options client.ContainerStopOptions) error {
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return nil
+	}
+	timeout := int64(10)
+	if options.Timeout != nil {
+		timeout = int64(*options.Timeout)
+	}
+	if _, err := c.runtime.StopContainer(ctx, &criapi.StopContainerRequest{ContainerId: sb.containerID, Timeout: timeout}); err != nil {
+		return fmt.Errorf("停止CRI容器失败: %w", err)
+	}
+	return nil
+}
+
+// ContainerRestart 先停止容器再重新启动同一个容器实体，CRI没有单独的restart RPC
+func (c *criAdapter) ContainerRestart(ctx context.Context, containerID string, options client.ContainerStopOptions) error {
+	if err := c.ContainerStop(ctx, containerID, options); err != nil {
+		return err
+	}
+	return c.ContainerStart(ctx, containerID, client.ContainerStartOptions{})
+}
+
+// ContainerRemove 删除容器及其所属的Pod sandbox，一个容器对应一个sandbox，不存在
+// "同一sandbox下还有其他容器"需要保留sandbox的情况
+func (c *criAdapter) ContainerRemove(ctx context.Context, containerID string, options client.ContainerRemoveOptions) error {
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	if _, err := c.runtime.RemoveContainer(ctx, &criapi.RemoveContainerRequest{ContainerId: sb.containerID}); err != nil {
+		return fmt.Errorf("删除CRI容器失败: %w", err)
+	}
+	if _, err := c.runtime.StopPodSandbox(ctx, &criapi.StopPodSandboxRequest{PodSandboxId: sb.podSandboxID}); err != nil {
+		return fmt.Errorf("停止Pod sandbox失败: %w", err)
+	}
+	if _, err := c.runtime.RemovePodSandbox(ctx, &criapi.RemovePodSandboxRequest{PodSandboxId: sb.podSandboxID}); err != nil {
+		return fmt.Errorf("删除Pod sandbox失败: %w", err)
+	}
+	c.mu.Lock()
+	delete(c.sandboxes, containerID)
+	c.mu.Unlock()
+	return nil
+}
+
+// ContainerInspect 把ContainerStatus映射为moby风格的InspectResponse
+func (c *criAdapter) ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return container.InspectResponse{}, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	resp, err := c.runtime.ContainerStatus(ctx, &criapi.ContainerStatusRequest{ContainerId: sb.containerID})
+	if err != nil {
+		return container.InspectResponse{}, fmt.Errorf("查询CRI容器状态失败: %w", err)
+	}
+	status := resp.Status
+
+	state := &container.State{}
+	switch status.State {
+	case criapi.ContainerState_CONTAINER_RUNNING:
+		state.Running = true
+		state.Status = "running"
+	case criapi.ContainerState_CONTAINER_EXITED:
+		state.Status = "exited"
+		state.ExitCode = int(status.ExitCode)
+		state.Dead = status.ExitCode != 0
+	default:
+		state.Status = "created"
+	}
+
+	return container.InspectResponse{
+		ContainerJSONBase: &container.ContainerJSONBase{
+			ID:    containerID,
+			Name:  "/" + containerID,
+			State: state,
+		},
+		Config: &container.Config{
+			Image:  status.Image.GetImage(),
+			Labels: status.Labels,
+		},
+	}, nil
+}
+
+// ContainerList 列出本适配器当前持有的所有Pod sandbox对应的容器
+func (c *criAdapter) ContainerList(ctx context.Context, options client.ContainerListOptions) ([]container.Summary, error) {
+	c.mu.RLock()
+	names := make(map[string]*criSandbox, len(c.sandboxes))
+	for name, sb := range c.sandboxes {
+		names[name] = sb
+	}
+	c.mu.RUnlock()
+
+	summaries := make([]container.Summary, 0, len(names))
+	for name, sb := range names {
+		resp, err := c.runtime.ContainerStatus(ctx, &criapi.ContainerStatusRequest{ContainerId: sb.containerID})
+		if err != nil {
+			continue
+		}
+		state := "created"
+		if resp.Status.State == criapi.ContainerState_CONTAINER_RUNNING {
+			state = "running"
+		}
+		summaries = append(summaries, container.Summary{
+			ID:     name,
+			Names:  []string{"/" + name},
+			Image:  resp.Status.Image.GetImage(),
+			State:  state,
+			Labels: resp.Status.Labels,
+		})
+	}
+	return summaries, nil
+}
+
+// ContainerLogs 读取CRI容器的LogPath；kubelet对每个容器都要求CRI运行时把输出写到该路径下的
+// 结构化日志文件（CRI log format），这里以只读方式打开文件原样交给调用方按行解析；
+// options（Follow/Tail等）不做处理，读到文件当前末尾即结束
+func (c *criAdapter) ContainerLogs(ctx context.Context, containerID string, options client.ContainerLogsOptions) (io.ReadCloser, error) {
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return nil, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	resp, err := c.runtime.ContainerStatus(ctx, &criapi.ContainerStatusRequest{ContainerId: sb.containerID})
+	if err != nil {
+		return nil, fmt.Errorf("查询CRI容器状态失败: %w", err)
+	}
+	if resp.Status.LogPath == "" {
+		return nil, fmt.Errorf("CRI运行时未报告LogPath，无法读取日志")
+	}
+	f, err := os.Open(resp.Status.LogPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开容器日志文件失败: %w", err)
+	}
+	return f, nil
+}
+
+// ImagePull 通过ImageService拉取镜像；返回的空内容流对调用方是安全的no-op，
+// 因为PullImage已经同步完成，调用方读到io.EOF即视为"拉取完成"
+func (c *criAdapter) ImagePull(ctx context.Context, refStr string, options client.ImagePullOptions) (io.ReadCloser, error) {
+	if _, err := c.image.PullImage(ctx, &criapi.PullImageRequest{Image: &criapi.ImageSpec{Image: refStr}}); err != nil {
+		return nil, fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// ImageLoad 在CRI后端下不受支持：CRI协议没有"从本地tar包导入镜像"的RPC，
+// 离线镜像分发只能依赖运行时自身（如 ctr/crictl）预先完成
+func (c *criAdapter) ImageLoad(ctx context.Context, input io.Reader, options client.ImageLoadOptions) (image.LoadResponse, error) {
+	return image.LoadResponse{}, fmt.Errorf("CRI后端暂不支持ImageLoad：离线镜像导入需运行时自身工具完成")
+}
+
+// ImageInspectWithRaw 查询镜像是否已存在于CRI运行时本地
+func (c *criAdapter) ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+	resp, err := c.image.ImageStatus(ctx, &criapi.ImageStatusRequest{Image: &criapi.ImageSpec{Image: imageID}})
+	if err != nil {
+		return image.InspectResponse{}, nil, err
+	}
+	if resp.Image == nil {
+		return image.InspectResponse{}, nil, fmt.Errorf("镜像 %s 不存在", imageID)
+	}
+	return image.InspectResponse{ID: resp.Image.Id}, nil, nil
+}
+
+// ImageRemove 通过ImageService.RemoveImage删除CRI运行时本地镜像，供 ImageManager 按引用计数清理
+func (c *criAdapter) ImageRemove(ctx context.Context, imageID string, options client.ImageRemoveOptions) ([]image.DeleteResponse, error) {
+	if _, err := c.image.RemoveImage(ctx, &criapi.RemoveImageRequest{Image: &criapi.ImageSpec{Image: imageID}}); err != nil {
+		return nil, fmt.Errorf("删除镜像失败: %w", err)
+	}
+	return []image.DeleteResponse{{Untagged: imageID}}, nil
+}
+
+// ContainerCommit 在CRI后端下不受支持：CRI协议不提供"把容器文件系统提交为新镜像"的RPC，
+// 与containerd后端的既有限制一致，课程快照提交仅在docker后端下可用
+func (c *criAdapter) ContainerCommit(ctx context.Context, containerID string, options client.ContainerCommitOptions) (container.CommitResponse, error) {
+	return container.CommitResponse{}, fmt.Errorf("CRI后端暂不支持ContainerCommit：课程快照提交仅在docker后端下可用")
+}
+
+// ContainerStats 在CRI后端下不受支持：CRI的ContainerStats RPC返回的是与dockerd不同的结构化
+// 用量快照，不是ResourceGovernor期望的JSON流，与ContainerCommit的既有限制保持一致
+func (c *criAdapter) ContainerStats(ctx context.Context, containerID string, options client.ContainerStatsOptions) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("CRI后端暂不支持ContainerStats：ResourceGovernor的资源配额统计仅在docker后端下可用")
+}
+
+// RegistryLogin 在CRI后端下不受支持：凭据校验与拉取是kubelet/CRI runtime自己的职责，
+// ImageService RPC里没有与"docker login"对应的独立校验接口
+func (c *criAdapter) RegistryLogin(ctx context.Context, auth registry.AuthConfig) (registry.AuthenticateOKBody, error) {
+	return registry.AuthenticateOKBody{}, fmt.Errorf("CRI后端暂不支持RegistryLogin：镜像仓库登录校验仅在docker后端下可用")
+}
+
+// ContainerExecCreate 对CRI而言创建与附加不可分离（Exec RPC一次性返回一个可连接的流式URL），
+// 这里只记录参数，真正发起Exec请求推迟到ContainerExecAttach
+func (c *criAdapter) ContainerExecCreate(ctx context.Context, containerID string, config client.ExecCreateOptions) (client.ExecCreateResult, error) {
+	if _, ok := c.lookupSandbox(containerID); !ok {
+		return client.ExecCreateResult{}, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	execID := fmt.Sprintf("%s:%s", containerID, strings.Join(config.Cmd, " "))
+	return client.ExecCreateResult{ID: execID}, nil
+}
+
+// ContainerExecAttach 向CRI运行时的流式服务发起Exec请求拿到URL，再用client-go的SPDY
+// executor连上去，把Stdin/Stdout/Stderr桥接到一对io.Pipe，与containerdAdapter的
+// pipeConn语义保持一致；CRI streaming server默认不做鉴权，这里用空*rest.Config即可
+func (c *criAdapter) ContainerExecAttach(ctx context.Context, execID string, config client.ExecAttachOptions) (client.HijackedResponse, error) {
+	containerID, cmd, _ := parseCRIExecID(execID)
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return client.HijackedResponse{}, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	execResp, err := c.runtime.Exec(ctx, &criapi.ExecRequest{
+		ContainerId: sb.containerID,
+		Cmd:         cmd,
+		Tty:         true,
+		Stdin:       true,
+		Stdout:      true,
+		Stderr:      true,
+	})
+	if err != nil {
+		return client.HijackedResponse{}, fmt.Errorf("发起CRI exec请求失败: %w", err)
+	}
+
+	streamURL, err := url.Parse(execResp.Url)
+	if err != nil {
+		return client.HijackedResponse{}, fmt.Errorf("解析CRI streaming URL失败: %w", err)
+	}
+
+	executor, err := remotecommand.NewSPDYExecutor(&restclient.Config{}, "POST", streamURL)
+	if err != nil {
+		return client.HijackedResponse{}, fmt.Errorf("创建CRI流式executor失败: %w", err)
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+			Stdin:  inR,
+			Stdout: outW,
+			Stderr: outW,
+			Tty:    true,
+		})
+		_ = outW.CloseWithError(err)
+	}()
+
+	conn := &criExecConn{r: outR, w: inW}
+	return client.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+}
+
+// parseCRIExecID 把ContainerExecCreate拼出的execID还原成容器ID与命令，与ContainerExecCreate
+// 里的拼接格式一一对应
+func parseCRIExecID(execID string) (containerID string, cmd []string, ok bool) {
+	containerID, cmdStr, found := strings.Cut(execID, ":")
+	if !found {
+		return execID, nil, false
+	}
+	return containerID, strings.Fields(cmdStr), true
+}
+
+// ContainerExecStart CRI的Exec在ContainerExecAttach里已经建立并开始流式传输，这里是no-op
+func (c *criAdapter) ContainerExecStart(ctx context.Context, execID string, config client.ExecStartOptions) error {
+	return nil
+}
+
+// ContainerExecInspect 在CRI后端下不受支持：CRI没有按execID单独查询的RPC，
+// exec进程的生命周期完全绑定在ContainerExecAttach建立的流式连接上，连接关闭即代表退出
+func (c *criAdapter) ContainerExecInspect(ctx context.Context, execID string) (client.ExecInspectResult, error) {
+	return client.ExecInspectResult{}, fmt.Errorf("CRI后端暂不支持ContainerExecInspect：exec状态只能通过流式连接本身判断")
+}
+
+// ContainerExecResize 在CRI后端下不受支持：remotecommand.StreamWithContext未暴露动态resize
+// 的句柄，调整终端大小需要TerminalSizeQueue，这里暂不接入
+func (c *criAdapter) ContainerExecResize(ctx context.Context, execID string, options client.ExecResizeOptions) error {
+	return fmt.Errorf("CRI后端暂不支持ContainerExecResize")
+}
+
+// Ping 用RuntimeService.Version探测CRI端点是否存活，没有等价于Docker daemon /_ping的专用RPC
+func (c *criAdapter) Ping(ctx context.Context) (client.PingResult, error) {
+	if _, err := c.runtime.Version(ctx, &criapi.VersionRequest{}); err != nil {
+		return client.PingResult{}, fmt.Errorf("连接CRI运行时失败: %w", err)
+	}
+	return client.PingResult{}, nil
+}
+
+// CopyToContainer 用"exec tar -x"的经典技巧写入文件系统，CRI没有暴露等价的底层拷贝接口；
+// ExecSync不接受stdin，所以走和ContainerExecAttach同一条Exec+SPDY流式路径灌入tar内容
+func (c *criAdapter) CopyToContainer(ctx context.Context, containerID string, options client.CopyToContainerOptions) (client.CopyToContainerResult, error) {
+	sb, ok := c.lookupSandbox(containerID)
+	if !ok {
+		return client.CopyToContainerResult{}, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	dest := options.DestinationPath
+	if dest == "" {
+		dest = "/"
+	}
+
+	execResp, err := c.runtime.Exec(ctx, &criapi.ExecRequest{
+		ContainerId: sb.containerID,
+		Cmd:         []string{"tar", "-x", "-C", dest, "-f", "-"},
+		Stdin:       true,
+		Stdout:      true,
+		Stderr:      true,
+	})
+	if err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("发起CRI exec请求失败: %w", err)
+	}
+	streamURL, err := url.Parse(execResp.Url)
+	if err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("解析CRI streaming URL失败: %w", err)
+	}
+	executor, err := remotecommand.NewSPDYExecutor(&restclient.Config{}, "POST", streamURL)
+	if err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("创建CRI流式executor失败: %w", err)
+	}
+
+	var stderr strings.Builder
+	if err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  options.Content,
+		Stdout: io.Discard,
+		Stderr: &stderr,
+	}); err != nil {
+		return client.CopyToContainerResult{}, fmt.Errorf("容器内tar解包失败: %w: %s", err, stderr.String())
+	}
+	return client.CopyToContainerResult{}, nil
+}
+
+// Events 在CRI后端下不受支持：标准CRI协议不提供事件推送RPC（kubelet自身通过轮询
+// ContainerStatus感知变化），Supervisor等事件驱动组件在该后端下应退化为轮询
+func (c *criAdapter) Events(ctx context.Context, options client.EventsListOptions) (<-chan events.Message, <-chan error) {
+	msgCh := make(chan events.Message)
+	errCh := make(chan error, 1)
+	errCh <- fmt.Errorf("CRI后端暂不支持Events：标准CRI协议没有事件推送RPC")
+	close(msgCh)
+	return msgCh, errCh
+}
+
+// Close 关闭与CRI运行时端点的gRPC连接
+func (c *criAdapter) Close() error {
+	return c.conn.Close()
+}
+
+// criExecConn 把一对 io.Pipe 适配成 net.Conn，用于在CRI后端上满足HijackedResponse.Conn的
+// 类型约定；remotecommand的流式传输基于SPDY帧而非原始socket，这里只需要Read/Write可用，
+// Deadline等方法按需实现为空操作，与containerdAdapter的pipeConn是同一套思路
+type criExecConn struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (p *criExecConn) Read(b []byte) (int, error)  { return p.r.Read(b) }
+func (p *criExecConn) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *criExecConn) Close() error {
+	_ = p.r.Close()
+	return p.w.Close()
+}
+func (p *criExecConn) LocalAddr() net.Addr                { return criExecAddr{} }
+func (p *criExecConn) RemoteAddr() net.Addr               { return criExecAddr{} }
+func (p *criExecConn) SetDeadline(t time.Time) error      { return nil }
+func (p *criExecConn) SetReadDeadline(t time.Time) error  { return nil }
+func (p *criExecConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// criExecAddr 是 criExecConn 的占位地址，CRI exec streaming 不涉及调用方关心的网络端点
+type criExecAddr struct{}
+
+func (criExecAddr) Network() string { return "cri-exec-stream" }
+func (criExecAddr) String() string  { return "cri-exec-stream" }
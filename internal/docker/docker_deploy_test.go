@@ -9,13 +9,16 @@ import (
 	"io"
 	"net"
 	"net/netip"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/metrics"
 
 	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
 	"github.com/moby/moby/api/types/image"
 	"github.com/moby/moby/api/types/network"
 	"github.com/moby/moby/client"
@@ -32,10 +35,26 @@ type fakeDockerClient struct {
 	inspectFn func(ctx context.Context, containerID string) (container.InspectResponse, error)
 
 	// ContainerExecCreate 控制
-	execCreateFn func(ctx context.Context, containerID string, config client.ExecCreateOptions) (client.ExecCreateResult, error)
+	execCreateFn   func(ctx context.Context, containerID string, config client.ExecCreateOptions) (client.ExecCreateResult, error)
+	execCreateCmds [][]string // 记录每次ContainerExecCreate收到的Cmd，供relabel等断言调用次数/参数
 
 	// ContainerExecAttach 控制
 	execAttachFn func(ctx context.Context, execID string, config client.ExecAttachOptions) (client.HijackedResponse, error)
+
+	// ContainerCreate 控制
+	createFn    func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error)
+	createCalls []createCall // 记录调用历史
+
+	// ContainerRemove 控制
+	removeCalls []string // 记录每次被移除的容器ID
+
+	// ImageInspectWithRaw 控制
+	imageInspectFn func(ctx context.Context, imageID string) (image.InspectResponse, []byte, error)
+}
+
+type createCall struct {
+	ContainerName string
+	Platform      *v1.Platform
 }
 
 type copyCall struct {
@@ -85,6 +104,7 @@ func (f *fakeDockerClient) ContainerInspect(ctx context.Context, containerID str
 }
 
 func (f *fakeDockerClient) ContainerExecCreate(ctx context.Context, containerID string, config client.ExecCreateOptions) (client.ExecCreateResult, error) {
+	f.execCreateCmds = append(f.execCreateCmds, config.Cmd)
 	if f.execCreateFn != nil {
 		return f.execCreateFn(ctx, containerID, config)
 	}
@@ -103,10 +123,16 @@ func (f *fakeDockerClient) ContainerExecAttach(ctx context.Context, execID strin
 	}, nil
 }
 
-// 以下方法为 DockerClientInterface 所需但本测试不使用的空实现
+// ContainerCreate 假实现，记录调用（含平台参数）并委托给自定义函数
 func (f *fakeDockerClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error) {
-	return container.CreateResponse{}, nil
+	f.createCalls = append(f.createCalls, createCall{ContainerName: containerName, Platform: platform})
+	if f.createFn != nil {
+		return f.createFn(ctx, config, hostConfig, networkingConfig, platform, containerName)
+	}
+	return container.CreateResponse{ID: "docker-new"}, nil
 }
+
+// 以下方法为 DockerClientInterface 所需但本测试不使用的空实现（ContainerRemove 另外记录被移除的容器ID）
 func (f *fakeDockerClient) ContainerStart(ctx context.Context, containerID string, options client.ContainerStartOptions) error {
 	return nil
 }
@@ -123,6 +149,7 @@ func (f *fakeDockerClient) ContainerRestart(ctx context.Context, containerID str
 	return nil
 }
 func (f *fakeDockerClient) ContainerRemove(ctx context.Context, containerID string, options client.ContainerRemoveOptions) error {
+	f.removeCalls = append(f.removeCalls, containerID)
 	return nil
 }
 func (f *fakeDockerClient) ContainerList(ctx context.Context, options client.ContainerListOptions) ([]container.Summary, error) {
@@ -134,8 +161,17 @@ func (f *fakeDockerClient) ContainerLogs(ctx context.Context, containerID string
 func (f *fakeDockerClient) ImagePull(ctx context.Context, refStr string, options client.ImagePullOptions) (io.ReadCloser, error) {
 	return nil, nil
 }
+func (f *fakeDockerClient) ImageLoad(ctx context.Context, input io.Reader, options client.ImageLoadOptions) (image.LoadResponse, error) {
+	return image.LoadResponse{}, nil
+}
 func (f *fakeDockerClient) ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
-	return image.InspectResponse{}, nil, nil
+	if f.imageInspectFn != nil {
+		return f.imageInspectFn(ctx, imageID)
+	}
+	return image.InspectResponse{Os: "linux", Architecture: "amd64"}, nil, nil
+}
+func (f *fakeDockerClient) ContainerCommit(ctx context.Context, containerID string, options client.ContainerCommitOptions) (container.CommitResponse, error) {
+	return container.CommitResponse{}, nil
 }
 func (f *fakeDockerClient) ContainerExecStart(ctx context.Context, execID string, config client.ExecStartOptions) error {
 	return nil
@@ -149,16 +185,31 @@ func (f *fakeDockerClient) ContainerExecResize(ctx context.Context, execID strin
 func (f *fakeDockerClient) Ping(ctx context.Context) (client.PingResult, error) {
 	return client.PingResult{}, nil
 }
+func (f *fakeDockerClient) Events(ctx context.Context, options client.EventsListOptions) (<-chan events.Message, <-chan error) {
+	msgCh := make(chan events.Message)
+	errCh := make(chan error)
+	close(msgCh)
+	close(errCh)
+	return msgCh, errCh
+}
+
 func (f *fakeDockerClient) Close() error { return nil }
 
-// newTestController 创建用于测试的 dockerController 实例
+// newTestController 创建用于测试的 dockerController 实例，字段初始化与 newControllerWithClient
+// 保持一致（不启动任何后台goroutine），因为 CreateContainer 等方法无条件依赖
+// imageManager/prewarmPool/resourceGovernor 不为nil
 func newTestController(fakeClient DockerClientInterface) *dockerController {
+	log := logger.NewLogger(logger.ERROR)
 	return &dockerController{
-		client:     fakeClient,
-		containers: make(map[string]*ContainerInfo),
-		cache:      newContainerCache(5 * time.Minute),
-		courseMu:   make(map[string]*sync.Mutex),
-		logger:     logger.NewLogger(logger.ERROR),
+		client:           fakeClient,
+		containers:       make(map[string]*ContainerInfo),
+		cache:            newContainerCache(5 * time.Minute),
+		courseMu:         make(map[string]*sync.Mutex),
+		logger:           log,
+		metricsRecorder:  metrics.DefaultRecorder,
+		imageManager:     newImageManager(fakeClient, log, imageManagerTTL),
+		prewarmPool:      newPrewarmPool(fakeClient, log),
+		resourceGovernor: newResourceGovernor(fakeClient, log, 0, 0),
 	}
 }
 
@@ -231,16 +282,52 @@ func TestCopyFilesToContainer_MultipleFiles(t *testing.T) {
 		t.Fatalf("CopyFilesToContainer failed: %v", err)
 	}
 
-	// 每个文件一次调用
+	// 两个文件共享同一顶层目录 "kaiwudb"，应合并为一次tar流调用
+	if len(fake.copyCalls) != 1 {
+		t.Fatalf("Expected 1 CopyToContainer call, got %d", len(fake.copyCalls))
+	}
+
+	call := fake.copyCalls[0]
+	if call.DestinationPath != "/" {
+		t.Errorf("Expected DestinationPath '/', got '%s'", call.DestinationPath)
+	}
+	if len(call.TarEntries) != 2 {
+		t.Errorf("Expected 2 tar entries, got %d: %v", len(call.TarEntries), keysOf(call.TarEntries))
+	}
+	for dst := range files {
+		name := strings.TrimPrefix(dst, "/")
+		if _, ok := call.TarEntries[name]; !ok {
+			t.Errorf("Expected tar entry %q, got entries: %v", name, keysOf(call.TarEntries))
+		}
+	}
+}
+
+func TestCopyFilesToContainer_UnrelatedTopLevelDirs_FallsBackToPerFile(t *testing.T) {
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+	addTestContainer(ctrl, "cont-1", "docker-abc", "quick-start")
+
+	files := map[string][]byte{
+		"/app/config.json": []byte("app-config"),
+		"/opt/data.bin":    []byte("opt-data"),
+	}
+
+	err := ctrl.CopyFilesToContainer(context.Background(), "cont-1", files)
+	if err != nil {
+		t.Fatalf("CopyFilesToContainer failed: %v", err)
+	}
+
+	// "app" 与 "opt" 是互不相关的顶层目录，应退化为逐文件调用
 	if len(fake.copyCalls) != 2 {
 		t.Fatalf("Expected 2 CopyToContainer calls, got %d", len(fake.copyCalls))
 	}
-
-	// 验证所有调用的 DestinationPath 都是 "/"
 	for i, call := range fake.copyCalls {
 		if call.DestinationPath != "/" {
 			t.Errorf("Call %d: expected DestinationPath '/', got '%s'", i, call.DestinationPath)
 		}
+		if len(call.TarEntries) != 1 {
+			t.Errorf("Call %d: expected 1 tar entry, got %d", i, len(call.TarEntries))
+		}
 	}
 }
 
@@ -361,6 +448,138 @@ func TestCopyFilesToContainer_TarEntryPath(t *testing.T) {
 	}
 }
 
+// ========== CopyFilesToContainerWithOptions SELinux relabel 测试 ==========
+
+// nopExecConn 是ContainerExecAttach假实现在relabel测试中使用的最小net.Conn，
+// Read直接返回io.EOF，模拟exec输出已结束，避免读取阻塞
+type nopExecConn struct{}
+
+func (nopExecConn) Read([]byte) (int, error)         { return 0, io.EOF }
+func (nopExecConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (nopExecConn) Close() error                     { return nil }
+func (nopExecConn) LocalAddr() net.Addr              { return nil }
+func (nopExecConn) RemoteAddr() net.Addr             { return nil }
+func (nopExecConn) SetDeadline(time.Time) error      { return nil }
+func (nopExecConn) SetReadDeadline(time.Time) error  { return nil }
+func (nopExecConn) SetWriteDeadline(time.Time) error { return nil }
+
+func newNopExecAttachFn() func(ctx context.Context, execID string, config client.ExecAttachOptions) (client.HijackedResponse, error) {
+	return func(ctx context.Context, execID string, config client.ExecAttachOptions) (client.HijackedResponse, error) {
+		return client.HijackedResponse{Conn: nopExecConn{}, Reader: bufio.NewReader(strings.NewReader(""))}, nil
+	}
+}
+
+func TestCopyFilesToContainerWithOptions_SELinuxLabel_RelabelsOncePerDestinationDir(t *testing.T) {
+	fake := &fakeDockerClient{execAttachFn: newNopExecAttachFn()}
+	ctrl := newTestController(fake)
+	addTestContainer(ctrl, "cont-1", "docker-abc", "quick-start")
+
+	files := map[string][]byte{
+		"/app/data/config.json": []byte("content"),
+	}
+
+	err := ctrl.CopyFilesToContainerWithOptions(context.Background(), "cont-1", files, CopyFilesOptions{SELinuxLabel: "system_u:object_r:container_file_t:s0"})
+	if err != nil {
+		t.Fatalf("CopyFilesToContainerWithOptions failed: %v", err)
+	}
+
+	if len(fake.execCreateCmds) != 1 {
+		t.Fatalf("Expected exactly 1 chcon exec, got %d: %v", len(fake.execCreateCmds), fake.execCreateCmds)
+	}
+	got := fake.execCreateCmds[0]
+	want := []string{"chcon", "-R", "system_u:object_r:container_file_t:s0", "/app"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected chcon cmd %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected chcon cmd %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestCopyFilesToContainerWithOptions_SELinuxLabel_MultipleTopLevelDirs(t *testing.T) {
+	fake := &fakeDockerClient{execAttachFn: newNopExecAttachFn()}
+	ctrl := newTestController(fake)
+	addTestContainer(ctrl, "cont-1", "docker-abc", "quick-start")
+
+	files := map[string][]byte{
+		"/app/config.json": []byte("app-config"),
+		"/opt/data.bin":    []byte("opt-data"),
+	}
+
+	err := ctrl.CopyFilesToContainerWithOptions(context.Background(), "cont-1", files, CopyFilesOptions{SELinuxLabel: "container_file_t"})
+	if err != nil {
+		t.Fatalf("CopyFilesToContainerWithOptions failed: %v", err)
+	}
+
+	// "app" 与 "opt" 是两个互不相关的顶层目录，各自应该且只应该被relabel一次
+	if len(fake.execCreateCmds) != 2 {
+		t.Fatalf("Expected exactly 2 chcon execs, got %d: %v", len(fake.execCreateCmds), fake.execCreateCmds)
+	}
+}
+
+func TestCopyFilesToContainerWithOptions_NoSELinuxLabel_SkipsRelabel(t *testing.T) {
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+	addTestContainer(ctrl, "cont-1", "docker-abc", "quick-start")
+
+	files := map[string][]byte{"/app/config.json": []byte("content")}
+
+	err := ctrl.CopyFilesToContainerWithOptions(context.Background(), "cont-1", files, CopyFilesOptions{})
+	if err != nil {
+		t.Fatalf("CopyFilesToContainerWithOptions failed: %v", err)
+	}
+	if len(fake.execCreateCmds) != 0 {
+		t.Errorf("Expected no chcon exec without SELinuxLabel, got %d", len(fake.execCreateCmds))
+	}
+}
+
+// ========== CreateContainer 安全选项测试 ==========
+
+func TestCreateContainer_SecurityOptions_SetsSecurityOptAndRelabelsBinds(t *testing.T) {
+	var gotHostConfig *container.HostConfig
+	fake := &fakeDockerClient{
+		imageInspectFn: func(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+			return image.InspectResponse{Os: "linux", Architecture: "amd64"}, nil, nil
+		},
+		createFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error) {
+			gotHostConfig = hostConfig
+			return container.CreateResponse{ID: "docker-new"}, nil
+		},
+	}
+	ctrl := newTestController(fake)
+
+	_, err := ctrl.CreateContainer(context.Background(), "quick-start", &ContainerConfig{
+		Image:   "kwdb:latest",
+		Volumes: map[string]string{"/host/data": "/data"},
+		Security: &SecurityOptions{
+			SELinuxLabel:  "system_u:object_r:container_file_t:s0",
+			RelabelShared: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateContainer failed: %v", err)
+	}
+
+	if gotHostConfig == nil {
+		t.Fatal("Expected ContainerCreate to receive a HostConfig")
+	}
+	if len(gotHostConfig.Binds) != 1 || !strings.HasSuffix(gotHostConfig.Binds[0], ":z") {
+		t.Errorf("Expected bind with ':z' relabel suffix, got %v", gotHostConfig.Binds)
+	}
+	found := false
+	for _, opt := range gotHostConfig.SecurityOpt {
+		if opt == "label:system_u:object_r:container_file_t:s0" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected SecurityOpt to contain SELinux label option, got %v", gotHostConfig.SecurityOpt)
+	}
+}
+
 // ========== GetContainerIP 测试 ==========
 
 func TestGetContainerIP_BridgeNetwork(t *testing.T) {
@@ -726,6 +945,258 @@ func TestCreateInteractiveExec_ExecAttachError(t *testing.T) {
 	}
 }
 
+// ========== ExecSession 测试 ==========
+
+func TestExecSession_Resize_ForwardsHeightAndWidth(t *testing.T) {
+	var gotExecID string
+	var gotOptions client.ExecResizeOptions
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+	ctrl.client = &resizeRecordingClient{
+		fakeDockerClient: fake,
+		resizeFn: func(ctx context.Context, execID string, options client.ExecResizeOptions) error {
+			gotExecID = execID
+			gotOptions = options
+			return nil
+		},
+	}
+
+	session := &ExecSession{execID: "exec-resize", ctrl: ctrl}
+	if err := session.Resize(context.Background(), 40, 120); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if gotExecID != "exec-resize" {
+		t.Errorf("Expected execID 'exec-resize', got '%s'", gotExecID)
+	}
+	if gotOptions.Height != 40 || gotOptions.Width != 120 {
+		t.Errorf("Expected Height=40 Width=120, got Height=%d Width=%d", gotOptions.Height, gotOptions.Width)
+	}
+}
+
+func TestExecSession_Wait_ReturnsExitCodeWhenDone(t *testing.T) {
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+	ctrl.client = &resizeRecordingClient{
+		fakeDockerClient: fake,
+		inspectResults: []client.ExecInspectResult{
+			{Running: true},
+			{Running: false, ExitCode: 7},
+		},
+	}
+
+	session := &ExecSession{execID: "exec-wait", ctrl: ctrl}
+	code, err := session.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if code != 7 {
+		t.Errorf("Expected exit code 7, got %d", code)
+	}
+}
+
+func TestExecSession_Wait_RespectsContextCancellation(t *testing.T) {
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+	ctrl.client = &resizeRecordingClient{
+		fakeDockerClient: fake,
+		inspectResults:   []client.ExecInspectResult{{Running: true}},
+	}
+
+	session := &ExecSession{execID: "exec-wait-cancel", ctrl: ctrl}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := session.Wait(ctx)
+	if err == nil {
+		t.Fatal("Expected error from cancelled context")
+	}
+}
+
+func TestExecSession_CloseStdin_ErrorsWithoutHalfCloseSupport(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	session := &ExecSession{
+		hr: client.HijackedResponse{Conn: clientConn, Reader: bufio.NewReader(clientConn)},
+	}
+	if err := session.CloseStdin(); err == nil {
+		t.Error("net.Pipe connections do not support CloseWrite, expected an error")
+	}
+}
+
+// resizeRecordingClient 包装 fakeDockerClient，为 ExecSession 测试提供 ContainerExecResize/ContainerExecInspect 的可控行为
+type resizeRecordingClient struct {
+	*fakeDockerClient
+	resizeFn       func(ctx context.Context, execID string, options client.ExecResizeOptions) error
+	inspectResults []client.ExecInspectResult
+}
+
+func (c *resizeRecordingClient) ContainerExecResize(ctx context.Context, execID string, options client.ExecResizeOptions) error {
+	if c.resizeFn != nil {
+		return c.resizeFn(ctx, execID, options)
+	}
+	return nil
+}
+
+func (c *resizeRecordingClient) ContainerExecInspect(ctx context.Context, execID string) (client.ExecInspectResult, error) {
+	if len(c.inspectResults) == 0 {
+		return client.ExecInspectResult{}, nil
+	}
+	result := c.inspectResults[0]
+	if len(c.inspectResults) > 1 {
+		c.inspectResults = c.inspectResults[1:]
+	}
+	return result, nil
+}
+
+// ========== CreateContainer 平台选择测试 ==========
+
+func TestCreateContainer_PlatformReachesContainerCreate(t *testing.T) {
+	fake := &fakeDockerClient{
+		imageInspectFn: func(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+			return image.InspectResponse{Os: "linux", Architecture: "arm64", Variant: "v8"}, nil, nil
+		},
+	}
+	ctrl := newTestController(fake)
+
+	_, err := ctrl.CreateContainer(context.Background(), "quick-start", &ContainerConfig{
+		Image:    "kaiwudb/kwbase:latest",
+		Platform: "linux/arm64/v8",
+	})
+	if err != nil {
+		t.Fatalf("CreateContainer failed: %v", err)
+	}
+
+	if len(fake.createCalls) != 1 {
+		t.Fatalf("Expected 1 ContainerCreate call, got %d", len(fake.createCalls))
+	}
+	platform := fake.createCalls[0].Platform
+	if platform == nil {
+		t.Fatal("Expected a non-nil platform to reach ContainerCreate")
+	}
+	if platform.OS != "linux" || platform.Architecture != "arm64" || platform.Variant != "v8" {
+		t.Errorf("Expected linux/arm64/v8, got %s/%s/%s", platform.OS, platform.Architecture, platform.Variant)
+	}
+}
+
+func TestCreateContainer_NoPlatform_PassesNil(t *testing.T) {
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+
+	_, err := ctrl.CreateContainer(context.Background(), "quick-start", &ContainerConfig{
+		Image: "kaiwudb/kwbase:latest",
+	})
+	if err != nil {
+		t.Fatalf("CreateContainer failed: %v", err)
+	}
+
+	if len(fake.createCalls) != 1 {
+		t.Fatalf("Expected 1 ContainerCreate call, got %d", len(fake.createCalls))
+	}
+	if fake.createCalls[0].Platform != nil {
+		t.Errorf("Expected nil platform when config.Platform is empty, got %v", fake.createCalls[0].Platform)
+	}
+}
+
+func TestCreateContainer_InvalidPlatform_ReturnsError(t *testing.T) {
+	fake := &fakeDockerClient{}
+	ctrl := newTestController(fake)
+
+	_, err := ctrl.CreateContainer(context.Background(), "quick-start", &ContainerConfig{
+		Image:    "kaiwudb/kwbase:latest",
+		Platform: "bogus",
+	})
+	if err == nil {
+		t.Fatal("Expected error for malformed platform string")
+	}
+	if len(fake.createCalls) != 0 {
+		t.Errorf("Expected ContainerCreate not to be called for an invalid platform, got %d calls", len(fake.createCalls))
+	}
+}
+
+// TestCreateContainer_PrewarmReuse_AppliesRequestedPortsAndLabels 验证命中 PrewarmPool 的空闲
+// 容器不会被直接当作最终容器使用：prewarm时刻的容器应被移除，实际创建的容器必须带上本次请求的
+// 端口映射与课程/会话标签，而不是prewarm时刻（courseID不同、sessionID为空）烙下的那一份
+func TestCreateContainer_PrewarmReuse_AppliesRequestedPortsAndLabels(t *testing.T) {
+	var gotConfig *container.Config
+	var gotHostConfig *container.HostConfig
+	fake := &fakeDockerClient{
+		imageInspectFn: func(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+			return image.InspectResponse{Os: "linux", Architecture: "amd64"}, nil, nil
+		},
+		createFn: func(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, platform *v1.Platform, containerName string) (container.CreateResponse, error) {
+			gotConfig = config
+			gotHostConfig = hostConfig
+			return container.CreateResponse{ID: "docker-new"}, nil
+		},
+	}
+	ctrl := newTestController(fake)
+
+	// 模拟池中已有一个为另一课程预热、未携带本次请求信息的空闲容器
+	const prewarmDockerID = "prewarm-docker-id"
+	ctrl.prewarmPool.pool["kwdb:latest"] = []*prewarmEntry{{dockerID: prewarmDockerID}}
+
+	info, err := ctrl.CreateContainerForSession(context.Background(), "sql-course", "session-1", &ContainerConfig{
+		Image: "kwdb:latest",
+		Ports: map[string]string{"26257": "15432"},
+		Env:   map[string]string{"FOO": "bar"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("CreateContainerForSession failed: %v", err)
+	}
+
+	// 预热的空闲容器必须被丢弃，不能作为本次请求的最终容器
+	removed := false
+	for _, id := range fake.removeCalls {
+		if id == prewarmDockerID {
+			removed = true
+		}
+	}
+	if !removed {
+		t.Errorf("Expected prewarmed container %s to be removed, removeCalls=%v", prewarmDockerID, fake.removeCalls)
+	}
+	if info.DockerID == prewarmDockerID {
+		t.Errorf("Expected a freshly created container, got the discarded prewarm container ID %s", info.DockerID)
+	}
+
+	if gotConfig == nil || gotHostConfig == nil {
+		t.Fatal("Expected ContainerCreate to be called with the real request config")
+	}
+	if gotHostConfig.PortBindings == nil || len(gotHostConfig.PortBindings) == 0 {
+		t.Errorf("Expected PortBindings for 26257, got %v", gotHostConfig.PortBindings)
+	}
+	if gotConfig.Labels[LabelCourseID] != "sql-course" {
+		t.Errorf("Expected LabelCourseID=sql-course, got %q", gotConfig.Labels[LabelCourseID])
+	}
+	if gotConfig.Labels[LabelSessionID] != "session-1" {
+		t.Errorf("Expected LabelSessionID=session-1, got %q", gotConfig.Labels[LabelSessionID])
+	}
+}
+
+func TestCreateContainer_PlatformMismatch_ReturnsError(t *testing.T) {
+	fake := &fakeDockerClient{
+		imageInspectFn: func(ctx context.Context, imageID string) (image.InspectResponse, []byte, error) {
+			return image.InspectResponse{Os: "linux", Architecture: "amd64"}, nil, nil
+		},
+	}
+	ctrl := newTestController(fake)
+
+	_, err := ctrl.CreateContainer(context.Background(), "quick-start", &ContainerConfig{
+		Image:    "kaiwudb/kwbase:latest",
+		Platform: "linux/arm64",
+	})
+	if err == nil {
+		t.Fatal("Expected an error when requested platform does not match the image")
+	}
+	if !contains(err.Error(), "平台不匹配") {
+		t.Errorf("Expected a platform mismatch error, got: %s", err.Error())
+	}
+	if len(fake.createCalls) != 0 {
+		t.Errorf("Expected ContainerCreate not to be called on platform mismatch, got %d calls", len(fake.createCalls))
+	}
+}
+
 // ========== 辅助函数 ==========
 
 func contains(s, substr string) bool {
@@ -0,0 +1,37 @@
+// Package dockerfake 提供一个仅实现 docker.Controller 部分方法的内存态假实现，
+// 供其他包（如 internal/api）编写单元测试时使用，避免测试依赖真实的Docker守护进程。
+// 未覆盖的方法通过嵌入的nil docker.Controller保留，调用会panic——测试应只练习
+// 自己实际覆盖到的方法，练习到未覆盖方法是测试本身需要先在这里补上假实现
+package dockerfake
+
+import (
+	"context"
+
+	"kwdb-playground/internal/docker"
+)
+
+// Controller 是 docker.Controller 的内存态假实现，仅覆盖 ListContainers/Close，
+// 其余方法委托给嵌入的nil接口（调用即panic），按需在这里逐步补充
+type Controller struct {
+	docker.Controller
+	Containers []*docker.ContainerInfo
+	ListErr    error
+}
+
+// New 创建一个以 containers 为固定列表的假Controller
+func New(containers ...*docker.ContainerInfo) *Controller {
+	return &Controller{Containers: containers}
+}
+
+// ListContainers 返回构造时固定的容器列表，或ListErr非nil时返回该错误
+func (f *Controller) ListContainers(ctx context.Context) ([]*docker.ContainerInfo, error) {
+	if f.ListErr != nil {
+		return nil, f.ListErr
+	}
+	return f.Containers, nil
+}
+
+// Close 假实现无需释放任何资源
+func (f *Controller) Close() error {
+	return nil
+}
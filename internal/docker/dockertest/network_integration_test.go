@@ -0,0 +1,139 @@
+// Package dockertest contains opt-in integration tests for internal/docker that exercise
+// a real Docker daemon instead of the fakeDockerClient used by the package's unit tests.
+//
+// Borrowing the pattern from moby/libnetwork's libnetwork_test.go, these tests spin up
+// disposable real networks/containers to catch bugs the unit-level fake can't: multiple
+// user-defined networks, real endpoint enumeration, and SetNetworkName's interaction with
+// GetContainerIP across them. They only run when PLAYGROUND_INTEGRATION=1 is set and skip
+// cleanly when no Docker daemon is reachable, so `go test ./...` stays hermetic by default.
+package dockertest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"kwdb-playground/internal/docker"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// testImage 是两个测试容器使用的镜像，选择它是因为体积小、在大多数环境里都已缓存或可快速拉取
+const testImage = "busybox:latest"
+
+// appLabelValue 必须与 docker 包内部的 appNameValue 保持一致，loadExistingContainers 靠它
+// 筛选出本应用创建的容器（该常量未导出，此处按其当前取值硬编码，变更需同步）
+const appLabelValue = "kwdb-playground"
+
+// newRealDockerClient 连接本机Docker守护进程，守护进程不可达时返回错误交由调用方 Skip
+func newRealDockerClient(ctx context.Context) (*client.Client, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("创建Docker客户端失败: %w", err)
+	}
+	if _, err := cli.Ping(ctx, client.PingOptions{}); err != nil {
+		cli.Close()
+		return nil, fmt.Errorf("无法连接到Docker守护进程: %w", err)
+	}
+	return cli, nil
+}
+
+// TestGetContainerIP_PrefersConfiguredNetwork_RealDaemon 对应单元测试
+// TestGetContainerIP_PrefersConfiguredNetwork，但用真实守护进程创建的网络/容器驱动，
+// 覆盖fakeDockerClient无法触及的真实端点枚举行为
+func TestGetContainerIP_PrefersConfiguredNetwork_RealDaemon(t *testing.T) {
+	if os.Getenv("PLAYGROUND_INTEGRATION") != "1" {
+		t.Skip("仅在设置 PLAYGROUND_INTEGRATION=1 时运行，需要本机可用的Docker守护进程")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	cli, err := newRealDockerClient(ctx)
+	if err != nil {
+		t.Skipf("跳过：%v", err)
+	}
+	defer cli.Close()
+
+	netName := fmt.Sprintf("kwdb-playground-net-test-%d", time.Now().UnixNano())
+	netResp, err := cli.NetworkCreate(ctx, netName, client.NetworkCreateOptions{Driver: "bridge"})
+	if err != nil {
+		t.Fatalf("创建测试网络失败: %v", err)
+	}
+	defer cli.NetworkRemove(ctx, netResp.ID, client.NetworkRemoveOptions{})
+
+	if err := pullImageIfMissing(ctx, cli, testImage); err != nil {
+		t.Skipf("跳过：拉取测试镜像 %s 失败: %v", testImage, err)
+	}
+
+	containerName := fmt.Sprintf("kwdb-playground-dockertest-%d", time.Now().UnixNano())
+	createResp, err := cli.ContainerCreate(ctx, client.ContainerCreateOptions{
+		Config: &container.Config{
+			Image: testImage,
+			Cmd:   []string{"sleep", "60"},
+			Labels: map[string]string{
+				docker.LabelAppName:  appLabelValue,
+				docker.LabelCourseID: "dockertest",
+			},
+		},
+		Name: containerName,
+	})
+	if err != nil {
+		t.Fatalf("创建测试容器失败: %v", err)
+	}
+	defer cli.ContainerRemove(ctx, createResp.ID, client.ContainerRemoveOptions{Force: true})
+
+	// 加入自定义网络，容器此时同时连接默认bridge和 netName 两个网络，
+	// 正是 SetNetworkName + 多网络端点选择需要覆盖的场景
+	if _, err := cli.NetworkConnect(ctx, netResp.ID, client.NetworkConnectOptions{Container: createResp.ID}); err != nil {
+		t.Fatalf("连接测试网络失败: %v", err)
+	}
+
+	if _, err := cli.ContainerStart(ctx, createResp.ID, client.ContainerStartOptions{}); err != nil {
+		t.Fatalf("启动测试容器失败: %v", err)
+	}
+
+	inspectResult, err := cli.ContainerInspect(ctx, createResp.ID, client.ContainerInspectOptions{})
+	if err != nil {
+		t.Fatalf("检查测试容器失败: %v", err)
+	}
+	endpoint, ok := inspectResult.Container.NetworkSettings.Networks[netName]
+	if !ok {
+		t.Fatalf("测试容器未加入网络 %s", netName)
+	}
+	wantIP := endpoint.IPAddress.String()
+
+	ctrl, err := docker.NewController()
+	if err != nil {
+		t.Fatalf("创建Controller失败: %v", err)
+	}
+	defer ctrl.Close()
+	ctrl.SetNetworkName(netName)
+
+	gotIP, err := ctrl.GetContainerIP(ctx, containerName)
+	if err != nil {
+		t.Fatalf("GetContainerIP失败: %v", err)
+	}
+	if gotIP != wantIP {
+		t.Errorf("期望GetContainerIP优先返回配置网络 %s 下的地址 %s，实际得到 %s", netName, wantIP, gotIP)
+	}
+}
+
+// pullImageIfMissing 仅在镜像尚不存在本地时拉取，避免重复测试运行反复打满网络
+func pullImageIfMissing(ctx context.Context, cli *client.Client, imageName string) error {
+	if _, err := cli.ImageInspect(ctx, imageName); err == nil {
+		return nil
+	}
+
+	reader, err := cli.ImagePull(ctx, imageName, client.ImagePullOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(io.Discard, reader)
+	return err
+}
@@ -0,0 +1,257 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/moby/moby/client"
+)
+
+// ExecOptions 描述一次交互式 exec 调用的参数，零值表示"沿用容器自身配置"
+type ExecOptions struct {
+	// Tty 是否分配伪终端；WebSocket终端场景下应为true，resize才有意义
+	Tty bool
+	// WorkingDir 为空时沿用容器 Config.WorkingDir
+	WorkingDir string
+	// Env 会追加在容器已有环境变量之后（与 prepareExecEnvironment 补齐的交互式变量叠加）
+	Env []string
+	// User 为空时沿用容器 Config.User
+	User string
+}
+
+// ExecPhase 描述 ExecCommandInteractive 生命周期中的一个阶段
+type ExecPhase string
+
+const (
+	// ExecPhaseStarted exec进程已创建并开始执行
+	ExecPhaseStarted ExecPhase = "started"
+	// ExecPhaseExited exec进程已退出，ExitCode/DurationMs有效
+	ExecPhaseExited ExecPhase = "exited"
+)
+
+// ExecLifecycleEvent 描述 ExecCommandInteractive 的一次生命周期状态变化，经
+// TerminalManagerInterface.BroadcastExecLifecycleEvent 转发给已附加的终端会话，
+// 使客户端即使在退出码为0的正常结束时也能感知到命令已经结束，而不是只在非0退出码时报错
+type ExecLifecycleEvent struct {
+	ExecID     string    `json:"execId"`
+	Phase      ExecPhase `json:"phase"`
+	ExitCode   int       `json:"exitCode,omitempty"`
+	DurationMs int64     `json:"durationMs,omitempty"`
+}
+
+// ExecSession 是一次活跃的容器内交互式执行
+// ExecCommandInteractive 会阻塞直到命令结束，execID 要到那时才随返回值交还，因而调用方无法在会话期间
+// 调整TTY尺寸；Exec 把附加后的连接与 execID 一并交还调用方，由调用方（如WebSocket终端）
+// 自行驱动读写循环并在收到 resize 控制帧时调用 Resize
+type ExecSession struct {
+	execID string
+	ctrl   *dockerController
+	hr     client.HijackedResponse
+
+	closeOnce sync.Once
+}
+
+// Read 读取容器输出（stdout/stderr，TTY模式下二者合流）
+func (s *ExecSession) Read(p []byte) (int, error) {
+	return s.hr.Reader.Read(p)
+}
+
+// Write 向容器标准输入写入
+func (s *ExecSession) Write(p []byte) (int, error) {
+	return s.hr.Conn.Write(p)
+}
+
+// Resize 调整本次 exec 对应TTY的行列数
+func (s *ExecSession) Resize(ctx context.Context, height, width uint) error {
+	return s.ctrl.ContainerExecResize(ctx, s.execID, client.ExecResizeOptions{Height: height, Width: width})
+}
+
+// ExitCode 查询本次 exec 的执行状态；running为true时exec仍在运行，code无意义
+func (s *ExecSession) ExitCode(ctx context.Context) (code int, running bool, err error) {
+	inspect, err := s.ctrl.client.ContainerExecInspect(ctx, s.execID)
+	if err != nil {
+		return 0, false, err
+	}
+	return inspect.ExitCode, inspect.Running, nil
+}
+
+// Close 关闭底层连接；是否连带终止容器内进程取决于该进程是否会在标准输入关闭/TTY挂起时退出
+func (s *ExecSession) Close() error {
+	s.closeOnce.Do(func() {
+		s.hr.Close()
+	})
+	return nil
+}
+
+// CloseStdin 半关闭标准输入的写入端，通知容器内进程输入已结束，同时保留 Read 继续读取输出；
+// 连接不支持半关闭（底层 net.Conn 未实现 CloseWrite）时返回错误
+func (s *ExecSession) CloseStdin() error {
+	closer, ok := s.hr.Conn.(interface{ CloseWrite() error })
+	if !ok {
+		return fmt.Errorf("underlying connection does not support half-close")
+	}
+	return closer.CloseWrite()
+}
+
+// Wait 阻塞直到exec进程退出或ctx被取消，返回退出码；ctx取消时返回ctx.Err()
+func (s *ExecSession) Wait(ctx context.Context) (int, error) {
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		inspect, err := s.ctrl.client.ContainerExecInspect(ctx, s.execID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to inspect exec: %w", err)
+		}
+		if !inspect.Running {
+			return inspect.ExitCode, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ttyControlBytes 把常见信号映射为TTY下由终端驱动转发给前台进程组的控制字符；
+// exec API本身不提供向一次exec发送任意信号的接口，TTY模式下这是唯一能让对端感知到的手段，
+// 因此只支持有对应控制字符的信号，其余一律报错而不是假装成功
+var ttyControlBytes = map[string]byte{
+	"SIGINT":  0x03, // Ctrl-C
+	"SIGQUIT": 0x1c, // Ctrl-\
+	"SIGTSTP": 0x1a, // Ctrl-Z
+}
+
+// Signal 通过向TTY写入对应的控制字符来模拟向exec进程发送信号；仅支持 ttyControlBytes 中列出的信号
+func (s *ExecSession) Signal(sig string) error {
+	b, ok := ttyControlBytes[sig]
+	if !ok {
+		return fmt.Errorf("signal %s cannot be delivered over a TTY exec session", sig)
+	}
+	_, err := s.hr.Conn.Write([]byte{b})
+	return err
+}
+
+// Exec 在容器中创建并启动一次交互式执行，返回可读写、可调整TTY尺寸的 ExecSession
+func (d *dockerController) Exec(ctx context.Context, containerID string, cmd []string, opts ExecOptions) (*ExecSession, error) {
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("command cannot be empty")
+	}
+
+	containerInfo, inspect, err := d.getContainerInfo(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	env, user, workingDir := d.prepareExecEnvironment(inspect, opts.Tty)
+	if len(opts.Env) > 0 {
+		env = append(env, opts.Env...)
+	}
+	if opts.User != "" {
+		user = opts.User
+	}
+	if opts.WorkingDir != "" {
+		workingDir = opts.WorkingDir
+	}
+
+	execConfig := client.ExecCreateOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+		AttachStdin:  true,
+		TTY:          opts.Tty,
+		WorkingDir:   workingDir,
+		Env:          env,
+		User:         user,
+		DetachKeys:   "ctrl-p,ctrl-q",
+	}
+
+	execResp, err := d.client.ContainerExecCreate(ctx, containerInfo.DockerID, execConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, client.ExecAttachOptions{TTY: opts.Tty})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach exec: %w", err)
+	}
+
+	if err := d.client.ContainerExecStart(ctx, execResp.ID, client.ExecStartOptions{}); err != nil {
+		attachResp.Close()
+		return nil, fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	return &ExecSession{execID: execResp.ID, ctrl: d, hr: attachResp}, nil
+}
+
+// InteractiveExecResult 是一次TTY交互式exec创建并附加后的原始连接，不做 ExecSession 的生命周期封装，
+// 供调用方需要直接持有 net.Conn/Reader 自行驱动读写循环的场景（例如既有WebSocket终端代码）
+type InteractiveExecResult struct {
+	ExecID string
+	Conn   net.Conn
+	Reader *bufio.Reader
+}
+
+// CreateInteractiveExec 在容器中创建并启动一次TTY交互式执行，返回原始的附加连接。
+// 与 Exec 的区别在于它固定使用TTY且不包装 ExecSession，调整大小、退出码查询等需调用方自行
+// 通过 execID 调用 ContainerExecResize/ContainerExecInspect 完成
+func (d *dockerController) CreateInteractiveExec(ctx context.Context, containerID string, cmd []string) (*InteractiveExecResult, error) {
+	if containerID == "" {
+		return nil, fmt.Errorf("container ID cannot be empty")
+	}
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("command cannot be empty")
+	}
+
+	containerInfo, inspect, err := d.getContainerInfo(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	env, user, workingDir := d.prepareExecEnvironment(inspect, true)
+
+	execConfig := client.ExecCreateOptions{
+		Cmd:          cmd,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		TTY:          true,
+		WorkingDir:   workingDir,
+		Env:          env,
+		User:         user,
+		DetachKeys:   "ctrl-p,ctrl-q",
+	}
+
+	execResp, err := d.client.ContainerExecCreate(ctx, containerInfo.DockerID, execConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attachResp, err := d.client.ContainerExecAttach(ctx, execResp.ID, client.ExecAttachOptions{TTY: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach exec: %w", err)
+	}
+
+	if err := d.client.ContainerExecStart(ctx, execResp.ID, client.ExecStartOptions{TTY: true}); err != nil {
+		attachResp.Close()
+		return nil, fmt.Errorf("failed to start exec: %w", err)
+	}
+
+	return &InteractiveExecResult{ExecID: execResp.ID, Conn: attachResp.Conn, Reader: attachResp.Reader}, nil
+}
+
+// InspectExec 查询execID对应exec进程是否仍在运行及退出码，是ExecSession.ExitCode的无封装版本，
+// 供只持有 InteractiveExecResult（没有 ExecSession）的调用方（如WebSocket终端的Shell回退探测）使用
+func (d *dockerController) InspectExec(ctx context.Context, execID string) (running bool, exitCode int, err error) {
+	inspect, err := d.client.ContainerExecInspect(ctx, execID)
+	if err != nil {
+		return false, 0, err
+	}
+	return inspect.Running, inspect.ExitCode, nil
+}
@@ -0,0 +1,145 @@
+package docker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/metrics"
+
+	"github.com/moby/moby/client"
+)
+
+// imageRefCount 记录单个镜像当前被多少课程持有，以及引用归零后的过期时间
+type imageRefCount struct {
+	refs      int
+	zeroSince time.Time // refs降为0的时刻，仅在refs==0时有意义
+}
+
+// ImageManager 按镜像维护跨课程的引用计数：多个课程复用同一镜像时只需为第一个引用者付一次
+// 拉取成本，引用归零后也不立即删除，而是保留ttl时间窗口等待下一次课程复用命中缓存，用磁盘空间
+// 换"删了又立刻重新拉取"的冷启动抖动；真正的清理交给后台sweep循环，按TTL到期逐个删除
+type ImageManager struct {
+	client          DockerClientInterface
+	logger          *logger.Logger
+	metricsRecorder metrics.Recorder
+	ttl             time.Duration
+
+	mu     sync.Mutex
+	counts map[string]*imageRefCount
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// imageManagerSweepInterval 后台清理循环的扫描周期
+const imageManagerSweepInterval = time.Minute
+
+// newImageManager 创建镜像引用计数管理器，不会自动启动后台清理goroutine，调用方需显式调用 Start
+func newImageManager(cli DockerClientInterface, log *logger.Logger, ttl time.Duration) *ImageManager {
+	return &ImageManager{
+		client:          cli,
+		logger:          log,
+		metricsRecorder: metrics.DefaultRecorder,
+		ttl:             ttl,
+		counts:          make(map[string]*imageRefCount),
+	}
+}
+
+// Start 启动后台清理循环；重复调用是安全的，第二次调用会先停止上一轮循环
+func (m *ImageManager) Start(ctx context.Context) {
+	m.Stop()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+
+	m.wg.Add(1)
+	go m.sweepLoop(runCtx)
+}
+
+// Stop 停止后台清理循环并等待其退出
+func (m *ImageManager) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	m.wg.Wait()
+}
+
+func (m *ImageManager) sweepLoop(ctx context.Context) {
+	defer m.wg.Done()
+	ticker := time.NewTicker(imageManagerSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sweep(ctx)
+		}
+	}
+}
+
+// Acquire 为 image 增加一次引用，返回true表示该镜像此前已被其他课程持有（缓存命中，
+// 调用方可以跳过一次PullImage），false表示这是该镜像当前唯一的引用（缓存未命中）
+func (m *ImageManager) Acquire(image string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rc, exists := m.counts[image]
+	if !exists {
+		m.counts[image] = &imageRefCount{refs: 1}
+		m.metricsRecorder.ImageCacheResult(image, false)
+		return false
+	}
+	rc.refs++
+	m.metricsRecorder.ImageCacheResult(image, true)
+	return true
+}
+
+// Release 释放一次 image 的引用；引用归零时不立即清理，只记录归零时刻，真正的删除留给sweep
+// 在ttl窗口过后执行
+func (m *ImageManager) Release(image string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rc, exists := m.counts[image]
+	if !exists {
+		return
+	}
+	rc.refs--
+	if rc.refs <= 0 {
+		rc.refs = 0
+		rc.zeroSince = time.Now()
+	}
+}
+
+// sweep 删除引用归零且已超过ttl的镜像；ImageRemove失败时保留计数条目，留给下一轮重试
+func (m *ImageManager) sweep(ctx context.Context) {
+	now := time.Now()
+	var expired []string
+
+	m.mu.Lock()
+	for image, rc := range m.counts {
+		if rc.refs == 0 && !rc.zeroSince.IsZero() && now.Sub(rc.zeroSince) >= m.ttl {
+			expired = append(expired, image)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, image := range expired {
+		if _, err := m.client.ImageRemove(ctx, image, client.ImageRemoveOptions{Force: false}); err != nil {
+			m.logger.Debug("清理空闲镜像 %s 失败，留待下一轮重试: %v", image, err)
+			continue
+		}
+		m.logger.Info("镜像 %s 引用计数归零超过 %s，已清理", image, m.ttl)
+		m.mu.Lock()
+		delete(m.counts, image)
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,281 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// PullPhase 一个镜像层在拉取生命周期中所处的阶段
+type PullPhase string
+
+const (
+	PullPhaseWaiting     PullPhase = "waiting"
+	PullPhaseDownloading PullPhase = "downloading"
+	PullPhaseExtracting  PullPhase = "extracting"
+	PullPhaseComplete    PullPhase = "complete"
+)
+
+// PullEvent 一次结构化的镜像拉取进度事件。LayerID为空表示整体事件（最终的Error、全部完成，
+// 或Message携带的"Pulling from…"/"Digest:"/"Status:"一类无层ID的终端文案）
+type PullEvent struct {
+	ImageName       string    `json:"imageName"`
+	LayerID         string    `json:"layerId,omitempty"`
+	Phase           PullPhase `json:"phase"`
+	Current         int64     `json:"current"`
+	Total           int64     `json:"total"`
+	OverallPercent  float64   `json:"overallPercent"`
+	BytesDownloaded int64     `json:"bytesDownloaded"`
+	BytesTotal      int64     `json:"bytesTotal"`
+	ETASeconds      float64   `json:"etaSeconds,omitempty"`
+	Message         string    `json:"message,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Done            bool      `json:"done,omitempty"`
+}
+
+// pullEventRateLimit 同一层两次事件上报之间的最短间隔，约等于每层每秒最多10条
+const pullEventRateLimit = 100 * time.Millisecond
+
+// layerProgress 单个镜像层的累计进度，由 streamPullEvents 在读取JSON流时维护
+type layerProgress struct {
+	current  int64
+	total    int64
+	phase    PullPhase
+	lastSent time.Time
+}
+
+// pullRateEMAWindow 字节/秒速率估算的EMA平滑窗口，约5秒后新的瞬时速率基本替换掉旧值
+const pullRateEMAWindow = 5 * time.Second
+
+// pullRateTracker 对累计下载字节数的时间序列做指数滑动平均，平滑瞬时速率抖动后用于估算ETA
+type pullRateTracker struct {
+	lastBytes int64
+	lastTime  time.Time
+	emaRate   float64 // 字节/秒
+}
+
+// sample 记录一次新的累计下载字节数观测，返回平滑后的字节/秒速率
+func (t *pullRateTracker) sample(now time.Time, bytesDownloaded int64) float64 {
+	if t.lastTime.IsZero() {
+		t.lastTime = now
+		t.lastBytes = bytesDownloaded
+		return t.emaRate
+	}
+	elapsed := now.Sub(t.lastTime).Seconds()
+	if elapsed <= 0 {
+		return t.emaRate
+	}
+	instantRate := float64(bytesDownloaded-t.lastBytes) / elapsed
+	alpha := 1 - math.Exp(-elapsed/pullRateEMAWindow.Seconds())
+	t.emaRate += alpha * (instantRate - t.emaRate)
+	t.lastBytes = bytesDownloaded
+	t.lastTime = now
+	return t.emaRate
+}
+
+// PullImageEvents 拉取镜像并返回结构化、按层聚合的进度事件channel。
+// 内部消费Docker daemon的JSON行进度流，按层聚合current/total并计算整体百分比，
+// 对每层的上报限速到约10/s，ctx.Done()会中止底层HTTP流并尽快关闭返回的channel
+func (d *dockerController) PullImageEvents(ctx context.Context, imageName string, platform *v1.Platform) (<-chan PullEvent, error) {
+	options, err := d.buildImagePullOptions(imageName, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.ImagePull(ctx, imageName, options)
+	if err != nil {
+		return nil, fmt.Errorf("拉取镜像失败: %w", err)
+	}
+
+	out := make(chan PullEvent, 16)
+	raw := make(chan PullEvent, 16)
+	stopWatch := make(chan struct{})
+
+	go func() {
+		// ctx被取消时主动关闭响应体，让下面的streamPullEvents读循环尽快以io错误退出，
+		// 而不是一直阻塞到daemon自然结束这次拉取
+		select {
+		case <-ctx.Done():
+			resp.Close()
+		case <-stopWatch:
+		}
+	}()
+
+	go func() {
+		defer close(raw)
+		defer close(stopWatch)
+		defer resp.Close()
+		streamPullEvents(imageName, resp, raw)
+	}()
+
+	go func() {
+		defer close(out)
+		for ev := range raw {
+			if d.terminalManager != nil {
+				d.terminalManager.BroadcastPullEvent(ev)
+			}
+			out <- ev
+		}
+	}()
+
+	return out, nil
+}
+
+// streamPullEvents 从r读取Docker daemon风格的JSON行进度流，按层聚合并写入out；
+// r被关闭（正常结束或ctx取消导致的提前关闭）时退出
+func streamPullEvents(imageName string, r io.Reader, out chan<- PullEvent) {
+	layers := make(map[string]*layerProgress)
+	decoder := json.NewDecoder(r)
+	var rate pullRateTracker
+
+	emit := func(ev PullEvent) {
+		ev.ImageName = imageName
+		ev.OverallPercent = overallPercent(layers)
+		ev.BytesDownloaded, ev.BytesTotal = aggregateBytes(layers)
+		ev.ETASeconds = estimateETASeconds(&rate, ev.BytesDownloaded, ev.BytesTotal)
+		out <- ev
+	}
+
+	for {
+		var msg struct {
+			ID             string `json:"id"`
+			Status         string `json:"status"`
+			Error          string `json:"error"`
+			ProgressDetail struct {
+				Current int64 `json:"current"`
+				Total   int64 `json:"total"`
+			} `json:"progressDetail"`
+		}
+
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				emit(PullEvent{Phase: PullPhaseComplete, Done: true})
+				return
+			}
+			// 流被ctx取消提前关闭，或daemon输出了一行非法JSON：两种情况都发出一条带错误的终止事件并结束读取
+			emit(PullEvent{Error: fmt.Sprintf("拉取进度流中断: %v", err), Done: true})
+			return
+		}
+
+		if msg.Error != "" {
+			emit(PullEvent{LayerID: msg.ID, Error: msg.Error, Done: true})
+			return
+		}
+
+		if msg.ID == "" {
+			// 不带层ID的汇总行（如"Pulling from ..."、"Digest: ..."、"Status: Downloaded newer image..."），
+			// 不单独建层，但作为独立的Message事件转发，便于前端按终端文案单独渲染一行
+			if msg.Status != "" {
+				emit(PullEvent{Message: msg.Status})
+			}
+			continue
+		}
+
+		phase, handled := statusToPullPhase(msg.Status)
+		if !handled {
+			continue
+		}
+
+		layer, ok := layers[msg.ID]
+		if !ok {
+			layer = &layerProgress{phase: PullPhaseWaiting}
+			layers[msg.ID] = layer
+		}
+		phaseChanged := layer.phase != phase
+		layer.phase = phase
+		if msg.ProgressDetail.Total > 0 {
+			layer.total = msg.ProgressDetail.Total
+		}
+		switch phase {
+		case PullPhaseDownloading:
+			layer.current = msg.ProgressDetail.Current
+		case PullPhaseExtracting:
+			layer.current = msg.ProgressDetail.Current
+		case PullPhaseComplete:
+			layer.current = layer.total
+		}
+
+		// 限速：同一层处于同一阶段时，多次"Downloading"/"Extracting"进度行只保留约10条/秒；
+		// 阶段切换（Waiting->Downloading->Extracting->Complete）始终不被限速丢弃
+		if !phaseChanged && time.Since(layer.lastSent) < pullEventRateLimit {
+			continue
+		}
+		layer.lastSent = time.Now()
+
+		emit(PullEvent{
+			LayerID: msg.ID,
+			Phase:   phase,
+			Current: layer.current,
+			Total:   layer.total,
+		})
+	}
+}
+
+// statusToPullPhase 把Docker daemon的自由格式status文案映射为PullPhase，
+// handled为false表示这条status与本次聚合无关（调用方应跳过）
+func statusToPullPhase(status string) (PullPhase, bool) {
+	switch status {
+	case "Waiting", "Pulling fs layer":
+		return PullPhaseWaiting, true
+	case "Downloading", "Verifying Checksum", "Download complete":
+		return PullPhaseDownloading, true
+	case "Extracting":
+		return PullPhaseExtracting, true
+	case "Pull complete", "Already exists":
+		return PullPhaseComplete, true
+	default:
+		return "", false
+	}
+}
+
+// overallPercent 按"下载+解压各占50%"对所有层加权汇总出整体百分比
+func overallPercent(layers map[string]*layerProgress) float64 {
+	var weightedCurrent, weightedTotal float64
+	for _, l := range layers {
+		if l.total <= 0 {
+			continue
+		}
+		weightedTotal += float64(l.total) * 2
+		switch l.phase {
+		case PullPhaseDownloading:
+			weightedCurrent += float64(l.current)
+		case PullPhaseExtracting:
+			weightedCurrent += float64(l.total) + float64(l.current)
+		case PullPhaseComplete:
+			weightedCurrent += float64(l.total) * 2
+		}
+	}
+	if weightedTotal == 0 {
+		return 0
+	}
+	percent := weightedCurrent / weightedTotal * 100
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// aggregateBytes 汇总所有已知层当前已下载字节数与预期总字节数，供前端展示总进度条
+func aggregateBytes(layers map[string]*layerProgress) (downloaded, total int64) {
+	for _, l := range layers {
+		downloaded += l.current
+		total += l.total
+	}
+	return downloaded, total
+}
+
+// estimateETASeconds 用rate记录的本次观测更新EMA速率，据此估算剩余字节下载完成所需秒数；
+// 速率尚未建立或已下载完成时返回0，表示ETA不可用
+func estimateETASeconds(rate *pullRateTracker, downloaded, total int64) float64 {
+	bytesPerSec := rate.sample(time.Now(), downloaded)
+	remaining := total - downloaded
+	if bytesPerSec <= 0 || remaining <= 0 {
+		return 0
+	}
+	return float64(remaining) / bytesPerSec
+}
@@ -0,0 +1,106 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeJSONMessageStream 拼出一段最小但真实的Docker daemon镜像拉取JSON行流：
+// 一层从Waiting到Downloading到Extracting到Pull complete，外加两行无层ID的汇总行
+const fakeJSONMessageStream = `
+{"status":"Waiting","id":"layer1"}
+{"status":"Downloading","id":"layer1","progressDetail":{"current":50,"total":100}}
+{"status":"Downloading","id":"layer1","progressDetail":{"current":100,"total":100}}
+{"status":"Extracting","id":"layer1","progressDetail":{"current":100,"total":100}}
+{"status":"Pull complete","id":"layer1"}
+{"status":"Digest: sha256:deadbeef"}
+{"status":"Status: Downloaded newer image for alpine:latest"}
+`
+
+func TestStreamPullEventsAggregatesLayerProgress(t *testing.T) {
+	out := make(chan PullEvent, 32)
+	streamPullEvents("alpine:latest", strings.NewReader(fakeJSONMessageStream), out)
+	close(out)
+
+	var events []PullEvent
+	for ev := range out {
+		events = append(events, ev)
+	}
+
+	if len(events) == 0 {
+		t.Fatal("expected at least one pull event")
+	}
+
+	last := events[len(events)-1]
+	if !last.Done {
+		t.Errorf("expected final event to be Done, got %+v", last)
+	}
+	if last.Error != "" {
+		t.Errorf("expected no error, got %q", last.Error)
+	}
+
+	var sawDownloading, sawExtracting, sawComplete bool
+	for _, ev := range events {
+		if ev.ImageName != "alpine:latest" {
+			t.Errorf("expected ImageName to be propagated on every event, got %q", ev.ImageName)
+		}
+		switch ev.Phase {
+		case PullPhaseDownloading:
+			sawDownloading = true
+		case PullPhaseExtracting:
+			sawExtracting = true
+		case PullPhaseComplete:
+			if ev.LayerID == "layer1" {
+				sawComplete = true
+			}
+		}
+	}
+	if !sawDownloading || !sawExtracting || !sawComplete {
+		t.Errorf("expected to observe downloading, extracting and complete phases; got %+v", events)
+	}
+
+	final := events[len(events)-2]
+	if final.OverallPercent != 100 {
+		t.Errorf("expected overall percent to reach 100 once the only layer completes, got %v", final.OverallPercent)
+	}
+}
+
+func TestStreamPullEventsPropagatesError(t *testing.T) {
+	out := make(chan PullEvent, 8)
+	streamPullEvents("broken:latest", strings.NewReader(`{"status":"Downloading","id":"layer1","progressDetail":{"current":1,"total":10}}
+{"error":"manifest unknown"}
+`), out)
+	close(out)
+
+	var last PullEvent
+	for ev := range out {
+		last = ev
+	}
+	if last.Error != "manifest unknown" {
+		t.Errorf("expected propagated daemon error, got %+v", last)
+	}
+	if !last.Done {
+		t.Errorf("expected error event to be terminal")
+	}
+}
+
+func TestStatusToPullPhase(t *testing.T) {
+	tests := []struct {
+		status      string
+		wantPhase   PullPhase
+		wantHandled bool
+	}{
+		{"Waiting", PullPhaseWaiting, true},
+		{"Downloading", PullPhaseDownloading, true},
+		{"Extracting", PullPhaseExtracting, true},
+		{"Pull complete", PullPhaseComplete, true},
+		{"Already exists", PullPhaseComplete, true},
+		{"some unrelated status", "", false},
+	}
+	for _, tt := range tests {
+		phase, handled := statusToPullPhase(tt.status)
+		if handled != tt.wantHandled || phase != tt.wantPhase {
+			t.Errorf("statusToPullPhase(%q) = (%q, %v), want (%q, %v)", tt.status, phase, handled, tt.wantPhase, tt.wantHandled)
+		}
+	}
+}
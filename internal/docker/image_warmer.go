@@ -0,0 +1,173 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/registry"
+)
+
+// ImageWarmer 在进程启动阶段并发探测课程引用的镜像是否可达，把结果缓存为 ImageAvailability
+// 供 GET /api/images/status 查询，避免第一个进入课程的学生等到容器创建时才触发一次完整拉取。
+// 探测复用 registry.Manager 已有的 RegistrySource.Probe（HEAD manifest，不等同于真正拉取）；
+// Prepull 为 true 时，探测不到任何可用源的镜像会再调用一次 EnsureImageAvailable 提前拉取
+type ImageWarmer struct {
+	controller      Controller
+	registryManager *registry.Manager
+	logger          *logger.Logger
+	concurrency     int
+	prepull         bool
+
+	mu         sync.RWMutex
+	status     map[string]*ImageAvailability
+	mirrorUsed map[string]string // image -> 上一次成功探测/拉取所使用的镜像源名称
+}
+
+// NewImageWarmer 创建镜像预热器；concurrency 非正数时退化为1（保证至少探测一个镜像/次）
+func NewImageWarmer(controller Controller, registryManager *registry.Manager, logger *logger.Logger, concurrency int, prepull bool) *ImageWarmer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ImageWarmer{
+		controller:      controller,
+		registryManager: registryManager,
+		logger:          logger,
+		concurrency:     concurrency,
+		prepull:         prepull,
+		status:          make(map[string]*ImageAvailability),
+		mirrorUsed:      make(map[string]string),
+	}
+}
+
+// Warm 对images去重后，用不超过concurrency个并发worker逐一探测；单个镜像的探测/预拉取失败
+// 只记录在该镜像自己的状态里，不影响其余镜像继续探测。ctx取消时尚未开始的探测被跳过
+func (w *ImageWarmer) Warm(ctx context.Context, images []string) {
+	unique := dedupeImages(images)
+	if len(unique) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	for _, image := range unique {
+		image := image
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			w.checkOne(ctx, image)
+		}()
+	}
+	wg.Wait()
+	w.logger.Info("镜像预热完成，共探测 %d 个镜像", len(unique))
+}
+
+// checkOne 探测单个镜像：先并发探测所有已配置镜像源的可达性，取响应最快的可用源；
+// 全部不可达且开启了Prepull时，再尝试一次EnsureImageAvailable的完整拉取兜底
+func (w *ImageWarmer) checkOne(ctx context.Context, image string) {
+	start := time.Now()
+	results := w.registryManager.CheckAvailability(ctx, image)
+
+	bestIdx := -1
+	for i, r := range results {
+		if !r.Available {
+			continue
+		}
+		if bestIdx == -1 || r.LatencyMs < results[bestIdx].LatencyMs {
+			bestIdx = i
+		}
+	}
+
+	status := &ImageAvailability{ImageName: image, CheckedAt: time.Now()}
+
+	if bestIdx >= 0 {
+		status.Available = true
+		status.ResponseTime = results[bestIdx].LatencyMs
+		status.Message = fmt.Sprintf("可通过镜像源 %s 拉取", results[bestIdx].Source)
+		w.setStatus(image, status, results[bestIdx].Source)
+		return
+	}
+
+	status.Message = "所有已配置镜像源均不可达"
+	if !w.prepull {
+		w.setStatus(image, status, "")
+		return
+	}
+
+	resolved, err := w.controller.EnsureImageAvailable(ctx, w.registryManager.List(), image, nil)
+	if err != nil {
+		status.Message = fmt.Sprintf("所有镜像源均不可达，预拉取也失败: %v", err)
+		w.setStatus(image, status, "")
+		return
+	}
+	status.Available = true
+	status.ResponseTime = time.Since(start).Milliseconds()
+	mirror := w.mirrorForResolvedRef(image, resolved)
+	status.Message = fmt.Sprintf("所有镜像源探测不可达，已预拉取成功（来自 %s）", mirrorDisplayName(mirror))
+	w.setStatus(image, status, mirror)
+}
+
+// mirrorForResolvedRef 反查 resolvedRef 是由哪个 RegistrySource.Resolve(image) 产生的，
+// 用于把 EnsureImageAvailable 兜底拉取成功时实际生效的镜像源记录下来
+func (w *ImageWarmer) mirrorForResolvedRef(image, resolvedRef string) string {
+	for _, src := range w.registryManager.List() {
+		if ref, err := src.Resolve(image); err == nil && ref == resolvedRef {
+			return src.Name()
+		}
+	}
+	return ""
+}
+
+func mirrorDisplayName(mirror string) string {
+	if mirror == "" {
+		return "未知镜像源"
+	}
+	return mirror
+}
+
+func (w *ImageWarmer) setStatus(image string, status *ImageAvailability, mirror string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status[image] = status
+	if mirror != "" {
+		w.mirrorUsed[image] = mirror
+	}
+}
+
+// Status 返回目前已探测过的全部镜像状态，按镜像名排序，供 GET /api/images/status 输出
+func (w *ImageWarmer) Status() []ImageAvailability {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	out := make([]ImageAvailability, 0, len(w.status))
+	for _, s := range w.status {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ImageName < out[j].ImageName })
+	return out
+}
+
+// dedupeImages 去重并跳过空字符串，不保证返回顺序（Warm本身是并发探测，顺序无意义）
+func dedupeImages(images []string) []string {
+	seen := make(map[string]struct{}, len(images))
+	out := make([]string, 0, len(images))
+	for _, img := range images {
+		if img == "" {
+			continue
+		}
+		if _, ok := seen[img]; ok {
+			continue
+		}
+		seen[img] = struct{}{}
+		out = append(out, img)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package docker
+
+import "testing"
+
+func TestDedupeImages(t *testing.T) {
+	got := dedupeImages([]string{"alpine:latest", "", "kwdb/kwdb:latest", "alpine:latest"})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 unique images, got %v", got)
+	}
+	seen := map[string]bool{}
+	for _, img := range got {
+		seen[img] = true
+	}
+	if !seen["alpine:latest"] || !seen["kwdb/kwdb:latest"] {
+		t.Errorf("unexpected dedupe result: %v", got)
+	}
+}
+
+func TestMirrorDisplayName(t *testing.T) {
+	if got := mirrorDisplayName(""); got != "未知镜像源" {
+		t.Errorf("mirrorDisplayName(\"\") = %q, want 未知镜像源", got)
+	}
+	if got := mirrorDisplayName("ghcr"); got != "ghcr" {
+		t.Errorf("mirrorDisplayName(\"ghcr\") = %q, want ghcr", got)
+	}
+}
+
+func TestImageWarmerStatusSortedByImageName(t *testing.T) {
+	w := NewImageWarmer(nil, nil, nil, 2, false)
+	w.setStatus("zeta:latest", &ImageAvailability{ImageName: "zeta:latest", Available: true}, "docker.io")
+	w.setStatus("alpha:latest", &ImageAvailability{ImageName: "alpha:latest", Available: false}, "")
+
+	got := w.Status()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 statuses, got %d", len(got))
+	}
+	if got[0].ImageName != "alpha:latest" || got[1].ImageName != "zeta:latest" {
+		t.Errorf("expected statuses sorted by image name, got %+v", got)
+	}
+}
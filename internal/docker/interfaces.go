@@ -4,12 +4,16 @@ import (
 	"context"
 	"io"
 
-	"github.com/moby/moby/api/types"
 	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/events"
 	"github.com/moby/moby/api/types/image"
 	"github.com/moby/moby/api/types/network"
+	registrytypes "github.com/moby/moby/api/types/registry"
 	"github.com/moby/moby/client"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"kwdb-playground/internal/metrics"
+	"kwdb-playground/internal/registry"
 )
 
 // DockerClientInterface Docker客户端接口
@@ -23,13 +27,26 @@ type DockerClientInterface interface {
 	ContainerList(ctx context.Context, options client.ContainerListOptions) ([]container.Summary, error)
 	ContainerLogs(ctx context.Context, containerID string, options client.ContainerLogsOptions) (io.ReadCloser, error)
 	ImagePull(ctx context.Context, refStr string, options client.ImagePullOptions) (io.ReadCloser, error)
+	ImageLoad(ctx context.Context, input io.Reader, options client.ImageLoadOptions) (image.LoadResponse, error)
 	ImageInspectWithRaw(ctx context.Context, imageID string) (image.InspectResponse, []byte, error)
-	ContainerExecCreate(ctx context.Context, containerID string, config container.ExecOptions) (container.ExecCreateResponse, error)
-	ContainerExecAttach(ctx context.Context, execID string, config container.ExecAttachOptions) (client.HijackedResponse, error)
-	ContainerExecStart(ctx context.Context, execID string, config container.ExecStartOptions) error
-	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
-	ContainerExecResize(ctx context.Context, execID string, options client.ContainerResizeOptions) error
-	Ping(ctx context.Context) (types.Ping, error)
+	ContainerCommit(ctx context.Context, containerID string, options client.ContainerCommitOptions) (container.CommitResponse, error)
+	ContainerExecCreate(ctx context.Context, containerID string, config client.ExecCreateOptions) (client.ExecCreateResult, error)
+	ContainerExecAttach(ctx context.Context, execID string, config client.ExecAttachOptions) (client.HijackedResponse, error)
+	ContainerExecStart(ctx context.Context, execID string, config client.ExecStartOptions) error
+	ContainerExecInspect(ctx context.Context, execID string) (client.ExecInspectResult, error)
+	ContainerExecResize(ctx context.Context, execID string, options client.ExecResizeOptions) error
+	Ping(ctx context.Context) (client.PingResult, error)
+	CopyToContainer(ctx context.Context, containerID string, options client.CopyToContainerOptions) (client.CopyToContainerResult, error)
+	// Events 订阅Docker事件流，供 Supervisor 监听容器的 start/die 等生命周期事件
+	Events(ctx context.Context, options client.EventsListOptions) (<-chan events.Message, <-chan error)
+	// ImageRemove 删除本地镜像，供 ImageManager 在引用计数归零且TTL到期后清理空间
+	ImageRemove(ctx context.Context, imageID string, options client.ImageRemoveOptions) ([]image.DeleteResponse, error)
+	// ContainerStats 订阅容器的cgroup资源用量流（CPU/内存/pids/网络/块IO），供 ResourceGovernor
+	// 按课程聚合统计；options.Stream为true时持续推送，调用方读到io.EOF视为容器已退出
+	ContainerStats(ctx context.Context, containerID string, options client.ContainerStatsOptions) (io.ReadCloser, error)
+	// RegistryLogin 向指定registry校验一组凭据，成功时daemon可能返回IdentityToken供后续拉取复用，
+	// 供 dockerController.Login 实现类似"docker login"的鉴权校验与凭据缓存
+	RegistryLogin(ctx context.Context, auth registrytypes.AuthConfig) (registrytypes.AuthenticateOKBody, error)
 	Close() error
 }
 
@@ -48,6 +65,14 @@ type ImagePullProgress struct {
 type TerminalManagerInterface interface {
 	BroadcastImagePullProgress(progress ImagePullProgress)
 	GetActiveSessionCount() int
+	// BroadcastContainerSupervisorEvent 向已附加到containerID的终端会话广播一次容器监督事件
+	// （容器退出/自动重启等），phase取值对应 SupervisorPhase
+	BroadcastContainerSupervisorEvent(containerID, phase, message string)
+	// BroadcastPullEvent 向已连接的终端会话广播一次结构化、按层聚合的镜像拉取进度事件
+	BroadcastPullEvent(event PullEvent)
+	// BroadcastExecLifecycleEvent 向已附加到containerID的终端会话广播一次exec生命周期事件
+	// （started/exited），见 ExecCommandInteractive
+	BroadcastExecLifecycleEvent(containerID string, event ExecLifecycleEvent)
 }
 
 // Controller Docker控制器接口
@@ -56,6 +81,8 @@ type Controller interface {
 	CreateContainer(ctx context.Context, courseID string, config *ContainerConfig) (*ContainerInfo, error)
 	// CreateContainerWithProgress 创建容器并支持镜像拉取进度回调
 	CreateContainerWithProgress(ctx context.Context, courseID string, config *ContainerConfig, progressCallback ImagePullProgressCallback) (*ContainerInfo, error)
+	// CreateContainerForSession 创建容器并将其绑定到指定会话，容器命名与清理均按会话隔离
+	CreateContainerForSession(ctx context.Context, courseID, sessionID string, config *ContainerConfig, progressCallback ImagePullProgressCallback) (*ContainerInfo, error)
 	// StartContainer 启动容器
 	StartContainer(ctx context.Context, containerID string) error
 	// StopContainer 停止容器
@@ -66,24 +93,61 @@ type Controller interface {
 	RemoveContainer(ctx context.Context, containerID string) error
 	// GetContainer 获取容器信息
 	GetContainer(ctx context.Context, containerID string) (*ContainerInfo, error)
+	// GetContainerIP 获取容器在目标网络中的IP，优先使用SetNetworkName配置的网络，否则退化为bridge
+	GetContainerIP(ctx context.Context, containerID string) (string, error)
+	// SetNetworkName 配置GetContainerIP优先选择的网络名
+	SetNetworkName(name string)
 	// ListContainers 列出所有容器
 	ListContainers(ctx context.Context) ([]*ContainerInfo, error)
 	// GetContainerLogs 获取容器日志
 	GetContainerLogs(ctx context.Context, containerID string, tail int, follow bool) (io.ReadCloser, error)
 	// PullImage 拉取镜像
 	PullImage(ctx context.Context, imageName string) error
+	// PullImageEvents 拉取镜像并返回结构化、按层聚合的进度事件channel，event既转发给调用方
+	// 也通过WebSocket广播给已连接的终端会话；ctx取消会中止底层拉取流并关闭返回的channel
+	PullImageEvents(ctx context.Context, imageName string, platform *v1.Platform) (<-chan PullEvent, error)
+	// EnsureImageAvailable 按镜像源优先级依次尝试拉取镜像，返回实际生效的镜像引用，支持离线 tar 包兜底
+	EnsureImageAvailable(ctx context.Context, mirrors []registry.RegistrySource, imageName string, progressCallback ImagePullProgressCallback) (string, error)
+	// SetRegistryAuthProvider 在默认的Login缓存/~/.docker/config.json鉴权链前插入一个额外的凭据Provider，
+	// 典型用法是注入来自playground配置文件(config.RegistryEntry)的静态per-registry用户名/密码
+	SetRegistryAuthProvider(provider RegistryAuthProvider)
+	// Login 向指定registry校验一组凭据并缓存结果供后续拉取复用，成功时返回daemon的状态文案，
+	// 镜像docker CLI的"docker login"行为
+	Login(ctx context.Context, host, username, password string) (string, error)
+	// Logout 清除 Login 为指定registry缓存的凭据
+	Logout(host string)
+	// SelectBestMirror 并发探测 mirrors 对 imageName 的可达性与延迟，返回本次探测延迟最低的可达源，探测结果同时计入历史EWMA统计
+	SelectBestMirror(ctx context.Context, mirrors []registry.RegistrySource, imageName string) (registry.RegistrySource, error)
+	// LoadImageFromFile 从本地 tar 包导入镜像
+	LoadImageFromFile(ctx context.Context, tarPath string) (string, error)
+	// CommitContainer 将容器当前文件系统提交为一个新镜像（打上 repoTag 标签），返回生效的镜像引用，用于课程快照
+	CommitContainer(ctx context.Context, containerID, repoTag string) (string, error)
 	// ExecCommand 在容器中执行命令
 	ExecCommand(ctx context.Context, containerID string, cmd []string) (string, error)
-	// ExecCommandInteractive 在容器中执行交互式命令
-	// 支持实时双向通信，与docker exec -it功能完全一致
-	ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer) error
+	// ExecCommandInteractive 在容器中执行交互式命令，支持实时双向通信；opts.Tty为true时
+	// 与docker exec -it功能完全一致（stdout/stderr合流），为false时按非TTY的多路复用帧
+	// 分别转发到stdoutWriter/stderrWriter。返回值execID供调用方（如需要）把后续的
+	// ResizeTerminal精确指向这次exec，而不是依赖调用顺序猜测
+	ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer, opts ExecOptions) (execID string, err error)
+
+	// Exec 创建并启动一次交互式执行，返回的 ExecSession 支持调用方自行驱动读写循环与TTY resize，
+	// 适合WebSocket终端这类需要在会话期间响应控制帧（而不是一次性阻塞到命令结束）的场景
+	Exec(ctx context.Context, containerID string, cmd []string, opts ExecOptions) (*ExecSession, error)
+
+	// CreateInteractiveExec 创建并附加一次TTY交互式执行，返回原始连接而非 ExecSession 封装
+	CreateInteractiveExec(ctx context.Context, containerID string, cmd []string) (*InteractiveExecResult, error)
+
+	// InspectExec 查询execID对应exec进程是否仍在运行及退出码；CreateInteractiveExec成功附加
+	// 并不保证Shell真的起来了（比如命令不存在时，错误只会体现为进程几乎立即以非0码退出），
+	// 调用方应在附加后短暂探测一次，而不是仅凭attach阶段是否报错来判断Shell是否可用
+	InspectExec(ctx context.Context, execID string) (running bool, exitCode int, err error)
 
 	// ResizeTerminal 调整终端大小
 	// 用于支持终端窗口大小变化时的动态调整
 	ResizeTerminal(ctx context.Context, execID string, height, width uint) error
 
 	// ContainerExecResize 调整执行实例的终端大小
-	ContainerExecResize(ctx context.Context, execID string, options client.ContainerResizeOptions) error
+	ContainerExecResize(ctx context.Context, execID string, options client.ExecResizeOptions) error
 
 	// IsContainerRunning 检查容器是否正在运行
 	IsContainerRunning(containerID string) (bool, error)
@@ -100,6 +164,40 @@ type Controller interface {
 	// 停止并删除所有kwdb-playground相关的容器
 	CleanupAllContainers(ctx context.Context) (*CleanupResult, error)
 
+	// AdoptLegacy 迁移没有 LabelAppName 标签的历史容器：把名称解析得到的课程ID写入本地
+	// adoption sidecar 文件，使 loadExistingContainers 后续可以不再依赖名称解析。返回本次迁移的容器数量
+	AdoptLegacy(ctx context.Context) (int, error)
+
+	// CopyFilesToContainer 把内存中的文件集合写入容器，目标路径共享同一顶层目录时合并为单次tar流调用
+	CopyFilesToContainer(ctx context.Context, containerID string, files map[string][]byte) error
+
 	// Close 关闭控制器
 	Close() error
+
+	// SetMetricsRecorder 替换默认的 Prometheus Recorder，nil 时保持不变
+	SetMetricsRecorder(r metrics.Recorder)
+
+	// StartSupervisor 启动容器监督：订阅Docker事件流，按policy自动重启异常退出的容器，
+	// 状态机持久化到statePath。重复调用会先停止上一轮监督再重新开始
+	StartSupervisor(ctx context.Context, policy RestartPolicy, statePath string)
+
+	// StopSupervisor 停止容器监督并等待正在进行的重启goroutine退出，应在 srv.Shutdown 之前调用
+	StopSupervisor()
+
+	// SupervisorEvents 订阅指定容器的监督事件，供 /api/containers/:id/events SSE使用；
+	// 返回的cancel函数必须在调用方结束监听时调用
+	SupervisorEvents(containerID string) (<-chan SupervisorEvent, func())
+
+	// Prewarm 为 config.Image 预热 n 个空闲的已停止容器，提前把镜像拉到本地；课程创建容器时
+	// 若 PrewarmPool 中有同镜像的空闲条目，仍会将其移除后按请求的真实端口/卷/标签重新创建
+	// （见 createContainer），省下的是镜像拉取等待而非ContainerCreate本身，courseID 仅用于日志与计量
+	Prewarm(ctx context.Context, courseID string, config *ContainerConfig, n int) error
+
+	// SubscribeStateChanges 订阅指定课程的容器状态变化（由Docker事件流驱动），供WebSocket终端
+	// /前端据此推送更新而不必轮询；返回的cancel函数必须在调用方结束监听时调用
+	SubscribeStateChanges(courseID string) (<-chan StateChange, func())
+
+	// GetCourseUsage 返回指定课程当前的聚合资源用量快照（由 ResourceGovernor 统计），
+	// ok为false表示该课程尚无正在被统计的容器
+	GetCourseUsage(courseID string) (CourseUsage, bool)
 }
@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"strconv"
+	"time"
+)
+
+// Docker 标签：标记由 kwdb-playground 创建的容器，取代脆弱的"从容器名称反解析课程ID"逻辑
+// loadExistingContainers 优先按 LabelAppName 过滤后读取 LabelCourseID/LabelSessionID/LabelOneTime/
+// LabelCreatedAt，cleanupCourseContainers/cleanupCourseSessionContainers 同样优先按
+// LabelCourseID/LabelSessionID 过滤；仅对没有这些标签的历史容器（旧版本创建）才退化到名称解析
+const (
+	LabelAppName   = "kwdb-playground.app"           // 固定取值 appNameValue，用于筛选本应用创建的容器
+	LabelCourseID  = "kwdb-playground.course-id"     // 课程ID
+	LabelSessionID = "kwdb-playground.session-id"    // 会话ID，课程级隔离（未按会话创建）时不设置该标签
+	LabelVersion   = "kwdb-playground.label-version" // 标签结构版本号，便于未来迁移时区分
+	LabelCreatedAt = "kwdb-playground.created-at"    // RFC3339 格式的创建时间
+	LabelOneTime   = "kwdb-playground.one-time"      // "true"/"false"，对应 ContainerConfig.IsOneTimeExecution
+
+	appNameValue        = "kwdb-playground"
+	currentLabelVersion = "1"
+)
+
+// containerLabels 组装创建容器时写入的标签集合
+func containerLabels(courseID, sessionID string, isOneTime bool) map[string]string {
+	labels := map[string]string{
+		LabelAppName:   appNameValue,
+		LabelCourseID:  courseID,
+		LabelVersion:   currentLabelVersion,
+		LabelCreatedAt: time.Now().UTC().Format(time.RFC3339),
+		LabelOneTime:   strconv.FormatBool(isOneTime),
+	}
+	if sessionID != "" {
+		labels[LabelSessionID] = sessionID
+	}
+	return labels
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moby/moby/api/types/image"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// parsePlatform 把形如 "os/arch" 或 "os/arch/variant" 的平台标识解析为 v1.Platform，
+// 空字符串返回 nil，表示不限定平台（沿用daemon默认行为）
+func parsePlatform(s string) (*v1.Platform, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("无效的平台标识 %q，期望格式为 os/arch 或 os/arch/variant", s)
+	}
+
+	platform := &v1.Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		platform.Variant = parts[2]
+	}
+	if platform.OS == "" || platform.Architecture == "" {
+		return nil, fmt.Errorf("无效的平台标识 %q，os 和 arch 不能为空", s)
+	}
+	return platform, nil
+}
+
+// checkPlatformMatch 校验镜像实际平台是否满足请求的平台，requested为nil时不做任何限制
+func checkPlatformMatch(requested *v1.Platform, imageInfo *image.InspectResponse) error {
+	if requested == nil {
+		return nil
+	}
+
+	if imageInfo.Os != requested.OS || imageInfo.Architecture != requested.Architecture {
+		return fmt.Errorf("镜像平台不匹配: 请求 %s，镜像实际平台为 %s/%s", platformString(requested), imageInfo.Os, imageInfo.Architecture)
+	}
+	if requested.Variant != "" && imageInfo.Variant != "" && requested.Variant != imageInfo.Variant {
+		return fmt.Errorf("镜像平台不匹配: 请求变体 %s，镜像实际变体为 %s", requested.Variant, imageInfo.Variant)
+	}
+	return nil
+}
+
+// platformString 把 v1.Platform 格式化为 "os/arch[/variant]" 形式，便于错误信息展示
+func platformString(p *v1.Platform) string {
+	if p.Variant != "" {
+		return fmt.Sprintf("%s/%s/%s", p.OS, p.Architecture, p.Variant)
+	}
+	return fmt.Sprintf("%s/%s", p.OS, p.Architecture)
+}
@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/metrics"
+
+	"github.com/moby/moby/api/types/container"
+)
+
+// prewarmEntry 是 PrewarmPool 中一个已创建（Created态，未Start）的空闲容器
+type prewarmEntry struct {
+	dockerID string
+}
+
+// PrewarmPool 按镜像维护一批已创建但未启动的空闲容器。fill()只知道镜像本身，不知道后续
+// 哪个课程/会话会用到它，而端口映射、卷绑定、课程/会话标签这些字段一旦ContainerCreate完成
+// 就无法再修改，所以createContainer acquire到空闲条目后仍必须整个丢弃、按这次请求的真实配置
+// 重新创建，不能当作最终容器直接Start——见createContainer里acquire调用点的说明
+type PrewarmPool struct {
+	client          DockerClientInterface
+	logger          *logger.Logger
+	metricsRecorder metrics.Recorder
+
+	mu   sync.Mutex
+	pool map[string][]*prewarmEntry // key: 镜像引用
+}
+
+// newPrewarmPool 创建预热容器池
+func newPrewarmPool(cli DockerClientInterface, log *logger.Logger) *PrewarmPool {
+	return &PrewarmPool{
+		client:          cli,
+		logger:          log,
+		metricsRecorder: metrics.DefaultRecorder,
+		pool:            make(map[string][]*prewarmEntry),
+	}
+}
+
+// fill 为 config.Image 创建 n 个Created态的空闲容器并加入池中；courseID用于容器命名并写入 LabelCourseID 标签，
+// 池中的容器在被acquire前不绑定任何会话
+func (p *PrewarmPool) fill(ctx context.Context, courseID string, config *ContainerConfig, n int) error {
+	platform, err := parsePlatform(config.Platform)
+	if err != nil {
+		return err
+	}
+
+	env := make([]string, 0, len(config.Env))
+	for key, value := range config.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("kwdb-playground-prewarm-%s-%d-%d", courseID, time.Now().UnixNano(), i)
+
+		containerConfig := &container.Config{
+			Image:  config.Image,
+			Env:    env,
+			Cmd:    config.Cmd,
+			Labels: containerLabels(courseID, "", config.IsOneTimeExecution),
+		}
+		hostConfig := &container.HostConfig{}
+		applyResourcePolicy(hostConfig, config.Resources)
+
+		resp, err := p.client.ContainerCreate(ctx, containerConfig, hostConfig, nil, platform, name)
+		if err != nil {
+			return fmt.Errorf("预热容器创建失败: %w", err)
+		}
+
+		p.mu.Lock()
+		p.pool[config.Image] = append(p.pool[config.Image], &prewarmEntry{dockerID: resp.ID})
+		p.mu.Unlock()
+	}
+
+	p.reportDepth()
+	p.logger.Info("为镜像 %s 预热了 %d 个空闲容器", config.Image, n)
+	return nil
+}
+
+// acquire 取出一个image对应的空闲预热容器（若有）。调用方不能直接Start它：容器的端口/卷/
+// 标签等字段是fill()时按prewarm时刻已知的信息创建的，与本次真实请求大概率不一致，调用方应
+// 将其移除后按请求的真实配置重新创建；不存在空闲条目时返回ok=false
+func (p *PrewarmPool) acquire(image string) (dockerID string, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries := p.pool[image]
+	if len(entries) == 0 {
+		return "", false
+	}
+	entry := entries[len(entries)-1]
+	p.pool[image] = entries[:len(entries)-1]
+
+	p.reportDepthLocked()
+	return entry.dockerID, true
+}
+
+// depth 返回当前每个镜像的空闲预热容器数，供外部只读查询
+func (p *PrewarmPool) depth() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	depth := make(map[string]int, len(p.pool))
+	for image, entries := range p.pool {
+		depth[image] = len(entries)
+	}
+	return depth
+}
+
+func (p *PrewarmPool) reportDepth() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reportDepthLocked()
+}
+
+func (p *PrewarmPool) reportDepthLocked() {
+	depth := make(map[string]int, len(p.pool))
+	for image, entries := range p.pool {
+		depth[image] = len(entries)
+	}
+	p.metricsRecorder.SetPrewarmPoolDepth(depth)
+}
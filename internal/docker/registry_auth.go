@@ -0,0 +1,231 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	registrytypes "github.com/moby/moby/api/types/registry"
+
+	"kwdb-playground/internal/config"
+)
+
+// RegistryAuthProvider 按镜像所属的registry host解析拉取凭据。pullImageWithProgress/
+// PullImage/PullImageEvents在实际ImagePull前都会经由它查询一次，ok为false表示该host没有
+// 可用凭据（按匿名拉取处理），与网络/解析失败的error是两回事
+type RegistryAuthProvider interface {
+	Resolve(host string) (registrytypes.AuthConfig, bool, error)
+}
+
+// chainAuthProvider 依次尝试多个Provider，第一个给出ok=true的结果生效，
+// 镜像docker CLI"credHelpers优先于credsStore优先于config.json里存的auths"的优先级语义
+type chainAuthProvider struct {
+	providers []RegistryAuthProvider
+}
+
+func newChainAuthProvider(providers ...RegistryAuthProvider) *chainAuthProvider {
+	return &chainAuthProvider{providers: providers}
+}
+
+func (c *chainAuthProvider) Resolve(host string) (registrytypes.AuthConfig, bool, error) {
+	for _, p := range c.providers {
+		auth, ok, err := p.Resolve(host)
+		if err != nil {
+			return registrytypes.AuthConfig{}, false, err
+		}
+		if ok {
+			return auth, true, nil
+		}
+	}
+	return registrytypes.AuthConfig{}, false, nil
+}
+
+// loginAuthProvider 缓存通过 dockerController.Login 成功验证过的凭据，进程生命周期内有效，
+// 不落盘；优先级高于 ~/.docker/config.json，镜像"docker login"之后后续拉取即可免再次输入密码
+type loginAuthProvider struct {
+	mu      sync.RWMutex
+	entries map[string]registrytypes.AuthConfig
+}
+
+func newLoginAuthProvider() *loginAuthProvider {
+	return &loginAuthProvider{entries: make(map[string]registrytypes.AuthConfig)}
+}
+
+func (p *loginAuthProvider) Resolve(host string) (registrytypes.AuthConfig, bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	auth, ok := p.entries[host]
+	return auth, ok, nil
+}
+
+func (p *loginAuthProvider) set(host string, auth registrytypes.AuthConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.entries[host] = auth
+}
+
+func (p *loginAuthProvider) remove(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.entries, host)
+}
+
+// staticAuthProvider 来自playground配置文件(config.RegistryEntry)的静态per-registry凭据，
+// 用于没有部署docker CLI凭据体系（~/.docker/config.json、凭据助手）的环境
+type staticAuthProvider struct {
+	entries map[string]registrytypes.AuthConfig
+}
+
+// newStaticAuthProvider 按 RegistryEntry.URL 的host归一化建索引；PasswordFile与registry包
+// 里ociSource解析密码的方式保持一致，避免在配置文件中明文存密码
+func newStaticAuthProvider(entries []config.RegistryEntry) *staticAuthProvider {
+	m := make(map[string]registrytypes.AuthConfig, len(entries))
+	for _, e := range entries {
+		if e.Username == "" {
+			continue
+		}
+		host := normalizeRegistryHost(e.URL)
+		password := ""
+		if e.PasswordFile != "" {
+			if data, err := os.ReadFile(e.PasswordFile); err == nil {
+				password = strings.TrimSpace(string(data))
+			}
+		}
+		m[host] = registrytypes.AuthConfig{ServerAddress: host, Username: e.Username, Password: password}
+	}
+	return &staticAuthProvider{entries: m}
+}
+
+// NewStaticRegistryAuthProvider 按playground配置文件里的per-registry用户名/密码（config.RegistryEntry）
+// 构建一个RegistryAuthProvider，供 Controller.SetRegistryAuthProvider 注入
+func NewStaticRegistryAuthProvider(entries []config.RegistryEntry) RegistryAuthProvider {
+	return newStaticAuthProvider(entries)
+}
+
+func (p *staticAuthProvider) Resolve(host string) (registrytypes.AuthConfig, bool, error) {
+	auth, ok := p.entries[host]
+	return auth, ok, nil
+}
+
+// normalizeRegistryHost 去掉URL的协议前缀与末尾斜杠，与 registry.NewGenericSource 解析host的方式一致，
+// 留空的URL视为官方 docker.io（与 imageRegistryLabel 对匿名镜像名的默认值保持一致）
+func normalizeRegistryHost(rawURL string) string {
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(rawURL, "https://"), "http://"), "/")
+	if host == "" {
+		return "docker.io"
+	}
+	return host
+}
+
+// dockerConfigFile 只解析 ~/.docker/config.json 中与鉴权相关的字段
+type dockerConfigFile struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// dockerConfigAuthProvider 读取 ~/.docker/config.json，按docker CLI的优先级：
+// 该host在credHelpers中指定的助手 > auths里直接存的base64(user:pass) > 全局credsStore助手
+type dockerConfigAuthProvider struct {
+	configPath string
+}
+
+func newDockerConfigAuthProvider() *dockerConfigAuthProvider {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return &dockerConfigAuthProvider{}
+	}
+	return &dockerConfigAuthProvider{configPath: filepath.Join(home, ".docker", "config.json")}
+}
+
+func (p *dockerConfigAuthProvider) Resolve(host string) (registrytypes.AuthConfig, bool, error) {
+	if p.configPath == "" {
+		return registrytypes.AuthConfig{}, false, nil
+	}
+	data, err := os.ReadFile(p.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registrytypes.AuthConfig{}, false, nil
+		}
+		return registrytypes.AuthConfig{}, false, fmt.Errorf("读取 %s 失败: %w", p.configPath, err)
+	}
+
+	var cfg dockerConfigFile
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return registrytypes.AuthConfig{}, false, fmt.Errorf("解析 %s 失败: %w", p.configPath, err)
+	}
+
+	if helper, ok := cfg.CredHelpers[host]; ok {
+		return runCredentialHelper(helper, host)
+	}
+	if entry, ok := cfg.Auths[host]; ok && entry.Auth != "" {
+		return decodeBasicAuth(host, entry.Auth)
+	}
+	if cfg.CredsStore != "" {
+		auth, found, err := runCredentialHelper(cfg.CredsStore, host)
+		if found || err != nil {
+			return auth, found, err
+		}
+	}
+	return registrytypes.AuthConfig{}, false, nil
+}
+
+// decodeBasicAuth 解码 config.json 中 auths.<host>.auth 字段，格式为 base64("username:password")
+func decodeBasicAuth(host, encoded string) (registrytypes.AuthConfig, bool, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return registrytypes.AuthConfig{}, false, fmt.Errorf("解析 %s 的auth字段失败: %w", host, err)
+	}
+	username, password, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return registrytypes.AuthConfig{}, false, fmt.Errorf("%s 的auth字段格式不正确", host)
+	}
+	return registrytypes.AuthConfig{ServerAddress: host, Username: username, Password: password}, true, nil
+}
+
+// runCredentialHelper 按docker-credential-*二进制协议调用凭据助手：
+// 向"docker-credential-<helper> get"的stdin写入registry host，stdout解析为
+// {ServerURL,Username,Secret}的JSON；助手找不到该host的凭据时以非0退出码+"credentials not found"
+// 结尾的stderr报告，这里按ok=false处理而非返回error
+func runCredentialHelper(helper, host string) (registrytypes.AuthConfig, bool, error) {
+	bin := "docker-credential-" + helper
+	cmd := exec.Command(bin, "get")
+	cmd.Stdin = strings.NewReader(host)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "credentials not found") {
+			return registrytypes.AuthConfig{}, false, nil
+		}
+		return registrytypes.AuthConfig{}, false, fmt.Errorf("凭据助手 %s 执行失败: %w (%s)", bin, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var resp struct {
+		ServerURL string `json:"ServerURL"`
+		Username  string `json:"Username"`
+		Secret    string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return registrytypes.AuthConfig{}, false, fmt.Errorf("解析凭据助手 %s 输出失败: %w", bin, err)
+	}
+	return registrytypes.AuthConfig{ServerAddress: resp.ServerURL, Username: resp.Username, Password: resp.Secret}, true, nil
+}
+
+// encodeRegistryAuth 把AuthConfig编码为 client.ImagePullOptions.RegistryAuth 期望的
+// base64(JSON) 格式，与docker CLI "X-Registry-Auth" 请求头的编码方式一致
+func encodeRegistryAuth(auth registrytypes.AuthConfig) (string, error) {
+	raw, err := json.Marshal(auth)
+	if err != nil {
+		return "", fmt.Errorf("编码鉴权信息失败: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,349 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/metrics"
+
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+// 课程级别的默认聚合资源配额：course.DockerHostConfig只约束单个容器的硬上限，ResourceGovernor
+// 在此之上统计同一courseID下所有容器的聚合用量，防止单课程通过并发创建多个容器绕过单容器限制
+// 占满宿主机。留空（<=0）时 newResourceGovernor 套用这里的默认值
+const (
+	defaultCourseMemoryQuotaMB int64 = 4096
+	defaultCoursePidsQuota     int64 = 2048
+	// defaultGlobalMemoryQuotaMB/defaultGlobalPidsQuota 是跨所有课程累加的playground级别硬上限，
+	// 防止某一时刻大量课程各自都在配额内、但合计仍然把宿主机内存/pids占满——单课程配额只约束
+	// "一个课程名下"的聚合用量，管不住"所有课程加在一起"
+	defaultGlobalMemoryQuotaMB int64 = 16384
+	defaultGlobalPidsQuota     int64 = 8192
+)
+
+// ErrQuotaExceeded 标记一次因课程聚合资源配额超限而被拒绝的容器创建请求；实现error接口，
+// 调用方可用 errors.As 取出CourseID/Resource/Requested/Limit用于返回给前端的提示信息
+type ErrQuotaExceeded struct {
+	CourseID  string
+	Resource  string // "memory" 或 "pids"
+	Requested int64
+	Limit     int64
+}
+
+func (e *ErrQuotaExceeded) Error() string {
+	return fmt.Sprintf("课程 %s 的%s配额已用尽：请求 %d，配额上限 %d", e.CourseID, e.Resource, e.Requested, e.Limit)
+}
+
+// CourseUsage 是 GetCourseUsage 返回的课程聚合用量快照
+type CourseUsage struct {
+	CourseID      string    `json:"courseId"`
+	MemoryBytes   uint64    `json:"memoryBytes"`
+	CPUPercent    float64   `json:"cpuPercent"`
+	Pids          uint64    `json:"pids"`
+	NetRxBytes    uint64    `json:"netRxBytes"`
+	NetTxBytes    uint64    `json:"netTxBytes"`
+	BlkReadBytes  uint64    `json:"blkReadBytes"`
+	BlkWriteBytes uint64    `json:"blkWriteBytes"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// containerSample 是某容器最近一次ContainerStats快照里、聚合到课程用量所需的字段
+type containerSample struct {
+	memoryBytes   uint64
+	cpuPercent    float64
+	pids          uint64
+	netRxBytes    uint64
+	netTxBytes    uint64
+	blkReadBytes  uint64
+	blkWriteBytes uint64
+
+	// 上一轮原始CPU计数器，用于下一次decode时按docker stats CLI同款公式换算瞬时CPU使用率
+	prevCPUTotal   uint64
+	prevSystemCPU  uint64
+	havePrevSample bool
+}
+
+// ResourceGovernor 按课程聚合 DockerClientInterface.ContainerStats 流上报的CPU/内存/pids/网络/
+// 块IO用量，createContainer 申请资源前据此拒绝会让课程聚合用量超出配额的请求（ErrQuotaExceeded），
+// 并把配额折算进 HostConfig 硬限制，双重防止单课程开多个容器绕过单容器限制占满宿主机
+type ResourceGovernor struct {
+	client          DockerClientInterface
+	logger          *logger.Logger
+	metricsRecorder metrics.Recorder
+
+	memoryQuotaMB int64
+	pidsQuota     int64
+
+	globalMemoryQuotaMB int64
+	globalPidsQuota     int64
+
+	mu      sync.RWMutex
+	samples map[string]map[string]*containerSample // courseID -> dockerID -> 最近一次采样
+	cancel  map[string]context.CancelFunc          // dockerID -> 该容器统计流的取消函数
+	wg      sync.WaitGroup
+}
+
+// newResourceGovernor 创建资源用量监督器，不会自动开始统计任何容器，需对每个已创建的容器
+// 显式调用 Track；memoryQuotaMB/pidsQuota留空（<=0）时套用策略默认值
+func newResourceGovernor(cli DockerClientInterface, log *logger.Logger, memoryQuotaMB, pidsQuota int64) *ResourceGovernor {
+	if memoryQuotaMB <= 0 {
+		memoryQuotaMB = defaultCourseMemoryQuotaMB
+	}
+	if pidsQuota <= 0 {
+		pidsQuota = defaultCoursePidsQuota
+	}
+	return &ResourceGovernor{
+		client:              cli,
+		logger:              log,
+		metricsRecorder:     metrics.DefaultRecorder,
+		memoryQuotaMB:       memoryQuotaMB,
+		pidsQuota:           pidsQuota,
+		globalMemoryQuotaMB: defaultGlobalMemoryQuotaMB,
+		globalPidsQuota:     defaultGlobalPidsQuota,
+		samples:             make(map[string]map[string]*containerSample),
+		cancel:              make(map[string]context.CancelFunc),
+	}
+}
+
+// CheckQuota 在创建容器前校验追加 requestedMemoryMB 是否会让courseID的聚合内存用量超过配额；
+// 只做准入检查，不做预占——真正生效的用量仍由 Track 启动的统计流滚动更新，短时间内并发创建
+// 多个容器仍可能短暂超出配额，等下一轮ContainerStats快照到达后才会被发现并在后续请求里拒绝
+func (g *ResourceGovernor) CheckQuota(courseID string, requestedMemoryMB int64) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var usedMB int64
+	var globalUsedMB int64
+	for cid, perContainer := range g.samples {
+		var courseMB int64
+		for _, sample := range perContainer {
+			courseMB += int64(sample.memoryBytes / (1024 * 1024))
+		}
+		globalUsedMB += courseMB
+		if cid == courseID {
+			usedMB = courseMB
+		}
+	}
+	if usedMB+requestedMemoryMB > g.memoryQuotaMB {
+		return &ErrQuotaExceeded{CourseID: courseID, Resource: "memory", Requested: usedMB + requestedMemoryMB, Limit: g.memoryQuotaMB}
+	}
+	// 全局playground级别的硬上限：即使每个课程都各自在配额内，所有课程加在一起也不能
+	// 超出宿主机能承受的总量，否则单个宿主机仍可能被大量守规矩的课程合力占满
+	if globalUsedMB+requestedMemoryMB > g.globalMemoryQuotaMB {
+		return &ErrQuotaExceeded{CourseID: courseID, Resource: "memory_global", Requested: globalUsedMB + requestedMemoryMB, Limit: g.globalMemoryQuotaMB}
+	}
+	return nil
+}
+
+// ApplyQuota 在 applyResourcePolicy 之后调用，把课程剩余配额折算进hostConfig的硬限制：
+// 只会收紧单容器上限（remainingMB/pidsQuota 更小时），不会放宽 applyResourcePolicy 已经设置的值
+func (g *ResourceGovernor) ApplyQuota(hostConfig *container.HostConfig, courseID string) {
+	g.mu.RLock()
+	var usedMB int64
+	for _, sample := range g.samples[courseID] {
+		usedMB += int64(sample.memoryBytes / (1024 * 1024))
+	}
+	g.mu.RUnlock()
+
+	remainingMB := g.memoryQuotaMB - usedMB
+	if remainingMB < 0 {
+		remainingMB = 0
+	}
+	if hostConfig.Memory <= 0 || hostConfig.Memory > remainingMB*1024*1024 {
+		hostConfig.Memory = remainingMB * 1024 * 1024
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > g.pidsQuota {
+		pidsLimit := g.pidsQuota
+		hostConfig.PidsLimit = &pidsLimit
+	}
+}
+
+// Track 为courseID名下新增的容器启动一个后台goroutine，持续消费其ContainerStats流并滚动更新
+// 聚合用量；流因容器退出/被删除而结束时自行退出，不会重连——调用方应在容器被移除时调用 Untrack
+func (g *ResourceGovernor) Track(ctx context.Context, courseID, dockerID string) {
+	streamCtx, cancel := context.WithCancel(ctx)
+
+	g.mu.Lock()
+	g.cancel[dockerID] = cancel
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go g.stream(streamCtx, courseID, dockerID)
+}
+
+// Untrack 停止courseID下某容器的统计流并清除其最近一次采样，使其不再计入聚合用量
+func (g *ResourceGovernor) Untrack(courseID, dockerID string) {
+	g.mu.Lock()
+	if cancel, ok := g.cancel[dockerID]; ok {
+		cancel()
+		delete(g.cancel, dockerID)
+	}
+	if perContainer, ok := g.samples[courseID]; ok {
+		delete(perContainer, dockerID)
+		if len(perContainer) == 0 {
+			delete(g.samples, courseID)
+		}
+	}
+	g.mu.Unlock()
+
+	g.reportUsage(courseID)
+}
+
+// Stop 停止所有正在进行的统计流并等待其goroutine退出，应在Close时调用
+func (g *ResourceGovernor) Stop() {
+	g.mu.Lock()
+	for dockerID, cancel := range g.cancel {
+		cancel()
+		delete(g.cancel, dockerID)
+	}
+	g.mu.Unlock()
+	g.wg.Wait()
+}
+
+// GetCourseUsage 返回courseID当前的聚合用量快照，ok为false表示该课程尚无正在被统计的容器
+func (g *ResourceGovernor) GetCourseUsage(courseID string) (CourseUsage, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	perContainer, ok := g.samples[courseID]
+	if !ok || len(perContainer) == 0 {
+		return CourseUsage{}, false
+	}
+
+	usage := CourseUsage{CourseID: courseID, UpdatedAt: time.Now()}
+	for _, sample := range perContainer {
+		usage.MemoryBytes += sample.memoryBytes
+		usage.CPUPercent += sample.cpuPercent
+		usage.Pids += sample.pids
+		usage.NetRxBytes += sample.netRxBytes
+		usage.NetTxBytes += sample.netTxBytes
+		usage.BlkReadBytes += sample.blkReadBytes
+		usage.BlkWriteBytes += sample.blkWriteBytes
+	}
+	return usage, true
+}
+
+// stream 持续消费单个容器的ContainerStats流并合入聚合用量；订阅失败或流中断（容器退出/被删除）
+// 后直接退出，不做重连——容器生命周期结束后重连没有意义，新容器由新的Track调用接管
+func (g *ResourceGovernor) stream(ctx context.Context, courseID, dockerID string) {
+	defer g.wg.Done()
+
+	body, err := g.client.ContainerStats(ctx, dockerID, client.ContainerStatsOptions{Stream: true})
+	if err != nil {
+		g.logger.Warn("容器 %s 的资源统计流订阅失败: %v", dockerID[:12], err)
+		return
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var stats container.StatsResponse
+		if err := decoder.Decode(&stats); err != nil {
+			return
+		}
+		g.applyStats(courseID, dockerID, &stats)
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// applyStats 把一次ContainerStats快照换算为 containerSample 并合入courseID的聚合用量，
+// CPU使用率按docker stats CLI同款公式（cpuDelta/systemDelta*在线CPU数*100）计算瞬时值
+func (g *ResourceGovernor) applyStats(courseID, dockerID string, stats *container.StatsResponse) {
+	var netRx, netTx uint64
+	for _, net := range stats.Networks {
+		netRx += net.RxBytes
+		netTx += net.TxBytes
+	}
+
+	var blkRead, blkWrite uint64
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch entry.Op {
+		case "read", "Read":
+			blkRead += entry.Value
+		case "write", "Write":
+			blkWrite += entry.Value
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	perContainer, ok := g.samples[courseID]
+	if !ok {
+		perContainer = make(map[string]*containerSample)
+		g.samples[courseID] = perContainer
+	}
+	sample, ok := perContainer[dockerID]
+	if !ok {
+		sample = &containerSample{}
+		perContainer[dockerID] = sample
+	}
+
+	sample.memoryBytes = stats.MemoryStats.Usage
+	sample.pids = stats.PidsStats.Current
+	sample.netRxBytes = netRx
+	sample.netTxBytes = netTx
+	sample.blkReadBytes = blkRead
+	sample.blkWriteBytes = blkWrite
+
+	cpuTotal := stats.CPUStats.CPUUsage.TotalUsage
+	systemCPU := stats.CPUStats.SystemUsage
+	if sample.havePrevSample {
+		cpuDelta := float64(cpuTotal) - float64(sample.prevCPUTotal)
+		systemDelta := float64(systemCPU) - float64(sample.prevSystemCPU)
+		if systemDelta > 0 && cpuDelta > 0 {
+			onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+			if onlineCPUs == 0 {
+				onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+			}
+			if onlineCPUs == 0 {
+				onlineCPUs = 1
+			}
+			sample.cpuPercent = (cpuDelta / systemDelta) * onlineCPUs * 100
+		}
+	}
+	sample.prevCPUTotal = cpuTotal
+	sample.prevSystemCPU = systemCPU
+	sample.havePrevSample = true
+
+	g.reportUsageLocked(courseID)
+}
+
+// reportUsage 加读写锁后上报courseID当前的聚合用量到Prometheus
+func (g *ResourceGovernor) reportUsage(courseID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.reportUsageLocked(courseID)
+}
+
+// reportUsageLocked 要求调用方已持有g.mu
+func (g *ResourceGovernor) reportUsageLocked(courseID string) {
+	var usage CourseUsage
+	for _, sample := range g.samples[courseID] {
+		usage.MemoryBytes += sample.memoryBytes
+		usage.CPUPercent += sample.cpuPercent
+		usage.Pids += sample.pids
+		usage.NetRxBytes += sample.netRxBytes
+		usage.NetTxBytes += sample.netTxBytes
+		usage.BlkReadBytes += sample.blkReadBytes
+		usage.BlkWriteBytes += sample.blkWriteBytes
+	}
+	g.metricsRecorder.SetCourseUsage(courseID, map[string]float64{
+		"memory_bytes":    float64(usage.MemoryBytes),
+		"cpu_percent":     usage.CPUPercent,
+		"pids":            float64(usage.Pids),
+		"net_rx_bytes":    float64(usage.NetRxBytes),
+		"net_tx_bytes":    float64(usage.NetTxBytes),
+		"blk_read_bytes":  float64(usage.BlkReadBytes),
+		"blk_write_bytes": float64(usage.BlkWriteBytes),
+	})
+}
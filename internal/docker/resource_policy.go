@@ -0,0 +1,96 @@
+package docker
+
+import (
+	"github.com/docker/go-units"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/api/types/strslice"
+)
+
+// 资源策略默认值与强制上限：课程YAML来自课程作者而非平台运营方，不能被用来申请无限资源
+// 或放开平台认为不安全的能力，因此这里的数值覆盖 config.Resources 未显式指定的字段，
+// 而 forcedCapDrop/maxMemoryMBCap 即使课程显式指定了更宽松的值也始终生效
+const (
+	defaultMemoryMB  int64 = 512
+	maxMemoryMBCap   int64 = 4096
+	defaultPidsLimit int64 = 512
+)
+
+// forcedCapDrop 无论课程如何声明都会被丢弃的能力
+var forcedCapDrop = []string{"NET_RAW"}
+
+// applyResourcePolicy 把课程声明的资源限制与加固选项写入hostConfig，res为nil时
+// 完全套用策略默认值；不为nil时以res的显式字段为准，但始终执行maxMemoryMBCap上限与forcedCapDrop
+func applyResourcePolicy(hostConfig *container.HostConfig, res *ContainerResources) {
+	memoryMB := defaultMemoryMB
+	if hostConfig.Memory > 0 {
+		// 已经由 ContainerConfig.MemoryLimit（历史字段）设置过，保留其值而不是退回默认值
+		memoryMB = hostConfig.Memory / (1024 * 1024)
+	}
+	pidsLimit := defaultPidsLimit
+	capDrop := append([]string{}, forcedCapDrop...)
+
+	if res != nil {
+		if res.MemoryMB > 0 {
+			memoryMB = res.MemoryMB
+		}
+		if res.PidsLimit > 0 {
+			pidsLimit = res.PidsLimit
+		}
+		if res.CPUShares > 0 {
+			hostConfig.CPUShares = res.CPUShares
+		}
+		if res.CPUQuota > 0 {
+			hostConfig.CPUQuota = res.CPUQuota
+			hostConfig.CPUPeriod = 100000
+			if res.CPUPeriod > 0 {
+				hostConfig.CPUPeriod = res.CPUPeriod
+			}
+		}
+		if res.NanoCPUs > 0 {
+			hostConfig.NanoCPUs = res.NanoCPUs
+		}
+		if res.MemorySwapMB > 0 {
+			hostConfig.MemorySwap = res.MemorySwapMB * 1024 * 1024
+		}
+		if res.BlkioWeight > 0 {
+			hostConfig.BlkioWeight = res.BlkioWeight
+		}
+		if res.OomScoreAdj != 0 {
+			hostConfig.OomScoreAdj = res.OomScoreAdj
+		}
+		hostConfig.ReadonlyRootfs = res.ReadOnlyRootfs
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, res.SecurityOpt...)
+		if len(res.Tmpfs) > 0 {
+			hostConfig.Tmpfs = res.Tmpfs
+		}
+		for _, extra := range res.CapDrop {
+			if !containsCap(capDrop, extra) {
+				capDrop = append(capDrop, extra)
+			}
+		}
+		for _, u := range res.Ulimits {
+			hostConfig.Ulimits = append(hostConfig.Ulimits, &units.Ulimit{
+				Name: u.Name,
+				Soft: u.Soft,
+				Hard: u.Hard,
+			})
+		}
+	}
+
+	if memoryMB > maxMemoryMBCap {
+		memoryMB = maxMemoryMBCap
+	}
+	hostConfig.Memory = memoryMB * 1024 * 1024
+	pidsLimitVal := pidsLimit
+	hostConfig.PidsLimit = &pidsLimitVal
+	hostConfig.CapDrop = strslice.StrSlice(capDrop)
+}
+
+func containsCap(caps []string, c string) bool {
+	for _, existing := range caps {
+		if existing == c {
+			return true
+		}
+	}
+	return false
+}
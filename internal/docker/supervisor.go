@@ -0,0 +1,460 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/logger"
+
+	"github.com/moby/moby/api/types/events"
+	"github.com/moby/moby/api/types/filters"
+	"github.com/moby/moby/client"
+)
+
+// SupervisorPhase 描述 Supervisor 为单个容器维护的状态机阶段，对标 dockerd 内部
+// container.monitor 的 Created→Running→Exited(→Restarting) 生命周期
+type SupervisorPhase string
+
+const (
+	SupervisorCreated    SupervisorPhase = "created"
+	SupervisorRunning    SupervisorPhase = "running"
+	SupervisorExited     SupervisorPhase = "exited"
+	SupervisorRestarting SupervisorPhase = "restarting"
+)
+
+// RestartPolicy 描述容器异常退出后的自动重启策略，取值形如 "none"（默认）、"always"、"on-failure:N"
+// （仅非0退出码重启，最多重试N次；N省略或非法视为不限次数）
+type RestartPolicy struct {
+	Name          string
+	MaxRetryCount int
+}
+
+// ParseRestartPolicy 解析重启策略字符串，无法识别的取值一律退化为 "none"（不自动重启，保持历史行为）
+func ParseRestartPolicy(s string) RestartPolicy {
+	name, rest, hasArg := strings.Cut(s, ":")
+	switch name {
+	case "always":
+		return RestartPolicy{Name: "always"}
+	case "on-failure":
+		policy := RestartPolicy{Name: "on-failure"}
+		if hasArg {
+			if n, err := strconv.Atoi(rest); err == nil && n > 0 {
+				policy.MaxRetryCount = n
+			}
+		}
+		return policy
+	default:
+		return RestartPolicy{Name: "none"}
+	}
+}
+
+// shouldRestart 判断容器以 exitCode 退出、此前已重试 restartCount 次的情况下是否应再次重启
+func (p RestartPolicy) shouldRestart(exitCode int, restartCount int) bool {
+	switch p.Name {
+	case "always":
+		return true
+	case "on-failure":
+		if exitCode == 0 {
+			return false
+		}
+		if p.MaxRetryCount > 0 && restartCount >= p.MaxRetryCount {
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// restartBackoff 重启前的等待时间：以1秒为基数指数退避，上限30秒，与 dockerd 的 restartmanager 量级一致
+func restartBackoff(restartCount int) time.Duration {
+	const cap = 30 * time.Second
+	d := time.Second << uint(restartCount)
+	if d <= 0 || d > cap { // 左移溢出（restartCount很大）时d可能变负或超出上限
+		return cap
+	}
+	return d
+}
+
+// SupervisedContainer 是 Supervisor 对单个容器维护的状态机快照，整份map定期持久化到 statePath，
+// 使kwdb-playground自身重启后仍能在下一次事件到达前，知道每个容器此前的重启次数与阶段
+type SupervisedContainer struct {
+	ContainerID  string          `json:"containerId"` // ContainerInfo.ID（容器名称），非Docker长ID
+	DockerID     string          `json:"dockerId"`
+	CourseID     string          `json:"courseId"`
+	Phase        SupervisorPhase `json:"phase"`
+	ExitCode     *int            `json:"exitCode,omitempty"`
+	RestartCount int             `json:"restartCount"`
+	LastLogLines []string        `json:"lastLogLines,omitempty"`
+	UpdatedAt    time.Time       `json:"updatedAt"`
+}
+
+// SupervisorEvent 是一次状态机迁移的通知，供 WebSocket 广播与 /api/containers/:id/events SSE共用
+type SupervisorEvent struct {
+	ContainerID  string          `json:"containerId"`
+	Phase        SupervisorPhase `json:"phase"`
+	ExitCode     *int            `json:"exitCode,omitempty"`
+	RestartCount int             `json:"restartCount"`
+	Message      string          `json:"message,omitempty"`
+	At           time.Time       `json:"at"`
+}
+
+// Supervisor 监督由本进程管理的课程容器：订阅 Docker 事件流，对异常退出的容器按 RestartPolicy
+// 自动重启（指数退避），状态机落盘到 statePath，并通过 terminalMgr 把"容器已退出/正在重启"
+// 之类的事件广播给对应容器已附加的终端会话
+type Supervisor struct {
+	client      DockerClientInterface
+	controller  *dockerController
+	terminalMgr TerminalManagerInterface
+	logger      *logger.Logger
+	policy      RestartPolicy
+	statePath   string
+
+	mu          sync.Mutex
+	states      map[string]*SupervisedContainer // key: ContainerInfo.ID（容器名称）
+	subscribers map[string][]chan SupervisorEvent
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newSupervisor 创建容器监督器，不会自动启动后台goroutine，调用方需显式调用 Start
+func newSupervisor(controller *dockerController, cli DockerClientInterface, terminalMgr TerminalManagerInterface, log *logger.Logger, policy RestartPolicy, statePath string) *Supervisor {
+	return &Supervisor{
+		client:      cli,
+		controller:  controller,
+		terminalMgr: terminalMgr,
+		logger:      log,
+		policy:      policy,
+		statePath:   statePath,
+		states:      make(map[string]*SupervisedContainer),
+		subscribers: make(map[string][]chan SupervisorEvent),
+	}
+}
+
+// Start 加载上次持久化的状态、以内存中现有容器为基准建立初始状态机，随后订阅Docker事件流并开始监督；
+// 重复调用是安全的，第二次调用会先停止上一轮监督
+func (sv *Supervisor) Start(ctx context.Context) {
+	sv.Stop()
+
+	sv.loadState()
+
+	sv.controller.mu.RLock()
+	for id, info := range sv.controller.containers {
+		phase := SupervisorExited
+		if info.State == StateRunning || info.State == StateStarting || info.State == StateCreating {
+			phase = SupervisorRunning
+		}
+		if existing, ok := sv.states[id]; ok {
+			existing.DockerID = info.DockerID
+			existing.CourseID = info.CourseID
+			continue
+		}
+		sv.states[id] = &SupervisedContainer{
+			ContainerID: id,
+			DockerID:    info.DockerID,
+			CourseID:    info.CourseID,
+			Phase:       phase,
+			UpdatedAt:   time.Now(),
+		}
+	}
+	sv.controller.mu.RUnlock()
+	sv.persistState()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	sv.cancel = cancel
+
+	msgCh, errCh := sv.client.Events(runCtx, client.EventsListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", "container"),
+			filters.Arg("label", LabelAppName+"="+appNameValue),
+		),
+	})
+
+	sv.wg.Add(1)
+	go sv.watchLoop(runCtx, msgCh, errCh)
+}
+
+// Stop 停止订阅Docker事件流并等待正在进行的重启goroutine退出；server.Run 在 srv.Shutdown 之前调用，
+// 避免关停过程中仍有监督逻辑在后台尝试重启容器
+func (sv *Supervisor) Stop() {
+	sv.mu.Lock()
+	cancel := sv.cancel
+	sv.cancel = nil
+	sv.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	sv.wg.Wait()
+}
+
+// watchLoop 消费Docker事件流，按Action分发到状态机迁移；errCh关闭或runCtx取消时退出
+func (sv *Supervisor) watchLoop(ctx context.Context, msgCh <-chan events.Message, errCh <-chan error) {
+	defer sv.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-errCh:
+			if !ok {
+				return
+			}
+			if err != nil && err != io.EOF {
+				sv.logger.Warn("容器事件流中断: %v", err)
+			}
+			return
+		case msg, ok := <-msgCh:
+			if !ok {
+				return
+			}
+			sv.handleEvent(ctx, msg)
+		}
+	}
+}
+
+// handleEvent 处理单条Docker容器事件；containerName取自 Actor.Attributes["name"]，与
+// d.containers 的key（去掉前导"/"的容器名称）保持同一格式
+func (sv *Supervisor) handleEvent(ctx context.Context, msg events.Message) {
+	containerName := strings.TrimPrefix(msg.Actor.Attributes["name"], "/")
+	if containerName == "" {
+		return
+	}
+
+	switch msg.Action {
+	case "start":
+		sv.transition(containerName, SupervisorRunning, nil, "")
+	case "die":
+		exitCode, _ := strconv.Atoi(msg.Actor.Attributes["exitCode"])
+		sv.onDie(ctx, containerName, exitCode)
+	case "stop", "kill":
+		// stop/kill 是人为触发的，不应被当作"意外退出"去重启；die事件会紧随其后以确定的exitCode触发真正的判断
+	}
+}
+
+// onDie 记录退出码与最后N行日志，按策略决定是否自动重启；exitCode==0或不满足策略时落定为Exited
+func (sv *Supervisor) onDie(ctx context.Context, containerName string, exitCode int) {
+	sv.mu.Lock()
+	state, ok := sv.states[containerName]
+	if !ok {
+		state = &SupervisedContainer{ContainerID: containerName}
+		sv.states[containerName] = state
+	}
+	state.ExitCode = &exitCode
+	state.Phase = SupervisorExited
+	state.UpdatedAt = time.Now()
+	restartCount := state.RestartCount
+	sv.mu.Unlock()
+
+	state.LastLogLines = sv.tailLogs(ctx, containerName)
+	sv.persistState()
+	sv.emit(containerName, SupervisorExited, &exitCode, restartCount, fmt.Sprintf("容器退出，退出码: %d", exitCode))
+
+	if !sv.policy.shouldRestart(exitCode, restartCount) {
+		return
+	}
+
+	sv.mu.Lock()
+	state.Phase = SupervisorRestarting
+	state.RestartCount++
+	attempt := state.RestartCount
+	sv.mu.Unlock()
+	sv.persistState()
+
+	backoff := restartBackoff(restartCount)
+	sv.emit(containerName, SupervisorRestarting, &exitCode, attempt, fmt.Sprintf("%s 后尝试第 %d 次自动重启", backoff, attempt))
+
+	sv.wg.Add(1)
+	go sv.restartAfter(containerName, backoff)
+}
+
+// restartAfter 等待backoff后调用 StartContainer 重启容器；restartAfter本身运行在独立goroutine中，
+// 不阻塞watchLoop继续消费后续事件
+func (sv *Supervisor) restartAfter(containerName string, backoff time.Duration) {
+	defer sv.wg.Done()
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	<-timer.C
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sv.controller.StartContainer(ctx, containerName); err != nil {
+		sv.logger.Warn("自动重启容器 %s 失败: %v", containerName, err)
+		sv.mu.Lock()
+		if state, ok := sv.states[containerName]; ok {
+			state.Phase = SupervisorExited
+			state.UpdatedAt = time.Now()
+		}
+		sv.mu.Unlock()
+		sv.persistState()
+		sv.emit(containerName, SupervisorExited, nil, 0, fmt.Sprintf("自动重启失败: %v", err))
+		return
+	}
+
+	sv.transition(containerName, SupervisorRunning, nil, "自动重启成功")
+}
+
+// transition 更新状态机阶段并落盘、广播
+func (sv *Supervisor) transition(containerName string, phase SupervisorPhase, exitCode *int, message string) {
+	sv.mu.Lock()
+	state, ok := sv.states[containerName]
+	if !ok {
+		state = &SupervisedContainer{ContainerID: containerName}
+		sv.states[containerName] = state
+	}
+	state.Phase = phase
+	state.UpdatedAt = time.Now()
+	restartCount := state.RestartCount
+	sv.mu.Unlock()
+
+	sv.persistState()
+	sv.emit(containerName, phase, exitCode, restartCount, message)
+}
+
+// tailLogs 读取容器最后20行日志，尽力而为——读取失败时返回nil，不影响状态机继续推进
+func (sv *Supervisor) tailLogs(ctx context.Context, containerName string) []string {
+	const tailLines = 20
+	reader, err := sv.controller.GetContainerLogs(ctx, containerName, tailLines, false)
+	if err != nil {
+		sv.logger.Debug("读取容器 %s 最后日志失败: %v", containerName, err)
+		return nil
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, 64*1024))
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > tailLines {
+		lines = lines[len(lines)-tailLines:]
+	}
+	return lines
+}
+
+// emit 把一次状态机迁移同时广播给WebSocket终端会话与 SubscribeEvents 的SSE订阅者；
+// 订阅者channel已满时直接丢弃该条通知（SSE/WS都只是展示性提示，允许偶发丢失）而不是阻塞状态机推进
+func (sv *Supervisor) emit(containerID string, phase SupervisorPhase, exitCode *int, restartCount int, message string) {
+	ev := SupervisorEvent{
+		ContainerID:  containerID,
+		Phase:        phase,
+		ExitCode:     exitCode,
+		RestartCount: restartCount,
+		Message:      message,
+		At:           time.Now(),
+	}
+
+	if sv.terminalMgr != nil {
+		sv.terminalMgr.BroadcastContainerSupervisorEvent(containerID, string(phase), message)
+	}
+
+	sv.mu.Lock()
+	subs := append([]chan SupervisorEvent(nil), sv.subscribers[containerID]...)
+	sv.mu.Unlock()
+	for _, ch := range subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe 注册一个容器的监督事件订阅，供 /api/containers/:id/events SSE使用；
+// 返回的cancel函数必须在调用方结束监听（如客户端断开）时调用，否则channel会一直占用在subscribers中
+func (sv *Supervisor) Subscribe(containerID string) (<-chan SupervisorEvent, func()) {
+	ch := make(chan SupervisorEvent, 8)
+	sv.mu.Lock()
+	sv.subscribers[containerID] = append(sv.subscribers[containerID], ch)
+	sv.mu.Unlock()
+
+	cancel := func() {
+		sv.mu.Lock()
+		defer sv.mu.Unlock()
+		subs := sv.subscribers[containerID]
+		for i, c := range subs {
+			if c == ch {
+				sv.subscribers[containerID] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// persistState 把当前状态机快照原子写入 sv.statePath（先写临时文件再rename，避免崩溃后留下半份JSON），
+// statePath为空时表示未启用持久化（理论上不会发生，Start总是传入非空路径），此时直接跳过
+func (sv *Supervisor) persistState() {
+	if sv.statePath == "" {
+		return
+	}
+
+	sv.mu.Lock()
+	snapshot := make(map[string]*SupervisedContainer, len(sv.states))
+	for k, v := range sv.states {
+		copyV := *v
+		snapshot[k] = &copyV
+	}
+	sv.mu.Unlock()
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		sv.logger.Warn("序列化容器监督状态失败: %v", err)
+		return
+	}
+
+	dir := filepath.Dir(sv.statePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		sv.logger.Warn("创建容器监督状态目录失败: %v", err)
+		return
+	}
+	tmp, err := os.CreateTemp(dir, ".state-*.tmp")
+	if err != nil {
+		sv.logger.Warn("创建容器监督状态临时文件失败: %v", err)
+		return
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		sv.logger.Warn("写入容器监督状态失败: %v", err)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		sv.logger.Warn("关闭容器监督状态临时文件失败: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, sv.statePath); err != nil {
+		sv.logger.Warn("替换容器监督状态文件失败: %v", err)
+	}
+}
+
+// loadState 从 sv.statePath 恢复上一次持久化的状态机快照，文件不存在或解析失败时静默从空状态开始
+func (sv *Supervisor) loadState() {
+	data, err := os.ReadFile(sv.statePath)
+	if err != nil {
+		return
+	}
+	var snapshot map[string]*SupervisedContainer
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		sv.logger.Warn("解析容器监督状态文件失败，从空状态开始: %v", err)
+		return
+	}
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	for k, v := range snapshot {
+		sv.states[k] = v
+	}
+}
@@ -12,6 +12,9 @@ const (
 	StateStopped  ContainerState = "stopped"
 	StateExited   ContainerState = "exited"
 	StateError    ContainerState = "error"
+	// StateOOMKilled 容器被内核OOM killer终止，从StateError中拆分出来，
+	// 使调用方不必再解析ExitReason字符串就能区分"命中内存上限"与其他错误
+	StateOOMKilled ContainerState = "oom_killed"
 )
 
 // ContainerInfo 容器信息结构体
@@ -29,6 +32,17 @@ type ContainerInfo struct {
 	Privileged bool              `json:"privileged,omitempty"`
 	Name       string            `json:"name,omitempty"` // 容器名称
 	Port       int               `json:"port,omitempty"` // 占用的端口号
+	// OOMKilled 对应Docker State.OOMKilled，为true表示容器是被内核OOM killer终止的，
+	// 用于和学生程序自身崩溃、管理员主动停止区分开
+	OOMKilled bool `json:"oomKilled,omitempty"`
+	// ExitReason 对容器退出原因的简要归类，取值见 exitReasonFromState：
+	// "oom_killed"、"completed"、"exit_code_N"、"error: ..."
+	ExitReason string `json:"exitReason,omitempty"`
+	// FinishedAt 对应Docker State.FinishedAt，容器仍在运行或尚未有过结束记录时为零值
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+	// IsOneTimeExecution 标记该容器是否为一次性执行（退出码0即视为正常完成，不触发Supervisor重启），
+	// 随 LabelOneTime 标签持久化，loadExistingContainers 重启后据此恢复
+	IsOneTimeExecution bool `json:"isOneTimeExecution,omitempty"`
 }
 
 // ContainerConfig 容器配置结构体
@@ -42,6 +56,60 @@ type ContainerConfig struct {
 	MemoryLimit int64             `json:"memoryLimit,omitempty"`
 	CPULimit    float64           `json:"cpuLimit,omitempty"`
 	Privileged  bool              `json:"privileged,omitempty"`
+	// Platform 指定目标平台，形如 "linux/amd64" 或 "linux/arm64/v8"，为空表示不限定，交由daemon决定
+	Platform string `json:"platform,omitempty"`
+	// Security 配置SELinux/AppArmor相关选项，nil表示不做任何特殊处理
+	Security *SecurityOptions `json:"security,omitempty"`
+	// Resources 课程级别的资源限制与加固选项，nil表示全部交由 applyResourcePolicy 套用默认值
+	Resources *ContainerResources `json:"resources,omitempty"`
+	// IsOneTimeExecution 标记该容器是否为一次性执行，写入LabelOneTime标签，详见 ContainerInfo.IsOneTimeExecution
+	IsOneTimeExecution bool `json:"isOneTimeExecution,omitempty"`
+}
+
+// ContainerResources 对应 course.DockerHostConfig，承载容器创建时要下发到 HostConfig 的
+// 资源限制与加固选项；零值字段表示"未指定"，由 applyResourcePolicy 套用策略默认值
+type ContainerResources struct {
+	CPUShares int64
+	CPUQuota  int64
+	// CPUPeriod 与CPUQuota配套的调度周期（微秒），留空（<=0）且CPUQuota>0时
+	// applyResourcePolicy套用Docker常见的100000（即100ms）默认值
+	CPUPeriod int64
+	// NanoCPUs 以十亿分之一CPU为单位的限制，与CPUShares/CPUQuota是Docker提供的两套
+	// 不同粒度的CPU限流机制，课程可以二选一，同时设置时两者都会下发给HostConfig
+	NanoCPUs       int64
+	MemoryMB       int64
+	MemorySwapMB   int64
+	PidsLimit      int64
+	ReadOnlyRootfs bool
+	CapDrop        []string
+	SecurityOpt    []string
+	Tmpfs          map[string]string
+	Ulimits        []ContainerUlimit
+	// BlkioWeight 块设备IO相对权重，取值范围10-1000，0表示不设置
+	BlkioWeight uint16
+	// OomScoreAdj 调整该容器主进程在宿主机OOM killer中的优先级，取值范围-1000到1000，
+	// 值越大越优先被杀掉；0是Docker默认值，与"未设置"无法区分，课程需要豁免时应显式传负值
+	OomScoreAdj int
+}
+
+// ContainerUlimit 对应 course.Ulimit
+type ContainerUlimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// SecurityOptions 描述容器的强制访问控制（MAC）相关配置，用于SELinux/AppArmor开启的宿主机
+type SecurityOptions struct {
+	// SELinuxLabel 写入 HostConfig.SecurityOpt 的 "label:..." 选项，为空表示不设置
+	SELinuxLabel string `json:"selinuxLabel,omitempty"`
+	// AppArmorProfile 写入 HostConfig.SecurityOpt 的 "apparmor=..." 选项，为空表示不设置
+	AppArmorProfile string `json:"apparmorProfile,omitempty"`
+	// RelabelShared 为true时给所有 bind mount 追加 "z" 后缀，允许多个容器共享同一份重新标记的内容
+	RelabelShared bool `json:"relabelShared,omitempty"`
+	// RelabelPrivate 为true时给所有 bind mount 追加 "Z" 后缀，仅本容器可访问重新标记的内容；
+	// 与 RelabelShared 同时为true时以 RelabelShared（"z"）优先
+	RelabelPrivate bool `json:"relabelPrivate,omitempty"`
 }
 
 // PortConflictInfo 端口冲突信息结构体
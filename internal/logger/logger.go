@@ -1,9 +1,16 @@
 package logger
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 )
 
 // LogLevel 定义日志级别类型
@@ -25,19 +32,43 @@ var LogLevelNames = map[LogLevel]string{
 	ERROR: "ERROR",
 }
 
+// 日志输出格式
+const (
+	FormatText = "text" // 默认格式，兼容原有 "[INFO] msg" 输出
+	FormatJSON = "json" // 结构化JSON行，供Loki/jq等工具按字段过滤
+)
+
 // ----------------------------
 // 全局日志级别覆盖控制
 // ----------------------------
-// globalLevelOverride 若不为 nil，则所有新创建的 Logger 都使用该级别
-// 设计意图：便于通过配置/环境变量统一控制日志级别，避免模块各自硬编码级别导致不一致
+// globalLevelOverride 若不为 nil，则所有 Logger 都使用该级别
+// 设计意图：便于通过配置/环境变量统一控制日志级别，避免模块各自硬编码级别导致不一致；
+// shouldLog 每次调用都会重新读取该值（而不是在 NewLogger 时快照），因此 SetGlobalLevel
+// 对已创建的 Logger 同样立即生效，配合 /api/admin/log-level 实现不重启热调级别
 var globalLevelOverride *LogLevel
 
-// SetGlobalLevel 设置全局日志级别覆盖
+// levelSubsMu/levelSubs 全局日志级别变更的订阅者列表，用法与 docker.Supervisor.Subscribe 一致：
+// SetGlobalLevel 变更时向每个订阅者非阻塞地投递一次，channel已满（订阅方处理不及时）则丢弃该次通知
+var (
+	levelSubsMu sync.Mutex
+	levelSubs   []chan LogLevel
+)
+
+// SetGlobalLevel 设置全局日志级别覆盖，并广播给所有 SubscribeLevelChanges 的订阅者
 // 注意：并发场景下建议在应用启动阶段（单线程）调用一次即可
 func SetGlobalLevel(level LogLevel) {
-	// 赋值给指针，避免基本类型偏执，便于判断是否设置过
 	lvl := level
 	globalLevelOverride = &lvl
+
+	levelSubsMu.Lock()
+	subs := append([]chan LogLevel(nil), levelSubs...)
+	levelSubsMu.Unlock()
+	for _, ch := range subs {
+		select {
+		case ch <- level:
+		default:
+		}
+	}
 }
 
 // GetGlobalLevel 获取当前全局日志级别覆盖（nil 表示未设置）
@@ -45,19 +76,156 @@ func GetGlobalLevel() *LogLevel {
 	return globalLevelOverride
 }
 
+// SubscribeLevelChanges 订阅全局日志级别变更通知，供需要感知 SetGlobalLevel 调用的组件使用
+// （例如多实例部署下把本实例收到的 /api/admin/log-level 变更转发给其他实例）。
+// 返回的cancel函数必须在调用方结束监听时调用，否则channel会一直占用在订阅列表中
+func SubscribeLevelChanges() (<-chan LogLevel, func()) {
+	ch := make(chan LogLevel, 4)
+	levelSubsMu.Lock()
+	levelSubs = append(levelSubs, ch)
+	levelSubsMu.Unlock()
+
+	cancel := func() {
+		levelSubsMu.Lock()
+		defer levelSubsMu.Unlock()
+		for i, c := range levelSubs {
+			if c == ch {
+				levelSubs = append(levelSubs[:i], levelSubs[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}
+	return ch, cancel
+}
+
+// Field 一个结构化日志字段，通过 Logger.With 附加到其后所有的日志条目上
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F 构造一个 Field，便于在调用处以 logger.F("courseId", id) 的形式传参
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// logEntry 异步写入channel中流转的一条日志，ack非空时表示这是Flush()投递的"排空哨兵"，
+// 不写入文件，drainLoop处理到它时只需关闭ack通知调用方
+type logEntry struct {
+	line string // 已完整渲染（含时间戳/级别/字段）的一行文本，写入时只需追加换行
+	ack  chan struct{}
+}
+
+// sink 承载异步落盘所需的共享状态：滚动文件writer、缓冲channel与落盘goroutine的等待组。
+// 从 Logger 中拆出来是为了让 With/WithContext 派生的子Logger可以共享同一个sink而无需
+// 复制 sync.WaitGroup（按值复制已在使用中的WaitGroup会被go vet的copylocks检查标记）
+type sink struct {
+	writer  *rotatingWriter
+	entries chan *logEntry
+	wg      sync.WaitGroup
+}
+
 // Logger 日志记录器结构体
+// 默认（NewLogger）下保持原有的同步行为：直接经标准库log包（或JSON格式下直接写stderr）输出。
+// 通过 NewRotatingLogger 创建时额外持有一个滚动文件sink，Debug/Info/Warn/Error只负责把
+// 已渲染好的日志行投递到channel，由单独的goroutine异步落盘，使LoadCourses等紧凑循环中的
+// 高频日志调用不阻塞在磁盘IO上
 type Logger struct {
-	level LogLevel // 当前日志级别
+	level  LogLevel
+	format string // FormatText（默认）或 FormatJSON
+	fields []Field
+
+	sink *sink // nil 表示同步模式
 }
 
-// NewLogger 创建新的日志记录器实例
-// 优先使用全局覆盖的日志级别，其次使用调用方提供的级别
+// NewLogger 创建新的日志记录器实例（同步、默认文本格式，不落盘滚动文件）
 func NewLogger(level LogLevel) *Logger {
-	// 提前返回，减少嵌套，提升可读性
-	if gl := GetGlobalLevel(); gl != nil {
-		return &Logger{level: *gl}
+	return &Logger{level: level, format: FormatText}
+}
+
+// RotateInterval 按时间切分日志文件的粒度
+type RotateInterval int
+
+const (
+	// RotateNone 不按时间切分，只依据 RotateOptions.MaxSizeBytes 按大小滚动
+	RotateNone RotateInterval = iota
+	// RotateDaily 每天切分一个新文件，文件名形如 "name-YYYYMMDD.log"
+	RotateDaily
+	// RotateHourly 每小时切分一个新文件，文件名形如 "name-YYYYMMDD-HH.log"
+	RotateHourly
+)
+
+// RotateOptions 配置 NewRotatingLogger 的滚动策略
+type RotateOptions struct {
+	MaxSizeBytes int64          // 当前文件达到该大小后在同一时间段内再滚动一次，<=0 表示不按大小滚动
+	MaxAge       time.Duration  // 超过该时长的历史日志文件会被清理，<=0 表示不按时间清理
+	MaxBackups   int            // 保留的历史日志文件数量上限，<=0 表示不限制
+	Interval     RotateInterval // 按小时/按天切分文件
+	BufferSize   int            // 异步写入channel的缓冲大小，<=0 时使用默认值(1024)
+}
+
+// NewRotatingLogger 创建写入滚动日志文件的Logger：按 opts.Interval 切分文件（小时/天），
+// 同一时间段内文件超过 opts.MaxSizeBytes 再按大小追加滚动，opts.MaxAge/MaxBackups 控制历史文件清理。
+// path 为日志文件路径，例如 "data/logs/app.log"；实际文件名会按切分策略追加时间后缀
+func NewRotatingLogger(path string, level LogLevel, opts RotateOptions) (*Logger, error) {
+	w, err := newRotatingWriter(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	bufSize := opts.BufferSize
+	if bufSize <= 0 {
+		bufSize = 1024
+	}
+
+	l := &Logger{
+		level:  level,
+		format: FormatText,
+		sink: &sink{
+			writer:  w,
+			entries: make(chan *logEntry, bufSize),
+		},
+	}
+	l.sink.wg.Add(1)
+	go l.sink.drainLoop()
+	return l, nil
+}
+
+// drainLoop 持续从entries读取日志条目并落盘，entries被关闭（Close）后退出
+func (s *sink) drainLoop() {
+	defer s.wg.Done()
+	for entry := range s.entries {
+		if entry.ack != nil {
+			close(entry.ack)
+			continue
+		}
+		if _, err := s.writer.Write([]byte(entry.line + "\n")); err != nil {
+			log.Printf("[logger] 写入日志文件失败: %v", err)
+		}
 	}
-	return &Logger{level: level}
+}
+
+// Flush 阻塞直至当前已投递到异步channel中的日志全部落盘
+// 同步模式（NewLogger创建）下为空操作；通常在进程退出前调用一次
+func (l *Logger) Flush() {
+	if l.sink == nil {
+		return
+	}
+	ack := make(chan struct{})
+	l.sink.entries <- &logEntry{ack: ack}
+	<-ack
+}
+
+// Close 停止异步写入goroutine并关闭底层日志文件，Flush后在Logger不再使用时调用
+// 同步模式（NewLogger创建）下为空操作
+func (l *Logger) Close() error {
+	if l.sink == nil {
+		return nil
+	}
+	close(l.sink.entries)
+	l.sink.wg.Wait()
+	return l.sink.writer.Close()
 }
 
 // ParseLogLevel 从字符串解析日志级别
@@ -76,48 +244,190 @@ func ParseLogLevel(levelStr string) LogLevel {
 	}
 }
 
-// shouldLog 检查是否应该记录指定级别的日志
+// ParseLogFormat 从字符串解析日志格式，无法识别时退化为 FormatText
+func ParseLogFormat(formatStr string) string {
+	if strings.EqualFold(formatStr, FormatJSON) {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// shouldLog 检查是否应该记录指定级别的日志；全局覆盖存在时优先于Logger自身的级别，
+// 且每次都重新读取 GetGlobalLevel()，使 SetGlobalLevel 对已创建的 Logger 立即生效
 func (l *Logger) shouldLog(level LogLevel) bool {
+	if gl := GetGlobalLevel(); gl != nil {
+		return level >= *gl
+	}
 	return level >= l.level
 }
 
-// formatMessage 格式化日志消息，添加级别标识
-func (l *Logger) formatMessage(level LogLevel, format string, args ...interface{}) string {
-	levelName := LogLevelNames[level]
-	message := fmt.Sprintf(format, args...)
-	return fmt.Sprintf("[%s] %s", levelName, message)
+// With 返回一个携带额外字段的子Logger，原Logger不受影响；字段按添加顺序合并，
+// 相同Key的新字段不会覆盖旧值（全部原样输出），与结构化日志惯例一致
+func (l *Logger) With(fields ...Field) *Logger {
+	if len(fields) == 0 {
+		return l
+	}
+	child := *l
+	child.fields = append(append([]Field(nil), l.fields...), fields...)
+	return &child
+}
+
+// WithContext 从ctx中提取由 ContextWithRequestID/CourseID/UserID/SessionID 注入的上下文字段
+// （典型来源：requestid.Middleware 为每个HTTP请求生成的requestID），返回附带这些字段的子Logger；
+// ctx中不存在任何已知字段时返回l本身，避免无意义的拷贝
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	var fields []Field
+	if v, ok := ctx.Value(ctxKeyRequestID).(string); ok && v != "" {
+		fields = append(fields, Field{"requestId", v})
+	}
+	if v, ok := ctx.Value(ctxKeyCourseID).(string); ok && v != "" {
+		fields = append(fields, Field{"courseId", v})
+	}
+	if v, ok := ctx.Value(ctxKeyUserID).(string); ok && v != "" {
+		fields = append(fields, Field{"userId", v})
+	}
+	if v, ok := ctx.Value(ctxKeySessionID).(string); ok && v != "" {
+		fields = append(fields, Field{"sessionId", v})
+	}
+	return l.With(fields...)
+}
+
+// contextKey 私有类型，避免与其他包注入context的key发生碰撞
+type contextKey int
+
+const (
+	ctxKeyRequestID contextKey = iota
+	ctxKeyCourseID
+	ctxKeyUserID
+	ctxKeySessionID
+)
+
+// ContextWithRequestID 把requestID注入ctx，供后续 WithContext 提取
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKeyRequestID, id)
+}
+
+// ContextWithCourseID 把courseID注入ctx，供后续 WithContext 提取
+func ContextWithCourseID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKeyCourseID, id)
+}
+
+// ContextWithUserID 把userID注入ctx，供后续 WithContext 提取
+func ContextWithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKeyUserID, id)
+}
+
+// ContextWithSessionID 把sessionID注入ctx，供后续 WithContext 提取
+func ContextWithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKeySessionID, id)
+}
+
+// RequestIDFromContext 读取由 ContextWithRequestID 注入的requestID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyRequestID).(string)
+	return v
+}
+
+// renderText 渲染为原有的可读文本格式："[LEVEL] msg key=value ..."，
+// 落盘场景（sink非nil）额外带上时间戳与调用方file:line，与历史行为一致
+func (l *Logger) renderText(level LogLevel, msg, file string, line int, t time.Time) string {
+	var b strings.Builder
+	if l.sink != nil {
+		b.WriteString(t.Format("2006-01-02T15:04:05.000Z07:00"))
+		b.WriteByte(' ')
+	}
+	fmt.Fprintf(&b, "[%s] ", LogLevelNames[level])
+	if l.sink != nil {
+		fmt.Fprintf(&b, "%s:%d ", file, line)
+	}
+	b.WriteString(msg)
+	for _, f := range l.fields {
+		fmt.Fprintf(&b, " %s=%v", f.Key, f.Value)
+	}
+	return b.String()
+}
+
+// renderJSON 渲染为单行JSON，字段固定包含 ts/level/msg/caller，再合并 l.fields，
+// 供Loki等工具按字段过滤（text格式下这些信息分散在一行字符串中，难以被机器解析）
+func (l *Logger) renderJSON(level LogLevel, msg, file string, line int, t time.Time) string {
+	record := make(map[string]interface{}, 4+len(l.fields))
+	record["ts"] = t.Format(time.RFC3339Nano)
+	record["level"] = LogLevelNames[level]
+	record["msg"] = msg
+	record["caller"] = fmt.Sprintf("%s:%d", file, line)
+	for _, f := range l.fields {
+		record[f.Key] = f.Value
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		// 序列化失败时退化为文本格式，保证这条日志本身不会丢失
+		return l.renderText(level, msg, file, line, t)
+	}
+	return string(data)
+}
+
+// log 是Debug/Info/Warn/Error的共同实现：同步模式下文本格式经标准库log输出（保留原有行为），
+// JSON格式下直接写stderr（避免stdlib log自带的时间前缀污染JSON行）；
+// 异步模式下把渲染好的整行文本投递到sink.entries，channel已满时降级为同步输出以避免悄悄丢日志
+func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
+	if !l.shouldLog(level) {
+		return
+	}
+
+	file, line := callerLocation()
+	msg := fmt.Sprintf(format, args...)
+	t := time.Now()
+
+	var rendered string
+	if l.format == FormatJSON {
+		rendered = l.renderJSON(level, msg, file, line, t)
+	} else {
+		rendered = l.renderText(level, msg, file, line, t)
+	}
+
+	if l.sink == nil {
+		if l.format == FormatJSON {
+			fmt.Fprintln(os.Stderr, rendered)
+		} else {
+			log.Print(rendered)
+		}
+		return
+	}
+
+	select {
+	case l.sink.entries <- &logEntry{line: rendered}:
+	default:
+		log.Print(rendered)
+	}
+}
+
+// callerLocation 返回Debug/Info/Warn/Error的调用方文件名（不含目录）与行号
+func callerLocation() (string, int) {
+	_, file, line, ok := runtime.Caller(3)
+	if !ok {
+		return "???", 0
+	}
+	return filepath.Base(file), line
 }
 
 // Debug 记录DEBUG级别日志
 func (l *Logger) Debug(format string, args ...interface{}) {
-	if l.shouldLog(DEBUG) {
-		message := l.formatMessage(DEBUG, format, args...)
-		log.Print(message)
-	}
+	l.log(DEBUG, format, args...)
 }
 
 // Info 记录INFO级别日志
 func (l *Logger) Info(format string, args ...interface{}) {
-	if l.shouldLog(INFO) {
-		message := l.formatMessage(INFO, format, args...)
-		log.Print(message)
-	}
+	l.log(INFO, format, args...)
 }
 
 // Warn 记录WARN级别日志
 func (l *Logger) Warn(format string, args ...interface{}) {
-	if l.shouldLog(WARN) {
-		message := l.formatMessage(WARN, format, args...)
-		log.Print(message)
-	}
+	l.log(WARN, format, args...)
 }
 
 // Error 记录ERROR级别日志
 func (l *Logger) Error(format string, args ...interface{}) {
-	if l.shouldLog(ERROR) {
-		message := l.formatMessage(ERROR, format, args...)
-		log.Print(message)
-	}
+	l.log(ERROR, format, args...)
 }
 
 // SetLevel 设置日志级别
@@ -129,3 +439,13 @@ func (l *Logger) SetLevel(level LogLevel) {
 func (l *Logger) GetLevel() LogLevel {
 	return l.level
 }
+
+// SetFormat 设置输出格式（FormatText 或 FormatJSON），未调用时默认为 FormatText
+func (l *Logger) SetFormat(format string) {
+	l.format = ParseLogFormat(format)
+}
+
+// GetFormat 获取当前输出格式
+func (l *Logger) GetFormat() string {
+	return l.format
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatingWriter 实现按大小与按小时/天切分的滚动文件写入
+// 两种策略叠加生效：先按 Interval 决定"当前应该写入哪个时间段的文件"，
+// 时间段内文件超过 MaxSizeBytes 时再按大小追加滚动（文件名追加 ".N" 序号）
+type rotatingWriter struct {
+	mu   sync.Mutex
+	dir  string
+	base string // 不含时间后缀与扩展名的文件名前缀，例如 "app"
+	ext  string // 扩展名，默认 ".log"
+	opts RotateOptions
+
+	file       *os.File
+	currentKey string // 当前时间段标识，例如 Interval=RotateHourly 时为 "20060102-15"
+	size       int64
+	seq        int // 当前时间段内的大小滚动序号，0表示尚未发生过大小滚动
+}
+
+// newRotatingWriter 创建滚动writer并立即打开当前时间段对应的日志文件
+func newRotatingWriter(path string, opts RotateOptions) (*rotatingWriter, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(filepath.Base(path), ext)
+	if ext == "" {
+		ext = ".log"
+	}
+
+	w := &rotatingWriter{dir: dir, base: base, ext: ext, opts: opts}
+	if err := w.openLocked(time.Now(), w.timeKey(time.Now()), 0); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 实现io.Writer，按需滚动后写入当前文件
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	key := w.timeKey(now)
+	switch {
+	case w.file == nil || key != w.currentKey:
+		if err := w.openLocked(now, key, 0); err != nil {
+			return 0, err
+		}
+	case w.opts.MaxSizeBytes > 0 && w.size+int64(len(p)) > w.opts.MaxSizeBytes:
+		if err := w.openLocked(now, key, w.seq+1); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// timeKey 根据 Interval 计算当前所属的时间段标识，RotateNone时返回空字符串（不按时间切分）
+func (w *rotatingWriter) timeKey(t time.Time) string {
+	switch w.opts.Interval {
+	case RotateHourly:
+		return t.Format("20060102-15")
+	case RotateDaily:
+		return t.Format("20060102")
+	default:
+		return ""
+	}
+}
+
+// pathFor 拼出指定时间段、指定大小滚动序号对应的文件路径
+func (w *rotatingWriter) pathFor(key string, seq int) string {
+	name := w.base
+	if key != "" {
+		name += "-" + key
+	}
+	if seq > 0 {
+		name += fmt.Sprintf(".%d", seq)
+	}
+	return filepath.Join(w.dir, name+w.ext)
+}
+
+// openLocked 关闭当前文件（如果有）并打开新的时间段/序号对应的文件，调用方必须持有 w.mu
+func (w *rotatingWriter) openLocked(now time.Time, key string, seq int) error {
+	if w.file != nil {
+		w.file.Close()
+	}
+
+	path := w.pathFor(key, seq)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("创建日志文件失败: %w", err)
+	}
+
+	w.file = f
+	w.currentKey = key
+	w.seq = seq
+	w.size = 0
+	if info, err := f.Stat(); err == nil {
+		w.size = info.Size()
+	}
+
+	w.cleanupLocked()
+	return nil
+}
+
+// cleanupLocked 按 MaxBackups/MaxAge 清理历史日志文件，调用方必须持有 w.mu
+func (w *rotatingWriter) cleanupLocked() {
+	if w.opts.MaxBackups <= 0 && w.opts.MaxAge <= 0 {
+		return
+	}
+
+	matches, err := filepath.Glob(filepath.Join(w.dir, w.base+"*"+w.ext))
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, m := range matches {
+		if w.file != nil && m == w.file.Name() {
+			continue // 当前正在写入的文件不参与清理
+		}
+		info, err := os.Stat(m)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: m, modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].modTime.After(backups[j].modTime) })
+
+	now := time.Now()
+	for i, b := range backups {
+		expired := w.opts.MaxAge > 0 && now.Sub(b.modTime) > w.opts.MaxAge
+		overflow := w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups
+		if expired || overflow {
+			os.Remove(b.path)
+		}
+	}
+}
+
+// Close 关闭当前持有的底层文件
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
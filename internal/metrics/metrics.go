@@ -0,0 +1,175 @@
+// Package metrics 定义对外暴露的 Prometheus 指标，以及 docker.Controller / sql.Driver
+// 上报这些指标所需的最小 Recorder 接口。两者只依赖 Recorder 接口而不直接依赖 prometheus，
+// 避免把埋点散落进业务逻辑、让 Controller/Driver 退化成测量用的 god-object。
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	containerStartTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kwdb_playground_container_start_total",
+		Help: "课程容器启动尝试次数，按课程与结果分类",
+	}, []string{"course", "outcome"})
+
+	containerStartDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kwdb_playground_container_start_seconds",
+		Help:    "课程容器启动耗时",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"course"})
+
+	imagePullBytesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kwdb_playground_image_pull_bytes_total",
+		Help: "镜像拉取/导入累计字节数，按镜像源分类",
+	}, []string{"registry"})
+
+	sqlQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kwdb_playground_sql_query_duration_seconds",
+		Help:    "结构化 SQL 查询耗时",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"courseId"})
+
+	containersRunning = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kwdb_playground_containers_running",
+		Help: "当前由 docker.Controller 管理的运行中容器数，按课程分类",
+	}, []string{"course_id"})
+
+	checkItem = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kwdb_playground_check_item",
+		Help: "最近一次环境检查各项结果，value 恒为 1，按检查项名称与是否通过分类",
+	}, []string{"name", "ok"})
+
+	wsSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "kwdb_playground_ws_sessions",
+		Help: "当前活跃的 WebSocket 会话数（终端 + SQL）",
+	})
+
+	imageCacheResultTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kwdb_playground_image_cache_result_total",
+		Help: "ImageManager 引用计数缓存命中/未命中次数，按镜像与结果分类",
+	}, []string{"image", "result"})
+
+	prewarmPoolDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kwdb_playground_prewarm_pool_depth",
+		Help: "PrewarmPool 当前每个镜像保有的空闲预热容器数",
+	}, []string{"image"})
+
+	courseResourceUsage = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kwdb_playground_course_resource_usage",
+		Help: "ResourceGovernor 统计的课程聚合资源用量快照，按课程与资源种类分类（memory_bytes/cpu_percent/pids等）",
+	}, []string{"course_id", "resource"})
+
+	quotaRejectedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kwdb_playground_quota_rejected_total",
+		Help: "ResourceGovernor 因课程聚合配额超限而拒绝的容器创建次数，按课程与超限的资源种类分类",
+	}, []string{"course_id", "resource"})
+)
+
+// CheckItemStatus 是 check.RunFromConfig 上报单项检查结果所需的最小信息，
+// 定义在本包而非依赖 internal/check，避免两包互相导入
+type CheckItemStatus struct {
+	Name string
+	OK   bool
+}
+
+// Recorder 是 docker.Controller / sql.Driver / check.RunFromConfig 上报指标所需的最小接口
+type Recorder interface {
+	ContainerStart(course, outcome string, d time.Duration)
+	ImagePullBytes(registry string, n int64)
+	SQLQuery(courseID string, d time.Duration)
+	SetContainersRunning(counts map[string]int)
+	SetWSSessions(n int)
+	SetCheckItems(items []CheckItemStatus)
+	// ImageCacheResult 记录一次 ImageManager 引用计数缓存命中/未命中
+	ImageCacheResult(image string, hit bool)
+	// SetPrewarmPoolDepth 用 depth（镜像 -> 当前空闲预热容器数）整体替换 prewarm_pool_depth 快照
+	SetPrewarmPoolDepth(depth map[string]int)
+	// SetCourseUsage 用 usage（资源种类 -> 当前值）整体替换某课程的 course_resource_usage 快照
+	SetCourseUsage(courseID string, usage map[string]float64)
+	// QuotaRejected 记录一次因courseID的resource配额超限而被拒绝的容器创建请求
+	QuotaRejected(courseID, resource string)
+}
+
+// promRecorder 是 Recorder 基于本包 Prometheus 指标的默认实现
+type promRecorder struct{}
+
+// DefaultRecorder 是进程内默认注入给 docker.Controller / sql.Driver 的 Recorder
+var DefaultRecorder Recorder = promRecorder{}
+
+func (promRecorder) ContainerStart(course, outcome string, d time.Duration) {
+	containerStartTotal.WithLabelValues(course, outcome).Inc()
+	containerStartDuration.WithLabelValues(course).Observe(d.Seconds())
+}
+
+func (promRecorder) ImagePullBytes(registry string, n int64) {
+	if n > 0 {
+		imagePullBytesTotal.WithLabelValues(registry).Add(float64(n))
+	}
+}
+
+func (promRecorder) SQLQuery(courseID string, d time.Duration) {
+	sqlQueryDuration.WithLabelValues(courseID).Observe(d.Seconds())
+}
+
+// SetContainersRunning 用 counts（课程ID -> 运行中容器数）整体替换当前的 containers_running 快照，
+// 先 Reset 再逐个 Set，避免已清空课程残留旧值
+func (promRecorder) SetContainersRunning(counts map[string]int) {
+	containersRunning.Reset()
+	for courseID, n := range counts {
+		containersRunning.WithLabelValues(courseID).Set(float64(n))
+	}
+}
+
+func (promRecorder) SetWSSessions(n int) {
+	wsSessions.Set(float64(n))
+}
+
+// SetCheckItems 用本次检查的 items 整体替换当前的 check_item 快照
+func (promRecorder) SetCheckItems(items []CheckItemStatus) {
+	checkItem.Reset()
+	for _, it := range items {
+		checkItem.WithLabelValues(it.Name, strconv.FormatBool(it.OK)).Set(1)
+	}
+}
+
+func (promRecorder) ImageCacheResult(image string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	imageCacheResultTotal.WithLabelValues(image, result).Inc()
+}
+
+// SetPrewarmPoolDepth 用 depth 整体替换当前的 prewarm_pool_depth 快照，先 Reset 再逐个 Set，
+// 避免已耗尽/已下线镜像的预热池残留旧值
+func (promRecorder) SetPrewarmPoolDepth(depth map[string]int) {
+	prewarmPoolDepth.Reset()
+	for image, n := range depth {
+		prewarmPoolDepth.WithLabelValues(image).Set(float64(n))
+	}
+}
+
+// SetCourseUsage 用 usage 整体替换courseID在 course_resource_usage 下的快照，先清空该课程的
+// 旧标签值再逐个 Set，避免已停用的资源种类残留旧值
+func (promRecorder) SetCourseUsage(courseID string, usage map[string]float64) {
+	courseResourceUsage.DeletePartialMatch(prometheus.Labels{"course_id": courseID})
+	for resource, v := range usage {
+		courseResourceUsage.WithLabelValues(courseID, resource).Set(v)
+	}
+}
+
+func (promRecorder) QuotaRejected(courseID, resource string) {
+	quotaRejectedTotal.WithLabelValues(courseID, resource).Inc()
+}
+
+// Handler 返回 /metrics 端点的 http.Handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
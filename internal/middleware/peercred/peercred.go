@@ -0,0 +1,55 @@
+// Package peercred 从 Unix 域套接字连接中读取对端进程的 uid（Linux 下为 SO_PEERCRED），
+// 供 server.Run 在注册 unix:// 监听地址时，为来自本机的调用方豁免 JWT 鉴权提供依据——
+// 与 dockerd 默认信任 unix:///var/run/docker.sock 本地调用方的模型一致。
+// 仅 Linux 支持该能力，其余平台下 extractUID 恒返回 false，IsLocalPeer 相应恒为 false，
+// 即豁免逻辑自动退化为不生效（维持鉴权历史行为），具体实现拆分在 peercred_<os>.go。
+package peercred
+
+import (
+	"context"
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ctxKey struct{}
+
+var connCtxKey = ctxKey{}
+
+const ginContextKey = "peerUID"
+
+// ConnContext 供 http.Server.ConnContext 使用：在连接建立时读取Unix域套接字对端的uid并存入请求上下文；
+// 非 Unix 域套接字连接（如 TCP 连接）原样返回ctx，不做任何注入
+func ConnContext(ctx context.Context, conn net.Conn) context.Context {
+	uid, ok := extractUID(conn)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, connCtxKey, uid)
+}
+
+// Middleware 把 ConnContext 注入的uid从请求上下文透传到Gin上下文，供 UID/IsLocalPeer 读取
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if uid, ok := c.Request.Context().Value(connCtxKey).(uint32); ok {
+			c.Set(ginContextKey, uid)
+		}
+		c.Next()
+	}
+}
+
+// UID 返回当前请求对端的uid；ok为false表示请求并非经由本机Unix域套接字到达，或当前平台不支持SO_PEERCRED
+func UID(c *gin.Context) (uint32, bool) {
+	v, exists := c.Get(ginContextKey)
+	if !exists {
+		return 0, false
+	}
+	uid, ok := v.(uint32)
+	return uid, ok
+}
+
+// IsLocalPeer 判断当前请求是否经由本机 Unix 域套接字到达
+func IsLocalPeer(c *gin.Context) bool {
+	_, ok := UID(c)
+	return ok
+}
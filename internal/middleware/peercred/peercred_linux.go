@@ -0,0 +1,33 @@
+//go:build linux
+
+package peercred
+
+import (
+	"net"
+	"syscall"
+)
+
+// extractUID 通过 SO_PEERCRED 读取 Unix 域套接字对端进程的 uid（仅 Linux 支持）
+func extractUID(conn net.Conn) (uint32, bool) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return 0, false
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+	var uid uint32
+	var sockErr error
+	if ctrlErr := raw.Control(func(fd uintptr) {
+		ucred, err := syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+		if err != nil {
+			sockErr = err
+			return
+		}
+		uid = ucred.Uid
+	}); ctrlErr != nil {
+		return 0, false
+	}
+	return uid, sockErr == nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package peercred
+
+import "net"
+
+// extractUID 在非 Linux 平台上没有 SO_PEERCRED 的等价实现，恒返回 false
+func extractUID(conn net.Conn) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,53 @@
+// Package requestid 为每个HTTP请求生成/透传一个requestID，并注入Gin与请求上下文，
+// 供 internal/logger 的 Logger.WithContext 提取后附加到该请求产生的每一条结构化日志上，
+// 便于在JSON日志里按requestID把一次请求的全部日志行串联起来排查问题。
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"kwdb-playground/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderName 承载requestID的请求/响应头，与Nginx/网关常见约定一致
+const HeaderName = "X-Request-Id"
+
+const ginContextKey = "requestID"
+
+// Middleware 优先复用客户端/上游网关传入的 X-Request-Id，不存在时生成一个新的；
+// 写入Gin上下文供Header/ID读取，并写入请求上下文供 logger.WithContext 提取
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HeaderName)
+		if id == "" {
+			id = newRequestID()
+		}
+
+		c.Set(ginContextKey, id)
+		c.Request = c.Request.WithContext(logger.ContextWithRequestID(c.Request.Context(), id))
+		c.Header(HeaderName, id)
+
+		c.Next()
+	}
+}
+
+// ID 返回当前请求的requestID，未经过 Middleware 时返回空字符串
+func ID(c *gin.Context) string {
+	v, _ := c.Get(ginContextKey)
+	id, _ := v.(string)
+	return id
+}
+
+// newRequestID 生成一个16字节随机requestID的十六进制表示
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		// crypto/rand 读取失败极其罕见（内核熵源异常），退化为固定占位而不是panic，
+		// 不影响请求处理本身，只是这一条requestID不再具备唯一性
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
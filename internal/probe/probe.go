@@ -0,0 +1,170 @@
+// Package probe 实现课程容器的就绪/存活探测，语义上参考 Kubernetes 的 readinessProbe/livenessProbe
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TCPSocketAction 通过尝试建立 TCP 连接判断目标端口是否就绪
+type TCPSocketAction struct {
+	Port int    `json:"port" yaml:"port"`
+	Host string `json:"host,omitempty" yaml:"host,omitempty"` // 默认 127.0.0.1
+}
+
+// HTTPGetAction 通过 HTTP GET 请求判断就绪，2xx/3xx 视为成功
+type HTTPGetAction struct {
+	Path string `json:"path,omitempty" yaml:"path,omitempty"` // 默认 "/"
+	Port int    `json:"port" yaml:"port"`
+	Host string `json:"host,omitempty" yaml:"host,omitempty"` // 默认 127.0.0.1
+}
+
+// ExecAction 在容器内执行命令，退出码为 0 视为成功
+type ExecAction struct {
+	Command []string `json:"command" yaml:"command"`
+}
+
+// Spec 探针配置，等价于课程 YAML 中的 backend.readinessProbe / backend.livenessProbe
+type Spec struct {
+	TCPSocket *TCPSocketAction `json:"tcpSocket,omitempty" yaml:"tcpSocket,omitempty"`
+	HTTPGet   *HTTPGetAction   `json:"httpGet,omitempty" yaml:"httpGet,omitempty"`
+	Exec      *ExecAction      `json:"exec,omitempty" yaml:"exec,omitempty"`
+
+	// InitialDelaySeconds 容器启动后，首次探测前的等待时间
+	InitialDelaySeconds int `json:"initialDelaySeconds,omitempty" yaml:"initialDelaySeconds,omitempty"`
+	// PeriodSeconds 探测间隔，默认 2 秒
+	PeriodSeconds int `json:"periodSeconds,omitempty" yaml:"periodSeconds,omitempty"`
+	// FailureThreshold 连续失败多少次后判定探测失败，默认 15 次
+	FailureThreshold int `json:"failureThreshold,omitempty" yaml:"failureThreshold,omitempty"`
+}
+
+// Executor 执行 exec 类型探针所需的最小依赖，通常由 docker.Controller 实现
+type Executor interface {
+	ExecCommand(ctx context.Context, containerID string, cmd []string) (string, error)
+}
+
+// IsZero 判断探针是否为空配置（未设置任何探测方式）
+func (s *Spec) IsZero() bool {
+	return s == nil || (s.TCPSocket == nil && s.HTTPGet == nil && s.Exec == nil)
+}
+
+func (s *Spec) periodSeconds() time.Duration {
+	if s.PeriodSeconds <= 0 {
+		return 2 * time.Second
+	}
+	return time.Duration(s.PeriodSeconds) * time.Second
+}
+
+func (s *Spec) failureThreshold() int {
+	if s.FailureThreshold <= 0 {
+		return 15
+	}
+	return s.FailureThreshold
+}
+
+// Wait 按照探针配置反复探测，直到成功、达到失败阈值或 ctx 被取消
+// containerID 仅在探针类型为 exec 时需要，由 executor 在容器内运行命令
+// onAttempt 每次探测后被调用一次，用于上报进度（例如驱动 SSE 事件流），可为 nil
+func Wait(ctx context.Context, spec *Spec, containerID string, executor Executor, onAttempt func(attempt int, err error)) error {
+	if spec.IsZero() {
+		return nil
+	}
+
+	if spec.InitialDelaySeconds > 0 {
+		select {
+		case <-time.After(time.Duration(spec.InitialDelaySeconds) * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	threshold := spec.failureThreshold()
+	period := spec.periodSeconds()
+
+	var lastErr error
+	for attempt := 1; attempt <= threshold; attempt++ {
+		lastErr = check(ctx, spec, containerID, executor)
+		if onAttempt != nil {
+			onAttempt(attempt, lastErr)
+		}
+		if lastErr == nil {
+			return nil
+		}
+		if attempt == threshold {
+			break
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("probe failed after %d attempts: %w", threshold, lastErr)
+}
+
+func check(ctx context.Context, spec *Spec, containerID string, executor Executor) error {
+	switch {
+	case spec.TCPSocket != nil:
+		return checkTCPSocket(ctx, spec.TCPSocket)
+	case spec.HTTPGet != nil:
+		return checkHTTPGet(ctx, spec.HTTPGet)
+	case spec.Exec != nil:
+		return checkExec(ctx, spec.Exec, containerID, executor)
+	default:
+		return nil
+	}
+}
+
+func checkTCPSocket(ctx context.Context, action *TCPSocketAction) error {
+	host := action.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", fmt.Sprintf("%s:%d", host, action.Port))
+	if err != nil {
+		return fmt.Errorf("tcpSocket probe failed: %w", err)
+	}
+	return conn.Close()
+}
+
+func checkHTTPGet(ctx context.Context, action *HTTPGetAction) error {
+	host := action.Host
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	path := action.Path
+	if path == "" {
+		path = "/"
+	}
+	url := fmt.Sprintf("http://%s:%d%s", host, action.Port, path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("httpGet probe build request failed: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("httpGet probe failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("httpGet probe got status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func checkExec(ctx context.Context, action *ExecAction, containerID string, executor Executor) error {
+	if executor == nil {
+		return fmt.Errorf("exec probe requires a container executor")
+	}
+	if containerID == "" {
+		return fmt.Errorf("exec probe requires a containerID")
+	}
+	if _, err := executor.ExecCommand(ctx, containerID, action.Command); err != nil {
+		return fmt.Errorf("exec probe failed: %w", err)
+	}
+	return nil
+}
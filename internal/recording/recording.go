@@ -0,0 +1,200 @@
+// Package recording 实现终端会话的 asciinema v2 格式录制与回放支持。
+// 录制文件（.cast）是按行分隔的 JSON：首行是 Header，此后每行是一个
+// [elapsedSeconds, type, data] 三元组事件；type 为 "o"（输出，镜像拉取进度
+// 行也记为 "o"）或 "r"（终端尺寸调整，data 形如 "80x24"）。
+// 详见 https://docs.asciinema.org/manual/asciicast/v2/
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultWidth/defaultHeight 客户端首次 resize 之前使用的默认终端尺寸
+const (
+	defaultWidth  = 80
+	defaultHeight = 24
+)
+
+// defaultMaxSizeBytes 未配置 course.Backend.Recording.MaxSizeBytes 时的单文件大小上限，
+// 超出后 Recorder 会截断回文件头重新累积，避免一次异常啰嗦的会话把磁盘写满
+const defaultMaxSizeBytes int64 = 10 * 1024 * 1024
+
+// Header asciinema v2 文件头，录制开始时写入的第一行
+type Header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event 已解析的单条录制事件
+type Event struct {
+	Time float64 // 相对录制开始的秒数
+	Type string  // "o" 或 "r"
+	Data string
+}
+
+// Recorder 把一个终端会话的输出/尺寸调整事件写入磁盘上的 .cast 文件
+// 终端会话本就只有一个输出通道（Send），录制只是在其旁路多落一份帧数据，
+// 因此 Recorder 本身不关心会话的生命周期，调用方负责在会话结束时 Close
+type Recorder struct {
+	mu         sync.Mutex
+	file       *os.File
+	start      time.Time
+	headerLine []byte // 文件头的原始字节，轮转时重新写回文件开头
+	written    int64  // 当前文件已写入的字节数（含文件头），用于判断是否需要轮转
+	maxBytes   int64  // 单文件大小上限，超出后 rotate 截断回文件头
+}
+
+// New 创建一个 Recorder，写入文件头后返回；path 所在目录不存在时会被自动创建
+// width/height 小于等于 0 时使用默认终端尺寸；maxBytes 小于等于 0 时使用 defaultMaxSizeBytes
+func New(path string, width, height int, env map[string]string, maxBytes int64) (*Recorder, error) {
+	if width <= 0 {
+		width = defaultWidth
+	}
+	if height <= 0 {
+		height = defaultHeight
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxSizeBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建录制目录失败: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建录制文件失败: %w", err)
+	}
+
+	data, err := json.Marshal(Header{Version: 2, Width: width, Height: height, Timestamp: time.Now().Unix(), Env: env})
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("序列化录制文件头失败: %w", err)
+	}
+	headerLine := append(data, '\n')
+	if _, err := f.Write(headerLine); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("写入录制文件头失败: %w", err)
+	}
+
+	return &Recorder{file: f, start: time.Now(), headerLine: headerLine, written: int64(len(headerLine)), maxBytes: maxBytes}, nil
+}
+
+// WriteOutput 记录一帧输出（"o" 事件）
+func (r *Recorder) WriteOutput(data string) error {
+	return r.writeEvent("o", data)
+}
+
+// WriteResize 记录一次终端尺寸调整（"r" 事件）
+func (r *Recorder) WriteResize(cols, rows int) error {
+	return r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// writeEvent 写入一行 [elapsedSeconds, type, data]，elapsedSeconds 相对录制开始时间；
+// 超出 maxBytes 时先 rotate 截断文件再写入，保证单个会话不会把录制文件撑到无限大
+func (r *Recorder) writeEvent(typ, data string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return fmt.Errorf("录制器已关闭")
+	}
+	line, err := json.Marshal([]interface{}{time.Since(r.start).Seconds(), typ, data})
+	if err != nil {
+		return fmt.Errorf("序列化录制事件失败: %w", err)
+	}
+	line = append(line, '\n')
+
+	if r.written+int64(len(line)) > r.maxBytes {
+		if err := r.rotate(); err != nil {
+			return fmt.Errorf("轮转录制文件失败: %w", err)
+		}
+	}
+
+	n, err := r.file.Write(line)
+	r.written += int64(n)
+	return err
+}
+
+// rotate 把文件截断回只剩文件头，为后续事件腾出空间；elapsed时间戳仍相对录制开始计算，
+// 回放时会看到从头部直接跳到被截断前最后一刻的效果——这是"轮转"而非"分卷"的代价
+func (r *Recorder) rotate() error {
+	if _, err := r.file.Seek(0, 0); err != nil {
+		return err
+	}
+	if err := r.file.Truncate(0); err != nil {
+		return err
+	}
+	n, err := r.file.Write(r.headerLine)
+	if err != nil {
+		return err
+	}
+	r.written = int64(n)
+	return nil
+}
+
+// Close 关闭底层文件，重复调用是安全的
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// ReadCast 解析 .cast 文件，返回文件头与按时间顺序排列的事件列表，供回放端使用
+func ReadCast(path string) (*Header, []Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开录制文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	if !scanner.Scan() {
+		return nil, nil, fmt.Errorf("录制文件为空")
+	}
+	var header Header
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, nil, fmt.Errorf("解析录制文件头失败: %w", err)
+	}
+
+	var events []Event
+	for scanner.Scan() {
+		var raw [3]json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &raw); err != nil {
+			return nil, nil, fmt.Errorf("解析录制事件失败: %w", err)
+		}
+		var (
+			elapsed float64
+			typ     string
+			data    string
+		)
+		if err := json.Unmarshal(raw[0], &elapsed); err != nil {
+			return nil, nil, fmt.Errorf("解析录制事件时间戳失败: %w", err)
+		}
+		if err := json.Unmarshal(raw[1], &typ); err != nil {
+			return nil, nil, fmt.Errorf("解析录制事件类型失败: %w", err)
+		}
+		if err := json.Unmarshal(raw[2], &data); err != nil {
+			return nil, nil, fmt.Errorf("解析录制事件数据失败: %w", err)
+		}
+		events = append(events, Event{Time: elapsed, Type: typ, Data: data})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("读取录制文件失败: %w", err)
+	}
+
+	return &header, events, nil
+}
@@ -0,0 +1,140 @@
+package recording
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store 负责录制文件在磁盘上的定位与查找
+// 目录布局：<dataDir>/recordings/<courseID>/<containerID>_<sessionID>[_progress].cast
+// courseID 为空时落在 "_" 目录下，与 snapshot.Store 按课程分目录的约定保持一致
+type Store struct {
+	dataDir string
+}
+
+// NewStore 基于 config.DataDir 构建录制存储
+func NewStore(dataDir string) *Store {
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	return &Store{dataDir: dataDir}
+}
+
+// Dir 返回录制文件的根目录
+func (s *Store) Dir() string {
+	return filepath.Join(s.dataDir, "recordings")
+}
+
+func (s *Store) courseDir(courseID string) string {
+	if courseID == "" {
+		courseID = "_"
+	}
+	return filepath.Join(s.Dir(), courseID)
+}
+
+// fileName 按 containerID+sessionID 拼出录制文件名；progressOnly 为 true 时使用独立后缀，
+// 避免同一会话的终端录制与镜像拉取进度录制互相覆盖
+func fileName(containerID, sessionID string, progressOnly bool) string {
+	if progressOnly {
+		return fmt.Sprintf("%s_%s_progress.cast", containerID, sessionID)
+	}
+	return fmt.Sprintf("%s_%s.cast", containerID, sessionID)
+}
+
+// Path 返回指定会话录制文件应写入的完整路径，调用前文件不必已存在
+func (s *Store) Path(courseID, containerID, sessionID string, progressOnly bool) string {
+	return filepath.Join(s.courseDir(courseID), fileName(containerID, sessionID, progressOnly))
+}
+
+// Find 按 sessionID 查找已落盘的录制文件（遍历所有课程子目录，因为调用方通常只持有 sessionID）
+// progressOnly 区分查找终端录制还是镜像拉取进度录制
+func (s *Store) Find(sessionID string, progressOnly bool) (string, error) {
+	entries, err := os.ReadDir(s.Dir())
+	if err != nil {
+		return "", fmt.Errorf("读取录制目录失败: %w", err)
+	}
+
+	suffix := "_" + sessionID + ".cast"
+	if progressOnly {
+		suffix = "_" + sessionID + "_progress.cast"
+	}
+
+	for _, courseEntry := range entries {
+		if !courseEntry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(s.Dir(), courseEntry.Name())
+		fileEntries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fe := range fileEntries {
+			if fe.IsDir() {
+				continue
+			}
+			if !progressOnly && strings.HasSuffix(fe.Name(), "_progress.cast") {
+				continue
+			}
+			if strings.HasSuffix(fe.Name(), suffix) {
+				return filepath.Join(dir, fe.Name()), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("会话 %s 的录制文件不存在", sessionID)
+}
+
+// RecordingInfo 单个录制文件的摘要信息，供 listCourseRecordings 这类列表接口展示
+type RecordingInfo struct {
+	CourseID     string    `json:"courseId"`
+	ContainerID  string    `json:"containerId"`
+	SessionID    string    `json:"sessionId"`
+	ProgressOnly bool      `json:"progressOnly"`
+	SizeBytes    int64     `json:"sizeBytes"`
+	ModifiedAt   time.Time `json:"modifiedAt"`
+}
+
+// List 列出指定课程下的所有录制文件摘要；courseID对应的目录不存在时返回空列表而非错误，
+// 因为"这门课还没有任何录制"是正常状态。containerID/sessionID按文件名中第一个"_"切分解析，
+// 目前唯二的容器ID来源（Docker十六进制容器ID、"courseID-纳秒"形式的containerd ID）都不含
+// 下划线，实践中足够准确
+func (s *Store) List(courseID string) ([]RecordingInfo, error) {
+	dir := s.courseDir(courseID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取课程录制目录失败: %w", err)
+	}
+
+	infos := make([]RecordingInfo, 0, len(entries))
+	for _, fe := range entries {
+		if fe.IsDir() || !strings.HasSuffix(fe.Name(), ".cast") {
+			continue
+		}
+		fi, err := fe.Info()
+		if err != nil {
+			continue
+		}
+
+		name := strings.TrimSuffix(fe.Name(), ".cast")
+		progressOnly := strings.HasSuffix(name, "_progress")
+		if progressOnly {
+			name = strings.TrimSuffix(name, "_progress")
+		}
+		containerID, sessionID, _ := strings.Cut(name, "_")
+
+		infos = append(infos, RecordingInfo{
+			CourseID:     courseID,
+			ContainerID:  containerID,
+			SessionID:    sessionID,
+			ProgressOnly: progressOnly,
+			SizeBytes:    fi.Size(),
+			ModifiedAt:   fi.ModTime(),
+		})
+	}
+	return infos, nil
+}
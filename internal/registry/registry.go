@@ -0,0 +1,208 @@
+// Package registry 实现课程镜像的多源解析与可用性探测
+// 课程容器只认得一个镜像名，但教室网络环境下 docker.io 经常不可达，因此这里把
+// "去哪拉镜像"抽象成按优先级排序的 RegistrySource 列表：docker.io、GHCR、私有 OCI 仓库、
+// 甚至本地离线 tar 包，docker 包只需要按顺序尝试这些源，不需要关心具体的鉴权/探测细节。
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/config"
+)
+
+const offlineFileScheme = "image://file/"
+
+// Availability 单次 Probe 调用的结果
+type Availability struct {
+	Available bool
+	LatencyMs int64
+	Error     string
+}
+
+// RegistrySource 描述一个镜像源：如何把裸镜像名解析成该源下的完整引用，以及如何探测其可用性
+// Docker Hub、GHCR、用户自建的通用 OCI Distribution v2 仓库都实现这个接口，
+// Manager 只依赖接口，不关心具体是哪一种
+type RegistrySource interface {
+	// ID 该源的稳定标识符，用于持久化与 DELETE /api/images/sources/:id 定位
+	ID() string
+	// Name 展示名称
+	Name() string
+	// Prefix 该源用来改写镜像名的 host 前缀，空字符串表示不改写（如官方 docker.io）
+	Prefix() string
+	// Resolve 把裸镜像名解析为该源下的完整引用
+	Resolve(image string) (canonicalRef string, err error)
+	// Probe 探测指定引用在该源是否可拉取
+	Probe(ctx context.Context, ref string) (Availability, error)
+}
+
+// OfflineResolver 可选接口，离线 tar 包源实现它以提供本地导入路径，
+// docker 包据此区分"走网络拉取"还是"从本地归档导入"，而不需要在 RegistrySource 上开洞
+type OfflineResolver interface {
+	TarPath(image string) string
+}
+
+// MirrorResult 单个镜像源针对某个镜像的可用性探测结果，对外 JSON 输出用
+type MirrorResult struct {
+	Source    string `json:"source"`
+	URL       string `json:"url"`
+	Available bool   `json:"available"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Manager 按优先级管理课程镜像的多个源
+// 源分两类：启动时由配置文件/环境变量固定下来的静态源，与运行期通过
+// POST/DELETE /api/images/sources 增删、持久化在磁盘上的用户自定义源
+type Manager struct {
+	mu     sync.RWMutex
+	static []RegistrySource
+	custom []RegistrySource
+	store  *SourceStore
+}
+
+// NewManager 依据配置构建有序的镜像源列表，并从 dataDir 下的持久化文件加载用户自定义源
+// 未配置任何源时，退化为仅使用官方 docker.io（历史行为）
+func NewManager(entries []config.RegistryEntry, dataDir string) *Manager {
+	static := make([]RegistrySource, 0, len(entries)+1)
+	static = append(static, NewDockerHubSource())
+	for i, e := range entries {
+		if e.URL == "" {
+			continue // 与默认 docker.io 重复，跳过
+		}
+		if strings.HasPrefix(e.URL, "file://") {
+			static = append(static, NewOfflineSource(fmt.Sprintf("mirror-%d", i+1), strings.TrimPrefix(e.URL, "file://")))
+			continue
+		}
+		src, err := NewGenericSource(fmt.Sprintf("mirror-%d", i+1), e.URL, e.Username, e.PasswordFile)
+		if err != nil {
+			continue
+		}
+		static = append(static, src)
+	}
+
+	m := &Manager{
+		static: static,
+		store:  NewSourceStore(dataDir),
+	}
+	m.custom, _ = m.store.Load()
+	return m
+}
+
+// List 返回按优先级排序的镜像源列表：静态源在前，用户自定义源在后
+func (m *Manager) List() []RegistrySource {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]RegistrySource, 0, len(m.static)+len(m.custom))
+	out = append(out, m.static...)
+	out = append(out, m.custom...)
+	return out
+}
+
+// AddSource 注册一个用户自定义的通用 OCI 仓库源，凭据加密持久化到磁盘后即可跨重启生效
+func (m *Manager) AddSource(id, rawURL, username, password string) (RegistrySource, error) {
+	src, err := NewGenericSource(id, rawURL, username, "")
+	if err != nil {
+		return nil, err
+	}
+	if ociSrc, ok := src.(*ociSource); ok {
+		ociSrc.password = password
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.custom {
+		if existing.ID() == id {
+			return nil, fmt.Errorf("镜像源 %s 已存在", id)
+		}
+	}
+	m.custom = append(m.custom, src)
+	if err := m.store.Save(m.custom); err != nil {
+		m.custom = m.custom[:len(m.custom)-1]
+		return nil, fmt.Errorf("持久化镜像源失败: %w", err)
+	}
+	return src, nil
+}
+
+// RemoveSource 删除一个用户自定义镜像源，静态源（docker.io 及配置文件指定的源）不可删除
+func (m *Manager) RemoveSource(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idx := -1
+	for i, s := range m.custom {
+		if s.ID() == id {
+			idx = i
+			break
+		}
+	}
+	if idx == -1 {
+		return fmt.Errorf("镜像源 %s 不存在或不可删除", id)
+	}
+	removed := m.custom[idx]
+	m.custom = append(m.custom[:idx], m.custom[idx+1:]...)
+	if err := m.store.Save(m.custom); err != nil {
+		m.custom = append(m.custom[:idx], append([]RegistrySource{removed}, m.custom[idx:]...)...)
+		return fmt.Errorf("持久化镜像源失败: %w", err)
+	}
+	return nil
+}
+
+// ParseOfflineFileRef 解析 "image://file/<path>" 形式的镜像名
+// 用于用户/课程直接指定一个离线 tar 包路径，跳过所有镜像源
+func ParseOfflineFileRef(imageName string) (path string, ok bool) {
+	if !strings.HasPrefix(imageName, offlineFileScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(imageName, offlineFileScheme), true
+}
+
+// CheckAvailability 并行探测所有已配置镜像源对指定镜像的可用性，返回每个源的延迟与是否可用
+func (m *Manager) CheckAvailability(ctx context.Context, imageName string) []MirrorResult {
+	sources := m.List()
+	results := make([]MirrorResult, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source RegistrySource) {
+			defer wg.Done()
+			results[i] = probeSource(ctx, source, imageName)
+		}(i, source)
+	}
+	wg.Wait()
+	return results
+}
+
+func probeSource(ctx context.Context, source RegistrySource, imageName string) MirrorResult {
+	start := time.Now()
+	result := MirrorResult{Source: source.Name(), URL: sourceDisplayURL(source)}
+
+	ref, err := source.Resolve(imageName)
+	if err != nil {
+		result.Error = err.Error()
+		result.LatencyMs = time.Since(start).Milliseconds()
+		return result
+	}
+
+	avail, err := source.Probe(ctx, ref)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Available = avail.Available
+	result.Error = avail.Error
+	if avail.LatencyMs > 0 {
+		result.LatencyMs = avail.LatencyMs
+	}
+	return result
+}
+
+func sourceDisplayURL(source RegistrySource) string {
+	if d, ok := source.(interface{ Describe() string }); ok {
+		return d.Describe()
+	}
+	return source.Prefix()
+}
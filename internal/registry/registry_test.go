@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"testing"
+)
+
+// TestOCISourceResolve 验证各类源对裸镜像名的前缀改写规则
+func TestOCISourceResolve(t *testing.T) {
+	hub := NewDockerHubSource()
+	if ref, err := hub.Resolve("kwdb/kwdb:latest"); err != nil || ref != "kwdb/kwdb:latest" {
+		t.Errorf("docker.io should not rewrite image name, got %q, err=%v", ref, err)
+	}
+
+	ghcr := NewGHCRSource("", "")
+	if ref, err := ghcr.Resolve("kwdb/kwdb:latest"); err != nil || ref != "ghcr.io/kwdb/kwdb:latest" {
+		t.Errorf("ghcr.io should prefix host, got %q, err=%v", ref, err)
+	}
+
+	generic, err := NewGenericSource("harbor", "https://harbor.example.com/", "", "")
+	if err != nil {
+		t.Fatalf("NewGenericSource failed: %v", err)
+	}
+	if ref, _ := generic.Resolve("kwdb/kwdb:latest"); ref != "harbor.example.com/kwdb/kwdb:latest" {
+		t.Errorf("generic source should prefix host, got %q", ref)
+	}
+	if generic.Prefix() != "harbor.example.com/" {
+		t.Errorf("unexpected prefix: %q", generic.Prefix())
+	}
+}
+
+// TestSourceStoreRoundTrip 验证自定义镜像源的加密持久化与重新加载
+func TestSourceStoreRoundTrip(t *testing.T) {
+	dataDir := t.TempDir()
+	store := NewSourceStore(dataDir)
+
+	src, err := NewGenericSource("harbor", "harbor.example.com", "ci", "")
+	if err != nil {
+		t.Fatalf("NewGenericSource failed: %v", err)
+	}
+	src.(*ociSource).password = "s3cr3t"
+
+	if err := store.Save([]RegistrySource{src}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	reloaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(reloaded) != 1 {
+		t.Fatalf("expected 1 source, got %d", len(reloaded))
+	}
+	got := reloaded[0].(*ociSource)
+	if got.ID() != "harbor" || got.username != "ci" || got.password != "s3cr3t" {
+		t.Errorf("unexpected reloaded source: id=%s username=%s password=%s", got.ID(), got.username, got.password)
+	}
+}
+
+// TestManagerAddRemoveSource 验证自定义镜像源的增删与持久化生效
+func TestManagerAddRemoveSource(t *testing.T) {
+	m := NewManager(nil, t.TempDir())
+
+	if _, err := m.AddSource("harbor", "harbor.example.com", "ci", "pw"); err != nil {
+		t.Fatalf("AddSource failed: %v", err)
+	}
+	if _, err := m.AddSource("harbor", "harbor.example.com", "ci", "pw"); err == nil {
+		t.Error("AddSource should reject duplicate id")
+	}
+
+	found := false
+	for _, s := range m.List() {
+		if s.ID() == "harbor" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("harbor source should appear in List() after AddSource")
+	}
+
+	if err := m.RemoveSource("harbor"); err != nil {
+		t.Fatalf("RemoveSource failed: %v", err)
+	}
+	if err := m.RemoveSource("docker.io"); err == nil {
+		t.Error("RemoveSource should reject static sources like docker.io")
+	}
+}
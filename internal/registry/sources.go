@@ -0,0 +1,248 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ociSource 是通用 OCI Distribution v2 仓库的 RegistrySource 实现，Docker Hub 与 GHCR
+// 都只是它的两组固定参数（host 与 prefix 不同），私有仓库则在运行期由用户提供 host
+type ociSource struct {
+	id           string
+	name         string
+	host         string // Registry v2 API 主机名，例如 registry-1.docker.io / ghcr.io
+	prefix       string // 解析镜像名时拼接的前缀；docker.io 为空（不改写裸镜像名）
+	username     string
+	passwordFile string // 来自配置文件的密码文件路径
+	password     string // 来自 API 持久化存储、已解密的密码，优先于 passwordFile
+
+	client *http.Client
+}
+
+func newOCISource(id, name, host, prefix, username, passwordFile string) *ociSource {
+	return &ociSource{
+		id:           id,
+		name:         name,
+		host:         host,
+		prefix:       prefix,
+		username:     username,
+		passwordFile: passwordFile,
+		client:       &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// NewDockerHubSource 构建官方 docker.io 源，裸镜像名不需要任何改写
+func NewDockerHubSource() RegistrySource {
+	return newOCISource("docker.io", "Docker Hub", "registry-1.docker.io", "", "", "")
+}
+
+// NewGHCRSource 构建 GitHub Container Registry 源
+func NewGHCRSource(username, passwordFile string) RegistrySource {
+	return newOCISource("ghcr.io", "GitHub Container Registry", "ghcr.io", "ghcr.io/", username, passwordFile)
+}
+
+// NewGenericSource 构建任意 OCI Distribution v2 私有仓库源，rawURL 可带或不带协议前缀
+func NewGenericSource(id, rawURL, username, passwordFile string) (RegistrySource, error) {
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(rawURL, "https://"), "http://"), "/")
+	if host == "" {
+		return nil, fmt.Errorf("镜像源地址不能为空")
+	}
+	return newOCISource(id, host, host, host+"/", username, passwordFile), nil
+}
+
+func (s *ociSource) ID() string     { return s.id }
+func (s *ociSource) Name() string   { return s.name }
+func (s *ociSource) Prefix() string { return s.prefix }
+func (s *ociSource) Describe() string {
+	if s.prefix == "" {
+		return s.host
+	}
+	return strings.TrimSuffix(s.prefix, "/")
+}
+
+// Resolve 把裸镜像名解析为该源下的完整引用：docker.io 不改写，其余源把 host 前缀拼接上去
+func (s *ociSource) Resolve(image string) (string, error) {
+	if s.prefix == "" {
+		return image, nil
+	}
+	return s.prefix + image, nil
+}
+
+// Probe 通过 HEAD /v2/<name>/manifests/<ref> 探测镜像是否存在，按需完成 Bearer Token 鉴权挑战
+func (s *ociSource) Probe(ctx context.Context, ref string) (Availability, error) {
+	repo, tag := s.splitRef(ref)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", s.host, repo, tag)
+
+	resp, err := s.headManifest(ctx, manifestURL, "")
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		token, terr := s.fetchBearerToken(ctx, resp.Header.Get("Www-Authenticate"))
+		if terr != nil {
+			return Availability{Error: fmt.Sprintf("鉴权失败: %v", terr)}, nil
+		}
+		resp, err = s.headManifest(ctx, manifestURL, token)
+	}
+	if err != nil {
+		return Availability{Error: err.Error()}, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Availability{Error: fmt.Sprintf("HTTP %d", resp.StatusCode)}, nil
+	}
+	return Availability{Available: true}, nil
+}
+
+func (s *ociSource) headManifest(ctx context.Context, manifestURL, bearerToken string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// 兼容 v2 清单格式与 Docker-Distribution-Api-Version 校验
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+	if bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+bearerToken)
+	}
+	return s.client.Do(req)
+}
+
+// fetchBearerToken 解析 WWW-Authenticate: Bearer realm=...,service=...,scope=... 挑战并换取访问令牌
+func (s *ociSource) fetchBearerToken(ctx context.Context, challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("不支持的鉴权方案: %s", challenge)
+	}
+	params := parseAuthParams(strings.TrimPrefix(challenge, "Bearer "))
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("鉴权挑战缺少 realm")
+	}
+
+	q := url.Values{}
+	if v := params["service"]; v != "" {
+		q.Set("service", v)
+	}
+	if v := params["scope"]; v != "" {
+		q.Set("scope", v)
+	}
+	reqURL := realm
+	if len(q) > 0 {
+		reqURL += "?" + q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if s.username != "" {
+		password, perr := s.resolvePassword()
+		if perr != nil {
+			return "", perr
+		}
+		req.SetBasicAuth(s.username, password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token 接口返回 %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// resolvePassword 优先使用已解密的内存密码（来自持久化存储），否则按配置的密码文件读取
+func (s *ociSource) resolvePassword() (string, error) {
+	if s.password != "" {
+		return s.password, nil
+	}
+	if s.passwordFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(s.passwordFile)
+	if err != nil {
+		return "", fmt.Errorf("读取密码文件失败: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// splitRef 将镜像引用拆分为 Registry v2 API 所需的 repository 与 tag
+func (s *ociSource) splitRef(ref string) (repo, tag string) {
+	tag = "latest"
+	ref = strings.TrimPrefix(ref, s.prefix)
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		tag = ref[i+1:]
+		ref = ref[:i]
+	}
+	repo = ref
+	if s.prefix == "" && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return repo, tag
+}
+
+func parseAuthParams(s string) map[string]string {
+	out := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		out[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return out
+}
+
+// offlineSource 代表一个本地离线 tar 包目录，Probe 只检查文件是否存在，不发起任何网络请求
+type offlineSource struct {
+	id  string
+	dir string
+}
+
+// NewOfflineSource 构建一个离线 tar 包源，dir 为 tar 包所在目录
+func NewOfflineSource(id, dir string) RegistrySource {
+	return &offlineSource{id: id, dir: dir}
+}
+
+func (s *offlineSource) ID() string     { return s.id }
+func (s *offlineSource) Name() string   { return s.id }
+func (s *offlineSource) Prefix() string { return "" }
+func (s *offlineSource) Describe() string {
+	return "file://" + s.dir
+}
+
+// Resolve 离线源不改写镜像名，Probe/TarPath 都直接用镜像名推导文件名
+func (s *offlineSource) Resolve(image string) (string, error) { return image, nil }
+
+// TarPath 返回离线源目录下，指定镜像对应的 tar 包路径
+// 约定文件名为镜像名中的 "/" 和 ":" 替换为 "_" 后加 .tar 后缀，满足 OfflineResolver 接口
+func (s *offlineSource) TarPath(image string) string {
+	fileName := strings.NewReplacer("/", "_", ":", "_").Replace(image) + ".tar"
+	return filepath.Join(s.dir, fileName)
+}
+
+func (s *offlineSource) Probe(ctx context.Context, ref string) (Availability, error) {
+	tarPath := s.TarPath(ref)
+	if _, err := os.Stat(tarPath); err != nil {
+		return Availability{Error: fmt.Sprintf("离线镜像包不存在: %s", tarPath)}, nil
+	}
+	return Availability{Available: true}, nil
+}
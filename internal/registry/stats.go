@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ewmaAlpha 控制历史延迟的权重衰减速度：值越大，最近一次探测结果对EWMA的影响越大
+const ewmaAlpha = 0.3
+
+// defaultLatencyRelPath 是 LatencyStore 默认持久化文件相对用户主目录的路径
+// 刻意不跟随 cfg.DataDir：同一台开发机/教室机上常常会起多个独立配置的 playground 实例，
+// 但"哪个镜像源在这台机器上拉得快"是机器级别的事实，值得在这些实例间共享，而不是各自重新探测
+const defaultLatencyRelPath = ".kwdb-playground/mirrors.json"
+
+// mirrorRecord 单个镜像源的历史表现：EWMA 延迟（毫秒）与累计成败次数
+type mirrorRecord struct {
+	EWMALatencyMs float64 `json:"ewmaLatencyMs"`
+	Successes     int     `json:"successes"`
+	Failures      int     `json:"failures"`
+}
+
+// LatencyStore 跨进程持久化各镜像源的EWMA延迟与成败次数
+// 用于把拉取顺序从固定的配置优先级，动态调整为"优先尝试历史上更快更可靠的源"
+type LatencyStore struct {
+	mu   sync.Mutex
+	path string
+	data map[string]*mirrorRecord
+}
+
+// NewLatencyStore 创建延迟统计存储并尝试从磁盘加载历史数据；path 为空时退化为 ~/.kwdb-playground/mirrors.json
+// 加载失败（文件不存在、内容损坏）时静默退化为空统计，不影响主流程
+func NewLatencyStore(path string) *LatencyStore {
+	if path == "" {
+		path = defaultLatencyPath()
+	}
+	s := &LatencyStore{path: path, data: make(map[string]*mirrorRecord)}
+	_ = s.load()
+	return s
+}
+
+func defaultLatencyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return defaultLatencyRelPath
+	}
+	return filepath.Join(home, defaultLatencyRelPath)
+}
+
+func (s *LatencyStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	records := make(map[string]*mirrorRecord)
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("解析镜像源延迟统计文件失败: %w", err)
+	}
+	s.mu.Lock()
+	s.data = records
+	s.mu.Unlock()
+	return nil
+}
+
+// save 整体覆盖写回磁盘；写入失败时静默忽略，延迟统计只是优化拉取顺序的提示信息，不是关键路径
+func (s *LatencyStore) save() {
+	s.mu.Lock()
+	snapshot := make(map[string]*mirrorRecord, len(s.data))
+	for k, v := range s.data {
+		snapshot[k] = v
+	}
+	s.mu.Unlock()
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(s.path, data, 0644)
+}
+
+// Record 按 EWMA 更新 id 对应镜像源的延迟并累加成败次数，随后落盘
+func (s *LatencyStore) Record(id string, latencyMs int64, success bool) {
+	s.mu.Lock()
+	rec, ok := s.data[id]
+	if !ok {
+		rec = &mirrorRecord{EWMALatencyMs: float64(latencyMs)}
+		s.data[id] = rec
+	} else {
+		rec.EWMALatencyMs = ewmaAlpha*float64(latencyMs) + (1-ewmaAlpha)*rec.EWMALatencyMs
+	}
+	if success {
+		rec.Successes++
+	} else {
+		rec.Failures++
+	}
+	s.mu.Unlock()
+	s.save()
+}
+
+// Rank 返回按历史表现重排后的镜像源列表（不修改输入切片）：
+//   - 从未探测过的源排在已知更快的源之前，以便获得首次探测机会；
+//   - 探测过但从未成功过的源排到最后；
+//   - 其余按 EWMA 延迟升序排列。
+//
+// 相同分数的源保持原有的配置优先级顺序（稳定排序）
+func (s *LatencyStore) Rank(sources []RegistrySource) []RegistrySource {
+	s.mu.Lock()
+	snapshot := make(map[string]*mirrorRecord, len(s.data))
+	for k, v := range s.data {
+		snapshot[k] = v
+	}
+	s.mu.Unlock()
+
+	ranked := make([]RegistrySource, len(sources))
+	copy(ranked, sources)
+	score := func(src RegistrySource) float64 {
+		rec, ok := snapshot[src.ID()]
+		if !ok {
+			return -1
+		}
+		if rec.Successes == 0 && rec.Failures > 0 {
+			return 1e9
+		}
+		return rec.EWMALatencyMs
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return score(ranked[i]) < score(ranked[j])
+	})
+	return ranked
+}
@@ -0,0 +1,175 @@
+package registry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SourceStore 负责用户通过 POST/DELETE /api/images/sources 增删的自定义镜像源的磁盘持久化
+// 凭据使用 AES-256-GCM 加密存储，密钥保存在同目录下的独立文件中，避免明文密码落盘
+type SourceStore struct {
+	dir     string
+	keyPath string
+}
+
+// persistedSource 是 SourceStore 落盘的记录格式；Password 为 AES-GCM 加密后的 base64 密文
+type persistedSource struct {
+	ID                string `json:"id"`
+	URL               string `json:"url"`
+	Username          string `json:"username,omitempty"`
+	EncryptedPassword string `json:"encryptedPassword,omitempty"`
+}
+
+// NewSourceStore 基于 config.DataDir 构建自定义镜像源存储
+func NewSourceStore(dataDir string) *SourceStore {
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	dir := filepath.Join(dataDir, "registry")
+	return &SourceStore{
+		dir:     dir,
+		keyPath: filepath.Join(dir, "sources.key"),
+	}
+}
+
+func (s *SourceStore) filePath() string {
+	return filepath.Join(s.dir, "sources.json")
+}
+
+// Load 从磁盘读取已持久化的自定义镜像源；文件不存在时返回空列表（首次启动的正常情况）
+func (s *SourceStore) Load() ([]RegistrySource, error) {
+	data, err := os.ReadFile(s.filePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []persistedSource
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析镜像源存储文件失败: %w", err)
+	}
+
+	key, err := s.loadKey()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]RegistrySource, 0, len(records))
+	for _, rec := range records {
+		src, err := NewGenericSource(rec.ID, rec.URL, rec.Username, "")
+		if err != nil {
+			continue
+		}
+		if ociSrc, ok := src.(*ociSource); ok && rec.EncryptedPassword != "" {
+			password, err := decrypt(key, rec.EncryptedPassword)
+			if err != nil {
+				return nil, fmt.Errorf("解密镜像源 %s 的凭据失败: %w", rec.ID, err)
+			}
+			ociSrc.password = password
+		}
+		out = append(out, src)
+	}
+	return out, nil
+}
+
+// Save 把当前自定义镜像源列表整体写回磁盘，覆盖旧内容
+func (s *SourceStore) Save(sources []RegistrySource) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建镜像源存储目录失败: %w", err)
+	}
+	key, err := s.loadKey()
+	if err != nil {
+		return err
+	}
+
+	records := make([]persistedSource, 0, len(sources))
+	for _, src := range sources {
+		ociSrc, ok := src.(*ociSource)
+		if !ok {
+			continue // 离线源等无凭据可言，不持久化为自定义源
+		}
+		rec := persistedSource{ID: ociSrc.ID(), URL: ociSrc.host, Username: ociSrc.username}
+		if ociSrc.password != "" {
+			enc, err := encrypt(key, ociSrc.password)
+			if err != nil {
+				return fmt.Errorf("加密镜像源 %s 的凭据失败: %w", ociSrc.ID(), err)
+			}
+			rec.EncryptedPassword = enc
+		}
+		records = append(records, rec)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.filePath(), data, 0600)
+}
+
+// loadKey 读取用于加密凭据的 AES-256 密钥，首次调用时随机生成并以 0600 权限写入磁盘
+func (s *SourceStore) loadKey() ([]byte, error) {
+	if data, err := os.ReadFile(s.keyPath); err == nil && len(data) == 32 {
+		return data, nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建镜像源存储目录失败: %w", err)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成加密密钥失败: %w", err)
+	}
+	if err := os.WriteFile(s.keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("写入加密密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+func encrypt(key []byte, plaintext string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decrypt(key []byte, ciphertext string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", fmt.Errorf("密文长度不足")
+	}
+	nonce, sealed := raw[:gcm.NonceSize()], raw[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,367 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd"
+	"github.com/containerd/containerd/cio"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/oci"
+
+	"kwdb-playground/internal/docker"
+	"kwdb-playground/internal/logger"
+)
+
+// ContainerdConfig containerd 后端的连接参数，对应 config.RuntimeConfig 中 containerd 相关字段
+type ContainerdConfig struct {
+	// Address containerd 守护进程的 unix socket 地址，留空时使用 /run/containerd/containerd.sock
+	Address string
+	// Namespace containerd 命名空间，隔离本程序管理的容器与宿主机上的其他workload
+	Namespace string
+}
+
+// containerdController 直连 containerd（无需 Docker 守护进程）实现 Controller
+// 容器/task/exec 句柄只存在于进程内存中，重启进程后需要依赖 containerd 自身的命名空间隔离
+// 重新发现，这里暂不做持久化，与 docker.Controller 通过 LabelAppName 做历史容器迁移的思路不同
+type containerdController struct {
+	client    *containerd.Client
+	namespace string
+	logger    *logger.Logger
+
+	mu         sync.RWMutex
+	containers map[string]containerd.Container // 容器ID -> containerd容器句柄
+	tasks      map[string]containerd.Task      // 容器ID -> 已启动的task，ResizeTerminal主进程resize据此定位
+	execs      map[string]containerd.Process   // execID -> 已启动的exec进程
+}
+
+// NewContainerdController 连接 containerd 的 unix socket 并返回一个 Controller 实现
+func NewContainerdController(cfg ContainerdConfig) (Controller, error) {
+	addr := cfg.Address
+	if addr == "" {
+		addr = "/run/containerd/containerd.sock"
+	}
+	ns := cfg.Namespace
+	if ns == "" {
+		ns = "kwdb-playground"
+	}
+
+	cli, err := containerd.New(addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接containerd失败(%s): %w", addr, err)
+	}
+
+	return &containerdController{
+		client:     cli,
+		namespace:  ns,
+		logger:     logger.NewLogger(logger.INFO),
+		containers: make(map[string]containerd.Container),
+		tasks:      make(map[string]containerd.Task),
+		execs:      make(map[string]containerd.Process),
+	}, nil
+}
+
+// withNamespace 把调用方的 context 绑定到本控制器的 containerd 命名空间
+func (c *containerdController) withNamespace(ctx context.Context) context.Context {
+	return namespaces.WithNamespace(ctx, c.namespace)
+}
+
+// CreateContainer 拉取镜像并创建（但不启动）一个containerd容器
+func (c *containerdController) CreateContainer(ctx context.Context, courseID string, config *docker.ContainerConfig) (*docker.ContainerInfo, error) {
+	nctx := c.withNamespace(ctx)
+
+	image, err := c.client.Pull(nctx, config.Image, containerd.WithPullUnpack)
+	if err != nil {
+		return nil, fmt.Errorf("拉取镜像失败: %w", err)
+	}
+
+	id := fmt.Sprintf("%s-%d", courseID, time.Now().UnixNano())
+
+	specOpts := []oci.SpecOpts{oci.WithImageConfig(image)}
+	if len(config.Cmd) > 0 {
+		specOpts = append(specOpts, oci.WithProcessArgs(config.Cmd...))
+	}
+	if config.WorkingDir != "" {
+		specOpts = append(specOpts, oci.WithProcessCwd(config.WorkingDir))
+	}
+	for k, v := range config.Env {
+		specOpts = append(specOpts, oci.WithEnv([]string{fmt.Sprintf("%s=%s", k, v)}))
+	}
+	if config.Privileged {
+		specOpts = append(specOpts, oci.WithPrivileged)
+	}
+
+	cntr, err := c.client.NewContainer(
+		nctx, id,
+		containerd.WithImage(image),
+		containerd.WithNewSnapshot(id+"-snapshot", image),
+		containerd.WithNewSpec(specOpts...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("创建containerd容器失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.containers[id] = cntr
+	c.mu.Unlock()
+
+	return &docker.ContainerInfo{
+		ID:         id,
+		CourseID:   courseID,
+		DockerID:   id,
+		State:      docker.StateCreating,
+		Image:      config.Image,
+		StartedAt:  time.Now(),
+		Env:        config.Env,
+		Ports:      config.Ports,
+		Privileged: config.Privileged,
+		Name:       id,
+	}, nil
+}
+
+// StartContainer 为已创建的容器启动一个task，task的stdio接到 /dev/null 等价的丢弃流
+// 交互式会话通过 CreateInteractiveExec 单独附加，不复用这里的stdio
+func (c *containerdController) StartContainer(ctx context.Context, containerID string) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	cntr, ok := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	task, err := cntr.NewTask(nctx, cio.NewCreator(cio.WithStdio))
+	if err != nil {
+		return fmt.Errorf("创建containerd task失败: %w", err)
+	}
+	if err := task.Start(nctx); err != nil {
+		return fmt.Errorf("启动containerd task失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.tasks[containerID] = task
+	c.mu.Unlock()
+	return nil
+}
+
+// ExecCommandInteractive 在容器主task内创建一次exec，阻塞直到命令退出
+func (c *containerdController) ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer, opts docker.ExecOptions) (string, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	task, ok := c.tasks[containerID]
+	cntr := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("容器 %s 没有正在运行的task", containerID)
+	}
+
+	spec, err := cntr.Spec(nctx)
+	if err != nil {
+		return "", fmt.Errorf("读取容器spec失败: %w", err)
+	}
+	pspec := spec.Process
+	pspec.Args = cmd
+	pspec.Terminal = opts.Tty
+
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	process, err := task.Exec(nctx, execID, pspec, cio.NewCreator(cio.WithStreams(stdinReader, stdoutWriter, stderrWriter)))
+	if err != nil {
+		return "", fmt.Errorf("创建containerd exec失败: %w", err)
+	}
+	defer process.Delete(nctx)
+
+	statusCh, err := process.Wait(nctx)
+	if err != nil {
+		return "", fmt.Errorf("等待containerd exec失败: %w", err)
+	}
+	if err := process.Start(nctx); err != nil {
+		return "", fmt.Errorf("启动containerd exec失败: %w", err)
+	}
+
+	status := <-statusCh
+	if code := status.ExitCode(); code != 0 {
+		return execID, fmt.Errorf("命令退出码非零: %d", code)
+	}
+	return execID, nil
+}
+
+// CreateInteractiveExec 创建一次TTY交互式exec，把containerd基于FIFO的stdio适配成pipeConn，
+// 使调用方（WebSocket终端）可以像docker后端一样直接读写同一个 net.Conn
+func (c *containerdController) CreateInteractiveExec(ctx context.Context, containerID string, cmd []string) (*docker.InteractiveExecResult, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	task, ok := c.tasks[containerID]
+	cntr := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("容器 %s 没有正在运行的task", containerID)
+	}
+
+	spec, err := cntr.Spec(nctx)
+	if err != nil {
+		return nil, fmt.Errorf("读取容器spec失败: %w", err)
+	}
+	pspec := spec.Process
+	pspec.Args = cmd
+	pspec.Terminal = true
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	process, err := task.Exec(nctx, execID, pspec, cio.NewCreator(cio.WithStreams(inR, outW, outW)))
+	if err != nil {
+		return nil, fmt.Errorf("创建containerd交互式exec失败: %w", err)
+	}
+	if err := process.Start(nctx); err != nil {
+		return nil, fmt.Errorf("启动containerd交互式exec失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.execs[execID] = process
+	c.mu.Unlock()
+
+	conn := &pipeConn{r: outR, w: inW}
+	return &docker.InteractiveExecResult{ExecID: execID, Conn: conn, Reader: bufio.NewReader(conn)}, nil
+}
+
+// InspectExec 查询一次exec进程当前是否仍在运行及退出码，与docker后端的ContainerExecInspect语义对齐，
+// 供Shell回退探测（CreateInteractiveExec附加成功不代表Shell真的起来了）复用
+func (c *containerdController) InspectExec(ctx context.Context, execID string) (running bool, exitCode int, err error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	process, ok := c.execs[execID]
+	c.mu.RUnlock()
+	if !ok {
+		return false, 0, fmt.Errorf("exec %s 不存在", execID)
+	}
+
+	status, err := process.Status(nctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("查询containerd exec状态失败: %w", err)
+	}
+	running = status.Status == containerd.Running || status.Status == containerd.Created
+	return running, int(status.ExitStatus), nil
+}
+
+// ResizeTerminal 调整一次exec（或退化为容器主task）对应的TTY行列数
+func (c *containerdController) ResizeTerminal(ctx context.Context, execID string, height, width uint) error {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.RLock()
+	process, ok := c.execs[execID]
+	if !ok {
+		process, ok = c.tasks[execID]
+	}
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("exec/task %s 不存在", execID)
+	}
+	return process.Resize(nctx, width, height)
+}
+
+// PullImage 拉取镜像，不上报进度
+func (c *containerdController) PullImage(ctx context.Context, imageName string) error {
+	return c.pullImage(ctx, imageName, nil)
+}
+
+// PullImageWithProgress 拉取镜像并把阶段性状态以 docker.ImagePullProgress 上报给回调，
+// 字段语义与 docker.Controller.CreateContainerWithProgress 保持一致，便于WebSocket终端统一展示
+func (c *containerdController) PullImageWithProgress(ctx context.Context, imageName string, progressCallback docker.ImagePullProgressCallback) error {
+	return c.pullImage(ctx, imageName, progressCallback)
+}
+
+func (c *containerdController) pullImage(ctx context.Context, imageName string, progressCallback docker.ImagePullProgressCallback) error {
+	nctx := c.withNamespace(ctx)
+
+	if progressCallback != nil {
+		progressCallback(docker.ImagePullProgress{ImageName: imageName, Status: "pulling"})
+	}
+
+	if _, err := c.client.Pull(nctx, imageName, containerd.WithPullUnpack); err != nil {
+		if progressCallback != nil {
+			progressCallback(docker.ImagePullProgress{ImageName: imageName, Status: "error", Error: err.Error()})
+		}
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+
+	if progressCallback != nil {
+		progressCallback(docker.ImagePullProgress{ImageName: imageName, Status: "complete"})
+	}
+	return nil
+}
+
+// CleanupCourseContainers 停止并删除属于指定课程的所有containerd容器（ID以 "<courseID>-" 为前缀）
+func (c *containerdController) CleanupCourseContainers(ctx context.Context, courseID string) (*docker.CleanupResult, error) {
+	nctx := c.withNamespace(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := courseID + "-"
+	cleaned := make([]*docker.ContainerInfo, 0)
+	for id, cntr := range c.containers {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		if task, ok := c.tasks[id]; ok {
+			if _, err := task.Delete(nctx, containerd.WithProcessKill); err != nil {
+				c.logger.Warn("删除containerd task %s 失败: %v", id, err)
+			}
+			delete(c.tasks, id)
+		}
+		if err := cntr.Delete(nctx, containerd.WithSnapshotCleanup(nctx, c.client.SnapshotService(""), id+"-snapshot")); err != nil {
+			c.logger.Warn("删除containerd容器 %s 失败: %v", id, err)
+			continue
+		}
+		delete(c.containers, id)
+		cleaned = append(cleaned, &docker.ContainerInfo{ID: id, CourseID: courseID, State: docker.StateStopped})
+	}
+
+	return &docker.CleanupResult{
+		Success:           true,
+		Message:           fmt.Sprintf("已清理 %d 个containerd容器", len(cleaned)),
+		CleanedContainers: cleaned,
+	}, nil
+}
+
+// Close 关闭与containerd守护进程的连接
+func (c *containerdController) Close() error {
+	return c.client.Close()
+}
+
+// pipeConn 把一对 io.Pipe 适配成 net.Conn，用于在containerd后端上满足
+// InteractiveExecResult.Conn 的类型约定；containerd 的 cio 基于 FIFO+io.Reader/Writer，
+// 没有原生的 net.Conn 语义，这里只需要 Read/Write 可用，Deadline 等方法按需实现为空操作
+type pipeConn struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (p *pipeConn) Read(b []byte) (int, error)  { return p.r.Read(b) }
+func (p *pipeConn) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *pipeConn) Close() error {
+	_ = p.r.Close()
+	return p.w.Close()
+}
+func (p *pipeConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (p *pipeConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (p *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (p *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (p *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// pipeAddr 是 pipeConn 的占位地址，containerd exec 不涉及真实网络端点
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "containerd-exec-pipe" }
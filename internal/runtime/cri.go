@@ -0,0 +1,283 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	criapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+
+	"kwdb-playground/internal/docker"
+	"kwdb-playground/internal/logger"
+)
+
+// criController 通过标准CRI gRPC端点实现Controller，是containerdController的姊妹实现：
+// 两者解决同一个问题（在没有Docker守护进程的主机上驱动WebSocket终端），但criController
+// 走kubelet同款的RuntimeService协议，使之也能接入CRI-O等非containerd的CRI运行时。
+// 与internal/docker/cri_adapter.go的criAdapter是两套独立实现，互不复用：
+// 后者给dockerController提供完整的容器生命周期管理，这里只给终端exec用的更窄接口
+type criController struct {
+	conn    *grpc.ClientConn
+	runtime criapi.RuntimeServiceClient
+	image   criapi.ImageServiceClient
+	logger  *logger.Logger
+
+	mu         sync.RWMutex
+	containers map[string]criSandboxHandle // 容器ID（本地命名） -> sandbox/容器句柄
+}
+
+// criSandboxHandle 记录一个由CreateContainer创建的Pod sandbox + 其内的唯一容器
+type criSandboxHandle struct {
+	podSandboxID string
+	containerID  string
+	courseID     string
+}
+
+// NewCRIController 连接CRI gRPC端点并返回一个Controller实现
+func NewCRIController(cfg ContainerdConfig) (Controller, error) {
+	addr := cfg.Address
+	if addr == "" {
+		addr = "/run/containerd/containerd.sock"
+	}
+
+	conn, err := grpc.NewClient("unix://"+addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("连接CRI运行时失败(%s): %w", addr, err)
+	}
+
+	return &criController{
+		conn:       conn,
+		runtime:    criapi.NewRuntimeServiceClient(conn),
+		image:      criapi.NewImageServiceClient(conn),
+		logger:     logger.NewLogger(logger.INFO),
+		containers: make(map[string]criSandboxHandle),
+	}, nil
+}
+
+// CreateContainer 为容器单独创建一个Pod sandbox，再在其中创建（但不启动）容器
+func (c *criController) CreateContainer(ctx context.Context, courseID string, config *docker.ContainerConfig) (*docker.ContainerInfo, error) {
+	id := fmt.Sprintf("%s-%d", courseID, time.Now().UnixNano())
+
+	sandboxCfg := &criapi.PodSandboxConfig{
+		Metadata: &criapi.PodSandboxMetadata{Name: id, Namespace: "kwdb-playground", Uid: id},
+		Labels:   map[string]string{"io.kwdb.playground.course_id": courseID},
+	}
+	sandboxResp, err := c.runtime.RunPodSandbox(ctx, &criapi.RunPodSandboxRequest{Config: sandboxCfg})
+	if err != nil {
+		return nil, fmt.Errorf("创建Pod sandbox失败: %w", err)
+	}
+
+	envs := make([]*criapi.KeyValue, 0, len(config.Env))
+	for k, v := range config.Env {
+		envs = append(envs, &criapi.KeyValue{Key: k, Value: v})
+	}
+
+	containerCfg := &criapi.ContainerConfig{
+		Metadata:   &criapi.ContainerMetadata{Name: id},
+		Image:      &criapi.ImageSpec{Image: config.Image},
+		Command:    config.Cmd,
+		Envs:       envs,
+		WorkingDir: config.WorkingDir,
+		Linux: &criapi.LinuxContainerConfig{
+			SecurityContext: &criapi.LinuxContainerSecurityContext{Privileged: config.Privileged},
+		},
+	}
+	createResp, err := c.runtime.CreateContainer(ctx, &criapi.CreateContainerRequest{
+		PodSandboxId:  sandboxResp.PodSandboxId,
+		Config:        containerCfg,
+		SandboxConfig: sandboxCfg,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建CRI容器失败: %w", err)
+	}
+
+	c.mu.Lock()
+	c.containers[id] = criSandboxHandle{podSandboxID: sandboxResp.PodSandboxId, containerID: createResp.ContainerId, courseID: courseID}
+	c.mu.Unlock()
+
+	return &docker.ContainerInfo{
+		ID:         id,
+		CourseID:   courseID,
+		DockerID:   id,
+		State:      docker.StateCreating,
+		Image:      config.Image,
+		StartedAt:  time.Now(),
+		Env:        config.Env,
+		Ports:      config.Ports,
+		Privileged: config.Privileged,
+		Name:       id,
+	}, nil
+}
+
+// StartContainer 启动CreateContainer已创建好的CRI容器
+func (c *criController) StartContainer(ctx context.Context, containerID string) error {
+	c.mu.RLock()
+	h, ok := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("容器 %s 不存在", containerID)
+	}
+	if _, err := c.runtime.StartContainer(ctx, &criapi.StartContainerRequest{ContainerId: h.containerID}); err != nil {
+		return fmt.Errorf("启动CRI容器失败: %w", err)
+	}
+	return nil
+}
+
+// ExecCommandInteractive 在容器内同步执行一次命令，阻塞直到命令退出；标准CRI的ExecSync
+// 不接受真正的交互式stdin流，这里按命令一次性运行到底处理，不转发stdinReader。ExecSync本身
+// 就把stdout/stderr分开返回，因此opts.Tty在这条路径上没有意义，忽略不用。ExecSync不暴露execID，
+// 返回值固定为空字符串
+func (c *criController) ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer, opts docker.ExecOptions) (string, error) {
+	c.mu.RLock()
+	h, ok := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	resp, err := c.runtime.ExecSync(ctx, &criapi.ExecSyncRequest{ContainerId: h.containerID, Cmd: cmd, Timeout: 30})
+	if err != nil {
+		return "", fmt.Errorf("执行CRI exec失败: %w", err)
+	}
+	if _, err := stdoutWriter.Write(resp.Stdout); err != nil {
+		return "", err
+	}
+	if _, err := stderrWriter.Write(resp.Stderr); err != nil {
+		return "", err
+	}
+	if resp.ExitCode != 0 {
+		return "", fmt.Errorf("命令退出码非零: %d", resp.ExitCode)
+	}
+	return "", nil
+}
+
+// CreateInteractiveExec 向CRI运行时发起Exec请求拿到流式URL，用client-go的SPDY executor
+// 连上去，并把读写桥接到调用方期望的docker.InteractiveExecResult形状
+func (c *criController) CreateInteractiveExec(ctx context.Context, containerID string, cmd []string) (*docker.InteractiveExecResult, error) {
+	c.mu.RLock()
+	h, ok := c.containers[containerID]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("容器 %s 不存在", containerID)
+	}
+
+	execResp, err := c.runtime.Exec(ctx, &criapi.ExecRequest{
+		ContainerId: h.containerID,
+		Cmd:         cmd,
+		Tty:         true,
+		Stdin:       true,
+		Stdout:      true,
+		Stderr:      true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("发起CRI exec请求失败: %w", err)
+	}
+	streamURL, err := url.Parse(execResp.Url)
+	if err != nil {
+		return nil, fmt.Errorf("解析CRI streaming URL失败: %w", err)
+	}
+	executor, err := remotecommand.NewSPDYExecutor(&restclient.Config{}, "POST", streamURL)
+	if err != nil {
+		return nil, fmt.Errorf("创建CRI流式executor失败: %w", err)
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		err := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+			Stdin:  inR,
+			Stdout: outW,
+			Stderr: outW,
+			Tty:    true,
+		})
+		_ = outW.CloseWithError(err)
+	}()
+
+	conn := &criStreamConn{r: outR, w: inW}
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	return &docker.InteractiveExecResult{ExecID: execID, Conn: conn, Reader: bufio.NewReader(conn)}, nil
+}
+
+// InspectExec 在该后端下不受支持：标准CRI没有按execID单独查询状态的RPC，
+// exec进程的生命周期完全绑定在CreateInteractiveExec建立的流式连接上
+func (c *criController) InspectExec(ctx context.Context, execID string) (running bool, exitCode int, err error) {
+	return false, 0, fmt.Errorf("CRI后端暂不支持InspectExec：exec状态只能通过流式连接本身判断")
+}
+
+// ResizeTerminal 在该后端下不受支持：remotecommand.StreamWithContext未暴露动态resize的句柄
+func (c *criController) ResizeTerminal(ctx context.Context, execID string, height, width uint) error {
+	return fmt.Errorf("CRI后端暂不支持ResizeTerminal")
+}
+
+// PullImage 拉取镜像，不上报进度
+func (c *criController) PullImage(ctx context.Context, imageName string) error {
+	if _, err := c.image.PullImage(ctx, &criapi.PullImageRequest{Image: &criapi.ImageSpec{Image: imageName}}); err != nil {
+		return fmt.Errorf("拉取镜像失败: %w", err)
+	}
+	return nil
+}
+
+// CleanupCourseContainers 停止并删除属于指定课程的所有CRI容器及其Pod sandbox
+func (c *criController) CleanupCourseContainers(ctx context.Context, courseID string) (*docker.CleanupResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cleaned := make([]*docker.ContainerInfo, 0)
+	for id, h := range c.containers {
+		if h.courseID != courseID {
+			continue
+		}
+		if _, err := c.runtime.StopContainer(ctx, &criapi.StopContainerRequest{ContainerId: h.containerID, Timeout: 10}); err != nil {
+			c.logger.Warn("停止CRI容器 %s 失败: %v", id, err)
+		}
+		if _, err := c.runtime.RemoveContainer(ctx, &criapi.RemoveContainerRequest{ContainerId: h.containerID}); err != nil {
+			c.logger.Warn("删除CRI容器 %s 失败: %v", id, err)
+			continue
+		}
+		if _, err := c.runtime.RemovePodSandbox(ctx, &criapi.RemovePodSandboxRequest{PodSandboxId: h.podSandboxID}); err != nil {
+			c.logger.Warn("删除Pod sandbox %s 失败: %v", h.podSandboxID, err)
+		}
+		delete(c.containers, id)
+		cleaned = append(cleaned, &docker.ContainerInfo{ID: id, CourseID: courseID, State: docker.StateStopped})
+	}
+
+	return &docker.CleanupResult{
+		Success:           true,
+		Message:           fmt.Sprintf("已清理 %d 个CRI容器", len(cleaned)),
+		CleanedContainers: cleaned,
+	}, nil
+}
+
+// Close 关闭与CRI运行时端点的gRPC连接
+func (c *criController) Close() error {
+	return c.conn.Close()
+}
+
+// criStreamConn 把一对 io.Pipe 适配成 net.Conn，与containerdController的pipeConn同一思路：
+// remotecommand基于SPDY帧而非原始socket，这里只需要Read/Write可用
+type criStreamConn struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func (p *criStreamConn) Read(b []byte) (int, error)  { return p.r.Read(b) }
+func (p *criStreamConn) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *criStreamConn) Close() error {
+	_ = p.r.Close()
+	return p.w.Close()
+}
+func (p *criStreamConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (p *criStreamConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (p *criStreamConn) SetDeadline(t time.Time) error      { return nil }
+func (p *criStreamConn) SetReadDeadline(t time.Time) error  { return nil }
+func (p *criStreamConn) SetWriteDeadline(t time.Time) error { return nil }
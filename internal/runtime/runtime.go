@@ -0,0 +1,65 @@
+// Package runtime 定义课程容器生命周期中与 WebSocket 终端相关的最小运行时接口，
+// 使这部分逻辑既可以跑在 Docker 上，也可以跑在没有 Docker 守护进程、只有 containerd 的宿主机上。
+// 注意：这只是 docker.Controller 的一个子集——快照提交、镜像源探测、SELinux 重标记等
+// Docker 专属能力仍然只能通过 docker.Controller 使用，containerd 后端目前不提供等价实现。
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"kwdb-playground/internal/docker"
+)
+
+// Kind 运行时后端类型，对应配置项 RUNTIME_TYPE
+type Kind string
+
+const (
+	// KindDocker 本地/远程 Docker 守护进程（默认）
+	KindDocker Kind = "docker"
+	// KindContainerd 直连 containerd（无需 Docker 守护进程），通过 /run/containerd/containerd.sock
+	KindContainerd Kind = "containerd"
+	// KindCRI 通过标准CRI（RuntimeService/ImageService）gRPC协议连接containerd/CRI-O，
+	// 与KindContainerd的区别在于走的是kubelet同款协议而非containerd私有API
+	KindCRI Kind = "cri"
+)
+
+// Controller 课程容器的运行时无关接口，方法集合与 docker.Controller 保持同名同参，
+// 使 docker.Controller 天然满足本接口，无需适配层
+type Controller interface {
+	// CreateContainer 创建容器
+	CreateContainer(ctx context.Context, courseID string, config *docker.ContainerConfig) (*docker.ContainerInfo, error)
+	// StartContainer 启动容器
+	StartContainer(ctx context.Context, containerID string) error
+	// ExecCommandInteractive 在容器中执行交互式命令，支持实时双向通信，返回值execID标识这次exec
+	ExecCommandInteractive(ctx context.Context, containerID string, cmd []string, stdinReader io.Reader, stdoutWriter, stderrWriter io.Writer, opts docker.ExecOptions) (execID string, err error)
+	// CreateInteractiveExec 创建并附加一次TTY交互式执行，返回原始连接供调用方自行驱动读写循环，
+	// 是WebSocket终端摆脱"shell out到docker exec"的关键入口
+	CreateInteractiveExec(ctx context.Context, containerID string, cmd []string) (*docker.InteractiveExecResult, error)
+	// InspectExec 查询execID对应exec进程是否仍在运行及退出码，用于Shell回退探测（见
+	// TerminalSession.createShellExec）：CreateInteractiveExec附加成功不代表Shell真的起来了
+	InspectExec(ctx context.Context, execID string) (running bool, exitCode int, err error)
+	// ResizeTerminal 调整终端大小
+	ResizeTerminal(ctx context.Context, execID string, height, width uint) error
+	// PullImage 拉取镜像
+	PullImage(ctx context.Context, imageName string) error
+	// CleanupCourseContainers 清理课程容器
+	CleanupCourseContainers(ctx context.Context, courseID string) (*docker.CleanupResult, error)
+}
+
+// 静态断言：docker.Controller 满足 Controller，保证两个接口不会随后续改动悄悄分叉
+var _ Controller = (docker.Controller)(nil)
+
+// NewContainerd 根据 Kind 构建一个独立于 docker.Controller 的运行时后端，目前只支持 containerd；
+// Docker 后端应直接复用调用方已持有的 docker.Controller（本身就满足 Controller），不必经由此函数重建连接
+func NewContainerd(kind Kind, cfg ContainerdConfig) (Controller, error) {
+	switch kind {
+	case KindContainerd:
+		return NewContainerdController(cfg)
+	case KindCRI:
+		return NewCRIController(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported runtime kind for NewContainerd: %s", kind)
+	}
+}
@@ -0,0 +1,268 @@
+// Package session 提供多租户会话隔离能力
+// 在同一个 Playground 部署上，多个学生/用户通过签名Cookie区分身份，
+// 容器命名、端口冲突检查与WebSocket终端均按会话维度隔离，避免互相抢占资源。
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"kwdb-playground/internal/config"
+	"kwdb-playground/internal/docker"
+	"kwdb-playground/internal/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContainerNamePrefix 返回某会话下某课程容器名称应使用的前缀
+// 形如 kwdb-playground-<courseId>-<sessionId>-
+func ContainerNamePrefix(courseID, sessionID string) string {
+	return fmt.Sprintf("kwdb-playground-%s-%s-", courseID, sessionID)
+}
+
+// Session 记录单个用户的会话状态
+type Session struct {
+	ID           string
+	CreatedAt    time.Time
+	LastActivity time.Time
+	// Containers 该会话当前持有的容器ID集合（课程容器的逻辑ID，而非DockerID）
+	Containers map[string]struct{}
+}
+
+// Manager 会话管理器
+// 负责签发/校验会话Cookie、维护每个会话的容器配额，并在空闲超时后回收容器
+type Manager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+
+	cfg              config.SessionConfig
+	dockerController docker.Controller
+	logger           *logger.Logger
+
+	stopReaper chan struct{}
+	reaperOnce sync.Once
+}
+
+// NewManager 创建会话管理器，但不启动回收器（需显式调用 StartReaper）
+func NewManager(cfg config.SessionConfig, dockerController docker.Controller, log *logger.Logger) *Manager {
+	return &Manager{
+		sessions:         make(map[string]*Session),
+		cfg:              cfg,
+		dockerController: dockerController,
+		logger:           log,
+		stopReaper:       make(chan struct{}),
+	}
+}
+
+// sign 对会话ID进行HMAC-SHA256签名，返回 "<id>.<signature>" 形式
+func (m *Manager) sign(id string) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.Secret))
+	mac.Write([]byte(id))
+	sig := hex.EncodeToString(mac.Sum(nil))
+	return id + "." + sig
+}
+
+// verify 校验已签名的Cookie值，返回其中的会话ID
+func (m *Manager) verify(signed string) (string, bool) {
+	idx := strings.LastIndex(signed, ".")
+	if idx <= 0 {
+		return "", false
+	}
+	id, sig := signed[:idx], signed[idx+1:]
+	expected := m.sign(id)
+	expectedSig := expected[strings.LastIndex(expected, ".")+1:]
+	if !hmac.Equal([]byte(sig), []byte(expectedSig)) {
+		return "", false
+	}
+	return id, true
+}
+
+// newSessionID 生成一个随机会话ID
+func newSessionID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Middleware 返回Gin中间件：从签名Cookie中解析会话，不存在则创建新会话并下发Cookie
+// 解析结果通过 Get(c) 获取
+func (m *Manager) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sess := m.resolveOrCreate(c)
+		c.Set(sessionContextKey, sess)
+		c.Next()
+	}
+}
+
+const sessionContextKey = "playgroundSession"
+
+// Get 从Gin上下文中取出当前请求绑定的会话，若中间件未启用则返回nil
+func Get(c *gin.Context) *Session {
+	v, ok := c.Get(sessionContextKey)
+	if !ok {
+		return nil
+	}
+	sess, _ := v.(*Session)
+	return sess
+}
+
+// resolveOrCreate 解析Cookie中的会话，校验失败或不存在时创建新会话并写入Cookie
+func (m *Manager) resolveOrCreate(c *gin.Context) *Session {
+	if raw, err := c.Cookie(m.cfg.CookieName); err == nil && raw != "" {
+		if id, ok := m.verify(raw); ok {
+			if sess := m.touch(id); sess != nil {
+				return sess
+			}
+			// Cookie合法但会话已被回收，复用相同ID重新注册
+			return m.register(id, c)
+		}
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		// 极端情况下随机源失败，退化为基于时间戳的ID，保证服务可用
+		id = fmt.Sprintf("fallback-%d", time.Now().UnixNano())
+	}
+	return m.register(id, c)
+}
+
+// register 注册新会话并在响应中下发签名Cookie
+func (m *Manager) register(id string, c *gin.Context) *Session {
+	m.mu.Lock()
+	sess := &Session{ID: id, CreatedAt: time.Now(), LastActivity: time.Now(), Containers: make(map[string]struct{})}
+	m.sessions[id] = sess
+	m.mu.Unlock()
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(m.cfg.CookieName, m.sign(id), 0, "/", "", false, true)
+	return sess
+}
+
+// touch 更新会话的最近活动时间，若会话不存在返回nil
+func (m *Manager) touch(id string) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sess, ok := m.sessions[id]
+	if !ok {
+		return nil
+	}
+	sess.LastActivity = time.Now()
+	return sess
+}
+
+// Touch 根据会话ID更新最近活动时间，供WebSocket终端心跳调用
+func (m *Manager) Touch(sessionID string) {
+	m.touch(sessionID)
+}
+
+// ErrQuotaExceeded 超出单用户容器配额时返回
+var ErrQuotaExceeded = fmt.Errorf("已达到该会话允许的最大并发容器数")
+
+// ReserveContainer 在创建容器前进行配额检查并登记，超出配额返回 ErrQuotaExceeded
+func (m *Manager) ReserveContainer(sessionID, containerID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sess, ok := m.sessions[sessionID]
+	if !ok {
+		sess = &Session{ID: sessionID, CreatedAt: time.Now(), Containers: make(map[string]struct{})}
+		m.sessions[sessionID] = sess
+	}
+
+	limit := m.cfg.MaxContainersPerUser
+	if limit > 0 && len(sess.Containers) >= limit {
+		return ErrQuotaExceeded
+	}
+	sess.Containers[containerID] = struct{}{}
+	sess.LastActivity = time.Now()
+	return nil
+}
+
+// ReleaseContainer 容器停止/删除后释放其在会话中的配额占用
+func (m *Manager) ReleaseContainer(sessionID, containerID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if sess, ok := m.sessions[sessionID]; ok {
+		delete(sess.Containers, containerID)
+	}
+}
+
+// StartReaper 启动后台回收器：按配置的间隔扫描所有会话，清理超过空闲TTL的容器
+func (m *Manager) StartReaper() {
+	m.reaperOnce.Do(func() {
+		interval := time.Duration(m.cfg.ReapIntervalSeconds) * time.Second
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		go m.reapLoop(interval)
+	})
+}
+
+// StopReaper 停止后台回收器，用于优雅关闭
+func (m *Manager) StopReaper() {
+	close(m.stopReaper)
+}
+
+func (m *Manager) reapLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.reapOnce()
+		case <-m.stopReaper:
+			return
+		}
+	}
+}
+
+// reapOnce 执行一轮空闲会话容器回收
+func (m *Manager) reapOnce() {
+	ttl := time.Duration(m.cfg.IdleTTLSeconds) * time.Second
+	if ttl <= 0 || m.dockerController == nil {
+		return
+	}
+
+	now := time.Now()
+	type pending struct {
+		sessionID   string
+		containerID string
+	}
+	var toRemove []pending
+
+	m.mu.Lock()
+	for id, sess := range m.sessions {
+		if now.Sub(sess.LastActivity) <= ttl {
+			continue
+		}
+		for containerID := range sess.Containers {
+			toRemove = append(toRemove, pending{sessionID: id, containerID: containerID})
+		}
+	}
+	m.mu.Unlock()
+
+	ctx := context.Background()
+	for _, p := range toRemove {
+		if err := m.dockerController.RemoveContainer(ctx, p.containerID); err != nil {
+			if m.logger != nil {
+				m.logger.Warn("[session.reaper] 清理空闲容器失败: session=%s container=%s err=%v", p.sessionID, p.containerID, err)
+			}
+			continue
+		}
+		m.ReleaseContainer(p.sessionID, p.containerID)
+		if m.logger != nil {
+			m.logger.Info("[session.reaper] 已回收空闲容器: session=%s container=%s", p.sessionID, p.containerID)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"testing"
+
+	"kwdb-playground/internal/config"
+)
+
+func newTestManager() *Manager {
+	cfg := config.SessionConfig{
+		CookieName:           "kwdb_session",
+		Secret:               "test-secret",
+		MaxContainersPerUser: 2,
+		IdleTTLSeconds:       0,
+		ReapIntervalSeconds:  0,
+	}
+	return NewManager(cfg, nil, nil)
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	m := newTestManager()
+
+	signed := m.sign("abc123")
+	id, ok := m.verify(signed)
+	if !ok {
+		t.Fatal("verify() should succeed for a value produced by sign()")
+	}
+	if id != "abc123" {
+		t.Errorf("verify() id = %s, want abc123", id)
+	}
+}
+
+func TestVerifyRejectsTamperedValue(t *testing.T) {
+	m := newTestManager()
+
+	signed := m.sign("abc123")
+	tampered := signed[:len(signed)-1] + "0"
+
+	if _, ok := m.verify(tampered); ok {
+		t.Error("verify() should reject a tampered signature")
+	}
+}
+
+func TestVerifyRejectsMalformedValue(t *testing.T) {
+	m := newTestManager()
+
+	if _, ok := m.verify("no-dot-here"); ok {
+		t.Error("verify() should reject a value without a signature separator")
+	}
+}
+
+func TestReserveContainerEnforcesQuota(t *testing.T) {
+	m := newTestManager()
+
+	if err := m.ReserveContainer("user-1", "course-a"); err != nil {
+		t.Fatalf("first reservation should succeed: %v", err)
+	}
+	if err := m.ReserveContainer("user-1", "course-b"); err != nil {
+		t.Fatalf("second reservation should succeed: %v", err)
+	}
+	if err := m.ReserveContainer("user-1", "course-c"); err != ErrQuotaExceeded {
+		t.Errorf("third reservation should fail with ErrQuotaExceeded, got: %v", err)
+	}
+
+	m.ReleaseContainer("user-1", "course-a")
+	if err := m.ReserveContainer("user-1", "course-c"); err != nil {
+		t.Errorf("reservation after release should succeed: %v", err)
+	}
+}
+
+func TestContainerNamePrefix(t *testing.T) {
+	got := ContainerNamePrefix("sql", "sess-1")
+	want := "kwdb-playground-sql-sess-1-"
+	if got != want {
+		t.Errorf("ContainerNamePrefix() = %s, want %s", got, want)
+	}
+}
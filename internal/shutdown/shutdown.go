@@ -0,0 +1,135 @@
+// Package shutdown 提供一个进程级的优雅关闭协调器，对标dockerd（moby/moby/pkg/signal）的
+// Trap模式：各长生命周期子系统（WebSocket终端、SQL连接池、Docker控制器、课程进度持久化……）
+// 按优先级注册各自的清理钩子，由这里统一捕获信号并按顺序执行，调用方不需要各自监听信号。
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"runtime"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"kwdb-playground/internal/logger"
+)
+
+// HookFunc 是一个关闭钩子：ctx 到期（或被第二次信号取消）后应尽快放弃未完成的清理并返回
+type HookFunc func(ctx context.Context) error
+
+// hook 是RegisterHook注册的一条钩子及其排序优先级
+type hook struct {
+	name     string
+	priority int
+	fn       HookFunc
+}
+
+// Coordinator 持有一组已注册的关闭钩子。包级别的 defaultCoordinator 供 RegisterHook/Trap 使用；
+// 需要在测试中验证钩子排序/超时行为的调用方可以用 NewCoordinator 构造独立实例
+type Coordinator struct {
+	mu    sync.Mutex
+	hooks []hook
+}
+
+// NewCoordinator 创建一个空的关闭协调器
+func NewCoordinator() *Coordinator {
+	return &Coordinator{}
+}
+
+// RegisterHook 注册一个关闭钩子，priority越小越先执行（例如"必须在底层连接关闭前flush完
+// 脏数据"的钩子应该用比它依赖的资源更小的priority）。同一priority内按注册顺序执行
+func (c *Coordinator) RegisterHook(name string, priority int, fn HookFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = append(c.hooks, hook{name: name, priority: priority, fn: fn})
+}
+
+// runHooks 按priority升序依次执行所有已注册钩子；ctx被取消（第二次信号升级）后，
+// 跳过所有尚未开始的钩子并立即返回，不再等待
+func (c *Coordinator) runHooks(ctx context.Context, log *logger.Logger) {
+	c.mu.Lock()
+	ordered := make([]hook, len(c.hooks))
+	copy(ordered, c.hooks)
+	c.mu.Unlock()
+
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].priority < ordered[j].priority })
+
+	for _, h := range ordered {
+		if ctx.Err() != nil {
+			log.Warn("关闭流程已被取消，跳过剩余钩子（含 %s）", h.name)
+			return
+		}
+		start := time.Now()
+		if err := h.fn(ctx); err != nil {
+			log.Error("关闭钩子 %s 执行失败（耗时 %s）: %v", h.name, time.Since(start), err)
+			continue
+		}
+		log.Debug("关闭钩子 %s 执行完成，耗时 %s", h.name, time.Since(start))
+	}
+}
+
+// dumpGoroutines 把当前所有goroutine的调用栈写到日志，供DEBUG模式下排查SIGQUIT触发的死锁/卡死
+func dumpGoroutines(log *logger.Logger) {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	log.Warn("收到SIGQUIT（DEBUG模式），转储全部goroutine栈，跳过清理:\n%s", buf[:n])
+}
+
+// Trap 注册SIGINT/SIGTERM/SIGQUIT信号处理：
+//   - 第一次信号：在deadline内按priority顺序运行所有已注册钩子，随后进程退出(0)
+//   - 第二次信号：取消正在进行的清理流程的ctx（钩子应尽快放弃并返回），不再等待deadline
+//   - 第三次及以后的信号：直接os.Exit(1)强制退出
+//
+// debug为true时（对应日志级别DEBUG），SIGQUIT会转储所有goroutine栈到日志并跳过清理，
+// 不计入上面的信号计数——可以反复发送用于诊断而不会提前触发强制退出
+func (c *Coordinator) Trap(log *logger.Logger, deadline time.Duration, debug bool) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	go func() {
+		var cancelRun context.CancelFunc
+		count := 0
+		for sig := range sigCh {
+			if debug && sig == syscall.SIGQUIT {
+				dumpGoroutines(log)
+				continue
+			}
+
+			count++
+			switch count {
+			case 1:
+				log.Info("收到信号 %v，开始优雅关闭（最长等待 %s）...", sig, deadline)
+				ctx, cancel := context.WithTimeout(context.Background(), deadline)
+				cancelRun = cancel
+				go func() {
+					c.runHooks(ctx, log)
+					cancel()
+					os.Exit(0)
+				}()
+			case 2:
+				log.Warn("再次收到信号 %v，取消正在进行的清理流程", sig)
+				if cancelRun != nil {
+					cancelRun()
+				}
+			default:
+				log.Error("第%d次收到信号 %v，强制退出", count, sig)
+				os.Exit(1)
+			}
+		}
+	}()
+}
+
+// defaultCoordinator 是进程内默认使用的关闭协调器，main及各子系统通过包级别函数访问它
+var defaultCoordinator = NewCoordinator()
+
+// RegisterHook 向默认协调器注册关闭钩子，参见 Coordinator.RegisterHook
+func RegisterHook(name string, priority int, fn HookFunc) {
+	defaultCoordinator.RegisterHook(name, priority, fn)
+}
+
+// Trap 用默认协调器捕获关闭信号，参见 Coordinator.Trap
+func Trap(log *logger.Logger, deadline time.Duration, debug bool) {
+	defaultCoordinator.Trap(log, deadline, debug)
+}
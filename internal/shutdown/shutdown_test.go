@@ -0,0 +1,110 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"kwdb-playground/internal/logger"
+)
+
+// TestCoordinatorRunHooksOrdersByPriority 验证钩子按priority升序执行
+func TestCoordinatorRunHooksOrdersByPriority(t *testing.T) {
+	c := NewCoordinator()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) HookFunc {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+
+	c.RegisterHook("third", 30, record("third"))
+	c.RegisterHook("first", 10, record("first"))
+	c.RegisterHook("second", 20, record("second"))
+
+	c.runHooks(context.Background(), logger.NewLogger(logger.ERROR))
+
+	want := []string{"first", "second", "third"}
+	if len(order) != len(want) {
+		t.Fatalf("Expected %d hooks to run, got %d: %v", len(want), len(order), order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("Expected hook order %v, got %v", want, order)
+			break
+		}
+	}
+}
+
+// TestCoordinatorRunHooksContinuesAfterError 验证某个钩子失败不影响后续钩子执行
+func TestCoordinatorRunHooksContinuesAfterError(t *testing.T) {
+	c := NewCoordinator()
+
+	ran := false
+	c.RegisterHook("failing", 1, func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+	c.RegisterHook("after-failure", 2, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	c.runHooks(context.Background(), logger.NewLogger(logger.ERROR))
+
+	if !ran {
+		t.Error("Expected hook after a failing one to still run")
+	}
+}
+
+// TestCoordinatorRunHooksSkipsRemainingWhenCancelled 验证ctx被取消后跳过尚未开始的钩子
+func TestCoordinatorRunHooksSkipsRemainingWhenCancelled(t *testing.T) {
+	c := NewCoordinator()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := false
+
+	c.RegisterHook("cancels-context", 1, func(ctx context.Context) error {
+		cancel()
+		return nil
+	})
+	c.RegisterHook("should-be-skipped", 2, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	c.runHooks(ctx, logger.NewLogger(logger.ERROR))
+
+	if ran {
+		t.Error("Expected hook registered after context cancellation to be skipped")
+	}
+}
+
+// TestCoordinatorRunHooksRespectsDeadline 验证超过deadline的ctx会让后续钩子被跳过
+func TestCoordinatorRunHooksRespectsDeadline(t *testing.T) {
+	c := NewCoordinator()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	ran := false
+	c.RegisterHook("slow", 1, func(ctx context.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})
+	c.RegisterHook("too-late", 2, func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	c.runHooks(ctx, logger.NewLogger(logger.ERROR))
+
+	if ran {
+		t.Error("Expected hook scheduled after the deadline elapsed to be skipped")
+	}
+}
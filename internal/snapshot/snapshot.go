@@ -0,0 +1,239 @@
+// Package snapshot 实现课程容器的快照与恢复
+// 一份快照由两部分组成：commit 出的容器镜像（保留容器文件系统的全部改动）与
+// backend.volumes 声明的宿主机卷数据打包（容器文件系统之外、挂载进来的持久化数据）。
+// 二者都可能缺失（例如镜像被清理、课程未声明卷），上层按需退化处理。
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Manifest 描述一份课程快照
+type Manifest struct {
+	ID            string    `json:"id"`
+	CourseID      string    `json:"courseId"`
+	Image         string    `json:"image"`                   // commit 后的镜像引用（courseID + 快照ID 作为 tag）
+	VolumeArchive string    `json:"volumeArchive,omitempty"` // 卷数据归档文件名（相对 Store 目录），为空表示课程未声明卷
+	VolumeSHA256  string    `json:"volumeSha256,omitempty"`  // 卷归档的内容摘要，用于恢复前校验完整性
+	SchemaDump    string    `json:"schemaDump,omitempty"`    // KWDB Schema 语句转储，镜像丢失时用于在新容器上重建
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Store 负责快照清单在磁盘上的持久化
+// 目录布局：<dataDir>/snapshots/<courseID>/<snapshotID>.json 与同目录下的卷归档文件
+type Store struct {
+	dataDir string
+}
+
+// NewStore 基于 config.DataDir 构建快照存储
+func NewStore(dataDir string) *Store {
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	return &Store{dataDir: dataDir}
+}
+
+// Dir 返回快照归档文件所在的根目录，供调用方拼接卷归档的完整路径
+func (s *Store) Dir() string {
+	return filepath.Join(s.dataDir, "snapshots")
+}
+
+func (s *Store) courseDir(courseID string) string {
+	return filepath.Join(s.Dir(), courseID)
+}
+
+// Save 将快照清单写入磁盘，调用前 VolumeArchive 指向的文件应已写入 s.Dir() 下
+func (s *Store) Save(m *Manifest) error {
+	dir := s.courseDir(m.CourseID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建快照目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化快照清单失败: %w", err)
+	}
+	path := filepath.Join(dir, m.ID+".json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入快照清单失败: %w", err)
+	}
+	return nil
+}
+
+// Get 按快照ID查找清单（遍历所有课程子目录，因为调用方可能只持有快照ID）
+func (s *Store) Get(snapshotID string) (*Manifest, error) {
+	entries, err := os.ReadDir(s.Dir())
+	if err != nil {
+		return nil, fmt.Errorf("读取快照目录失败: %w", err)
+	}
+	for _, courseEntry := range entries {
+		if !courseEntry.IsDir() {
+			continue
+		}
+		path := filepath.Join(s.courseDir(courseEntry.Name()), snapshotID+".json")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("解析快照清单失败: %w", err)
+		}
+		return &m, nil
+	}
+	return nil, fmt.Errorf("快照 %s 不存在", snapshotID)
+}
+
+// List 返回指定课程的全部快照清单，按创建时间升序排列
+func (s *Store) List(courseID string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(s.courseDir(courseID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取快照目录失败: %w", err)
+	}
+
+	var manifests []*Manifest
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.courseDir(courseID), entry.Name()))
+		if err != nil {
+			continue
+		}
+		var m Manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		manifests = append(manifests, &m)
+	}
+	for i := 1; i < len(manifests); i++ {
+		for j := i; j > 0 && manifests[j-1].CreatedAt.After(manifests[j].CreatedAt); j-- {
+			manifests[j-1], manifests[j] = manifests[j], manifests[j-1]
+		}
+	}
+	return manifests, nil
+}
+
+// ExportVolumes 将一组宿主机目录打包为单个 tar.gz 归档，tar 内条目保留各自的绝对路径，
+// 以便 ImportVolumes 能原样还原到相同位置
+func ExportVolumes(hostPaths []string, destPath string) (sha256Hex string, err error) {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return "", fmt.Errorf("创建归档目录失败: %w", err)
+	}
+	f, err := os.Create(destPath)
+	if err != nil {
+		return "", fmt.Errorf("创建归档文件失败: %w", err)
+	}
+	defer f.Close()
+
+	digest := sha256.New()
+	gw := gzip.NewWriter(io.MultiWriter(f, digest))
+	tw := tar.NewWriter(gw)
+
+	for _, hostPath := range hostPaths {
+		if err := addToTar(tw, hostPath); err != nil {
+			tw.Close()
+			gw.Close()
+			return "", fmt.Errorf("打包卷数据失败 %s: %w", hostPath, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("关闭 tar 写入器失败: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return "", fmt.Errorf("关闭 gzip 写入器失败: %w", err)
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// addToTar 递归写入单个路径（文件或目录）及其内容，tar 头名使用绝对路径
+func addToTar(tw *tar.Writer, root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return writeFileToTar(tw, root, root, info)
+	}
+	return filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		return writeFileToTar(tw, path, path, fi)
+	})
+}
+
+func writeFileToTar(tw *tar.Writer, name, path string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = strings.TrimPrefix(name, string(filepath.Separator))
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ImportVolumes 解压归档，将每个条目还原到 tar 头中记录的原始绝对路径
+func ImportVolumes(archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开归档文件失败: %w", err)
+	}
+	defer f.Close()
+
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("读取归档失败: %w", err)
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("解析归档条目失败: %w", err)
+		}
+
+		destPath := string(filepath.Separator) + header.Name
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return fmt.Errorf("创建还原目录失败 %s: %w", destPath, err)
+		}
+		out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+		if err != nil {
+			return fmt.Errorf("写入还原文件失败 %s: %w", destPath, err)
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return fmt.Errorf("写入还原文件内容失败 %s: %w", destPath, err)
+		}
+		out.Close()
+	}
+}
@@ -1,19 +1,53 @@
 package sql
 
 import (
-    "context"
-    "fmt"
-    "time"
+	"context"
+	"fmt"
+	"sync"
+	"time"
 
-    "kwdb-playground/internal/course"
-    "kwdb-playground/internal/docker"
+	"kwdb-playground/internal/course"
+	"kwdb-playground/internal/docker"
+	"kwdb-playground/internal/metrics"
 
-    "github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Driver 管理KWDB的连接与就绪
 type Driver struct {
-    pool *pgxpool.Pool
+	pool            *pgxpool.Pool
+	metricsRecorder metrics.Recorder
+
+	sandboxMu sync.Mutex
+	sandboxes map[string]*Sandbox
+}
+
+// Sandbox 返回课程对应的沙箱运行时状态（并发信号量、限流桶、角色缓存等），首次调用时按 cfg 创建并缓存
+func (d *Driver) Sandbox(courseID string, cfg SandboxConfig) *Sandbox {
+	d.sandboxMu.Lock()
+	defer d.sandboxMu.Unlock()
+	if d.sandboxes == nil {
+		d.sandboxes = make(map[string]*Sandbox)
+	}
+	sb, ok := d.sandboxes[courseID]
+	if !ok {
+		sb = NewSandbox(cfg)
+		d.sandboxes[courseID] = sb
+	}
+	return sb
+}
+
+// SetMetricsRecorder 替换默认的 Prometheus Recorder，未调用时 Observe 不上报指标
+func (d *Driver) SetMetricsRecorder(r metrics.Recorder) {
+	d.metricsRecorder = r
+}
+
+// RecordQuery 上报一次 SQL 查询的耗时为 kwdb_playground_sql_query_duration_seconds 指标
+// 调用方自行计时（通常用 defer d.RecordQuery(courseID, time.Now())），避免每个 API handler 各自重复埋点细节
+func (d *Driver) RecordQuery(courseID string, start time.Time) {
+	if d.metricsRecorder != nil {
+		d.metricsRecorder.SQLQuery(courseID, time.Since(start))
+	}
 }
 
 // EnsureReady 确保容器与数据库就绪，并初始化连接池
@@ -21,49 +55,49 @@ type Driver struct {
 // - course: 课程配置（包含 backend.port 等）
 // - dc: Docker 控制器，用于启动/复用容器
 func (d *Driver) EnsureReady(ctx context.Context, course *course.Course, dc docker.Controller) error {
-    if course == nil {
-        return fmt.Errorf("course is nil")
-    }
-    port := course.Backend.Port
-    if port <= 0 {
-        return fmt.Errorf("invalid backend.port: %d", port)
-    }
+	if course == nil {
+		return fmt.Errorf("course is nil")
+	}
+	port := course.Backend.Port
+	if port <= 0 {
+		return fmt.Errorf("invalid backend.port: %d", port)
+	}
 
-    // 说明：为了避免阻塞接口（导致前端长时间显示“正在加载连接信息...”），
-    // 这里改为短时快速探测策略：在 1 秒内尝试最多 3 次连接，失败立即返回，让前端显示未连接状态。
+	// 说明：为了避免阻塞接口（导致前端长时间显示“正在加载连接信息...”），
+	// 这里改为短时快速探测策略：在 1 秒内尝试最多 3 次连接，失败立即返回，让前端显示未连接状态。
 
-    attempts := 3               // 最多尝试次数
-    interval := 300 * time.Millisecond // 每次尝试间隔
-    deadline := time.Now().Add(1 * time.Second)
+	attempts := 3                      // 最多尝试次数
+	interval := 300 * time.Millisecond // 每次尝试间隔
+	deadline := time.Now().Add(1 * time.Second)
 
-    dsn := fmt.Sprintf("postgresql://root@localhost:%d/defaultdb?sslmode=disable", port)
-    cfg, err := pgxpool.ParseConfig(dsn)
-    if err != nil {
-        return fmt.Errorf("parse dsn failed: %w", err)
-    }
-    cfg.MaxConns = 4
+	dsn := fmt.Sprintf("postgresql://root@localhost:%d/defaultdb?sslmode=disable", port)
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return fmt.Errorf("parse dsn failed: %w", err)
+	}
+	cfg.MaxConns = 4
 
-    for i := 0; i < attempts && time.Now().Before(deadline); i++ {
-        // 为每次尝试设置一个短超时，避免阻塞
-        attemptCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
-        pool, err := pgxpool.NewWithConfig(attemptCtx, cfg)
-        if err == nil {
-            var one int
-            // SELECT 1 进行连通性验证（短超时）
-            err = pool.QueryRow(attemptCtx, "SELECT 1").Scan(&one)
-            if err == nil && one == 1 {
-                d.pool = pool
-                cancel()
-                return nil
-            }
-            pool.Close()
-        }
-        cancel()
-        time.Sleep(interval)
-    }
+	for i := 0; i < attempts && time.Now().Before(deadline); i++ {
+		// 为每次尝试设置一个短超时，避免阻塞
+		attemptCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		pool, err := pgxpool.NewWithConfig(attemptCtx, cfg)
+		if err == nil {
+			var one int
+			// SELECT 1 进行连通性验证（短超时）
+			err = pool.QueryRow(attemptCtx, "SELECT 1").Scan(&one)
+			if err == nil && one == 1 {
+				d.pool = pool
+				cancel()
+				return nil
+			}
+			pool.Close()
+		}
+		cancel()
+		time.Sleep(interval)
+	}
 
-    return fmt.Errorf("KWDB not ready on port %d (quick probe failed)", port)
+	return fmt.Errorf("KWDB not ready on port %d (quick probe failed)", port)
 }
 
 // Pool 返回连接池
-func (d *Driver) Pool() *pgxpool.Pool { return d.pool }
\ No newline at end of file
+func (d *Driver) Pool() *pgxpool.Pool { return d.pool }
@@ -4,35 +4,117 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"kwdb-playground/internal/course"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// defaultDriverIdleTTL 驱动空闲超过该时长且没有被占用的连接时，由janitor后台goroutine关闭并回收
+const defaultDriverIdleTTL = 30 * time.Minute
+
+// defaultMaxOpenPools 默认同时保留的最大连接池课程数，超出后按最近使用时间淘汰最旧的
+const defaultMaxOpenPools = 32
+
+// driverEvictCheckInterval janitor后台goroutine的巡检间隔
+const driverEvictCheckInterval = time.Minute
+
+// driverEntry 包装一个课程的Driver及DriverManager层面的元数据（最近一次使用时间）
+type driverEntry struct {
+	driver *Driver
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+func (e *driverEntry) touch() {
+	e.mu.Lock()
+	e.lastUsed = time.Now()
+	e.mu.Unlock()
+}
+
+func (e *driverEntry) getLastUsed() time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.lastUsed
+}
+
+// poolStat 返回驱动当前连接池的占用/空闲连接数，驱动尚未建立连接池时返回0,0
+func poolStat(d *Driver) (inUse, idle int32) {
+	if d.pool == nil {
+		return 0, 0
+	}
+	stat := d.pool.Stat()
+	if stat == nil {
+		return 0, 0
+	}
+	return stat.AcquiredConns(), stat.IdleConns()
+}
+
+// DriverStats 单个课程驱动的运行状态快照，供Stats()汇总使用
+type DriverStats struct {
+	CourseID   string
+	LastUsed   time.Time
+	InUseConns int32
+	IdleConns  int32
+}
+
+// ManagerStats DriverManager的整体运行状态快照
+type ManagerStats struct {
+	Drivers   []DriverStats
+	Evictions uint64
+}
+
 // DriverManager 管理多个课程的SQL驱动实例
 // 为每个课程维护独立的连接池，避免并发冲突
+// 连接池不会无限累积：janitor后台goroutine按 idleTTL 关闭长期空闲（且没有被占用连接）的连接池，
+// 并在课程数超过 maxOpenPools 时按最近使用时间淘汰最旧的，避免一波课程激活把连接耗尽
 type DriverManager struct {
-	drivers map[string]*Driver // key: courseID
+	drivers map[string]*driverEntry
 	mu      sync.RWMutex
+
+	idleTTL      time.Duration
+	maxOpenPools int
+	evictions    uint64
+
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
-// NewDriverManager 创建新的驱动管理器
+// NewDriverManager 创建新的驱动管理器，使用默认的空闲淘汰策略
+// （30分钟未使用即回收连接池，最多同时保留32个课程的连接池）
 func NewDriverManager() *DriverManager {
-	return &DriverManager{
-		drivers: make(map[string]*Driver),
+	return NewDriverManagerWithEviction(defaultDriverIdleTTL, defaultMaxOpenPools)
+}
+
+// NewDriverManagerWithEviction 创建新的驱动管理器，可自定义空闲淘汰时长与最大连接池数
+// idleTTL <= 0 表示禁用janitor（不会自动回收空闲连接池）；maxOpenPools <= 0 表示不限制课程数量
+func NewDriverManagerWithEviction(idleTTL time.Duration, maxOpenPools int) *DriverManager {
+	m := &DriverManager{
+		drivers:      make(map[string]*driverEntry),
+		idleTTL:      idleTTL,
+		maxOpenPools: maxOpenPools,
+		stopCh:       make(chan struct{}),
 	}
+	if idleTTL > 0 {
+		m.wg.Add(1)
+		go m.janitorLoop()
+	}
+	return m
 }
 
 // GetDriver 获取指定课程的驱动实例
-// 如果不存在，会自动创建新的实例
+// 如果不存在，会自动创建新的实例；每次获取都会刷新该课程的最近使用时间
 func (m *DriverManager) GetDriver(courseID string) *Driver {
 	m.mu.RLock()
-	driver, exists := m.drivers[courseID]
+	entry, exists := m.drivers[courseID]
 	m.mu.RUnlock()
 
 	if exists {
-		return driver
+		entry.touch()
+		return entry.driver
 	}
 
 	// 需要创建新的驱动实例
@@ -40,13 +122,15 @@ func (m *DriverManager) GetDriver(courseID string) *Driver {
 	defer m.mu.Unlock()
 
 	// 双重检查，防止并发创建
-	if driver, exists = m.drivers[courseID]; exists {
-		return driver
+	if entry, exists = m.drivers[courseID]; exists {
+		entry.touch()
+		return entry.driver
 	}
 
-	driver = &Driver{}
-	m.drivers[courseID] = driver
-	return driver
+	entry = &driverEntry{driver: &Driver{}, lastUsed: time.Now()}
+	m.drivers[courseID] = entry
+	m.evictOverCapLocked()
+	return entry.driver
 }
 
 // EnsureReady 确保指定课程的数据库连接就绪
@@ -65,28 +149,44 @@ func (m *DriverManager) Pool(courseID string) *pgxpool.Pool {
 	return driver.Pool()
 }
 
+// Touch 刷新指定课程驱动的最近使用时间，防止其在长查询执行期间被janitor误判为空闲而淘汰
+// 持有 Pool() 返回的连接池并反复发起查询的调用方，应在每次实际使用时调用本方法续期
+func (m *DriverManager) Touch(courseID string) {
+	m.mu.RLock()
+	entry, exists := m.drivers[courseID]
+	m.mu.RUnlock()
+	if exists {
+		entry.touch()
+	}
+}
+
 // RemoveDriver 移除指定课程的驱动实例（课程停止时调用）
 func (m *DriverManager) RemoveDriver(courseID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if driver, exists := m.drivers[courseID]; exists {
+	if entry, exists := m.drivers[courseID]; exists {
 		// 关闭连接池
-		if driver.pool != nil {
-			driver.pool.Close()
+		if entry.driver.pool != nil {
+			entry.driver.pool.Close()
 		}
 		delete(m.drivers, courseID)
 	}
 }
 
-// Close 关闭所有驱动实例
+// Close 停止janitor后台goroutine并关闭所有驱动实例
 func (m *DriverManager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.stopCh)
+	})
+	m.wg.Wait()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for courseID, driver := range m.drivers {
-		if driver.pool != nil {
-			driver.pool.Close()
+	for courseID, entry := range m.drivers {
+		if entry.driver.pool != nil {
+			entry.driver.pool.Close()
 		}
 		delete(m.drivers, courseID)
 	}
@@ -98,3 +198,100 @@ func (m *DriverManager) GetCourseCount() int {
 	defer m.mu.RUnlock()
 	return len(m.drivers)
 }
+
+// Stats 返回当前所有课程驱动的运行状态快照（最近使用时间、占用/空闲连接数）及累计淘汰次数，
+// 供监控/诊断使用
+func (m *DriverManager) Stats() ManagerStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := ManagerStats{
+		Drivers:   make([]DriverStats, 0, len(m.drivers)),
+		Evictions: m.evictions,
+	}
+	for courseID, entry := range m.drivers {
+		inUse, idle := poolStat(entry.driver)
+		stats.Drivers = append(stats.Drivers, DriverStats{
+			CourseID:   courseID,
+			LastUsed:   entry.getLastUsed(),
+			InUseConns: inUse,
+			IdleConns:  idle,
+		})
+	}
+	return stats
+}
+
+// janitorLoop 按 driverEvictCheckInterval 周期巡检并淘汰空闲超时的驱动，stopCh关闭后退出
+func (m *DriverManager) janitorLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(driverEvictCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.evictIdle()
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// evictIdle 关闭并移除所有空闲超过 idleTTL 且当前没有被占用连接的驱动
+func (m *DriverManager) evictIdle() {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for courseID, entry := range m.drivers {
+		if entry.driver.pool == nil {
+			continue
+		}
+		if now.Sub(entry.getLastUsed()) < m.idleTTL {
+			continue
+		}
+		if inUse, _ := poolStat(entry.driver); inUse > 0 {
+			continue
+		}
+		entry.driver.pool.Close()
+		delete(m.drivers, courseID)
+		m.evictions++
+	}
+}
+
+// evictOverCapLocked 在课程数超过 maxOpenPools 时，按最近使用时间反复淘汰最旧且当前未被占用
+// 连接的驱动，直到回到上限以内。调用方必须持有 m.mu 写锁
+func (m *DriverManager) evictOverCapLocked() {
+	if m.maxOpenPools <= 0 {
+		return
+	}
+
+	for {
+		openCount := 0
+		var oldestID string
+		var oldestEntry *driverEntry
+
+		for courseID, entry := range m.drivers {
+			if entry.driver.pool == nil {
+				continue
+			}
+			openCount++
+			if inUse, _ := poolStat(entry.driver); inUse > 0 {
+				continue
+			}
+			if oldestEntry == nil || entry.getLastUsed().Before(oldestEntry.getLastUsed()) {
+				oldestID, oldestEntry = courseID, entry
+			}
+		}
+
+		if openCount <= m.maxOpenPools || oldestEntry == nil {
+			return
+		}
+
+		oldestEntry.driver.pool.Close()
+		delete(m.drivers, oldestID)
+		m.evictions++
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 )
 
 // TestDriverManagerConcurrentAccess 测试驱动管理器的并发安全性
@@ -97,6 +98,69 @@ func TestDriverManagerClose(t *testing.T) {
 	}
 }
 
+// TestDriverManagerTouch 测试Touch刷新最近使用时间，以及对未知课程调用时为no-op
+func TestDriverManagerTouch(t *testing.T) {
+	manager := NewDriverManagerWithEviction(0, 0)
+	defer manager.Close()
+
+	manager.GetDriver("course-touch")
+
+	before := manager.Stats().Drivers[0].LastUsed
+	time.Sleep(5 * time.Millisecond)
+	manager.Touch("course-touch")
+	after := manager.Stats().Drivers[0].LastUsed
+
+	if !after.After(before) {
+		t.Errorf("Touch should advance LastUsed, before=%v after=%v", before, after)
+	}
+
+	// 对不存在的课程调用Touch不应panic，也不应凭空创建驱动
+	manager.Touch("no-such-course")
+	if count := manager.GetCourseCount(); count != 1 {
+		t.Errorf("Touch on unknown course should not create a driver, got count=%d", count)
+	}
+}
+
+// TestDriverManagerStats 测试Stats返回的快照内容
+func TestDriverManagerStats(t *testing.T) {
+	manager := NewDriverManagerWithEviction(0, 0)
+	defer manager.Close()
+
+	for i := 0; i < 3; i++ {
+		manager.GetDriver(fmt.Sprintf("course-%d", i))
+	}
+
+	stats := manager.Stats()
+	if len(stats.Drivers) != 3 {
+		t.Fatalf("Expected 3 entries in Stats(), got %d", len(stats.Drivers))
+	}
+	if stats.Evictions != 0 {
+		t.Errorf("Expected 0 evictions, got %d", stats.Evictions)
+	}
+	for _, ds := range stats.Drivers {
+		// 尚未建立连接池（EnsureReady未被调用），占用/空闲连接数应均为0
+		if ds.InUseConns != 0 || ds.IdleConns != 0 {
+			t.Errorf("Expected zero conns for course %s before EnsureReady, got inUse=%d idle=%d",
+				ds.CourseID, ds.InUseConns, ds.IdleConns)
+		}
+	}
+}
+
+// TestDriverManagerCapSkipsDriversWithoutPool 测试maxOpenPools上限只约束已建立连接池的驱动，
+// 尚未调用EnsureReady的课程（没有连接池可关闭）不会被janitor/cap逻辑误删
+func TestDriverManagerCapSkipsDriversWithoutPool(t *testing.T) {
+	manager := NewDriverManagerWithEviction(0, 2)
+	defer manager.Close()
+
+	for i := 0; i < 5; i++ {
+		manager.GetDriver(fmt.Sprintf("course-%d", i))
+	}
+
+	if count := manager.GetCourseCount(); count != 5 {
+		t.Errorf("Expected all 5 bare drivers to survive (no open pool to evict), got %d", count)
+	}
+}
+
 // BenchmarkDriverManagerGetDriver 测试驱动获取性能
 func BenchmarkDriverManagerGetDriver(b *testing.B) {
 	manager := NewDriverManager()
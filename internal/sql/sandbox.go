@@ -0,0 +1,214 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// 沙箱模式未显式配置时套用的默认限制
+const (
+	defaultStatementTimeoutMs         = 5000
+	defaultIdleInTransactionTimeoutMs = 5000
+	defaultMaxResultBytes             = 2 << 20 // 2MiB
+	defaultMaxConcurrentQueries       = 2
+	defaultQueriesPerMinute           = 30
+	defaultMaxTempTables              = 4
+)
+
+// SandboxConfig 沙箱模式下单个课程的执行限制
+type SandboxConfig struct {
+	StatementTimeoutMs         int
+	IdleInTransactionTimeoutMs int
+	MaxResultBytes             int
+	MaxConcurrentQueries       int
+	QueriesPerMinute           int
+	MaxTempTables              int
+}
+
+// NewSandboxConfig 按课程声明的字段构建 SandboxConfig，字段为0（未配置）时套用包内默认值
+func NewSandboxConfig(statementTimeoutMs, idleInTxTimeoutMs, maxResultBytes, maxConcurrentQueries, queriesPerMinute, maxTempTables int) SandboxConfig {
+	cfg := SandboxConfig{
+		StatementTimeoutMs:         statementTimeoutMs,
+		IdleInTransactionTimeoutMs: idleInTxTimeoutMs,
+		MaxResultBytes:             maxResultBytes,
+		MaxConcurrentQueries:       maxConcurrentQueries,
+		QueriesPerMinute:           queriesPerMinute,
+		MaxTempTables:              maxTempTables,
+	}
+	if cfg.StatementTimeoutMs <= 0 {
+		cfg.StatementTimeoutMs = defaultStatementTimeoutMs
+	}
+	if cfg.IdleInTransactionTimeoutMs <= 0 {
+		cfg.IdleInTransactionTimeoutMs = defaultIdleInTransactionTimeoutMs
+	}
+	if cfg.MaxResultBytes <= 0 {
+		cfg.MaxResultBytes = defaultMaxResultBytes
+	}
+	if cfg.MaxConcurrentQueries <= 0 {
+		cfg.MaxConcurrentQueries = defaultMaxConcurrentQueries
+	}
+	if cfg.QueriesPerMinute <= 0 {
+		cfg.QueriesPerMinute = defaultQueriesPerMinute
+	}
+	if cfg.MaxTempTables <= 0 {
+		cfg.MaxTempTables = defaultMaxTempTables
+	}
+	return cfg
+}
+
+// SandboxRoleName 返回课程对应的沙箱只读角色名，courseID 中的非字母数字字符会被替换为下划线以得到合法标识符
+func SandboxRoleName(courseID string) string {
+	var b strings.Builder
+	b.WriteString("sandbox_")
+	for _, r := range courseID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// tokenBucket 简单令牌桶限流器：容量等于每分钟配额，按 perMinute/60 的速率持续补充
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	rate     float64 // 每秒补充的令牌数
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int) *tokenBucket {
+	capacity := float64(perMinute)
+	return &tokenBucket{tokens: capacity, capacity: capacity, rate: capacity / 60, last: time.Now()}
+}
+
+// Allow 尝试消耗一个令牌，桶中暂无可用令牌时返回 false
+func (b *tokenBucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Sandbox 某课程SQL终端沙箱模式下的运行时状态：只读角色的惰性创建、跨会话的并发查询信号量，
+// 以及按用户/会话归属的限流桶与临时表配额
+type Sandbox struct {
+	cfg SandboxConfig
+	sem chan struct{}
+
+	roleOnce sync.Once
+	roleName string
+	roleErr  error
+
+	bucketsMu sync.Mutex
+	buckets   map[string]*tokenBucket
+
+	tempMu    sync.Mutex
+	tempCount map[string]int
+}
+
+// NewSandbox 创建沙箱运行时状态，按 cfg.MaxConcurrentQueries 初始化并发信号量
+func NewSandbox(cfg SandboxConfig) *Sandbox {
+	return &Sandbox{
+		cfg:       cfg,
+		sem:       make(chan struct{}, cfg.MaxConcurrentQueries),
+		buckets:   make(map[string]*tokenBucket),
+		tempCount: make(map[string]int),
+	}
+}
+
+// Config 返回本沙箱生效的限制配置
+func (s *Sandbox) Config() SandboxConfig { return s.cfg }
+
+// Allow 按 key（通常是JWT中的userID，退化为会话ID）做每分钟限流，超出配额返回 false
+func (s *Sandbox) Allow(key string) bool {
+	s.bucketsMu.Lock()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = newTokenBucket(s.cfg.QueriesPerMinute)
+		s.buckets[key] = b
+	}
+	s.bucketsMu.Unlock()
+	return b.Allow()
+}
+
+// Acquire 获取一个并发查询槽位，ctx取消/超时时返回其错误
+func (s *Sandbox) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case s.sem <- struct{}{}:
+		return func() { <-s.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ReserveTempTable 为 key 申请一个临时表配额，超出 cfg.MaxTempTables 时返回 false
+func (s *Sandbox) ReserveTempTable(key string) bool {
+	s.tempMu.Lock()
+	defer s.tempMu.Unlock()
+	if s.tempCount[key] >= s.cfg.MaxTempTables {
+		return false
+	}
+	s.tempCount[key]++
+	return true
+}
+
+// ReleaseTempTables 清空 key 持有的临时表配额计数（会话结束、连接被 DISCARD 时调用）
+func (s *Sandbox) ReleaseTempTables(key string) {
+	s.tempMu.Lock()
+	defer s.tempMu.Unlock()
+	delete(s.tempCount, key)
+}
+
+// EnsureRole 确保沙箱只读角色存在（幂等，仅执行一次），返回角色名供调用方 SET ROLE 使用
+// 角色本身不持有任何额外写权限，真正的只读限制由调用方在拿到的连接上 SET default_transaction_read_only
+func (s *Sandbox) EnsureRole(ctx context.Context, pool *pgxpool.Pool, roleName string) (string, error) {
+	s.roleOnce.Do(func() {
+		_, err := pool.Exec(ctx, fmt.Sprintf(
+			`DO $$ BEGIN IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '%s') THEN CREATE ROLE %s NOLOGIN; END IF; END $$;`,
+			roleName, roleName))
+		if err != nil {
+			s.roleErr = fmt.Errorf("创建沙箱角色失败: %w", err)
+			return
+		}
+		if _, err := pool.Exec(ctx, fmt.Sprintf("GRANT %s TO CURRENT_USER", roleName)); err != nil {
+			s.roleErr = fmt.Errorf("授予沙箱角色失败: %w", err)
+			return
+		}
+		s.roleName = roleName
+	})
+	return s.roleName, s.roleErr
+}
+
+// ConfigureConn 在给定连接上应用沙箱会话级限制：切换到只读角色、默认只读事务、语句与空闲事务超时
+func (s *Sandbox) ConfigureConn(ctx context.Context, conn *pgxpool.Conn, roleName string) error {
+	stmts := []string{
+		fmt.Sprintf("SET ROLE %s", roleName),
+		"SET default_transaction_read_only = on",
+		fmt.Sprintf("SET statement_timeout = %d", s.cfg.StatementTimeoutMs),
+		fmt.Sprintf("SET idle_in_transaction_session_timeout = %d", s.cfg.IdleInTransactionTimeoutMs),
+	}
+	for _, stmt := range stmts {
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("配置沙箱连接失败: %w", err)
+		}
+	}
+	return nil
+}
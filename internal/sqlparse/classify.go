@@ -0,0 +1,354 @@
+// Package sqlparse 提供轻量级的 SQL 语句分类与切分能力。
+// 它不是一个完整的 SQL 解析器，只做到足以正确区分语句类型、
+// 正确跳过字符串/注释/美元引用内容所需的最小分词。
+package sqlparse
+
+import "strings"
+
+// StatementKind 描述一条 SQL 语句的类别
+type StatementKind int
+
+const (
+	// Unknown 无法识别（空语句或仅包含注释）
+	Unknown StatementKind = iota
+	// Select 返回结果集的查询（SELECT，含以 WITH 开头但最终落到 SELECT 的 CTE）
+	Select
+	// Show 元数据查看类语句（SHOW/DESCRIBE/DESC）
+	Show
+	// Explain EXPLAIN 语句，本身不返回业务数据但会返回一个结果集
+	Explain
+	// DML 数据操纵语句（INSERT/UPDATE/DELETE/MERGE，含落到它们的 CTE）
+	DML
+	// DDL 数据定义语句（CREATE/ALTER/DROP/TRUNCATE/COMMENT ON）
+	DDL
+	// TCL 事务控制语句（BEGIN/COMMIT/ROLLBACK/SAVEPOINT/...）
+	TCL
+	// SessionControl 修改会话级 GUC/权限的语句（SET/RESET，含 SET ROLE、
+	// SET SESSION AUTHORIZATION），单独归类是因为沙箱模式需要针对它们做拒绝而非放行
+	SessionControl
+	// Utility 其余工具类语句（COPY/GRANT/VACUUM/CALL/...）
+	Utility
+)
+
+// String 返回 StatementKind 的可读名称，便于日志与调试
+func (k StatementKind) String() string {
+	switch k {
+	case Select:
+		return "Select"
+	case Show:
+		return "Show"
+	case Explain:
+		return "Explain"
+	case DML:
+		return "DML"
+	case DDL:
+		return "DDL"
+	case TCL:
+		return "TCL"
+	case SessionControl:
+		return "SessionControl"
+	case Utility:
+		return "Utility"
+	default:
+		return "Unknown"
+	}
+}
+
+// IsQuery 返回该语句是否会产生可流式读取的结果集（Select/Show/Explain）
+func (k StatementKind) IsQuery() bool {
+	return k == Select || k == Show || k == Explain
+}
+
+var (
+	dmlLeadKeywords = map[string]bool{"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true}
+	ddlKeywords     = map[string]bool{
+		"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "COMMENT": true, "RENAME": true,
+	}
+	tclKeywords = map[string]bool{
+		"BEGIN": true, "START": true, "COMMIT": true, "ROLLBACK": true, "SAVEPOINT": true,
+		"RELEASE": true, "END": true,
+	}
+	sessionControlKeywords = map[string]bool{"SET": true, "RESET": true}
+)
+
+// Classify 判断单条 SQL 语句（不含分号）属于哪种 StatementKind。
+// 传入的语句不应再包含其他语句，多语句请先用 SplitStatements 切分。
+func Classify(stmt string) StatementKind {
+	tokens := tokenize(stmt)
+	if len(tokens) == 0 {
+		return Unknown
+	}
+
+	head := tokens[0]
+	switch head {
+	case "SELECT":
+		return Select
+	case "SHOW", "DESCRIBE", "DESC":
+		return Show
+	case "EXPLAIN":
+		return Explain
+	case "WITH":
+		return classifyWith(tokens)
+	}
+
+	if dmlLeadKeywords[head] {
+		return DML
+	}
+	if ddlKeywords[head] {
+		return DDL
+	}
+	if tclKeywords[head] {
+		return TCL
+	}
+	if sessionControlKeywords[head] {
+		return SessionControl
+	}
+	return Utility
+}
+
+// classifyWith 跳过 WITH 子句中的 CTE 列表（可能嵌套括号），
+// 找到其后真正执行的语句关键词，从而正确区分
+// `WITH x AS (...) SELECT ...`（Select）与 `WITH x AS (...) DELETE ...`（DML）
+func classifyWith(tokens []string) StatementKind {
+	i := 1
+	if i < len(tokens) && tokens[i] == "RECURSIVE" {
+		i++
+	}
+	for i < len(tokens) {
+		// 跳过一个 CTE 定义：name [(col, ...)] AS ( ... )，用括号深度越过定义体
+		for i < len(tokens) && tokens[i] != "(" && tokens[i] != "," {
+			if isFinalStatementKeyword(tokens[i]) {
+				return finalStatementKind(tokens[i])
+			}
+			i++
+		}
+		if i < len(tokens) && tokens[i] == "(" {
+			depth := 1
+			i++
+			for i < len(tokens) && depth > 0 {
+				switch tokens[i] {
+				case "(":
+					depth++
+				case ")":
+					depth--
+				}
+				i++
+			}
+			continue
+		}
+		if i < len(tokens) && tokens[i] == "," {
+			i++
+			continue
+		}
+	}
+	return Select // 语法不完整时退化为最常见的情形
+}
+
+func isFinalStatementKeyword(tok string) bool {
+	switch tok {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "MERGE":
+		return true
+	}
+	return false
+}
+
+func finalStatementKind(tok string) StatementKind {
+	if tok == "SELECT" {
+		return Select
+	}
+	return DML
+}
+
+// SplitStatements 将可能包含多条由 `;` 分隔语句的文本切分为独立语句，
+// 正确跳过字符串字面量（含 E'...' 转义串）、带引号标识符、美元引用（$tag$...$tag$）
+// 以及单行/嵌套块注释内部的分号。空语句（仅空白或注释）会被丢弃。
+func SplitStatements(sql string) []string {
+	runes := []rune(sql)
+	n := len(runes)
+	var stmts []string
+	start := 0
+	depth := 0
+
+	for i := 0; i < n; {
+		switch {
+		case runes[i] == '-' && i+1 < n && runes[i+1] == '-':
+			i += 2
+			for i < n && runes[i] != '\n' {
+				i++
+			}
+		case runes[i] == '/' && i+1 < n && runes[i+1] == '*':
+			i = skipBlockComment(runes, i)
+		case runes[i] == '\'':
+			i = skipQuoted(runes, i, '\'', isEStringPrefix(runes, i))
+		case runes[i] == '"':
+			i = skipQuoted(runes, i, '"', false)
+		case runes[i] == '$':
+			if end, ok := skipDollarQuoted(runes, i); ok {
+				i = end
+				continue
+			}
+			i++
+		case runes[i] == '(':
+			depth++
+			i++
+		case runes[i] == ')':
+			if depth > 0 {
+				depth--
+			}
+			i++
+		case runes[i] == ';' && depth == 0:
+			if s := strings.TrimSpace(string(runes[start:i])); s != "" {
+				stmts = append(stmts, s)
+			}
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// tokenize 将一条语句切分为大写关键词/标识符及 "(" "," 两个结构性符号，
+// 跳过注释与字符串/引用内容，用于 Classify 的关键词扫描
+func tokenize(stmt string) []string {
+	runes := []rune(stmt)
+	n := len(runes)
+	var tokens []string
+
+	for i := 0; i < n; {
+		r := runes[i]
+		switch {
+		case r == '-' && i+1 < n && runes[i+1] == '-':
+			i += 2
+			for i < n && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < n && runes[i+1] == '*':
+			i = skipBlockComment(runes, i)
+		case r == '\'':
+			i = skipQuoted(runes, i, '\'', isEStringPrefix(runes, i))
+		case r == '"':
+			i = skipQuoted(runes, i, '"', false)
+		case r == '$':
+			if end, ok := skipDollarQuoted(runes, i); ok {
+				i = end
+				continue
+			}
+			i++
+		case r == '(' || r == ',':
+			tokens = append(tokens, string(r))
+			i++
+		case isIdentRune(r):
+			j := i
+			for j < n && isIdentRune(runes[j]) {
+				j++
+			}
+			tokens = append(tokens, strings.ToUpper(string(runes[i:j])))
+			i = j
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' ||
+		(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// isEStringPrefix 判断 pos 处的单引号是否是 E'...' / e'...' 形式的转义字符串，
+// 这类字符串允许反斜杠转义，与默认的 '' 转义规则不同
+func isEStringPrefix(runes []rune, pos int) bool {
+	if pos == 0 {
+		return false
+	}
+	prev := runes[pos-1]
+	if prev != 'E' && prev != 'e' {
+		return false
+	}
+	if pos >= 2 && isIdentRune(runes[pos-2]) {
+		return false // 前面还有字母/数字/下划线，说明 E 是某个更长标识符的一部分
+	}
+	return true
+}
+
+// skipQuoted 跳过一个以 quote 开头的引用/字符串字面量，返回结束引号之后的位置。
+// allowBackslashEscape 为 true 时按 E'...' 规则处理反斜杠转义，否则按标准 SQL 用双写引号转义。
+func skipQuoted(runes []rune, start int, quote rune, allowBackslashEscape bool) int {
+	n := len(runes)
+	i := start + 1
+	for i < n {
+		if allowBackslashEscape && runes[i] == '\\' && i+1 < n {
+			i += 2
+			continue
+		}
+		if runes[i] == quote {
+			if i+1 < n && runes[i+1] == quote {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return n
+}
+
+// skipBlockComment 跳过从 start 处开始的 /* ... */ 块注释，支持嵌套
+func skipBlockComment(runes []rune, start int) int {
+	n := len(runes)
+	depth := 1
+	i := start + 2
+	for i < n && depth > 0 {
+		switch {
+		case runes[i] == '/' && i+1 < n && runes[i+1] == '*':
+			depth++
+			i += 2
+		case runes[i] == '*' && i+1 < n && runes[i+1] == '/':
+			depth--
+			i += 2
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// skipDollarQuoted 尝试把 start 处开始的内容解析为美元引用字符串 $tag$...$tag$（tag 可为空），
+// 成功时返回结束定界符之后的位置；不是合法美元引用起始时返回 ok=false
+func skipDollarQuoted(runes []rune, start int) (int, bool) {
+	n := len(runes)
+	i := start + 1
+	tagStart := i
+	for i < n && isIdentRune(runes[i]) {
+		i++
+	}
+	if i >= n || runes[i] != '$' {
+		return 0, false
+	}
+	tag := string(runes[tagStart:i])
+	delim := "$" + tag + "$"
+	delimRunes := []rune(delim)
+	bodyStart := i + 1
+
+	for j := bodyStart; j+len(delimRunes) <= n; j++ {
+		if matchesAt(runes, j, delimRunes) {
+			return j + len(delimRunes), true
+		}
+	}
+	return n, true
+}
+
+func matchesAt(runes []rune, pos int, target []rune) bool {
+	for k, r := range target {
+		if runes[pos+k] != r {
+			return false
+		}
+	}
+	return true
+}
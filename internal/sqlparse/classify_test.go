@@ -0,0 +1,102 @@
+package sqlparse
+
+import "testing"
+
+// TestClassify 覆盖各类语句关键词，以及 WITH 前缀下的 Select/DML 歧义场景
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want StatementKind
+	}{
+		{"select", "SELECT * FROM t", Select},
+		{"select_lowercase", "select 1", Select},
+		{"show", "SHOW TABLES", Show},
+		{"describe", "DESCRIBE t", Show},
+		{"explain", "EXPLAIN SELECT 1", Explain},
+		{"insert", "INSERT INTO t VALUES (1)", DML},
+		{"update", "UPDATE t SET a = 1", DML},
+		{"delete", "DELETE FROM t", DML},
+		{"create", "CREATE TABLE t (id INT)", DDL},
+		{"drop", "DROP TABLE t", DDL},
+		{"begin", "BEGIN", TCL},
+		{"commit", "COMMIT", TCL},
+		{"set", "SET search_path = public", SessionControl},
+		{"set_role", "SET ROLE sandbox_role", SessionControl},
+		{"reset", "RESET ROLE", SessionControl},
+		{"reset_all", "RESET ALL", SessionControl},
+		{"with_select", "WITH x AS (SELECT 1) SELECT * FROM x", Select},
+		{"with_delete", "WITH x AS (SELECT id FROM t) DELETE FROM t WHERE id IN (SELECT id FROM x)", DML},
+		{"with_update", "WITH x AS (SELECT 1) UPDATE t SET a = 1", DML},
+		{"with_recursive_select", "WITH RECURSIVE x AS (SELECT 1) SELECT * FROM x", Select},
+		{"leading_comment", "-- pick rows\nSELECT * FROM t", Select},
+		{"block_comment_prefix", "/* note */ SELECT 1", Select},
+		{"empty", "   ", Unknown},
+		{"only_comment", "-- nothing here", Unknown},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Classify(tc.sql); got != tc.want {
+				t.Errorf("Classify(%q) = %s, want %s", tc.sql, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestSplitStatements 验证分号切分在各类引用/注释场景下的正确性
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			name: "simple",
+			sql:  "SELECT 1; SELECT 2",
+			want: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name: "semicolon_in_string",
+			sql:  "INSERT INTO t VALUES ('a;b'); SELECT 1",
+			want: []string{"INSERT INTO t VALUES ('a;b')", "SELECT 1"},
+		},
+		{
+			name: "semicolon_in_e_string",
+			sql:  `SELECT E'a;\'b'; SELECT 2`,
+			want: []string{`SELECT E'a;\'b'`, "SELECT 2"},
+		},
+		{
+			name: "semicolon_in_dollar_quote",
+			sql:  "CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql; SELECT 1",
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql",
+				"SELECT 1",
+			},
+		},
+		{
+			name: "semicolon_in_comment",
+			sql:  "SELECT 1; -- trailing; comment\nSELECT 2",
+			want: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name: "trailing_semicolon",
+			sql:  "SELECT 1;",
+			want: []string{"SELECT 1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SplitStatements(tc.sql)
+			if len(got) != len(tc.want) {
+				t.Fatalf("SplitStatements(%q) = %v, want %v", tc.sql, got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("statement %d = %q, want %q", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
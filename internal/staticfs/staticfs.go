@@ -0,0 +1,148 @@
+// Package staticfs 为前端静态资源目录（磁盘上的 dist/ 或嵌入的 embed.FS）建立一次性索引，
+// 取代 server.Run 里散落的"ReadFile+手写Content-Type+手写Cache-Control"重复逻辑。
+// 索引内容包括：按文件内容计算的 SHA-256 前缀 ETag，以及同名的 .br/.gz 预压缩兄弟文件（若存在），
+// 使响应可以按 If-None-Match 返回 304、按 Accept-Encoding 协商直接发送预压缩字节。
+package staticfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Asset 是索引中单个文件的只读快照
+type Asset struct {
+	ContentType string
+	ETag        string // 带引号的弱校验ETag，如 `"a1b2c3d4e5f6a7b8"`
+	Raw         []byte
+	Brotli      []byte // 非nil表示存在同名 .br 预压缩变体
+	Gzip        []byte // 非nil表示存在同名 .gz 预压缩变体
+}
+
+// Set 是对一棵静态资源目录建立索引后的只读视图
+type Set struct {
+	assets map[string]*Asset // key: 相对路径，不含前导'/'，如 "assets/index-abc123.js"
+}
+
+// NewSet 遍历 fsys 下所有常规文件为其建立索引；.br/.gz 文件本身不单独建立索引条目，
+// 而是作为其原文件的预压缩变体被加载。fsys 通常是 os.DirFS("dist") 或嵌入FS的 dist 子树
+func NewSet(fsys fs.FS) (*Set, error) {
+	assets := make(map[string]*Asset)
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(path, ".br") || strings.HasSuffix(path, ".gz") {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		asset := &Asset{
+			ContentType: contentTypeFor(path),
+			ETag:        etagFor(data),
+			Raw:         data,
+		}
+		if br, err := fs.ReadFile(fsys, path+".br"); err == nil {
+			asset.Brotli = br
+		}
+		if gz, err := fs.ReadFile(fsys, path+".gz"); err == nil {
+			asset.Gzip = gz
+		}
+		assets[path] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Set{assets: assets}, nil
+}
+
+// etagFor 取内容SHA-256摘要的前16个十六进制字符作为弱校验ETag，足够区分不同构建产物，
+// 无需为节省几字节而纠结碰撞概率
+func etagFor(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:])[:16] + `"`
+}
+
+// Serve 查找 path 对应的资产并写入响应：命中 If-None-Match 时返回304；否则按 Accept-Encoding
+// 协商后优先发送Brotli、其次Gzip预压缩变体，均不可用时发送原文。noCache为true时（用于index.html，
+// 确保SPA在新版本发布后不会继续使用旧的入口文件）设置 Cache-Control: no-cache，
+// 否则设置长期不可变缓存——ETag已经保证了内容变化时URL不变但校验值会变。
+// 返回值表示是否找到并处理了该资产；未找到时不会写入任何响应，调用方应继续尝试下一个数据源或404
+func (s *Set) Serve(c *gin.Context, path string, noCache bool) bool {
+	asset, ok := s.assets[strings.TrimPrefix(path, "/")]
+	if !ok {
+		return false
+	}
+
+	c.Header("ETag", asset.ETag)
+	c.Header("Vary", "Accept-Encoding")
+	if noCache {
+		c.Header("Cache-Control", "no-cache")
+	} else {
+		c.Header("Cache-Control", "public, max-age=31536000")
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" && match == asset.ETag {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+
+	acceptEncoding := c.GetHeader("Accept-Encoding")
+	switch {
+	case asset.Brotli != nil && acceptsEncoding(acceptEncoding, "br"):
+		c.Header("Content-Encoding", "br")
+		c.Data(http.StatusOK, asset.ContentType, asset.Brotli)
+	case asset.Gzip != nil && acceptsEncoding(acceptEncoding, "gzip"):
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, asset.ContentType, asset.Gzip)
+	default:
+		c.Data(http.StatusOK, asset.ContentType, asset.Raw)
+	}
+	return true
+}
+
+// acceptsEncoding 判断逗号分隔的 Accept-Encoding 头中是否包含 enc这个token（忽略;q=权重）
+func acceptsEncoding(header, enc string) bool {
+	for _, tok := range strings.Split(header, ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(tok), ";")
+		if strings.EqualFold(name, enc) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentTypeFor 根据文件扩展名返回对应的Content-Type，取代原先 server.go 里的 getContentType
+func contentTypeFor(p string) string {
+	switch {
+	case strings.HasSuffix(p, ".html"):
+		return "text/html; charset=utf-8"
+	case strings.HasSuffix(p, ".js"):
+		return "application/javascript"
+	case strings.HasSuffix(p, ".css"):
+		return "text/css"
+	case strings.HasSuffix(p, ".svg"):
+		return "image/svg+xml"
+	case strings.HasSuffix(p, ".png"):
+		return "image/png"
+	case strings.HasSuffix(p, ".jpg"), strings.HasSuffix(p, ".jpeg"):
+		return "image/jpeg"
+	case strings.HasSuffix(p, ".gif"):
+		return "image/gif"
+	case strings.HasSuffix(p, ".ico"):
+		return "image/x-icon"
+	case strings.HasSuffix(p, ".woff"), strings.HasSuffix(p, ".woff2"):
+		return "font/woff"
+	case strings.HasSuffix(p, ".ttf"):
+		return "font/ttf"
+	default:
+		return "text/plain"
+	}
+}
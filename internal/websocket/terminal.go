@@ -2,17 +2,17 @@ package websocket
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
-	"os/exec"
 	"sync"
 	"time"
 
 	"kwdb-playground/internal/docker"
 	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/recording"
+	"kwdb-playground/internal/runtime"
 
-	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
 )
 
@@ -40,24 +40,48 @@ type Message struct {
 // 使用docker包中的ImagePullProgressMessage类型
 type ImagePullProgressMessage = docker.ImagePullProgress
 
+// ConnRole 描述一条WebSocket连接在共享终端会话中的角色
+type ConnRole string
+
+const (
+	// RoleDriver 可读可写：input/resize会被实际转发给底层exec，一个会话可以有多个driver（如TA协助学生）
+	RoleDriver ConnRole = "driver"
+	// RoleFollower 只读：会收到与driver完全相同的output/image_pull_progress广播，但input/resize会被静默丢弃
+	RoleFollower ConnRole = "follower"
+)
+
+// sessionConn 一条已挂载到共享会话上的WebSocket连接
+type sessionConn struct {
+	conn *websocket.Conn
+	role ConnRole
+	done chan struct{} // 该连接自身的读循环退出时关闭，供调用方感知"这一条连接"（而非整个会话）已经结束
+}
+
 // TerminalSession 终端会话
+// 多个WebSocket连接可以共享同一个会话（同一个底层exec）：教师以driver身份操作，
+// 学生以follower身份围观；反过来TA也可以以driver身份加入一个学生的会话帮忙排障
 type TerminalSession struct {
 	sessionID   string
 	containerID string
-	conn        *websocket.Conn
-	cmd         *exec.Cmd
-	pty         *os.File
+	courseID    string // 所属课程ID，为空表示调用方未提供（录制文件会落在课程无关目录下）
+	connsMu     sync.Mutex
+	conns       []*sessionConn                // 当前挂载在本会话上的所有连接，driver/follower混合
+	started     bool                          // Start*Session调用后置true；此后attach的新连接会立即拿到自己的读循环
+	runtimeCtrl runtime.Controller            // 实际驱动exec的运行时后端（docker或containerd），nil表示未配置
+	exec        *docker.InteractiveExecResult // StartInteractiveSession成功后持有的交互式exec连接
 	ctx         context.Context
 	cancel      context.CancelFunc
-	logger      *logger.Logger // 日志记录器实例
-	sendCh      chan Message   // 发送消息的通道，确保并发安全
+	logger      *logger.Logger      // 日志记录器实例
+	sendCh      chan Message        // 发送消息的通道，确保并发安全
+	recorder    *recording.Recorder // 非nil时，所有输出帧与尺寸调整都会额外录制为 asciinema v2 事件
 }
 
 // TerminalManager 终端管理器
 type TerminalManager struct {
-	sessions map[string]*TerminalSession
-	mu       sync.RWMutex
-	logger   *logger.Logger // 日志记录器实例
+	sessions    map[string]*TerminalSession
+	mu          sync.RWMutex
+	logger      *logger.Logger     // 日志记录器实例
+	runtimeCtrl runtime.Controller // WebSocket终端实际驱动exec的运行时后端，由SetRuntimeController配置
 }
 
 // NewTerminalManager 创建终端管理器
@@ -74,57 +98,129 @@ func (tm *TerminalManager) SetLogger(loggerInstance *logger.Logger) {
 	tm.logger = loggerInstance
 }
 
-// CreateSession 创建新的终端会话
-func (tm *TerminalManager) CreateSession(sessionID, containerID string, conn *websocket.Conn) *TerminalSession {
+// SetRuntimeController 配置WebSocket终端实际驱动exec的运行时后端（docker或containerd）。
+// 留空（nil）时 StartInteractiveSession 会直接报错，不再回退到shell出 "docker exec" 的旧行为
+func (tm *TerminalManager) SetRuntimeController(ctrl runtime.Controller) {
+	tm.runtimeCtrl = ctrl
+}
+
+// CreateSession 按sessionID创建或加入终端会话：若该sessionID已存在会话（多用户共享场景），
+// 直接把这条新连接以给定角色挂载上去并返回既有会话，而不是关闭旧会话另起一个——
+// 这样教师刷新页面重连、或学生/TA加入同一个sessionID时，大家看到的是同一个底层exec。
+// 返回值的第二个bool表示会话是否为本次调用新建——调用方应仅在新建时调用
+// StartInteractiveSession/StartProgressSession与EnableRecording，加入已有会话时这些
+// 都已经跑起来了
+// courseID 为空表示调用方（如进度模式）未关联具体课程
+func (tm *TerminalManager) CreateSession(sessionID, containerID, courseID string, conn *websocket.Conn, role ConnRole) (*TerminalSession, bool) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	// 清理已存在的会话
-	if old, exists := tm.sessions[sessionID]; exists {
-		old.Close()
-		delete(tm.sessions, sessionID)
+	if existing, ok := tm.sessions[sessionID]; ok {
+		existing.attach(conn, role)
+		return existing, false
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	session := &TerminalSession{
 		sessionID:   sessionID,
 		containerID: containerID,
-		conn:        conn,
+		courseID:    courseID,
+		runtimeCtrl: tm.runtimeCtrl,
 		ctx:         ctx,
 		cancel:      cancel,
 		logger:      tm.logger, // 传递logger实例
 		sendCh:      make(chan Message, 256),
 	}
+	session.attach(conn, role)
 
 	tm.sessions[sessionID] = session
-	return session
+	return session, true
 }
 
-// StartInteractiveSession 启动交互式终端会话 - 核心功能：docker exec -it /bin/bash
-func (ts *TerminalSession) StartInteractiveSession() error {
-	// 优先尝试使用 /bin/bash，不存在时回退到 /bin/sh，提升不同基础镜像的兼容性
-	tryStart := func(shell string) (*exec.Cmd, *os.File, error) {
-		cmd := exec.CommandContext(ts.ctx, "docker", "exec", "-it", ts.containerID, shell)
-		ptyFile, err := pty.Start(cmd)
-		if err != nil {
-			return nil, nil, fmt.Errorf("启动伪终端失败(%s): %v", shell, err)
+// attach 把一条WebSocket连接以给定角色挂载到会话上。会话尚未Start*Session过时只是单纯登记
+// （此时也没有exec/writePump可供这条连接交互），真正启动读循环要等markStarted；会话已经在跑
+// 的情况下（加入者），这里会立即为它启动独立的输入读循环
+func (ts *TerminalSession) attach(conn *websocket.Conn, role ConnRole) *sessionConn {
+	sc := &sessionConn{conn: conn, role: role, done: make(chan struct{})}
+
+	ts.connsMu.Lock()
+	ts.conns = append(ts.conns, sc)
+	started := ts.started
+	ts.connsMu.Unlock()
+
+	if started {
+		go ts.handleWebSocketInput(sc)
+	}
+	return sc
+}
+
+// markStarted 把会话标记为已启动，并返回此刻已挂载的连接快照，供Start*Session为它们逐一
+// 启动读循环；此后再attach的连接会在attach内直接启动，不需要再经过这里
+func (ts *TerminalSession) markStarted() []*sessionConn {
+	ts.connsMu.Lock()
+	defer ts.connsMu.Unlock()
+	ts.started = true
+	return append([]*sessionConn(nil), ts.conns...)
+}
+
+// detach 把一条连接从会话上摘除，返回摘除后本会话是否已不再有任何连接（driver/follower皆无）
+func (ts *TerminalSession) detach(conn *websocket.Conn) bool {
+	ts.connsMu.Lock()
+	defer ts.connsMu.Unlock()
+
+	remaining := ts.conns[:0]
+	for _, sc := range ts.conns {
+		if sc.conn != conn {
+			remaining = append(remaining, sc)
 		}
-		return cmd, ptyFile, nil
+	}
+	ts.conns = remaining
+	return len(ts.conns) == 0
+}
+
+// recordingEnv 写入 .cast 文件头的环境信息，与 StartInteractiveSession 实际使用的Shell保持一致
+var recordingEnv = map[string]string{"SHELL": "/bin/bash", "TERM": "xterm-256color"}
+
+// EnableRecording 为会话开启 asciinema v2 录制，写入路径由 store 按 courseID/containerID/sessionID 确定
+// progressOnly 为 true 时录制镜像拉取进度行（配合 StartProgressSession），否则录制终端输出与尺寸调整
+// maxSizeBytes 是单个录制文件的大小上限（通常来自课程的 Backend.Recording.MaxSizeBytes 配置），
+// 小于等于0时使用recording包内置默认值
+// 必须在 Start*Session 之前调用，以确保录制从第一帧开始
+func (ts *TerminalSession) EnableRecording(store *recording.Store, progressOnly bool, maxSizeBytes int64) error {
+	path := store.Path(ts.courseID, ts.containerID, ts.sessionID, progressOnly)
+	rec, err := recording.New(path, 0, 0, recordingEnv, maxSizeBytes)
+	if err != nil {
+		return fmt.Errorf("开启终端会话录制失败: %w", err)
+	}
+	ts.recorder = rec
+	return nil
+}
+
+// shellProbeDelay 创建Shell exec后到探测其ExecInspect退出状态之间的等待时长：command not found
+// 这类错误在TTY attach阶段通常不会报错，而是体现为进程几乎立即以非0码退出，需要等一小会儿
+// 才能通过InspectExec观察到，等太久会让回退 /bin/bash -> /bin/sh 的用户感知延迟变得明显
+const shellProbeDelay = 150 * time.Millisecond
+
+// StartInteractiveSession 启动交互式终端会话：通过runtimeCtrl在容器内创建一次TTY exec，
+// docker/containerd两种后端殊途同归地落到同一个 runtime.Controller.CreateInteractiveExec，
+// 终端会话本身不再关心底层究竟是哪种运行时
+func (ts *TerminalSession) StartInteractiveSession() error {
+	if ts.runtimeCtrl == nil {
+		return fmt.Errorf("未配置容器运行时后端，无法启动交互式终端")
 	}
 
-	cmd, ptyFile, err := tryStart("/bin/bash")
+	// 优先尝试使用 /bin/bash，不存在时回退到 /bin/sh，提升不同基础镜像的兼容性
+	execResult, err := ts.createShellExec("/bin/bash")
 	if err != nil {
-		// 记录日志并尝试回退到 /bin/sh
 		ts.logger.Warn("/bin/bash 不可用，尝试使用 /bin/sh，容器: %s，错误: %v", ts.containerID, err)
-		cmd, ptyFile, err = tryStart("/bin/sh")
+		execResult, err = ts.createShellExec("/bin/sh")
 		if err != nil {
 			// 两种Shell均失败，返回错误，让上层进行错误处理与反馈
 			return fmt.Errorf("启动交互式终端失败(无可用Shell): %v", err)
 		}
 	}
 
-	ts.cmd = cmd
-	ts.pty = ptyFile
+	ts.exec = execResult
 
 	// 启动写入泵（Write Pump）处理所有出站消息
 	go ts.writePump()
@@ -136,20 +232,41 @@ func (ts *TerminalSession) StartInteractiveSession() error {
 	})
 	ts.logger.Debug("终端会话已启动，会话ID: %s", ts.sessionID)
 
-	// 启动双向通信处理
-	go ts.handleWebSocketInput() // 处理前端输入
-	go ts.handleTerminalOutput() // 处理终端输出
-	go ts.waitForTerminalExit()  // 等待终端退出
+	// 为此刻已挂载的连接（通常只有发起者这一条）启动输入读循环；此后再加入的连接由attach直接启动
+	for _, sc := range ts.markStarted() {
+		go ts.handleWebSocketInput(sc)
+	}
+	go ts.handleTerminalOutput() // 处理终端输出，并在exec退出时触发Close
 
 	return nil
 }
 
+// createShellExec 创建一次Shell的TTY交互式exec，并在短暂等待后通过InspectExec确认它真的启动
+// 成功而不是刚执行就以非0码退出——TTY attach本身在Shell不存在时通常也会返回成功，"exec format
+// error"/"not found"这类问题只有这样才能在不阻塞到命令结束的前提下尽早探测到
+func (ts *TerminalSession) createShellExec(shell string) (*docker.InteractiveExecResult, error) {
+	execResult, err := ts.runtimeCtrl.CreateInteractiveExec(ts.ctx, ts.containerID, []string{shell})
+	if err != nil {
+		return nil, err
+	}
+
+	time.Sleep(shellProbeDelay)
+	if running, exitCode, inspectErr := ts.runtimeCtrl.InspectExec(ts.ctx, execResult.ExecID); inspectErr == nil && !running && exitCode != 0 {
+		execResult.Conn.Close()
+		return nil, fmt.Errorf("%s 启动后立即以退出码 %d 结束", shell, exitCode)
+	}
+
+	return execResult, nil
+}
+
 // StartProgressSession 启动仅进度模式会话
 func (ts *TerminalSession) StartProgressSession() {
 	// 启动写入泵（Write Pump）处理所有出站消息
 	go ts.writePump()
-	// 启动输入处理（主要用于处理Ping/Pong和关闭帧）
-	go ts.handleWebSocketInput()
+	// 为此刻已挂载的连接启动输入读循环（主要用于处理Ping/Pong和关闭帧）；此后加入的连接由attach直接启动
+	for _, sc := range ts.markStarted() {
+		go ts.handleWebSocketInput(sc)
+	}
 
 	ts.Send(Message{
 		Type: "connected",
@@ -159,41 +276,56 @@ func (ts *TerminalSession) StartProgressSession() {
 
 // Send 发送消息到WebSocket连接（线程安全）
 func (ts *TerminalSession) Send(msg Message) {
+	ts.recordMessage(msg)
 	select {
 	case ts.sendCh <- msg:
 	case <-ts.ctx.Done():
 	}
 }
 
-// writePump 将消息从Hub发送到WebSocket连接
-// 确保每个连接只有一个并发写入器
+// recordMessage 若会话已开启录制，将输出类消息旁路写入 .cast 文件
+// 终端输出与镜像拉取进度行都记为 asciinema 的 "o" 事件；录制失败不影响终端会话本身，因此只忽略错误
+func (ts *TerminalSession) recordMessage(msg Message) {
+	if ts.recorder == nil {
+		return
+	}
+	switch msg.Type {
+	case "output":
+		if data, ok := msg.Data.(string); ok {
+			_ = ts.recorder.WriteOutput(data)
+		}
+	case "image_pull_progress":
+		if line, err := json.Marshal(msg.Data); err == nil {
+			_ = ts.recorder.WriteOutput(string(line))
+		}
+	}
+}
+
+// writePump 将消息从Hub广播到所有已挂载的WebSocket连接（driver与follower一视同仁）
+// 确保每条连接只有一个并发写入器；某条连接写失败视为它已经断开，就地关闭并从conns中摘除，
+// 但不影响会话上其余连接继续工作
 func (ts *TerminalSession) writePump() {
 	ticker := time.NewTicker(pingPeriod)
-	defer func() {
-		ticker.Stop()
-		ts.conn.Close()
-	}()
+	defer ticker.Stop()
 
 	for {
 		select {
 		case msg, ok := <-ts.sendCh:
-			ts.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// 通道已关闭
-				ts.conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			if err := ts.conn.WriteJSON(msg); err != nil {
-				ts.logger.Error("写入WebSocket失败: %v", err)
+				ts.broadcast(func(c *websocket.Conn) error {
+					return c.WriteMessage(websocket.CloseMessage, []byte{})
+				})
 				return
 			}
+			ts.broadcast(func(c *websocket.Conn) error {
+				return c.WriteJSON(msg)
+			})
 
 		case <-ticker.C:
-			ts.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if err := ts.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
-				return
-			}
+			ts.broadcast(func(c *websocket.Conn) error {
+				return c.WriteMessage(websocket.PingMessage, nil)
+			})
 
 		case <-ts.ctx.Done():
 			return
@@ -201,16 +333,36 @@ func (ts *TerminalSession) writePump() {
 	}
 }
 
-// handleWebSocketInput 处理来自前端的输入
-func (ts *TerminalSession) handleWebSocketInput() {
-	defer func() {
-		ts.Close()
-	}()
+// broadcast 把一次写操作应用到所有已挂载连接上；写失败的连接会被关闭并摘除，
+// 其自身的读循环随后会因ReadJSON出错而退出（handleWebSocketInput负责关闭done）
+func (ts *TerminalSession) broadcast(write func(*websocket.Conn) error) {
+	ts.connsMu.Lock()
+	defer ts.connsMu.Unlock()
+
+	remaining := ts.conns[:0]
+	for _, sc := range ts.conns {
+		sc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := write(sc.conn); err != nil {
+			ts.logger.Warn("写入WebSocket失败(角色:%s): %v", sc.role, err)
+			sc.conn.Close()
+			continue
+		}
+		remaining = append(remaining, sc)
+	}
+	ts.conns = remaining
+}
 
-	ts.conn.SetReadLimit(maxMessageSize)
-	ts.conn.SetReadDeadline(time.Now().Add(pongWait))
-	ts.conn.SetPongHandler(func(string) error {
-		ts.conn.SetReadDeadline(time.Now().Add(pongWait))
+// handleWebSocketInput 处理某一条已挂载连接的输入。follower角色只读：resize/input会被
+// 静默丢弃，其余（ping等）与driver一致。这条连接自己的读循环退出只意味着它自己离开了会话，
+// 并不会结束整个会话——是否需要结束由调用方（DetachConnection）根据是否还有连接留存来决定
+func (ts *TerminalSession) handleWebSocketInput(sc *sessionConn) {
+	defer close(sc.done)
+
+	conn := sc.conn
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
 		return nil
 	})
 
@@ -221,15 +373,15 @@ func (ts *TerminalSession) handleWebSocketInput() {
 		default:
 			var msg Message
 			// ReadJSON 会阻塞直到有消息
-			if err := ts.conn.ReadJSON(&msg); err != nil {
+			if err := conn.ReadJSON(&msg); err != nil {
 				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					ts.logger.Error("WebSocket读取错误: %v", err)
+					ts.logger.Error("WebSocket读取错误(角色:%s): %v", sc.role, err)
 				}
 				return
 			}
 
 			// 收到任何消息都重置读取截止时间，确保连接活跃
-			ts.conn.SetReadDeadline(time.Now().Add(pongWait))
+			conn.SetReadDeadline(time.Now().Add(pongWait))
 
 			// 处理前端发送的心跳
 			if msg.Type == "ping" {
@@ -237,17 +389,21 @@ func (ts *TerminalSession) handleWebSocketInput() {
 				continue
 			}
 
+			if sc.role == RoleFollower && (msg.Type == "resize" || msg.Type == "input") {
+				// follower只读，任何会改变终端状态的消息都直接丢弃
+				continue
+			}
+
 			// 处理终端大小调整
-			if msg.Type == "resize" && ts.pty != nil {
+			if msg.Type == "resize" && ts.exec != nil {
 				if dataMap, ok := msg.Data.(map[string]interface{}); ok {
 					cols, ok1 := dataMap["cols"].(float64)
 					rows, ok2 := dataMap["rows"].(float64)
 					if ok1 && ok2 {
-						if err := pty.Setsize(ts.pty, &pty.Winsize{
-							Rows: uint16(rows),
-							Cols: uint16(cols),
-						}); err != nil {
+						if err := ts.runtimeCtrl.ResizeTerminal(ts.ctx, ts.exec.ExecID, uint(rows), uint(cols)); err != nil {
 							ts.logger.Warn("调整终端大小失败: %v", err)
+						} else if ts.recorder != nil {
+							_ = ts.recorder.WriteResize(int(cols), int(rows))
 						}
 					}
 				}
@@ -255,10 +411,10 @@ func (ts *TerminalSession) handleWebSocketInput() {
 			}
 
 			// 只处理输入类型的消息
-			if msg.Type == "input" && ts.pty != nil {
+			if msg.Type == "input" && ts.exec != nil {
 				// 使用类型断言将interface{}转换为string，然后转换为[]byte
 				if dataStr, ok := msg.Data.(string); ok {
-					_, err := ts.pty.Write([]byte(dataStr))
+					_, err := ts.exec.Conn.Write([]byte(dataStr))
 					if err != nil {
 						ts.logger.Warn("写入终端失败: %v", err)
 						return
@@ -271,19 +427,21 @@ func (ts *TerminalSession) handleWebSocketInput() {
 	}
 }
 
-// handleTerminalOutput 处理终端输出并发送到前端
+// handleTerminalOutput 处理终端输出并发送到前端；exec连接读到EOF（容器内shell退出）或
+// 出现读错误都意味着会话已经结束，由这里统一触发Close，不再需要单独的exit-wait协程
 func (ts *TerminalSession) handleTerminalOutput() {
+	defer ts.Close()
+
 	buf := make([]byte, 1024)
 	for {
 		select {
 		case <-ts.ctx.Done():
 			return
 		default:
-			n, err := ts.pty.Read(buf)
+			n, err := ts.exec.Reader.Read(buf)
 			if err != nil {
 				if err != io.EOF {
 					// 只有非EOF错误才记录为Error，EOF通常意味着shell退出了
-					// 某些情况下 pty 关闭也会导致 read error
 					ts.logger.Debug("读取终端输出结束: %v", err)
 				}
 				return
@@ -299,22 +457,7 @@ func (ts *TerminalSession) handleTerminalOutput() {
 	}
 }
 
-// waitForTerminalExit 等待终端命令退出
-func (ts *TerminalSession) waitForTerminalExit() {
-	if ts.cmd != nil {
-		err := ts.cmd.Wait()
-		if err != nil {
-			ts.logger.Debug("终端命令退出: %v", err)
-			ts.Send(Message{
-				Type: "error",
-				Data: fmt.Sprintf("终端会话结束: %v", err),
-			})
-		}
-	}
-	ts.Close()
-}
-
-// Close 关闭终端会话
+// Close 关闭终端会话（所有挂载在其上的driver/follower连接都会被一并断开）
 func (ts *TerminalSession) Close() {
 	// 使用Once确保只关闭一次，避免panic
 	if ts.ctx.Err() != nil {
@@ -323,22 +466,50 @@ func (ts *TerminalSession) Close() {
 
 	ts.cancel() // 取消上下文，这将停止所有goroutine
 
-	if ts.pty != nil {
-		ts.pty.Close()
+	if ts.exec != nil {
+		ts.exec.Conn.Close()
 	}
 
-	// 注意：不要在这里关闭 conn，writePump 会在 ctx.Done() 时关闭它
-	// 或者 writePump 会检测到 sendCh 关闭
-	// 实际上，最好的方式是关闭 sendCh，让 writePump 退出并关闭 conn
-	// 但这里我们用 context 控制，writePump 监听 ctx.Done()
+	if ts.recorder != nil {
+		ts.recorder.Close()
+	}
+
+	ts.connsMu.Lock()
+	for _, sc := range ts.conns {
+		// 关闭前尽量发送一次正常关闭帧，让前端区分"会话正常结束"与"连接异常断开"；
+		// 写超时很短，对方已经掉线时不阻塞整体关闭流程
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "会话已结束")
+		sc.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		sc.conn.Close()
+	}
+	ts.conns = nil
+	ts.connsMu.Unlock()
 }
 
-// Done 返回会话结束信号
+// Done 返回会话结束信号（整个会话结束，而非某一条连接离开）
 func (ts *TerminalSession) Done() <-chan struct{} {
 	return ts.ctx.Done()
 }
 
-// RemoveSession 从管理器中移除会话
+// ConnDone 返回某条已挂载连接自身的结束信号：它的读循环退出（对端断开、写失败等）时关闭。
+// 与Done()的区别是不要求整个会话都结束——调用方（如handleTerminalWebSocket）应同时等待
+// ConnDone(conn)和Done()，任意一个触发都意味着这个HTTP handler可以返回了。
+// 找不到对应连接（例如从未挂载成功，或已经被摘除）时返回一个已关闭的通道
+func (ts *TerminalSession) ConnDone(conn *websocket.Conn) <-chan struct{} {
+	ts.connsMu.Lock()
+	defer ts.connsMu.Unlock()
+
+	for _, sc := range ts.conns {
+		if sc.conn == conn {
+			return sc.done
+		}
+	}
+	closed := make(chan struct{})
+	close(closed)
+	return closed
+}
+
+// RemoveSession 从管理器中整体移除并关闭会话，挂载在其上的所有连接（driver与follower）都会被断开
 func (tm *TerminalManager) RemoveSession(sessionID string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
@@ -348,6 +519,27 @@ func (tm *TerminalManager) RemoveSession(sessionID string) {
 	}
 }
 
+// DetachConnection 把一条连接从共享会话上正常摘除；这是连接断开时的收尾动作，
+// 不等价于整体终止会话——只有当摘除后这个会话已经不再有任何连接（既无driver也无follower）时，
+// 才会真正关闭底层exec并从管理器中删除该会话，避免一个学生断开就打断其他人正在围观的会话
+func (tm *TerminalManager) DetachConnection(sessionID string, conn *websocket.Conn) {
+	tm.mu.Lock()
+	session, exists := tm.sessions[sessionID]
+	if !exists {
+		tm.mu.Unlock()
+		return
+	}
+	empty := session.detach(conn)
+	if empty {
+		delete(tm.sessions, sessionID)
+	}
+	tm.mu.Unlock()
+
+	if empty {
+		session.Close()
+	}
+}
+
 // BroadcastImagePullProgress 向所有活跃的WebSocket连接广播镜像拉取进度
 func (tm *TerminalManager) BroadcastImagePullProgress(progress ImagePullProgressMessage) {
 	tm.mu.RLock()
@@ -371,10 +563,106 @@ func (tm *TerminalManager) BroadcastImagePullProgress(progress ImagePullProgress
 		// 使用 Send 方法，它是并发安全的
 		session.Send(msg)
 	}
-	// 不需要手动清理断开的会话，writePump和handleInput会自动处理并从manager移除(如果我们在Close里调用RemoveSession? 不，RemoveSession由Manager调用)
-	// 目前的设计是 Manager.RemoveSession 是外部调用的。
-	// 实际上，当 session.Close() 被调用时，它只是停止了内部循环。
-	// handleTerminalWebSocket defer RemoveSession，所以当handler退出时会移除。
+	// 不需要在这里手动清理：handleTerminalWebSocket defer DetachConnection，
+	// 当最后一条连接（driver或follower）离开时会话才会被真正关闭并从manager移除。
+}
+
+// BroadcastPullEvent 向所有活跃的WebSocket连接广播一次结构化的镜像拉取进度事件（image_pull_event），
+// 与历史的 image_pull_progress（自由格式字符串）并存，供能渲染分层进度条的前端使用
+func (tm *TerminalManager) BroadcastPullEvent(event docker.PullEvent) {
+	tm.mu.RLock()
+	activeSessions := make(map[string]*TerminalSession)
+	for sessionID, session := range tm.sessions {
+		activeSessions[sessionID] = session
+	}
+	tm.mu.RUnlock()
+
+	msg := Message{
+		Type: "image_pull_event",
+		Data: event,
+	}
+
+	for _, session := range activeSessions {
+		session.Send(msg)
+	}
+}
+
+// CourseUpdatedMessage course_updated消息的Data负载
+type CourseUpdatedMessage struct {
+	CourseID string `json:"courseId"`
+	Event    string `json:"event"` // 对应 course.CourseEventType："updated"或"removed"
+}
+
+// BroadcastCourseUpdated 向所有归属该课程的活跃终端会话广播一次课程热重载事件，
+// 使作者编辑课程内容（Markdown、index.yaml）时浏览器无需刷新即可感知变化。
+// 只广播给 courseID 与 TerminalSession.courseID 匹配的会话，与其它课程的学生无关
+func (tm *TerminalManager) BroadcastCourseUpdated(courseID, event string) {
+	tm.mu.RLock()
+	activeSessions := make([]*TerminalSession, 0, len(tm.sessions))
+	for _, session := range tm.sessions {
+		if session.courseID == courseID {
+			activeSessions = append(activeSessions, session)
+		}
+	}
+	tm.mu.RUnlock()
+
+	msg := Message{
+		Type: "course_updated",
+		Data: CourseUpdatedMessage{CourseID: courseID, Event: event},
+	}
+	for _, session := range activeSessions {
+		session.Send(msg)
+	}
+}
+
+// ContainerSupervisorMessage container_supervisor消息的Data负载
+type ContainerSupervisorMessage struct {
+	ContainerID string `json:"containerId"`
+	Phase       string `json:"phase"`
+	Message     string `json:"message,omitempty"`
+}
+
+// BroadcastContainerSupervisorEvent 向已附加到containerID的活跃终端会话广播一次容器监督事件
+// （如容器意外退出、正在自动重启），只广播给 containerID 与 TerminalSession.containerID 匹配的会话
+func (tm *TerminalManager) BroadcastContainerSupervisorEvent(containerID, phase, message string) {
+	tm.mu.RLock()
+	activeSessions := make([]*TerminalSession, 0, len(tm.sessions))
+	for _, session := range tm.sessions {
+		if session.containerID == containerID {
+			activeSessions = append(activeSessions, session)
+		}
+	}
+	tm.mu.RUnlock()
+
+	msg := Message{
+		Type: "container_supervisor",
+		Data: ContainerSupervisorMessage{ContainerID: containerID, Phase: phase, Message: message},
+	}
+	for _, session := range activeSessions {
+		session.Send(msg)
+	}
+}
+
+// BroadcastExecLifecycleEvent 向已附加到containerID的活跃终端会话广播一次exec生命周期事件
+// （started/exited），使客户端即使在退出码为0的正常结束时也能感知到命令已经结束，
+// 而不是只有非0退出码才能看到报错
+func (tm *TerminalManager) BroadcastExecLifecycleEvent(containerID string, event docker.ExecLifecycleEvent) {
+	tm.mu.RLock()
+	activeSessions := make([]*TerminalSession, 0, len(tm.sessions))
+	for _, session := range tm.sessions {
+		if session.containerID == containerID {
+			activeSessions = append(activeSessions, session)
+		}
+	}
+	tm.mu.RUnlock()
+
+	msg := Message{
+		Type: "exec_lifecycle",
+		Data: event,
+	}
+	for _, session := range activeSessions {
+		session.Send(msg)
+	}
 }
 
 // GetActiveSessionCount 获取活跃会话数量
@@ -383,3 +671,22 @@ func (tm *TerminalManager) GetActiveSessionCount() int {
 	defer tm.mu.RUnlock()
 	return len(tm.sessions)
 }
+
+// DrainSessions 关闭当前所有终端会话（断开其上挂载的driver/follower连接并终止底层exec），
+// 供进程优雅关闭时调用，确保退出前不留下孤儿容器exec连接
+func (tm *TerminalManager) DrainSessions(ctx context.Context) error {
+	tm.mu.RLock()
+	sessionIDs := make([]string, 0, len(tm.sessions))
+	for id := range tm.sessions {
+		sessionIDs = append(sessionIDs, id)
+	}
+	tm.mu.RUnlock()
+
+	for _, id := range sessionIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tm.RemoveSession(id)
+	}
+	return nil
+}
@@ -37,10 +37,13 @@ func TestTerminalManager_CreateSession(t *testing.T) {
 	tm := NewTerminalManager()
 	tm.SetLogger(logger.NewLogger(logger.ERROR))
 
-	session := tm.CreateSession("session-123", "container-abc", nil, nil)
+	session, isNew := tm.CreateSession("session-123", "container-abc", "", nil, RoleDriver)
 	if session == nil {
 		t.Fatal("CreateSession should return non-nil session")
 	}
+	if !isNew {
+		t.Error("first CreateSession call for a sessionID should report isNew=true")
+	}
 
 	if session.sessionID != "session-123" {
 		t.Errorf("Expected sessionID 'session-123', got: %s", session.sessionID)
@@ -60,7 +63,7 @@ func TestTerminalManager_RemoveSession(t *testing.T) {
 	tm := NewTerminalManager()
 	tm.SetLogger(logger.NewLogger(logger.ERROR))
 
-	tm.CreateSession("session-123", "container-abc", nil, nil)
+	tm.CreateSession("session-123", "container-abc", "", nil, RoleDriver)
 
 	tm.RemoveSession("session-123")
 
@@ -77,24 +80,35 @@ func TestTerminalManager_RemoveSession_NotFound(t *testing.T) {
 	tm.RemoveSession("nonexistent-session")
 }
 
-func TestTerminalManager_CreateSession_ReplacesExisting(t *testing.T) {
+func TestTerminalManager_CreateSession_AttachesToExisting(t *testing.T) {
 	tm := NewTerminalManager()
 	tm.SetLogger(logger.NewLogger(logger.ERROR))
 
-	tm.CreateSession("session-123", "container-1", nil, nil)
-	tm.CreateSession("session-123", "container-2", nil, nil)
+	first, isNew1 := tm.CreateSession("session-123", "container-1", "", nil, RoleDriver)
+	second, isNew2 := tm.CreateSession("session-123", "container-2", "", nil, RoleFollower)
+
+	if !isNew1 {
+		t.Error("first CreateSession call should report isNew=true")
+	}
+	if isNew2 {
+		t.Error("second CreateSession call for an existing sessionID should report isNew=false")
+	}
+	if first != second {
+		t.Error("CreateSession should return the existing session, not a new one")
+	}
 
 	count := tm.GetActiveSessionCount()
 	if count != 1 {
-		t.Errorf("Expected 1 active session (replaced), got: %d", count)
+		t.Errorf("Expected 1 active session (shared, not replaced), got: %d", count)
 	}
 
-	tm.mu.RLock()
-	session := tm.sessions["session-123"]
-	tm.mu.RUnlock()
+	// containerID来自最初创建时的那次调用，后续加入者不应该把它改掉
+	if first.containerID != "container-1" {
+		t.Errorf("Expected containerID 'container-1' (unchanged), got: %s", first.containerID)
+	}
 
-	if session.containerID != "container-2" {
-		t.Errorf("Expected containerID 'container-2', got: %s", session.containerID)
+	if len(first.conns) != 2 {
+		t.Errorf("Expected 2 attached connections (driver+follower), got: %d", len(first.conns))
 	}
 }
 
@@ -107,13 +121,13 @@ func TestTerminalManager_GetActiveSessionCount(t *testing.T) {
 		t.Errorf("Expected 0, got: %d", count)
 	}
 
-	tm.CreateSession("session-1", "container-1", nil, nil)
+	tm.CreateSession("session-1", "container-1", "", nil, RoleDriver)
 	count = tm.GetActiveSessionCount()
 	if count != 1 {
 		t.Errorf("Expected 1, got: %d", count)
 	}
 
-	tm.CreateSession("session-2", "container-2", nil, nil)
+	tm.CreateSession("session-2", "container-2", "", nil, RoleDriver)
 	count = tm.GetActiveSessionCount()
 	if count != 2 {
 		t.Errorf("Expected 2, got: %d", count)
@@ -134,7 +148,7 @@ func TestTerminalManager_ConcurrentAccess(t *testing.T) {
 
 	for i := 0; i < 10; i++ {
 		go func(idx int) {
-			tm.CreateSession("session-"+string(rune('0'+idx)), "container-1", nil, nil)
+			tm.CreateSession("session-"+string(rune('0'+idx)), "container-1", "", nil, RoleDriver)
 			_ = tm.GetActiveSessionCount()
 			done <- true
 		}(i)
@@ -186,7 +200,7 @@ func TestTerminalSession_Fields(t *testing.T) {
 	tm := NewTerminalManager()
 	tm.SetLogger(logger.NewLogger(logger.ERROR))
 
-	session := tm.CreateSession("sess-001", "cont-xyz", nil, nil)
+	session, _ := tm.CreateSession("sess-001", "cont-xyz", "", nil, RoleDriver)
 
 	if session.sessionID != "sess-001" {
 		t.Errorf("Expected sessionID 'sess-001', got: %s", session.sessionID)
@@ -196,8 +210,8 @@ func TestTerminalSession_Fields(t *testing.T) {
 		t.Errorf("Expected containerID 'cont-xyz', got: %s", session.containerID)
 	}
 
-	if session.conn != nil {
-		t.Error("conn should be nil for this test")
+	if len(session.conns) != 1 || session.conns[0].conn != nil {
+		t.Error("the single attached connection should wrap a nil conn for this test")
 	}
 
 	if session.ctx == nil {
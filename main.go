@@ -7,7 +7,6 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -19,6 +18,7 @@ import (
 	"kwdb-playground/internal/course"
 	"kwdb-playground/internal/docker"
 	"kwdb-playground/internal/logger"
+	"kwdb-playground/internal/shutdown"
 	"kwdb-playground/internal/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -33,7 +33,7 @@ var staticFiles embed.FS
 // 守护进程相关默认路径（相对当前工作目录）
 const (
 	pidFilePath   = "tmp/kwdb-playground.pid" // PID 文件路径，用于确保唯一性
-	daemonLogPath = "logs/daemon.log"        // 守护进程日志文件，重定向标准输出/错误
+	daemonLogPath = "logs/daemon.log"         // 守护进程日志文件，重定向标准输出/错误
 )
 
 // containsDaemonFlag 检查命令行是否包含守护进程标志（-d 或 --daemon）
@@ -204,19 +204,54 @@ func main() {
 		appLogger.Info("Course service initialized in disk mode: %s", cfg.Course.Dir)
 	}
 	courseService.SetLogger(appLogger) // 设置统一的logger实例
+	for _, srcCfg := range cfg.Course.ExtraSources {
+		src, err := course.NewSourceFromConfig(srcCfg, cfg.DataDir)
+		if err != nil {
+			appLogger.Warn("跳过无效的课程数据源配置 %+v: %v", srcCfg, err)
+			continue
+		}
+		courseService.AddSource(src)
+	}
 	if err := courseService.LoadCourses(); err != nil {
 		// 课程加载失败不应该阻止应用启动，但需要记录警告
 		appLogger.Warn("Warning: failed to load courses: %v", err)
 	}
+	// 启动课程内容热重载监听（本地磁盘目录fsnotify + 远程数据源后台轮询），随进程生命周期运行
+	if err := courseService.Watch(context.Background()); err != nil {
+		appLogger.Warn("Warning: failed to start course hot-reload watcher: %v", err)
+	}
 
 	// 初始化WebSocket终端管理器 - 简化版本，专注于docker exec -it /bin/bash
 	terminalManager := websocket.NewTerminalManager()
 
-	// 初始化Docker控制器，传入WebSocket管理器
-	dockerController, err := docker.NewControllerWithTerminalManager(terminalManager)
+	// 初始化容器控制器，传入WebSocket管理器；按 cfg.Runtime.Type 选择实际后端：
+	// "docker"（默认）走Docker守护进程，"containerd"直连containerd私有API，
+	// "cri"走标准CRI gRPC（RuntimeService/ImageService），"auto"按containerd socket→Docker
+	// socket的顺序自动探测，对上层调用方完全透明
+	var dockerController docker.Controller
+	var err error
+	switch cfg.Runtime.Type {
+	case "containerd":
+		dockerController, err = docker.NewContainerdController(docker.ContainerdConfig{
+			Address:   cfg.Runtime.ContainerdAddress,
+			Namespace: cfg.Runtime.ContainerdNamespace,
+		}, terminalManager)
+	case "cri":
+		dockerController, err = docker.NewCRIController(docker.CRIConfig{
+			Endpoint:  cfg.Runtime.CRIEndpoint,
+			Namespace: cfg.Runtime.ContainerdNamespace,
+		}, terminalManager)
+	case "auto":
+		dockerController, err = docker.NewAutoDetectController(docker.ContainerdConfig{
+			Address:   cfg.Runtime.ContainerdAddress,
+			Namespace: cfg.Runtime.ContainerdNamespace,
+		}, terminalManager)
+	default:
+		dockerController, err = docker.NewControllerWithTerminalManager(terminalManager)
+	}
 	if err != nil {
-		// Docker服务不可用时记录警告但不阻止应用启动
-		appLogger.Warn("Warning: Docker service not available: %v", err)
+		// 容器运行时不可用时记录警告但不阻止应用启动
+		appLogger.Warn("Warning: container runtime not available: %v", err)
 		dockerController = nil
 	}
 	terminalManager.SetLogger(appLogger) // 设置统一的logger实例
@@ -327,33 +362,54 @@ func main() {
 		}
 	}()
 
-	// 等待中断信号以优雅关闭服务器
-	quit := make(chan os.Signal, 1)
-	// 监听SIGINT和SIGTERM信号
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	appLogger.Info("Shutting down server...")
-
-	// 给服务器5秒时间完成现有请求
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		appLogger.Error("Server forced to shutdown: %v", err)
+	// 按优先级注册各长生命周期子系统的关闭钩子，统一交给shutdown协调器处理信号：
+	// 先停止接收新请求，再断开终端会话与容器连接，最后落盘课程进度/清理PID文件
+	shutdown.RegisterHook("http-server", 10, func(ctx context.Context) error {
+		appLogger.Info("正在停止HTTP服务器...")
+		return srv.Shutdown(ctx)
+	})
+	shutdown.RegisterHook("terminal-sessions", 20, func(ctx context.Context) error {
+		appLogger.Info("正在断开所有终端会话...")
+		return terminalManager.DrainSessions(ctx)
+	})
+	if dockerController != nil {
+		shutdown.RegisterHook("docker-controller", 30, func(ctx context.Context) error {
+			appLogger.Info("正在停止所有课程容器...")
+			if _, err := dockerController.CleanupAllContainers(ctx); err != nil {
+				appLogger.Warn("停止容器时出现错误: %v", err)
+			}
+			appLogger.Info("正在关闭Docker控制器...")
+			return dockerController.Close()
+		})
 	}
+	shutdown.RegisterHook("course-service", 40, func(ctx context.Context) error {
+		appLogger.Info("正在落盘课程进度...")
+		return courseService.Close()
+	})
+	shutdown.RegisterHook("pid-file", 50, func(ctx context.Context) error {
+		if os.Getenv("DAEMON_MODE") == "1" {
+			removePIDFile(pidFilePath)
+		}
+		return nil
+	})
+	shutdown.RegisterHook("logger-flush", 90, func(ctx context.Context) error {
+		appLogger.Flush()
+		return nil
+	})
 
-	// 守护子进程退出时清理 PID 文件
-	if os.Getenv("DAEMON_MODE") == "1" {
-		removePIDFile(pidFilePath)
-	}
+	// DEBUG日志级别下，SIGQUIT转储goroutine栈用于排查卡死问题而不是触发关闭
+	debug := appLogger.GetLevel() <= logger.DEBUG
+	shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second
+	shutdown.Trap(appLogger, shutdownTimeout, debug)
 
-	appLogger.Info("Server exited")
+	select {} // 阻塞主goroutine；进程退出由shutdown.Trap收到信号后调用os.Exit完成
 }
 
 // getContentType 根据文件路径返回对应的Content-Type
 // 用于设置HTTP响应的Content-Type头，支持常见的Web文件类型
 // 参数:
 //
-//  filepath: 文件路径
+//	filepath: 文件路径
 //
 // 返回: 对应的MIME类型字符串
 func getContentType(filepath string) string {